@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	agentSvc "go.keploy.io/server/v2/pkg/service/agent"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("agent", Agent)
+}
+
+func Agent(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "agent",
+		Short:   "serve a local http and grpc api to drive the record and test sessions",
+		Example: `keploy agent -c "/path/to/user/app" --address localhost:36789 --grpc-address localhost:36790`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, cmd.Name())
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var agent agentSvc.Service
+			var ok bool
+			if agent, ok = svc.(agentSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy agent service interface")
+				return nil
+			}
+
+			err = agent.Start(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to run the agent")
+				return nil
+			}
+
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add agent flags")
+		return nil
+	}
+
+	return cmd
+}
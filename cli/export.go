@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	exporterSvc "go.keploy.io/server/v2/pkg/service/exporter"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("export", Export)
+}
+
+func Export(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "export",
+		Short:   "Export the test-sets as HAR files or Postman collections",
+		Example: `keploy export --format postman -t test-set-1 -o ./collections`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, "export")
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var exporter exporterSvc.Service
+			var ok bool
+			if exporter, ok = svc.(exporterSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy exporter service interface")
+				return nil
+			}
+
+			err = exporter.Export(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to export the test-sets")
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add export flags")
+		return nil
+	}
+	return cmd
+}
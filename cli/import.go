@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	importerSvc "go.keploy.io/server/v2/pkg/service/importer"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("import", Import)
+}
+
+func Import(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "import",
+		Short: "Import the traffic captured outside of keploy, or the requests of the other http tools, into a test-set",
+	}
+
+	cmd.AddCommand(ImportPcap(ctx, logger, serviceFactory, cmdConfigurator))
+	cmd.AddCommand(ImportHar(ctx, logger, serviceFactory, cmdConfigurator))
+	cmd.AddCommand(ImportPostman(ctx, logger, serviceFactory, cmdConfigurator))
+	for _, subCmd := range cmd.Commands() {
+		err := cmdConfigurator.AddFlags(subCmd)
+		if err != nil {
+			utils.LogError(logger, err, "failed to add flags to command", zap.String("command", subCmd.Name()))
+		}
+	}
+	return cmd
+}
+
+func ImportPcap(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "pcap <file>",
+		Short:   "Import the test cases and the mocks from a packet capture (pcap or pcapng)",
+		Example: `keploy import pcap capture.pcap --app-port 8080`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			svc, err := serviceFactory.GetService(ctx, "import")
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var importer importerSvc.Service
+			var ok bool
+			if importer, ok = svc.(importerSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy importer service interface")
+				return nil
+			}
+
+			err = importer.ImportPcap(ctx, args[0])
+			if err != nil {
+				utils.LogError(logger, err, "failed to import the capture")
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func ImportHar(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "har <file>",
+		Short:   "Import the test cases from a HAR file, e.g. saved from the network tab of a browser",
+		Example: `keploy import har requests.har`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			importer, ok := getImporter(ctx, logger, serviceFactory)
+			if !ok {
+				return nil
+			}
+			err := importer.ImportHar(ctx, args[0])
+			if err != nil {
+				utils.LogError(logger, err, "failed to import the har file")
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func ImportPostman(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "postman <file>",
+		Short:   "Import the test cases from a Postman collection, the saved responses of the requests are the expected ones",
+		Example: `keploy import postman collection.postman_collection.json`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			importer, ok := getImporter(ctx, logger, serviceFactory)
+			if !ok {
+				return nil
+			}
+			err := importer.ImportPostman(ctx, args[0])
+			if err != nil {
+				utils.LogError(logger, err, "failed to import the postman collection")
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func getImporter(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory) (importerSvc.Service, bool) {
+	svc, err := serviceFactory.GetService(ctx, "import")
+	if err != nil {
+		utils.LogError(logger, err, "failed to get service")
+		return nil, false
+	}
+	importer, ok := svc.(importerSvc.Service)
+	if !ok {
+		utils.LogError(logger, nil, "service doesn't satisfy importer service interface")
+		return nil, false
+	}
+	return importer, true
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	migratorSvc "go.keploy.io/server/v2/pkg/service/migrator"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("migrate", Migrate)
+}
+
+func Migrate(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "migrate",
+		Short:   "Migrate the yaml test-sets and reports to the sqlite storage backend",
+		Example: `keploy migrate -p .`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, "migrate")
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var migrator migratorSvc.Service
+			var ok bool
+			if migrator, ok = svc.(migratorSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy migrator service interface")
+				return nil
+			}
+
+			err = migrator.Migrate(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to migrate the test-sets")
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add migrate flags")
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	mockSvc "go.keploy.io/server/v2/pkg/service/mockserver"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("mock", Mock)
+}
+
+func Mock(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "mock",
+		Short: "Manage the recorded mocks of keploy",
+	}
+
+	cmd.AddCommand(Serve(ctx, logger, serviceFactory, cmdConfigurator))
+	for _, subCmd := range cmd.Commands() {
+		err := cmdConfigurator.AddFlags(subCmd)
+		if err != nil {
+			utils.LogError(logger, err, "failed to add flags to command", zap.String("command", subCmd.Name()))
+		}
+	}
+	return cmd
+}
+
+func Serve(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "serve",
+		Short:   "serve the mocks of a test-set without instrumenting the application",
+		Example: `keploy mock serve --test-set test-set-0 --listen 6379:redis --listen 5432:postgres_v2 --http-proxy localhost:16790`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, "mock")
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var server mockSvc.Service
+			var ok bool
+			if server, ok = svc.(mockSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy mock server service interface")
+				return nil
+			}
+
+			err = server.Serve(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to serve the mocks")
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -185,6 +185,25 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 			cmd.Flags().String("driven", c.cfg.Contract.Driven, "Specify the path to download contracts")
 		}
 
+	case "serve":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
+		cmd.Flags().StringP("test-set", "t", c.cfg.MockServer.TestSet, "Test-set whose mocks are served")
+		cmd.Flags().StringSlice("listen", nil, "Ports on which the calls to the dependencies are received, in the port[:protocol[:upstream]] form e.g. --listen \"6379:redis,5432:postgres_v2\"")
+		cmd.Flags().String("http-proxy", c.cfg.MockServer.HTTPProxy, "Address of the http forward proxy serving the http(s) mocks e.g. localhost:16790")
+		cmd.Flags().Bool("fallBack-on-miss", c.cfg.Test.FallBackOnMiss, "Pass the call to the upstream of the listener if no mock is found")
+		cmd.Flags().String("mongo-password", c.cfg.Test.MongoPassword, "Authentication password for mocking MongoDB conn")
+	case "pcap":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where the imported testcases/mocks are stored")
+		cmd.Flags().UintSlice("app-port", nil, "Ports on which the app receives the calls, the other connections of the capture are its calls to the dependencies")
+	case "har", "postman":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where the imported testcases are stored")
+	case "export":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases are stored")
+		cmd.Flags().StringSliceP("test-sets", "t", c.cfg.Export.TestSets, "Testsets to export, all of them by default e.g. --test-sets \"test-set-1, test-set-2\"")
+		cmd.Flags().String("format", "postman", "Format of the exported files (har, postman)")
+		cmd.Flags().StringP("output", "o", ".", "Directory where the exported files are written")
+	case "migrate":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks/reports are stored")
 	case "update":
 		return nil
 	case "normalize":
@@ -202,7 +221,7 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 		cmd.Flags().String("test-file-path", "", "Path to the input test file.")
 		cmd.Flags().String("coverage-report-path", "coverage.xml", "Path to the code coverage report file.")
 		cmd.Flags().String("test-command", "", "The command to run tests and generate coverage report.")
-		cmd.Flags().String("coverage-format", "cobertura", "Type of coverage report.")
+		cmd.Flags().String("coverage-format", "cobertura", "Type of coverage report (cobertura, jacoco, lcov, go).")
 		cmd.Flags().Int("expected-coverage", 100, "The desired coverage percentage.")
 		cmd.Flags().Int("max-iterations", 5, "The maximum number of iterations.")
 		cmd.Flags().String("test-dir", "", "Path to the test directory.")
@@ -217,7 +236,7 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 			return errors.New(errMsg)
 		}
 
-	case "record", "test", "rerecord":
+	case "record", "test", "rerecord", "agent":
 		if cmd.Parent() != nil && cmd.Parent().Name() == "contract" {
 			cmd.Flags().StringSliceP("services", "s", c.cfg.Contract.Services, "Specify the services for which to generate contracts")
 			cmd.Flags().StringP("path", "p", ".", "Specify the path to generate contracts")
@@ -240,13 +259,14 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 		cmd.Flags().String("app-name", c.cfg.AppName, "Name of the user's application")
 		cmd.Flags().Bool("generate-github-actions", c.cfg.GenerateGithubActions, "Generate Github Actions workflow file")
 		cmd.Flags().Bool("in-ci", c.cfg.InCi, "is CI Running or not")
-		//add rest of the uncommon flags for record, test, rerecord commands
+		//add rest of the uncommon flags for record, test, rerecord, agent commands
 		c.AddUncommonFlags(cmd)
 
 	case "keploy":
 		cmd.PersistentFlags().Bool("debug", c.cfg.Debug, "Run in debug mode")
 		cmd.PersistentFlags().Bool("disable-tele", c.cfg.DisableTele, "Run in telemetry mode")
 		cmd.PersistentFlags().Bool("disable-ansi", c.cfg.DisableANSI, "Disable ANSI color in logs")
+		cmd.PersistentFlags().String("storage-backend", c.cfg.StorageBackend, "Storage of the tests, mocks and reports e.g. \"yaml\" or \"sqlite\"")
 		err = cmd.PersistentFlags().MarkHidden("disable-tele")
 		if err != nil {
 			errMsg := "failed to mark telemetry as hidden flag"
@@ -271,6 +291,9 @@ func (c *CmdConfigurator) AddUncommonFlags(cmd *cobra.Command) {
 	switch cmd.Name() {
 	case "record":
 		cmd.Flags().Uint64("record-timer", 0, "User provided time to record its application")
+	case "agent":
+		cmd.Flags().String("address", c.cfg.Agent.Address, "Address on which the agent serves the HTTP control API")
+		cmd.Flags().String("grpc-address", c.cfg.Agent.GRPCAddress, "Address on which the agent serves the gRPC control API, empty to not serve it")
 	case "test", "rerecord":
 		cmd.Flags().StringSliceP("test-sets", "t", utils.Keys(c.cfg.Test.SelectedTests), "Testsets to run e.g. --testsets \"test-set-1, test-set-2\"")
 		cmd.Flags().String("host", c.cfg.Test.Host, "Custom host to replace the actual host in the testcases")
@@ -285,6 +308,8 @@ func (c *CmdConfigurator) AddUncommonFlags(cmd *cobra.Command) {
 			cmd.Flags().Bool("skip-coverage", c.cfg.Test.SkipCoverage, "skip code coverage computation while running the test cases")
 			cmd.Flags().Bool("remove-unused-mocks", c.cfg.Test.RemoveUnusedMocks, "Clear the unused mocks for the passed test-sets")
 			cmd.Flags().Bool("fallBack-on-miss", c.cfg.Test.FallBackOnMiss, "Enable connecting to actual service if mock not found during test mode")
+			cmd.Flags().Bool("strict-mocks", c.cfg.Test.StrictMocks, "Fail the testcases making an outgoing call that doesn't match any mock")
+			cmd.Flags().Bool("record-on-miss", c.cfg.Test.RecordOnMiss, "Call the actual service when no mock matches an outgoing call and append the recorded calls to the mocks of the test-set")
 			cmd.Flags().String("jacoco-agent-path", c.cfg.Test.JacocoAgentPath, "Only applicable for test coverage for Java projects. You can override the jacoco agent jar by proving its path")
 			cmd.Flags().String("base-path", c.cfg.Test.BasePath, "Custom api basePath/origin to replace the actual basePath/origin in the testcases; App flag is ignored and app will not be started & instrumented when this is set since the application running on a different machine")
 			cmd.Flags().Bool("update-temp", c.cfg.Test.UpdateTemplate, "Update the template with the result of the testcases.")
@@ -292,6 +317,12 @@ func (c *CmdConfigurator) AddUncommonFlags(cmd *cobra.Command) {
 			cmd.Flags().Bool("disableMockUpload", c.cfg.Test.DisableMockUpload, "Store/Fetch mocks locally")
 			cmd.Flags().Bool("useLocalMock", false, "Use local mocks instead of fetching from the cloud")
 			cmd.Flags().Bool("disable-line-coverage", c.cfg.Test.DisableLineCoverage, "Disable line coverage generation.")
+			cmd.Flags().Int("parallel", c.cfg.Test.Parallel, "Number of test-sets to run concurrently, each on its own instance of the app when keploy starts it with docker run")
+			cmd.Flags().StringSlice("report-format", c.cfg.Test.ReportFormat, "Formats of the test run report in addition to the yaml reports e.g. --report-format \"junit,json\"")
+			cmd.Flags().Uint64("latency-threshold", c.cfg.Test.Latency.Global.Absolute, "Milliseconds a testcase may respond slower than recorded before it's flagged as a latency regression")
+			cmd.Flags().Float64("latency-threshold-percent", c.cfg.Test.Latency.Global.Percentage, "Percentage of the recorded latency a testcase may respond slower before it's flagged as a latency regression")
+			cmd.Flags().Bool("denoise", false, "Replay the test-sets several times against the same mocks and add the response fields which vary between the runs to the noise of the testcases")
+			cmd.Flags().Int("denoise-runs", 2, "Number of times the test-sets are replayed to find the noisy fields, used with --denoise")
 		}
 	}
 }
@@ -309,6 +340,9 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"removeUnusedMocks":     "remove-unused-mocks",
 		"goCoverage":            "go-coverage",
 		"fallBackOnMiss":        "fallBack-on-miss",
+		"strictMocks":           "strict-mocks",
+		"recordOnMiss":          "record-on-miss",
+		"denoiseRuns":           "denoise-runs",
 		"basePath":              "base-path",
 		"updateTemplate":        "update-template",
 		"mocking":               "mocking",
@@ -338,6 +372,7 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"generateGithubActions": "generate-github-actions",
 		"disableTele":           "disable-tele",
 		"disableANSI":           "disable-ansi",
+		"storageBackend":        "storage-backend",
 		"selectedTests":         "selected-tests",
 		"testReport":            "test-report",
 		"enableTesting":         "enable-testing",
@@ -356,12 +391,17 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 }
 
 func (c *CmdConfigurator) Validate(ctx context.Context, cmd *cobra.Command) error {
-	err := isCompatible(c.logger)
-	if err != nil {
-		return err
+	// the mock server, the import, the export and the migration don't load the eBPF hooks
+	switch cmd.Name() {
+	case "serve", "pcap", "har", "postman", "export", "migrate":
+	default:
+		err := isCompatible(c.logger)
+		if err != nil {
+			return err
+		}
 	}
 	defaultCfg := *c.cfg
-	err = c.PreProcessFlags(cmd)
+	err := c.PreProcessFlags(cmd)
 	if err != nil {
 		c.logger.Error("failed to preprocess flags", zap.Error(err))
 		return err
@@ -473,6 +513,16 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 		c.logger.Info("Color encoding is disabled")
 	}
 
+	switch c.cfg.StorageBackend {
+	case "":
+		c.cfg.StorageBackend = "yaml"
+	case "yaml", "sqlite":
+	default:
+		errMsg := fmt.Sprintf("invalid storage backend %q, supported backends are yaml and sqlite", c.cfg.StorageBackend)
+		utils.LogError(c.logger, nil, errMsg)
+		return errors.New(errMsg)
+	}
+
 	c.logger.Debug("config has been initialised", zap.Any("for cmd", cmd.Name()), zap.Any("config", c.cfg))
 
 	switch cmd.Name() {
@@ -527,7 +577,7 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 			utils.LogError(c.logger, err, errMsg)
 			return errors.New(errMsg)
 		}
-	case "record", "test", "rerecord":
+	case "record", "test", "rerecord", "agent":
 
 		if cmd.Parent() != nil && cmd.Parent().Name() == "contract" {
 			path, err := cmd.Flags().GetString("path")
@@ -684,6 +734,50 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 				return nil
 			}
 
+			for _, format := range c.cfg.Test.ReportFormat {
+				switch format {
+				case "yaml", "junit", "json":
+				default:
+					errMsg := fmt.Sprintf("unsupported report format: %s, supported formats are yaml, junit and json", format)
+					utils.LogError(c.logger, nil, errMsg)
+					return errors.New(errMsg)
+				}
+			}
+
+			if cmd.Flags().Changed("latency-threshold") {
+				c.cfg.Test.Latency.Global.Absolute, err = cmd.Flags().GetUint64("latency-threshold")
+				if err != nil {
+					errMsg := "failed to get the latency threshold"
+					utils.LogError(c.logger, err, errMsg)
+					return errors.New(errMsg)
+				}
+			}
+			if cmd.Flags().Changed("latency-threshold-percent") {
+				c.cfg.Test.Latency.Global.Percentage, err = cmd.Flags().GetFloat64("latency-threshold-percent")
+				if err != nil {
+					errMsg := "failed to get the latency threshold percentage"
+					utils.LogError(c.logger, err, errMsg)
+					return errors.New(errMsg)
+				}
+			}
+			if c.cfg.Test.Latency.Global.Percentage < 0 {
+				errMsg := "latency threshold percentage can't be negative"
+				utils.LogError(c.logger, nil, errMsg)
+				return errors.New(errMsg)
+			}
+
+			if c.cfg.Test.Denoise {
+				if c.cfg.Test.DenoiseRuns < 2 {
+					errMsg := "at least 2 runs are needed to find the noisy fields, use --denoise-runs 2 or more"
+					utils.LogError(c.logger, nil, errMsg)
+					return errors.New(errMsg)
+				}
+				// the runs of the denoise only collect the responses, the test-sets are left as they are
+				c.cfg.Test.SkipCoverage = true
+				c.cfg.Test.RemoveUnusedMocks = false
+				c.cfg.Test.RecordOnMiss = false
+			}
+
 			// skip coverage by default if command is of type docker
 			if utils.CmdType(c.cfg.CommandType) != "native" && !cmd.Flags().Changed("skip-coverage") {
 				c.cfg.Test.SkipCoverage = true
@@ -698,6 +792,148 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 				}
 			}
 		}
+		if cmd.Name() == "agent" {
+			var err error
+			if cmd.Flags().Changed("address") {
+				c.cfg.Agent.Address, err = cmd.Flags().GetString("address")
+				if err != nil {
+					errMsg := "failed to get the address of the agent api"
+					utils.LogError(c.logger, err, errMsg)
+					return errors.New(errMsg)
+				}
+			}
+			if cmd.Flags().Changed("grpc-address") {
+				c.cfg.Agent.GRPCAddress, err = cmd.Flags().GetString("grpc-address")
+				if err != nil {
+					errMsg := "failed to get the address of the agent grpc api"
+					utils.LogError(c.logger, err, errMsg)
+					return errors.New(errMsg)
+				}
+			}
+		}
+
+	case "serve":
+		absPath, err := utils.GetAbsPath(c.cfg.Path)
+		if err != nil {
+			utils.LogError(c.logger, err, "error while getting absolute path")
+			return errors.New("failed to get the absolute path")
+		}
+		c.cfg.Path = absPath + "/keploy"
+
+		if cmd.Flags().Changed("test-set") {
+			c.cfg.MockServer.TestSet, err = cmd.Flags().GetString("test-set")
+			if err != nil {
+				errMsg := "failed to get the test-set"
+				utils.LogError(c.logger, err, errMsg)
+				return errors.New(errMsg)
+			}
+		}
+		if c.cfg.MockServer.TestSet == "" {
+			errMsg := "missing required --test-set flag or mockServer.testSet in config file"
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
+		if cmd.Flags().Changed("http-proxy") {
+			c.cfg.MockServer.HTTPProxy, err = cmd.Flags().GetString("http-proxy")
+			if err != nil {
+				errMsg := "failed to get the address of the http proxy"
+				utils.LogError(c.logger, err, errMsg)
+				return errors.New(errMsg)
+			}
+		}
+		if cmd.Flags().Changed("fallBack-on-miss") {
+			c.cfg.Test.FallBackOnMiss, err = cmd.Flags().GetBool("fallBack-on-miss")
+			if err != nil {
+				errMsg := "failed to get the fallBackOnMiss flag"
+				utils.LogError(c.logger, err, errMsg)
+				return errors.New(errMsg)
+			}
+		}
+		if cmd.Flags().Changed("mongo-password") {
+			c.cfg.Test.MongoPassword, err = cmd.Flags().GetString("mongo-password")
+			if err != nil {
+				errMsg := "failed to get the mongo password"
+				utils.LogError(c.logger, err, errMsg)
+				return errors.New(errMsg)
+			}
+		}
+		if cmd.Flags().Changed("listen") {
+			listens, err := cmd.Flags().GetStringSlice("listen")
+			if err != nil {
+				errMsg := "failed to get the ports to listen on"
+				utils.LogError(c.logger, err, errMsg)
+				return errors.New(errMsg)
+			}
+			listeners, err := config.ParseMockListeners(listens)
+			if err != nil {
+				utils.LogError(c.logger, err, "failed to parse the ports to listen on")
+				return err
+			}
+			c.cfg.MockServer.Listeners = listeners
+		}
+		if len(c.cfg.MockServer.Listeners) == 0 && c.cfg.MockServer.HTTPProxy == "" {
+			errMsg := "missing --listen or --http-proxy flag, the mocks need a port to be served on"
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
+
+	case "pcap":
+		absPath, err := utils.GetAbsPath(c.cfg.Path)
+		if err != nil {
+			utils.LogError(c.logger, err, "error while getting absolute path")
+			return errors.New("failed to get the absolute path")
+		}
+		c.cfg.Path = absPath + "/keploy"
+
+		appPorts, err := cmd.Flags().GetUintSlice("app-port")
+		if err != nil {
+			errMsg := "failed to get the ports of the app"
+			utils.LogError(c.logger, err, errMsg)
+			return errors.New(errMsg)
+		}
+		if len(appPorts) == 0 {
+			errMsg := "missing required --app-port flag, the calls received by the app can't be told from the ones it made"
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
+		c.cfg.Import.AppPorts = nil
+		for _, port := range appPorts {
+			c.cfg.Import.AppPorts = append(c.cfg.Import.AppPorts, uint32(port))
+		}
+
+	case "har", "postman":
+		absPath, err := utils.GetAbsPath(c.cfg.Path)
+		if err != nil {
+			utils.LogError(c.logger, err, "error while getting absolute path")
+			return errors.New("failed to get the absolute path")
+		}
+		c.cfg.Path = absPath + "/keploy"
+
+	case "export":
+		absPath, err := utils.GetAbsPath(c.cfg.Path)
+		if err != nil {
+			utils.LogError(c.logger, err, "error while getting absolute path")
+			return errors.New("failed to get the absolute path")
+		}
+		c.cfg.Path = absPath + "/keploy"
+		c.cfg.Export.Output, err = utils.GetAbsPath(c.cfg.Export.Output)
+		if err != nil {
+			utils.LogError(c.logger, err, "error while getting absolute path of the output directory")
+			return errors.New("failed to get the absolute path of the output directory")
+		}
+		if c.cfg.Export.Format != "har" && c.cfg.Export.Format != "postman" {
+			errMsg := fmt.Sprintf("unsupported export format %q, use --format har or --format postman", c.cfg.Export.Format)
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
+
+	case "migrate":
+		absPath, err := utils.GetAbsPath(c.cfg.Path)
+		if err != nil {
+			utils.LogError(c.logger, err, "error while getting absolute path")
+			return errors.New("failed to get the absolute path")
+		}
+		c.cfg.Path = absPath + "/keploy"
 
 	case "normalize":
 		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
@@ -1,13 +1,22 @@
 package provider
 
 import (
+	"context"
+	"time"
+
+	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/report"
+	"go.keploy.io/server/v2/pkg/platform/sqlite"
 	"go.keploy.io/server/v2/pkg/platform/storage"
 	"go.keploy.io/server/v2/pkg/platform/yaml/configdb/testset"
 	mockdb "go.keploy.io/server/v2/pkg/platform/yaml/mockdb"
 	openapidb "go.keploy.io/server/v2/pkg/platform/yaml/openapidb"
 	reportdb "go.keploy.io/server/v2/pkg/platform/yaml/reportdb"
 	testdb "go.keploy.io/server/v2/pkg/platform/yaml/testdb"
+	"go.keploy.io/server/v2/pkg/service/migrator"
+	"go.keploy.io/server/v2/pkg/service/replay"
+	"go.uber.org/zap"
 )
 
 type commonPlatformServices struct {
@@ -17,4 +26,94 @@ type commonPlatformServices struct {
 	YamlReportDb  *reportdb.TestReport
 	YamlTestSetDB *testset.Db[*models.TestSet]
 	Storage       *storage.Storage
+	// the stores of the configured storage backend, the yaml files or the sqlite database
+	TestDB    testDB
+	MockDB    mockDB
+	ReportDB  reportDB
+	TestSetDB testSetDB
+}
+
+type testDB interface {
+	GetAllTestSetIDs(ctx context.Context) ([]string, error)
+	GetTestCases(ctx context.Context, testSetID string) ([]*models.TestCase, error)
+	InsertTestCase(ctx context.Context, tc *models.TestCase, testSetID string) error
+	UpdateTestCase(ctx context.Context, tc *models.TestCase, testSetID string) error
+	DeleteTests(ctx context.Context, testSetID string, testCaseIDs []string) error
+	DeleteTestSet(ctx context.Context, testSetID string) error
+}
+
+type mockDB interface {
+	InsertMock(ctx context.Context, mock *models.Mock, testSetID string) error
+	AppendMocks(ctx context.Context, testSetID string, mocks []*models.Mock) error
+	UpdateMocks(ctx context.Context, testSetID string, mockNames map[string]bool) error
+	GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+	GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+}
+
+type reportDB interface {
+	GetAllTestRunIDs(ctx context.Context) ([]string, error)
+	GetTestCaseResults(ctx context.Context, testRunID string, testSetID string) ([]models.TestResult, error)
+	GetReport(ctx context.Context, testRunID string, testSetID string) (*models.TestReport, error)
+	InsertTestCaseResult(ctx context.Context, testRunID string, testSetID string, result *models.TestResult) error
+	InsertReport(ctx context.Context, testRunID string, testSetID string, testReport *models.TestReport) error
+	UpdateReport(ctx context.Context, testRunID string, testCoverage any) error
+}
+
+type testSetDB interface {
+	Read(ctx context.Context, testSetID string) (*models.TestSet, error)
+	Write(ctx context.Context, testSetID string, testSet *models.TestSet) error
+}
+
+// newPlatformServices returns the yaml stores, and the stores of the configured storage backend. The contract
+// service works on the yaml files only.
+func newPlatformServices(logger *zap.Logger, c *config.Config, openAPIDb *openapidb.OpenAPIYaml, storage *storage.Storage) (commonPlatformServices, error) {
+	services := commonPlatformServices{
+		YamlTestDB:    testdb.New(logger, c.Path),
+		YamlMockDb:    mockdb.New(logger, c.Path, ""),
+		YamlOpenAPIDb: openAPIDb,
+		YamlReportDb:  reportdb.New(logger, c.Path+"/reports"),
+		YamlTestSetDB: testset.New[*models.TestSet](logger, c.Path),
+		Storage:       storage,
+	}
+	switch c.StorageBackend {
+	case "sqlite":
+		db, err := sqlite.Open(logger, c.Path)
+		if err != nil {
+			return services, err
+		}
+		services.TestDB = sqlite.NewTestDB(db)
+		services.MockDB = sqlite.NewMockDB(db)
+		services.ReportDB = sqlite.NewReportDB(db)
+		services.TestSetDB = sqlite.NewTestSetDB[*models.TestSet](db)
+	default:
+		services.TestDB = services.YamlTestDB
+		services.MockDB = services.YamlMockDb
+		services.ReportDB = services.YamlReportDb
+		services.TestSetDB = services.YamlTestSetDB
+	}
+	return services, nil
+}
+
+// getMigrator returns the service migrating the yaml test-sets to the sqlite database of the keploy directory.
+func getMigrator(logger *zap.Logger, c *config.Config, services *commonPlatformServices) (interface{}, error) {
+	db, err := sqlite.Open(logger, c.Path)
+	if err != nil {
+		return nil, err
+	}
+	return migrator.New(logger, services.YamlTestDB, services.YamlMockDb, services.YamlTestSetDB, services.YamlReportDb, db, c), nil
+}
+
+// getReportExporters returns the exporters of the test run reports for the configured formats,
+// the yaml reports are always written by the report db.
+func getReportExporters(logger *zap.Logger, cfg *config.Config) []replay.ReportExporter {
+	var exporters []replay.ReportExporter
+	for _, format := range cfg.Test.ReportFormat {
+		switch format {
+		case "junit":
+			exporters = append(exporters, report.NewJUnit(logger, cfg.Path+"/reports"))
+		case "json":
+			exporters = append(exporters, report.NewJSON(logger, cfg.Path+"/reports"))
+		}
+	}
+	return exporters
 }
@@ -13,17 +13,16 @@ import (
 	"go.keploy.io/server/v2/pkg/core/hooks"
 	"go.keploy.io/server/v2/pkg/core/proxy"
 	"go.keploy.io/server/v2/pkg/core/tester"
-	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/platform/docker"
 	"go.keploy.io/server/v2/pkg/platform/storage"
 	"go.keploy.io/server/v2/pkg/platform/telemetry"
-	"go.keploy.io/server/v2/pkg/platform/yaml/configdb/testset"
-	mockdb "go.keploy.io/server/v2/pkg/platform/yaml/mockdb"
 	openapidb "go.keploy.io/server/v2/pkg/platform/yaml/openapidb"
-	reportdb "go.keploy.io/server/v2/pkg/platform/yaml/reportdb"
-	testdb "go.keploy.io/server/v2/pkg/platform/yaml/testdb"
 	"go.keploy.io/server/v2/pkg/service"
+	"go.keploy.io/server/v2/pkg/service/agent"
 	"go.keploy.io/server/v2/pkg/service/contract"
+	"go.keploy.io/server/v2/pkg/service/exporter"
+	"go.keploy.io/server/v2/pkg/service/importer"
+	"go.keploy.io/server/v2/pkg/service/mockserver"
 	"go.keploy.io/server/v2/pkg/service/orchestrator"
 	"go.keploy.io/server/v2/pkg/service/record"
 	"go.keploy.io/server/v2/pkg/service/replay"
@@ -43,8 +42,8 @@ func Get(ctx context.Context, cmd string, cfg *config.Config, logger *zap.Logger
 		return nil, err
 	}
 	contractSvc := contract.New(logger, commonServices.YamlTestDB, commonServices.YamlMockDb, commonServices.YamlOpenAPIDb, cfg)
-	recordSvc := record.New(logger, commonServices.YamlTestDB, commonServices.YamlMockDb, tel, commonServices.Instrumentation, cfg)
-	replaySvc := replay.NewReplayer(logger, commonServices.YamlTestDB, commonServices.YamlMockDb, commonServices.YamlReportDb, commonServices.YamlTestSetDB, tel, commonServices.Instrumentation, auth, commonServices.Storage, cfg)
+	recordSvc := record.New(logger, commonServices.TestDB, commonServices.MockDB, commonServices.TestSetDB, tel, commonServices.Instrumentation, cfg)
+	replaySvc := replay.NewReplayer(logger, commonServices.TestDB, commonServices.MockDB, commonServices.ReportDB, getReportExporters(logger, cfg), commonServices.TestSetDB, tel, commonServices.Instrumentation, auth, commonServices.Storage, cfg)
 
 	switch cmd {
 	case "rerecord":
@@ -55,6 +54,19 @@ func Get(ctx context.Context, cmd string, cfg *config.Config, logger *zap.Logger
 		return replaySvc, nil
 	case "contract":
 		return contractSvc, nil
+	case "agent":
+		// the mocks injected through the agent are served along with the mocks of the test-set
+		injector := agent.NewInjector(commonServices.Instrumentation)
+		agentReplaySvc := replay.NewReplayer(logger, commonServices.TestDB, commonServices.MockDB, commonServices.ReportDB, getReportExporters(logger, cfg), commonServices.TestSetDB, tel, injector, auth, commonServices.Storage, cfg)
+		return agent.New(logger, recordSvc, agentReplaySvc, commonServices.ReportDB, injector, cfg), nil
+	case "import":
+		return importer.New(logger, commonServices.TestDB, commonServices.MockDB, commonServices.TestSetDB, commonServices.Instrumentation, cfg), nil
+	case "export":
+		return exporter.New(logger, commonServices.TestDB, commonServices.TestSetDB, cfg), nil
+	case "migrate":
+		return getMigrator(logger, cfg, &commonServices.commonPlatformServices)
+	case "mock":
+		return mockserver.New(logger, commonServices.MockDB, commonServices.TestSetDB, commonServices.Instrumentation, cfg), nil
 	default:
 		return nil, errors.New("invalid command")
 	}
@@ -98,21 +110,14 @@ func GetCommonServices(_ context.Context, c *config.Config, logger *zap.Logger)
 	}
 
 	instrumentation := core.New(logger, h, p, t, client)
-	testDB := testdb.New(logger, c.Path)
-	mockDB := mockdb.New(logger, c.Path, "")
 	openAPIdb := openapidb.New(logger, filepath.Join(c.Path, "schema"))
-	reportDB := reportdb.New(logger, c.Path+"/reports")
-	testSetDb := testset.New[*models.TestSet](logger, c.Path)
 	storage := storage.New(c.APIServerURL, logger)
+	services, err := newPlatformServices(logger, c, openAPIdb, storage)
+	if err != nil {
+		return nil, err
+	}
 	return &CommonInternalService{
-		commonPlatformServices{
-			YamlTestDB:    testDB,
-			YamlMockDb:    mockDB,
-			YamlOpenAPIDb: openAPIdb,
-			YamlReportDb:  reportDB,
-			YamlTestSetDB: testSetDb,
-			Storage:       storage,
-		},
+		services,
 		instrumentation,
 	}, nil
 }
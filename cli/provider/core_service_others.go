@@ -8,16 +8,13 @@ import (
 
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg/core"
-	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/platform/telemetry"
-	"go.keploy.io/server/v2/pkg/platform/yaml/configdb/testset"
-	mockdb "go.keploy.io/server/v2/pkg/platform/yaml/mockdb"
 	openapidb "go.keploy.io/server/v2/pkg/platform/yaml/openapidb"
-	reportdb "go.keploy.io/server/v2/pkg/platform/yaml/reportdb"
-	testdb "go.keploy.io/server/v2/pkg/platform/yaml/testdb"
 
 	"go.keploy.io/server/v2/pkg/service"
 	"go.keploy.io/server/v2/pkg/service/contract"
+	"go.keploy.io/server/v2/pkg/service/exporter"
+	"go.keploy.io/server/v2/pkg/service/importer"
 	"go.keploy.io/server/v2/pkg/service/replay"
 	"go.uber.org/zap"
 )
@@ -34,7 +31,7 @@ func Get(ctx context.Context, cmd string, c *config.Config, logger *zap.Logger,
 	}
 	contractSvc := contract.New(logger, commonServices.YamlTestDB, commonServices.YamlMockDb, commonServices.YamlOpenAPIDb, c)
 
-	replaySvc := replay.NewReplayer(logger, commonServices.YamlTestDB, commonServices.YamlMockDb, commonServices.YamlReportDb, commonServices.YamlTestSetDB, tel, commonServices.Instrumentation, auth, commonServices.Storage, c)
+	replaySvc := replay.NewReplayer(logger, commonServices.TestDB, commonServices.MockDB, commonServices.ReportDB, getReportExporters(logger, c), commonServices.TestSetDB, tel, commonServices.Instrumentation, auth, commonServices.Storage, c)
 
 	if (cmd == "test" && c.Test.BasePath != "") || cmd == "normalize" || cmd == "templatize" {
 		return replaySvc, nil
@@ -42,25 +39,29 @@ func Get(ctx context.Context, cmd string, c *config.Config, logger *zap.Logger,
 	if cmd == "contract" {
 		return contractSvc, nil
 	}
+	// the collections of the other http tools are converted without any instrumentation
+	if cmd == "import" {
+		return importer.New(logger, commonServices.TestDB, commonServices.MockDB, commonServices.TestSetDB, commonServices.Instrumentation, c), nil
+	}
+	if cmd == "migrate" {
+		return getMigrator(logger, c, &commonServices.commonPlatformServices)
+	}
+	if cmd == "export" {
+		return exporter.New(logger, commonServices.TestDB, commonServices.TestSetDB, c), nil
+	}
 
 	return nil, errors.New("command not supported in non linux os")
 }
 
 func GetCommonServices(_ context.Context, c *config.Config, logger *zap.Logger) (*CommonInternalService, error) {
 	instrumentation := core.New(logger)
-	testDB := testdb.New(logger, c.Path)
-	mockDB := mockdb.New(logger, c.Path, "")
 	openAPIdb := openapidb.New(logger, c.Path)
-	reportDB := reportdb.New(logger, c.Path+"/reports")
-	testSetDb := testset.New[*models.TestSet](logger, c.Path)
+	services, err := newPlatformServices(logger, c, openAPIdb, nil)
+	if err != nil {
+		return nil, err
+	}
 	return &CommonInternalService{
-		commonPlatformServices{
-			YamlTestDB:    testDB,
-			YamlMockDb:    mockDB,
-			YamlOpenAPIDb: openAPIdb,
-			YamlReportDb:  reportDB,
-			YamlTestSetDB: testSetDb,
-		},
+		services,
 		instrumentation,
 	}, nil
 }
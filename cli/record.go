@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
 	recordSvc "go.keploy.io/server/v2/pkg/service/record"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
@@ -35,7 +36,7 @@ func Record(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFa
 				return nil
 			}
 
-			err = record.Start(ctx, false)
+			err = record.Start(ctx, models.RecordOptions{})
 			if err != nil {
 				utils.LogError(logger, err, "failed to record")
 				return nil
@@ -4,39 +4,46 @@ package config
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type Config struct {
-	Path                  string       `json:"path" yaml:"path" mapstructure:"path"`
-	AppID                 uint64       `json:"appId" yaml:"appId" mapstructure:"appId"`
-	AppName               string       `json:"appName" yaml:"appName" mapstructure:"appName"`
-	Command               string       `json:"command" yaml:"command" mapstructure:"command"`
-	Templatize            Templatize   `json:"templatize" yaml:"templatize" mapstructure:"templatize"`
-	Port                  uint32       `json:"port" yaml:"port" mapstructure:"port"`
-	DNSPort               uint32       `json:"dnsPort" yaml:"dnsPort" mapstructure:"dnsPort"`
-	ProxyPort             uint32       `json:"proxyPort" yaml:"proxyPort" mapstructure:"proxyPort"`
-	Debug                 bool         `json:"debug" yaml:"debug" mapstructure:"debug"`
-	DisableTele           bool         `json:"disableTele" yaml:"disableTele" mapstructure:"disableTele"`
-	DisableANSI           bool         `json:"disableANSI" yaml:"disableANSI" mapstructure:"disableANSI"`
-	InDocker              bool         `json:"inDocker" yaml:"-" mapstructure:"inDocker"`
-	ContainerName         string       `json:"containerName" yaml:"containerName" mapstructure:"containerName"`
-	NetworkName           string       `json:"networkName" yaml:"networkName" mapstructure:"networkName"`
-	BuildDelay            uint64       `json:"buildDelay" yaml:"buildDelay" mapstructure:"buildDelay"`
-	Test                  Test         `json:"test" yaml:"test" mapstructure:"test"`
-	Record                Record       `json:"record" yaml:"record" mapstructure:"record"`
-	Gen                   UtGen        `json:"gen" yaml:"-" mapstructure:"gen"`
-	Normalize             Normalize    `json:"normalize" yaml:"-" mapstructure:"normalize"`
-	ReRecord              ReRecord     `json:"rerecord" yaml:"-" mapstructure:"rerecord"`
-	ConfigPath            string       `json:"configPath" yaml:"configPath" mapstructure:"configPath"`
-	BypassRules           []BypassRule `json:"bypassRules" yaml:"bypassRules" mapstructure:"bypassRules"`
-	EnableTesting         bool         `json:"enableTesting" yaml:"-" mapstructure:"enableTesting"`
-	GenerateGithubActions bool         `json:"generateGithubActions" yaml:"generateGithubActions" mapstructure:"generateGithubActions"`
-	KeployContainer       string       `json:"keployContainer" yaml:"keployContainer" mapstructure:"keployContainer"`
-	KeployNetwork         string       `json:"keployNetwork" yaml:"keployNetwork" mapstructure:"keployNetwork"`
-	CommandType           string       `json:"cmdType" yaml:"cmdType" mapstructure:"cmdType"`
-	Contract              Contract     `json:"contract" yaml:"contract" mapstructure:"contract"`
+	Path                  string         `json:"path" yaml:"path" mapstructure:"path"`
+	AppID                 uint64         `json:"appId" yaml:"appId" mapstructure:"appId"`
+	AppName               string         `json:"appName" yaml:"appName" mapstructure:"appName"`
+	Command               string         `json:"command" yaml:"command" mapstructure:"command"`
+	Templatize            Templatize     `json:"templatize" yaml:"templatize" mapstructure:"templatize"`
+	Port                  uint32         `json:"port" yaml:"port" mapstructure:"port"`
+	DNSPort               uint32         `json:"dnsPort" yaml:"dnsPort" mapstructure:"dnsPort"`
+	ProxyPort             uint32         `json:"proxyPort" yaml:"proxyPort" mapstructure:"proxyPort"`
+	Debug                 bool           `json:"debug" yaml:"debug" mapstructure:"debug"`
+	DisableTele           bool           `json:"disableTele" yaml:"disableTele" mapstructure:"disableTele"`
+	DisableANSI           bool           `json:"disableANSI" yaml:"disableANSI" mapstructure:"disableANSI"`
+	StorageBackend        string         `json:"storageBackend" yaml:"storageBackend" mapstructure:"storageBackend"`
+	InDocker              bool           `json:"inDocker" yaml:"-" mapstructure:"inDocker"`
+	ContainerName         string         `json:"containerName" yaml:"containerName" mapstructure:"containerName"`
+	NetworkName           string         `json:"networkName" yaml:"networkName" mapstructure:"networkName"`
+	BuildDelay            uint64         `json:"buildDelay" yaml:"buildDelay" mapstructure:"buildDelay"`
+	Test                  Test           `json:"test" yaml:"test" mapstructure:"test"`
+	Record                Record         `json:"record" yaml:"record" mapstructure:"record"`
+	Gen                   UtGen          `json:"gen" yaml:"-" mapstructure:"gen"`
+	Normalize             Normalize      `json:"normalize" yaml:"-" mapstructure:"normalize"`
+	ReRecord              ReRecord       `json:"rerecord" yaml:"-" mapstructure:"rerecord"`
+	ConfigPath            string         `json:"configPath" yaml:"configPath" mapstructure:"configPath"`
+	BypassRules           []BypassRule   `json:"bypassRules" yaml:"bypassRules" mapstructure:"bypassRules"`
+	ProtocolRules         []ProtocolRule `json:"protocolRules" yaml:"protocolRules" mapstructure:"protocolRules"`
+	EnableTesting         bool           `json:"enableTesting" yaml:"-" mapstructure:"enableTesting"`
+	GenerateGithubActions bool           `json:"generateGithubActions" yaml:"generateGithubActions" mapstructure:"generateGithubActions"`
+	KeployContainer       string         `json:"keployContainer" yaml:"keployContainer" mapstructure:"keployContainer"`
+	KeployNetwork         string         `json:"keployNetwork" yaml:"keployNetwork" mapstructure:"keployNetwork"`
+	CommandType           string         `json:"cmdType" yaml:"cmdType" mapstructure:"cmdType"`
+	Contract              Contract       `json:"contract" yaml:"contract" mapstructure:"contract"`
+	Agent                 Agent          `json:"agent" yaml:"agent" mapstructure:"agent"`
+	MockServer            MockServer     `json:"mockServer" yaml:"mockServer" mapstructure:"mockServer"`
+	Import                Import         `json:"import" yaml:"-" mapstructure:"import"`
+	Export                Export         `json:"export" yaml:"-" mapstructure:"export"`
 
 	InCi           bool   `json:"inCi" yaml:"inCi" mapstructure:"inCi"`
 	InstallationID string `json:"-" yaml:"-" mapstructure:"-"`
@@ -88,17 +95,61 @@ type Mappings struct {
 	Self            string              `json:"self" yaml:"self" mapstructure:"self"`
 }
 
+// Agent configures the local control API served by keploy agent, the gRPC API is not served when its
+// address is empty.
+type Agent struct {
+	Address     string `json:"address" yaml:"address" mapstructure:"address"`
+	GRPCAddress string `json:"grpcAddress" yaml:"grpcAddress" mapstructure:"grpcAddress"`
+}
+
+// MockServer configures the mock server started by keploy mock serve, which serves the mocks of a
+// test-set without instrumenting the application.
+type MockServer struct {
+	TestSet   string         `json:"testSet" yaml:"testSet" mapstructure:"testSet"`
+	Listeners []MockListener `json:"listeners" yaml:"listeners" mapstructure:"listeners"`
+	HTTPProxy string         `json:"httpProxy" yaml:"httpProxy" mapstructure:"httpProxy"`
+}
+
+// MockListener is a local port of the mock server on which the calls to a dependency are received.
+// The protocol is detected from the first message unless given. The upstream is the address of the
+// dependency, the calls are passed to it when no mock is found and fallBackOnMiss is set.
+type MockListener struct {
+	Port     uint32 `json:"port" yaml:"port" mapstructure:"port"`
+	Protocol string `json:"protocol" yaml:"protocol" mapstructure:"protocol"`
+	Upstream string `json:"upstream" yaml:"upstream" mapstructure:"upstream"`
+}
+
 type Normalize struct {
 	SelectedTests []SelectedTests `json:"selectedTests" yaml:"selectedTests" mapstructure:"selectedTests"`
 	TestRun       string          `json:"testReport" yaml:"testReport" mapstructure:"testReport"`
 }
 
+// Import configures keploy import, which turns the traffic captured outside of keploy into a test-set.
+type Import struct {
+	// AppPorts are the ports on which the app receives the calls, the other connections are the calls to its dependencies
+	AppPorts []uint32 `json:"appPorts" yaml:"appPorts" mapstructure:"appPorts"`
+}
+
+// Export configures keploy export, which writes the test-sets as HAR files or Postman collections.
+type Export struct {
+	Format   string   `json:"format" yaml:"format" mapstructure:"format"` // har or postman
+	Output   string   `json:"output" yaml:"output" mapstructure:"output"` // directory of the exported files
+	TestSets []string `json:"testSets" yaml:"testSets" mapstructure:"testSets"`
+}
+
 type BypassRule struct {
 	Path string `json:"path" yaml:"path" mapstructure:"path"`
 	Host string `json:"host" yaml:"host" mapstructure:"host"`
 	Port uint   `json:"port" yaml:"port" mapstructure:"port"`
 }
 
+// ProtocolRule routes the outgoing calls made on a port to the parser of the protocol, e.g. mysql on 3307.
+// The opt-in parsers, e.g. postgres_v2, are only used on the ports routed to them.
+type ProtocolRule struct {
+	Port     uint32 `json:"port" yaml:"port" mapstructure:"port"`
+	Protocol string `json:"protocol" yaml:"protocol" mapstructure:"protocol"`
+}
+
 type Filter struct {
 	BypassRule `mapstructure:",squash"`
 	URLMethods []string          `json:"urlMethods" yaml:"urlMethods" mapstructure:"urlMethods"`
@@ -119,6 +170,8 @@ type Test struct {
 	Language            Language            `json:"language" yaml:"language" mapstructure:"language"`
 	RemoveUnusedMocks   bool                `json:"removeUnusedMocks" yaml:"removeUnusedMocks" mapstructure:"removeUnusedMocks"`
 	FallBackOnMiss      bool                `json:"fallBackOnMiss" yaml:"fallBackOnMiss" mapstructure:"fallBackOnMiss"`
+	StrictMocks         bool                `json:"strictMocks" yaml:"strictMocks" mapstructure:"strictMocks"`    // fail the test cases making a call that no mock matches
+	RecordOnMiss        bool                `json:"recordOnMiss" yaml:"recordOnMiss" mapstructure:"recordOnMiss"` // append the calls that no mock matches to the mocks of the test-set
 	JacocoAgentPath     string              `json:"jacocoAgentPath" yaml:"jacocoAgentPath" mapstructure:"jacocoAgentPath"`
 	BasePath            string              `json:"basePath" yaml:"basePath" mapstructure:"basePath"`
 	Mocking             bool                `json:"mocking" yaml:"mocking" mapstructure:"mocking"`
@@ -127,6 +180,12 @@ type Test struct {
 	DisableMockUpload   bool                `json:"disableMockUpload" yaml:"disableMockUpload" mapstructure:"disableMockUpload"`
 	UseLocalMock        bool                `json:"useLocalMock" yaml:"useLocalMock" mapstructure:"useLocalMock"`
 	UpdateTemplate      bool                `json:"updateTemplate" yaml:"updateTemplate" mapstructure:"updateTemplate"`
+	RedisNoise          map[string][]string `json:"redisNoise" yaml:"redisNoise" mapstructure:"redisNoise"`       // args of the redis commands to be ignored while matching mocks, by index or by option name (e.g. SET: ["EX"])
+	Parallel            int                 `json:"parallel" yaml:"parallel" mapstructure:"parallel"`             // number of test-sets to run concurrently
+	ReportFormat        []string            `json:"reportFormat" yaml:"reportFormat" mapstructure:"reportFormat"` // formats of the test run report in addition to the yaml reports (junit, json)
+	Latency             Latency             `json:"latency" yaml:"latency" mapstructure:"latency"`                // thresholds of the latency regressions, not checked when none is set
+	Denoise             bool                `json:"denoise" yaml:"-" mapstructure:"denoise"`                      // replay the test-sets several times and propose the fields which vary as noise
+	DenoiseRuns         int                 `json:"denoiseRuns" yaml:"-" mapstructure:"denoiseRuns"`              // number of the replays compared by the denoise
 }
 
 type Language string
@@ -157,6 +216,38 @@ type Globalnoise struct {
 	Testsets TestsetNoise `json:"test-sets" yaml:"test-sets" mapstructure:"test-sets"`
 }
 
+type Latency struct {
+	Global   LatencyThreshold            `json:"global" yaml:"global" mapstructure:"global"`
+	Testsets map[string]LatencyThreshold `json:"test-sets" yaml:"test-sets" mapstructure:"test-sets"`
+}
+
+// LatencyThreshold is the increase of the response time over the recorded latency allowed in the test run. When
+// both are set, a test case regresses only when its increase exceeds both, so that the fast endpoints aren't
+// flagged for a few milliseconds of jitter.
+type LatencyThreshold struct {
+	Absolute   uint64  `json:"absolute" yaml:"absolute" mapstructure:"absolute"`       // in milliseconds
+	Percentage float64 `json:"percentage" yaml:"percentage" mapstructure:"percentage"` // of the recorded latency
+}
+
+// IsSet reports whether the threshold checks the latency at all.
+func (l LatencyThreshold) IsSet() bool {
+	return l.Absolute > 0 || l.Percentage > 0
+}
+
+// For returns the latency threshold of the test-set, its fields override the global ones when set.
+func (l Latency) For(testSetID string) LatencyThreshold {
+	threshold := l.Global
+	if ts, ok := l.Testsets[testSetID]; ok {
+		if ts.Absolute > 0 {
+			threshold.Absolute = ts.Absolute
+		}
+		if ts.Percentage > 0 {
+			threshold.Percentage = ts.Percentage
+		}
+	}
+	return threshold
+}
+
 type SelectedTests struct {
 	TestSet string   `json:"testSet" yaml:"testSet" mapstructure:"testSet"`
 	Tests   []string `json:"tests" yaml:"tests" mapstructure:"tests"`
@@ -178,6 +269,15 @@ func SetByPassPorts(conf *Config, ports []uint) {
 	}
 }
 
+// GetProtocolRules returns the port to protocol mapping of the configured protocol rules
+func GetProtocolRules(conf *Config) map[uint32]string {
+	protocols := make(map[uint32]string, len(conf.ProtocolRules))
+	for _, rule := range conf.ProtocolRules {
+		protocols[rule.Port] = strings.ToLower(rule.Protocol)
+	}
+	return protocols
+}
+
 func GetByPassPorts(conf *Config) []uint {
 	var ports []uint
 	for _, rule := range conf.BypassRules {
@@ -224,3 +324,24 @@ func SetSelectedTestsNormalize(conf *Config, value string) error {
 	conf.Normalize.SelectedTests = tests
 	return nil
 }
+
+// ParseMockListeners parses the listeners of the mock server given in the port[:protocol[:upstream]] form.
+func ParseMockListeners(values []string) ([]MockListener, error) {
+	listeners := make([]MockListener, 0, len(values))
+	for _, value := range values {
+		parts := strings.SplitN(strings.TrimSpace(value), ":", 3)
+		port, err := strconv.ParseUint(parts[0], 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("invalid port of the listener: %s", value)
+		}
+		l := MockListener{Port: uint32(port)}
+		if len(parts) > 1 {
+			l.Protocol = strings.ToLower(parts[1])
+		}
+		if len(parts) > 2 {
+			l.Upstream = parts[2]
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
@@ -18,6 +18,7 @@ proxyPort: 16789
 dnsPort: 26789
 debug: false
 disableANSI: false
+storageBackend: yaml
 disableTele: false
 generateGithubActions: false
 containerName: ""
@@ -42,7 +43,15 @@ test:
   mocking: true
   disableLineCoverage: false
   fallbackOnMiss: false
+  strictMocks: false
+  recordOnMiss: false
   disableMockUpload: true
+  redisNoise: {}
+  parallel: 1
+  latency:
+    global: {}
+    test-sets: {}
+  reportFormat: []
 record:
   recordTimer: 0s
   filters: []
@@ -50,8 +59,16 @@ contract:
   driven: "consumer"
   servicesMapping: {}
   self: "s1"
+agent:
+  address: "localhost:36789"
+  grpcAddress: "localhost:36790"
+mockServer:
+  testSet: ""
+  listeners: []
+  httpProxy: ""
 configPath: ""
 bypassRules: []
+protocolRules: []
 `
 
 func GetDefaultConfig() string {
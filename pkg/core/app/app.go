@@ -53,6 +53,7 @@ type App struct {
 	keployContainer  string
 	keployIPv4       string
 	inodeChan        chan uint64
+	ipChan           chan string
 	EnableTesting    bool
 	Mode             models.Mode
 }
@@ -303,6 +304,10 @@ func (a *App) extractMeta(ctx context.Context, e events.Message) (bool, error) {
 		return false, fmt.Errorf("container network not found: %s", fmt.Sprintf("%+v", info.NetworkSettings.Networks))
 	}
 	a.SetContainerIPv4Addr(n.IPAddress)
+	select {
+	case a.ipChan <- n.IPAddress:
+	default:
+	}
 	return inode != 0 && n.IPAddress != "", nil
 }
 
@@ -415,8 +420,9 @@ func (a *App) runDocker(ctx context.Context) models.AppError {
 	}
 }
 
-func (a *App) Run(ctx context.Context, inodeChan chan uint64) models.AppError {
+func (a *App) Run(ctx context.Context, inodeChan chan uint64, ipChan chan string) models.AppError {
 	a.inodeChan = inodeChan
+	a.ipChan = ipChan
 
 	if utils.IsDockerCmd(a.kind) {
 		return a.runDocker(ctx)
@@ -27,6 +27,7 @@ type Core struct {
 	logger       *zap.Logger
 	id           utils.AutoInc
 	apps         sync.Map
+	proxyMu      sync.Mutex
 	proxyStarted bool
 }
 
@@ -112,6 +113,12 @@ func (c *Core) Hook(ctx context.Context, id uint64, opts models.HookOptions) err
 	proxyCtx, proxyCtxCancel := context.WithCancel(proxyCtx)
 	proxyCtx = context.WithValue(proxyCtx, models.ErrGroupKey, proxyErrGrp)
 
+	// the proxy is shared by the apps hooked at the same time, it is started along with the first one
+	c.proxyMu.Lock()
+	startProxy := !c.proxyStarted
+	c.proxyStarted = true
+	c.proxyMu.Unlock()
+
 	g.Go(func() error {
 		<-ctx.Done()
 
@@ -120,6 +127,11 @@ func (c *Core) Hook(ctx context.Context, id uint64, opts models.HookOptions) err
 		if err != nil {
 			utils.LogError(c.logger, err, "failed to stop the proxy")
 		}
+		if startProxy {
+			c.proxyMu.Lock()
+			c.proxyStarted = false
+			c.proxyMu.Unlock()
+		}
 
 		hookCtxCancel()
 		err := hookErrGrp.Wait()
@@ -129,7 +141,14 @@ func (c *Core) Hook(ctx context.Context, id uint64, opts models.HookOptions) err
 
 		//deleting in order to free the memory in case of rerecord. otherwise different app id will be created for the same app.
 		c.apps.Delete(id)
-		c.id = utils.AutoInc{}
+		remaining := false
+		c.apps.Range(func(_, _ interface{}) bool {
+			remaining = true
+			return false
+		})
+		if !remaining {
+			c.id = utils.AutoInc{}
+		}
 
 		return nil
 	})
@@ -148,32 +167,28 @@ func (c *Core) Hook(ctx context.Context, id uint64, opts models.HookOptions) err
 		return hookErr
 	}
 
-	if c.proxyStarted {
-		c.logger.Debug("Proxy already started")
-		// return nil
-	}
-
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	// TODO: Hooks can be loaded multiple times but proxy should be started only once
 	// if there is another containerized app, then we need to pass new (ip:port) of proxy to the eBPF
 	// as the network namespace is different for each container and so is the keploy/proxy IP to communicate with the app.
 	// start proxy
-	err = c.Proxy.StartProxy(proxyCtx, ProxyOptions{
-		DNSIPv4Addr: a.KeployIPv4Addr(),
-		//DnsIPv6Addr: ""
-	})
-	if err != nil {
-		utils.LogError(c.logger, err, "failed to start proxy")
-		return hookErr
+	if startProxy {
+		err = c.Proxy.StartProxy(proxyCtx, ProxyOptions{
+			DNSIPv4Addr: a.KeployIPv4Addr(),
+			//DnsIPv6Addr: ""
+		})
+		if err != nil {
+			utils.LogError(c.logger, err, "failed to start proxy")
+			return hookErr
+		}
+	} else {
+		c.logger.Debug("Proxy already started")
 	}
 
-	c.proxyStarted = true
-
 	// For keploy test bench
 	if opts.EnableTesting {
 
@@ -204,6 +219,7 @@ func (c *Core) Run(ctx context.Context, id uint64, _ models.RunOptions) models.A
 	inodeErrCh := make(chan error, 1)
 	appErrCh := make(chan models.AppError, 1)
 	inodeChan := make(chan uint64, 1) //send inode to the hook
+	ipChan := make(chan string, 1)    //send the ip of the app container to the proxy, to attribute its dns queries
 
 	defer func() {
 		err := runAppErrGrp.Wait()
@@ -226,17 +242,23 @@ func (c *Core) Run(ctx context.Context, id uint64, _ models.RunOptions) models.A
 				utils.LogError(c.logger, err, "")
 
 				inodeErrCh <- errors.New("failed to send inode to the kernel")
+				return nil
 			}
 		case <-ctx.Done():
 			return nil
 		}
+		select {
+		case ip := <-ipChan:
+			c.Proxy.SetAppIP(id, ip)
+		case <-ctx.Done():
+		}
 		return nil
 	})
 
 	runAppErrGrp.Go(func() error {
 		defer utils.Recover(c.logger)
 		defer close(appErrCh)
-		appErr := a.Run(runAppCtx, inodeChan)
+		appErr := a.Run(runAppCtx, inodeChan, ipChan)
 		if appErr.Err != nil {
 			utils.LogError(c.logger, appErr.Err, "error while running the app")
 			appErrCh <- appErr
@@ -44,6 +44,32 @@ func (c *Core) GetConsumedMocks(ctx context.Context, id uint64) ([]string, error
 	return nil, errUnsupported
 }
 
+func (c *Core) GetUnmatchedCalls(ctx context.Context, id uint64) ([]models.UnmatchedCall, error) {
+	return nil, errUnsupported
+}
+
+func (c *Core) GetRecordedMocks(ctx context.Context, id uint64) ([]*models.Mock, error) {
+	return nil, errUnsupported
+}
+
+func (c *Core) GetProtocols(ctx context.Context, id uint64) (map[uint32]string, error) {
+	return nil, errUnsupported
+}
+
+func (c *Core) ServeMocks(ctx context.Context, opts models.MockServerOptions, filtered []*models.Mock, unFiltered []*models.Mock) error {
+	return errUnsupported
+}
+
+func (c *Core) CaptureIncoming(ctx context.Context, captured *models.CapturedConn, opts models.IncomingOptions) <-chan *models.TestCase {
+	t := make(chan *models.TestCase)
+	close(t)
+	return t
+}
+
+func (c *Core) CaptureOutgoing(ctx context.Context, captured *models.CapturedConn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	return errUnsupported
+}
+
 func (c *Core) Run(ctx context.Context, id uint64, _ models.RunOptions) models.AppError {
 	return models.AppError{
 		Err: errUnsupported,
@@ -0,0 +1,94 @@
+//go:build linux
+
+package conn
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// CaptureConn assembles the http (and grpc) calls received by the app on a connection read from a packet
+// capture into test cases. The data is fed to a tracker the way the data events of eBPF are, so the calls
+// are assembled as if they were recorded, with the timestamps of the capture.
+func CaptureConn(ctx context.Context, logger *zap.Logger, captured *models.CapturedConn, t chan *models.TestCase, opts models.IncomingOptions) {
+	tracker := NewTracker(ID{}, logger)
+	event := &SocketDataEvent{}
+
+	// sizes of the request and the response in progress, and of the last complete ones
+	var reqSize, respSize, lastReqSize, lastRespSize int64
+	// the tracker takes the current time as the time of the response, the capture has the actual one
+	var respEnds []time.Time
+	var lastResp time.Time
+	lastWasResp := false
+
+	flush := func() {
+		for atomic.LoadInt32(&tracker.recTestCounter) > 0 && len(respEnds) > 0 {
+			ok, requestBuf, responseBuf, reqTimestamp, _ := tracker.IsComplete()
+			resTimestamp := respEnds[0]
+			respEnds = respEnds[1:]
+			if ok {
+				captureExchange(ctx, logger, tracker, t, requestBuf, responseBuf, reqTimestamp, resTimestamp, opts)
+			}
+		}
+	}
+
+	for _, chunk := range captured.Chunks {
+		if ctx.Err() != nil {
+			return
+		}
+		if chunk.FromClient {
+			if lastWasResp {
+				lastRespSize, respSize = respSize, 0
+				respEnds = append(respEnds, lastResp)
+			}
+			event.Direction = IngressTraffic
+		} else {
+			if !lastWasResp {
+				lastReqSize, reqSize = reqSize, 0
+			}
+			event.Direction = EgressTraffic
+			lastResp = chunk.Timestamp
+		}
+		lastWasResp = !chunk.FromClient
+
+		for data := chunk.Data; len(data) > 0; {
+			n := copy(event.Msg[:], data)
+			data = data[n:]
+			event.MsgSize = uint32(n)
+			event.EntryTimestampNano = uint64(chunk.Timestamp.UnixNano())
+			event.TimestampNano = event.EntryTimestampNano
+			if chunk.FromClient {
+				reqSize += int64(n)
+			} else {
+				respSize += int64(n)
+			}
+			// the sizes of the kernel are the ones of the complete request and response
+			event.ValidateReadBytes = lastReqSize
+			event.ValidateWrittenBytes = lastRespSize
+			tracker.AddDataEvent(*event)
+		}
+		flush()
+	}
+
+	// the capture is over, and so is the websocket the connection was upgraded to
+	if ws, _ := tracker.webSocket(); ws != nil {
+		captureWebSocket(ctx, logger, t, ws)
+		return
+	}
+
+	// the last response is complete once the connection has been inactive for a while
+	if lastWasResp {
+		respEnds = append(respEnds, lastResp)
+		tracker.mutex.Lock()
+		tracker.lastActivityTimestamp = 0
+		tracker.mutex.Unlock()
+		ok, requestBuf, responseBuf, reqTimestamp, _ := tracker.IsComplete()
+		if ok {
+			captureExchange(ctx, logger, tracker, t, requestBuf, responseBuf, reqTimestamp, respEnds[len(respEnds)-1], opts)
+		}
+	}
+}
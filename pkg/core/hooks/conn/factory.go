@@ -48,26 +48,17 @@ func (factory *Factory) ProcessActiveTrackers(ctx context.Context, t chan *model
 		case <-ctx.Done():
 			return
 		default:
+			if ws, closed := tracker.webSocket(); ws != nil {
+				if closed {
+					captureWebSocket(ctx, factory.logger, t, ws)
+					trackersToDelete = append(trackersToDelete, connID)
+				}
+				// the frames of a live websocket must not be parsed as http, however long it is idle
+				continue
+			}
 			ok, requestBuf, responseBuf, reqTimestampTest, resTimestampTest := tracker.IsComplete()
 			if ok {
-
-				if len(requestBuf) == 0 || len(responseBuf) == 0 {
-					factory.logger.Warn("failed processing a request due to invalid request or response", zap.Any("Request Size", len(requestBuf)), zap.Any("Response Size", len(responseBuf)))
-					continue
-				}
-
-				parsedHTTPReq, err := pkg.ParseHTTPRequest(requestBuf)
-				if err != nil {
-					utils.LogError(factory.logger, err, "failed to parse the http request from byte array", zap.Any("requestBuf", requestBuf))
-					continue
-				}
-				parsedHTTPRes, err := pkg.ParseHTTPResponse(responseBuf, parsedHTTPReq)
-				if err != nil {
-					utils.LogError(factory.logger, err, "failed to parse the http response from byte array", zap.Any("responseBuf", responseBuf))
-					continue
-				}
-				capture(ctx, factory.logger, t, parsedHTTPReq, parsedHTTPRes, reqTimestampTest, resTimestampTest, opts)
-
+				captureExchange(ctx, factory.logger, tracker, t, requestBuf, responseBuf, reqTimestampTest, resTimestampTest, opts)
 			} else if tracker.IsInactive(factory.inactivityThreshold) {
 				trackersToDelete = append(trackersToDelete, connID)
 			}
@@ -93,6 +84,48 @@ func (factory *Factory) GetOrCreate(connectionID ID) *Tracker {
 	return tracker
 }
 
+// captureExchange parses the request and the response completed on the tracker into a test case.
+func captureExchange(ctx context.Context, logger *zap.Logger, tracker *Tracker, t chan *models.TestCase, requestBuf, responseBuf []byte, reqTimestampTest, resTimestampTest time.Time, opts models.IncomingOptions) {
+	if len(requestBuf) == 0 || len(responseBuf) == 0 {
+		logger.Warn("failed processing a request due to invalid request or response", zap.Any("Request Size", len(requestBuf)), zap.Any("Response Size", len(responseBuf)))
+		return
+	}
+
+	if tracker.grpc != nil || isHTTP2(requestBuf) {
+		if tracker.grpc == nil {
+			tracker.grpc = newGrpcConn()
+		}
+		err := tracker.grpc.processRequest(requestBuf)
+		if err != nil {
+			utils.LogError(logger, err, "failed to parse the grpc request from byte array")
+			return
+		}
+		streams, err := tracker.grpc.processResponse(responseBuf)
+		if err != nil {
+			utils.LogError(logger, err, "failed to parse the grpc response from byte array")
+			return
+		}
+		captureGrpc(ctx, logger, t, streams, reqTimestampTest, resTimestampTest, opts)
+		return
+	}
+
+	parsedHTTPReq, err := pkg.ParseHTTPRequest(requestBuf)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the http request from byte array", zap.Any("requestBuf", requestBuf))
+		return
+	}
+	parsedHTTPRes, err := pkg.ParseHTTPResponse(responseBuf, parsedHTTPReq)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the http response from byte array", zap.Any("responseBuf", responseBuf))
+		return
+	}
+	if isWebSocketHandshake(parsedHTTPReq, parsedHTTPRes) {
+		tracker.upgradeToWebSocket(newWebSocketConn(logger, parsedHTTPReq, parsedHTTPRes, responseBuf, reqTimestampTest, resTimestampTest, opts))
+		return
+	}
+	capture(ctx, logger, t, parsedHTTPReq, parsedHTTPRes, reqTimestampTest, resTimestampTest, opts)
+}
+
 func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req *http.Request, resp *http.Response, reqTimeTest time.Time, resTimeTest time.Time, opts models.IncomingOptions) {
 	reqBody, err := io.ReadAll(req.Body)
 	if err != nil {
@@ -0,0 +1,144 @@
+//go:build linux
+
+package conn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/grpc"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// isHTTP2 checks whether the request data starts with the HTTP/2 client connection preface.
+func isHTTP2(requestBuf []byte) bool {
+	return bytes.HasPrefix(requestBuf, []byte(http2.ClientPreface))
+}
+
+// grpcConn holds the HTTP/2 state of an ingress gRPC connection. HPACK is stateful,
+// so the decoders have to live as long as the connection itself.
+type grpcConn struct {
+	reqDecoder  *hpack.Decoder
+	respDecoder *hpack.Decoder
+	sic         *grpc.StreamInfoCollection
+}
+
+func newGrpcConn() *grpcConn {
+	return &grpcConn{
+		reqDecoder:  grpc.NewDecoder(),
+		respDecoder: grpc.NewDecoder(),
+		sic:         grpc.NewStreamInfoCollection(),
+	}
+}
+
+// processRequest decodes the frames sent by the client to the application.
+func (gc *grpcConn) processRequest(buf []byte) error {
+	buf = bytes.TrimPrefix(buf, []byte(http2.ClientPreface))
+	framer := http2.NewFramer(io.Discard, bytes.NewReader(buf))
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read the http2 request frame: %v", err)
+		}
+		switch frame := frame.(type) {
+		case *http2.HeadersFrame:
+			pseudoHeaders, ordinaryHeaders, err := grpc.ExtractHeaders(frame, gc.reqDecoder)
+			if err != nil {
+				return err
+			}
+			gc.sic.AddHeadersForRequest(frame.StreamID, pseudoHeaders, true)
+			gc.sic.AddHeadersForRequest(frame.StreamID, ordinaryHeaders, false)
+		case *http2.DataFrame:
+			gc.sic.InitialiseStream(frame.StreamID)
+			gc.sic.AddPayloadForRequest(frame.StreamID, frame.Data())
+		}
+	}
+}
+
+// processResponse decodes the frames sent back by the application and returns
+// the streams which have been completed by the trailers.
+func (gc *grpcConn) processResponse(buf []byte) ([]models.GrpcStream, error) {
+	var streams []models.GrpcStream
+	framer := http2.NewFramer(io.Discard, bytes.NewReader(buf))
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			if err == io.EOF {
+				return streams, nil
+			}
+			return streams, fmt.Errorf("failed to read the http2 response frame: %v", err)
+		}
+		switch frame := frame.(type) {
+		case *http2.HeadersFrame:
+			pseudoHeaders, ordinaryHeaders, err := grpc.ExtractHeaders(frame, gc.respDecoder)
+			if err != nil {
+				return streams, err
+			}
+			// If this is the last fragment of a stream from the server, it has to be a trailer.
+			isTrailer := frame.StreamEnded()
+			gc.sic.AddHeadersForResponse(frame.StreamID, pseudoHeaders, true, isTrailer)
+			gc.sic.AddHeadersForResponse(frame.StreamID, ordinaryHeaders, false, isTrailer)
+			if isTrailer {
+				streams = append(streams, models.GrpcStream{
+					StreamID: frame.StreamID,
+					GrpcReq:  gc.sic.FetchRequestForStream(frame.StreamID),
+					GrpcResp: gc.sic.FetchResponseForStream(frame.StreamID),
+				})
+				gc.sic.ResetStream(frame.StreamID)
+			}
+		case *http2.DataFrame:
+			gc.sic.AddPayloadForResponse(frame.StreamID, frame.Data())
+		}
+	}
+}
+
+// captureGrpc converts the completed grpc streams of an ingress connection into test cases.
+func captureGrpc(_ context.Context, logger *zap.Logger, t chan *models.TestCase, streams []models.GrpcStream, reqTimeTest time.Time, resTimeTest time.Time, opts models.IncomingOptions) {
+	for _, stream := range streams {
+		if isFiltered(logger, grpcToHTTPRequest(stream.GrpcReq), opts) {
+			logger.Debug("The grpc request is a filtered request")
+			continue
+		}
+
+		stream.GrpcReq.Timestamp = reqTimeTest
+		stream.GrpcResp.Timestamp = resTimeTest
+		t <- &models.TestCase{
+			Version:  models.GetVersion(),
+			Name:     stream.GrpcReq.Headers.OrdinaryHeaders["keploy-test-name"],
+			Kind:     models.GRPC_EXPORT,
+			Created:  time.Now().Unix(),
+			GrpcReq:  stream.GrpcReq,
+			GrpcResp: stream.GrpcResp,
+			Noise:    map[string][]string{},
+		}
+	}
+}
+
+// grpcToHTTPRequest builds the equivalent http request of a grpc call, so that the
+// record filters can be applied on it.
+func grpcToHTTPRequest(grpcReq models.GrpcReq) *http.Request {
+	authority := grpcReq.Headers.PseudoHeaders[pkg.GrpcAuthority]
+	path := grpcReq.Headers.PseudoHeaders[pkg.GrpcPath]
+	req := &http.Request{
+		Method: http.MethodPost,
+		Host:   authority,
+		URL:    &url.URL{Scheme: "http", Host: authority, Path: path},
+		Header: http.Header{},
+	}
+	for k, v := range grpcReq.Headers.OrdinaryHeaders {
+		req.Header.Set(k, v)
+	}
+	return req
+}
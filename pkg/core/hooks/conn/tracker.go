@@ -66,6 +66,14 @@ type Tracker struct {
 
 	reqTimestamps []time.Time
 	isNewRequest  bool
+
+	// grpc holds the HTTP/2 state, if the conn turns out to be a gRPC (HTTP/2) conn.
+	// It is only accessed by the factory while processing the trackers.
+	grpc *grpcConn
+
+	// ws holds the messages of the conn once it is upgraded to a websocket, its data isn't queued as
+	// requests and responses anymore.
+	ws *webSocketConn
 }
 
 func NewTracker(connID ID, logger *zap.Logger) *Tracker {
@@ -295,6 +303,15 @@ func (conn *Tracker) AddDataEvent(event SocketDataEvent) {
 
 	conn.logger.Debug(fmt.Sprintf("Got a data event from eBPF, Direction:%v || current Event Size:%v || ConnectionID:%v\n", event.Direction, event.MsgSize, event.ConnID))
 
+	if conn.ws != nil {
+		msgLength := event.MsgSize
+		if event.MsgSize > EventBodyMaxSize {
+			msgLength = EventBodyMaxSize
+		}
+		conn.ws.add(event.Direction == IngressTraffic, event.Msg[:msgLength], ConvertUnixNanoToTime(event.EntryTimestampNano))
+		return
+	}
+
 	switch event.Direction {
 	case EgressTraffic:
 		// Capturing the timestamp of response as the response just started to come.
@@ -370,6 +387,46 @@ func (conn *Tracker) AddDataEvent(event SocketDataEvent) {
 	}
 }
 
+// upgradeToWebSocket moves the conn to the websocket once its handshake is captured. The data which came after
+// the handshake has been queued as requests and responses in turns, it is added to the websocket in order.
+func (conn *Tracker) upgradeToWebSocket(ws *webSocketConn) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+
+	timestamp := ws.lastTime
+	for i := range conn.userReqs {
+		ws.add(true, conn.userReqs[i], timestamp)
+		if i < len(conn.userResps) {
+			ws.add(false, conn.userResps[i], timestamp)
+		}
+	}
+	if conn.lastChunkWasReq {
+		ws.add(true, conn.req, timestamp)
+	} else if conn.lastChunkWasResp {
+		ws.add(false, conn.resp, timestamp)
+	}
+
+	conn.ws = ws
+	conn.kernelReqSizes, conn.kernelRespSizes = []uint64{}, []uint64{}
+	conn.userReqSizes, conn.userRespSizes = []uint64{}, []uint64{}
+	conn.userReqs, conn.userResps = [][]byte{}, [][]byte{}
+	conn.reqTimestamps = nil
+	atomic.StoreInt32(&conn.recTestCounter, 0)
+	conn.reset()
+}
+
+// webSocket returns the websocket the conn was upgraded to, if any, and whether it is over: closed by both of
+// its sides, or by the socket. The conn stays a websocket however long it is idle, until it is closed.
+func (conn *Tracker) webSocket() (*webSocketConn, bool) {
+	conn.mutex.RLock()
+	defer conn.mutex.RUnlock()
+	if conn.ws == nil {
+		return nil, false
+	}
+	elapsed := time.Duration(uint64(time.Now().UnixNano()) - conn.lastActivityTimestamp)
+	return conn.ws, conn.ws.closed() || (conn.closeTimestamp != 0 && elapsed >= 2*time.Second)
+}
+
 func (conn *Tracker) AddOpenEvent(event SocketOpenEvent) {
 	conn.mutex.Lock()
 	defer conn.mutex.Unlock()
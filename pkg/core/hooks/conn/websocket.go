@@ -0,0 +1,141 @@
+//go:build linux
+
+package conn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// webSocketConn holds the state of an ingress connection upgraded to a websocket. Its data isn't paired
+// into requests and responses anymore, the frames of both sides are logged in the order they come.
+type webSocketConn struct {
+	req     *http.Request
+	resp    *http.Response
+	reqTime time.Time
+	// lastTime is the time of the last message, the end of the testcase
+	lastTime time.Time
+	// filtered connections are tracked until they are over, but not captured
+	filtered bool
+
+	clientFrames, serverFrames     []byte // the partial frames of each side
+	clientMessages, serverMessages *pkg.WebSocketMessages
+	clientClosed, serverClosed     bool
+	messages                       []models.WebSocketMessage
+	err                            error
+}
+
+// isWebSocketHandshake checks whether the response accepts the upgrade of the request to a websocket.
+func isWebSocketHandshake(req *http.Request, resp *http.Response) bool {
+	return resp.StatusCode == http.StatusSwitchingProtocols && pkg.IsWebSocketUpgrade(req.Header) && pkg.IsWebSocketUpgrade(resp.Header)
+}
+
+func newWebSocketConn(logger *zap.Logger, req *http.Request, resp *http.Response, responseBuf []byte, reqTime, respTime time.Time, opts models.IncomingOptions) *webSocketConn {
+	ws := &webSocketConn{
+		req:            req,
+		resp:           resp,
+		reqTime:        reqTime,
+		lastTime:       respTime,
+		filtered:       isFiltered(logger, req, opts),
+		clientMessages: pkg.NewWebSocketMessages(models.WebSocketFromClient),
+		serverMessages: pkg.NewWebSocketMessages(models.WebSocketFromServer),
+	}
+	// the frames sent by the server right after the handshake come along with it
+	if end := bytes.Index(responseBuf, []byte("\r\n\r\n")); end >= 0 {
+		ws.add(false, responseBuf[end+4:], respTime)
+	}
+	return ws
+}
+
+// add appends the data sent by a side of the connection, and logs the messages it completes.
+func (ws *webSocketConn) add(fromClient bool, data []byte, timestamp time.Time) {
+	if ws.err != nil || len(data) == 0 {
+		return
+	}
+	frames, messages := &ws.serverFrames, ws.serverMessages
+	if fromClient {
+		frames, messages = &ws.clientFrames, ws.clientMessages
+	}
+	*frames = append(*frames, data...)
+	for {
+		r := bytes.NewReader(*frames)
+		frame, err := pkg.ReadWebSocketFrame(r)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// the rest of the frame comes with the next data
+			return
+		}
+		if err != nil {
+			ws.err = err
+			return
+		}
+		*frames = (*frames)[len(*frames)-r.Len():]
+		msg, err := messages.Add(frame)
+		if err != nil {
+			ws.err = err
+			return
+		}
+		if msg == nil {
+			continue
+		}
+		ws.messages = append(ws.messages, *msg)
+		if timestamp.After(ws.lastTime) {
+			ws.lastTime = timestamp
+		}
+		if msg.Type == models.WebSocketClose {
+			if fromClient {
+				ws.clientClosed = true
+			} else {
+				ws.serverClosed = true
+			}
+		}
+	}
+}
+
+// closed checks whether both sides of the connection sent their close.
+func (ws *webSocketConn) closed() bool {
+	return ws.clientClosed && ws.serverClosed
+}
+
+// captureWebSocket converts the handshake and the messages of a websocket connection into a test case.
+func captureWebSocket(_ context.Context, logger *zap.Logger, t chan *models.TestCase, ws *webSocketConn) {
+	if ws.filtered {
+		logger.Debug("The websocket request is a filtered request")
+		return
+	}
+	if ws.err != nil {
+		logger.Warn("failed to capture the messages of the websocket connection, it isn't recorded", zap.Any("url", ws.req.URL.String()), zap.Error(ws.err))
+		return
+	}
+
+	t <- &models.TestCase{
+		Version: models.GetVersion(),
+		Name:    pkg.ToYamlHTTPHeader(ws.req.Header)["Keploy-Test-Name"],
+		Kind:    models.WebSocket,
+		Created: time.Now().Unix(),
+		HTTPReq: models.HTTPReq{
+			Method:     models.Method(ws.req.Method),
+			ProtoMajor: ws.req.ProtoMajor,
+			ProtoMinor: ws.req.ProtoMinor,
+			URL:        fmt.Sprintf("http://%s%s", ws.req.Host, ws.req.URL.RequestURI()),
+			Header:     pkg.ToYamlHTTPHeader(ws.req.Header),
+			URLParams:  pkg.URLParams(ws.req),
+			Timestamp:  ws.reqTime,
+		},
+		HTTPResp: models.HTTPResp{
+			StatusCode:    ws.resp.StatusCode,
+			Header:        pkg.ToYamlHTTPHeader(ws.resp.Header),
+			Timestamp:     ws.lastTime,
+			StatusMessage: http.StatusText(ws.resp.StatusCode),
+		},
+		WebSocket: ws.messages,
+		Noise:     map[string][]string{},
+	}
+}
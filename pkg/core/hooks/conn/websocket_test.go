@@ -0,0 +1,86 @@
+//go:build linux
+
+package conn
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func newTestWebSocketConn() *webSocketConn {
+	return &webSocketConn{
+		clientMessages: pkg.NewWebSocketMessages(models.WebSocketFromClient),
+		serverMessages: pkg.NewWebSocketMessages(models.WebSocketFromServer),
+	}
+}
+
+func encodeMessage(t *testing.T, msg models.WebSocketMessage, mask bool) []byte {
+	frame, err := pkg.WebSocketMessageFrame(msg)
+	if err != nil {
+		t.Fatalf("failed to encode the message: %v", err)
+	}
+	return pkg.EncodeWebSocketFrame(frame, mask)
+}
+
+// TestWebSocketConnAdd splits the frames of both sides at every byte, the messages are logged once their
+// frames are complete whatever the chunks the data is captured in.
+func TestWebSocketConnAdd(t *testing.T) {
+	want := []models.WebSocketMessage{
+		{From: models.WebSocketFromClient, Type: models.WebSocketText, Data: "hello"},
+		{From: models.WebSocketFromServer, Type: models.WebSocketText, Data: "world"},
+		{From: models.WebSocketFromClient, Type: models.WebSocketClose, CloseCode: 1000},
+		{From: models.WebSocketFromServer, Type: models.WebSocketClose, CloseCode: 1000},
+	}
+	var chunks [][]byte
+	for _, msg := range want {
+		chunks = append(chunks, encodeMessage(t, msg, msg.From == models.WebSocketFromClient))
+	}
+
+	for split := 0; split < len(chunks[0]); split++ {
+		ws := newTestWebSocketConn()
+		now := time.Now()
+		for i, chunk := range chunks {
+			fromClient := want[i].From == models.WebSocketFromClient
+			at := min(split, len(chunk))
+			ws.add(fromClient, chunk[:at], now)
+			ws.add(fromClient, chunk[at:], now)
+		}
+		if ws.err != nil {
+			t.Fatalf("failed to log the messages split at %d: %v", split, ws.err)
+		}
+		if !reflect.DeepEqual(ws.messages, want) || !ws.closed() {
+			t.Fatalf("got the messages %+v split at %d (closed: %t)", ws.messages, split, ws.closed())
+		}
+	}
+}
+
+func TestWebSocketConnAddMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "unknown opcode", data: []byte{0x83, 0}},
+		{name: "orphan continuation", data: []byte{0x80, 0}},
+		{name: "frame larger than the maximum", data: []byte{0x82, 127, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "compressed message", data: []byte{0xc1, 1, 'a'}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := newTestWebSocketConn()
+			ws.add(false, tt.data, time.Now())
+			if ws.err == nil {
+				t.Fatalf("the malformed frame is logged as %+v", ws.messages)
+			}
+			// the data following the malformed frame is ignored
+			ws.add(false, encodeMessage(t, models.WebSocketMessage{Type: models.WebSocketText, Data: "x"}, false), time.Now())
+			if len(ws.messages) != 0 {
+				t.Fatalf("got the messages %+v after the malformed frame", ws.messages)
+			}
+		})
+	}
+}
@@ -48,6 +48,9 @@ type Hooks struct {
 	dnsPort   uint32
 
 	m sync.Mutex
+	// loadMu guards the loading of the eBPF programs, which are shared by all the apps hooked
+	loadMu sync.Mutex
+	apps   int
 	// eBPF C shared maps
 	clientRegistrationMap    *ebpf.Map
 	agentRegistartionMap     *ebpf.Map
@@ -87,29 +90,50 @@ type Hooks struct {
 	objects     bpfObjects
 	writev      link.Link
 	writevRet   link.Link
-	appID       uint64
+	// dockerApps holds the key of the dockerAppRegistrationMap entry of each app
+	dockerApps map[uint64]uint64
 }
 
 func (h *Hooks) Load(ctx context.Context, id uint64, opts core.HookCfg) error {
 
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
 	h.sess.Set(id, &core.Session{
 		ID: id,
 	})
 
-	err := h.load(ctx, opts)
+	h.loadMu.Lock()
+	var err error
+	if h.apps == 0 {
+		err = h.load(ctx, opts)
+	} else {
+		// the eBPF programs are already loaded for another app, this app is only registered with them
+		err = h.register(ctx, opts)
+	}
+	if err == nil {
+		h.apps++
+	}
+	h.loadMu.Unlock()
 	if err != nil {
+		h.sess.Delete(id)
 		return err
 	}
 
-	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
-	if !ok {
-		return errors.New("failed to get the error group from the context")
-	}
-
 	g.Go(func() error {
 		defer utils.Recover(h.logger)
 		<-ctx.Done()
-		h.unLoad(ctx)
+
+		h.loadMu.Lock()
+		h.apps--
+		if h.apps == 0 {
+			h.unLoad(ctx)
+		} else {
+			h.unregister(id)
+		}
+		h.loadMu.Unlock()
 
 		//deleting in order to free the memory in case of rerecord.
 		h.sess.Delete(id)
@@ -408,6 +432,11 @@ func (h *Hooks) load(ctx context.Context, opts core.HookCfg) error {
 
 	h.logger.Info("keploy initialized and probes added to the kernel.")
 
+	return h.register(ctx, opts)
+}
+
+// register sends the info of the app and of the proxy to the eBPF programs.
+func (h *Hooks) register(ctx context.Context, opts core.HookCfg) error {
 	var clientInfo structs.ClientInfo = structs.ClientInfo{}
 
 	switch opts.Mode {
@@ -494,6 +523,18 @@ func (h *Hooks) Record(ctx context.Context, _ uint64, opts models.IncomingOption
 	return conn.ListenSocket(ctx, h.logger, h.objects.SocketOpenEvents, h.objects.SocketDataEvents, h.objects.SocketCloseEvents, opts)
 }
 
+// CaptureIncoming assembles the calls received by the app on the captured connection the way the calls
+// traced by eBPF are, the channel is closed once the connection is processed.
+func (h *Hooks) CaptureIncoming(ctx context.Context, captured *models.CapturedConn, opts models.IncomingOptions) <-chan *models.TestCase {
+	t := make(chan *models.TestCase, 100)
+	go func() {
+		defer utils.Recover(h.logger)
+		defer close(t)
+		conn.CaptureConn(ctx, h.logger, captured, t, opts)
+	}()
+	return t
+}
+
 func (h *Hooks) unLoad(_ context.Context) {
 	// closing all events
 	//other
@@ -591,5 +632,8 @@ func (h *Hooks) unLoad(_ context.Context) {
 	if err := h.objects.Close(); err != nil {
 		utils.LogError(h.logger, err, "failed to close the objects")
 	}
+	h.m.Lock()
+	h.dockerApps = nil
+	h.m.Unlock()
 	h.logger.Info("eBPF resources released successfully...")
 }
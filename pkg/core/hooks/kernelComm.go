@@ -23,8 +23,12 @@ func (h *Hooks) Get(_ context.Context, srcPort uint16) (*core.NetworkAddress, er
 	if err != nil {
 		return nil, err
 	}
-	// TODO : need to implement eBPF code to differentiate between different apps
-	s, ok := h.sess.Get(0)
+	// the connections of the dockerized apps are attributed to their app by the eBPF programs,
+	// the ones of a native app are attributed to the keploy client
+	s, ok := h.sess.Get(d.ClientID)
+	if !ok {
+		s, ok = h.sess.Get(0)
+	}
 	if !ok {
 		return nil, fmt.Errorf("session not found")
 	}
@@ -84,21 +88,43 @@ func (h *Hooks) SendAgentInfo(agentInfo structs.AgentInfo) error {
 	return nil
 }
 
-func (h *Hooks) SendDockerAppInfo(_ uint64, dockerAppInfo structs.DockerAppInfo) error {
-	if h.appID != 0 {
-		err := h.dockerAppRegistrationMap.Delete(h.appID)
+func (h *Hooks) SendDockerAppInfo(id uint64, dockerAppInfo structs.DockerAppInfo) error {
+	h.m.Lock()
+	defer h.m.Unlock()
+	if h.dockerApps == nil {
+		h.dockerApps = map[uint64]uint64{}
+	}
+	// the entry of a previous container of the app is replaced, the ones of the other apps are kept
+	if key, ok := h.dockerApps[id]; ok {
+		err := h.dockerAppRegistrationMap.Delete(key)
 		if err != nil {
 			utils.LogError(h.logger, err, "failed to remove entry from dockerAppRegistrationMap")
 			return err
 		}
+		delete(h.dockerApps, id)
 	}
 	r := rand.New(rand.NewSource(rand.Int63()))
-	randomNum := r.Uint64()
-	h.appID = randomNum
-	err := h.dockerAppRegistrationMap.Update(h.appID, dockerAppInfo, ebpf.UpdateAny)
+	key := r.Uint64()
+	err := h.dockerAppRegistrationMap.Update(key, dockerAppInfo, ebpf.UpdateAny)
 	if err != nil {
 		utils.LogError(h.logger, err, "failed to send the dockerAppInfo info to the ebpf program")
 		return err
 	}
+	h.dockerApps[id] = key
 	return nil
 }
+
+// unregister removes the entries of an app from the eBPF maps, when the programs keep running for other apps.
+func (h *Hooks) unregister(id uint64) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	if key, ok := h.dockerApps[id]; ok {
+		if err := h.dockerAppRegistrationMap.Delete(key); err != nil {
+			h.logger.Debug("failed to remove the app from dockerAppRegistrationMap", zap.Uint64("app", id), zap.Error(err))
+		}
+		delete(h.dockerApps, id)
+	}
+	if err := h.clientRegistrationMap.Delete(id); err != nil {
+		h.logger.Debug("failed to remove the app from clientRegistrationMap", zap.Uint64("app", id), zap.Error(err))
+	}
+}
@@ -0,0 +1,286 @@
+//go:build linux
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// captureID is the id under which the protocols of the captured connections are looked up.
+const captureID uint64 = 0
+
+// CaptureOutgoing decodes the calls made by the app on a connection read from a packet capture into mocks.
+// The captured data is fed to the parser of the protocol through in-memory connections, the way the
+// parser would record the live connection, and the mocks are given the timestamps of the capture.
+// The server-first protocols detected from the greeting of the server are added to opts.Protocols.
+func (p *Proxy) CaptureOutgoing(ctx context.Context, captured *models.CapturedConn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	if len(captured.Chunks) == 0 {
+		return nil
+	}
+	if len(p.Integrations) == 0 {
+		err := p.InitIntegrations(ctx)
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to initialize the integrations")
+			return err
+		}
+	}
+	logger := p.logger.With(zap.Any("Client IP Address", captured.ClientAddr), zap.Any("Destination IP Address", captured.ServerAddr))
+
+	p.setProtocols(captureID, opts.Protocols)
+	protocol := p.getProtocol(captureID, captured.ServerPort)
+	first := captured.Chunks[0]
+	if protocol == "" && !first.FromClient {
+		protocol = identifyServerGreeting(first.Data)
+		if protocol != "" {
+			logger.Debug("detected a server-first protocol", zap.Any("protocol", protocol), zap.Any("port", captured.ServerPort))
+			p.setProtocols(captureID, map[uint32]string{captured.ServerPort: protocol})
+			if opts.Protocols != nil {
+				opts.Protocols[captured.ServerPort] = protocol
+			}
+		}
+	}
+	parser, ok := p.Integrations[protocol]
+	if !ok {
+		var initialBuf []byte
+		for _, chunk := range captured.Chunks {
+			if chunk.FromClient {
+				initialBuf = chunk.Data
+				break
+			}
+		}
+		if isTLSHandshake(initialBuf) {
+			logger.Warn("skipping the tls-encrypted connection, its calls can't be decoded from the capture")
+			return nil
+		}
+		parser = p.Integrations["generic"]
+		for _, prs := range p.Integrations {
+			if prs.MatchType(ctx, initialBuf) {
+				parser = prs
+				break
+			}
+		}
+	}
+
+	src := newCapturedConn(captured.ServerAddr, captured.ClientAddr)
+	dst := newCapturedConn(captured.ClientAddr, captured.ServerAddr)
+	src.peer, dst.peer = dst, src
+
+	parserErrGrp, parserCtx := errgroup.WithContext(ctx)
+	parserCtx = context.WithValue(parserCtx, models.ErrGroupKey, parserErrGrp)
+	parserCtx = context.WithValue(parserCtx, models.ClientConnectionIDKey, fmt.Sprint(util.GetNextID()))
+	parserCtx = context.WithValue(parserCtx, models.DestConnectionIDKey, fmt.Sprint(util.GetNextID()))
+	parserCtx, cancel := context.WithCancel(parserCtx)
+	defer cancel()
+
+	// index of the chunk being fed, the mocks made meanwhile are of the calls sent before it
+	var mu sync.Mutex
+	fed := 0
+	next := 0
+	mc := make(chan *models.Mock)
+	done := make(chan struct{})
+	go func() {
+		defer utils.Recover(logger)
+		defer close(done)
+		for mock := range mc {
+			mu.Lock()
+			next = stampMock(mock, captured.Chunks, next, fed)
+			mu.Unlock()
+			mocks <- mock
+		}
+	}()
+
+	feedDone := make(chan struct{})
+	go func() {
+		defer utils.Recover(logger)
+		defer close(feedDone)
+		defer src.Close()
+		defer dst.Close()
+		for i, chunk := range captured.Chunks {
+			mu.Lock()
+			fed = i
+			mu.Unlock()
+			conn := dst
+			if chunk.FromClient {
+				conn = src
+			}
+			if !conn.feed(parserCtx, chunk.Data) {
+				return
+			}
+		}
+		mu.Lock()
+		fed = len(captured.Chunks)
+		mu.Unlock()
+	}()
+
+	err := parser.RecordOutgoing(parserCtx, src, dst, mc, opts)
+	cancel()
+	<-feedDone
+	gErr := parserErrGrp.Wait()
+	close(mc)
+	<-done
+	if err == nil {
+		err = gErr
+	}
+	if err != nil && err != io.EOF && err != context.Canceled {
+		logger.Warn("failed to decode the captured connection", zap.Error(err))
+	}
+	return nil
+}
+
+// stampMock sets the timestamps of the mock to the ones of the chunks of its call, i.e. the chunks from
+// the first one not yet mocked up to the one being fed. It returns the first chunk of the next call.
+func stampMock(mock *models.Mock, chunks []models.CapturedChunk, from, fed int) int {
+	if fed > len(chunks)-1 {
+		fed = len(chunks) - 1
+	}
+	req, resp := -1, -1
+	for i := from; i <= fed; i++ {
+		if chunks[i].FromClient && req == -1 {
+			req = i
+		}
+		if !chunks[i].FromClient {
+			resp = i
+		}
+	}
+	if req == -1 {
+		req = min(from, fed)
+	}
+	if resp < req {
+		resp = req
+	}
+	mock.Spec.ReqTimestampMock = chunks[req].Timestamp
+	mock.Spec.ResTimestampMock = chunks[resp].Timestamp
+	return resp + 1
+}
+
+// capturedConn is a side of a captured connection as seen by the parser, it reads the data sent by
+// the other side and drops the data written to it.
+type capturedConn struct {
+	local, remote net.Addr
+	// peer is the other side of the connection
+	peer *capturedConn
+
+	mu     sync.Mutex
+	buf    []byte
+	closed bool
+	// waiting is the count of the reads waiting for data
+	waiting int
+	// ready is signalled when data is fed or the conn is closed, progress when data is read or a read
+	// of the peer waits for data
+	ready    chan struct{}
+	progress chan struct{}
+}
+
+func newCapturedConn(local, remote string) *capturedConn {
+	return &capturedConn{
+		local:    capturedAddr(local),
+		remote:   capturedAddr(remote),
+		ready:    make(chan struct{}, 1),
+		progress: make(chan struct{}, 1),
+	}
+}
+
+// feed makes the data readable and waits until the parser reads it, or until the parser waits for the
+// data of the peer instead e.g. for the response of the first one of pipelined requests, the rest of the
+// data is read later on. It returns false if the context is done i.e. the parser returned.
+func (c *capturedConn) feed(ctx context.Context, data []byte) bool {
+	c.mu.Lock()
+	c.buf = append(c.buf, data...)
+	c.mu.Unlock()
+	signal(c.ready)
+
+	for {
+		c.mu.Lock()
+		left := len(c.buf)
+		c.mu.Unlock()
+		if left == 0 || c.peer.isWaiting() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-c.progress:
+		}
+	}
+}
+
+func (c *capturedConn) isWaiting() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.waiting > 0
+}
+
+func (c *capturedConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if len(c.buf) > 0 {
+			n := copy(b, c.buf)
+			c.buf = c.buf[n:]
+			c.mu.Unlock()
+			signal(c.progress)
+			return n, nil
+		}
+		if c.closed {
+			c.mu.Unlock()
+			return 0, io.EOF
+		}
+		c.waiting++
+		c.mu.Unlock()
+		if c.peer != nil {
+			signal(c.peer.progress)
+		}
+		<-c.ready
+		c.mu.Lock()
+		c.waiting--
+		c.mu.Unlock()
+	}
+}
+
+func (c *capturedConn) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (c *capturedConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	signal(c.ready)
+	return nil
+}
+
+func (c *capturedConn) LocalAddr() net.Addr                { return c.local }
+func (c *capturedConn) RemoteAddr() net.Addr               { return c.remote }
+func (c *capturedConn) SetDeadline(_ time.Time) error      { return nil }
+func (c *capturedConn) SetReadDeadline(_ time.Time) error  { return nil }
+func (c *capturedConn) SetWriteDeadline(_ time.Time) error { return nil }
+
+// signal wakes up the waiter of the channel, if any, without blocking.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// capturedAddr returns the tcp address of an end of a captured connection, the parsers expect the
+// addresses of the live connections.
+func capturedAddr(addr string) net.Addr {
+	ap, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		return &net.TCPAddr{}
+	}
+	return net.TCPAddrFromAddrPort(ap)
+}
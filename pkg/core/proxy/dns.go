@@ -9,14 +9,24 @@ import (
 	"net"
 	"os"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/miekg/dns"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 )
 
+func init() {
+	integrations.RegisterMockKey(models.DNS, func(mock *models.Mock) string {
+		if mock.Spec.DNSReq == nil {
+			return ""
+		}
+		return dnsKey(mock.Spec.DNSReq.Name, mock.Spec.DNSReq.Qtype)
+	})
+}
+
 func (p *Proxy) startTCPDNSServer(_ context.Context) error {
 	addr := fmt.Sprintf(":%v", p.DNSPort)
 
@@ -62,14 +72,65 @@ func (p *Proxy) startUDPDNSServer(_ context.Context) error {
 	return nil
 }
 
-// For DNS caching
-var cache = struct {
-	sync.RWMutex
-	m map[string][]dns.RR
-}{m: make(map[string][]dns.RR)}
+// dnsKey returns the key of the question on which the dns mocks are indexed.
+func dnsKey(name, qtype string) string {
+	return strings.ToLower(name) + " " + qtype
+}
+
+func generateCacheKey(id uint64, name string, qtype uint16) string {
+	return fmt.Sprintf("%d-%s-%s", id, strings.ToLower(name), dns.TypeToString[qtype])
+}
 
-func generateCacheKey(name string, qtype uint16) string {
-	return fmt.Sprintf("%s-%s", name, dns.TypeToString[qtype])
+// SetAppIP sets the ip of the app container, the dns queries sent from it are attributed to the app.
+func (p *Proxy) SetAppIP(id uint64, ip string) {
+	p.appIPs.Range(func(k, v interface{}) bool {
+		if v.(uint64) == id {
+			p.appIPs.Delete(k)
+		}
+		return true
+	})
+	p.appIPs.Store(ip, id)
+}
+
+// dnsAppID returns the app which sent the dns query. The query is attributed to the only session when
+// there is one, otherwise to the app container it is sent from. The native apps share the addresses of
+// the host, so the query of a native app is only attributed when it is the only one.
+func (p *Proxy) dnsAppID(addr net.Addr) (uint64, bool) {
+	sessions := p.sessions.GetAll()
+	if len(sessions) == 1 {
+		return sessions[0].ID, true
+	}
+
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	default:
+		return 0, false
+	}
+	if id, ok := p.appIPs.Load(ip.String()); ok {
+		return id.(uint64), true
+	}
+
+	// the apps not running in a container
+	var native []uint64
+	for _, session := range sessions {
+		containerized := false
+		p.appIPs.Range(func(_, v interface{}) bool {
+			containerized = v.(uint64) == session.ID
+			return !containerized
+		})
+		if !containerized {
+			native = append(native, session.ID)
+		}
+	}
+	if len(native) == 1 {
+		return native[0], true
+	}
+	p.logger.Debug("failed to find the app of the dns query", zap.Any("source", addr.String()))
+	return 0, false
 }
 
 func (p *Proxy) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
@@ -79,50 +140,67 @@ func (p *Proxy) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	msg.SetReply(r)
 	msg.Authoritative = true
 	p.logger.Debug("Got some Dns queries")
+	appID, appFound := p.dnsAppID(w.RemoteAddr())
 	for _, question := range r.Question {
 		p.logger.Debug("", zap.Any("Record Type", question.Qtype), zap.Any("Received Query", question.Name))
 
-		key := generateCacheKey(question.Name, question.Qtype)
+		var answers []dns.RR
+		rcode := dns.RcodeSuccess
+		found := false
+		fallback := false
 
-		// Check if the answer is cached
-		cache.RLock()
-		answers, found := cache.m[key]
-		cache.RUnlock()
+		switch models.GetMode() {
+		case models.MODE_RECORD:
+			key := generateCacheKey(appID, question.Name, question.Qtype)
 
-		if !found {
-			// If not found in cache, resolve the DNS query only in case of record mode
+			// Check if the answer is cached for the current recording
+			if cached, ok := p.dnsCache.Load(key); ok {
+				answers = cached.([]dns.RR)
+				found = true
+				break
+			}
 			//TODO: Add support for passThrough here using the src<->dst mapping
-			if models.GetMode() == models.MODE_RECORD {
-				answers = resolveDNSQuery(p.logger, question.Name)
+			answers, rcode = resolveDNSQuery(p.logger, question)
+		case models.MODE_TEST:
+			// serve the answers recorded for the question, if any
+			if appFound {
+				answers, rcode, found = p.getDNSMock(appID, question)
 			}
+		}
+
+		if len(answers) == 0 && rcode == dns.RcodeSuccess {
+			// If the resolution failed, return a default A record with Proxy IP
+			fallback = true
+			if question.Qtype == dns.TypeA {
+				answers = []dns.RR{&dns.A{
+					Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 3600},
+					A:   net.ParseIP(p.IP4),
+				}}
+				p.logger.Debug("failed to resolve dns query hence sending proxy ip4", zap.Any("proxy Ip", p.IP4))
+			} else if question.Qtype == dns.TypeAAAA {
+				answers = []dns.RR{&dns.AAAA{
+					Hdr:  dns.RR_Header{Name: question.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 3600},
+					AAAA: net.ParseIP(p.IP6),
+				}}
+				p.logger.Debug("failed to resolve dns query hence sending proxy ip6", zap.Any("proxy Ip", p.IP6))
 
-			if len(answers) == 0 {
-				// If the resolution failed, return a default A record with Proxy IP
-				if question.Qtype == dns.TypeA {
-					answers = []dns.RR{&dns.A{
-						Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 3600},
-						A:   net.ParseIP(p.IP4),
-					}}
-					p.logger.Debug("failed to resolve dns query hence sending proxy ip4", zap.Any("proxy Ip", p.IP4))
-				} else if question.Qtype == dns.TypeAAAA {
-					answers = []dns.RR{&dns.AAAA{
-						Hdr:  dns.RR_Header{Name: question.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 3600},
-						AAAA: net.ParseIP(p.IP6),
-					}}
-					p.logger.Debug("failed to resolve dns query hence sending proxy ip6", zap.Any("proxy Ip", p.IP6))
-
-				}
-
-				p.logger.Debug(fmt.Sprintf("Answers[when resolution failed for query:%v]:\n%v\n", question.Qtype, answers))
 			}
 
-			// Cache the answer
-			cache.Lock()
-			cache.m[key] = answers
-			cache.Unlock()
+			p.logger.Debug(fmt.Sprintf("Answers[when resolution failed for query:%v]:\n%v\n", question.Qtype, answers))
+		}
+
+		// the answers pointing to the proxy are not resolved ones, the question is resolved again next time
+		if models.GetMode() == models.MODE_RECORD && !found && !fallback {
+			// Cache the answer and record it as a mock, so that the same answers are served in test mode
+			p.dnsCache.Store(generateCacheKey(appID, question.Name, question.Qtype), answers)
+			p.recordDNSMock(appID, appFound, question, answers, rcode)
 			p.logger.Debug(fmt.Sprintf("Answers[after caching it]:\n%v\n", answers))
 		}
 
+		if rcode != dns.RcodeSuccess {
+			msg.Rcode = rcode
+		}
+
 		p.logger.Debug(fmt.Sprintf("Answers[before appending to msg]:\n%v\n", answers))
 		msg.Answer = append(msg.Answer, answers...)
 		p.logger.Debug(fmt.Sprintf("Answers[After appending to msg]:\n%v\n", msg.Answer))
@@ -138,41 +216,172 @@ func (p *Proxy) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 }
 
 // TODO: passThrough the dns queries rather than resolving them.
-func resolveDNSQuery(logger *zap.Logger, domain string) []dns.RR {
+func resolveDNSQuery(logger *zap.Logger, question dns.Question) ([]dns.RR, int) {
 	// Remove the last dot from the domain name if it exists
-	domain = strings.TrimSuffix(domain, ".")
+	domain := strings.TrimSuffix(question.Name, ".")
+	hdr := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: question.Name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: 3600}
+	}
 
 	// Use the default system resolver
 	resolver := net.DefaultResolver
+	ctx := context.Background()
 
-	// Perform the lookup with the context
-	ips, err := resolver.LookupIPAddr(context.Background(), domain)
-	if err != nil {
-		logger.Debug(fmt.Sprintf("failed to resolve the dns query for:%v", domain), zap.Error(err))
-		return nil
+	var answers []dns.RR
+	var err error
+	switch question.Qtype {
+	case dns.TypeA, dns.TypeAAAA:
+		network := "ip4"
+		if question.Qtype == dns.TypeAAAA {
+			network = "ip6"
+		}
+		var ips []net.IP
+		ips, err = resolver.LookupIP(ctx, network, domain)
+		for _, ip := range ips {
+			if question.Qtype == dns.TypeA {
+				answers = append(answers, &dns.A{Hdr: hdr(dns.TypeA), A: ip.To4()})
+			} else {
+				answers = append(answers, &dns.AAAA{Hdr: hdr(dns.TypeAAAA), AAAA: ip})
+			}
+		}
+	case dns.TypeCNAME:
+		var cname string
+		cname, err = resolver.LookupCNAME(ctx, domain)
+		if err == nil && !strings.EqualFold(cname, dns.Fqdn(domain)) {
+			answers = append(answers, &dns.CNAME{Hdr: hdr(dns.TypeCNAME), Target: cname})
+		}
+	case dns.TypeSRV:
+		// the name of the question already holds the service and the protocol, e.g. _grpc._tcp.example.com
+		var srvs []*net.SRV
+		_, srvs, err = resolver.LookupSRV(ctx, "", "", domain)
+		for _, srv := range srvs {
+			answers = append(answers, &dns.SRV{Hdr: hdr(dns.TypeSRV), Priority: srv.Priority, Weight: srv.Weight, Port: srv.Port, Target: srv.Target})
+		}
+	case dns.TypeTXT:
+		var txts []string
+		txts, err = resolver.LookupTXT(ctx, domain)
+		for _, txt := range txts {
+			answers = append(answers, &dns.TXT{Hdr: hdr(dns.TypeTXT), Txt: []string{txt}})
+		}
+	case dns.TypeMX:
+		var mxs []*net.MX
+		mxs, err = resolver.LookupMX(ctx, domain)
+		for _, mx := range mxs {
+			answers = append(answers, &dns.MX{Hdr: hdr(dns.TypeMX), Preference: mx.Pref, Mx: mx.Host})
+		}
+	default:
+		logger.Debug("unsupported dns query type", zap.Any("type", dns.TypeToString[question.Qtype]), zap.Any("domain", domain))
+		return nil, dns.RcodeSuccess
 	}
 
-	// Convert the resolved IPs to dns.RR
-	var answers []dns.RR
-	for _, ip := range ips {
-		if ipv4 := ip.IP.To4(); ipv4 != nil {
-			answers = append(answers, &dns.A{
-				Hdr: dns.RR_Header{Name: dns.Fqdn(domain), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 3600},
-				A:   ipv4,
-			})
-		} else {
-			answers = append(answers, &dns.AAAA{
-				Hdr:  dns.RR_Header{Name: dns.Fqdn(domain), Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 3600},
-				AAAA: ip.IP,
-			})
+	if err != nil {
+		logger.Debug(fmt.Sprintf("failed to resolve the dns query for:%v", domain), zap.Any("type", dns.TypeToString[question.Qtype]), zap.Error(err))
+		var dnsErr *net.DNSError
+		// the non-existence of the service discovery records is an answer of its own
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound && question.Qtype != dns.TypeA && question.Qtype != dns.TypeAAAA {
+			return nil, dns.RcodeNameError
 		}
+		return nil, dns.RcodeSuccess
 	}
 
 	if len(answers) > 0 {
-		logger.Debug("net.LookupIP resolved the ip address...")
+		logger.Debug("net resolver resolved the dns query...", zap.Any("type", dns.TypeToString[question.Qtype]))
+	}
+
+	return answers, dns.RcodeSuccess
+}
+
+// recordDNSMock sends the answers of the question as a mock to the app being recorded, or to all of them
+// when the app of the query is not known. The dns resolution never waits for a slow consumer of the mocks.
+func (p *Proxy) recordDNSMock(id uint64, appFound bool, question dns.Question, answers []dns.RR, rcode int) {
+	now := time.Now()
+	records := make([]string, 0, len(answers))
+	for _, rr := range answers {
+		records = append(records, rr.String())
+	}
+
+	for _, session := range p.sessions.GetAll() {
+		if session.Mode != models.MODE_RECORD || session.MC == nil || (appFound && session.ID != id) {
+			continue
+		}
+		mock := &models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.DNS,
+			Spec: models.MockSpec{
+				Metadata: map[string]string{
+					"type": "config",
+				},
+				DNSReq: &models.DNSReq{
+					Name:   question.Name,
+					Qtype:  dns.TypeToString[question.Qtype],
+					Qclass: dns.ClassToString[question.Qclass],
+				},
+				DNSResp: &models.DNSResp{
+					Rcode:   rcode,
+					Answers: records,
+				},
+				ReqTimestampMock: now,
+				ResTimestampMock: now,
+			},
+		}
+		select {
+		case session.MC <- mock:
+		default:
+			p.logger.Warn("dropped the dns mock as the mocks of the app are not consumed fast enough", zap.Any("name", question.Name), zap.Any("AppID", session.ID))
+		}
 	}
+}
+
+// getDNSMock returns the answers recorded for the question in the mocks of the app. DNS mocks are not consumed
+// since the same query can be made any number of times depending on the caching of the resolver.
+func (p *Proxy) getDNSMock(id uint64, question dns.Question) ([]dns.RR, int, bool) {
+	qtype := dns.TypeToString[question.Qtype]
 
-	return answers
+	key := dnsKey(question.Name, qtype)
+
+	v, ok := p.MockManagers.Load(id)
+	if !ok {
+		p.logger.Debug("no mocks found for the app of the dns query", zap.Any("AppID", id))
+		return nil, dns.RcodeSuccess, false
+	}
+	m := v.(*MockManager)
+
+	var result *models.DNSResp
+	unfiltered, err := m.GetUnFilteredMocksByKey(models.DNS, key)
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to get the unfiltered mocks")
+	}
+	filtered, err := m.GetFilteredMocksByKey(models.DNS, key)
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to get the filtered mocks")
+	}
+	for _, mock := range append(unfiltered, filtered...) {
+		if mock.Spec.DNSResp == nil {
+			continue
+		}
+		result = mock.Spec.DNSResp
+		if err := m.FlagMockAsUsed(*mock); err != nil {
+			p.logger.Debug("failed to flag the dns mock as used", zap.Error(err))
+		}
+		break
+	}
+
+	if result == nil {
+		p.logger.Debug("no dns mock found for the query", zap.Any("name", question.Name), zap.Any("type", qtype))
+		return nil, dns.RcodeSuccess, false
+	}
+
+	answers := make([]dns.RR, 0, len(result.Answers))
+	for _, record := range result.Answers {
+		rr, err := dns.NewRR(record)
+		if err != nil || rr == nil {
+			utils.LogError(p.logger, err, "failed to parse the recorded dns answer", zap.Any("answer", record))
+			continue
+		}
+		answers = append(answers, rr)
+	}
+	return answers, result.Rcode, true
 }
 
 func (p *Proxy) stopDNSServers(_ context.Context) error {
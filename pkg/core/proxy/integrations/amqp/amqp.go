@@ -0,0 +1,59 @@
+//go:build linux
+
+// Package amqp provides the integration for AMQP 0-9-1 (RabbitMQ), recording the commands of the publishers
+// and the consumers along with the replies and the deliveries of the broker.
+package amqp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register(string(integrations.AMQP), NewAMQP)
+}
+
+type AMQP struct {
+	logger *zap.Logger
+}
+
+func NewAMQP(logger *zap.Logger) integrations.Integrations {
+	return &AMQP{
+		logger: logger,
+	}
+}
+
+// MatchType checks whether the connection starts with the protocol header of AMQP 0-9-1. The broker
+// speaks first after the header, starting the connection negotiation.
+func (a *AMQP) MatchType(_ context.Context, buf []byte) bool {
+	return bytes.HasPrefix(buf, protocolHeader)
+}
+
+func (a *AMQP) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := a.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := encodeAMQP(ctx, logger, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the amqp message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (a *AMQP) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := a.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := decodeAMQP(ctx, logger, src, dstCfg, mockDb, opts)
+	if err != nil && err != io.EOF {
+		utils.LogError(logger, err, "failed to decode the amqp message from the yaml")
+		return err
+	}
+	return nil
+}
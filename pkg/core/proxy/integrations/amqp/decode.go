@@ -0,0 +1,264 @@
+//go:build linux
+
+package amqp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// mockChannel holds the numbers the broker assigns per channel, which are rewritten in the replies.
+type mockChannel struct {
+	confirm     bool
+	publishSeq  uint64
+	deliveryTag uint64
+}
+
+// session replies to the commands of the client with the recorded commands of the broker.
+type session struct {
+	logger     *zap.Logger
+	clientConn net.Conn
+	dstCfg     *integrations.ConditionalDstCfg
+	mockDb     integrations.MockMemDb
+	frameMax   uint32
+	channels   map[uint16]*mockChannel
+}
+
+func decodeAMQP(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the amqp parser in test mode")
+	errCh := make(chan error, 1)
+	s := &session{
+		logger:     logger,
+		clientConn: clientConn,
+		dstCfg:     dstCfg,
+		mockDb:     mockDb,
+		channels:   make(map[uint16]*mockChannel),
+	}
+
+	go func(errCh chan error) {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		reader := bufio.NewReader(clientConn)
+		header, err := readProtocolHeader(reader)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		if !bytes.Equal(header, protocolHeader) {
+			// the broker replies with the protocol version it supports and closes the connection
+			_, err = clientConn.Write(protocolHeader)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the amqp protocol header to the client application")
+			}
+			errCh <- fmt.Errorf("unsupported amqp protocol header %q", header)
+			return
+		}
+		err = s.handle(ctx, protocolHeaderCommand(header))
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		asm := newAssembler()
+		for {
+			f, err := readFrame(reader)
+			if err != nil {
+				if err != io.EOF {
+					logger.Debug("failed to read the amqp frame from the client", zap.Error(err))
+				}
+				errCh <- err
+				return
+			}
+			if f.typ == frameHeartbeat {
+				// the heartbeats keep the connection of the client alive
+				_, err = clientConn.Write(heartbeatFrame)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				continue
+			}
+
+			cmd, err := asm.add(f)
+			if err != nil {
+				logger.Debug("failed to decode the amqp frame of the client", zap.Error(err))
+				if cmd == nil {
+					errCh <- err
+					return
+				}
+			}
+			if cmd == nil {
+				continue
+			}
+			err = s.handle(ctx, cmd)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}(errCh)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func (s *session) channel(id uint16) *mockChannel {
+	ch, ok := s.channels[id]
+	if !ok {
+		ch = &mockChannel{}
+		s.channels[id] = ch
+	}
+	return ch
+}
+
+// handle writes the replies of the mock matching the command, and the messages recorded for a consumer.
+func (s *session) handle(ctx context.Context, cmd *models.AMQPCommand) error {
+	switch cmd.Method {
+	case "channel.open":
+		delete(s.channels, cmd.Channel)
+	case "connection.tune-ok":
+		s.frameMax = uint32(uintField(cmd, "frame-max"))
+	}
+	ch := s.channel(cmd.Channel)
+	if cmd.Method == "confirm.select" {
+		ch.confirm = true
+	}
+	awaitsReply := expectsReply(cmd)
+	if cmd.Method == "basic.publish" && ch.confirm {
+		ch.publishSeq++
+		awaitsReply = true
+	}
+
+	mock, err := matchCommand(ctx, cmd, s.mockDb)
+	if err != nil {
+		if ctx.Err() == nil {
+			utils.LogError(s.logger, err, "error while matching amqp mocks")
+		}
+		return err
+	}
+	if mock == nil {
+		s.mockDb.RecordUnmatched(models.UnmatchedCall{
+			Kind:        models.AMQP,
+			Destination: s.dstCfg.Addr,
+			Summary:     summarizeCommand(cmd),
+		})
+		if !awaitsReply {
+			// the client doesn't wait for the broker, e.g. for an ack or a publish without confirms
+			s.logger.Debug("no matching amqp mock found for the command of the client", zap.Any("method", cmd.Method))
+			return nil
+		}
+		err := fmt.Errorf("no matching amqp mock found for the %s command", cmd.Method)
+		utils.LogError(s.logger, err, "failed to mock the amqp command", zap.Any("fields", cmd.Fields))
+		return err
+	}
+
+	consumerTag := stringField(cmd, "consumer-tag")
+	for _, resp := range mock.Spec.AMQPResp {
+		switch resp.Method {
+		case "basic.ack", "basic.nack":
+			// the confirm of the current message, or of all the messages up to it when multiple is set
+			resp, err = withDeliveryTag(resp, ch.publishSeq)
+		case "basic.get-ok":
+			ch.deliveryTag++
+			resp, err = withDeliveryTag(resp, ch.deliveryTag)
+		case "basic.consume-ok":
+			if consumerTag == "" {
+				consumerTag = stringField(&resp, "consumer-tag")
+			} else {
+				resp, err = withConsumerTag(resp, consumerTag)
+			}
+		}
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to rewrite the recorded amqp command", zap.Any("method", resp.Method))
+			return err
+		}
+		err = s.write(&resp, cmd.Channel)
+		if err != nil {
+			return err
+		}
+		if resp.Method == "basic.consume-ok" {
+			err = s.deliver(cmd, consumerTag)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deliver writes the messages recorded for the consumer. They are delivered once the consumer is registered,
+// since the broker pushes them without a command of the client.
+func (s *session) deliver(consume *models.AMQPCommand, consumerTag string) error {
+	deliveries, err := getDeliveries(s.mockDb, consume)
+	if err != nil {
+		utils.LogError(s.logger, err, "error while getting the amqp deliveries")
+		return err
+	}
+	ch := s.channel(consume.Channel)
+	for _, mock := range deliveries {
+		if !s.mockDb.ConsumeMock(mock) {
+			continue
+		}
+		for _, resp := range mock.Spec.AMQPResp {
+			if resp.Method == "basic.deliver" {
+				ch.deliveryTag++
+				resp, err = withConsumerTag(resp, consumerTag)
+				if err == nil {
+					resp, err = withDeliveryTag(resp, ch.deliveryTag)
+				}
+				if err != nil {
+					utils.LogError(s.logger, err, "failed to rewrite the recorded amqp delivery", zap.Any("mock", mock.Name))
+					return err
+				}
+			}
+			err = s.write(&resp, consume.Channel)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *session) write(cmd *models.AMQPCommand, channel uint16) error {
+	buf, err := encodeCommand(cmd, channel, s.frameMax)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to encode the recorded amqp command", zap.Any("method", cmd.Method))
+		return err
+	}
+	_, err = s.clientConn.Write(buf)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to write the amqp command to the client application")
+		return err
+	}
+	return nil
+}
+
+// summarizeCommand returns the method of the command along with the exchange, the routing key and the queue
+// it refers to, for the report of the unmatched calls.
+func summarizeCommand(cmd *models.AMQPCommand) string {
+	summary := cmd.Method
+	for _, name := range []string{"exchange", "routing-key", "queue"} {
+		if value := stringField(cmd, name); value != "" {
+			summary += fmt.Sprintf(" %s=%s", name, value)
+		}
+	}
+	return summary
+}
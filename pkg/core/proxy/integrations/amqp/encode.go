@@ -0,0 +1,306 @@
+//go:build linux
+
+package amqp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// exchange is a command of the client along with the commands the broker replied with.
+type exchange struct {
+	req              *models.AMQPCommand
+	resp             []models.AMQPCommand
+	reqTimestampMock time.Time
+	// seq is the sequence number of a message published on a channel in confirm mode
+	seq uint64
+}
+
+type channelState struct {
+	// pending is the synchronous command awaiting the reply of the broker, the clients don't send another
+	// synchronous command on the channel before the reply.
+	pending *exchange
+	confirm bool
+	// publishSeq numbers the messages published in confirm mode, the broker acks them by their number
+	publishSeq  uint64
+	unconfirmed []*exchange
+	// consumers holds the consume commands by the consumer tag of the broker, for the deliveries
+	consumers map[string]*models.AMQPCommand
+}
+
+// recorder pairs the commands of the client with the replies of the broker into mocks.
+type recorder struct {
+	logger   *zap.Logger
+	connID   string
+	mu       sync.Mutex
+	channels map[uint16]*channelState
+}
+
+func encodeAMQP(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+	errCh := make(chan error, 2)
+	rec := &recorder{
+		logger:   logger,
+		connID:   connID,
+		channels: make(map[uint16]*channelState),
+	}
+
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
+	// Read and process the commands of the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		reader := bufio.NewReader(clientConn)
+		header, err := readProtocolHeader(reader)
+		if err != nil {
+			utils.LogError(logger, err, "failed to read the amqp protocol header from the client")
+			errCh <- err
+			return nil
+		}
+		// the commands are tracked before they are forwarded, the reply of the broker may be read right after
+		rec.client(protocolHeaderCommand(header), time.Now())
+		_, err = destConn.Write(header)
+		if err != nil {
+			utils.LogError(logger, err, "failed to write the amqp protocol header to the destination server")
+			errCh <- err
+			return nil
+		}
+
+		asm := newAssembler()
+		for {
+			f, err := readFrame(reader)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the amqp frame from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			reqTimestampMock := time.Now()
+
+			var completed []*models.Mock
+			if f.typ != frameHeartbeat {
+				cmd, err := asm.add(f)
+				if err != nil {
+					logger.Debug("failed to decode the amqp frame of the client", zap.Error(err))
+				}
+				if cmd != nil {
+					completed = rec.client(cmd, reqTimestampMock)
+				}
+			}
+
+			_, err = destConn.Write(f.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the amqp frame to the destination server")
+				errCh <- err
+				return nil
+			}
+			for _, mock := range completed {
+				mocks <- mock
+			}
+		}
+	})
+
+	// Read and process the commands of the broker
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		reader := bufio.NewReader(destConn)
+		asm := newAssembler()
+		for {
+			f, err := readFrame(reader)
+			if err != nil {
+				if err == io.EOF {
+					logger.Debug("the amqp broker closed the connection")
+				} else {
+					utils.LogError(logger, err, "failed to read the amqp frame from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			// the reply is attributed before it is forwarded, the client may send its next command right after
+			var completed []*models.Mock
+			if f.typ != frameHeartbeat {
+				cmd, err := asm.add(f)
+				if err != nil {
+					logger.Debug("failed to decode the amqp frame of the broker", zap.Error(err))
+				}
+				if cmd != nil {
+					completed = rec.server(cmd, time.Now())
+				}
+			}
+
+			_, err = clientConn.Write(f.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the amqp frame to the client")
+				errCh <- err
+				return nil
+			}
+			for _, mock := range completed {
+				mocks <- mock
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func (r *recorder) channel(id uint16) *channelState {
+	st, ok := r.channels[id]
+	if !ok {
+		st = &channelState{consumers: make(map[string]*models.AMQPCommand)}
+		r.channels[id] = st
+	}
+	return st
+}
+
+// client tracks the command of the client, it returns the mocks of the commands the broker doesn't reply to.
+func (r *recorder) client(cmd *models.AMQPCommand, reqTimestampMock time.Time) []*models.Mock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ex := &exchange{req: cmd, reqTimestampMock: reqTimestampMock}
+	switch cmd.Method {
+	case "channel.open":
+		delete(r.channels, cmd.Channel)
+	case "channel.close-ok":
+		// the channel has been closed by the broker
+		defer delete(r.channels, cmd.Channel)
+	}
+	st := r.channel(cmd.Channel)
+	if cmd.Method == "confirm.select" {
+		st.confirm = true
+	}
+
+	switch {
+	case cmd.Method == "basic.publish" && st.confirm:
+		st.publishSeq++
+		ex.seq = st.publishSeq
+		st.unconfirmed = append(st.unconfirmed, ex)
+	case expectsReply(cmd):
+		st.pending = ex
+	default:
+		return []*models.Mock{r.mock(ex, cmd.Method, reqTimestampMock)}
+	}
+	return nil
+}
+
+// server attributes the command of the broker to the command of the client it replies to, it returns the
+// mocks of the completed commands.
+func (r *recorder) server(cmd *models.AMQPCommand, resTimestampMock time.Time) []*models.Mock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.channel(cmd.Channel)
+	switch cmd.Method {
+	case "basic.deliver":
+		tag := stringField(cmd, "consumer-tag")
+		consume, ok := st.consumers[tag]
+		if !ok {
+			consume = &models.AMQPCommand{
+				Channel:  cmd.Channel,
+				Method:   "basic.consume",
+				ClassID:  60,
+				MethodID: 20,
+				Fields:   map[string]interface{}{"consumer-tag": tag},
+			}
+		}
+		ex := &exchange{req: consume, resp: []models.AMQPCommand{*cmd}, reqTimestampMock: resTimestampMock}
+		return []*models.Mock{r.mock(ex, cmd.Method, resTimestampMock)}
+
+	case "basic.ack", "basic.nack":
+		if !st.confirm {
+			r.logger.Debug("ignoring the amqp ack of the broker on a channel not in confirm mode", zap.Any("channel", cmd.Channel))
+			return nil
+		}
+		// the broker acks the messages up to the delivery tag at once when multiple is set
+		tag := uintField(cmd, "delivery-tag")
+		multiple, _ := cmd.Fields["multiple"].(bool)
+		var confirmed []*models.Mock
+		unconfirmed := st.unconfirmed[:0]
+		for _, ex := range st.unconfirmed {
+			switch {
+			case ex.seq == tag:
+				ex.resp = append(ex.resp, *cmd)
+				confirmed = append(confirmed, r.mock(ex, ex.req.Method, resTimestampMock))
+			case multiple && ex.seq < tag:
+				confirmed = append(confirmed, r.mock(ex, ex.req.Method, resTimestampMock))
+			default:
+				unconfirmed = append(unconfirmed, ex)
+			}
+		}
+		st.unconfirmed = unconfirmed
+		return confirmed
+
+	case "basic.return":
+		// the unroutable mandatory message is returned before it is acked
+		for _, ex := range st.unconfirmed {
+			if stringField(ex.req, "exchange") == stringField(cmd, "exchange") && stringField(ex.req, "routing-key") == stringField(cmd, "routing-key") {
+				ex.resp = append(ex.resp, *cmd)
+				return nil
+			}
+		}
+		r.logger.Debug("ignoring the amqp message returned by the broker", zap.Any("channel", cmd.Channel))
+		return nil
+	}
+
+	if st.pending == nil || (!isReply(cmd) && cmd.Method != "channel.close" && cmd.Method != "connection.close") {
+		// e.g. the cancel of a consumer or the close of a channel initiated by the broker
+		r.logger.Debug("ignoring the amqp command of the broker which doesn't reply to the client", zap.Any("method", cmd.Method), zap.Any("channel", cmd.Channel))
+		return nil
+	}
+
+	// a synchronous command failing is replied to with the close of its channel or of the connection
+	ex := st.pending
+	st.pending = nil
+	ex.resp = append(ex.resp, *cmd)
+	switch cmd.Method {
+	case "basic.consume-ok":
+		st.consumers[stringField(cmd, "consumer-tag")] = ex.req
+	case "channel.close-ok":
+		delete(r.channels, cmd.Channel)
+	}
+	return []*models.Mock{r.mock(ex, ex.req.Method, resTimestampMock)}
+}
+
+func (r *recorder) mock(ex *exchange, operation string, resTimestampMock time.Time) *models.Mock {
+	return &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.AMQP,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{
+				"type":      "config",
+				"operation": operation,
+			},
+			AMQPReq:          ex.req,
+			AMQPResp:         ex.resp,
+			ReqTimestampMock: ex.reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: r.connID,
+	}
+}
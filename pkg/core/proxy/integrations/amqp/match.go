@@ -0,0 +1,155 @@
+//go:build linux
+
+package amqp
+
+import (
+	"context"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// ignoredFields are left out while matching the commands. The delivery tags and the consumer tags are
+// numbered or generated per connection and rewritten in the replies, the client properties and the
+// credentials vary between the runs.
+var ignoredFields = map[string]bool{
+	"delivery-tag":      true,
+	"consumer-tag":      true,
+	"client-properties": true,
+	"response":          true,
+	"new-secret":        true,
+}
+
+// matchCommand finds the mock of the given command. The method and its fields have to be the same, the
+// channels and the tags are ignored. The mocks having the same body are preferred, the filtered mocks
+// first. The messages may hold timestamps or ids, hence the body and the properties are only used to
+// break the ties.
+func matchCommand(ctx context.Context, cmd *models.AMQPCommand, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		filteredMocks, unfilteredMocks, _, err := getAMQPMocks(mockDb)
+		if err != nil {
+			return nil, err
+		}
+
+		mock := findExactMatch(filteredMocks, cmd)
+		if mock == nil {
+			mock = findExactMatch(unfilteredMocks, cmd)
+		}
+		if mock == nil {
+			mock = findBestMatch(append(filteredMocks, unfilteredMocks...), cmd)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		if !mockDb.ConsumeMock(mock) {
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// getAMQPMocks returns the filtered and the unfiltered mocks of the commands, and the mocks of the messages
+// delivered to the consumers.
+func getAMQPMocks(mockDb integrations.MockMemDb) ([]*models.Mock, []*models.Mock, []*models.Mock, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.AMQP)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var filteredMocks []*models.Mock
+	var unfilteredMocks []*models.Mock
+	var deliveries []*models.Mock
+
+	for _, mock := range mocks {
+		if mock.Spec.AMQPReq == nil {
+			continue
+		}
+		switch {
+		case mock.Spec.Metadata["operation"] == "basic.deliver":
+			deliveries = append(deliveries, mock)
+		case mock.TestModeInfo.IsFiltered:
+			filteredMocks = append(filteredMocks, mock)
+		default:
+			unfilteredMocks = append(unfilteredMocks, mock)
+		}
+	}
+	return filteredMocks, unfilteredMocks, deliveries, nil
+}
+
+// getDeliveries returns the messages recorded for the consumer, in the order they were delivered.
+func getDeliveries(mockDb integrations.MockMemDb, consume *models.AMQPCommand) ([]*models.Mock, error) {
+	_, _, mocks, err := getAMQPMocks(mockDb)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []*models.Mock
+	for _, mock := range mocks {
+		if len(mock.Spec.AMQPResp) > 0 && fieldsEqual(mock.Spec.AMQPReq.Fields, consume.Fields) {
+			deliveries = append(deliveries, mock)
+		}
+	}
+	return deliveries, nil
+}
+
+// findExactMatch returns the first mock having the same method, fields and body as the command, preferring
+// the one having the same properties.
+func findExactMatch(mocks []*models.Mock, cmd *models.AMQPCommand) *models.Mock {
+	var match *models.Mock
+	for _, mock := range mocks {
+		expected := mock.Spec.AMQPReq
+		if expected.Method != cmd.Method || !fieldsEqual(expected.Fields, cmd.Fields) || expected.Body != cmd.Body {
+			continue
+		}
+		if fieldsEqual(expected.Properties, cmd.Properties) {
+			return mock
+		}
+		if match == nil {
+			match = mock
+		}
+	}
+	return match
+}
+
+// findBestMatch returns the mock of the same method and fields having the most properties in common.
+func findBestMatch(mocks []*models.Mock, cmd *models.AMQPCommand) *models.Mock {
+	var match *models.Mock
+	best := -1
+	for _, mock := range mocks {
+		expected := mock.Spec.AMQPReq
+		if expected.Method != cmd.Method || !fieldsEqual(expected.Fields, cmd.Fields) {
+			continue
+		}
+		score := 0
+		for name, value := range expected.Properties {
+			if fmt.Sprint(value) == fmt.Sprint(cmd.Properties[name]) {
+				score++
+			}
+		}
+		if score > best {
+			best = score
+			match = mock
+		}
+	}
+	return match
+}
+
+// fieldsEqual compares the fields decoded from the wire with the ones decoded from the yaml of a mock, by
+// their text as the numbers are decoded into different types.
+func fieldsEqual(a, b map[string]interface{}) bool {
+	for name, value := range a {
+		if !ignoredFields[name] && fmt.Sprint(value) != fmt.Sprint(b[name]) {
+			return false
+		}
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok && !ignoredFields[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,707 @@
+//go:build linux
+
+package amqp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// frame types
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+	frameEnd       = 0xCE
+)
+
+const (
+	// defaultFrameMax is the frame size used until the client has tuned the connection
+	defaultFrameMax = 131072
+	// frameMinSize is the smallest frame-max the peers may agree on
+	frameMinSize = 4096
+	// maxTableDepth bounds the nesting of the tables and the arrays of the arguments
+	maxTableDepth = 64
+	maxFrameSize  = 128 << 20
+	// methodProtocolHeader is the name of the command holding the protocol header sent by the client
+	methodProtocolHeader = "protocol-header"
+)
+
+var protocolHeader = []byte("AMQP\x00\x00\x09\x01")
+
+var heartbeatFrame = []byte{frameHeartbeat, 0, 0, 0, 0, 0, 0, frameEnd}
+
+var errMalformed = errors.New("malformed amqp frame")
+
+// field types of the arguments of the methods
+const (
+	typeOctet     = 'o'
+	typeShort     = 's'
+	typeLong      = 'l'
+	typeLongLong  = 'L'
+	typeShortStr  = 'S'
+	typeLongStr   = 'X'
+	typeBit       = 'b'
+	typeTable     = 'T'
+	typeTimestamp = 't'
+)
+
+type field struct {
+	name string
+	typ  byte
+}
+
+type methodSpec struct {
+	name   string
+	fields []field
+	// content is set for the methods followed by a content header and body frames
+	content bool
+	// sync is set for the methods of the client which the broker replies to, unless no-wait is set
+	sync bool
+}
+
+func methodKey(classID, methodID uint16) uint32 {
+	return uint32(classID)<<16 | uint32(methodID)
+}
+
+var (
+	reserved1Short    = field{"reserved-1", typeShort}
+	reserved1ShortStr = field{"reserved-1", typeShortStr}
+	closeFields       = []field{{"reply-code", typeShort}, {"reply-text", typeShortStr}, {"class-id", typeShort}, {"method-id", typeShort}}
+	tuneFields        = []field{{"channel-max", typeShort}, {"frame-max", typeLong}, {"heartbeat", typeShort}}
+	bindFields        = []field{reserved1Short, {"destination", typeShortStr}, {"source", typeShortStr}, {"routing-key", typeShortStr}, {"no-wait", typeBit}, {"arguments", typeTable}}
+)
+
+// methods of AMQP 0-9-1 by class and method id
+var methods = map[uint32]methodSpec{
+	methodKey(10, 10): {name: "connection.start", fields: []field{{"version-major", typeOctet}, {"version-minor", typeOctet}, {"server-properties", typeTable}, {"mechanisms", typeLongStr}, {"locales", typeLongStr}}},
+	methodKey(10, 11): {name: "connection.start-ok", sync: true, fields: []field{{"client-properties", typeTable}, {"mechanism", typeShortStr}, {"response", typeLongStr}, {"locale", typeShortStr}}},
+	methodKey(10, 20): {name: "connection.secure", fields: []field{{"challenge", typeLongStr}}},
+	methodKey(10, 21): {name: "connection.secure-ok", sync: true, fields: []field{{"response", typeLongStr}}},
+	methodKey(10, 30): {name: "connection.tune", fields: tuneFields},
+	methodKey(10, 31): {name: "connection.tune-ok", fields: tuneFields},
+	methodKey(10, 40): {name: "connection.open", sync: true, fields: []field{{"virtual-host", typeShortStr}, reserved1ShortStr, {"reserved-2", typeBit}}},
+	methodKey(10, 41): {name: "connection.open-ok", fields: []field{reserved1ShortStr}},
+	methodKey(10, 50): {name: "connection.close", sync: true, fields: closeFields},
+	methodKey(10, 51): {name: "connection.close-ok"},
+	methodKey(10, 60): {name: "connection.blocked", fields: []field{{"reason", typeShortStr}}},
+	methodKey(10, 61): {name: "connection.unblocked"},
+	methodKey(10, 70): {name: "connection.update-secret", sync: true, fields: []field{{"new-secret", typeLongStr}, {"reason", typeShortStr}}},
+	methodKey(10, 71): {name: "connection.update-secret-ok"},
+
+	methodKey(20, 10): {name: "channel.open", sync: true, fields: []field{reserved1ShortStr}},
+	methodKey(20, 11): {name: "channel.open-ok", fields: []field{{"reserved-1", typeLongStr}}},
+	methodKey(20, 20): {name: "channel.flow", sync: true, fields: []field{{"active", typeBit}}},
+	methodKey(20, 21): {name: "channel.flow-ok", fields: []field{{"active", typeBit}}},
+	methodKey(20, 40): {name: "channel.close", sync: true, fields: closeFields},
+	methodKey(20, 41): {name: "channel.close-ok"},
+
+	methodKey(40, 10): {name: "exchange.declare", sync: true, fields: []field{reserved1Short, {"exchange", typeShortStr}, {"type", typeShortStr}, {"passive", typeBit}, {"durable", typeBit}, {"auto-delete", typeBit}, {"internal", typeBit}, {"no-wait", typeBit}, {"arguments", typeTable}}},
+	methodKey(40, 11): {name: "exchange.declare-ok"},
+	methodKey(40, 20): {name: "exchange.delete", sync: true, fields: []field{reserved1Short, {"exchange", typeShortStr}, {"if-unused", typeBit}, {"no-wait", typeBit}}},
+	methodKey(40, 21): {name: "exchange.delete-ok"},
+	methodKey(40, 30): {name: "exchange.bind", sync: true, fields: bindFields},
+	methodKey(40, 31): {name: "exchange.bind-ok"},
+	methodKey(40, 40): {name: "exchange.unbind", sync: true, fields: bindFields},
+	methodKey(40, 51): {name: "exchange.unbind-ok"},
+
+	methodKey(50, 10): {name: "queue.declare", sync: true, fields: []field{reserved1Short, {"queue", typeShortStr}, {"passive", typeBit}, {"durable", typeBit}, {"exclusive", typeBit}, {"auto-delete", typeBit}, {"no-wait", typeBit}, {"arguments", typeTable}}},
+	methodKey(50, 11): {name: "queue.declare-ok", fields: []field{{"queue", typeShortStr}, {"message-count", typeLong}, {"consumer-count", typeLong}}},
+	methodKey(50, 20): {name: "queue.bind", sync: true, fields: []field{reserved1Short, {"queue", typeShortStr}, {"exchange", typeShortStr}, {"routing-key", typeShortStr}, {"no-wait", typeBit}, {"arguments", typeTable}}},
+	methodKey(50, 21): {name: "queue.bind-ok"},
+	methodKey(50, 30): {name: "queue.purge", sync: true, fields: []field{reserved1Short, {"queue", typeShortStr}, {"no-wait", typeBit}}},
+	methodKey(50, 31): {name: "queue.purge-ok", fields: []field{{"message-count", typeLong}}},
+	methodKey(50, 40): {name: "queue.delete", sync: true, fields: []field{reserved1Short, {"queue", typeShortStr}, {"if-unused", typeBit}, {"if-empty", typeBit}, {"no-wait", typeBit}}},
+	methodKey(50, 41): {name: "queue.delete-ok", fields: []field{{"message-count", typeLong}}},
+	methodKey(50, 50): {name: "queue.unbind", sync: true, fields: []field{reserved1Short, {"queue", typeShortStr}, {"exchange", typeShortStr}, {"routing-key", typeShortStr}, {"arguments", typeTable}}},
+	methodKey(50, 51): {name: "queue.unbind-ok"},
+
+	methodKey(60, 10):  {name: "basic.qos", sync: true, fields: []field{{"prefetch-size", typeLong}, {"prefetch-count", typeShort}, {"global", typeBit}}},
+	methodKey(60, 11):  {name: "basic.qos-ok"},
+	methodKey(60, 20):  {name: "basic.consume", sync: true, fields: []field{reserved1Short, {"queue", typeShortStr}, {"consumer-tag", typeShortStr}, {"no-local", typeBit}, {"no-ack", typeBit}, {"exclusive", typeBit}, {"no-wait", typeBit}, {"arguments", typeTable}}},
+	methodKey(60, 21):  {name: "basic.consume-ok", fields: []field{{"consumer-tag", typeShortStr}}},
+	methodKey(60, 30):  {name: "basic.cancel", sync: true, fields: []field{{"consumer-tag", typeShortStr}, {"no-wait", typeBit}}},
+	methodKey(60, 31):  {name: "basic.cancel-ok", fields: []field{{"consumer-tag", typeShortStr}}},
+	methodKey(60, 40):  {name: "basic.publish", content: true, fields: []field{reserved1Short, {"exchange", typeShortStr}, {"routing-key", typeShortStr}, {"mandatory", typeBit}, {"immediate", typeBit}}},
+	methodKey(60, 50):  {name: "basic.return", content: true, fields: []field{{"reply-code", typeShort}, {"reply-text", typeShortStr}, {"exchange", typeShortStr}, {"routing-key", typeShortStr}}},
+	methodKey(60, 60):  {name: "basic.deliver", content: true, fields: []field{{"consumer-tag", typeShortStr}, {"delivery-tag", typeLongLong}, {"redelivered", typeBit}, {"exchange", typeShortStr}, {"routing-key", typeShortStr}}},
+	methodKey(60, 70):  {name: "basic.get", sync: true, fields: []field{reserved1Short, {"queue", typeShortStr}, {"no-ack", typeBit}}},
+	methodKey(60, 71):  {name: "basic.get-ok", content: true, fields: []field{{"delivery-tag", typeLongLong}, {"redelivered", typeBit}, {"exchange", typeShortStr}, {"routing-key", typeShortStr}, {"message-count", typeLong}}},
+	methodKey(60, 72):  {name: "basic.get-empty", fields: []field{reserved1ShortStr}},
+	methodKey(60, 80):  {name: "basic.ack", fields: []field{{"delivery-tag", typeLongLong}, {"multiple", typeBit}}},
+	methodKey(60, 90):  {name: "basic.reject", fields: []field{{"delivery-tag", typeLongLong}, {"requeue", typeBit}}},
+	methodKey(60, 100): {name: "basic.recover-async", fields: []field{{"requeue", typeBit}}},
+	methodKey(60, 110): {name: "basic.recover", sync: true, fields: []field{{"requeue", typeBit}}},
+	methodKey(60, 111): {name: "basic.recover-ok"},
+	methodKey(60, 120): {name: "basic.nack", fields: []field{{"delivery-tag", typeLongLong}, {"multiple", typeBit}, {"requeue", typeBit}}},
+
+	methodKey(85, 10): {name: "confirm.select", sync: true, fields: []field{{"no-wait", typeBit}}},
+	methodKey(85, 11): {name: "confirm.select-ok"},
+
+	methodKey(90, 10): {name: "tx.select", sync: true},
+	methodKey(90, 11): {name: "tx.select-ok"},
+	methodKey(90, 20): {name: "tx.commit", sync: true},
+	methodKey(90, 21): {name: "tx.commit-ok"},
+	methodKey(90, 30): {name: "tx.rollback", sync: true},
+	methodKey(90, 31): {name: "tx.rollback-ok"},
+}
+
+// properties of the content header of the basic class, in the order of their flags starting from the highest bit
+var basicProperties = []field{
+	{"content-type", typeShortStr},
+	{"content-encoding", typeShortStr},
+	{"headers", typeTable},
+	{"delivery-mode", typeOctet},
+	{"priority", typeOctet},
+	{"correlation-id", typeShortStr},
+	{"reply-to", typeShortStr},
+	{"expiration", typeShortStr},
+	{"message-id", typeShortStr},
+	{"timestamp", typeTimestamp},
+	{"type", typeShortStr},
+	{"user-id", typeShortStr},
+	{"app-id", typeShortStr},
+	{"cluster-id", typeShortStr},
+}
+
+type frame struct {
+	typ     byte
+	channel uint16
+	payload []byte
+	// raw holds the whole frame, to forward it as it is
+	raw []byte
+}
+
+// readProtocolHeader reads the 8 bytes of the protocol header sent by the client before the frames.
+func readProtocolHeader(reader *bufio.Reader) ([]byte, error) {
+	header := make([]byte, len(protocolHeader))
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// readFrame reads a frame: the type, the channel, the size of the payload, the payload and the frame end.
+func readFrame(reader *bufio.Reader) (*frame, error) {
+	header := make([]byte, 7)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[3:])
+	if size > maxFrameSize {
+		return nil, errMalformed
+	}
+	raw := make([]byte, 7+int(size)+1)
+	copy(raw, header)
+	_, err = io.ReadFull(reader, raw[7:])
+	if err != nil {
+		return nil, err
+	}
+	if raw[len(raw)-1] != frameEnd {
+		return nil, errMalformed
+	}
+	return &frame{
+		typ:     header[0],
+		channel: binary.BigEndian.Uint16(header[1:]),
+		payload: raw[7 : 7+size],
+		raw:     raw,
+	}, nil
+}
+
+func protocolHeaderCommand(header []byte) *models.AMQPCommand {
+	return &models.AMQPCommand{
+		Method:    methodProtocolHeader,
+		Fields:    map[string]interface{}{"version": fmt.Sprintf("%d-%d-%d", header[5], header[6], header[7])},
+		Arguments: base64.StdEncoding.EncodeToString(header),
+	}
+}
+
+// partialCommand is a method carrying a message whose content frames are being read.
+type partialCommand struct {
+	cmd  *models.AMQPCommand
+	size uint64
+	body []byte
+	// header is set once the content header has been read
+	header bool
+}
+
+// assembler joins the method frames with the content frames of their channel into commands. The content
+// frames of the messages published on different channels may be interleaved.
+type assembler struct {
+	partial map[uint16]*partialCommand
+}
+
+func newAssembler() *assembler {
+	return &assembler{partial: make(map[uint16]*partialCommand)}
+}
+
+// add decodes the frame, it returns the command once all of its frames have been read.
+func (a *assembler) add(f *frame) (*models.AMQPCommand, error) {
+	switch f.typ {
+	case frameMethod:
+		cmd, content, err := decodeMethod(f.channel, f.payload)
+		if err != nil {
+			return cmd, err
+		}
+		if !content {
+			return cmd, nil
+		}
+		a.partial[f.channel] = &partialCommand{cmd: cmd}
+		return nil, nil
+	case frameHeader:
+		p, ok := a.partial[f.channel]
+		if !ok || p.header {
+			return nil, fmt.Errorf("unexpected content header on the channel %d", f.channel)
+		}
+		size, props, err := decodeContentHeader(f.payload)
+		if err != nil {
+			delete(a.partial, f.channel)
+			return p.cmd, err
+		}
+		p.header = true
+		p.size = size
+		p.cmd.Properties = props
+		p.cmd.Header = base64.StdEncoding.EncodeToString(f.payload)
+		if size == 0 {
+			return a.finish(f.channel), nil
+		}
+		return nil, nil
+	case frameBody:
+		p, ok := a.partial[f.channel]
+		if !ok || !p.header {
+			return nil, fmt.Errorf("unexpected content body on the channel %d", f.channel)
+		}
+		p.body = append(p.body, f.payload...)
+		if uint64(len(p.body)) >= p.size {
+			return a.finish(f.channel), nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown amqp frame type %d", f.typ)
+}
+
+func (a *assembler) finish(channel uint16) *models.AMQPCommand {
+	p := a.partial[channel]
+	delete(a.partial, channel)
+	p.cmd.Body, p.cmd.BodyType = encodeBody(p.body)
+	return p.cmd
+}
+
+// decodeMethod decodes the class, the method and the arguments of a method frame. It reports whether the
+// method is followed by content frames.
+func decodeMethod(channel uint16, payload []byte) (*models.AMQPCommand, bool, error) {
+	if len(payload) < 4 {
+		return nil, false, errMalformed
+	}
+	classID := binary.BigEndian.Uint16(payload)
+	methodID := binary.BigEndian.Uint16(payload[2:])
+	cmd := &models.AMQPCommand{
+		Channel:   channel,
+		ClassID:   classID,
+		MethodID:  methodID,
+		Arguments: base64.StdEncoding.EncodeToString(payload[4:]),
+	}
+	spec, ok := methods[methodKey(classID, methodID)]
+	if !ok {
+		cmd.Method = fmt.Sprintf("%d.%d", classID, methodID)
+		return cmd, false, nil
+	}
+	cmd.Method = spec.name
+	fields, err := decodeFields(spec.fields, payload[4:])
+	if len(fields) > 0 {
+		cmd.Fields = fields
+	}
+	return cmd, spec.content, err
+}
+
+// decodeFields decodes the arguments of a method, the reserved fields are left out.
+func decodeFields(spec []field, buf []byte) (map[string]interface{}, error) {
+	r := &wireReader{buf: buf}
+	fields := make(map[string]interface{}, len(spec))
+	var bits byte
+	bit := 8
+	for _, f := range spec {
+		var v interface{}
+		if f.typ == typeBit {
+			// the consecutive bits are packed into octets
+			if bit == 8 {
+				bits = r.octet()
+				bit = 0
+			}
+			v = bits&(1<<bit) != 0
+			bit++
+		} else {
+			bit = 8
+			v = r.field(f.typ)
+		}
+		if r.err != nil {
+			return fields, r.err
+		}
+		if !strings.HasPrefix(f.name, "reserved") {
+			fields[f.name] = v
+		}
+	}
+	return fields, nil
+}
+
+// decodeContentHeader decodes the size of the body and the properties of a content header.
+func decodeContentHeader(payload []byte) (uint64, map[string]interface{}, error) {
+	r := &wireReader{buf: payload}
+	classID := r.short()
+	r.short() // weight
+	size := r.longlong()
+	var flags []uint16
+	for r.err == nil {
+		flag := r.short()
+		flags = append(flags, flag)
+		// the lowest bit tells whether another word of flags follows
+		if flag&1 == 0 {
+			break
+		}
+	}
+	if r.err != nil {
+		return 0, nil, r.err
+	}
+	if classID != 60 {
+		return size, nil, nil
+	}
+	props := map[string]interface{}{}
+	for i, p := range basicProperties {
+		if flags[0]&(1<<(15-i)) == 0 {
+			continue
+		}
+		props[p.name] = r.field(p.typ)
+	}
+	if r.err != nil {
+		return size, nil, r.err
+	}
+	if len(props) == 0 {
+		props = nil
+	}
+	return size, props, nil
+}
+
+// wireReader reads the fields of the arguments, the first error is kept and stops the reads.
+type wireReader struct {
+	buf []byte
+	off int
+	err error
+	// depth is the nesting of the table or the array being read
+	depth int
+}
+
+func (r *wireReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.off+n > len(r.buf) {
+		r.err = errMalformed
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *wireReader) octet() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *wireReader) short() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *wireReader) long() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *wireReader) longlong() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *wireReader) shortstr() string {
+	return string(r.next(int(r.octet())))
+}
+
+func (r *wireReader) longstr() string {
+	return string(r.next(int(r.long())))
+}
+
+func (r *wireReader) field(typ byte) interface{} {
+	switch typ {
+	case typeOctet:
+		return r.octet()
+	case typeShort:
+		return r.short()
+	case typeLong:
+		return r.long()
+	case typeLongLong, typeTimestamp:
+		return r.longlong()
+	case typeShortStr:
+		return r.shortstr()
+	case typeLongStr:
+		return r.longstr()
+	case typeTable:
+		return r.table()
+	}
+	r.err = fmt.Errorf("unknown amqp field type %q", typ)
+	return nil
+}
+
+// table decodes a field table, the values are decoded into the types yaml can hold.
+func (r *wireReader) table() map[string]interface{} {
+	size := int(r.long())
+	t := &wireReader{buf: r.next(size), depth: r.depth + 1}
+	if r.err == nil && t.depth > maxTableDepth {
+		r.err = errMalformed
+	}
+	if r.err != nil {
+		return nil
+	}
+	table := map[string]interface{}{}
+	for t.err == nil && t.off < len(t.buf) {
+		key := t.shortstr()
+		table[key] = t.value()
+	}
+	if t.err != nil {
+		r.err = t.err
+	}
+	return table
+}
+
+func (r *wireReader) value() interface{} {
+	switch typ := r.octet(); typ {
+	case 't':
+		return r.octet() != 0
+	case 'b':
+		return int8(r.octet())
+	case 'B':
+		return r.octet()
+	case 's':
+		return int16(r.short())
+	case 'u':
+		return r.short()
+	case 'I':
+		return int32(r.long())
+	case 'i':
+		return r.long()
+	case 'l':
+		return int64(r.longlong())
+	case 'f':
+		return math.Float32frombits(r.long())
+	case 'd':
+		return math.Float64frombits(r.longlong())
+	case 'D':
+		scale := r.octet()
+		return float64(int32(r.long())) / math.Pow10(int(scale))
+	case 'S':
+		return r.longstr()
+	case 'x':
+		return base64.StdEncoding.EncodeToString(r.next(int(r.long())))
+	case 'A':
+		a := &wireReader{buf: r.next(int(r.long())), depth: r.depth + 1}
+		if r.err == nil && a.depth > maxTableDepth {
+			r.err = errMalformed
+			return nil
+		}
+		values := []interface{}{}
+		for a.err == nil && a.off < len(a.buf) {
+			values = append(values, a.value())
+		}
+		if a.err != nil && r.err == nil {
+			r.err = a.err
+		}
+		return values
+	case 'T':
+		return r.longlong()
+	case 'F':
+		return r.table()
+	case 'V':
+		return nil
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unknown amqp field value type %q", typ)
+		}
+		return nil
+	}
+}
+
+// encodeBody stores the body of the messages as text, unless it is binary.
+func encodeBody(body []byte) (string, models.BodyType) {
+	if len(body) == 0 {
+		return "", ""
+	}
+	if utf8.Valid(body) {
+		return string(body), models.BodyTypeUtf8
+	}
+	return base64.StdEncoding.EncodeToString(body), models.BodyTypeBinary
+}
+
+func decodeBody(cmd *models.AMQPCommand) ([]byte, error) {
+	if cmd.BodyType == models.BodyTypeBinary {
+		return base64.StdEncoding.DecodeString(cmd.Body)
+	}
+	return []byte(cmd.Body), nil
+}
+
+// encodeCommand encodes the command into a method frame, followed by its content frames on the given channel.
+// The size of the body in the content header is set from the body of the command, which may have been edited.
+func encodeCommand(cmd *models.AMQPCommand, channel uint16, frameMax uint32) ([]byte, error) {
+	args, err := base64.StdEncoding.DecodeString(cmd.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the arguments of %s: %w", cmd.Method, err)
+	}
+	var buf bytes.Buffer
+	method := make([]byte, 4, 4+len(args))
+	binary.BigEndian.PutUint16(method, cmd.ClassID)
+	binary.BigEndian.PutUint16(method[2:], cmd.MethodID)
+	writeFrame(&buf, frameMethod, channel, append(method, args...))
+	if cmd.Header == "" {
+		return buf.Bytes(), nil
+	}
+
+	header, err := base64.StdEncoding.DecodeString(cmd.Header)
+	if err != nil || len(header) < 14 {
+		return nil, fmt.Errorf("failed to decode the content header of %s", cmd.Method)
+	}
+	body, err := decodeBody(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the body of %s: %w", cmd.Method, err)
+	}
+	binary.BigEndian.PutUint64(header[4:], uint64(len(body)))
+	writeFrame(&buf, frameHeader, channel, header)
+
+	if frameMax == 0 {
+		frameMax = defaultFrameMax
+	}
+	frameMax = max(frameMax, frameMinSize)
+	// the frame-max includes the header and the end of the frame
+	chunk := int(frameMax) - 8
+	for len(body) > 0 {
+		n := min(chunk, len(body))
+		writeFrame(&buf, frameBody, channel, body[:n])
+		body = body[n:]
+	}
+	return buf.Bytes(), nil
+}
+
+func writeFrame(buf *bytes.Buffer, typ byte, channel uint16, payload []byte) {
+	header := make([]byte, 7)
+	header[0] = typ
+	binary.BigEndian.PutUint16(header[1:], channel)
+	binary.BigEndian.PutUint32(header[3:], uint32(len(payload)))
+	buf.Write(header)
+	buf.Write(payload)
+	buf.WriteByte(frameEnd)
+}
+
+// withDeliveryTag returns a copy of the command with the delivery tag set in its arguments. The tag is the
+// first argument, except for basic.deliver where it follows the consumer tag.
+func withDeliveryTag(cmd models.AMQPCommand, tag uint64) (models.AMQPCommand, error) {
+	args, err := base64.StdEncoding.DecodeString(cmd.Arguments)
+	if err != nil {
+		return cmd, err
+	}
+	off := 0
+	if cmd.Method == "basic.deliver" {
+		if len(args) == 0 {
+			return cmd, errMalformed
+		}
+		off = 1 + int(args[0])
+	}
+	if len(args) < off+8 {
+		return cmd, errMalformed
+	}
+	binary.BigEndian.PutUint64(args[off:], tag)
+	cmd.Arguments = base64.StdEncoding.EncodeToString(args)
+	return cmd, nil
+}
+
+// withConsumerTag returns a copy of the command with the consumer tag set, which is the first argument of
+// basic.consume-ok, basic.cancel-ok and basic.deliver.
+func withConsumerTag(cmd models.AMQPCommand, tag string) (models.AMQPCommand, error) {
+	args, err := base64.StdEncoding.DecodeString(cmd.Arguments)
+	if err != nil {
+		return cmd, err
+	}
+	if len(args) == 0 || len(args) < 1+int(args[0]) || len(tag) > math.MaxUint8 {
+		return cmd, errMalformed
+	}
+	patched := make([]byte, 0, len(args)-int(args[0])+len(tag))
+	patched = append(patched, byte(len(tag)))
+	patched = append(patched, tag...)
+	patched = append(patched, args[1+int(args[0]):]...)
+	cmd.Arguments = base64.StdEncoding.EncodeToString(patched)
+	return cmd, nil
+}
+
+// expectsReply tells whether the broker replies to the command of the client.
+func expectsReply(cmd *models.AMQPCommand) bool {
+	if cmd.Method == methodProtocolHeader {
+		return true
+	}
+	spec, ok := methods[methodKey(cmd.ClassID, cmd.MethodID)]
+	if !ok || !spec.sync {
+		return false
+	}
+	noWait, _ := cmd.Fields["no-wait"].(bool)
+	return !noWait
+}
+
+// isReply tells whether the command of the broker replies to a synchronous command of the client.
+func isReply(cmd *models.AMQPCommand) bool {
+	switch cmd.Method {
+	case "connection.start", "connection.secure", "connection.tune", "basic.get-empty":
+		return true
+	}
+	return strings.HasSuffix(cmd.Method, "-ok")
+}
+
+func stringField(cmd *models.AMQPCommand, name string) string {
+	s, _ := cmd.Fields[name].(string)
+	return s
+}
+
+// uintField returns the numeric field, which is decoded from the wire or from the yaml of a mock.
+func uintField(cmd *models.AMQPCommand, name string) uint64 {
+	switch v := cmd.Fields[name].(type) {
+	case uint64:
+		return v
+	case uint32:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case int:
+		return uint64(v)
+	}
+	return 0
+}
@@ -0,0 +1,354 @@
+//go:build linux
+
+package amqp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util/wiretest"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func appendShortStr(b []byte, s string) []byte {
+	return append(append(b, byte(len(s))), s...)
+}
+
+// queueDeclareArgs are the arguments of a queue.declare of a durable queue.
+func queueDeclareArgs() []byte {
+	args := wiretest.AppendUint16(nil, 0)
+	args = appendShortStr(args, "orders")
+	args = append(args, 0b00010)
+	return wiretest.AppendLongBytes(args, nil)
+}
+
+// publishHeader is the content header of a basic.publish with a content type and a header.
+func publishHeader(size uint64) []byte {
+	header := wiretest.AppendUint16(nil, 60)
+	header = wiretest.AppendUint16(header, 0)
+	header = binary.BigEndian.AppendUint64(header, size)
+	header = wiretest.AppendUint16(header, 1<<15|1<<13)
+	header = appendShortStr(header, "text/plain")
+	table := appendShortStr(nil, "x-trace")
+	table = append(table, 'S')
+	table = wiretest.AppendUint32(table, 3)
+	table = append(table, "abc"...)
+	return wiretest.AppendLongBytes(header, table)
+}
+
+func publishArgs() []byte {
+	args := wiretest.AppendUint16(nil, 0)
+	args = appendShortStr(args, "events")
+	args = appendShortStr(args, "order.created")
+	return append(args, 0)
+}
+
+func command(method string, classID, methodID uint16, args []byte) *models.AMQPCommand {
+	return &models.AMQPCommand{
+		Method:    method,
+		ClassID:   classID,
+		MethodID:  methodID,
+		Arguments: base64.StdEncoding.EncodeToString(args),
+	}
+}
+
+// readCommands reads the frames of the buffer and assembles them into commands.
+func readCommands(t *testing.T, buf []byte) []*models.AMQPCommand {
+	t.Helper()
+	reader := bufio.NewReader(bytes.NewReader(buf))
+	asm := newAssembler()
+	var cmds []*models.AMQPCommand
+	for {
+		f, err := readFrame(reader)
+		if err == io.EOF {
+			return cmds
+		}
+		if err != nil {
+			t.Fatalf("failed to read the frame: %v", err)
+		}
+		cmd, err := asm.add(f)
+		if err != nil {
+			t.Fatalf("failed to decode the frame: %v", err)
+		}
+		if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+}
+
+func TestCommandRoundTrip(t *testing.T) {
+	publish := command("basic.publish", 60, 40, publishArgs())
+	publish.Header = base64.StdEncoding.EncodeToString(publishHeader(0))
+	publish.Body, publish.BodyType = "order 42", models.BodyTypeUtf8
+
+	large := *publish
+	large.Body = strings.Repeat("a", 3*frameMinSize)
+
+	binaryBody := *publish
+	binaryBody.Body, binaryBody.BodyType = base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe, 0x00}), models.BodyTypeBinary
+
+	publishFields := map[string]interface{}{"exchange": "events", "routing-key": "order.created", "mandatory": false, "immediate": false}
+	publishProps := map[string]interface{}{"content-type": "text/plain", "headers": map[string]interface{}{"x-trace": "abc"}}
+
+	tests := []struct {
+		name       string
+		cmd        *models.AMQPCommand
+		frameMax   uint32
+		wantFields map[string]interface{}
+		wantProps  map[string]interface{}
+		wantFrames int
+	}{
+		{
+			name:       "method without content",
+			cmd:        command("queue.declare", 50, 10, queueDeclareArgs()),
+			wantFields: map[string]interface{}{"queue": "orders", "passive": false, "durable": true, "exclusive": false, "auto-delete": false, "no-wait": false, "arguments": map[string]interface{}{}},
+			wantFrames: 1,
+		},
+		{
+			name:       "method with content",
+			cmd:        publish,
+			wantFields: publishFields,
+			wantProps:  publishProps,
+			wantFrames: 3,
+		},
+		{
+			name:       "body split on the frame-max",
+			cmd:        &large,
+			frameMax:   frameMinSize,
+			wantFields: publishFields,
+			wantProps:  publishProps,
+			wantFrames: 2 + 4,
+		},
+		{
+			name:       "frame-max below the minimum frame size",
+			cmd:        &large,
+			frameMax:   1,
+			wantFields: publishFields,
+			wantProps:  publishProps,
+			wantFrames: 2 + 4,
+		},
+		{
+			name:       "binary body",
+			cmd:        &binaryBody,
+			wantFields: publishFields,
+			wantProps:  publishProps,
+			wantFrames: 3,
+		},
+		{
+			name:       "unknown method",
+			cmd:        command("99.1", 99, 1, []byte{1, 2, 3}),
+			wantFrames: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := encodeCommand(tt.cmd, 1, tt.frameMax)
+			if err != nil {
+				t.Fatalf("failed to encode the command: %v", err)
+			}
+			frames := 0
+			reader := bufio.NewReader(bytes.NewReader(buf))
+			for {
+				_, err := readFrame(reader)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("failed to read the frame: %v", err)
+				}
+				frames++
+			}
+			if frames != tt.wantFrames {
+				t.Errorf("got %d frames, want %d", frames, tt.wantFrames)
+			}
+
+			cmds := readCommands(t, buf)
+			if len(cmds) != 1 {
+				t.Fatalf("got %d commands, want 1", len(cmds))
+			}
+			got := cmds[0]
+			if got.Method != tt.cmd.Method || got.Channel != 1 || got.Arguments != tt.cmd.Arguments {
+				t.Errorf("got the command %s on the channel %d with the arguments %s", got.Method, got.Channel, got.Arguments)
+			}
+			if !reflect.DeepEqual(got.Fields, tt.wantFields) {
+				t.Errorf("got the fields %v, want %v", got.Fields, tt.wantFields)
+			}
+			if !reflect.DeepEqual(got.Properties, tt.wantProps) {
+				t.Errorf("got the properties %v, want %v", got.Properties, tt.wantProps)
+			}
+			if got.Body != tt.cmd.Body || got.BodyType != tt.cmd.BodyType {
+				t.Errorf("got the %s body %q, want the %s body %q", got.BodyType, got.Body, tt.cmd.BodyType, got.Body)
+			}
+		})
+	}
+}
+
+func TestReadFrameMalformed(t *testing.T) {
+	valid, err := encodeCommand(command("queue.declare", 50, 10, queueDeclareArgs()), 1, 0)
+	if err != nil {
+		t.Fatalf("failed to encode the command: %v", err)
+	}
+	badEnd := bytes.Clone(valid)
+	badEnd[len(badEnd)-1] = 0
+	oversized := []byte{frameMethod, 0, 1, 0xff, 0xff, 0xff, 0xff}
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{name: "invalid frame end", buf: badEnd},
+		{name: "size above the maximum", buf: oversized},
+	}
+	for i := range valid {
+		tests = append(tests, struct {
+			name string
+			buf  []byte
+		}{name: "truncated", buf: valid[:i]})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readFrame(bufio.NewReader(bytes.NewReader(tt.buf)))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestAssemblerMalformed(t *testing.T) {
+	newFrame := func(typ byte, payload []byte) *frame {
+		return &frame{typ: typ, channel: 1, payload: payload}
+	}
+	method := func(classID, methodID uint16, args []byte) []byte {
+		return append(wiretest.AppendUint16(wiretest.AppendUint16(nil, classID), methodID), args...)
+	}
+	unknownValue := wiretest.AppendUint16(nil, 0)
+	unknownValue = appendShortStr(unknownValue, "orders")
+	unknownValue = append(unknownValue, 0)
+	unknownValue = wiretest.AppendLongBytes(unknownValue, append(appendShortStr(nil, "k"), '?'))
+
+	// arrays nested in the arguments table of a queue.declare
+	const levels = 100000
+	var nested []byte
+	for i := levels - 1; i >= 0; i-- {
+		// each array holds the arrays nested in it, of 5 bytes per level
+		nested = wiretest.AppendUint32(append(nested, 'A'), uint32(5*i))
+	}
+	nestedArgs := wiretest.AppendUint16(nil, 0)
+	nestedArgs = appendShortStr(nestedArgs, "orders")
+	nestedArgs = append(nestedArgs, 0)
+	nestedArgs = wiretest.AppendLongBytes(nestedArgs, append(appendShortStr(nil, "k"), nested...))
+
+	tests := []struct {
+		name   string
+		frames []*frame
+	}{
+		{name: "arrays nested too deeply", frames: []*frame{newFrame(frameMethod, method(50, 10, nestedArgs))}},
+		{name: "short method frame", frames: []*frame{newFrame(frameMethod, []byte{0, 50})}},
+		{name: "unknown value type in a table", frames: []*frame{newFrame(frameMethod, method(50, 10, unknownValue))}},
+		{name: "table larger than the arguments", frames: []*frame{newFrame(frameMethod, method(50, 10, wiretest.AppendUint32(queueDeclareArgs()[:10], 100)))}},
+		{name: "content header without a method", frames: []*frame{newFrame(frameHeader, publishHeader(1))}},
+		{name: "content body without a header", frames: []*frame{newFrame(frameMethod, method(60, 40, publishArgs())), newFrame(frameBody, []byte("x"))}},
+		{name: "unknown frame type", frames: []*frame{newFrame(7, nil)}},
+	}
+	args := queueDeclareArgs()
+	for i := range args {
+		tests = append(tests, struct {
+			name   string
+			frames []*frame
+		}{name: "truncated arguments", frames: []*frame{newFrame(frameMethod, method(50, 10, args[:i]))}})
+	}
+	header := publishHeader(1)
+	for i := range header {
+		tests = append(tests, struct {
+			name   string
+			frames []*frame
+		}{name: "truncated content header", frames: []*frame{newFrame(frameMethod, method(60, 40, publishArgs())), newFrame(frameHeader, header[:i])}})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asm := newAssembler()
+			var err error
+			for _, f := range tt.frames {
+				_, err = asm.add(f)
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestRewriteMalformedArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  models.AMQPCommand
+	}{
+		{name: "invalid base64", cmd: models.AMQPCommand{Method: "basic.ack", Arguments: "!"}},
+		{name: "empty arguments", cmd: models.AMQPCommand{Method: "basic.deliver"}},
+		{name: "consumer tag longer than the arguments", cmd: *command("basic.deliver", 60, 60, []byte{200, 'a'})},
+		{name: "delivery tag truncated", cmd: *command("basic.ack", 60, 80, []byte{0, 0, 0})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errDelivery := withDeliveryTag(tt.cmd, 7)
+			_, errConsumer := withConsumerTag(tt.cmd, "ctag")
+			if errDelivery == nil && errConsumer == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestEncodeCommandMalformed(t *testing.T) {
+	shortHeader := command("basic.publish", 60, 40, publishArgs())
+	shortHeader.Header = base64.StdEncoding.EncodeToString([]byte{0, 60})
+
+	badBody := command("basic.publish", 60, 40, publishArgs())
+	badBody.Header = base64.StdEncoding.EncodeToString(publishHeader(0))
+	badBody.Body, badBody.BodyType = "!", models.BodyTypeBinary
+
+	tests := []struct {
+		name string
+		cmd  *models.AMQPCommand
+	}{
+		{name: "invalid arguments", cmd: &models.AMQPCommand{Method: "basic.publish", Arguments: "!"}},
+		{name: "short content header", cmd: shortHeader},
+		{name: "invalid binary body", cmd: badBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encodeCommand(tt.cmd, 1, 0)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestDecodeContentHeaderOtherClass(t *testing.T) {
+	header := wiretest.AppendUint16(nil, 70)
+	header = wiretest.AppendUint16(header, 0)
+	header = binary.BigEndian.AppendUint64(header, 5)
+	header = wiretest.AppendUint16(header, 0)
+	size, props, err := decodeContentHeader(header)
+	if err != nil || size != 5 || props != nil {
+		t.Fatalf("got the size %d, the properties %v and the error %v", size, props, err)
+	}
+	_, _, err = decodeContentHeader(header[:3])
+	if !errors.Is(err, errMalformed) {
+		t.Fatalf("got the error %v, want %v", err, errMalformed)
+	}
+}
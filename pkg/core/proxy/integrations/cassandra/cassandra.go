@@ -0,0 +1,60 @@
+//go:build linux
+
+// Package cassandra provides the integration for the CQL native protocol of Cassandra and ScyllaDB.
+package cassandra
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/cassandra/recorder"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/cassandra/replayer"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/cassandra/wire"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register(string(integrations.CASSANDRA), New)
+}
+
+type Cassandra struct {
+	logger *zap.Logger
+}
+
+func New(logger *zap.Logger) integrations.Integrations {
+	return &Cassandra{
+		logger: logger,
+	}
+}
+
+// MatchType checks whether the connection starts with an OPTIONS or a STARTUP request of the v3 to v5
+// protocols, which the drivers send before any other request.
+func (c *Cassandra) MatchType(_ context.Context, buf []byte) bool {
+	return wire.IsStartMessage(buf)
+}
+
+func (c *Cassandra) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := c.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := recorder.Record(ctx, logger, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the cassandra message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (c *Cassandra) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := c.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := replayer.Replay(ctx, logger, src, dstCfg, mockDb, opts)
+	if err != nil && err != io.EOF {
+		utils.LogError(logger, err, "failed to decode the cassandra message from the yaml")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+//go:build linux
+
+// Package recorder is used to record the Cassandra traffic between the client and the server.
+package recorder
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/cassandra/wire"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// prepared holds the statements prepared by the server by their id. The ids are the same on all the
+// connections to the cluster, the drivers prepare a statement once and execute it on any connection.
+var prepared sync.Map
+
+func lookupPrepared(id string) *wire.Prepared {
+	if p, ok := prepared.Load(id); ok {
+		return p.(*wire.Prepared)
+	}
+	return nil
+}
+
+type inflight struct {
+	req              *models.CassandraRequest
+	reqTimestampMock time.Time
+	// framing is set for the STARTUP of v5, the messages following its response are framed
+	framing bool
+}
+
+// Binary to Mock Yaml
+
+func Record(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+	errCh := make(chan error, 2)
+
+	// the requests awaiting their response by their stream id, the clients send several requests at once
+	var mu sync.Mutex
+	requests := make(map[int16]inflight)
+	// passthrough is set when the messages can't be decoded, they are then forwarded without being recorded
+	var passthrough atomic.Bool
+
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
+	// Read and process the requests from the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		buf := bufio.NewReader(clientConn)
+		reader := wire.NewReader(buf)
+		for {
+			raw, envelopes, err := reader.Next()
+			if err != nil && raw == nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the cassandra request from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			if err != nil {
+				logger.Debug("failed to parse the cassandra request", zap.Error(err))
+			}
+			reqTimestampMock := time.Now()
+
+			for _, env := range envelopes {
+				req, err := wire.DecodeRequest(env, lookupPrepared)
+				if err != nil {
+					logger.Debug("failed to decode the cassandra request", zap.Error(err))
+				}
+				framing := env.Opcode == wire.OpStartup && env.UsesFraming()
+				if framing {
+					if _, ok := req.Options["COMPRESSION"]; ok {
+						logger.Warn("the compressed frames of the cql protocol v5 aren't supported, the connection is forwarded without being recorded. Disable the compression of the driver to record it.")
+						passthrough.Store(true)
+					}
+					reader.SetFramed()
+				}
+				mu.Lock()
+				requests[env.Stream] = inflight{req: req, reqTimestampMock: reqTimestampMock, framing: framing}
+				mu.Unlock()
+			}
+
+			_, err = destConn.Write(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the cassandra request to the destination server")
+				errCh <- err
+				return nil
+			}
+			if passthrough.Load() {
+				_, err = io.Copy(destConn, buf)
+				errCh <- err
+				return nil
+			}
+		}
+	})
+
+	// Read and process the responses from the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		buf := bufio.NewReader(destConn)
+		reader := wire.NewReader(buf)
+		for {
+			raw, envelopes, err := reader.Next()
+			if err != nil && raw == nil {
+				if err == io.EOF {
+					logger.Debug("the cassandra server closed the connection")
+				} else {
+					utils.LogError(logger, err, "failed to read the cassandra response from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+			if err != nil {
+				logger.Debug("failed to parse the cassandra response", zap.Error(err))
+			}
+
+			started := false
+			for _, env := range envelopes {
+				// the events pushed by the server aren't replies to a request
+				if env.Stream < 0 {
+					continue
+				}
+				mu.Lock()
+				req, ok := requests[env.Stream]
+				delete(requests, env.Stream)
+				mu.Unlock()
+				if !ok {
+					logger.Debug("no cassandra request found for the response", zap.Any("stream", env.Stream))
+					continue
+				}
+
+				resp, p, err := wire.DecodeResponse(env)
+				if err != nil {
+					logger.Debug("failed to decode the cassandra response", zap.Error(err))
+				}
+				if p != nil && req.req.Opcode == wire.OpcodeName(wire.OpPrepare) {
+					p.Query = req.req.Query
+					p.Keyspace = req.req.Keyspace
+					prepared.Store(resp.PreparedID, p)
+				}
+				if req.framing && (env.Opcode == wire.OpReady || env.Opcode == wire.OpAuthenticate) {
+					started = true
+				}
+				recordMock(req, resp, connID, mocks)
+			}
+
+			_, err = clientConn.Write(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the cassandra response to the client")
+				errCh <- err
+				return nil
+			}
+			if started {
+				if passthrough.Load() {
+					_, err = io.Copy(clientConn, buf)
+					errCh <- err
+					return nil
+				}
+				reader.SetFramed()
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func recordMock(req inflight, resp *models.CassandraResponse, connID string, mocks chan<- *models.Mock) {
+	mockType := "mocks"
+	switch req.req.Opcode {
+	case "STARTUP", "OPTIONS", "AUTH_RESPONSE", "REGISTER":
+		mockType = "config"
+	}
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Kind:    models.Cassandra,
+		Name:    mockType,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{
+				"type":      mockType,
+				"operation": req.req.Opcode,
+			},
+			CassandraReq:     req.req,
+			CassandraResp:    resp,
+			ReqTimestampMock: req.reqTimestampMock,
+			ResTimestampMock: time.Now(),
+		},
+		ConnectionID: connID,
+	}
+}
@@ -0,0 +1,168 @@
+//go:build linux
+
+package replayer
+
+import (
+	"context"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// matchRequest finds the mock of the given request. The opcode and the statements have to be the same, the
+// stream ids are ignored and the executions are compared by the queries they were prepared from rather than
+// by their prepared ids. The mocks having the same consistency, bound values and paging state are preferred,
+// the filtered mocks first.
+func matchRequest(ctx context.Context, req *models.CassandraRequest, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		filteredMocks, unfilteredMocks, err := getCassandraMocks(mockDb)
+		if err != nil {
+			return nil, err
+		}
+
+		mock := findExactMatch(filteredMocks, req)
+		if mock == nil {
+			mock = findExactMatch(unfilteredMocks, req)
+		}
+		if mock == nil {
+			// e.g. a value bound to the current time
+			mock = findBestMatch(append(filteredMocks, unfilteredMocks...), req)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		if !mockDb.ConsumeMock(mock) {
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// getCassandraMocks returns the filtered and the unfiltered cassandra mocks.
+func getCassandraMocks(mockDb integrations.MockMemDb) ([]*models.Mock, []*models.Mock, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.Cassandra)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var filteredMocks []*models.Mock
+	var unfilteredMocks []*models.Mock
+
+	for _, mock := range mocks {
+		if mock.Spec.CassandraReq == nil || mock.Spec.CassandraResp == nil {
+			continue
+		}
+		if mock.TestModeInfo.IsFiltered {
+			filteredMocks = append(filteredMocks, mock)
+		} else {
+			unfilteredMocks = append(unfilteredMocks, mock)
+		}
+	}
+	return filteredMocks, unfilteredMocks, nil
+}
+
+// findExactMatch returns the first mock having the same statements, parameters and values as the request.
+func findExactMatch(mocks []*models.Mock, req *models.CassandraRequest) *models.Mock {
+	for _, mock := range mocks {
+		expected := mock.Spec.CassandraReq
+		if !sameStatement(expected, req) || expected.Consistency != req.Consistency || expected.SerialConsistency != req.SerialConsistency ||
+			expected.PageSize != req.PageSize || expected.PagingState != req.PagingState || expected.Body != req.Body ||
+			!valuesEqual(expected.Values, req.Values) || !optionsEqual(expected.Options, req.Options) {
+			continue
+		}
+		statementsMatch := true
+		for i := range expected.Statements {
+			if !valuesEqual(expected.Statements[i].Values, req.Statements[i].Values) {
+				statementsMatch = false
+				break
+			}
+		}
+		if statementsMatch {
+			return mock
+		}
+	}
+	return nil
+}
+
+// findBestMatch returns the mock of the same statements having the highest score.
+func findBestMatch(mocks []*models.Mock, req *models.CassandraRequest) *models.Mock {
+	var match *models.Mock
+	best := -1
+	for _, mock := range mocks {
+		expected := mock.Spec.CassandraReq
+		if !sameStatement(expected, req) {
+			continue
+		}
+		score := 0
+		if valuesEqual(expected.Values, req.Values) {
+			score += 2
+		}
+		if expected.Consistency == req.Consistency {
+			score++
+		}
+		if expected.PagingState == req.PagingState {
+			score++
+		}
+		if expected.Body == req.Body {
+			score++
+		}
+		if score > best {
+			best = score
+			match = mock
+		}
+	}
+	return match
+}
+
+// sameStatement compares the opcodes and the statements of the requests.
+func sameStatement(a, b *models.CassandraRequest) bool {
+	if a.Opcode != b.Opcode || a.Keyspace != b.Keyspace || a.BatchType != b.BatchType || len(a.Statements) != len(b.Statements) {
+		return false
+	}
+	if !queryEqual(a.Query, a.PreparedID, b.Query, b.PreparedID) {
+		return false
+	}
+	for i := range a.Statements {
+		if !queryEqual(a.Statements[i].Query, a.Statements[i].PreparedID, b.Statements[i].Query, b.Statements[i].PreparedID) {
+			return false
+		}
+	}
+	return true
+}
+
+// queryEqual compares the queries, or the prepared ids when the query of a recorded execution is unknown.
+func queryEqual(queryA, idA, queryB, idB string) bool {
+	if queryA != "" && queryB != "" {
+		return queryA == queryB
+	}
+	return queryA == queryB && idA == idB
+}
+
+func valuesEqual(a, b []models.CassandraValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Value != b[i].Value || a[i].Null != b[i].Null || a[i].Unset != b[i].Unset {
+			return false
+		}
+	}
+	return true
+}
+
+func optionsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if v, ok := b[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
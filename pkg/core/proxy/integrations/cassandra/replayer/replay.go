@@ -0,0 +1,173 @@
+//go:build linux
+
+// Package replayer is used to mock the Cassandra traffic between the client and the server.
+package replayer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/cassandra/wire"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// Mock Yaml to Binary
+
+func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	errCh := make(chan error, 1)
+
+	go func(errCh chan error) {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		reader := wire.NewReader(bufio.NewReader(clientConn))
+		lookup := preparedLookup(mockDb)
+		for {
+			_, envelopes, err := reader.Next()
+			if err != nil {
+				if err != io.EOF {
+					logger.Debug("failed to read the cassandra request from the client", zap.Error(err))
+				}
+				errCh <- err
+				return
+			}
+
+			var resp []byte
+			started := false
+			for _, env := range envelopes {
+				out, ok, err := handleRequest(ctx, logger, env, dstCfg, mockDb, lookup)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				// the messages following the startup of v5 are framed once it is accepted
+				if env.Opcode == wire.OpStartup && env.UsesFraming() && ok {
+					started = true
+				}
+				resp = append(resp, out...)
+			}
+			if reader.Framed() {
+				resp = wire.EncodeSegments(resp)
+			}
+
+			_, err = clientConn.Write(resp)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				utils.LogError(logger, err, "failed to write the cassandra response to the client application")
+				errCh <- err
+				return
+			}
+			if started {
+				reader.SetFramed()
+			}
+		}
+	}(errCh)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// handleRequest returns the response to the request, it reports whether the recorded response is a success.
+// The requests no mock matches are replied to with an error, so that the client keeps its connection.
+func handleRequest(ctx context.Context, logger *zap.Logger, env *wire.Envelope, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, lookup wire.PreparedLookup) ([]byte, bool, error) {
+	version := env.ProtocolVersion()
+	req, err := wire.DecodeRequest(env, lookup)
+	if err != nil {
+		logger.Debug("failed to decode the cassandra request", zap.Error(err))
+	}
+
+	// the statement was prepared with an id which isn't recorded, the driver prepares it again on this error
+	if id := unknownPreparedID(req); id != "" {
+		logger.Debug("the prepared statement of the cassandra request isn't recorded", zap.Any("prepared id", id))
+		return wire.EncodeUnprepared(version, env.Stream, id), false, nil
+	}
+
+	mock, err := matchRequest(ctx, req, mockDb)
+	if err != nil {
+		if ctx.Err() == nil {
+			utils.LogError(logger, err, "error while matching cassandra mocks")
+		}
+		return nil, false, err
+	}
+	if mock == nil {
+		summary := summarizeRequest(req)
+		utils.LogError(logger, nil, "no matching cassandra mock found for the request", zap.Any("request", summary))
+		mockDb.RecordUnmatched(models.UnmatchedCall{
+			Kind:        models.Cassandra,
+			Destination: dstCfg.Addr,
+			Summary:     summary,
+		})
+		return wire.EncodeError(version, env.Stream, wire.ErrServer, "keploy: no mock found for "+summary), false, nil
+	}
+
+	out, err := wire.EncodeResponse(mock.Spec.CassandraResp, version, env.Stream)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the cassandra response", zap.Any("mock", mock.Name))
+		return nil, false, err
+	}
+	return out, mock.Spec.CassandraResp.Opcode != wire.OpcodeName(wire.OpError), nil
+}
+
+// preparedLookup looks up the statements in the recorded PREPARE requests. The client executes the statements
+// with the ids of the recorded responses, which are then matched with the recorded executions by their query.
+func preparedLookup(mockDb integrations.MockMemDb) wire.PreparedLookup {
+	return func(id string) *wire.Prepared {
+		mocks, err := mockDb.GetUnFilteredMocksByKind(models.Cassandra)
+		if err != nil {
+			return nil
+		}
+		for _, mock := range mocks {
+			req, resp := mock.Spec.CassandraReq, mock.Spec.CassandraResp
+			if req == nil || resp == nil || resp.Kind != "Prepared" || resp.PreparedID != id {
+				continue
+			}
+			return &wire.Prepared{
+				Query:     req.Query,
+				Keyspace:  req.Keyspace,
+				Variables: resp.Variables,
+			}
+		}
+		return nil
+	}
+}
+
+// unknownPreparedID returns the id of the statement executed by the request whose preparation isn't recorded.
+func unknownPreparedID(req *models.CassandraRequest) string {
+	if req.PreparedID != "" && req.Query == "" {
+		return req.PreparedID
+	}
+	for _, stmt := range req.Statements {
+		if stmt.PreparedID != "" && stmt.Query == "" {
+			return stmt.PreparedID
+		}
+	}
+	return ""
+}
+
+// summarizeRequest returns the opcode of the request along with its statements, for the report of the
+// unmatched calls.
+func summarizeRequest(req *models.CassandraRequest) string {
+	summary := req.Opcode
+	if req.Query != "" {
+		summary += " " + req.Query
+	}
+	for _, stmt := range req.Statements {
+		summary += fmt.Sprintf("; %s", stmt.Query)
+	}
+	return summary
+}
@@ -0,0 +1,463 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// flags of the query parameters
+const (
+	queryValues            = 0x01
+	querySkipMetadata      = 0x02
+	queryPageSize          = 0x04
+	queryPagingState       = 0x08
+	querySerialConsistency = 0x10
+	queryDefaultTimestamp  = 0x20
+	queryValueNames        = 0x40
+	queryKeyspace          = 0x80
+	queryNowInSeconds      = 0x100
+)
+
+// kinds of the results
+const (
+	resultVoid         = 0x0001
+	resultRows         = 0x0002
+	resultSetKeyspace  = 0x0003
+	resultPrepared     = 0x0004
+	resultSchemaChange = 0x0005
+)
+
+// flags of the metadata of the rows and the prepared statements
+const (
+	metadataGlobalTableSpec = 0x01
+	metadataHasMorePages    = 0x02
+	metadataNoMetadata      = 0x04
+	metadataChanged         = 0x08
+)
+
+var consistencies = []string{"ANY", "ONE", "TWO", "THREE", "QUORUM", "ALL", "LOCAL_QUORUM", "EACH_QUORUM", "SERIAL", "LOCAL_SERIAL", "LOCAL_ONE"}
+
+var batchTypes = []string{"LOGGED", "UNLOGGED", "COUNTER"}
+
+// Prepared is a statement prepared by the server, its variables are used to decode the bound values of
+// the executions.
+type Prepared struct {
+	Query     string
+	Keyspace  string
+	Variables []models.CassandraColumn
+}
+
+// PreparedLookup returns the statement prepared with the given id, or nil if it is unknown.
+type PreparedLookup func(id string) *Prepared
+
+// DecodeRequest decodes the message of the client. The statement and the variables of an EXECUTE are looked
+// up by its prepared id. The compressed messages are only stored as base64.
+func DecodeRequest(env *Envelope, lookup PreparedLookup) (*models.CassandraRequest, error) {
+	req := &models.CassandraRequest{
+		Version:  env.ProtocolVersion(),
+		Opcode:   OpcodeName(env.Opcode),
+		StreamID: env.Stream,
+	}
+	if env.Flags&FlagCompression != 0 {
+		req.Body = base64.StdEncoding.EncodeToString(env.Body)
+		return req, nil
+	}
+	version := env.ProtocolVersion()
+	r := &bodyReader{buf: env.Body}
+	if env.Flags&FlagCustomPayload != 0 {
+		r.bytesMap()
+	}
+
+	switch env.Opcode {
+	case OpStartup:
+		req.Options = r.stringMap()
+	case OpOptions:
+	case OpQuery:
+		req.Query = r.longString()
+		r.queryParameters(req, version, nil)
+	case OpPrepare:
+		req.Query = r.longString()
+		if version >= 5 {
+			if flags := r.int(); flags&0x01 != 0 {
+				req.Keyspace = r.string()
+			}
+		}
+	case OpExecute:
+		req.PreparedID = hex.EncodeToString(r.shortBytes())
+		if version >= 5 {
+			r.shortBytes() // the id of the metadata of the result
+		}
+		var variables []models.CassandraColumn
+		if prepared := lookup(req.PreparedID); prepared != nil {
+			req.Query = prepared.Query
+			variables = prepared.Variables
+		}
+		r.queryParameters(req, version, variables)
+	case OpBatch:
+		r.batch(req, version, lookup)
+	default:
+		// e.g. the credentials of AUTH_RESPONSE or the events of REGISTER
+		req.Body = base64.StdEncoding.EncodeToString(env.Body)
+	}
+	if r.err != nil {
+		return req, fmt.Errorf("failed to decode the cql %s request: %w", req.Opcode, r.err)
+	}
+	return req, nil
+}
+
+// DecodeResponse decodes the message of the server. It returns the statement prepared by a PREPARED result,
+// without its query which is sent by the client.
+func DecodeResponse(env *Envelope) (*models.CassandraResponse, *Prepared, error) {
+	resp := &models.CassandraResponse{
+		Opcode: OpcodeName(env.Opcode),
+		Flags:  env.Flags,
+		Body:   base64.StdEncoding.EncodeToString(env.Body),
+	}
+	if env.Flags&FlagCompression != 0 {
+		return resp, nil, nil
+	}
+	version := env.ProtocolVersion()
+	r := &bodyReader{buf: env.Body}
+	if env.Flags&FlagTracing != 0 {
+		r.next(16)
+	}
+	if env.Flags&FlagWarning != 0 {
+		r.stringList()
+	}
+	if env.Flags&FlagCustomPayload != 0 {
+		r.bytesMap()
+	}
+
+	var prepared *Prepared
+	switch env.Opcode {
+	case OpError:
+		resp.ErrorCode = r.int()
+		resp.Message = r.string()
+	case OpResult:
+		switch kind := r.int(); kind {
+		case resultVoid:
+			resp.Kind = "Void"
+		case resultRows:
+			resp.Kind = "Rows"
+			resp.Columns = r.rowsMetadata(version)
+			resp.Rows = r.rows(resp.Columns)
+		case resultSetKeyspace:
+			resp.Kind = "SetKeyspace"
+			resp.Message = r.string()
+		case resultPrepared:
+			resp.Kind = "Prepared"
+			resp.PreparedID = hex.EncodeToString(r.shortBytes())
+			if version >= 5 {
+				r.shortBytes() // the id of the metadata of the result
+			}
+			resp.Variables = r.preparedMetadata(version)
+			resp.Columns = r.rowsMetadata(version)
+			prepared = &Prepared{Variables: resp.Variables}
+		case resultSchemaChange:
+			resp.Kind = "SchemaChange"
+		default:
+			resp.Kind = fmt.Sprintf("0x%04x", kind)
+		}
+	}
+	if r.err != nil {
+		return resp, prepared, fmt.Errorf("failed to decode the cql %s response: %w", resp.Opcode, r.err)
+	}
+	return resp, prepared, nil
+}
+
+// bodyReader reads the notations of the protocol, the first error is kept and stops the reads.
+type bodyReader struct {
+	buf []byte
+	off int
+	err error
+	// depth is the nesting of the types being decoded
+	depth int
+}
+
+func (r *bodyReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.off+n > len(r.buf) {
+		r.err = ErrMalformed
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+// capacity bounds the count read from the body by the bytes left, each element taking at least one byte.
+func (r *bodyReader) capacity(count int) int {
+	return max(min(count, len(r.buf)-r.off), 0)
+}
+
+func (r *bodyReader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *bodyReader) short() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *bodyReader) int() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *bodyReader) long() int64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (r *bodyReader) string() string {
+	return string(r.next(int(r.short())))
+}
+
+func (r *bodyReader) longString() string {
+	return string(r.next(int(r.int())))
+}
+
+func (r *bodyReader) shortBytes() []byte {
+	return r.next(int(r.short()))
+}
+
+// bytes returns nil for a null value, n is the length read (-1 for null, -2 for not set).
+func (r *bodyReader) bytes() ([]byte, int32) {
+	n := r.int()
+	if n < 0 {
+		return nil, n
+	}
+	return r.next(int(n)), n
+}
+
+func (r *bodyReader) stringList() []string {
+	n := int(r.short())
+	list := make([]string, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		list = append(list, r.string())
+	}
+	return list
+}
+
+func (r *bodyReader) stringMap() map[string]string {
+	n := int(r.short())
+	m := make(map[string]string, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		key := r.string()
+		m[key] = r.string()
+	}
+	return m
+}
+
+func (r *bodyReader) bytesMap() {
+	n := int(r.short())
+	for i := 0; i < n && r.err == nil; i++ {
+		r.string()
+		r.bytes()
+	}
+}
+
+func (r *bodyReader) consistency() string {
+	c := int(r.short())
+	if c < len(consistencies) {
+		return consistencies[c]
+	}
+	return fmt.Sprint(c)
+}
+
+// queryParameters decodes the parameters of a QUERY or an EXECUTE. The timestamps set by the clients and the
+// current time of v5 vary between the runs, they are left out.
+func (r *bodyReader) queryParameters(req *models.CassandraRequest, version byte, variables []models.CassandraColumn) {
+	req.Consistency = r.consistency()
+	var flags int32
+	if version >= 5 {
+		flags = r.int()
+	} else {
+		flags = int32(r.byte())
+	}
+	if flags&queryValues != 0 {
+		req.Values = r.values(flags&queryValueNames != 0, variables)
+	}
+	if flags&queryPageSize != 0 {
+		req.PageSize = r.int()
+	}
+	if flags&queryPagingState != 0 {
+		state, _ := r.bytes()
+		req.PagingState = base64.StdEncoding.EncodeToString(state)
+	}
+	if flags&querySerialConsistency != 0 {
+		req.SerialConsistency = r.consistency()
+	}
+	if flags&queryDefaultTimestamp != 0 {
+		r.long()
+	}
+	if version >= 5 {
+		if flags&queryKeyspace != 0 {
+			req.Keyspace = r.string()
+		}
+		if flags&queryNowInSeconds != 0 {
+			r.int()
+		}
+	}
+}
+
+// values decodes the bound values by the types of the variables, by their position or by their name.
+func (r *bodyReader) values(named bool, variables []models.CassandraColumn) []models.CassandraValue {
+	n := int(r.short())
+	values := make([]models.CassandraValue, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		var v models.CassandraValue
+		if named {
+			v.Name = r.string()
+		}
+		raw, length := r.bytes()
+		switch {
+		case named:
+			for _, variable := range variables {
+				if variable.Name == v.Name {
+					v.Type = variable.Type
+					break
+				}
+			}
+		case i < len(variables):
+			v.Type = variables[i].Type
+		}
+		switch length {
+		case -1:
+			v.Null = true
+		case -2:
+			v.Unset = true
+		default:
+			v.Value = FormatValue(v.Type, raw)
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func (r *bodyReader) batch(req *models.CassandraRequest, version byte, lookup PreparedLookup) {
+	batchType := int(r.byte())
+	if batchType < len(batchTypes) {
+		req.BatchType = batchTypes[batchType]
+	}
+	n := int(r.short())
+	for i := 0; i < n && r.err == nil; i++ {
+		var stmt models.CassandraStatement
+		var variables []models.CassandraColumn
+		if kind := r.byte(); kind == 0 {
+			stmt.Query = r.longString()
+		} else {
+			stmt.PreparedID = hex.EncodeToString(r.shortBytes())
+			if prepared := lookup(stmt.PreparedID); prepared != nil {
+				stmt.Query = prepared.Query
+				variables = prepared.Variables
+			}
+		}
+		stmt.Values = r.values(false, variables)
+		req.Statements = append(req.Statements, stmt)
+	}
+	req.Consistency = r.consistency()
+	var flags int32
+	if version >= 5 {
+		flags = r.int()
+	} else {
+		flags = int32(r.byte())
+	}
+	if flags&querySerialConsistency != 0 {
+		req.SerialConsistency = r.consistency()
+	}
+	if flags&queryDefaultTimestamp != 0 {
+		r.long()
+	}
+	if version >= 5 && flags&queryKeyspace != 0 {
+		req.Keyspace = r.string()
+	}
+}
+
+// preparedMetadata decodes the variables of a prepared statement.
+func (r *bodyReader) preparedMetadata(version byte) []models.CassandraColumn {
+	flags := r.int()
+	count := int(r.int())
+	if version >= 4 {
+		pkCount := int(r.int())
+		r.next(2 * pkCount)
+	}
+	return r.columnSpecs(flags, count)
+}
+
+// rowsMetadata decodes the columns of the rows, they are missing if the client asked to skip them.
+func (r *bodyReader) rowsMetadata(version byte) []models.CassandraColumn {
+	flags := r.int()
+	count := int(r.int())
+	if flags&metadataHasMorePages != 0 {
+		r.bytes()
+	}
+	if version >= 5 && flags&metadataChanged != 0 {
+		r.shortBytes()
+	}
+	if flags&metadataNoMetadata != 0 {
+		return nil
+	}
+	return r.columnSpecs(flags, count)
+}
+
+func (r *bodyReader) columnSpecs(flags int32, count int) []models.CassandraColumn {
+	if flags&metadataGlobalTableSpec != 0 {
+		r.string()
+		r.string()
+	}
+	columns := make([]models.CassandraColumn, 0, r.capacity(count))
+	for i := 0; i < count && r.err == nil; i++ {
+		if flags&metadataGlobalTableSpec == 0 {
+			r.string()
+			r.string()
+		}
+		name := r.string()
+		columns = append(columns, models.CassandraColumn{Name: name, Type: r.option()})
+	}
+	return columns
+}
+
+// rows decodes the rows of the result by the types of the columns, the values are stored as base64 when the
+// columns are unknown.
+func (r *bodyReader) rows(columns []models.CassandraColumn) [][]string {
+	count := int(r.int())
+	if len(columns) == 0 {
+		return nil
+	}
+	rows := make([][]string, 0, r.capacity(count))
+	for i := 0; i < count && r.err == nil; i++ {
+		row := make([]string, len(columns))
+		for j, column := range columns {
+			raw, length := r.bytes()
+			if length < 0 {
+				row[j] = "null"
+				continue
+			}
+			row[j] = FormatValue(column.Type, raw)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
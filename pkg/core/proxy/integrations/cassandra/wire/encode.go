@@ -0,0 +1,62 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// error codes
+const (
+	ErrServer     = 0x0000
+	ErrUnprepared = 0x2500
+)
+
+// EncodeResponse returns the recorded response as an envelope replying to the stream of the request.
+func EncodeResponse(resp *models.CassandraResponse, version byte, stream int16) ([]byte, error) {
+	opcode, ok := OpcodeFromName(resp.Opcode)
+	if !ok {
+		return nil, fmt.Errorf("unknown cql opcode %s", resp.Opcode)
+	}
+	body, err := base64.StdEncoding.DecodeString(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the body of the cql %s response: %w", resp.Opcode, err)
+	}
+	env := &Envelope{
+		Version: version | responseBit,
+		Flags:   resp.Flags,
+		Stream:  stream,
+		Opcode:  opcode,
+		Body:    body,
+	}
+	return env.Encode(), nil
+}
+
+// EncodeError returns an ERROR response with the given code and message.
+func EncodeError(version byte, stream int16, code int32, message string) []byte {
+	body := binary.BigEndian.AppendUint32(nil, uint32(code))
+	body = appendString(body, message)
+	env := &Envelope{Version: version | responseBit, Stream: stream, Opcode: OpError, Body: body}
+	return env.Encode()
+}
+
+// EncodeUnprepared returns the error telling the client to prepare the statement of the id again.
+func EncodeUnprepared(version byte, stream int16, preparedID string) []byte {
+	id, _ := hex.DecodeString(preparedID)
+	body := binary.BigEndian.AppendUint32(nil, uint32(ErrUnprepared))
+	body = appendString(body, "Prepared query with ID "+preparedID+" not found")
+	body = binary.BigEndian.AppendUint16(body, uint16(len(id)))
+	body = append(body, id...)
+	env := &Envelope{Version: version | responseBit, Stream: stream, Opcode: OpError, Body: body}
+	return env.Encode()
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
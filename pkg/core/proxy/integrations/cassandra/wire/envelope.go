@@ -0,0 +1,333 @@
+//go:build linux
+
+// Package wire decodes the envelopes of the CQL native protocol (v3 to v5) and the segments the v5 envelopes
+// are framed in once the connection is started.
+package wire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// opcodes of the messages
+const (
+	OpError         = 0x00
+	OpStartup       = 0x01
+	OpReady         = 0x02
+	OpAuthenticate  = 0x03
+	OpOptions       = 0x05
+	OpSupported     = 0x06
+	OpQuery         = 0x07
+	OpResult        = 0x08
+	OpPrepare       = 0x09
+	OpExecute       = 0x0A
+	OpRegister      = 0x0B
+	OpEvent         = 0x0C
+	OpBatch         = 0x0D
+	OpAuthChallenge = 0x0E
+	OpAuthResponse  = 0x0F
+	OpAuthSuccess   = 0x10
+)
+
+var opcodes = map[byte]string{
+	OpError:         "ERROR",
+	OpStartup:       "STARTUP",
+	OpReady:         "READY",
+	OpAuthenticate:  "AUTHENTICATE",
+	OpOptions:       "OPTIONS",
+	OpSupported:     "SUPPORTED",
+	OpQuery:         "QUERY",
+	OpResult:        "RESULT",
+	OpPrepare:       "PREPARE",
+	OpExecute:       "EXECUTE",
+	OpRegister:      "REGISTER",
+	OpEvent:         "EVENT",
+	OpBatch:         "BATCH",
+	OpAuthChallenge: "AUTH_CHALLENGE",
+	OpAuthResponse:  "AUTH_RESPONSE",
+	OpAuthSuccess:   "AUTH_SUCCESS",
+}
+
+// flags of the envelopes
+const (
+	FlagCompression   = 0x01
+	FlagTracing       = 0x02
+	FlagCustomPayload = 0x04
+	FlagWarning       = 0x08
+	FlagBeta          = 0x10
+)
+
+const (
+	// HeaderLength is the length of the header of the envelopes since v3
+	HeaderLength = 9
+	// responseBit is set in the version of the envelopes sent by the server
+	responseBit       = 0x80
+	maxEnvelopeLength = 256 << 20
+
+	segmentHeaderLength = 6
+	segmentCRCLength    = 4
+	maxSegmentPayload   = 1<<17 - 1
+	selfContainedBit    = 1 << 17
+)
+
+var ErrMalformed = errors.New("malformed cql envelope")
+
+// Envelope is a message of the native protocol with its header.
+type Envelope struct {
+	Version byte
+	Flags   byte
+	Stream  int16
+	Opcode  byte
+	Body    []byte
+}
+
+func (e *Envelope) IsResponse() bool {
+	return e.Version&responseBit != 0
+}
+
+// ProtocolVersion returns the version of the protocol without the direction bit.
+func (e *Envelope) ProtocolVersion() byte {
+	return e.Version &^ responseBit
+}
+
+// UsesFraming tells whether the envelopes following the startup are framed in segments, which is the
+// case from v5 on. The v5 beta of the older servers keeps the envelopes unframed.
+func (e *Envelope) UsesFraming() bool {
+	return e.ProtocolVersion() >= 5 && e.Flags&FlagBeta == 0
+}
+
+func OpcodeName(opcode byte) string {
+	if name, ok := opcodes[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", opcode)
+}
+
+// OpcodeFromName returns the opcode of the message named in a mock.
+func OpcodeFromName(name string) (byte, bool) {
+	for opcode, n := range opcodes {
+		if n == name {
+			return opcode, true
+		}
+	}
+	return 0, false
+}
+
+// IsStartMessage tells whether the buffer starts with an OPTIONS or a STARTUP request, which are the first
+// messages the drivers send on a connection.
+func IsStartMessage(buf []byte) bool {
+	if len(buf) < HeaderLength {
+		return false
+	}
+	version := buf[0]
+	if version < 3 || version > 5 {
+		return false
+	}
+	if buf[4] != OpOptions && buf[4] != OpStartup {
+		return false
+	}
+	return binary.BigEndian.Uint32(buf[5:]) <= maxEnvelopeLength
+}
+
+// Encode returns the envelope with its header.
+func (e *Envelope) Encode() []byte {
+	buf := make([]byte, HeaderLength, HeaderLength+len(e.Body))
+	buf[0] = e.Version
+	buf[1] = e.Flags
+	binary.BigEndian.PutUint16(buf[2:], uint16(e.Stream))
+	buf[4] = e.Opcode
+	binary.BigEndian.PutUint32(buf[5:], uint32(len(e.Body)))
+	return append(buf, e.Body...)
+}
+
+// Reader reads the envelopes of one side of a connection. Once framed, the envelopes are read from the
+// payloads of the segments, a segment may hold several envelopes or a part of a large one.
+type Reader struct {
+	r      *bufio.Reader
+	framed bool
+	// buf holds the payloads of the segments which aren't split into envelopes yet
+	buf []byte
+}
+
+func NewReader(r *bufio.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// SetFramed switches the reader to the segments, after the startup of a v5 connection.
+func (r *Reader) SetFramed() {
+	r.framed = true
+}
+
+func (r *Reader) Framed() bool {
+	return r.framed
+}
+
+// Next reads an envelope, or a segment once framed. It returns the bytes read, to forward them as they are,
+// and the envelopes completed by them.
+func (r *Reader) Next() ([]byte, []*Envelope, error) {
+	if !r.framed {
+		raw, err := r.readEnvelope()
+		if err != nil {
+			return nil, nil, err
+		}
+		env, _, err := parseEnvelope(raw)
+		if err != nil {
+			return raw, nil, err
+		}
+		return raw, []*Envelope{env}, nil
+	}
+
+	raw, payload, err := r.readSegment()
+	if err != nil {
+		return nil, nil, err
+	}
+	r.buf = append(r.buf, payload...)
+	var envelopes []*Envelope
+	for {
+		env, n, err := parseEnvelope(r.buf)
+		if err != nil {
+			return raw, envelopes, err
+		}
+		if env == nil {
+			break
+		}
+		envelopes = append(envelopes, env)
+		r.buf = r.buf[n:]
+	}
+	if len(r.buf) == 0 {
+		r.buf = nil
+	}
+	return raw, envelopes, nil
+}
+
+func (r *Reader) readEnvelope() ([]byte, error) {
+	header := make([]byte, HeaderLength)
+	_, err := io.ReadFull(r.r, header)
+	if err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[5:])
+	if length > maxEnvelopeLength {
+		return nil, ErrMalformed
+	}
+	raw := make([]byte, HeaderLength+int(length))
+	copy(raw, header)
+	_, err = io.ReadFull(r.r, raw[HeaderLength:])
+	if err == io.EOF {
+		// the connection is closed in the middle of the envelope
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// readSegment reads an uncompressed segment: the length of the payload and the self-contained flag on 3 bytes
+// and their CRC24, followed by the payload and its CRC32.
+func (r *Reader) readSegment() ([]byte, []byte, error) {
+	header := make([]byte, segmentHeaderLength)
+	_, err := io.ReadFull(r.r, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if crc24(header[:3]) != get3b(header[3:]) {
+		return nil, nil, errors.New("invalid crc of the cql segment header, the compressed segments are not supported")
+	}
+	length := int(get3b(header) & maxSegmentPayload)
+	raw := make([]byte, segmentHeaderLength+length+segmentCRCLength)
+	copy(raw, header)
+	_, err = io.ReadFull(r.r, raw[segmentHeaderLength:])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, raw[segmentHeaderLength : segmentHeaderLength+length], nil
+}
+
+// parseEnvelope returns the envelope at the start of the buffer and its length, or nil if the buffer doesn't
+// hold it entirely.
+func parseEnvelope(buf []byte) (*Envelope, int, error) {
+	if len(buf) < HeaderLength {
+		return nil, 0, nil
+	}
+	length := binary.BigEndian.Uint32(buf[5:])
+	if length > maxEnvelopeLength {
+		return nil, 0, ErrMalformed
+	}
+	end := HeaderLength + int(length)
+	if len(buf) < end {
+		return nil, 0, nil
+	}
+	return &Envelope{
+		Version: buf[0],
+		Flags:   buf[1],
+		Stream:  int16(binary.BigEndian.Uint16(buf[2:])),
+		Opcode:  buf[4],
+		Body:    buf[HeaderLength:end:end],
+	}, end, nil
+}
+
+// EncodeSegments frames the envelopes into uncompressed segments. The envelopes fitting in a segment are
+// sent in a self-contained one, the larger ones are split over several segments.
+func EncodeSegments(envelopes []byte) []byte {
+	if len(envelopes) <= maxSegmentPayload {
+		return encodeSegment(envelopes, true)
+	}
+	var buf []byte
+	for len(envelopes) > 0 {
+		n := min(len(envelopes), maxSegmentPayload)
+		buf = append(buf, encodeSegment(envelopes[:n], false)...)
+		envelopes = envelopes[n:]
+	}
+	return buf
+}
+
+func encodeSegment(payload []byte, selfContained bool) []byte {
+	header := uint32(len(payload))
+	if selfContained {
+		header |= selfContainedBit
+	}
+	buf := make([]byte, segmentHeaderLength, segmentHeaderLength+len(payload)+segmentCRCLength)
+	put3b(buf, header)
+	put3b(buf[3:], crc24(buf[:3]))
+	buf = append(buf, payload...)
+	return binary.LittleEndian.AppendUint32(buf, payloadCRC(payload))
+}
+
+// crc24 is the checksum of the segment headers, computed over the little endian bytes of the header.
+func crc24(data []byte) uint32 {
+	crc := uint32(0x875060)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1974F0B
+			}
+		}
+	}
+	return crc
+}
+
+// payloadCRC is the CRC32 of the payload of the segments, seeded with 4 fixed bytes.
+func payloadCRC(payload []byte) uint32 {
+	crc := crc32.ChecksumIEEE([]byte{0xFA, 0x2D, 0x55, 0xCA})
+	return crc32.Update(crc, crc32.IEEETable, payload)
+}
+
+func get3b(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+func put3b(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
@@ -0,0 +1,152 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var nativeTypes = map[uint16]string{
+	0x0001: "ascii",
+	0x0002: "bigint",
+	0x0003: "blob",
+	0x0004: "boolean",
+	0x0005: "counter",
+	0x0006: "decimal",
+	0x0007: "double",
+	0x0008: "float",
+	0x0009: "int",
+	0x000B: "timestamp",
+	0x000C: "uuid",
+	0x000D: "text",
+	0x000E: "varint",
+	0x000F: "timeuuid",
+	0x0010: "inet",
+	0x0011: "date",
+	0x0012: "time",
+	0x0013: "smallint",
+	0x0014: "tinyint",
+	0x0015: "duration",
+}
+
+// maxTypeDepth bounds the nesting of the collections, the tuples and the user types
+const maxTypeDepth = 64
+
+// option decodes the type of a column into its CQL name, e.g. map<text, int>.
+func (r *bodyReader) option() string {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > maxTypeDepth && r.err == nil {
+		r.err = ErrMalformed
+	}
+	id := r.short()
+	if name, ok := nativeTypes[id]; ok {
+		return name
+	}
+	switch id {
+	case 0x0000:
+		return r.string()
+	case 0x0020:
+		return "list<" + r.option() + ">"
+	case 0x0021:
+		key := r.option()
+		return "map<" + key + ", " + r.option() + ">"
+	case 0x0022:
+		return "set<" + r.option() + ">"
+	case 0x0030:
+		keyspace := r.string()
+		name := r.string()
+		n := int(r.short())
+		for i := 0; i < n && r.err == nil; i++ {
+			r.string()
+			r.option()
+		}
+		return keyspace + "." + name
+	case 0x0031:
+		n := int(r.short())
+		types := make([]string, 0, n)
+		for i := 0; i < n && r.err == nil; i++ {
+			types = append(types, r.option())
+		}
+		return "tuple<" + strings.Join(types, ", ") + ">"
+	}
+	if r.err == nil {
+		r.err = fmt.Errorf("unknown cql type 0x%04x", id)
+	}
+	return ""
+}
+
+// FormatValue returns the text of a value of the given type. The values of the collections, the user types
+// and the unknown types are returned as base64.
+func FormatValue(typ string, raw []byte) string {
+	switch typ {
+	case "ascii", "text", "varchar":
+		return string(raw)
+	case "boolean":
+		if len(raw) == 1 {
+			return strconv.FormatBool(raw[0] != 0)
+		}
+	case "tinyint":
+		if len(raw) == 1 {
+			return strconv.Itoa(int(int8(raw[0])))
+		}
+	case "smallint":
+		if len(raw) == 2 {
+			return strconv.Itoa(int(int16(binary.BigEndian.Uint16(raw))))
+		}
+	case "int":
+		if len(raw) == 4 {
+			return strconv.Itoa(int(int32(binary.BigEndian.Uint32(raw))))
+		}
+	case "bigint", "counter", "time":
+		if len(raw) == 8 {
+			return strconv.FormatInt(int64(binary.BigEndian.Uint64(raw)), 10)
+		}
+	case "float":
+		if len(raw) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), 'g', -1, 32)
+		}
+	case "double":
+		if len(raw) == 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(raw)), 'g', -1, 64)
+		}
+	case "timestamp":
+		if len(raw) == 8 {
+			return time.UnixMilli(int64(binary.BigEndian.Uint64(raw))).UTC().Format(time.RFC3339Nano)
+		}
+	case "date":
+		// the days since the epoch, centered on 2^31
+		if len(raw) == 4 {
+			days := int64(binary.BigEndian.Uint32(raw)) - 1<<31
+			return time.Unix(days*86400, 0).UTC().Format(time.DateOnly)
+		}
+	case "uuid", "timeuuid":
+		if len(raw) == 16 {
+			return fmt.Sprintf("%x-%x-%x-%x-%x", raw[0:4], raw[4:6], raw[6:8], raw[8:10], raw[10:16])
+		}
+	case "inet":
+		if len(raw) == 4 || len(raw) == 16 {
+			return net.IP(raw).String()
+		}
+	case "varint":
+		return varint(raw).String()
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// varint decodes the two's complement big-endian integer.
+func varint(raw []byte) *big.Int {
+	v := new(big.Int).SetBytes(raw)
+	if len(raw) > 0 && raw[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(raw))*8))
+	}
+	return v
+}
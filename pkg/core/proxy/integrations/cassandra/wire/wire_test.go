@@ -0,0 +1,529 @@
+//go:build linux
+
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util/wiretest"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// appendInt appends the signed [int] of CQL, whose -1 stands for the null values and the unset lengths.
+func appendInt(b []byte, v int32) []byte {
+	return wiretest.AppendUint32(b, uint32(v))
+}
+
+func appendBytes(b []byte, v []byte) []byte {
+	if v == nil {
+		return appendInt(b, -1)
+	}
+	return wiretest.AppendLongBytes(b, v)
+}
+
+func intValue(v int32) []byte {
+	return appendInt(nil, v)
+}
+
+func lookupID(prepared *Prepared) PreparedLookup {
+	return func(id string) *Prepared {
+		if id == "abcd" {
+			return prepared
+		}
+		return nil
+	}
+}
+
+var preparedSelect = &Prepared{
+	Query:     "SELECT * FROM users WHERE id = ?",
+	Variables: []models.CassandraColumn{{Name: "id", Type: "int"}},
+}
+
+func queryBody() []byte {
+	body := wiretest.AppendLongBytes(nil, []byte("SELECT * FROM users"))
+	body = wiretest.AppendUint16(body, 1)
+	body = append(body, queryValues|queryPageSize)
+	body = wiretest.AppendUint16(body, 2)
+	body = appendBytes(body, intValue(42))
+	body = appendBytes(body, nil)
+	return appendInt(body, 100)
+}
+
+func executeBody(version byte) []byte {
+	body := wiretest.AppendUint16(nil, 2)
+	body = append(body, 0xab, 0xcd)
+	if version >= 5 {
+		body = wiretest.AppendUint16(body, 1)
+		body = append(body, 0x01)
+	}
+	body = wiretest.AppendUint16(body, 4)
+	if version >= 5 {
+		body = appendInt(body, queryValues)
+	} else {
+		body = append(body, queryValues)
+	}
+	body = wiretest.AppendUint16(body, 1)
+	return appendBytes(body, intValue(7))
+}
+
+func batchBody() []byte {
+	body := []byte{1}
+	body = wiretest.AppendUint16(body, 2)
+	body = append(body, 0)
+	body = wiretest.AppendLongBytes(body, []byte("INSERT INTO users (id) VALUES (1)"))
+	body = wiretest.AppendUint16(body, 0)
+	body = append(body, 1)
+	body = wiretest.AppendUint16(body, 2)
+	body = append(body, 0xab, 0xcd)
+	body = wiretest.AppendUint16(body, 1)
+	body = appendBytes(body, intValue(7))
+	body = wiretest.AppendUint16(body, 1)
+	return append(body, 0)
+}
+
+func startupBody() []byte {
+	body := wiretest.AppendUint16(nil, 1)
+	body = appendString(body, "CQL_VERSION")
+	return appendString(body, "3.0.0")
+}
+
+func TestDecodeRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		env  *Envelope
+		want *models.CassandraRequest
+	}{
+		{
+			name: "query",
+			env:  &Envelope{Version: 4, Stream: 1, Opcode: OpQuery, Body: queryBody()},
+			want: &models.CassandraRequest{
+				Version: 4, Opcode: "QUERY", StreamID: 1, Query: "SELECT * FROM users", Consistency: "ONE", PageSize: 100,
+				Values: []models.CassandraValue{{Value: "AAAAKg=="}, {Null: true}},
+			},
+		},
+		{
+			name: "execute",
+			env:  &Envelope{Version: 4, Stream: 2, Opcode: OpExecute, Body: executeBody(4)},
+			want: &models.CassandraRequest{
+				Version: 4, Opcode: "EXECUTE", StreamID: 2, Query: preparedSelect.Query, PreparedID: "abcd", Consistency: "QUORUM",
+				Values: []models.CassandraValue{{Type: "int", Value: "7"}},
+			},
+		},
+		{
+			name: "execute of v5",
+			env:  &Envelope{Version: 5, Stream: 2, Opcode: OpExecute, Body: executeBody(5)},
+			want: &models.CassandraRequest{
+				Version: 5, Opcode: "EXECUTE", StreamID: 2, Query: preparedSelect.Query, PreparedID: "abcd", Consistency: "QUORUM",
+				Values: []models.CassandraValue{{Type: "int", Value: "7"}},
+			},
+		},
+		{
+			name: "batch",
+			env:  &Envelope{Version: 4, Stream: 3, Opcode: OpBatch, Body: batchBody()},
+			want: &models.CassandraRequest{
+				Version: 4, Opcode: "BATCH", StreamID: 3, BatchType: "UNLOGGED", Consistency: "ONE",
+				Statements: []models.CassandraStatement{
+					{Query: "INSERT INTO users (id) VALUES (1)", Values: []models.CassandraValue{}},
+					{Query: preparedSelect.Query, PreparedID: "abcd", Values: []models.CassandraValue{{Type: "int", Value: "7"}}},
+				},
+			},
+		},
+		{
+			name: "startup",
+			env:  &Envelope{Version: 4, Opcode: OpStartup, Body: startupBody()},
+			want: &models.CassandraRequest{Version: 4, Opcode: "STARTUP", Options: map[string]string{"CQL_VERSION": "3.0.0"}},
+		},
+		{
+			name: "compressed",
+			env:  &Envelope{Version: 4, Flags: FlagCompression, Opcode: OpQuery, Body: []byte{1, 2, 3}},
+			want: &models.CassandraRequest{Version: 4, Opcode: "QUERY", Body: "AQID"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeRequest(tt.env, lookupID(preparedSelect))
+			if err != nil {
+				t.Fatalf("failed to decode the request: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// rowsBody is a ROWS result of the columns id int and tags list<text>, with a row of 7 and null.
+func rowsBody() []byte {
+	body := appendInt(nil, resultRows)
+	body = appendInt(body, metadataGlobalTableSpec)
+	body = appendInt(body, 2)
+	body = appendString(body, "ks")
+	body = appendString(body, "users")
+	body = appendString(body, "id")
+	body = wiretest.AppendUint16(body, 0x0009)
+	body = appendString(body, "tags")
+	body = wiretest.AppendUint16(body, 0x0020)
+	body = wiretest.AppendUint16(body, 0x000D)
+	body = appendInt(body, 1)
+	body = appendBytes(body, intValue(7))
+	return appendBytes(body, nil)
+}
+
+func preparedBody() []byte {
+	body := appendInt(nil, resultPrepared)
+	body = wiretest.AppendUint16(body, 2)
+	body = append(body, 0xab, 0xcd)
+	body = appendInt(body, metadataGlobalTableSpec)
+	body = appendInt(body, 1)
+	body = appendInt(body, 1)
+	body = wiretest.AppendUint16(body, 0)
+	body = appendString(body, "ks")
+	body = appendString(body, "users")
+	body = appendString(body, "id")
+	body = wiretest.AppendUint16(body, 0x0009)
+	body = appendInt(body, metadataNoMetadata)
+	return appendInt(body, 0)
+}
+
+func TestDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          *Envelope
+		want         *models.CassandraResponse
+		wantPrepared *Prepared
+	}{
+		{
+			name: "rows",
+			env:  &Envelope{Version: 4 | responseBit, Opcode: OpResult, Body: rowsBody()},
+			want: &models.CassandraResponse{
+				Opcode: "RESULT", Kind: "Rows",
+				Columns: []models.CassandraColumn{{Name: "id", Type: "int"}, {Name: "tags", Type: "list<text>"}},
+				Rows:    [][]string{{"7", "null"}},
+			},
+		},
+		{
+			name: "prepared",
+			env:  &Envelope{Version: 4 | responseBit, Opcode: OpResult, Body: preparedBody()},
+			want: &models.CassandraResponse{
+				Opcode: "RESULT", Kind: "Prepared", PreparedID: "abcd",
+				Variables: []models.CassandraColumn{{Name: "id", Type: "int"}},
+			},
+			wantPrepared: &Prepared{Variables: []models.CassandraColumn{{Name: "id", Type: "int"}}},
+		},
+		{
+			name: "void",
+			env:  &Envelope{Version: 4 | responseBit, Opcode: OpResult, Body: appendInt(nil, resultVoid)},
+			want: &models.CassandraResponse{Opcode: "RESULT", Kind: "Void"},
+		},
+		{
+			name: "error",
+			env:  &Envelope{Version: 4 | responseBit, Opcode: OpError, Body: appendString(appendInt(nil, 0x2200), "bad query")},
+			want: &models.CassandraResponse{Opcode: "ERROR", ErrorCode: 0x2200, Message: "bad query"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, prepared, err := DecodeResponse(tt.env)
+			if err != nil {
+				t.Fatalf("failed to decode the response: %v", err)
+			}
+			// the body is checked by the round trip
+			got.Body = ""
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(prepared, tt.wantPrepared) {
+				t.Errorf("got the prepared statement %+v, want %+v", prepared, tt.wantPrepared)
+			}
+		})
+	}
+}
+
+func TestEncodeResponseRoundTrip(t *testing.T) {
+	recorded := &Envelope{Version: 4 | responseBit, Stream: 1, Opcode: OpResult, Body: rowsBody()}
+	resp, _, err := DecodeResponse(recorded)
+	if err != nil {
+		t.Fatalf("failed to decode the response: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		buf  func() ([]byte, error)
+		want *Envelope
+	}{
+		{
+			name: "recorded response",
+			buf:  func() ([]byte, error) { return EncodeResponse(resp, 4, 9) },
+			want: &Envelope{Version: 4 | responseBit, Stream: 9, Opcode: OpResult, Body: rowsBody()},
+		},
+		{
+			name: "error",
+			buf:  func() ([]byte, error) { return EncodeError(5, 2, ErrServer, "no mock"), nil },
+			want: &Envelope{Version: 5 | responseBit, Stream: 2, Opcode: OpError, Body: appendString(appendInt(nil, ErrServer), "no mock")},
+		},
+		{
+			name: "unprepared",
+			buf:  func() ([]byte, error) { return EncodeUnprepared(4, 3, "abcd"), nil },
+			want: &Envelope{Version: 4 | responseBit, Stream: 3, Opcode: OpError, Body: append(wiretest.AppendUint16(appendString(appendInt(nil, ErrUnprepared), "Prepared query with ID abcd not found"), 2), 0xab, 0xcd)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := tt.buf()
+			if err != nil {
+				t.Fatalf("failed to encode the response: %v", err)
+			}
+			env, n, err := parseEnvelope(buf)
+			if err != nil || env == nil || n != len(buf) {
+				t.Fatalf("failed to parse the envelope: %v", err)
+			}
+			if !reflect.DeepEqual(env, tt.want) {
+				t.Errorf("got %+v, want %+v", env, tt.want)
+			}
+			if !env.IsResponse() {
+				t.Error("the envelope is not a response")
+			}
+		})
+	}
+
+	_, err = EncodeResponse(&models.CassandraResponse{Opcode: "UNKNOWN"}, 4, 1)
+	if err == nil {
+		t.Error("expected an error for an unknown opcode")
+	}
+	_, err = EncodeResponse(&models.CassandraResponse{Opcode: "RESULT", Body: "!"}, 4, 1)
+	if err == nil {
+		t.Error("expected an error for an invalid body")
+	}
+}
+
+func TestReader(t *testing.T) {
+	query := (&Envelope{Version: 5, Stream: 1, Opcode: OpQuery, Body: queryBody()}).Encode()
+	options := (&Envelope{Version: 5, Stream: 2, Opcode: OpOptions}).Encode()
+	large := (&Envelope{Version: 5, Stream: 3, Opcode: OpQuery, Body: bytes.Repeat([]byte{'a'}, maxSegmentPayload+10)}).Encode()
+
+	tests := []struct {
+		name   string
+		framed bool
+		stream []byte
+		// want is the number of envelopes completed by each read
+		want []int
+	}{
+		{name: "envelopes", stream: append(bytes.Clone(query), options...), want: []int{1, 1}},
+		{name: "self-contained segment", framed: true, stream: EncodeSegments(append(bytes.Clone(query), options...)), want: []int{2}},
+		{name: "envelope split over segments", framed: true, stream: EncodeSegments(large), want: []int{0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(bufio.NewReader(bytes.NewReader(tt.stream)))
+			if tt.framed {
+				r.SetFramed()
+			}
+			var read []byte
+			var got []int
+			for {
+				raw, envelopes, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("failed to read: %v", err)
+				}
+				read = append(read, raw...)
+				got = append(got, len(envelopes))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v envelopes per read, want %v", got, tt.want)
+			}
+			if !bytes.Equal(read, tt.stream) {
+				t.Error("the bytes read differ from the stream")
+			}
+		})
+	}
+}
+
+func TestReaderMalformed(t *testing.T) {
+	query := (&Envelope{Version: 4, Stream: 1, Opcode: OpQuery, Body: queryBody()}).Encode()
+	tooLong := bytes.Clone(query)
+	binary.BigEndian.PutUint32(tooLong[5:], maxEnvelopeLength+1)
+	segments := EncodeSegments(query)
+	badCRC := bytes.Clone(segments)
+	badCRC[3] ^= 0xff
+	badLength := EncodeSegments(tooLong)
+
+	tests := []struct {
+		name   string
+		framed bool
+		stream []byte
+	}{
+		{name: "envelope longer than the maximum", stream: tooLong},
+		{name: "invalid crc of the segment header", framed: true, stream: badCRC},
+		{name: "framed envelope longer than the maximum", framed: true, stream: badLength},
+	}
+	for i := 1; i < len(query); i++ {
+		tests = append(tests, struct {
+			name   string
+			framed bool
+			stream []byte
+		}{name: "truncated envelope", stream: query[:i]})
+	}
+	for i := 1; i < len(segments); i++ {
+		tests = append(tests, struct {
+			name   string
+			framed bool
+			stream []byte
+		}{name: "truncated segment", framed: true, stream: segments[:i]})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(bufio.NewReader(bytes.NewReader(tt.stream)))
+			if tt.framed {
+				r.SetFramed()
+			}
+			for {
+				_, _, err := r.Next()
+				if err != nil {
+					if err == io.EOF {
+						t.Fatal("expected an error other than EOF")
+					}
+					return
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeTruncated decodes every prefix of the bodies, a truncated body must fail without panicking.
+func TestDecodeTruncated(t *testing.T) {
+	requests := []*Envelope{
+		{Version: 4, Opcode: OpQuery, Body: queryBody()},
+		{Version: 4, Opcode: OpExecute, Body: executeBody(4)},
+		{Version: 5, Opcode: OpExecute, Body: executeBody(5)},
+		{Version: 4, Opcode: OpBatch, Body: batchBody()},
+		{Version: 4, Opcode: OpStartup, Body: startupBody()},
+	}
+	for _, env := range requests {
+		t.Run("request "+OpcodeName(env.Opcode), func(t *testing.T) {
+			for i := range env.Body {
+				truncated := *env
+				truncated.Body = env.Body[:i]
+				_, err := DecodeRequest(&truncated, lookupID(preparedSelect))
+				if err == nil {
+					t.Fatalf("expected an error for the body truncated to %d bytes", i)
+				}
+			}
+		})
+	}
+
+	responses := map[string][]byte{"rows": rowsBody(), "prepared": preparedBody()}
+	for name, body := range responses {
+		t.Run("response "+name, func(t *testing.T) {
+			for i := range body {
+				_, _, err := DecodeResponse(&Envelope{Version: 4 | responseBit, Opcode: OpResult, Body: body[:i]})
+				if err == nil {
+					t.Fatalf("expected an error for the body truncated to %d bytes", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeResponseMalformed(t *testing.T) {
+	nested := appendInt(nil, resultRows)
+	nested = appendInt(nested, metadataGlobalTableSpec)
+	nested = appendInt(nested, 1)
+	nested = appendString(nested, "ks")
+	nested = appendString(nested, "users")
+	nested = appendString(nested, "tags")
+	for i := 0; i < 100000; i++ {
+		nested = wiretest.AppendUint16(nested, 0x0020)
+	}
+	nested = wiretest.AppendUint16(nested, 0x000D)
+
+	manyRows := appendInt(nil, resultRows)
+	manyRows = appendInt(manyRows, metadataGlobalTableSpec)
+	manyRows = appendInt(manyRows, 1)
+	manyRows = appendString(manyRows, "ks")
+	manyRows = appendString(manyRows, "users")
+	manyRows = appendString(manyRows, "id")
+	manyRows = wiretest.AppendUint16(manyRows, 0x0009)
+	manyRows = appendInt(manyRows, 1<<31-1)
+
+	manyColumns := appendInt(nil, resultRows)
+	manyColumns = appendInt(manyColumns, 0)
+	manyColumns = appendInt(manyColumns, 1<<31-1)
+
+	negativeColumns := appendInt(nil, resultRows)
+	negativeColumns = appendInt(negativeColumns, 0)
+	negativeColumns = appendInt(negativeColumns, -5)
+	negativeColumns = appendInt(negativeColumns, 0)
+
+	unknownType := appendInt(nil, resultRows)
+	unknownType = appendInt(unknownType, metadataGlobalTableSpec)
+	unknownType = appendInt(unknownType, 1)
+	unknownType = appendString(unknownType, "ks")
+	unknownType = appendString(unknownType, "users")
+	unknownType = appendString(unknownType, "id")
+	unknownType = wiretest.AppendUint16(unknownType, 0x0099)
+
+	tests := []struct {
+		name    string
+		body    []byte
+		wantErr bool
+	}{
+		{name: "types nested too deeply", body: nested, wantErr: true},
+		{name: "row count above the body", body: manyRows, wantErr: true},
+		{name: "column count above the body", body: manyColumns, wantErr: true},
+		{name: "negative column count", body: negativeColumns},
+		{name: "unknown column type", body: unknownType, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := DecodeResponse(&Envelope{Version: 4 | responseBit, Opcode: OpResult, Body: tt.body})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got the error %v, want an error: %t", err, tt.wantErr)
+			}
+			if err != nil && tt.name == "row count above the body" && !errors.Is(err, ErrMalformed) {
+				t.Errorf("got the error %v, want %v", err, ErrMalformed)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		typ  string
+		raw  []byte
+		want string
+	}{
+		{typ: "int", raw: intValue(-7), want: "-7"},
+		{typ: "boolean", raw: []byte{1}, want: "true"},
+		{typ: "varint", raw: []byte{0xff, 0x00}, want: "-256"},
+		{typ: "uuid", raw: bytes.Repeat([]byte{0xab}, 16), want: "abababab-abab-abab-abab-abababababab"},
+		{typ: "inet", raw: []byte{127, 0, 0, 1}, want: "127.0.0.1"},
+		{typ: "date", raw: appendInt(nil, -1<<31+1), want: "1970-01-02"},
+		// the values of an unexpected length are kept as base64
+		{typ: "int", raw: []byte{1}, want: "AQ=="},
+		{typ: "double", raw: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			got := FormatValue(tt.typ, tt.raw)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
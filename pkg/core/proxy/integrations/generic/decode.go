@@ -17,7 +17,7 @@ import (
 	"go.uber.org/zap"
 )
 
-func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
 	genericRequests := [][]byte{reqBuf}
 	logger.Debug("Into the generic parser in test mode")
 	errCh := make(chan error, 1)
@@ -60,12 +60,25 @@ func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clien
 			}
 
 			if !matched {
+				mockDb.RecordUnmatched(models.UnmatchedCall{
+					Kind:        models.GENERIC,
+					Destination: dstCfg.Addr,
+					Summary:     pUtil.Summarize(genericRequests),
+				})
 				err := clientConn.SetReadDeadline(time.Time{})
 				if err != nil {
 					utils.LogError(logger, err, "failed to set the read deadline for the client conn")
 					return
 				}
 
+				if opts.RecordOnMiss {
+					err = pUtil.RecordOnMiss(ctx, logger, clientConn, dstCfg, genericRequests, mockDb, opts, encodeGeneric)
+					if err != nil {
+						utils.LogError(logger, err, "failed to record the generic requests on a miss")
+					}
+					return
+				}
+
 				logger.Debug("the genericRequests before pass through are", zap.Any("length", len(genericRequests)))
 				for _, genReq := range genericRequests {
 					logger.Debug("the genericRequests are:", zap.Any("h", string(genReq)))
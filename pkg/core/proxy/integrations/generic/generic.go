@@ -15,6 +15,7 @@ import (
 
 func init() {
 	integrations.Register("generic", NewGeneric)
+	integrations.RegisterMissRecorder("generic")
 }
 
 type Generic struct {
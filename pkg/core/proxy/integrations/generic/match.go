@@ -26,7 +26,7 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetUnFilteredMocks()
+			mocks, err := mockDb.GetUnFilteredMocksByKind(models.GENERIC)
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
 			}
@@ -35,9 +35,6 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 			var unfilteredMocks []*models.Mock
 
 			for _, mock := range mocks {
-				if mock.Kind != "Generic" {
-					continue
-				}
 				if mock.TestModeInfo.IsFiltered {
 					filteredMocks = append(filteredMocks, mock)
 				} else {
@@ -67,7 +67,7 @@ func transferFrame(ctx context.Context, lhs net.Conn, rhs net.Conn, sic *StreamI
 				if err != nil {
 					return fmt.Errorf("could not write headers frame: %v", err)
 				}
-				pseudoHeaders, ordinaryHeaders, err := extractHeaders(headersFrame, decoder)
+				pseudoHeaders, ordinaryHeaders, err := ExtractHeaders(headersFrame, decoder)
 				if err != nil {
 					return fmt.Errorf("could not extract headers from frame: %v", err)
 				}
@@ -168,7 +168,8 @@ const (
 	KmaxDynamicTableSize = 2048
 )
 
-func extractHeaders(frame *http2.HeadersFrame, decoder *hpack.Decoder) (pseudoHeaders, ordinaryHeaders map[string]string, err error) {
+// ExtractHeaders decodes the header block of the frame into pseudo and ordinary headers.
+func ExtractHeaders(frame *http2.HeadersFrame, decoder *hpack.Decoder) (pseudoHeaders, ordinaryHeaders map[string]string, err error) {
 	hf, err := decoder.DecodeFull(frame.HeaderBlockFragment())
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not decode headers: %v", err)
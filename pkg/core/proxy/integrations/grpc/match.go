@@ -38,7 +38,7 @@ func FilterMocksBasedOnGrpcRequest(ctx context.Context, _ *zap.Logger, grpcReq m
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetFilteredMocks()
+			mocks, err := mockDb.GetFilteredMocksByKind(models.GRPC_EXPORT)
 			if err != nil {
 				return nil, fmt.Errorf("error while getting tsc mocks %v", err)
 			}
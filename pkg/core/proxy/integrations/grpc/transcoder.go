@@ -7,7 +7,9 @@ import (
 	"context"
 	"fmt"
 
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 
 	"go.uber.org/zap"
@@ -85,6 +87,11 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 		return fmt.Errorf("failed match mocks: %v", err)
 	}
 	if mock == nil {
+		srv.mockDb.RecordUnmatched(models.UnmatchedCall{
+			Kind:        models.GRPC_EXPORT,
+			Destination: grpcReq.Headers.PseudoHeaders[":authority"],
+			Summary:     grpcReq.Headers.PseudoHeaders[":path"],
+		})
 		return fmt.Errorf("failed to mock the output for unrecorded outgoing grpc call")
 	}
 
@@ -129,7 +136,7 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 		return err
 	}
 
-	payload, err := createPayloadFromLengthPrefixedMessage(grpcMockResp.Body)
+	payload, err := pkg.CreatePayloadFromLengthPrefixedMessage(grpcMockResp.Body)
 	if err != nil {
 		utils.LogError(srv.logger, err, "could not create grpc payload from mocks")
 		return err
@@ -228,7 +235,7 @@ func (srv *Transcoder) ProcessHeadersFrame(headersFrame *http2.HeadersFrame) err
 		return http2.ConnectionError(http2.ErrCodeProtocol)
 	}
 
-	pseudoHeaders, ordinaryHeaders, err := extractHeaders(headersFrame, srv.decoder)
+	pseudoHeaders, ordinaryHeaders, err := ExtractHeaders(headersFrame, srv.decoder)
 	if err != nil {
 		utils.LogError(srv.logger, err, "could not extract headers from frame")
 	}
@@ -98,6 +98,15 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 			if !ok {
 				if !IsPassThrough(logger, request, dstCfg.Port, opts) {
 					utils.LogError(logger, nil, "Didn't match any preExisting http mock", zap.Any("metadata", getReqMeta(request)))
+					mockDb.RecordUnmatched(models.UnmatchedCall{
+						Kind:        models.HTTP,
+						Destination: dstCfg.Addr,
+						Summary:     request.Method + " " + request.Host + request.URL.RequestURI(),
+					})
+				}
+				if opts.RecordOnMiss && !IsPassThrough(logger, request, dstCfg.Port, opts) {
+					errCh <- pUtil.RecordOnMiss(ctx, logger, clientConn, dstCfg, [][]byte{reqBuf}, mockDb, opts, encodeHTTP)
+					return
 				}
 				if opts.FallBackOnMiss {
 					_, err = pUtil.PassThrough(ctx, logger, clientConn, dstCfg, [][]byte{reqBuf})
@@ -111,6 +120,11 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 				return
 			}
 
+			if len(stub.Spec.WebSocket) > 0 || stub.Spec.HTTPResp.StatusCode == http.StatusSwitchingProtocols {
+				errCh <- mockWebSocket(ctx, logger, clientConn, request, stub, dstCfg, mockDb)
+				return
+			}
+
 			statusLine := fmt.Sprintf("HTTP/%d.%d %d %s\r\n", stub.Spec.HTTPReq.ProtoMajor, stub.Spec.HTTPReq.ProtoMinor, stub.Spec.HTTPResp.StatusCode, http.StatusText(stub.Spec.HTTPResp.StatusCode))
 
 			body := stub.Spec.HTTPResp.Body
@@ -27,7 +27,7 @@ func encodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 	destPort := uint(remoteAddr.Port)
 
 	//Writing the request to the server.
-	_, err := destConn.Write(reqBuf)
+	_, err := destConn.Write(withoutWebSocketExtensions(reqBuf))
 	if err != nil {
 		utils.LogError(logger, err, "failed to write request message to the destination server")
 		return err
@@ -213,6 +213,12 @@ func encodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 				resTimestampMock: resTimestampMock,
 			}
 
+			// the connection isn't http anymore once upgraded to a websocket
+			if isWebSocketHandshake(finalReq, finalResp) {
+				errCh <- recordWebSocket(ctx, logger, m, clientConn, destConn, destPort, mocks, opts)
+				return nil
+			}
+
 			err = ParseFinalHTTP(ctx, logger, m, destPort, mocks, opts)
 			if err != nil {
 				utils.LogError(logger, err, "failed to parse the final http request and response")
@@ -236,7 +242,7 @@ func encodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 				return nil
 			}
 			// write the request message to the actual destination server
-			_, err = destConn.Write(finalReq)
+			_, err = destConn.Write(withoutWebSocketExtensions(finalReq))
 			if err != nil {
 				if ctx.Err() != nil {
 					return ctx.Err()
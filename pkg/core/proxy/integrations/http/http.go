@@ -25,6 +25,8 @@ import (
 
 func init() {
 	integrations.Register("http", NewHTTP)
+	integrations.RegisterMissRecorder("http")
+	integrations.RegisterMockKey(models.HTTP, mockKey)
 }
 
 type HTTP struct {
@@ -43,6 +45,8 @@ type finalHTTP struct {
 	resp             []byte
 	reqTimestampMock time.Time
 	resTimestampMock time.Time
+	// the messages exchanged after the response, if it upgraded the connection to a websocket
+	webSocket []models.WebSocketMessage
 }
 
 // MatchType function determines if the outgoing network call is HTTP by comparing the
@@ -185,6 +189,7 @@ func ParseFinalHTTP(_ context.Context, logger *zap.Logger, mock *finalHTTP, dest
 				Header:     pkg.ToYamlHTTPHeader(respParsed.Header),
 				Body:       string(respBody),
 			},
+			WebSocket:        mock.webSocket,
 			Created:          time.Now().Unix(),
 			ReqTimestampMock: mock.resTimestampMock,
 			ResTimestampMock: mock.resTimestampMock,
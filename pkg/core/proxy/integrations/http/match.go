@@ -8,7 +8,6 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"math"
 	"net/http"
 	"net/url"
 	"strings"
@@ -35,14 +34,8 @@ func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrati
 			return false, nil, ctx.Err()
 		}
 
-		mocks, err := mockDb.GetUnFilteredMocks()
-		var unfilteredMocks []*models.Mock
-		for _, mock := range mocks {
-			if mock.Kind != "Http" {
-				continue
-			}
-			unfilteredMocks = append(unfilteredMocks, mock)
-		}
+		// the method and the path have to be the same, only the mocks having them are looked up
+		unfilteredMocks, err := mockDb.GetUnFilteredMocksByKey(models.HTTP, requestKey(input.method, input.url.Path))
 		if err != nil {
 			utils.LogError(logger, err, "failed to get unfilteredMocks mocks")
 			return false, nil, errors.New("error while matching the request with the mocks")
@@ -377,21 +370,23 @@ func isCSV(data []byte) bool {
 }
 
 // updateMock processes the matched mock based on its filtered status.
-func updateMock(_ context.Context, logger *zap.Logger, matchedMock *models.Mock, mockDb integrations.MockMemDb) bool {
-	if matchedMock.TestModeInfo.IsFiltered {
-		originalMatchedMock := *matchedMock
-		matchedMock.TestModeInfo.IsFiltered = false
-		matchedMock.TestModeInfo.SortOrder = math.MaxInt
-		//UpdateUnFilteredMock also marks the mock as used
-		updated := mockDb.UpdateUnFilteredMock(&originalMatchedMock, matchedMock)
-		return updated
-	}
+func updateMock(_ context.Context, _ *zap.Logger, matchedMock *models.Mock, mockDb integrations.MockMemDb) bool {
+	// a filtered mock is consumed, the others are only flagged as used
+	return mockDb.ConsumeMock(matchedMock)
+}
 
-	// we don't update the mock if the IsFiltered is false
-	err := mockDb.FlagMockAsUsed(*matchedMock)
+// mockKey returns the method and the path of the mocked request, on which the http mocks are indexed.
+func mockKey(mock *models.Mock) string {
+	if mock.Spec.HTTPReq == nil {
+		return ""
+	}
+	parsedURL, err := url.Parse(mock.Spec.HTTPReq.URL)
 	if err != nil {
-		logger.Error("failed to flag mock as used", zap.Error(err))
+		return ""
 	}
+	return requestKey(string(mock.Spec.HTTPReq.Method), parsedURL.Path)
+}
 
-	return true
+func requestKey(method, path string) string {
+	return method + " " + path
 }
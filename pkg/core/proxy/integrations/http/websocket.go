@@ -0,0 +1,321 @@
+//go:build linux
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// closeTimeout is how long the close of a side of a websocket connection waits for the close of the other.
+const closeTimeout = 5 * time.Second
+
+// webSocketLog is the log of the messages of a websocket connection, appended by both of its sides.
+type webSocketLog struct {
+	mu       sync.Mutex
+	messages []models.WebSocketMessage
+	err      error
+}
+
+// isWebSocketHandshake checks whether the request is an upgrade to a websocket which the response accepts.
+func isWebSocketHandshake(reqBuf, respBuf []byte) bool {
+	if !bytes.HasPrefix(respBuf, []byte("HTTP/1.1 101")) {
+		return false
+	}
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(reqBuf)))
+	if err != nil {
+		return false
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(respBuf)), req)
+	if err != nil {
+		return false
+	}
+	return pkg.IsWebSocketUpgrade(req.Header) && pkg.IsWebSocketUpgrade(resp.Header)
+}
+
+// withoutWebSocketExtensions removes the extensions the client offers in its upgrade to a websocket, so that
+// the messages aren't compressed and can be recorded. Any other request is returned as is.
+func withoutWebSocketExtensions(reqBuf []byte) []byte {
+	end := bytes.Index(reqBuf, []byte("\r\n\r\n"))
+	if end < 0 || !bytes.Contains(bytes.ToLower(reqBuf[:end]), []byte("sec-websocket-extensions:")) {
+		return reqBuf
+	}
+	lines := bytes.Split(reqBuf[:end], []byte("\r\n"))
+	kept := make([][]byte, 0, len(lines))
+	for _, line := range lines {
+		if bytes.HasPrefix(bytes.ToLower(line), []byte("sec-websocket-extensions:")) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	out := bytes.Join(kept, []byte("\r\n"))
+	return append(out, reqBuf[end:]...)
+}
+
+// recordWebSocket forwards the frames of the connection upgraded by the handshake in both directions. Once the
+// connection is closed, or the recording stops, the handshake is recorded as a mock along with the log of the
+// messages exchanged.
+func recordWebSocket(ctx context.Context, logger *zap.Logger, handshake *finalHTTP, clientConn, destConn net.Conn, destPort uint, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
+	// the frames read along with the response of the handshake have been forwarded already
+	end := bytes.Index(handshake.resp, []byte("\r\n\r\n")) + 4
+	leftover := handshake.resp[end:]
+	handshake.resp = handshake.resp[:end]
+
+	err := clientConn.SetReadDeadline(time.Time{})
+	if err != nil {
+		utils.LogError(logger, err, "failed to reset the read deadline of the client conn")
+		return err
+	}
+	err = destConn.SetReadDeadline(time.Time{})
+	if err != nil {
+		utils.LogError(logger, err, "failed to reset the read deadline of the destination conn")
+		return err
+	}
+
+	log := &webSocketLog{}
+	done := make(chan error, 2)
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		done <- log.forward(models.WebSocketFromClient, clientConn, destConn)
+		return nil
+	})
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		done <- log.forward(models.WebSocketFromServer, io.MultiReader(bytes.NewReader(leftover), destConn), &skipWriter{w: clientConn, skip: len(leftover)})
+		return nil
+	})
+
+	// the messages exchanged so far are recorded as well when the recording stops on a live connection
+	select {
+	case <-ctx.Done():
+	case err = <-done:
+		if err == nil {
+			// a side sent its close, the other one answers it with its own
+			select {
+			case <-ctx.Done():
+			case <-done:
+			case <-time.After(closeTimeout):
+			}
+		}
+	}
+
+	messages, err := log.result()
+	if err != nil {
+		logger.Warn("failed to record the websocket connection, its messages were forwarded without being recorded", zap.Error(err))
+		return nil
+	}
+	handshake.webSocket = messages
+	handshake.resTimestampMock = time.Now()
+	return ParseFinalHTTP(ctx, logger, handshake, destPort, mocks, opts)
+}
+
+// forward reads the frames of a side of the connection until its close, and forwards them to the other side.
+// Each frame is logged before it is forwarded, so that the answer of the other side follows it in the log. The
+// frames following a frame which can't be recorded are still forwarded.
+func (l *webSocketLog) forward(from string, r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	messages := pkg.NewWebSocketMessages(from)
+	var raw bytes.Buffer
+	for {
+		raw.Reset()
+		frame, err := pkg.ReadWebSocketFrame(io.TeeReader(reader, &raw))
+		if err != nil {
+			_, _ = w.Write(raw.Bytes())
+			return err
+		}
+		msg, err := messages.Add(frame)
+		if err != nil {
+			l.fail(err)
+			_, err = w.Write(raw.Bytes())
+			if err == nil {
+				_, err = io.Copy(w, reader)
+			}
+			if err == nil {
+				err = io.EOF
+			}
+			return err
+		}
+		if msg != nil {
+			l.add(*msg)
+		}
+		_, err = w.Write(raw.Bytes())
+		if err != nil {
+			return err
+		}
+		if msg != nil && msg.Type == models.WebSocketClose {
+			// the close is the last frame a side sends
+			return nil
+		}
+	}
+}
+
+// skipWriter drops the first bytes written to it, the ones which were already forwarded.
+type skipWriter struct {
+	w    io.Writer
+	skip int
+}
+
+func (s *skipWriter) Write(p []byte) (int, error) {
+	if s.skip >= len(p) {
+		s.skip -= len(p)
+		return len(p), nil
+	}
+	_, err := s.w.Write(p[s.skip:])
+	s.skip = 0
+	return len(p), err
+}
+
+func (l *webSocketLog) add(msg models.WebSocketMessage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, msg)
+}
+
+func (l *webSocketLog) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+func (l *webSocketLog) result() ([]models.WebSocketMessage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]models.WebSocketMessage{}, l.messages...), l.err
+}
+
+// mockWebSocket answers the upgrade of the client with the recorded handshake, and replays the messages
+// recorded after it. The messages of the server are sent in order, and each message of the client is awaited
+// and compared with the recorded one before the messages following it are sent.
+func mockWebSocket(ctx context.Context, logger *zap.Logger, clientConn net.Conn, request *http.Request, stub *models.Mock, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb) error {
+	header := pkg.ToHTTPHeader(stub.Spec.HTTPResp.Header)
+	header.Del("Content-Length")
+	// the accept is derived from the key of the client, which is random
+	header.Set("Sec-WebSocket-Accept", pkg.WebSocketAccept(request.Header.Get("Sec-WebSocket-Key")))
+	var handshake bytes.Buffer
+	handshake.WriteString(fmt.Sprintf("HTTP/1.1 %d %s\r\n", stub.Spec.HTTPResp.StatusCode, http.StatusText(stub.Spec.HTTPResp.StatusCode)))
+	err := header.Write(&handshake)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the headers of the websocket handshake")
+		return err
+	}
+	handshake.WriteString("\r\n")
+
+	_, err = clientConn.Write(handshake.Bytes())
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		utils.LogError(logger, err, "failed to write the websocket handshake to the user application", zap.Any("metadata", getReqMeta(request)))
+		return err
+	}
+
+	err = clientConn.SetReadDeadline(time.Time{})
+	if err != nil {
+		utils.LogError(logger, err, "failed to reset the read deadline of the client conn")
+		return err
+	}
+	reader := bufio.NewReader(clientConn)
+	messages := pkg.NewWebSocketMessages(models.WebSocketFromClient)
+
+	unmatched := func(msg *models.WebSocketMessage) {
+		summary := fmt.Sprintf("websocket %s %s %s", request.URL.Path, msg.Type, msg.Data)
+		utils.LogError(logger, nil, "Didn't match the websocket message of the client with the recorded one", zap.Any("metadata", getReqMeta(request)), zap.Any("message", summary))
+		mockDb.RecordUnmatched(models.UnmatchedCall{
+			Kind:        models.HTTP,
+			Destination: dstCfg.Addr,
+			Summary:     pUtil.Summarize([][]byte{[]byte(summary)}),
+		})
+	}
+
+	clientClosed, serverClosed := false, false
+	for _, expected := range stub.Spec.WebSocket {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if expected.From == models.WebSocketFromServer {
+			frame, err := pkg.WebSocketMessageFrame(expected)
+			if err != nil {
+				utils.LogError(logger, err, "failed to encode the recorded websocket message", zap.Any("mock", stub.Name))
+				return err
+			}
+			_, err = clientConn.Write(pkg.EncodeWebSocketFrame(frame, false))
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				utils.LogError(logger, err, "failed to write the websocket message to the user application")
+				return err
+			}
+			serverClosed = serverClosed || expected.Type == models.WebSocketClose
+			continue
+		}
+
+		actual, err := pkg.ReadWebSocketMessage(reader, messages)
+		if err != nil {
+			if err != io.EOF {
+				logger.Debug("failed to read the websocket message of the user application", zap.Error(err))
+			}
+			return nil
+		}
+		if !pkg.SameWebSocketMessage(expected, *actual) {
+			unmatched(actual)
+		}
+		clientClosed = clientClosed || actual.Type == models.WebSocketClose
+	}
+	if clientClosed && serverClosed {
+		return nil
+	}
+
+	// the recorded messages are over, the close of the client is still answered
+	for {
+		actual, err := pkg.ReadWebSocketMessage(reader, messages)
+		if err != nil {
+			return nil
+		}
+		var reply *models.WebSocketMessage
+		switch actual.Type {
+		case models.WebSocketClose:
+			reply = &models.WebSocketMessage{Type: models.WebSocketClose, CloseCode: actual.CloseCode}
+		case models.WebSocketPing:
+			reply = &models.WebSocketMessage{Type: models.WebSocketPong, Data: actual.Data}
+		case models.WebSocketPong:
+		default:
+			unmatched(actual)
+		}
+		if reply == nil {
+			continue
+		}
+		frame, err := pkg.WebSocketMessageFrame(*reply)
+		if err != nil {
+			return err
+		}
+		_, err = clientConn.Write(pkg.EncodeWebSocketFrame(frame, false))
+		if err != nil || reply.Type == models.WebSocketClose {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,159 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const upgradeRequest = "GET /chat HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+	"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+
+const upgradeResponse = "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+	"Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n\r\n"
+
+func encodeMessage(t *testing.T, msg models.WebSocketMessage, mask bool) []byte {
+	frame, err := pkg.WebSocketMessageFrame(msg)
+	if err != nil {
+		t.Fatalf("failed to encode the message: %v", err)
+	}
+	return pkg.EncodeWebSocketFrame(frame, mask)
+}
+
+func TestIsWebSocketHandshake(t *testing.T) {
+	tests := []struct {
+		name string
+		req  string
+		resp string
+		want bool
+	}{
+		{name: "upgrade", req: upgradeRequest + "\r\n", resp: upgradeResponse, want: true},
+		{name: "frames following the handshake", req: upgradeRequest + "\r\n", resp: upgradeResponse + "\x81\x02hi", want: true},
+		{name: "refused upgrade", req: upgradeRequest + "\r\n", resp: "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n"},
+		{name: "other protocol", req: "GET / HTTP/1.1\r\nHost: a\r\nUpgrade: h2c\r\nConnection: Upgrade\r\n\r\n", resp: "HTTP/1.1 101 Switching Protocols\r\nUpgrade: h2c\r\nConnection: Upgrade\r\n\r\n"},
+		{name: "malformed request", req: "GET\r\n\r\n", resp: upgradeResponse},
+		{name: "truncated response", req: upgradeRequest + "\r\n", resp: "HTTP/1.1 101"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWebSocketHandshake([]byte(tt.req), []byte(tt.resp)); got != tt.want {
+				t.Fatalf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutWebSocketExtensions(t *testing.T) {
+	tests := []struct {
+		name string
+		req  string
+		want string
+	}{
+		{
+			name: "extensions",
+			req:  upgradeRequest + "Sec-WebSocket-Extensions: permessage-deflate; client_max_window_bits\r\n\r\n",
+			want: upgradeRequest + "\r\n",
+		},
+		{
+			name: "lowercase header",
+			req:  "GET / HTTP/1.1\r\nsec-websocket-extensions: permessage-deflate\r\nHost: a\r\n\r\nbody",
+			want: "GET / HTTP/1.1\r\nHost: a\r\n\r\nbody",
+		},
+		{name: "no extensions", req: upgradeRequest + "\r\n", want: upgradeRequest + "\r\n"},
+		{name: "incomplete headers", req: "GET / HTTP/1.1\r\nSec-WebSocket-Extensions: x\r\n", want: "GET / HTTP/1.1\r\nSec-WebSocket-Extensions: x\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(withoutWebSocketExtensions([]byte(tt.req))); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForward(t *testing.T) {
+	messages := []models.WebSocketMessage{
+		{From: models.WebSocketFromClient, Type: models.WebSocketText, Data: "hello"},
+		{From: models.WebSocketFromClient, Type: models.WebSocketBinary, Data: "AAE="},
+		{From: models.WebSocketFromClient, Type: models.WebSocketClose, CloseCode: 1000},
+	}
+	var stream []byte
+	for _, msg := range messages {
+		stream = append(stream, encodeMessage(t, msg, true)...)
+	}
+	// the frames following the close aren't read
+	trailing := encodeMessage(t, models.WebSocketMessage{Type: models.WebSocketText, Data: "late"}, true)
+
+	log := &webSocketLog{}
+	var out bytes.Buffer
+	err := log.forward(models.WebSocketFromClient, bytes.NewReader(append(append([]byte{}, stream...), trailing...)), &out)
+	if err != nil {
+		t.Fatalf("failed to forward the frames: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), stream) {
+		t.Fatalf("got the forwarded bytes %x, want %x", out.Bytes(), stream)
+	}
+	got, err := log.result()
+	if err != nil || !reflect.DeepEqual(got, messages) {
+		t.Fatalf("got the messages %+v (error: %v), want %+v", got, err, messages)
+	}
+}
+
+func TestForwardMalformed(t *testing.T) {
+	text := encodeMessage(t, models.WebSocketMessage{Type: models.WebSocketText, Data: "hello"}, false)
+	compressed := pkg.EncodeWebSocketFrame(&pkg.WebSocketFrame{Fin: true, Compressed: true, Opcode: 0x1, Payload: []byte{0xf2, 0x48}}, false)
+	unknown := []byte{0x83, 0}
+
+	tests := []struct {
+		name    string
+		stream  []byte
+		wantErr error
+		failed  bool
+	}{
+		{name: "compressed message", stream: append(append(append([]byte{}, text...), compressed...), text...), wantErr: io.EOF, failed: true},
+		{name: "unknown opcode", stream: append(append(append([]byte{}, text...), unknown...), text...), wantErr: io.EOF, failed: true},
+		{name: "truncated frame", stream: append(append([]byte{}, text...), text[:3]...), wantErr: io.ErrUnexpectedEOF},
+		{name: "connection closed", stream: text, wantErr: io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &webSocketLog{}
+			var out bytes.Buffer
+			err := log.forward(models.WebSocketFromServer, bytes.NewReader(tt.stream), &out)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got the error %v, want %v", err, tt.wantErr)
+			}
+			// the frames are forwarded as they are, whether they can be recorded or not
+			if !bytes.Equal(out.Bytes(), tt.stream) {
+				t.Fatalf("got the forwarded bytes %x, want %x", out.Bytes(), tt.stream)
+			}
+			if _, err := log.result(); (err != nil) != tt.failed {
+				t.Fatalf("got the error %v of the log, want a failed log: %t", err, tt.failed)
+			}
+		})
+	}
+}
+
+func TestSkipWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &skipWriter{w: &out, skip: 5}
+	for _, chunk := range []string{"abc", "defg", "hij"} {
+		n, err := w.Write([]byte(chunk))
+		if err != nil || n != len(chunk) {
+			t.Fatalf("got %d bytes written (error: %v), want %d", n, err, len(chunk))
+		}
+	}
+	if out.String() != "fghij" {
+		t.Fatalf("got %q, want %q", out.String(), "fghij")
+	}
+}
@@ -26,10 +26,26 @@ const (
 	POSTGRES_V2 integrationType = "postgres_v2"
 	MONGO       integrationType = "mongo"
 	REDIS       integrationType = "redis"
+	KAFKA       integrationType = "kafka"
+	AMQP        integrationType = "amqp"
+	CASSANDRA   integrationType = "cassandra"
+	MEMCACHED   integrationType = "memcached"
+	MSSQL       integrationType = "mssql"
 )
 
 var Registered = make(map[string]Initializer)
 
+// MockKeyFunc returns the key on which the mocks of a kind are indexed in the mock db, e.g. the method
+// and the path of a http request. The parsers compute the same key from the incoming request to look up
+// the mocks having it.
+type MockKeyFunc func(mock *models.Mock) string
+
+var mockKeys = make(map[models.Kind]MockKeyFunc)
+
+// missRecorders are the integrations recording the rest of a connection when no mock matched a request
+// of it in test mode (--record-on-miss).
+var missRecorders = make(map[string]bool)
+
 type ConditionalDstCfg struct {
 	Addr   string // Destination Addr (ip:port)
 	Port   uint
@@ -46,6 +62,30 @@ func Register(name string, i Initializer) {
 	Registered[name] = i
 }
 
+// RegisterMissRecorder marks the integration as recording the connections on a miss.
+func RegisterMissRecorder(name string) {
+	missRecorders[name] = true
+}
+
+// RecordsOnMiss tells whether the integration records the connections on a miss.
+func RecordsOnMiss(name string) bool {
+	return missRecorders[name]
+}
+
+// RegisterMockKey sets the function computing the index key of the mocks of the given kind.
+func RegisterMockKey(kind models.Kind, fn MockKeyFunc) {
+	mockKeys[kind] = fn
+}
+
+// MockKey returns the index key of the mock, it is empty if the kind of the mock is not indexed.
+func MockKey(mock *models.Mock) string {
+	fn, ok := mockKeys[mock.Kind]
+	if !ok {
+		return ""
+	}
+	return fn(mock)
+}
+
 type MockMemDb interface {
 	GetFilteredMocks() ([]*models.Mock, error)
 	GetUnFilteredMocks() ([]*models.Mock, error)
@@ -54,4 +94,16 @@ type MockMemDb interface {
 	DeleteUnFilteredMock(mock models.Mock) bool
 	// Flag the mock as used which matches the external request from application in test mode
 	FlagMockAsUsed(mock models.Mock) error
+	// The mocks of a kind, or of a kind and an index key, are looked up without copying the other mocks
+	GetFilteredMocksByKind(kind models.Kind) ([]*models.Mock, error)
+	GetUnFilteredMocksByKind(kind models.Kind) ([]*models.Mock, error)
+	GetFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error)
+	GetUnFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error)
+	// ConsumeMock flags the unfiltered mock as used. A mock which is still filtered is moved behind the
+	// other mocks, it returns false when the mock has already been consumed by another request.
+	ConsumeMock(mock *models.Mock) bool
+	// RecordUnmatched keeps the outgoing call that no mock matched, to report it in the result of the test case
+	RecordUnmatched(call models.UnmatchedCall)
+	// AddRecordedMock keeps the mock recorded on a miss in test mode, to append it to the test-set
+	AddRecordedMock(mock *models.Mock)
 }
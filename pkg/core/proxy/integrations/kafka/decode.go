@@ -0,0 +1,110 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func decodeKafka(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the kafka parser in test mode")
+	errCh := make(chan error, 1)
+
+	go func(errCh chan error) {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		reader := bufio.NewReader(clientConn)
+		for {
+			frame, err := readMessage(reader)
+			if err != nil {
+				if err != io.EOF {
+					logger.Debug("failed to read the kafka request from the client", zap.Error(err))
+				}
+				errCh <- err
+				return
+			}
+
+			req, err := decodeRequest(frame[4:])
+			if err != nil {
+				logger.Debug("failed to decode the kafka request", zap.Error(err))
+				if req == nil {
+					errCh <- err
+					return
+				}
+			}
+			// the broker does not respond to a produce request without acks
+			if req.APIKey == apiProduce && req.Acks == 0 {
+				continue
+			}
+
+			mock, err := matchRequest(ctx, req, mockDb)
+			if err != nil {
+				if ctx.Err() == nil {
+					utils.LogError(logger, err, "error while matching kafka mocks")
+				}
+				errCh <- err
+				return
+			}
+			if mock == nil {
+				err := fmt.Errorf("no matching kafka mock found for the %s request (version %d)", req.APIName, req.APIVersion)
+				utils.LogError(logger, err, "failed to mock the kafka request", zap.Any("topics", req.Topics), zap.Any("group", req.GroupID))
+				mockDb.RecordUnmatched(models.UnmatchedCall{
+					Kind:        models.Kafka,
+					Destination: dstCfg.Addr,
+					Summary:     summarizeRequest(req),
+				})
+				errCh <- err
+				return
+			}
+
+			// the response carries the correlation id of the current request
+			resp, err := encodeResponse(mock.Spec.KafkaResp, req.CorrelationID)
+			if err != nil {
+				utils.LogError(logger, err, "failed to encode the kafka response")
+				errCh <- err
+				return
+			}
+			_, err = clientConn.Write(resp)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				utils.LogError(logger, err, "failed to write the response message to the client application")
+				errCh <- err
+				return
+			}
+		}
+	}(errCh)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// summarizeRequest returns the api of the request along with its topics and group, for the report of the unmatched calls.
+func summarizeRequest(req *models.KafkaRequest) string {
+	summary := fmt.Sprintf("%s v%d", req.APIName, req.APIVersion)
+	for _, topic := range req.Topics {
+		summary += " " + topic.Name
+	}
+	if req.GroupID != "" {
+		summary += " group " + req.GroupID
+	}
+	return summary
+}
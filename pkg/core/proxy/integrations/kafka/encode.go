@@ -0,0 +1,151 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+type inflight struct {
+	req              *models.KafkaRequest
+	reqTimestampMock time.Time
+}
+
+func encodeKafka(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+	errCh := make(chan error, 2)
+
+	// the requests awaiting their response, the clients may send several requests before reading the responses
+	var mu sync.Mutex
+	var requests []inflight
+
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
+	// Read and process the requests from the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		reader := bufio.NewReader(clientConn)
+		for {
+			frame, err := readMessage(reader)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the kafka request from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			reqTimestampMock := time.Now()
+
+			_, err = destConn.Write(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the kafka request to the destination server")
+				errCh <- err
+				return nil
+			}
+
+			req, err := decodeRequest(frame[4:])
+			if err != nil {
+				logger.Debug("failed to decode the kafka request", zap.Error(err))
+				if req == nil {
+					continue
+				}
+			}
+			// the broker does not respond to a produce request without acks
+			if req.APIKey == apiProduce && req.Acks == 0 {
+				continue
+			}
+			mu.Lock()
+			requests = append(requests, inflight{req: req, reqTimestampMock: reqTimestampMock})
+			mu.Unlock()
+		}
+	})
+
+	// Read and process the responses from the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		reader := bufio.NewReader(destConn)
+		for {
+			frame, err := readMessage(reader)
+			if err != nil {
+				if err == io.EOF {
+					logger.Debug("the kafka broker closed the connection")
+				} else {
+					utils.LogError(logger, err, "failed to read the kafka response from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			_, err = clientConn.Write(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the kafka response to the client")
+				errCh <- err
+				return nil
+			}
+
+			resp, err := decodeResponse(frame[4:])
+			if err != nil {
+				logger.Debug("failed to decode the kafka response", zap.Error(err))
+				continue
+			}
+
+			mu.Lock()
+			var req *inflight
+			for i := range requests {
+				if requests[i].req.CorrelationID == resp.CorrelationID {
+					req = &requests[i]
+					requests = append(requests[:i:i], requests[i+1:]...)
+					break
+				}
+			}
+			mu.Unlock()
+			if req == nil {
+				logger.Debug("no kafka request found for the response", zap.Any("correlation id", resp.CorrelationID))
+				continue
+			}
+
+			mocks <- &models.Mock{
+				Version: models.GetVersion(),
+				Name:    "mocks",
+				Kind:    models.Kafka,
+				Spec: models.MockSpec{
+					Metadata: map[string]string{
+						"type":    "config",
+						"apiName": req.req.APIName,
+					},
+					KafkaReq:         req.req,
+					KafkaResp:        resp,
+					ReqTimestampMock: req.reqTimestampMock,
+					ResTimestampMock: time.Now(),
+				},
+				ConnectionID: connID,
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+// Package kafka provides the integration for the kafka wire protocol, recording the requests of the
+// producers and the consumers along with the responses of the brokers.
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register(string(integrations.KAFKA), NewKafka)
+}
+
+type Kafka struct {
+	logger *zap.Logger
+}
+
+func NewKafka(logger *zap.Logger) integrations.Integrations {
+	return &Kafka{
+		logger: logger,
+	}
+}
+
+// MatchType checks whether the first request is an ApiVersions or a SaslHandshake request, which are
+// sent by the kafka clients before any other request. The brokers on the other ports can be configured
+// with the kafka protocol in the protocol rules.
+func (k *Kafka) MatchType(_ context.Context, buf []byte) bool {
+	if len(buf) < 14 {
+		return false
+	}
+	size := int32(binary.BigEndian.Uint32(buf))
+	if size < 10 || size > maxMessageSize {
+		return false
+	}
+	apiKey := int16(binary.BigEndian.Uint16(buf[4:]))
+	apiVersion := int16(binary.BigEndian.Uint16(buf[6:]))
+	clientIDLen := int16(binary.BigEndian.Uint16(buf[12:]))
+	if clientIDLen < -1 || int32(clientIDLen) > size-10 {
+		return false
+	}
+	switch apiKey {
+	case apiAPIVersions:
+		return apiVersion >= 0 && apiVersion <= 4
+	case apiSaslHandshake:
+		return apiVersion >= 0 && apiVersion <= 1
+	}
+	return false
+}
+
+func (k *Kafka) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := k.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := encodeKafka(ctx, logger, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the kafka message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (k *Kafka) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := k.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := decodeKafka(ctx, logger, src, dstCfg, mockDb, opts)
+	if err != nil && err != io.EOF {
+		utils.LogError(logger, err, "failed to decode the kafka message from the yaml")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+//go:build linux
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// matchRequest finds the mock of the given request. The api and its version have to be the same,
+// the correlation ids are ignored. The mocks whose topics, partitions, offsets and groups are the
+// same as the request are preferred, the filtered mocks first. The record batches of the produce
+// requests hold timestamps, hence the body is only used to break the ties.
+func matchRequest(ctx context.Context, req *models.KafkaRequest, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		filteredMocks, unfilteredMocks, err := getKafkaMocks(mockDb)
+		if err != nil {
+			return nil, err
+		}
+
+		mock := findExactMatch(filteredMocks, req)
+		if mock == nil {
+			mock = findExactMatch(unfilteredMocks, req)
+		}
+		if mock == nil {
+			// e.g. the consumer fetches from an offset different from the recorded one
+			mock = findBestMatch(append(filteredMocks, unfilteredMocks...), req)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		if !mockDb.ConsumeMock(mock) {
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// getKafkaMocks returns the filtered and the unfiltered kafka mocks.
+func getKafkaMocks(mockDb integrations.MockMemDb) ([]*models.Mock, []*models.Mock, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.Kafka)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var filteredMocks []*models.Mock
+	var unfilteredMocks []*models.Mock
+
+	for _, mock := range mocks {
+		if mock.Spec.KafkaReq == nil || mock.Spec.KafkaResp == nil {
+			continue
+		}
+		if mock.TestModeInfo.IsFiltered {
+			filteredMocks = append(filteredMocks, mock)
+		} else {
+			unfilteredMocks = append(unfilteredMocks, mock)
+		}
+	}
+	return filteredMocks, unfilteredMocks, nil
+}
+
+// findExactMatch returns the first mock having the same decoded fields as the request, preferring
+// the one having the same body.
+func findExactMatch(mocks []*models.Mock, req *models.KafkaRequest) *models.Mock {
+	var match *models.Mock
+	for _, mock := range mocks {
+		expected := mock.Spec.KafkaReq
+		if !sameAPI(expected, req) || !topicsEqual(expected.Topics, req.Topics, true) ||
+			expected.GroupID != req.GroupID || !stringsEqual(expected.CoordinatorKeys, req.CoordinatorKeys) {
+			continue
+		}
+		if expected.Body == req.Body {
+			return mock
+		}
+		if match == nil {
+			match = mock
+		}
+	}
+	return match
+}
+
+// findBestMatch returns the mock of the same api having the highest score, the topics and the group
+// of the mock have to be the same as the request.
+func findBestMatch(mocks []*models.Mock, req *models.KafkaRequest) *models.Mock {
+	var match *models.Mock
+	best := -1
+	for _, mock := range mocks {
+		expected := mock.Spec.KafkaReq
+		if !sameAPI(expected, req) || !topicsEqual(expected.Topics, req.Topics, false) || expected.GroupID != req.GroupID {
+			continue
+		}
+		score := 0
+		if stringsEqual(expected.CoordinatorKeys, req.CoordinatorKeys) {
+			score++
+		}
+		if expected.ClientID == req.ClientID {
+			score++
+		}
+		if score > best {
+			best = score
+			match = mock
+		}
+	}
+	return match
+}
+
+func sameAPI(a, b *models.KafkaRequest) bool {
+	return a.APIKey == b.APIKey && a.APIVersion == b.APIVersion
+}
+
+// topicsEqual compares the topics and their partitions, the offsets are only compared if asked for.
+func topicsEqual(a, b []models.KafkaTopic, offsets bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || len(a[i].Partitions) != len(b[i].Partitions) {
+			return false
+		}
+		for j := range a[i].Partitions {
+			if a[i].Partitions[j].Index != b[i].Partitions[j].Index {
+				return false
+			}
+			if offsets && a[i].Partitions[j].Offset != b[i].Partitions[j].Offset {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
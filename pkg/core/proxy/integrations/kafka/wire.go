@@ -0,0 +1,447 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// api keys of the requests decoded from the body
+const (
+	apiProduce         = 0
+	apiFetch           = 1
+	apiListOffsets     = 2
+	apiMetadata        = 3
+	apiOffsetCommit    = 8
+	apiOffsetFetch     = 9
+	apiFindCoordinator = 10
+	apiJoinGroup       = 11
+	apiHeartbeat       = 12
+	apiLeaveGroup      = 13
+	apiSyncGroup       = 14
+	apiDescribeGroups  = 15
+	apiSaslHandshake   = 17
+	apiAPIVersions     = 18
+)
+
+const maxMessageSize = 100 << 20
+
+var errMalformed = errors.New("malformed kafka message")
+
+// apis holds the names of the apis and the first version using the flexible (compact) encoding,
+// -1 for the apis without a flexible version.
+var apis = map[int16]struct {
+	name     string
+	flexible int16
+}{
+	apiProduce:         {"Produce", 9},
+	apiFetch:           {"Fetch", 12},
+	apiListOffsets:     {"ListOffsets", 6},
+	apiMetadata:        {"Metadata", 9},
+	4:                  {"LeaderAndIsr", 4},
+	5:                  {"StopReplica", 2},
+	6:                  {"UpdateMetadata", 6},
+	7:                  {"ControlledShutdown", 3},
+	apiOffsetCommit:    {"OffsetCommit", 8},
+	apiOffsetFetch:     {"OffsetFetch", 6},
+	apiFindCoordinator: {"FindCoordinator", 3},
+	apiJoinGroup:       {"JoinGroup", 6},
+	apiHeartbeat:       {"Heartbeat", 4},
+	apiLeaveGroup:      {"LeaveGroup", 4},
+	apiSyncGroup:       {"SyncGroup", 4},
+	apiDescribeGroups:  {"DescribeGroups", 5},
+	16:                 {"ListGroups", 3},
+	apiSaslHandshake:   {"SaslHandshake", -1},
+	apiAPIVersions:     {"ApiVersions", 3},
+	19:                 {"CreateTopics", 5},
+	20:                 {"DeleteTopics", 4},
+	21:                 {"DeleteRecords", 2},
+	22:                 {"InitProducerId", 2},
+	23:                 {"OffsetForLeaderEpoch", 4},
+	24:                 {"AddPartitionsToTxn", 3},
+	25:                 {"AddOffsetsToTxn", 3},
+	26:                 {"EndTxn", 3},
+	28:                 {"TxnOffsetCommit", 3},
+	32:                 {"DescribeConfigs", 4},
+	33:                 {"AlterConfigs", 2},
+	36:                 {"SaslAuthenticate", 2},
+	37:                 {"CreatePartitions", 2},
+	42:                 {"DeleteGroups", 2},
+	47:                 {"OffsetDelete", -1},
+	60:                 {"DescribeCluster", 0},
+}
+
+func apiName(key int16) string {
+	if api, ok := apis[key]; ok {
+		return api.name
+	}
+	return fmt.Sprintf("Unknown(%d)", key)
+}
+
+func isFlexible(key, version int16) bool {
+	api, ok := apis[key]
+	return ok && api.flexible >= 0 && version >= api.flexible
+}
+
+// readMessage reads a size delimited kafka message, the returned frame includes the size.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(hdr))
+	if size < 4 || size > maxMessageSize {
+		return nil, fmt.Errorf("%w: invalid size %d", errMalformed, size)
+	}
+	frame := make([]byte, 4+size)
+	copy(frame, hdr)
+	if _, err := io.ReadFull(r, frame[4:]); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil || n < 0 || len(d.buf) < n {
+		d.err = errMalformed
+		return nil
+	}
+	v := d.buf[:n]
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) int8() int8 {
+	v := d.next(1)
+	if v == nil {
+		return 0
+	}
+	return int8(v[0])
+}
+
+func (d *decoder) int16() int16 {
+	v := d.next(2)
+	if v == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(v))
+}
+
+func (d *decoder) int32() int32 {
+	v := d.next(4)
+	if v == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(v))
+}
+
+func (d *decoder) int64() int64 {
+	v := d.next(8)
+	if v == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(v))
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errMalformed
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// string reads a string, which is prefixed by its length as int16 or as unsigned varint (length+1) when compact.
+func (d *decoder) string(compact bool) string {
+	var n int
+	if compact {
+		n = int(d.uvarint()) - 1
+	} else {
+		n = int(d.int16())
+	}
+	if n < 0 {
+		// null string
+		return ""
+	}
+	return string(d.next(n))
+}
+
+// bytes skips a byte array.
+func (d *decoder) bytes(compact bool) {
+	var n int
+	if compact {
+		n = int(d.uvarint()) - 1
+	} else {
+		n = int(d.int32())
+	}
+	if n > 0 {
+		d.next(n)
+	}
+}
+
+// arrayLen reads the length of an array, a null array has no element.
+func (d *decoder) arrayLen(compact bool) int {
+	var n int
+	if compact {
+		n = int(d.uvarint()) - 1
+	} else {
+		n = int(d.int32())
+	}
+	if n < 0 || d.err != nil {
+		return 0
+	}
+	// each element takes at least a byte
+	if n > len(d.buf) {
+		d.err = errMalformed
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) uuid() string {
+	return hex.EncodeToString(d.next(16))
+}
+
+// taggedFields skips the tagged fields of a flexible version.
+func (d *decoder) taggedFields(flexible bool) {
+	if !flexible {
+		return
+	}
+	n := int(d.uvarint())
+	for i := 0; i < n && d.err == nil; i++ {
+		d.uvarint()
+		size := int(d.uvarint())
+		d.next(size)
+	}
+}
+
+// decodeRequest decodes the header of a request and the fields of the body used to match the mocks.
+// The body of the apis which are not decoded is matched as it is.
+func decodeRequest(msg []byte) (*models.KafkaRequest, error) {
+	d := &decoder{buf: msg}
+	req := &models.KafkaRequest{
+		APIKey:        d.int16(),
+		APIVersion:    d.int16(),
+		CorrelationID: d.int32(),
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	req.APIName = apiName(req.APIKey)
+	// the client id is never compact
+	req.ClientID = d.string(false)
+	flexible := isFlexible(req.APIKey, req.APIVersion)
+	d.taggedFields(flexible)
+	if d.err != nil {
+		return nil, d.err
+	}
+	req.Body = base64.StdEncoding.EncodeToString(d.buf)
+
+	body := &decoder{buf: d.buf}
+	body.decodeBody(req, flexible)
+	if body.err != nil {
+		// the header is enough to replay the response, the body is matched as it is
+		return req, fmt.Errorf("failed to decode the body of the %s request: %w", req.APIName, body.err)
+	}
+	return req, nil
+}
+
+func (d *decoder) decodeBody(req *models.KafkaRequest, flexible bool) {
+	v := req.APIVersion
+	switch req.APIKey {
+	case apiProduce:
+		if v >= 3 {
+			d.string(flexible) // transactional id
+		}
+		req.Acks = d.int16()
+		d.int32() // timeout
+		for i, n := 0, d.arrayLen(flexible); i < n && d.err == nil; i++ {
+			topic := models.KafkaTopic{Name: d.string(flexible)}
+			for j, m := 0, d.arrayLen(flexible); j < m && d.err == nil; j++ {
+				topic.Partitions = append(topic.Partitions, models.KafkaPartition{Index: d.int32()})
+				d.bytes(flexible) // records
+				d.taggedFields(flexible)
+			}
+			d.taggedFields(flexible)
+			req.Topics = append(req.Topics, topic)
+		}
+	case apiFetch:
+		if v <= 14 {
+			d.int32() // replica id
+		}
+		d.int32() // max wait
+		d.int32() // min bytes
+		if v >= 3 {
+			d.int32() // max bytes
+		}
+		if v >= 4 {
+			d.int8() // isolation level
+		}
+		if v >= 7 {
+			d.int32() // session id
+			d.int32() // session epoch
+		}
+		for i, n := 0, d.arrayLen(flexible); i < n && d.err == nil; i++ {
+			var topic models.KafkaTopic
+			if v >= 13 {
+				topic.Name = d.uuid()
+			} else {
+				topic.Name = d.string(flexible)
+			}
+			for j, m := 0, d.arrayLen(flexible); j < m && d.err == nil; j++ {
+				p := models.KafkaPartition{Index: d.int32()}
+				if v >= 9 {
+					d.int32() // current leader epoch
+				}
+				p.Offset = d.int64()
+				if v >= 12 {
+					d.int32() // last fetched epoch
+				}
+				if v >= 5 {
+					d.int64() // log start offset
+				}
+				d.int32() // partition max bytes
+				d.taggedFields(flexible)
+				topic.Partitions = append(topic.Partitions, p)
+			}
+			d.taggedFields(flexible)
+			req.Topics = append(req.Topics, topic)
+		}
+	case apiListOffsets:
+		d.int32() // replica id
+		if v >= 2 {
+			d.int8() // isolation level
+		}
+		for i, n := 0, d.arrayLen(flexible); i < n && d.err == nil; i++ {
+			topic := models.KafkaTopic{Name: d.string(flexible)}
+			for j, m := 0, d.arrayLen(flexible); j < m && d.err == nil; j++ {
+				p := models.KafkaPartition{Index: d.int32()}
+				if v >= 4 {
+					d.int32() // current leader epoch
+				}
+				// the timestamp of the offset to list, e.g. -1 for the latest offset
+				p.Offset = d.int64()
+				if v == 0 {
+					d.int32() // max num offsets
+				}
+				d.taggedFields(flexible)
+				topic.Partitions = append(topic.Partitions, p)
+			}
+			d.taggedFields(flexible)
+			req.Topics = append(req.Topics, topic)
+		}
+	case apiMetadata:
+		for i, n := 0, d.arrayLen(flexible); i < n && d.err == nil; i++ {
+			var topic models.KafkaTopic
+			if v >= 10 {
+				id := d.uuid()
+				topic.Name = d.string(flexible)
+				if topic.Name == "" {
+					topic.Name = id
+				}
+			} else {
+				topic.Name = d.string(flexible)
+			}
+			d.taggedFields(flexible)
+			req.Topics = append(req.Topics, topic)
+		}
+	case apiOffsetCommit:
+		req.GroupID = d.string(flexible)
+		if v >= 1 {
+			d.int32()          // generation id
+			d.string(flexible) // member id
+		}
+		if v >= 7 {
+			d.string(flexible) // group instance id
+		}
+		if v >= 2 && v <= 4 {
+			d.int64() // retention time
+		}
+		for i, n := 0, d.arrayLen(flexible); i < n && d.err == nil; i++ {
+			topic := models.KafkaTopic{Name: d.string(flexible)}
+			for j, m := 0, d.arrayLen(flexible); j < m && d.err == nil; j++ {
+				p := models.KafkaPartition{Index: d.int32(), Offset: d.int64()}
+				if v >= 6 {
+					d.int32() // committed leader epoch
+				}
+				if v == 1 {
+					d.int64() // commit timestamp
+				}
+				d.string(flexible) // committed metadata
+				d.taggedFields(flexible)
+				topic.Partitions = append(topic.Partitions, p)
+			}
+			d.taggedFields(flexible)
+			req.Topics = append(req.Topics, topic)
+		}
+	case apiOffsetFetch:
+		if v <= 7 {
+			req.GroupID = d.string(flexible)
+			for i, n := 0, d.arrayLen(flexible); i < n && d.err == nil; i++ {
+				topic := models.KafkaTopic{Name: d.string(flexible)}
+				for j, m := 0, d.arrayLen(flexible); j < m && d.err == nil; j++ {
+					topic.Partitions = append(topic.Partitions, models.KafkaPartition{Index: d.int32()})
+				}
+				d.taggedFields(flexible)
+				req.Topics = append(req.Topics, topic)
+			}
+		}
+	case apiFindCoordinator:
+		if v <= 3 {
+			key := d.string(flexible)
+			req.CoordinatorKeys = []string{key}
+			if v >= 1 {
+				d.int8() // key type
+			}
+			return
+		}
+		d.int8() // key type
+		for i, n := 0, d.arrayLen(flexible); i < n && d.err == nil; i++ {
+			req.CoordinatorKeys = append(req.CoordinatorKeys, d.string(flexible))
+		}
+	case apiJoinGroup, apiHeartbeat, apiLeaveGroup, apiSyncGroup:
+		// the member ids are assigned by the broker and differ between the runs, only the group is matched
+		req.GroupID = d.string(flexible)
+	}
+}
+
+// encodeResponse builds the response of a mock for the request with the given correlation id.
+func encodeResponse(resp *models.KafkaResponse, correlationID int32) ([]byte, error) {
+	body, err := base64.StdEncoding.DecodeString(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(4+len(body)))
+	binary.BigEndian.PutUint32(buf[4:], uint32(correlationID))
+	return append(buf, body...), nil
+}
+
+// decodeResponse splits a response of the broker into the correlation id and the rest of the response.
+func decodeResponse(msg []byte) (*models.KafkaResponse, error) {
+	if len(msg) < 4 {
+		return nil, errMalformed
+	}
+	return &models.KafkaResponse{
+		CorrelationID: int32(binary.BigEndian.Uint32(msg)),
+		Body:          base64.StdEncoding.EncodeToString(msg[4:]),
+	}, nil
+}
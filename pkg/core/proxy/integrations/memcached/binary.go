@@ -0,0 +1,288 @@
+//go:build linux
+
+package memcached
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const (
+	magicRequest  = 0x80
+	magicResponse = 0x81
+	headerLength  = 24
+)
+
+// packet is a request or a response of the binary protocol.
+type packet struct {
+	magic  byte
+	opcode byte
+	status uint16 // the vbucket id in the requests
+	opaque uint32
+	cas    uint64
+	extras []byte
+	key    []byte
+	value  []byte
+	raw    []byte
+}
+
+var opcodes = map[byte]string{
+	0x00: "get", 0x01: "set", 0x02: "add", 0x03: "replace", 0x04: "delete", 0x05: "increment", 0x06: "decrement",
+	0x07: "quit", 0x08: "flush", 0x09: "getq", 0x0a: "noop", 0x0b: "version", 0x0c: "getk", 0x0d: "getkq",
+	0x0e: "append", 0x0f: "prepend", 0x10: "stat", 0x11: "setq", 0x12: "addq", 0x13: "replaceq", 0x14: "deleteq",
+	0x15: "incrementq", 0x16: "decrementq", 0x17: "quitq", 0x18: "flushq", 0x19: "appendq", 0x1a: "prependq",
+	0x1b: "verbosity", 0x1c: "touch", 0x1d: "gat", 0x1e: "gatq", 0x20: "sasl-list-mechs", 0x21: "sasl-auth",
+	0x22: "sasl-step", 0x23: "gatk", 0x24: "gatkq",
+}
+
+// quietOpcodes are the commands to which the server replies only on an error, or on a hit for the retrievals
+var quietOpcodes = map[byte]bool{
+	0x09: true, 0x0d: true, 0x11: true, 0x12: true, 0x13: true, 0x14: true, 0x15: true, 0x16: true,
+	0x17: true, 0x18: true, 0x19: true, 0x1a: true, 0x1e: true, 0x24: true,
+}
+
+var statuses = map[uint16]string{
+	0x0000: "no-error", 0x0001: "key-not-found", 0x0002: "key-exists", 0x0003: "value-too-large",
+	0x0004: "invalid-arguments", 0x0005: "item-not-stored", 0x0006: "non-numeric-value", 0x0007: "vbucket-not-here",
+	0x0020: "auth-error", 0x0021: "auth-continue", 0x0081: "unknown-command", 0x0082: "out-of-memory",
+	0x0083: "not-supported", 0x0084: "internal-error", 0x0085: "busy", 0x0086: "temporary-failure",
+}
+
+const statusInternalError = 0x0084
+
+func opcodeName(opcode byte) string {
+	if name, ok := opcodes[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", opcode)
+}
+
+func opcodeFromName(name string) (byte, error) {
+	for opcode, n := range opcodes {
+		if n == name {
+			return opcode, nil
+		}
+	}
+	opcode, err := strconv.ParseUint(name, 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("unknown memcached binary command %s", name)
+	}
+	return byte(opcode), nil
+}
+
+func statusName(status uint16) string {
+	if name, ok := statuses[status]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", status)
+}
+
+func statusFromName(name string) (uint16, error) {
+	for status, n := range statuses {
+		if n == name {
+			return status, nil
+		}
+	}
+	status, err := strconv.ParseUint(name, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("unknown memcached binary status %s", name)
+	}
+	return uint16(status), nil
+}
+
+// isBinaryRequest checks whether the buffer starts with the header of a request of the binary protocol.
+func isBinaryRequest(buf []byte) bool {
+	if len(buf) < headerLength || buf[0] != magicRequest || buf[5] != 0 {
+		return false
+	}
+	if _, ok := opcodes[buf[1]]; !ok {
+		return false
+	}
+	keyLen := uint32(binary.BigEndian.Uint16(buf[2:]))
+	return keyLen+uint32(buf[4]) <= binary.BigEndian.Uint32(buf[8:])
+}
+
+// readPacket reads a packet of the binary protocol: the header, the extras, the key and the value.
+func readPacket(reader *bufio.Reader) (*packet, error) {
+	header := make([]byte, headerLength)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return nil, err
+	}
+	keyLen := int(binary.BigEndian.Uint16(header[2:]))
+	extrasLen := int(header[4])
+	bodyLen := int(binary.BigEndian.Uint32(header[8:]))
+	if (header[0] != magicRequest && header[0] != magicResponse) || bodyLen > maxValueSize || keyLen+extrasLen > bodyLen {
+		// the header is returned to be forwarded along with the rest of the connection
+		return &packet{raw: header}, errMalformed
+	}
+	raw := make([]byte, headerLength+bodyLen)
+	copy(raw, header)
+	_, err = io.ReadFull(reader, raw[headerLength:])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	body := raw[headerLength:]
+	return &packet{
+		magic:  header[0],
+		opcode: header[1],
+		status: binary.BigEndian.Uint16(header[6:]),
+		opaque: binary.BigEndian.Uint32(header[12:]),
+		cas:    binary.BigEndian.Uint64(header[16:]),
+		extras: body[:extrasLen],
+		key:    body[extrasLen : extrasLen+keyLen],
+		value:  body[extrasLen+keyLen:],
+		raw:    raw,
+	}, nil
+}
+
+// decodeBinaryRequest decodes the extras of the commands into the fields of the request, the extras of the
+// other commands are stored as base64.
+func decodeBinaryRequest(p *packet) *models.MemcachedRequest {
+	req := &models.MemcachedRequest{
+		Protocol: protocolBinary,
+		Command:  opcodeName(p.opcode),
+		CAS:      p.cas,
+		Opaque:   p.opaque,
+		NoReply:  quietOpcodes[p.opcode],
+	}
+	if len(p.key) > 0 {
+		req.Keys = []string{string(p.key)}
+	}
+	req.Value, req.ValueType = encodeValue(p.value)
+
+	extras := p.extras
+	switch {
+	case isStorage(req.Command) && len(extras) == 8:
+		req.Flags = binary.BigEndian.Uint32(extras)
+		req.Exptime = int64(binary.BigEndian.Uint32(extras[4:]))
+	case isCounter(req.Command) && len(extras) == 20:
+		req.Delta = binary.BigEndian.Uint64(extras)
+		req.Initial = binary.BigEndian.Uint64(extras[8:])
+		req.Exptime = int64(binary.BigEndian.Uint32(extras[16:]))
+	case (isTouch(req.Command) || req.Command == "flush" || req.Command == "flushq") && len(extras) == 4:
+		req.Exptime = int64(binary.BigEndian.Uint32(extras))
+	case req.Command == "verbosity" && len(extras) == 4:
+		req.Args = []string{strconv.FormatUint(uint64(binary.BigEndian.Uint32(extras)), 10)}
+	case len(extras) > 0:
+		req.Extras = base64.StdEncoding.EncodeToString(extras)
+	}
+	return req
+}
+
+// decodeBinaryResponse decodes a response packet to the given command. The value of a counter is stored as
+// a number.
+func decodeBinaryResponse(p *packet, req *models.MemcachedRequest) models.MemcachedResponse {
+	resp := models.MemcachedResponse{Status: statusName(p.status)}
+	item := models.MemcachedItem{Key: string(p.key), CAS: p.cas}
+	if isRetrieval(req.Command) && p.status == 0 && len(p.extras) == 4 {
+		item.Flags = binary.BigEndian.Uint32(p.extras)
+	}
+	if isCounter(req.Command) && p.status == 0 && len(p.value) == 8 {
+		item.Value, item.ValueType = strconv.FormatUint(binary.BigEndian.Uint64(p.value), 10), models.BodyTypeUtf8
+	} else {
+		item.Value, item.ValueType = encodeValue(p.value)
+	}
+	if item != (models.MemcachedItem{}) {
+		resp.Items = []models.MemcachedItem{item}
+	}
+	return resp
+}
+
+// encodeBinaryResponse encodes the recorded response packets for the given request, the opaque value of the
+// request is set in them.
+func encodeBinaryResponse(req *models.MemcachedRequest, responses []models.MemcachedResponse) ([]byte, error) {
+	opcode, err := opcodeFromName(req.Command)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	for _, resp := range responses {
+		status, err := statusFromName(resp.Status)
+		if err != nil {
+			return nil, err
+		}
+		item := models.MemcachedItem{}
+		if len(resp.Items) > 0 {
+			item = resp.Items[0]
+		}
+		var extras, value []byte
+		if isRetrieval(req.Command) && status == 0 {
+			extras = binary.BigEndian.AppendUint32(nil, item.Flags)
+		}
+		if isCounter(req.Command) && status == 0 {
+			counter, err := strconv.ParseUint(item.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of the memcached counter %s: %w", item.Value, err)
+			}
+			value = binary.BigEndian.AppendUint64(nil, counter)
+		} else {
+			value, err = decodeValue(item.Value, item.ValueType)
+			if err != nil {
+				return nil, err
+			}
+		}
+		buf = appendPacket(buf, magicResponse, opcode, status, req.Opaque, item.CAS, extras, []byte(item.Key), value)
+	}
+	return buf, nil
+}
+
+// encodeBinaryError returns a response with the given status and message.
+func encodeBinaryError(req *models.MemcachedRequest, status uint16, message string) []byte {
+	opcode, _ := opcodeFromName(req.Command)
+	return appendPacket(nil, magicResponse, opcode, status, req.Opaque, 0, nil, nil, []byte(message))
+}
+
+func appendPacket(buf []byte, magic, opcode byte, status uint16, opaque uint32, cas uint64, extras, key, value []byte) []byte {
+	buf = append(buf, magic, opcode)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(key)))
+	buf = append(buf, byte(len(extras)), 0)
+	buf = binary.BigEndian.AppendUint16(buf, status)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(extras)+len(key)+len(value)))
+	buf = binary.BigEndian.AppendUint32(buf, opaque)
+	buf = binary.BigEndian.AppendUint64(buf, cas)
+	buf = append(buf, extras...)
+	buf = append(buf, key...)
+	return append(buf, value...)
+}
+
+func isStorage(cmd string) bool {
+	switch cmd {
+	case "set", "add", "replace", "setq", "addq", "replaceq":
+		return true
+	}
+	return false
+}
+
+func isCounter(cmd string) bool {
+	switch cmd {
+	case "increment", "decrement", "incrementq", "decrementq":
+		return true
+	}
+	return false
+}
+
+func isTouch(cmd string) bool {
+	switch cmd {
+	case "touch", "gat", "gatq", "gatk", "gatkq":
+		return true
+	}
+	return false
+}
+
+func isRetrieval(cmd string) bool {
+	switch cmd {
+	case "get", "getq", "getk", "getkq", "gat", "gatq", "gatk", "gatkq":
+		return true
+	}
+	return false
+}
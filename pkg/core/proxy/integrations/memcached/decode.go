@@ -0,0 +1,145 @@
+//go:build linux
+
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func decodeMemcached(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	errCh := make(chan error, 1)
+
+	go func(errCh chan error) {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		reader := bufio.NewReader(clientConn)
+		first, err := reader.Peek(1)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		binary := first[0] == magicRequest
+
+		for {
+			raw, req, err := readRequest(reader, binary)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the memcached command from the client")
+				}
+				errCh <- err
+				return
+			}
+
+			mock, err := matchRequest(ctx, req, mockDb)
+			if err != nil {
+				if ctx.Err() == nil {
+					utils.LogError(logger, err, "error while matching memcached mocks")
+				}
+				errCh <- err
+				return
+			}
+
+			var resp []byte
+			if mock == nil {
+				summary := summarizeRequest(req)
+				mockDb.RecordUnmatched(models.UnmatchedCall{
+					Kind:        models.Memcached,
+					Destination: dstCfg.Addr,
+					Summary:     summary,
+				})
+				if opts.RecordOnMiss {
+					// the commands already buffered are recorded along with the one no mock matched
+					buffered, _ := reader.Peek(reader.Buffered())
+					err = pUtil.RecordOnMiss(ctx, logger, clientConn, dstCfg, [][]byte{raw, buffered}, mockDb, opts, encodeMemcached)
+					if err != nil {
+						utils.LogError(logger, err, "failed to record the memcached commands on a miss")
+					}
+					return
+				}
+				utils.LogError(logger, nil, "no matching memcached mock found for the command", zap.Any("command", summary))
+				resp = encodeMiss(req, summary)
+			} else {
+				resp, err = encodeResponse(req, mock.Spec.MemcachedResp)
+				if err != nil {
+					utils.LogError(logger, err, "failed to encode the memcached reply", zap.Any("mock", mock.Name))
+					errCh <- err
+					return
+				}
+			}
+			if len(resp) == 0 {
+				continue
+			}
+
+			_, err = clientConn.Write(resp)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				utils.LogError(logger, err, "failed to write the memcached reply to the client application")
+				errCh <- err
+				return
+			}
+		}
+	}(errCh)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// encodeResponse encodes the recorded replies to the command, there are none for the commands without a reply.
+func encodeResponse(req *models.MemcachedRequest, responses []models.MemcachedResponse) ([]byte, error) {
+	if req.Protocol == protocolBinary {
+		return encodeBinaryResponse(req, responses)
+	}
+	if !expectsTextReply(req) {
+		return nil, nil
+	}
+	var buf []byte
+	for i := range responses {
+		resp, err := encodeTextResponse(req, &responses[i])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, resp...)
+	}
+	return buf, nil
+}
+
+// encodeMiss returns the error replied to a command no mock matched, so that the client keeps its connection.
+func encodeMiss(req *models.MemcachedRequest, summary string) []byte {
+	message := "keploy: no mock found for " + summary
+	if req.Protocol == protocolBinary {
+		if req.Command == "quitq" {
+			return nil
+		}
+		return encodeBinaryError(req, statusInternalError, message)
+	}
+	if !expectsTextReply(req) {
+		return nil
+	}
+	return []byte("SERVER_ERROR " + message + "\r\n")
+}
+
+// summarizeRequest returns the command along with its keys, e.g. "get user:1 user:2", for the report of
+// the unmatched calls.
+func summarizeRequest(req *models.MemcachedRequest) string {
+	summary := strings.TrimSpace(req.Command + " " + strings.Join(req.Keys, " "))
+	return pUtil.Summarize([][]byte{[]byte(summary)})
+}
@@ -0,0 +1,290 @@
+//go:build linux
+
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// exchange is a command of the client along with the replies of the server.
+type exchange struct {
+	req              *models.MemcachedRequest
+	resp             []models.MemcachedResponse
+	reqTimestampMock time.Time
+	resTimestampMock time.Time
+}
+
+// recorder pairs the commands of the client with the replies of the server into mocks. The server replies
+// to the commands in the order they were sent, the commands without a reply are saved as they are sent
+// or, for the quiet commands of the binary protocol, once a reply to a later command is read.
+type recorder struct {
+	connID  string
+	binary  bool
+	mu      sync.Mutex
+	pending []*exchange
+}
+
+func encodeMemcached(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+	errCh := make(chan error, 2)
+	// passthrough is set when the replies can't be paired with the commands, the rest of the connection is
+	// then forwarded without being recorded
+	var passthrough atomic.Bool
+
+	clientReader := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+	first, err := clientReader.Peek(1)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		utils.LogError(logger, err, "failed to read the memcached command from the client")
+		return err
+	}
+	rec := &recorder{
+		connID: connID,
+		binary: first[0] == magicRequest,
+	}
+
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
+	// Read and process the commands of the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		for {
+			raw, req, err := readRequest(clientReader, rec.binary)
+			if err == errMalformed {
+				logger.Warn("failed to decode the memcached command, the connection is forwarded without being recorded")
+				passthrough.Store(true)
+			} else if err != nil {
+				if len(raw) > 0 {
+					_, _ = destConn.Write(raw)
+				}
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the memcached command from the client")
+				}
+				errCh <- err
+				return nil
+			}
+
+			var done *exchange
+			if req != nil && isQuietMeta(req) {
+				logger.Warn("the quiet mode of the memcached meta commands isn't supported, the connection is forwarded without being recorded")
+				passthrough.Store(true)
+			} else if req != nil {
+				// the commands are tracked before they are forwarded, the reply of the server may be read right after
+				done = rec.request(req, time.Now())
+			}
+
+			_, err = destConn.Write(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the memcached command to the destination server")
+				errCh <- err
+				return nil
+			}
+			if done != nil {
+				rec.save(done, mocks)
+			}
+			if passthrough.Load() {
+				_, err = io.Copy(destConn, clientReader)
+				errCh <- err
+				return nil
+			}
+		}
+	})
+
+	// Read and process the replies of the server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		reader := bufio.NewReader(destConn)
+		for {
+			_, err := reader.Peek(1)
+			if err != nil {
+				if err == io.EOF {
+					logger.Debug("the memcached server closed the connection")
+				} else {
+					utils.LogError(logger, err, "failed to read the memcached reply from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			var raw []byte
+			var done []*exchange
+			if rec.binary {
+				p, err := readPacket(reader)
+				if err != nil {
+					utils.LogError(logger, err, "failed to read the memcached reply from the destination server")
+					errCh <- err
+					return nil
+				}
+				raw = p.raw
+				done = rec.binaryResponse(logger, p)
+			} else {
+				ex := rec.head()
+				if ex == nil {
+					if !passthrough.Load() {
+						logger.Debug("no memcached command found for the reply of the server")
+					}
+					_, err = io.Copy(clientConn, reader)
+					errCh <- err
+					return nil
+				}
+				var resp models.MemcachedResponse
+				raw, resp, err = readTextResponse(reader, ex.req)
+				if err != nil {
+					if len(raw) > 0 {
+						_, _ = clientConn.Write(raw)
+					}
+					utils.LogError(logger, err, "failed to read the memcached reply from the destination server")
+					errCh <- err
+					return nil
+				}
+				done = rec.textResponse(resp)
+			}
+
+			_, err = clientConn.Write(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the memcached reply to the client")
+				errCh <- err
+				return nil
+			}
+			for _, ex := range done {
+				rec.save(ex, mocks)
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// readRequest reads a command of the binary or of the text protocol, along with its raw bytes.
+func readRequest(reader *bufio.Reader, binary bool) ([]byte, *models.MemcachedRequest, error) {
+	if !binary {
+		return readTextRequest(reader)
+	}
+	p, err := readPacket(reader)
+	if err != nil {
+		if p != nil {
+			return p.raw, nil, err
+		}
+		return nil, nil, err
+	}
+	return p.raw, decodeBinaryRequest(p), nil
+}
+
+// request tracks the command until the server replies to it, the command is returned if no reply is expected.
+func (r *recorder) request(req *models.MemcachedRequest, now time.Time) *exchange {
+	ex := &exchange{req: req, reqTimestampMock: now}
+	if (!r.binary && !expectsTextReply(req)) || req.Command == "quitq" {
+		ex.resTimestampMock = now
+		return ex
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = append(r.pending, ex)
+	return nil
+}
+
+// head returns the oldest command awaiting a reply.
+func (r *recorder) head() *exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) == 0 {
+		return nil
+	}
+	return r.pending[0]
+}
+
+// textResponse completes the oldest command with the reply of the server.
+func (r *recorder) textResponse(resp models.MemcachedResponse) []*exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) == 0 {
+		return nil
+	}
+	ex := r.pending[0]
+	r.pending = r.pending[1:]
+	ex.resp = append(ex.resp, resp)
+	ex.resTimestampMock = time.Now()
+	return []*exchange{ex}
+}
+
+// binaryResponse pairs the response packet with its command by the opcode and the opaque value. The quiet
+// commands sent before it got no reply, they are completed as well. The replies to a stat command are
+// collected until the packet without a key ending them.
+func (r *recorder) binaryResponse(logger *zap.Logger, p *packet) []*exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var done []*exchange
+	for len(r.pending) > 0 {
+		ex := r.pending[0]
+		if ex.req.Command == opcodeName(p.opcode) && ex.req.Opaque == p.opaque {
+			ex.resp = append(ex.resp, decodeBinaryResponse(p, ex.req))
+			ex.resTimestampMock = time.Now()
+			if ex.req.Command == "stat" && len(p.key) > 0 && p.status == 0 {
+				return done
+			}
+			r.pending = r.pending[1:]
+			return append(done, ex)
+		}
+		if !ex.req.NoReply {
+			break
+		}
+		ex.resTimestampMock = time.Now()
+		r.pending = r.pending[1:]
+		done = append(done, ex)
+	}
+	logger.Debug("no memcached command found for the response packet", zap.Any("opcode", opcodeName(p.opcode)), zap.Any("opaque", p.opaque))
+	return done
+}
+
+// save sends the command along with its replies as a mock.
+func (r *recorder) save(ex *exchange, mocks chan<- *models.Mock) {
+	mockType := "mocks"
+	switch ex.req.Command {
+	case "version", "stats", "stat", "noop", "verbosity", "sasl-list-mechs", "sasl-auth", "sasl-step":
+		mockType = "config"
+	}
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Kind:    models.Memcached,
+		Name:    mockType,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{
+				"type":      mockType,
+				"operation": ex.req.Command,
+			},
+			MemcachedReq:     ex.req,
+			MemcachedResp:    ex.resp,
+			ReqTimestampMock: ex.reqTimestampMock,
+			ResTimestampMock: ex.resTimestampMock,
+		},
+		ConnectionID: r.connID,
+	}
+}
@@ -0,0 +1,142 @@
+//go:build linux
+
+package memcached
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// matchRequest finds the mock of the given command. The protocol, the command and the set of keys have to be
+// the same, the cas tokens and the opaque values are ignored. The mocks storing the same value with the same
+// flags and expiry are preferred, the filtered mocks first.
+func matchRequest(ctx context.Context, req *models.MemcachedRequest, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		filteredMocks, unfilteredMocks, err := getMemcachedMocks(mockDb, req)
+		if err != nil {
+			return nil, err
+		}
+
+		mock := findExactMatch(filteredMocks, req)
+		if mock == nil {
+			mock = findExactMatch(unfilteredMocks, req)
+		}
+		if mock == nil {
+			// e.g. an expiry given as a unix time
+			mock = findBestMatch(append(filteredMocks, unfilteredMocks...), req)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		if !mockDb.ConsumeMock(mock) {
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// getMemcachedMocks returns the filtered and the unfiltered memcached mocks of the same command and keys.
+func getMemcachedMocks(mockDb integrations.MockMemDb, req *models.MemcachedRequest) ([]*models.Mock, []*models.Mock, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.Memcached, requestKey(req))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var filteredMocks []*models.Mock
+	var unfilteredMocks []*models.Mock
+
+	for _, mock := range mocks {
+		if mock.Spec.MemcachedReq == nil {
+			continue
+		}
+		if mock.TestModeInfo.IsFiltered {
+			filteredMocks = append(filteredMocks, mock)
+		} else {
+			unfilteredMocks = append(unfilteredMocks, mock)
+		}
+	}
+	return filteredMocks, unfilteredMocks, nil
+}
+
+// findExactMatch returns the first mock of the same command and keys having the same arguments and value.
+func findExactMatch(mocks []*models.Mock, req *models.MemcachedRequest) *models.Mock {
+	for _, mock := range mocks {
+		expected := mock.Spec.MemcachedReq
+		if sameCommand(expected, req) && expected.Value == req.Value && expected.ValueType == req.ValueType &&
+			expected.Flags == req.Flags && expected.Exptime == req.Exptime && expected.Delta == req.Delta &&
+			expected.Initial == req.Initial && expected.Extras == req.Extras && slices.Equal(expected.Args, req.Args) {
+			return mock
+		}
+	}
+	return nil
+}
+
+// findBestMatch returns the mock of the same command and keys having the highest score.
+func findBestMatch(mocks []*models.Mock, req *models.MemcachedRequest) *models.Mock {
+	var match *models.Mock
+	best := -1
+	for _, mock := range mocks {
+		expected := mock.Spec.MemcachedReq
+		if !sameCommand(expected, req) {
+			continue
+		}
+		score := 0
+		if expected.Value == req.Value {
+			score += 2
+		}
+		if expected.Flags == req.Flags {
+			score++
+		}
+		if expected.Exptime == req.Exptime {
+			score++
+		}
+		if expected.Delta == req.Delta && expected.Initial == req.Initial {
+			score++
+		}
+		if slices.Equal(expected.Args, req.Args) {
+			score++
+		}
+		if score > best {
+			best = score
+			match = mock
+		}
+	}
+	return match
+}
+
+// sameCommand compares the protocols, the commands and the keys of the requests, the keys of a multi-get
+// may be given in any order.
+func sameCommand(a, b *models.MemcachedRequest) bool {
+	if a.Protocol != b.Protocol || a.Command != b.Command || a.NoReply != b.NoReply || len(a.Keys) != len(b.Keys) {
+		return false
+	}
+	keysA := slices.Clone(a.Keys)
+	keysB := slices.Clone(b.Keys)
+	slices.Sort(keysA)
+	slices.Sort(keysB)
+	return slices.Equal(keysA, keysB)
+}
+
+func mockKey(mock *models.Mock) string {
+	if mock.Spec.MemcachedReq == nil {
+		return ""
+	}
+	return requestKey(mock.Spec.MemcachedReq)
+}
+
+// requestKey is the index key of the request, made of the protocol, the command and the set of keys
+// compared by sameCommand.
+func requestKey(req *models.MemcachedRequest) string {
+	keys := slices.Clone(req.Keys)
+	slices.Sort(keys)
+	return fmt.Sprintf("%s %s %t %s", req.Protocol, req.Command, req.NoReply, strings.Join(keys, " "))
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+// Package memcached provides the integration for the text and the binary protocols of memcached, the
+// commands are recorded along with the replies of the server and matched on their keys.
+package memcached
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register(string(integrations.MEMCACHED), NewMemcached)
+	integrations.RegisterMissRecorder(string(integrations.MEMCACHED))
+	integrations.RegisterMockKey(models.Memcached, mockKey)
+}
+
+type Memcached struct {
+	logger *zap.Logger
+}
+
+func NewMemcached(logger *zap.Logger) integrations.Integrations {
+	return &Memcached{
+		logger: logger,
+	}
+}
+
+// MatchType checks whether the connection starts with a request of the binary protocol or with a command
+// of the text protocol, the commands of the text protocol are lowercase unlike the methods of HTTP.
+func (m *Memcached) MatchType(_ context.Context, buf []byte) bool {
+	return isBinaryRequest(buf) || isTextCommand(buf)
+}
+
+func (m *Memcached) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := encodeMemcached(ctx, logger, nil, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the memcached message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (m *Memcached) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := decodeMemcached(ctx, logger, src, dstCfg, mockDb, opts)
+	if err != nil && err != io.EOF {
+		utils.LogError(logger, err, "failed to decode the memcached message from the yaml")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,416 @@
+//go:build linux
+
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func newReader(s string) *bufio.Reader {
+	return bufio.NewReader(strings.NewReader(s))
+}
+
+func TestReadTextRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want *models.MemcachedRequest
+	}{
+		{
+			name: "set",
+			raw:  "set user:1 5 300 5\r\nalice\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "set", Keys: []string{"user:1"}, Args: []string{}, Flags: 5, Exptime: 300, Value: "alice", ValueType: models.BodyTypeUtf8},
+		},
+		{
+			name: "cas without reply",
+			raw:  "cas user:1 0 0 3 42 noreply\r\nbob\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "cas", Keys: []string{"user:1"}, Args: []string{}, CAS: 42, NoReply: true, Value: "bob", ValueType: models.BodyTypeUtf8},
+		},
+		{
+			name: "binary value",
+			raw:  "set blob 0 0 2\r\n\xff\xfe\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "set", Keys: []string{"blob"}, Args: []string{}, Value: "//4=", ValueType: models.BodyTypeBinary},
+		},
+		{
+			name: "multi-get",
+			raw:  "gets user:1 user:2\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "gets", Keys: []string{"user:1", "user:2"}},
+		},
+		{
+			name: "gat",
+			raw:  "gat 60 user:1\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "gat", Keys: []string{"user:1"}, Exptime: 60},
+		},
+		{
+			name: "incr",
+			raw:  "incr hits 3\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "incr", Keys: []string{"hits"}, Delta: 3},
+		},
+		{
+			name: "meta set",
+			raw:  "ms user:1 2 T60 q\r\nhi\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "ms", Keys: []string{"user:1"}, Args: []string{"T60", "q"}, Value: "hi", ValueType: models.BodyTypeUtf8},
+		},
+		{
+			name: "storage command without its size",
+			raw:  "set user:1 0 0\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "set", Args: []string{"user:1", "0", "0"}},
+		},
+		{
+			name: "storage command with an invalid size",
+			raw:  "set user:1 0 0 many\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText, Command: "set", Args: []string{"user:1", "0", "0", "many"}},
+		},
+		{
+			name: "empty line",
+			raw:  "\r\n",
+			want: &models.MemcachedRequest{Protocol: protocolText},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, got, err := readTextRequest(newReader(tt.raw))
+			if err != nil {
+				t.Fatalf("failed to read the request: %v", err)
+			}
+			if string(raw) != tt.raw {
+				t.Errorf("got the raw bytes %q, want %q", raw, tt.raw)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextResponseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *models.MemcachedRequest
+		resp models.MemcachedResponse
+		raw  string
+	}{
+		{
+			name: "multi-get",
+			req:  &models.MemcachedRequest{Protocol: protocolText, Command: "get", Keys: []string{"a", "b"}},
+			resp: models.MemcachedResponse{Status: "END", Items: []models.MemcachedItem{
+				{Key: "a", Flags: 1, Value: "x", ValueType: models.BodyTypeUtf8},
+				{Key: "b", Value: "//4=", ValueType: models.BodyTypeBinary},
+			}},
+			raw: "VALUE a 1 1\r\nx\r\nVALUE b 0 2\r\n\xff\xfe\r\nEND\r\n",
+		},
+		{
+			name: "gets",
+			req:  &models.MemcachedRequest{Protocol: protocolText, Command: "gets", Keys: []string{"a"}},
+			resp: models.MemcachedResponse{Status: "END", Items: []models.MemcachedItem{{Key: "a", CAS: 9, Value: "x", ValueType: models.BodyTypeUtf8}}},
+			raw:  "VALUE a 0 1 9\r\nx\r\nEND\r\n",
+		},
+		{
+			name: "stats",
+			req:  &models.MemcachedRequest{Protocol: protocolText, Command: "stats"},
+			resp: models.MemcachedResponse{Status: "END", Items: []models.MemcachedItem{{Key: "pid", Value: "1"}, {Key: "uptime", Value: "20"}}},
+			raw:  "STAT pid 1\r\nSTAT uptime 20\r\nEND\r\n",
+		},
+		{
+			name: "meta get",
+			req:  &models.MemcachedRequest{Protocol: protocolText, Command: "mg", Keys: []string{"a"}, Args: []string{"v"}},
+			resp: models.MemcachedResponse{Status: "VA f1", Items: []models.MemcachedItem{{Value: "xy", ValueType: models.BodyTypeUtf8}}},
+			raw:  "VA 2 f1\r\nxy\r\n",
+		},
+		{
+			name: "incr",
+			req:  &models.MemcachedRequest{Protocol: protocolText, Command: "incr", Keys: []string{"hits"}, Delta: 1},
+			resp: models.MemcachedResponse{Status: "4"},
+			raw:  "4\r\n",
+		},
+		{
+			name: "set",
+			req:  &models.MemcachedRequest{Protocol: protocolText, Command: "set", Keys: []string{"a"}},
+			resp: models.MemcachedResponse{Status: "STORED"},
+			raw:  "STORED\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeTextResponse(tt.req, &tt.resp)
+			if err != nil {
+				t.Fatalf("failed to encode the response: %v", err)
+			}
+			if string(encoded) != tt.raw {
+				t.Errorf("got %q, want %q", encoded, tt.raw)
+			}
+			raw, got, err := readTextResponse(newReader(tt.raw), tt.req)
+			if err != nil {
+				t.Fatalf("failed to read the response: %v", err)
+			}
+			if string(raw) != tt.raw {
+				t.Errorf("got the raw bytes %q, want %q", raw, tt.raw)
+			}
+			if !reflect.DeepEqual(got, tt.resp) {
+				t.Errorf("got %+v, want %+v", got, tt.resp)
+			}
+		})
+	}
+}
+
+func TestReadTextMalformed(t *testing.T) {
+	get := &models.MemcachedRequest{Protocol: protocolText, Command: "get", Keys: []string{"a"}}
+	mg := &models.MemcachedRequest{Protocol: protocolText, Command: "mg", Keys: []string{"a"}}
+
+	tests := []struct {
+		name string
+		read func() error
+	}{
+		{name: "negative size of the data block", read: func() error {
+			_, _, err := readTextRequest(newReader("set a 0 0 -1\r\n"))
+			return err
+		}},
+		{name: "data block larger than the maximum", read: func() error {
+			_, _, err := readTextRequest(newReader("set a 0 0 999999999999\r\n"))
+			return err
+		}},
+		{name: "value line without its size", read: func() error {
+			_, _, err := readTextResponse(newReader("VALUE a 0\r\n"), get)
+			return err
+		}},
+		{name: "value line with an invalid size", read: func() error {
+			_, _, err := readTextResponse(newReader("VALUE a 0 x\r\n"), get)
+			return err
+		}},
+		{name: "meta value with an invalid size", read: func() error {
+			_, _, err := readTextResponse(newReader("VA x\r\n"), mg)
+			return err
+		}},
+		{name: "meta value with a negative size", read: func() error {
+			_, _, err := readTextResponse(newReader("VA -3\r\n"), mg)
+			return err
+		}},
+	}
+
+	request := "set user:1 0 0 5\r\nalice\r\n"
+	for i := 1; i < len(request); i++ {
+		truncated := request[:i]
+		tests = append(tests, struct {
+			name string
+			read func() error
+		}{name: "truncated request", read: func() error {
+			_, _, err := readTextRequest(newReader(truncated))
+			return err
+		}})
+	}
+	response := "VALUE a 1 1\r\nx\r\nEND\r\n"
+	for i := 1; i < len(response); i++ {
+		truncated := response[:i]
+		tests = append(tests, struct {
+			name string
+			read func() error
+		}{name: "truncated response", read: func() error {
+			_, _, err := readTextResponse(newReader(truncated), get)
+			return err
+		}})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.read()
+			if err == nil || err == io.EOF {
+				t.Fatalf("got the error %v, want a malformed or a truncated message", err)
+			}
+		})
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	setExtras := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 7), 300)
+	incrExtras := binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(nil, 2), 10)
+	incrExtras = binary.BigEndian.AppendUint32(incrExtras, 0)
+
+	tests := []struct {
+		name      string
+		packet    []byte
+		want      *models.MemcachedRequest
+		responses []models.MemcachedResponse
+	}{
+		{
+			name:   "get",
+			packet: appendPacket(nil, magicRequest, 0x00, 0, 11, 0, nil, []byte("user:1"), nil),
+			want:   &models.MemcachedRequest{Protocol: protocolBinary, Command: "get", Keys: []string{"user:1"}, Opaque: 11},
+			responses: []models.MemcachedResponse{{Status: "no-error", Items: []models.MemcachedItem{
+				{Flags: 7, CAS: 3, Value: "alice", ValueType: models.BodyTypeUtf8},
+			}}},
+		},
+		{
+			name:      "set",
+			packet:    appendPacket(nil, magicRequest, 0x01, 0, 12, 5, setExtras, []byte("user:1"), []byte("alice")),
+			want:      &models.MemcachedRequest{Protocol: protocolBinary, Command: "set", Keys: []string{"user:1"}, Opaque: 12, CAS: 5, Flags: 7, Exptime: 300, Value: "alice", ValueType: models.BodyTypeUtf8},
+			responses: []models.MemcachedResponse{{Status: "key-exists", Items: []models.MemcachedItem{{Value: "Data exists for key.", ValueType: models.BodyTypeUtf8}}}},
+		},
+		{
+			name:      "increment",
+			packet:    appendPacket(nil, magicRequest, 0x05, 0, 13, 0, incrExtras, []byte("hits"), nil),
+			want:      &models.MemcachedRequest{Protocol: protocolBinary, Command: "increment", Keys: []string{"hits"}, Opaque: 13, Delta: 2, Initial: 10},
+			responses: []models.MemcachedResponse{{Status: "no-error", Items: []models.MemcachedItem{{CAS: 4, Value: "12", ValueType: models.BodyTypeUtf8}}}},
+		},
+		{
+			name:      "quiet set",
+			packet:    appendPacket(nil, magicRequest, 0x11, 0, 14, 0, setExtras, []byte("k"), []byte{0xff}),
+			want:      &models.MemcachedRequest{Protocol: protocolBinary, Command: "setq", Keys: []string{"k"}, Opaque: 14, NoReply: true, Flags: 7, Exptime: 300, Value: "/w==", ValueType: models.BodyTypeBinary},
+			responses: nil,
+		},
+		{
+			name:      "unknown extras",
+			packet:    appendPacket(nil, magicRequest, 0x0a, 0, 15, 0, []byte{1, 2}, nil, nil),
+			want:      &models.MemcachedRequest{Protocol: protocolBinary, Command: "noop", Opaque: 15, Extras: "AQI="},
+			responses: []models.MemcachedResponse{{Status: "no-error"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !isBinaryRequest(tt.packet) {
+				t.Fatal("the packet is not detected as a binary request")
+			}
+			raw, got, err := readRequest(bufio.NewReader(bytes.NewReader(tt.packet)), true)
+			if err != nil {
+				t.Fatalf("failed to read the request: %v", err)
+			}
+			if !bytes.Equal(raw, tt.packet) {
+				t.Errorf("got the raw bytes %x, want %x", raw, tt.packet)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+
+			encoded, err := encodeBinaryResponse(got, tt.responses)
+			if err != nil {
+				t.Fatalf("failed to encode the responses: %v", err)
+			}
+			reader := bufio.NewReader(bytes.NewReader(encoded))
+			var responses []models.MemcachedResponse
+			for {
+				p, err := readPacket(reader)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("failed to read the response: %v", err)
+				}
+				if p.opaque != got.Opaque || p.magic != magicResponse {
+					t.Errorf("got the opaque %d and the magic %#x", p.opaque, p.magic)
+				}
+				responses = append(responses, decodeBinaryResponse(p, got))
+			}
+			if !reflect.DeepEqual(responses, tt.responses) {
+				t.Errorf("got the responses %+v, want %+v", responses, tt.responses)
+			}
+		})
+	}
+}
+
+func TestReadPacketMalformed(t *testing.T) {
+	valid := appendPacket(nil, magicRequest, 0x01, 0, 1, 0, make([]byte, 8), []byte("key"), []byte("value"))
+	badMagic := bytes.Clone(valid)
+	badMagic[0] = 0x42
+	keyAboveBody := bytes.Clone(valid)
+	binary.BigEndian.PutUint16(keyAboveBody[2:], 100)
+	tooLarge := bytes.Clone(valid)
+	binary.BigEndian.PutUint32(tooLarge[8:], maxValueSize+1)
+
+	tests := []struct {
+		name   string
+		packet []byte
+	}{
+		{name: "invalid magic", packet: badMagic},
+		{name: "key longer than the body", packet: keyAboveBody},
+		{name: "body larger than the maximum", packet: tooLarge},
+	}
+	for i := 1; i < len(valid); i++ {
+		tests = append(tests, struct {
+			name   string
+			packet []byte
+		}{name: "truncated", packet: valid[:i]})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readRequest(bufio.NewReader(bytes.NewReader(tt.packet)), true)
+			if err == nil || err == io.EOF {
+				t.Fatalf("got the error %v, want a malformed or a truncated packet", err)
+			}
+		})
+	}
+}
+
+func TestEncodeMalformedMocks(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *models.MemcachedRequest
+		responses []models.MemcachedResponse
+	}{
+		{
+			name:      "unknown binary command",
+			req:       &models.MemcachedRequest{Protocol: protocolBinary, Command: "fly"},
+			responses: []models.MemcachedResponse{{Status: "no-error"}},
+		},
+		{
+			name:      "unknown binary status",
+			req:       &models.MemcachedRequest{Protocol: protocolBinary, Command: "get"},
+			responses: []models.MemcachedResponse{{Status: "maybe"}},
+		},
+		{
+			name:      "counter which isn't a number",
+			req:       &models.MemcachedRequest{Protocol: protocolBinary, Command: "increment"},
+			responses: []models.MemcachedResponse{{Status: "no-error", Items: []models.MemcachedItem{{Value: "many"}}}},
+		},
+		{
+			name:      "invalid binary value",
+			req:       &models.MemcachedRequest{Protocol: protocolText, Command: "get"},
+			responses: []models.MemcachedResponse{{Status: "END", Items: []models.MemcachedItem{{Key: "a", Value: "!", ValueType: models.BodyTypeBinary}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encodeResponse(tt.req, tt.responses)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestMockKey(t *testing.T) {
+	recorded := &models.Mock{Spec: models.MockSpec{MemcachedReq: &models.MemcachedRequest{Protocol: protocolText, Command: "get", Keys: []string{"b", "a"}}}}
+	tests := []struct {
+		name string
+		req  *models.MemcachedRequest
+		same bool
+	}{
+		{name: "keys in another order", req: &models.MemcachedRequest{Protocol: protocolText, Command: "get", Keys: []string{"a", "b"}}, same: true},
+		{name: "another command", req: &models.MemcachedRequest{Protocol: protocolText, Command: "gets", Keys: []string{"a", "b"}}},
+		{name: "another protocol", req: &models.MemcachedRequest{Protocol: protocolBinary, Command: "get", Keys: []string{"a", "b"}}},
+		{name: "fewer keys", req: &models.MemcachedRequest{Protocol: protocolText, Command: "get", Keys: []string{"a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same := mockKey(recorded) == requestKey(tt.req)
+			if same != tt.same || same != sameCommand(recorded.Spec.MemcachedReq, tt.req) {
+				t.Errorf("got the same key: %t, want %t", same, tt.same)
+			}
+		})
+	}
+	if mockKey(&models.Mock{}) != "" {
+		t.Error("expected no key for a mock without a memcached request")
+	}
+}
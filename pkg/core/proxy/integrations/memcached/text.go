@@ -0,0 +1,340 @@
+//go:build linux
+
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const (
+	protocolText   = "text"
+	protocolBinary = "binary"
+)
+
+// maxValueSize is the limit of the size of an item, the sizes above it are treated as malformed commands
+const maxValueSize = 128 << 20
+
+var errMalformed = errors.New("malformed memcached message")
+
+var (
+	storageCommands   = map[string]bool{"set": true, "add": true, "replace": true, "append": true, "prepend": true, "cas": true}
+	retrievalCommands = map[string]bool{"get": true, "gets": true, "gat": true, "gats": true}
+	// noReplyCommands are the commands of the text protocol taking the noreply argument
+	noReplyCommands = map[string]bool{"set": true, "add": true, "replace": true, "append": true, "prepend": true, "cas": true,
+		"delete": true, "incr": true, "decr": true, "touch": true, "flush_all": true, "verbosity": true}
+	metaCommands = map[string]bool{"mg": true, "ms": true, "md": true, "ma": true, "mn": true, "me": true}
+	textCommands = map[string]bool{"delete": true, "incr": true, "decr": true, "touch": true, "flush_all": true, "version": true,
+		"verbosity": true, "stats": true, "quit": true, "cache_memlimit": true}
+)
+
+// isTextCommand checks whether the buffer starts with a command of the text protocol.
+func isTextCommand(buf []byte) bool {
+	end := bytes.IndexAny(buf, " \r\n")
+	if end <= 0 {
+		return false
+	}
+	cmd := string(buf[:end])
+	return storageCommands[cmd] || retrievalCommands[cmd] || metaCommands[cmd] || textCommands[cmd]
+}
+
+// readLine reads a line terminated by \r\n, the line is returned along with its terminator.
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return line, err
+	}
+	return line, nil
+}
+
+// readDataBlock reads a data block of the given size, followed by \r\n.
+func readDataBlock(reader *bufio.Reader, size int) ([]byte, error) {
+	if size < 0 || size > maxValueSize {
+		return nil, errMalformed
+	}
+	data := make([]byte, size+2)
+	_, err := io.ReadFull(reader, data)
+	if err == io.EOF {
+		// the connection is closed before the data block of the command
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readTextRequest reads a command of the text protocol along with the data block of the storage commands.
+// The raw bytes of the command are returned to be forwarded to the server.
+func readTextRequest(reader *bufio.Reader) ([]byte, *models.MemcachedRequest, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return line, nil, err
+	}
+	raw := line
+	fields := strings.Fields(string(line))
+	req := &models.MemcachedRequest{Protocol: protocolText}
+	if len(fields) == 0 {
+		return raw, req, nil
+	}
+	req.Command = fields[0]
+	args := fields[1:]
+	if noReplyCommands[req.Command] && len(args) > 0 && args[len(args)-1] == "noreply" {
+		req.NoReply = true
+		args = args[:len(args)-1]
+	}
+
+	switch {
+	case storageCommands[req.Command]:
+		// <command> <key> <flags> <exptime> <bytes> [<cas unique>]
+		n := 4
+		if req.Command == "cas" {
+			n = 5
+		}
+		if len(args) < n {
+			// the server replies with an error without reading a data block
+			req.Args = args
+			return raw, req, nil
+		}
+		flags, err1 := strconv.ParseUint(args[1], 10, 32)
+		exptime, err2 := strconv.ParseInt(args[2], 10, 64)
+		size, err3 := strconv.Atoi(args[3])
+		if err := errors.Join(err1, err2, err3); err != nil {
+			req.Args = args
+			return raw, req, nil
+		}
+		if n == 5 {
+			req.CAS, _ = strconv.ParseUint(args[4], 10, 64)
+		}
+		req.Keys = []string{args[0]}
+		req.Flags = uint32(flags)
+		req.Exptime = exptime
+		req.Args = args[n:]
+		data, err := readDataBlock(reader, size)
+		if err != nil {
+			return raw, nil, err
+		}
+		raw = append(raw, data...)
+		req.Value, req.ValueType = encodeValue(data[:size])
+	case req.Command == "ms":
+		// ms <key> <datalen> <flags>*
+		if len(args) < 2 {
+			req.Args = args
+			return raw, req, nil
+		}
+		size, err := strconv.Atoi(args[1])
+		if err != nil {
+			req.Args = args
+			return raw, req, nil
+		}
+		req.Keys = []string{args[0]}
+		req.Args = args[2:]
+		data, err := readDataBlock(reader, size)
+		if err != nil {
+			return raw, nil, err
+		}
+		raw = append(raw, data...)
+		req.Value, req.ValueType = encodeValue(data[:size])
+	case req.Command == "gat" || req.Command == "gats":
+		// gat <exptime> <key>*
+		if len(args) > 0 {
+			req.Exptime, _ = strconv.ParseInt(args[0], 10, 64)
+			req.Keys = args[1:]
+		}
+	case retrievalCommands[req.Command]:
+		req.Keys = args
+	case req.Command == "incr" || req.Command == "decr":
+		if len(args) == 2 {
+			req.Keys = []string{args[0]}
+			req.Delta, _ = strconv.ParseUint(args[1], 10, 64)
+		} else {
+			req.Args = args
+		}
+	case req.Command == "touch":
+		if len(args) == 2 {
+			req.Keys = []string{args[0]}
+			req.Exptime, _ = strconv.ParseInt(args[1], 10, 64)
+		} else {
+			req.Args = args
+		}
+	case req.Command == "delete" || metaCommands[req.Command] && req.Command != "mn":
+		// the meta commands take the key followed by their flags
+		if len(args) > 0 {
+			req.Keys = []string{args[0]}
+			req.Args = args[1:]
+		}
+	default:
+		req.Args = args
+	}
+	return raw, req, nil
+}
+
+// isQuietMeta checks whether the meta command has the q flag, with which the server omits the common replies.
+func isQuietMeta(req *models.MemcachedRequest) bool {
+	if req.Protocol != protocolText || !metaCommands[req.Command] {
+		return false
+	}
+	for _, flag := range req.Args {
+		if flag == "q" {
+			return true
+		}
+	}
+	return false
+}
+
+// expectsTextReply checks whether the server replies to the command of the text protocol.
+func expectsTextReply(req *models.MemcachedRequest) bool {
+	return !req.NoReply && req.Command != "quit"
+}
+
+// readTextResponse reads the reply of the server to the given command of the text protocol.
+func readTextResponse(reader *bufio.Reader, req *models.MemcachedRequest) ([]byte, models.MemcachedResponse, error) {
+	var raw []byte
+	resp := models.MemcachedResponse{}
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF && len(raw) > 0 {
+			// the connection is closed in the middle of the reply
+			err = io.ErrUnexpectedEOF
+		}
+		raw = append(raw, line...)
+		if err != nil {
+			return raw, resp, err
+		}
+		text := strings.TrimRight(string(line), "\r\n")
+
+		switch {
+		case retrievalCommands[req.Command] && strings.HasPrefix(text, "VALUE "):
+			// VALUE <key> <flags> <bytes> [<cas unique>]
+			fields := strings.Fields(text)
+			if len(fields) < 4 {
+				return raw, resp, errMalformed
+			}
+			flags, err1 := strconv.ParseUint(fields[2], 10, 32)
+			size, err2 := strconv.Atoi(fields[3])
+			if err := errors.Join(err1, err2); err != nil {
+				return raw, resp, errMalformed
+			}
+			item := models.MemcachedItem{Key: fields[1], Flags: uint32(flags)}
+			if len(fields) > 4 {
+				item.CAS, _ = strconv.ParseUint(fields[4], 10, 64)
+			}
+			data, err := readDataBlock(reader, size)
+			if err != nil {
+				return raw, resp, err
+			}
+			raw = append(raw, data...)
+			item.Value, item.ValueType = encodeValue(data[:size])
+			resp.Items = append(resp.Items, item)
+			continue
+		case req.Command == "stats" && !isFinalStatsLine(text):
+			item := models.MemcachedItem{Value: text}
+			if stat, ok := strings.CutPrefix(text, "STAT "); ok {
+				item.Key, item.Value, _ = strings.Cut(stat, " ")
+			}
+			resp.Items = append(resp.Items, item)
+			continue
+		case (req.Command == "mg" || req.Command == "ma") && strings.HasPrefix(text, "VA "):
+			// VA <size> <flags>*, the size is left out of the status as it is set from the value
+			fields := strings.Fields(text)
+			if len(fields) < 2 {
+				return raw, resp, errMalformed
+			}
+			size, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return raw, resp, errMalformed
+			}
+			data, err := readDataBlock(reader, size)
+			if err != nil {
+				return raw, resp, err
+			}
+			raw = append(raw, data...)
+			item := models.MemcachedItem{}
+			item.Value, item.ValueType = encodeValue(data[:size])
+			resp.Items = append(resp.Items, item)
+			text = strings.Join(append([]string{"VA"}, fields[2:]...), " ")
+		}
+		resp.Status = text
+		return raw, resp, nil
+	}
+}
+
+// isFinalStatsLine checks whether the line ends the reply of a stats command.
+func isFinalStatsLine(line string) bool {
+	switch line {
+	case "END", "RESET", "OK", "ERROR":
+		return true
+	}
+	return strings.HasPrefix(line, "CLIENT_ERROR") || strings.HasPrefix(line, "SERVER_ERROR")
+}
+
+// encodeTextResponse encodes the recorded reply to the given command of the text protocol.
+func encodeTextResponse(req *models.MemcachedRequest, resp *models.MemcachedResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	status := resp.Status
+	for _, item := range resp.Items {
+		switch {
+		case retrievalCommands[req.Command]:
+			value, err := decodeValue(item.Value, item.ValueType)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "VALUE %s %d %d", item.Key, item.Flags, len(value))
+			if req.Command == "gets" || req.Command == "gats" {
+				fmt.Fprintf(&buf, " %d", item.CAS)
+			}
+			buf.WriteString("\r\n")
+			buf.Write(value)
+			buf.WriteString("\r\n")
+		case req.Command == "stats":
+			if item.Key != "" {
+				fmt.Fprintf(&buf, "STAT %s %s\r\n", item.Key, item.Value)
+			} else {
+				buf.WriteString(item.Value + "\r\n")
+			}
+		case strings.HasPrefix(status, "VA"):
+			value, err := decodeValue(item.Value, item.ValueType)
+			if err != nil {
+				return nil, err
+			}
+			fields := strings.Fields(status)
+			line := strings.Join(append([]string{"VA", strconv.Itoa(len(value))}, fields[1:]...), " ")
+			buf.WriteString(line + "\r\n")
+			buf.Write(value)
+			buf.WriteString("\r\n")
+			return buf.Bytes(), nil
+		}
+	}
+	buf.WriteString(status + "\r\n")
+	return buf.Bytes(), nil
+}
+
+// encodeValue stores the values as text, unless they are binary.
+func encodeValue(value []byte) (string, models.BodyType) {
+	if len(value) == 0 {
+		return "", ""
+	}
+	if utf8.Valid(value) {
+		return string(value), models.BodyTypeUtf8
+	}
+	return base64.StdEncoding.EncodeToString(value), models.BodyTypeBinary
+}
+
+func decodeValue(value string, typ models.BodyType) ([]byte, error) {
+	if typ == models.BodyTypeBinary {
+		return base64.StdEncoding.DecodeString(value)
+	}
+	return []byte(value), nil
+}
@@ -22,8 +22,9 @@ import (
 )
 
 // decodeMongo decodes the mongo wire message from the client connection
-// and sends the response back to the client.
-func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+// and sends the response back to the client. The connection is recorded with encode
+// from the first request which no mock matches, in the record on miss mode.
+func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions, encode util.EncodeFunc) error {
 	startedDecoding := time.Now()
 	requestBuffers := [][]byte{reqBuf}
 
@@ -124,7 +125,7 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 				var maxMatchScore = 0.0
 				var configMocks []*models.Mock
 				for {
-					configMocks, err = mockDb.GetUnFilteredMocks()
+					configMocks, err = mockDb.GetUnFilteredMocksByKind(models.Mongo)
 					if err != nil {
 						utils.LogError(logger, err, "error while getting config mock")
 					}
@@ -286,6 +287,15 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 				}
 				if !matched {
 					logger.Debug("mongo request not matched with any tcsMocks", zap.Any("request", mongoRequests))
+					mockDb.RecordUnmatched(models.UnmatchedCall{
+						Kind:        models.Mongo,
+						Destination: dstCfg.Addr,
+						Summary:     summarizeRequests(mongoRequests),
+					})
+					if opts.RecordOnMiss {
+						errCh <- util.RecordOnMiss(ctx, logger, clientConn, dstCfg, requestBuffers, mockDb, opts, encode)
+						return
+					}
 					reqBuf, err = util.PassThrough(ctx, logger, clientConn, dstCfg, requestBuffers)
 					if err != nil {
 						utils.LogError(logger, err, "failed to passthrough the mongo request to the actual database server")
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/utils"
 	"go.mongodb.org/mongo-driver/bson"
 
@@ -24,17 +25,14 @@ func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.Mongo
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetFilteredMocks()
+			// the mocks of the same command on the same collection are looked up first
+			tcsMocks, err := mockDb.GetFilteredMocksByKey(models.Mongo, requestsKey(mongoRequests))
+			if err == nil && len(tcsMocks) == 0 {
+				tcsMocks, err = mockDb.GetFilteredMocksByKind(models.Mongo)
+			}
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting tcs mock: %v", err)
 			}
-			var tcsMocks []*models.Mock
-			for _, mock := range mocks {
-				if mock.Kind != "Mongo" {
-					continue
-				}
-				tcsMocks = append(tcsMocks, mock)
-			}
 			maxMatchScore := 0.0
 			bestMatchIndex := -1
 			// iterate over the tcsMocks and compare the incoming mongo requests with the recorded mongo requests.
@@ -90,6 +88,58 @@ func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.Mongo
 	}
 }
 
+// mockKey returns the opcode, the command and the collection of the first request of the mock, on which
+// the mongo mocks are indexed.
+func mockKey(mock *models.Mock) string {
+	return requestsKey(mock.Spec.MongoRequests)
+}
+
+func requestsKey(mongoRequests []models.MongoRequest) string {
+	if len(mongoRequests) == 0 || mongoRequests[0].Header == nil {
+		return ""
+	}
+	var collection string
+	switch msg := mongoRequests[0].Message.(type) {
+	case *models.MongoOpMessage:
+		collection = sectionCollection(msg.Sections)
+	case *models.MongoOpQuery:
+		collection = msg.FullCollectionName
+	}
+	return fmt.Sprintf("%d %s", mongoRequests[0].Header.Opcode, collection)
+}
+
+// summarizeRequests returns the document of the first request, for the report of the unmatched calls.
+func summarizeRequests(mongoRequests []models.MongoRequest) string {
+	if len(mongoRequests) == 0 {
+		return ""
+	}
+	var summary string
+	switch msg := mongoRequests[0].Message.(type) {
+	case *models.MongoOpMessage:
+		summary = strings.Join(msg.Sections, " ")
+	case *models.MongoOpQuery:
+		summary = msg.FullCollectionName + " " + msg.Query
+	}
+	return pUtil.Summarize([][]byte{[]byte(summary)})
+}
+
+// sectionCollection returns the command and the collection of the document of the OpMsg.
+func sectionCollection(sections []string) string {
+	if len(sections) == 0 || !strings.HasPrefix(sections[0], "{ SectionSingle msg:") {
+		return ""
+	}
+	doc, err := extractSectionSingle(sections[0])
+	if err != nil {
+		return ""
+	}
+	var cmd bson.D
+	if err := bson.UnmarshalExtJSON([]byte(doc), true, &cmd); err != nil || len(cmd) == 0 {
+		return ""
+	}
+	collection, _ := cmd[0].Value.(string)
+	return cmd[0].Key + " " + collection
+}
+
 func compareOpMsgSection(logger *zap.Logger, expectedSection, actualSection string) float64 {
 	// check that the sections are of same type. SectionSingle (section[16] is "m") or SectionSequence (section[16] is "i").
 	if (len(expectedSection) < 16 || len(actualSection) < 16) && expectedSection[16] != actualSection[16] {
@@ -20,6 +20,8 @@ import (
 
 func init() {
 	integrations.Register("mongo", NewMongo)
+	integrations.RegisterMissRecorder("mongo")
+	integrations.RegisterMockKey(models.Mongo, mockKey)
 }
 
 type Mongo struct {
@@ -83,7 +85,7 @@ func (m *Mongo) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrat
 	}
 
 	// converts the yaml string into the binary packet
-	err = decodeMongo(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	err = decodeMongo(ctx, logger, reqBuf, src, dstCfg, mockDb, opts, m.encodeMongo)
 	if err != nil {
 		utils.LogError(logger, err, "failed to decode the mongo message")
 		return err
@@ -0,0 +1,61 @@
+//go:build linux
+
+// Package mssql provides the integration for the Tabular Data Stream protocol of Microsoft SQL Server,
+// including the TLS negotiated within its PRELOGIN messages.
+package mssql
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mssql/recorder"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mssql/replayer"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mssql/wire"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register(string(integrations.MSSQL), New)
+}
+
+type MSSQL struct {
+	logger *zap.Logger
+}
+
+func New(logger *zap.Logger) integrations.Integrations {
+	return &MSSQL{
+		logger: logger,
+	}
+}
+
+// MatchType checks whether the connection starts with a PRELOGIN message, which the clients send before
+// negotiating the TLS and logging in.
+func (m *MSSQL) MatchType(_ context.Context, buf []byte) bool {
+	return wire.IsPreLogin(buf)
+}
+
+func (m *MSSQL) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := recorder.Record(ctx, logger, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the mssql message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (m *MSSQL) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := replayer.Replay(ctx, logger, src, dstCfg, mockDb, opts)
+	if err != nil && err != io.EOF {
+		utils.LogError(logger, err, "failed to decode the mssql message from the yaml")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+//go:build linux
+
+// Package recorder is used to record the SQL Server traffic between the client and the server.
+package recorder
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mssql/wire"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// stream is a side of the connection, either in the clear or within the TLS negotiated by the PRELOGIN.
+type stream struct {
+	r io.Reader
+	w io.Writer
+}
+
+type inflight struct {
+	req              *models.MSSQLRequest
+	reqTimestampMock time.Time
+}
+
+// session pairs the requests of the client with the replies of the server, which replies to them in the
+// order they were sent. It holds the statements prepared on the connection by their handle.
+type session struct {
+	mu       sync.Mutex
+	requests []inflight
+	handles  map[int32]string
+}
+
+// Binary to Mock Yaml
+
+func Record(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+	errCh := make(chan error, 2)
+
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
+	client, server, mars, err := handshake(ctx, logger, clientConn, destConn, connID, mocks)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if mars {
+		logger.Warn("the multiple active result sets of mssql aren't supported, the connection is forwarded without being recorded. Disable MARS in the connection string to record it.")
+		g.Go(func() error {
+			defer pUtil.Recover(logger, clientConn, destConn)
+			_, err := io.Copy(server.w, client.r)
+			errCh <- err
+			return nil
+		})
+		g.Go(func() error {
+			defer pUtil.Recover(logger, clientConn, destConn)
+			_, err := io.Copy(client.w, server.r)
+			errCh <- err
+			return nil
+		})
+	} else {
+		s := &session{handles: make(map[int32]string)}
+
+		// Read and process the requests from the client
+		g.Go(func() error {
+			defer pUtil.Recover(logger, clientConn, destConn)
+			for {
+				msg, err := wire.ReadMessage(client.r)
+				if err != nil {
+					if err != io.EOF {
+						utils.LogError(logger, err, "failed to read the mssql request from the client")
+					}
+					errCh <- err
+					return nil
+				}
+				req, err := wire.DecodeRequest(msg, s.lookup)
+				if err != nil {
+					logger.Debug("failed to decode the mssql request", zap.Error(err))
+				}
+				// the request is tracked before it is forwarded, the reply of the server may be read right after
+				s.push(inflight{req: req, reqTimestampMock: time.Now()})
+
+				_, err = server.w.Write(msg.Raw)
+				if err != nil {
+					utils.LogError(logger, err, "failed to write the mssql request to the destination server")
+					errCh <- err
+					return nil
+				}
+			}
+		})
+
+		// Read and process the replies from the destination server
+		g.Go(func() error {
+			defer pUtil.Recover(logger, clientConn, destConn)
+			for {
+				msg, err := wire.ReadMessage(server.r)
+				if err != nil {
+					if err == io.EOF {
+						logger.Debug("the mssql server closed the connection")
+					} else {
+						utils.LogError(logger, err, "failed to read the mssql reply from the destination server")
+					}
+					errCh <- err
+					return nil
+				}
+				resp, attention, err := wire.DecodeResponse(msg.Payload)
+				if err != nil {
+					logger.Debug("failed to decode the mssql reply", zap.Error(err))
+				}
+				req, ok := s.pop(attention)
+				if ok {
+					if handle, query, prepared := wire.PreparedHandle(req.req, resp); prepared {
+						s.prepare(handle, query)
+					}
+				}
+
+				_, err = client.w.Write(msg.Raw)
+				if err != nil {
+					utils.LogError(logger, err, "failed to write the mssql reply to the client")
+					errCh <- err
+					return nil
+				}
+				if ok {
+					recordMock(req, resp, connID, mocks)
+				}
+			}
+		})
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// handshake forwards the PRELOGIN and the LOGIN7 messages along with the replies of the server, and runs the
+// TLS they negotiate with both the client and the server. It returns the sides of the connection the
+// following messages are exchanged on, and whether the server accepted the multiple active result sets.
+func handshake(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, connID string, mocks chan<- *models.Mock) (stream, stream, bool, error) {
+	clientReader := bufio.NewReader(clientConn)
+	destReader := bufio.NewReader(destConn)
+	plainClient := stream{r: clientReader, w: clientConn}
+	plainServer := stream{r: destReader, w: destConn}
+	client, server := plainClient, plainServer
+
+	preLogin, err := forward(client, server, wire.PacketPreLogin)
+	if err != nil {
+		return client, server, false, err
+	}
+	reply, err := wire.ReadMessage(server.r)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the mssql prelogin reply from the destination server")
+		return client, server, false, err
+	}
+	_, err = client.w.Write(reply.Raw)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the mssql prelogin reply to the client")
+		return client, server, false, err
+	}
+	resp := &models.MSSQLResponse{Payload: base64.StdEncoding.EncodeToString(reply.Payload)}
+	resp.PreLogin, err = wire.DecodePreLogin(reply.Payload)
+	if err != nil {
+		logger.Debug("failed to decode the mssql prelogin reply", zap.Error(err))
+	}
+	recordMock(preLogin, resp, connID, mocks)
+	mars := wire.MARSEnabled(resp.PreLogin)
+
+	encryption := wire.Negotiate(wire.EncryptionOf(resp.PreLogin))
+	if encryption != wire.EncryptNone {
+		cfg, ok := ctx.Value(models.TLSConfigKey).(*tls.Config)
+		if !ok {
+			return client, server, false, errors.New("failed to get the tls config from the context")
+		}
+		host, _, err := net.SplitHostPort(destConn.RemoteAddr().String())
+		if err != nil {
+			return client, server, false, err
+		}
+
+		serverHandshake := wire.NewHandshakeConn(destConn, destReader)
+		serverConn := tls.Client(serverHandshake, wire.ClientTLSConfig(host))
+		err = serverConn.HandshakeContext(ctx)
+		if err != nil {
+			utils.LogError(logger, err, "failed to complete the tls handshake with the mssql server")
+			return client, server, false, err
+		}
+		serverHandshake.Complete()
+
+		clientHandshake := wire.NewHandshakeConn(clientConn, clientReader)
+		clientTLS := tls.Server(clientHandshake, wire.ServerTLSConfig(cfg, host))
+		err = clientTLS.HandshakeContext(ctx)
+		if err != nil {
+			utils.LogError(logger, err, "failed to complete the tls handshake with the mssql client")
+			return client, server, false, err
+		}
+		clientHandshake.Complete()
+
+		client = stream{r: clientTLS, w: clientTLS}
+		server = stream{r: serverConn, w: serverConn}
+	}
+
+	login, err := forward(client, server, wire.PacketLogin7)
+	if err != nil {
+		return client, server, false, err
+	}
+	if encryption == wire.EncryptLogin {
+		// only the LOGIN7 is encrypted, the connection is in the clear right after it
+		client, server = plainClient, plainServer
+	}
+	reply, err = wire.ReadMessage(server.r)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the mssql login reply from the destination server")
+		return client, server, false, err
+	}
+	resp, _, err = wire.DecodeResponse(reply.Payload)
+	if err != nil {
+		logger.Debug("failed to decode the mssql login reply", zap.Error(err))
+	}
+	_, err = client.w.Write(reply.Raw)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the mssql login reply to the client")
+		return client, server, false, err
+	}
+	recordMock(login, resp, connID, mocks)
+	return client, server, mars, nil
+}
+
+// forward reads the message of the given type from the client and writes it to the server.
+func forward(client, server stream, typ byte) (inflight, error) {
+	msg, err := wire.ReadMessage(client.r)
+	if err != nil {
+		return inflight{}, err
+	}
+	reqTimestampMock := time.Now()
+	if msg.Type != typ {
+		return inflight{}, fmt.Errorf("expected a %s message from the mssql client, got %s", wire.PacketTypeName(typ), wire.PacketTypeName(msg.Type))
+	}
+	req, err := wire.DecodeRequest(msg, nil)
+	if err != nil {
+		return inflight{}, fmt.Errorf("failed to decode the %s message of the mssql client: %v", wire.PacketTypeName(typ), err)
+	}
+	_, err = server.w.Write(msg.Raw)
+	if err != nil {
+		return inflight{}, err
+	}
+	return inflight{req: req, reqTimestampMock: reqTimestampMock}, nil
+}
+
+func (s *session) push(req inflight) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+}
+
+// pop returns the oldest request awaiting a reply. The reply acknowledging an attention ends the request the
+// client cancelled, the attention is then dropped as well. The attentions aren't recorded, the replayer
+// acknowledges them by itself.
+func (s *session) pop(attention bool) (inflight, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return inflight{}, false
+	}
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	if isAttention(req.req) {
+		return req, false
+	}
+	if attention {
+		for i := range s.requests {
+			if isAttention(s.requests[i].req) {
+				s.requests = append(s.requests[:i], s.requests[i+1:]...)
+				break
+			}
+		}
+	}
+	return req, true
+}
+
+func (s *session) prepare(handle int32, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handles[handle] = query
+}
+
+func (s *session) lookup(handle int32) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handles[handle]
+}
+
+func isAttention(req *models.MSSQLRequest) bool {
+	return req.Type == wire.PacketTypeName(wire.PacketAttention)
+}
+
+func recordMock(req inflight, resp *models.MSSQLResponse, connID string, mocks chan<- *models.Mock) {
+	mockType := "mocks"
+	switch req.req.Type {
+	case wire.PacketTypeName(wire.PacketPreLogin), wire.PacketTypeName(wire.PacketLogin7), wire.PacketTypeName(wire.PacketSSPI):
+		mockType = "config"
+	}
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Kind:    models.MSSQL,
+		Name:    mockType,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{
+				"type":      mockType,
+				"operation": req.req.Type,
+			},
+			MSSQLReq:         req.req,
+			MSSQLResp:        resp,
+			ReqTimestampMock: req.reqTimestampMock,
+			ResTimestampMock: time.Now(),
+		},
+		ConnectionID: connID,
+	}
+}
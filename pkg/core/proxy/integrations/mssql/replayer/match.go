@@ -0,0 +1,171 @@
+//go:build linux
+
+package replayer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// matchRequest finds the mock of the given request. The type and the statements have to be the same, the
+// executions of the prepared statements are compared by the statements rather than by their handles. The
+// mocks having the same parameters are preferred, the filtered mocks first.
+func matchRequest(ctx context.Context, req *models.MSSQLRequest, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		filteredMocks, unfilteredMocks, err := getMSSQLMocks(mockDb)
+		if err != nil {
+			return nil, err
+		}
+
+		mock := findExactMatch(filteredMocks, req)
+		if mock == nil {
+			mock = findExactMatch(unfilteredMocks, req)
+		}
+		if mock == nil {
+			// e.g. a parameter bound to the current time
+			mock = findBestMatch(append(filteredMocks, unfilteredMocks...), req)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		if !mockDb.ConsumeMock(mock) {
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// matchLogin finds the recorded login closest to the given one. Any recorded login is replayed, the ones of
+// the same user, database and application are preferred.
+func matchLogin(login *models.MSSQLLogin, mockDb integrations.MockMemDb) *models.Mock {
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.MSSQL)
+	if err != nil {
+		return nil
+	}
+	var match *models.Mock
+	best := -1
+	for _, mock := range mocks {
+		req, resp := mock.Spec.MSSQLReq, mock.Spec.MSSQLResp
+		if req == nil || resp == nil || req.Login == nil {
+			continue
+		}
+		score := 0
+		if req.Login.UserName == login.UserName {
+			score += 2
+		}
+		if strings.EqualFold(req.Login.Database, login.Database) {
+			score += 2
+		}
+		if req.Login.AppName == login.AppName {
+			score++
+		}
+		if req.Login.TDSVersion == login.TDSVersion {
+			score++
+		}
+		if score > best {
+			best = score
+			match = mock
+		}
+	}
+	if match != nil {
+		mockDb.ConsumeMock(match)
+	}
+	return match
+}
+
+// getMSSQLMocks returns the filtered and the unfiltered mssql mocks of the requests following the login.
+func getMSSQLMocks(mockDb integrations.MockMemDb) ([]*models.Mock, []*models.Mock, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.MSSQL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var filteredMocks []*models.Mock
+	var unfilteredMocks []*models.Mock
+
+	for _, mock := range mocks {
+		req := mock.Spec.MSSQLReq
+		if req == nil || mock.Spec.MSSQLResp == nil || req.PreLogin != nil || req.Login != nil {
+			continue
+		}
+		if mock.TestModeInfo.IsFiltered {
+			filteredMocks = append(filteredMocks, mock)
+		} else {
+			unfilteredMocks = append(unfilteredMocks, mock)
+		}
+	}
+	return filteredMocks, unfilteredMocks, nil
+}
+
+// findExactMatch returns the first mock of the same statements having the same parameters.
+func findExactMatch(mocks []*models.Mock, req *models.MSSQLRequest) *models.Mock {
+	for _, mock := range mocks {
+		expected := mock.Spec.MSSQLReq
+		if !sameStatements(expected, req) {
+			continue
+		}
+		equal := true
+		for i := range req.RPCs {
+			if !slices.Equal(expected.RPCs[i].Parameters, req.RPCs[i].Parameters) {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return mock
+		}
+	}
+	return nil
+}
+
+// findBestMatch returns the mock of the same statements having the most parameters in common.
+func findBestMatch(mocks []*models.Mock, req *models.MSSQLRequest) *models.Mock {
+	var match *models.Mock
+	best := -1
+	for _, mock := range mocks {
+		expected := mock.Spec.MSSQLReq
+		if !sameStatements(expected, req) {
+			continue
+		}
+		score := 0
+		for i := range req.RPCs {
+			for j, param := range req.RPCs[i].Parameters {
+				if j < len(expected.RPCs[i].Parameters) && expected.RPCs[i].Parameters[j] == param {
+					score++
+				}
+			}
+		}
+		if score > best {
+			best = score
+			match = mock
+		}
+	}
+	return match
+}
+
+// sameStatements compares the types, the batches and the procedures called by the requests. The handles of
+// the prepared statements are only compared when their statements are unknown.
+func sameStatements(a, b *models.MSSQLRequest) bool {
+	if a.Type != b.Type || a.Query != b.Query || a.Payload != b.Payload || len(a.RPCs) != len(b.RPCs) {
+		return false
+	}
+	for i := range a.RPCs {
+		x, y := a.RPCs[i], b.RPCs[i]
+		if !strings.EqualFold(x.Procedure, y.Procedure) || x.Query != y.Query {
+			return false
+		}
+		if x.Query == "" && x.Handle != y.Handle {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+//go:build linux
+
+// Package replayer is used to mock the SQL Server traffic between the client and the server.
+package replayer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mssql/wire"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// Mock Yaml to Binary
+
+func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	errCh := make(chan error, 1)
+
+	go func(errCh chan error) {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		r, w, packetSize, err := handshake(ctx, logger, clientConn, dstCfg, mockDb)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		// the statements prepared on the connection by the handles of the replayed replies
+		handles := make(map[int32]string)
+		lookup := func(handle int32) string {
+			return handles[handle]
+		}
+		for {
+			msg, err := wire.ReadMessage(r)
+			if err != nil {
+				if err != io.EOF {
+					logger.Debug("failed to read the mssql request from the client", zap.Error(err))
+				}
+				errCh <- err
+				return
+			}
+
+			var payload []byte
+			if msg.Type == wire.PacketAttention {
+				// the reply to the cancelled request is already sent
+				payload = wire.EncodeAttentionAck()
+			} else {
+				req, err := wire.DecodeRequest(msg, lookup)
+				if err != nil {
+					logger.Debug("failed to decode the mssql request", zap.Error(err))
+				}
+				var resp *models.MSSQLResponse
+				payload, resp, err = handleRequest(ctx, logger, req, dstCfg, mockDb)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				if handle, query, ok := wire.PreparedHandle(req, resp); ok {
+					handles[handle] = query
+				}
+			}
+
+			_, err = w.Write(wire.EncodeMessage(wire.PacketReply, payload, packetSize))
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				utils.LogError(logger, err, "failed to write the mssql reply to the client application")
+				errCh <- err
+				return
+			}
+		}
+	}(errCh)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// handshake replies to the PRELOGIN and the LOGIN7 of the client. The TLS is run with the clients asking for
+// it, with the certificate of keploy, and refused to the others so that the LOGIN7 can be read. The reply
+// to the login is the one recorded for the closest login, no credentials are checked. It returns the side
+// of the connection the following messages are exchanged on and the size of its packets.
+func handshake(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb) (io.Reader, io.Writer, int, error) {
+	reader := bufio.NewReader(clientConn)
+	var r io.Reader = reader
+	var w io.Writer = clientConn
+
+	msg, err := wire.ReadMessage(r)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if msg.Type != wire.PacketPreLogin {
+		return nil, nil, 0, fmt.Errorf("expected a PreLogin message from the mssql client, got %s", wire.PacketTypeName(msg.Type))
+	}
+	options, err := wire.DecodePreLogin(msg.Payload)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the mssql prelogin of the client")
+		return nil, nil, 0, err
+	}
+
+	reply := recordedPreLogin(mockDb)
+	encrypt := wire.WantsEncryption(options)
+	if encrypt {
+		wire.SetEncryption(reply, wire.EncryptOn)
+	} else {
+		wire.SetEncryption(reply, wire.EncryptNotSup)
+	}
+	wire.DisableMARS(reply)
+	payload, err := wire.EncodePreLogin(reply)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the mssql prelogin reply")
+		return nil, nil, 0, err
+	}
+	_, err = w.Write(wire.EncodeMessage(wire.PacketReply, payload, wire.DefaultPacketSize))
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the mssql prelogin reply to the client application")
+		return nil, nil, 0, err
+	}
+
+	if encrypt {
+		cfg, ok := ctx.Value(models.TLSConfigKey).(*tls.Config)
+		if !ok {
+			return nil, nil, 0, errors.New("failed to get the tls config from the context")
+		}
+		host, _, err := net.SplitHostPort(dstCfg.Addr)
+		if err != nil {
+			host = dstCfg.Addr
+		}
+		handshakeConn := wire.NewHandshakeConn(clientConn, reader)
+		tlsConn := tls.Server(handshakeConn, wire.ServerTLSConfig(cfg, host))
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			utils.LogError(logger, err, "failed to complete the tls handshake with the mssql client")
+			return nil, nil, 0, err
+		}
+		handshakeConn.Complete()
+		r, w = tlsConn, tlsConn
+	}
+
+	msg, err = wire.ReadMessage(r)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if msg.Type != wire.PacketLogin7 {
+		return nil, nil, 0, fmt.Errorf("expected a Login7 message from the mssql client, got %s", wire.PacketTypeName(msg.Type))
+	}
+	req, err := wire.DecodeRequest(msg, nil)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the mssql login of the client")
+		return nil, nil, 0, err
+	}
+
+	mock := matchLogin(req.Login, mockDb)
+	if mock == nil {
+		summary := summarizeRequest(req)
+		utils.LogError(logger, nil, "no matching mssql mock found for the login", zap.Any("login", summary))
+		mockDb.RecordUnmatched(models.UnmatchedCall{
+			Kind:        models.MSSQL,
+			Destination: dstCfg.Addr,
+			Summary:     summary,
+		})
+		_, _ = w.Write(wire.EncodeMessage(wire.PacketReply, wire.EncodeError("keploy: no mock found for "+summary), wire.DefaultPacketSize))
+		return nil, nil, 0, errors.New("no mssql mock found for the login")
+	}
+	payload, err = base64.StdEncoding.DecodeString(mock.Spec.MSSQLResp.Payload)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the payload of the mssql login reply", zap.Any("mock", mock.Name))
+		return nil, nil, 0, err
+	}
+	_, err = w.Write(wire.EncodeMessage(wire.PacketReply, payload, wire.DefaultPacketSize))
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the mssql login reply to the client application")
+		return nil, nil, 0, err
+	}
+
+	packetSize := wire.PacketSizeOf(mock.Spec.MSSQLResp)
+	if packetSize == 0 {
+		packetSize = wire.DefaultPacketSize
+	}
+	return r, w, packetSize, nil
+}
+
+// handleRequest returns the tokens replying to the request along with the recorded reply. The requests no
+// mock matches are replied to with an error, so that the client keeps its connection.
+func handleRequest(ctx context.Context, logger *zap.Logger, req *models.MSSQLRequest, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb) ([]byte, *models.MSSQLResponse, error) {
+	mock, err := matchRequest(ctx, req, mockDb)
+	if err != nil {
+		if ctx.Err() == nil {
+			utils.LogError(logger, err, "error while matching mssql mocks")
+		}
+		return nil, nil, err
+	}
+	if mock == nil {
+		summary := summarizeRequest(req)
+		utils.LogError(logger, nil, "no matching mssql mock found for the request", zap.Any("request", summary))
+		mockDb.RecordUnmatched(models.UnmatchedCall{
+			Kind:        models.MSSQL,
+			Destination: dstCfg.Addr,
+			Summary:     summary,
+		})
+		return wire.EncodeError("keploy: no mock found for " + summary), nil, nil
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(mock.Spec.MSSQLResp.Payload)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the payload of the mssql reply", zap.Any("mock", mock.Name))
+		return nil, nil, err
+	}
+	return payload, mock.Spec.MSSQLResp, nil
+}
+
+// recordedPreLogin returns the options of the recorded PRELOGIN reply, or the default ones if none is
+// recorded.
+func recordedPreLogin(mockDb integrations.MockMemDb) map[string]string {
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.MSSQL)
+	if err != nil {
+		return wire.DefaultPreLogin()
+	}
+	for _, mock := range mocks {
+		req, resp := mock.Spec.MSSQLReq, mock.Spec.MSSQLResp
+		if req == nil || resp == nil || req.Type != wire.PacketTypeName(wire.PacketPreLogin) || resp.PreLogin == nil {
+			continue
+		}
+		mockDb.ConsumeMock(mock)
+		return maps.Clone(resp.PreLogin)
+	}
+	return wire.DefaultPreLogin()
+}
+
+// summarizeRequest returns the type of the request along with its statements, for the report of the
+// unmatched calls.
+func summarizeRequest(req *models.MSSQLRequest) string {
+	summary := req.Type
+	if req.Login != nil {
+		summary += fmt.Sprintf(" user=%s database=%s", req.Login.UserName, req.Login.Database)
+	}
+	if req.Query != "" {
+		summary += " " + req.Query
+	}
+	var calls []string
+	for _, rpc := range req.RPCs {
+		call := rpc.Procedure
+		if rpc.Query != "" {
+			call += " " + rpc.Query
+		}
+		calls = append(calls, call)
+	}
+	if len(calls) > 0 {
+		summary += " " + strings.Join(calls, "; ")
+	}
+	return pUtil.Summarize([][]byte{[]byte(summary)})
+}
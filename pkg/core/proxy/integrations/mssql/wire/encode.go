@@ -0,0 +1,49 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/binary"
+)
+
+// the number and the class of the error replied to the requests no mock matches, 50000 is the number of the
+// errors raised by RAISERROR and 16 the severity of the errors the user can correct
+const (
+	errorNumber = 50000
+	errorClass  = 16
+	// maxErrorText is the longest text of the message fitting in the length of the ERROR token
+	maxErrorText = 0xFFF0
+)
+
+// EncodeError encodes the tokens of an error reply, an ERROR token with the message followed by a DONE
+// token flagging the error.
+func EncodeError(message string) []byte {
+	var body []byte
+	body = binary.LittleEndian.AppendUint32(body, errorNumber)
+	body = append(body, 1, errorClass)
+	text := encodeUCS2(message)
+	if len(text) > maxErrorText {
+		text = text[:maxErrorText]
+	}
+	body = binary.LittleEndian.AppendUint16(body, uint16(len(text)/2))
+	body = append(body, text...)
+	body = append(body, 0, 0) // the names of the server and of the procedure
+	body = binary.LittleEndian.AppendUint32(body, 1)
+
+	out := []byte{tokenError}
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(body)))
+	out = append(out, body...)
+	return append(out, encodeDone(doneError)...)
+}
+
+// EncodeAttentionAck encodes the DONE token acknowledging an attention of the client.
+func EncodeAttentionAck() []byte {
+	return encodeDone(doneAttn)
+}
+
+func encodeDone(status uint16) []byte {
+	out := []byte{tokenDone}
+	out = binary.LittleEndian.AppendUint16(out, status)
+	out = binary.LittleEndian.AppendUint16(out, 0)
+	return binary.LittleEndian.AppendUint64(out, 0)
+}
@@ -0,0 +1,59 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const (
+	login7FixedLength = 36
+	// fIntSecurity of the OptionFlags2 is set when the client authenticates with SSPI
+	login7IntegratedSecurity = 0x80
+)
+
+// DecodeLogin7 decodes the LOGIN7 message of the client, the password is not decoded.
+func DecodeLogin7(payload []byte) (*models.MSSQLLogin, error) {
+	if len(payload) < login7FixedLength+9*4 {
+		return nil, ErrMalformed
+	}
+	login := &models.MSSQLLogin{
+		TDSVersion:         fmt.Sprintf("0x%08X", binary.LittleEndian.Uint32(payload[4:])),
+		PacketSize:         binary.LittleEndian.Uint32(payload[8:]),
+		IntegratedSecurity: payload[25]&login7IntegratedSecurity != 0,
+	}
+	// the offsets and the lengths in characters of the texts follow the fixed fields
+	text := func(field int) (string, error) {
+		pos := login7FixedLength + field*4
+		off := int(binary.LittleEndian.Uint16(payload[pos:]))
+		length := 2 * int(binary.LittleEndian.Uint16(payload[pos+2:]))
+		if off+length > len(payload) {
+			return "", ErrMalformed
+		}
+		return ucs2(payload[off : off+length]), nil
+	}
+	fields := []struct {
+		index int
+		value *string
+	}{
+		{0, &login.HostName},
+		{1, &login.UserName},
+		// 2 is the password
+		{3, &login.AppName},
+		{4, &login.ServerName},
+		{6, &login.Library},
+		{7, &login.Language},
+		{8, &login.Database},
+	}
+	for _, field := range fields {
+		value, err := text(field.index)
+		if err != nil {
+			return login, err
+		}
+		*field.value = value
+	}
+	return login, nil
+}
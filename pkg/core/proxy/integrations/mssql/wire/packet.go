@@ -0,0 +1,135 @@
+//go:build linux
+
+// Package wire decodes the packets of the Tabular Data Stream protocol (TDS 7.x) of Microsoft SQL Server, the
+// messages of the client and the token streams the server replies with.
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// types of the packets
+const (
+	PacketSQLBatch    = 0x01
+	PacketRPC         = 0x03
+	PacketReply       = 0x04
+	PacketAttention   = 0x06
+	PacketBulkLoad    = 0x07
+	PacketFedAuth     = 0x08
+	PacketTransaction = 0x0E
+	PacketLogin7      = 0x10
+	PacketSSPI        = 0x11
+	PacketPreLogin    = 0x12
+)
+
+var packetTypes = map[byte]string{
+	PacketSQLBatch:    "SQLBatch",
+	PacketRPC:         "RPC",
+	PacketReply:       "Reply",
+	PacketAttention:   "Attention",
+	PacketBulkLoad:    "BulkLoad",
+	PacketFedAuth:     "FedAuthToken",
+	PacketTransaction: "TransactionManager",
+	PacketLogin7:      "Login7",
+	PacketSSPI:        "SSPI",
+	PacketPreLogin:    "PreLogin",
+}
+
+// status flags of the packets
+const (
+	StatusEOM             = 0x01
+	StatusIgnore          = 0x02
+	StatusResetConnection = 0x08
+)
+
+const (
+	HeaderLen = 8
+	// DefaultPacketSize is the size of the packets until the server acknowledges the one asked by the LOGIN7
+	DefaultPacketSize = 4096
+	maxPacketSize     = 32767
+)
+
+var ErrMalformed = errors.New("malformed tds packet")
+
+// PacketTypeName returns the name of the packet type, e.g. "SQLBatch".
+func PacketTypeName(typ byte) string {
+	if name, ok := packetTypes[typ]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02X", typ)
+}
+
+// Message is a message of the client or the server, the payloads of its packets are joined.
+type Message struct {
+	Type byte
+	// Status is the status of the first packet, it holds the flags resetting the connection
+	Status  byte
+	Payload []byte
+	// Raw holds the packets as they were read
+	Raw []byte
+}
+
+// ReadMessage reads the packets of a message until the one ending it.
+func ReadMessage(r io.Reader) (*Message, error) {
+	msg := &Message{}
+	header := make([]byte, HeaderLen)
+	for first := true; ; first = false {
+		_, err := io.ReadFull(r, header)
+		if err != nil {
+			if !first && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(header[2:4]))
+		if length < HeaderLen || length > maxPacketSize+HeaderLen {
+			return nil, ErrMalformed
+		}
+		if first {
+			msg.Type = header[0]
+			msg.Status = header[1]
+		}
+		packet := make([]byte, length)
+		copy(packet, header)
+		_, err = io.ReadFull(r, packet[HeaderLen:])
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		msg.Raw = append(msg.Raw, packet...)
+		msg.Payload = append(msg.Payload, packet[HeaderLen:]...)
+		if header[1]&StatusEOM != 0 {
+			return msg, nil
+		}
+	}
+}
+
+// EncodeMessage splits the payload into the packets of the given size, the last one ending the message.
+func EncodeMessage(typ byte, payload []byte, packetSize int) []byte {
+	if packetSize <= HeaderLen || packetSize > maxPacketSize {
+		packetSize = DefaultPacketSize
+	}
+	chunk := packetSize - HeaderLen
+	var out []byte
+	id := byte(1)
+	for {
+		n := min(len(payload), chunk)
+		status := byte(0)
+		if n == len(payload) {
+			status = StatusEOM
+		}
+		out = append(out, typ, status, 0, 0, 0, 0, id, 0)
+		binary.BigEndian.PutUint16(out[len(out)-6:], uint16(HeaderLen+n))
+		out = append(out, payload[:n]...)
+		payload = payload[n:]
+		id++
+		if status == StatusEOM {
+			return out
+		}
+	}
+}
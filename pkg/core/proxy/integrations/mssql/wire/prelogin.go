@@ -0,0 +1,209 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tokens of the PRELOGIN options
+const (
+	optionVersion      = 0x00
+	optionEncryption   = 0x01
+	optionInstance     = 0x02
+	optionThreadID     = 0x03
+	optionMARS         = 0x04
+	optionTraceID      = 0x05
+	optionFedAuth      = 0x06
+	optionNonce        = 0x07
+	optionTerminator   = 0xFF
+	optionHeaderLength = 5
+)
+
+var preLoginOptions = map[byte]string{
+	optionVersion:    "VERSION",
+	optionEncryption: "ENCRYPTION",
+	optionInstance:   "INSTOPT",
+	optionThreadID:   "THREADID",
+	optionMARS:       "MARS",
+	optionTraceID:    "TRACEID",
+	optionFedAuth:    "FEDAUTHREQUIRED",
+	optionNonce:      "NONCEOPT",
+}
+
+// values of the ENCRYPTION option
+const (
+	EncryptOff      = 0x00
+	EncryptOn       = 0x01
+	EncryptNotSup   = 0x02
+	EncryptRequired = 0x03
+	// encryptClientCert is set along with the value when the client authenticates with a certificate
+	encryptClientCert = 0x80
+)
+
+// Encryption is how much of the connection is encrypted once the PRELOGIN messages are exchanged.
+type Encryption int
+
+const (
+	// EncryptNone leaves the whole connection in the clear
+	EncryptNone Encryption = iota
+	// EncryptLogin encrypts the LOGIN7 only, the connection is in the clear after it
+	EncryptLogin
+	// EncryptFull encrypts the whole connection
+	EncryptFull
+)
+
+// Negotiate returns the encryption of the connection from the ENCRYPTION option of the reply of the server,
+// which settles it along with the option of the client.
+func Negotiate(server byte) Encryption {
+	switch server &^ encryptClientCert {
+	case EncryptNotSup:
+		return EncryptNone
+	case EncryptOff:
+		return EncryptLogin
+	default:
+		return EncryptFull
+	}
+}
+
+// IsPreLogin checks whether the buffer starts with a PRELOGIN message whose option table is well-formed,
+// which the clients send before any other message.
+func IsPreLogin(buf []byte) bool {
+	if len(buf) < HeaderLen || buf[0] != PacketPreLogin || buf[1]&StatusEOM == 0 {
+		return false
+	}
+	length := int(binary.BigEndian.Uint16(buf[2:4]))
+	if length <= HeaderLen || length > len(buf) {
+		return false
+	}
+	_, err := DecodePreLogin(buf[HeaderLen:length])
+	return err == nil
+}
+
+// DecodePreLogin decodes the options of a PRELOGIN message, their values are given in hex by their name.
+func DecodePreLogin(payload []byte) (map[string]string, error) {
+	options := make(map[string]string)
+	for off := 0; ; off += optionHeaderLength {
+		if off >= len(payload) {
+			return nil, ErrMalformed
+		}
+		token := payload[off]
+		if token == optionTerminator {
+			return options, nil
+		}
+		if off+optionHeaderLength > len(payload) {
+			return nil, ErrMalformed
+		}
+		start := int(binary.BigEndian.Uint16(payload[off+1:]))
+		length := int(binary.BigEndian.Uint16(payload[off+3:]))
+		if start+length > len(payload) {
+			return nil, ErrMalformed
+		}
+		options[optionName(token)] = hex.EncodeToString(payload[start : start+length])
+	}
+}
+
+// EncodePreLogin encodes the options of a PRELOGIN message in the order of their tokens.
+func EncodePreLogin(options map[string]string) ([]byte, error) {
+	type option struct {
+		token byte
+		value []byte
+	}
+	var opts []option
+	for name, value := range options {
+		token, err := optionToken(name)
+		if err != nil {
+			return nil, err
+		}
+		b, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of the prelogin option %s: %v", name, err)
+		}
+		opts = append(opts, option{token: token, value: b})
+	}
+	sort.Slice(opts, func(i, j int) bool { return opts[i].token < opts[j].token })
+
+	off := len(opts)*optionHeaderLength + 1
+	var table, data []byte
+	for _, opt := range opts {
+		table = append(table, opt.token)
+		table = binary.BigEndian.AppendUint16(table, uint16(off+len(data)))
+		table = binary.BigEndian.AppendUint16(table, uint16(len(opt.value)))
+		data = append(data, opt.value...)
+	}
+	table = append(table, optionTerminator)
+	return append(table, data...), nil
+}
+
+// EncryptionOf returns the value of the ENCRYPTION option, the clients not sending it don't support it.
+func EncryptionOf(options map[string]string) byte {
+	b, err := hex.DecodeString(options[preLoginOptions[optionEncryption]])
+	if err != nil || len(b) != 1 {
+		return EncryptNotSup
+	}
+	return b[0]
+}
+
+// WantsEncryption reports whether the client asks for the whole connection to be encrypted, the clients
+// turning the encryption off only encrypt their LOGIN7 if the server supports it.
+func WantsEncryption(options map[string]string) bool {
+	value := EncryptionOf(options) &^ encryptClientCert
+	return value == EncryptOn || value == EncryptRequired
+}
+
+// SetEncryption sets the value of the ENCRYPTION option.
+func SetEncryption(options map[string]string, value byte) {
+	options[preLoginOptions[optionEncryption]] = hex.EncodeToString([]byte{value})
+}
+
+// MARSEnabled reports whether the options enable the multiple active result sets, whose messages are
+// multiplexed with the session multiplex protocol.
+func MARSEnabled(options map[string]string) bool {
+	return options[preLoginOptions[optionMARS]] == "01"
+}
+
+// DisableMARS turns off the multiple active result sets.
+func DisableMARS(options map[string]string) {
+	if _, ok := options[preLoginOptions[optionMARS]]; ok {
+		options[preLoginOptions[optionMARS]] = "00"
+	}
+}
+
+// DefaultPreLogin returns the options the server replies with when no PRELOGIN is recorded.
+func DefaultPreLogin() map[string]string {
+	return map[string]string{
+		// 15.0.2000, i.e. SQL Server 2019
+		preLoginOptions[optionVersion]:    "0f0007d00000",
+		preLoginOptions[optionEncryption]: "02",
+		preLoginOptions[optionInstance]:   "00",
+		preLoginOptions[optionThreadID]:   "",
+		preLoginOptions[optionMARS]:       "00",
+	}
+}
+
+func optionName(token byte) string {
+	if name, ok := preLoginOptions[token]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02X", token)
+}
+
+func optionToken(name string) (byte, error) {
+	for token, n := range preLoginOptions {
+		if n == name {
+			return token, nil
+		}
+	}
+	if strings.HasPrefix(name, "0x") {
+		token, err := strconv.ParseUint(name[2:], 16, 8)
+		if err == nil {
+			return byte(token), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown prelogin option %s", name)
+}
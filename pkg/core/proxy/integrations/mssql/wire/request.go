@@ -0,0 +1,241 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// ids of the stored procedures the clients call without their name
+const (
+	procExecuteSQL = 10
+	procPrepare    = 11
+	procExecute    = 12
+	procPrepExec   = 13
+	procUnprepare  = 15
+)
+
+var procedures = map[uint16]string{
+	1:              "sp_cursor",
+	2:              "sp_cursoropen",
+	3:              "sp_cursorprepare",
+	4:              "sp_cursorexecute",
+	5:              "sp_cursorprepexec",
+	6:              "sp_cursorunprepare",
+	7:              "sp_cursorfetch",
+	8:              "sp_cursoroption",
+	9:              "sp_cursorclose",
+	procExecuteSQL: "sp_executesql",
+	procPrepare:    "sp_prepare",
+	procExecute:    "sp_execute",
+	procPrepExec:   "sp_prepexec",
+	14:             "sp_prepexecrpc",
+	procUnprepare:  "sp_unprepare",
+}
+
+// counts of the parameters the system procedures can't be called without, fewer of them is a truncated call
+var requiredParameters = map[uint16]int{
+	procExecuteSQL: 1, // the statement
+	procPrepare:    3, // the handle, the declaration of the parameters and the statement
+	procPrepExec:   3,
+	procExecute:    1, // the handle
+	procUnprepare:  1,
+}
+
+// requests of the TransactionManager messages
+var transactionRequests = map[uint16]string{
+	0: "TM_GET_DTC_ADDRESS",
+	1: "TM_PROPAGATE_XACT",
+	5: "TM_BEGIN_XACT",
+	6: "TM_PROMOTE_XACT",
+	7: "TM_COMMIT_XACT",
+	8: "TM_ROLLBACK_XACT",
+	9: "TM_SAVE_XACT",
+}
+
+const (
+	// rpcOutput is set in the status of the parameters passed by reference
+	rpcOutput = 0x01
+	// the separators of the procedures batched in an RPC message
+	rpcBatchFlag      = 0x80
+	rpcNoExecFlag     = 0xFE
+	rpcBatchSeparator = 0xFF
+)
+
+// HandleLookup returns the statement prepared with the given handle on the connection, or "" if it is unknown.
+type HandleLookup func(handle int32) string
+
+// DecodeRequest decodes the message of the client. The statements run by sp_execute are looked up by the
+// handle they were prepared with. The headers preceding the batches and the RPCs hold the transaction and
+// the trace of the request, they are skipped. The messages which aren't decoded are stored as base64.
+func DecodeRequest(msg *Message, lookup HandleLookup) (*models.MSSQLRequest, error) {
+	req := &models.MSSQLRequest{
+		Type: PacketTypeName(msg.Type),
+	}
+	var err error
+	switch msg.Type {
+	case PacketPreLogin:
+		req.PreLogin, err = DecodePreLogin(msg.Payload)
+	case PacketLogin7:
+		req.Login, err = DecodeLogin7(msg.Payload)
+	case PacketAttention:
+	case PacketSQLBatch:
+		body := skipHeaders(msg.Payload)
+		if len(body)%2 != 0 {
+			err = ErrMalformed
+		}
+		req.Query = ucs2(body)
+	case PacketRPC:
+		body := skipHeaders(msg.Payload)
+		req.RPCs, err = decodeRPCs(body, lookup)
+		if err != nil {
+			req.Payload = base64.StdEncoding.EncodeToString(body)
+		}
+	case PacketTransaction:
+		body := skipHeaders(msg.Payload)
+		if len(body) >= 2 {
+			typ := binary.LittleEndian.Uint16(body)
+			req.Query = transactionRequests[typ]
+			if req.Query == "" {
+				req.Query = strconv.Itoa(int(typ))
+			}
+		}
+		req.Payload = base64.StdEncoding.EncodeToString(body)
+	default:
+		req.Payload = base64.StdEncoding.EncodeToString(msg.Payload)
+	}
+	return req, err
+}
+
+// skipHeaders skips the ALL_HEADERS preceding the body of the message.
+func skipHeaders(payload []byte) []byte {
+	if len(payload) < 4 {
+		return payload
+	}
+	length := int(binary.LittleEndian.Uint32(payload))
+	if length < 4 || length > len(payload) {
+		return payload
+	}
+	return payload[length:]
+}
+
+// decodeRPCs decodes the procedures called by the RPC message along with their parameters.
+func decodeRPCs(body []byte, lookup HandleLookup) ([]models.MSSQLRPC, error) {
+	r := &bodyReader{buf: body}
+	var rpcs []models.MSSQLRPC
+	// whether the last call is followed by a separator, i.e. by another call
+	separated := false
+	for r.remaining() > 0 && r.err == nil {
+		separated = false
+		var rpc models.MSSQLRPC
+		id := uint16(0)
+		nameLength := r.uint16()
+		if nameLength == 0xFFFF {
+			id = r.uint16()
+			rpc.Procedure = procedures[id]
+			if rpc.Procedure == "" {
+				rpc.Procedure = strconv.Itoa(int(id))
+			}
+		} else {
+			rpc.Procedure = ucs2(r.next(2 * int(nameLength)))
+			id = procedureID(rpc.Procedure)
+		}
+		r.uint16() // the option flags
+
+		for r.remaining() > 0 && r.err == nil {
+			if b := r.buf[r.off]; b == rpcBatchSeparator || b == rpcNoExecFlag || b == rpcBatchFlag {
+				r.off++
+				separated = true
+				break
+			}
+			name := r.bVarChar()
+			status := r.byte()
+			ti := r.readTypeInfo(false)
+			data, null := r.readValue(ti, false)
+			param := models.MSSQLParameter{
+				Name:   name,
+				Type:   typeName(ti),
+				Null:   null,
+				Output: status&rpcOutput != 0,
+			}
+			if !null {
+				param.Value = formatValue(ti, data)
+			}
+			rpc.Parameters = append(rpc.Parameters, param)
+		}
+		if n := requiredParameters[id]; r.err == nil && len(rpc.Parameters) < n {
+			r.fail(fmt.Errorf("%w: %s called with %d of its %d parameters", ErrMalformed, rpc.Procedure, len(rpc.Parameters), n))
+		}
+		resolveStatement(&rpc, id, lookup)
+		rpcs = append(rpcs, rpc)
+	}
+	if r.err == nil && (len(rpcs) == 0 || separated) {
+		r.fail(fmt.Errorf("%w: missing procedure call", ErrMalformed))
+	}
+	return rpcs, r.err
+}
+
+// procedureID returns the id of the procedure called by its name, or 0 for the other procedures.
+func procedureID(name string) uint16 {
+	for id, procedure := range procedures {
+		if strings.EqualFold(procedure, name) {
+			return id
+		}
+	}
+	return 0
+}
+
+// resolveStatement sets the statement run by the procedure. The handles of the prepared statements are
+// moved out of the parameters, the server gives different ones to each connection.
+func resolveStatement(rpc *models.MSSQLRPC, id uint16, lookup HandleLookup) {
+	param := func(i int) string {
+		if i < len(rpc.Parameters) {
+			return rpc.Parameters[i].Value
+		}
+		return ""
+	}
+	switch id {
+	case procExecuteSQL:
+		rpc.Query = param(0)
+	case procPrepare, procPrepExec:
+		rpc.Query = param(2)
+		if len(rpc.Parameters) > 0 {
+			rpc.Parameters = rpc.Parameters[1:]
+		}
+	case procExecute, procUnprepare:
+		handle, err := strconv.ParseInt(param(0), 10, 32)
+		if err != nil {
+			return
+		}
+		rpc.Handle = int32(handle)
+		if lookup != nil {
+			rpc.Query = lookup(rpc.Handle)
+		}
+		rpc.Parameters = rpc.Parameters[1:]
+	}
+}
+
+// IsPrepare reports whether the procedure prepares a statement, its handle is returned by the server.
+func IsPrepare(rpc models.MSSQLRPC) bool {
+	id := procedureID(rpc.Procedure)
+	return id == procPrepare || id == procPrepExec
+}
+
+// PreparedHandle returns the handle of the statement prepared by the request, it is the first value returned
+// by the server.
+func PreparedHandle(req *models.MSSQLRequest, resp *models.MSSQLResponse) (int32, string, bool) {
+	if req == nil || resp == nil || len(req.RPCs) == 0 || !IsPrepare(req.RPCs[0]) || len(resp.ReturnValues) == 0 {
+		return 0, "", false
+	}
+	handle, err := strconv.ParseInt(resp.ReturnValues[0].Value, 10, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return int32(handle), req.RPCs[0].Query, true
+}
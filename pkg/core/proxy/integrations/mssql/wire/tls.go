@@ -0,0 +1,88 @@
+//go:build linux
+
+package wire
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+)
+
+// HandshakeConn carries the TLS handshake in the payload of PRELOGIN packets, as the clients and the server
+// exchange it once the encryption is negotiated. The TLS records are exchanged as they are once the
+// handshake is complete.
+type HandshakeConn struct {
+	net.Conn
+	reader    *bufio.Reader
+	handshake bool
+	pending   []byte
+}
+
+// NewHandshakeConn wraps the connection whose bytes are read through the reader.
+func NewHandshakeConn(conn net.Conn, reader *bufio.Reader) *HandshakeConn {
+	return &HandshakeConn{
+		Conn:      conn,
+		reader:    reader,
+		handshake: true,
+	}
+}
+
+func (c *HandshakeConn) Read(b []byte) (int, error) {
+	if !c.handshake {
+		return c.reader.Read(b)
+	}
+	if len(c.pending) == 0 {
+		msg, err := ReadMessage(c.reader)
+		if err != nil {
+			return 0, err
+		}
+		c.pending = msg.Payload
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *HandshakeConn) Write(b []byte) (int, error) {
+	if !c.handshake {
+		return c.Conn.Write(b)
+	}
+	_, err := c.Conn.Write(EncodeMessage(PacketPreLogin, b, DefaultPacketSize))
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Complete ends the handshake, the TLS records are no longer wrapped.
+func (c *HandshakeConn) Complete() {
+	c.handshake = false
+}
+
+// ServerTLSConfig returns the config terminating the TLS of the client. The clients of SQL Server often
+// don't send the name of the server, the certificate is then made for the given host.
+func ServerTLSConfig(cfg *tls.Config, host string) *tls.Config {
+	cfg = cfg.Clone()
+	// the clients using TDS 7.x don't support TLS 1.3 within the PRELOGIN packets
+	cfg.MaxVersion = tls.VersionTLS12
+	getCertificate := cfg.GetCertificate
+	if getCertificate != nil {
+		cfg.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName == "" {
+				hello.ServerName = host
+			}
+			return getCertificate(hello)
+		}
+	}
+	return cfg
+}
+
+// ClientTLSConfig returns the config of the TLS with the server, its certificate isn't verified as the
+// client of the application verifies the one of keploy.
+func ClientTLSConfig(host string) *tls.Config {
+	return &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12,
+	}
+}
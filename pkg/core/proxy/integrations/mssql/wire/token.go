@@ -0,0 +1,261 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// tokens of the replies of the server
+const (
+	tokenColMetadata  = 0x81
+	tokenRow          = 0xD1
+	tokenNBCRow       = 0xD2
+	tokenDone         = 0xFD
+	tokenDoneProc     = 0xFE
+	tokenDoneInProc   = 0xFF
+	tokenError        = 0xAA
+	tokenInfo         = 0xAB
+	tokenEnvChange    = 0xE3
+	tokenLoginAck     = 0xAD
+	tokenReturnStatus = 0x79
+	tokenReturnValue  = 0xAC
+	tokenOrder        = 0xA9
+	tokenTabName      = 0xA4
+	tokenColInfo      = 0xA5
+	tokenOffset       = 0x78
+	tokenFeatureExt   = 0xAE
+	tokenSSPI         = 0xED
+	tokenFedAuthInfo  = 0xEE
+	tokenSessionState = 0xE4
+	tokenDataClass    = 0xA3
+)
+
+// status flags of the DONE tokens
+const (
+	doneMore  = 0x0001
+	doneError = 0x0002
+	doneCount = 0x0010
+	doneAttn  = 0x0020
+)
+
+// types of the ENVCHANGE tokens
+const (
+	envPacketSize = 4
+)
+
+var envChanges = map[byte]string{
+	1:             "Database",
+	2:             "Language",
+	3:             "CharacterSet",
+	envPacketSize: "PacketSize",
+	5:             "UnicodeSortingLocale",
+	6:             "UnicodeComparisonFlags",
+	7:             "SQLCollation",
+	8:             "BeginTransaction",
+	9:             "CommitTransaction",
+	10:            "RollbackTransaction",
+	11:            "EnlistDTCTransaction",
+	12:            "DefectTransaction",
+	13:            "DatabaseMirroringPartner",
+	15:            "PromoteTransaction",
+	16:            "TransactionManagerAddress",
+	17:            "TransactionEnded",
+	18:            "ResetConnectionAck",
+	19:            "UserInstance",
+	20:            "Routing",
+}
+
+// column is a column of the result set being decoded.
+type column struct {
+	name string
+	ti   typeInfo
+}
+
+// DecodeResponse decodes the tokens of the reply of the server, it reports whether the reply acknowledges an
+// attention of the client. The decoding stops at the first token which isn't supported, the reply is then
+// only replayed from its payload.
+func DecodeResponse(payload []byte) (*models.MSSQLResponse, bool, error) {
+	resp := &models.MSSQLResponse{
+		Payload: base64.StdEncoding.EncodeToString(payload),
+	}
+	r := &bodyReader{buf: payload}
+	attention := false
+	// a response ends with a done token not followed by more results, the others are truncated
+	final := false
+	var columns []column
+	for r.remaining() > 0 && r.err == nil {
+		final = false
+		token := r.byte()
+		switch token {
+		case tokenColMetadata:
+			columns = decodeColMetadata(r)
+			if columns != nil {
+				set := models.MSSQLResultSet{Columns: make([]models.MSSQLColumn, len(columns))}
+				for i, col := range columns {
+					set.Columns[i] = models.MSSQLColumn{Name: col.name, Type: typeName(col.ti)}
+				}
+				resp.ResultSets = append(resp.ResultSets, set)
+			}
+		case tokenRow, tokenNBCRow:
+			var nulls []byte
+			if token == tokenNBCRow {
+				nulls = r.next((len(columns) + 7) / 8)
+			}
+			row := make([]string, len(columns))
+			for i, col := range columns {
+				if nulls != nil && nulls[i/8]&(1<<(i%8)) != 0 {
+					row[i] = "NULL"
+					continue
+				}
+				data, null := r.readValue(col.ti, true)
+				if null {
+					row[i] = "NULL"
+				} else {
+					row[i] = formatValue(col.ti, data)
+				}
+			}
+			if n := len(resp.ResultSets); n > 0 && r.err == nil {
+				resp.ResultSets[n-1].Rows = append(resp.ResultSets[n-1].Rows, row)
+			}
+		case tokenDone, tokenDoneProc, tokenDoneInProc:
+			status := r.uint16()
+			r.uint16() // the current command
+			count := r.uint64()
+			if status&doneCount != 0 {
+				resp.RowCounts = append(resp.RowCounts, count)
+			}
+			if status&doneAttn != 0 {
+				attention = true
+			}
+			final = token != tokenDoneInProc && status&doneMore == 0
+		case tokenError, tokenInfo:
+			resp.Messages = append(resp.Messages, decodeMessage(r, token))
+		case tokenEnvChange:
+			if change, ok := decodeEnvChange(r); ok {
+				resp.EnvChanges = append(resp.EnvChanges, change)
+			}
+		case tokenLoginAck:
+			body := &bodyReader{buf: r.next(int(r.uint16()))}
+			body.byte() // the interface
+			version := body.next(4)
+			name := body.bVarChar()
+			prog := body.next(4)
+			if body.err == nil {
+				resp.LoginAck = fmt.Sprintf("%s %d.%d.%d (TDS 0x%08X)", name, prog[0], prog[1], int(prog[2])<<8|int(prog[3]), binary.BigEndian.Uint32(version))
+			}
+		case tokenReturnStatus:
+			resp.ReturnStatus = append(resp.ReturnStatus, int32(r.uint32()))
+		case tokenReturnValue:
+			r.uint16() // the ordinal of the parameter
+			name := r.bVarChar()
+			r.byte()   // the status
+			r.uint32() // the user type
+			r.uint16() // the flags
+			ti := r.readTypeInfo(false)
+			data, null := r.readValue(ti, false)
+			value := models.MSSQLParameter{Name: name, Type: typeName(ti), Null: null, Output: true}
+			if !null {
+				value.Value = formatValue(ti, data)
+			}
+			resp.ReturnValues = append(resp.ReturnValues, value)
+		case tokenOrder, tokenTabName, tokenColInfo, tokenSSPI, tokenDataClass:
+			r.next(int(r.uint16()))
+		case tokenOffset:
+			r.next(4)
+		case tokenFedAuthInfo, tokenSessionState:
+			r.next(int(r.uint32()))
+		case tokenFeatureExt:
+			for r.err == nil {
+				if r.byte() == 0xFF {
+					break
+				}
+				r.next(int(r.uint32()))
+			}
+		default:
+			return resp, attention, fmt.Errorf("unsupported tds token 0x%02X", token)
+		}
+	}
+	if r.err == nil && !final {
+		r.fail(fmt.Errorf("%w: the response ends without its final done token", ErrMalformed))
+	}
+	return resp, attention, r.err
+}
+
+// decodeColMetadata decodes the columns of the result set, there are none if the count is 0xFFFF.
+func decodeColMetadata(r *bodyReader) []column {
+	count := r.uint16()
+	if count == 0xFFFF {
+		return nil
+	}
+	// each column takes at least the user type, the flags, the type and the length of its name
+	if int(count)*8 > r.remaining() {
+		r.fail(ErrMalformed)
+		return nil
+	}
+	columns := make([]column, count)
+	for i := range columns {
+		r.uint32() // the user type
+		r.uint16() // the flags
+		columns[i].ti = r.readTypeInfo(true)
+		columns[i].name = r.bVarChar()
+	}
+	return columns
+}
+
+// decodeMessage decodes an ERROR or an INFO token.
+func decodeMessage(r *bodyReader, token byte) models.MSSQLMessage {
+	body := &bodyReader{buf: r.next(int(r.uint16()))}
+	msg := models.MSSQLMessage{Kind: "INFO"}
+	if token == tokenError {
+		msg.Kind = "ERROR"
+	}
+	msg.Number = int32(body.uint32())
+	msg.State = body.byte()
+	msg.Class = body.byte()
+	msg.Message = body.usVarChar()
+	body.bVarChar() // the name of the server
+	msg.Procedure = body.bVarChar()
+	msg.Line = int32(body.uint32())
+	return msg
+}
+
+// decodeEnvChange decodes an ENVCHANGE token, the values which aren't texts are given in hex.
+func decodeEnvChange(r *bodyReader) (models.MSSQLEnvChange, bool) {
+	body := &bodyReader{buf: r.next(int(r.uint16()))}
+	typ := body.byte()
+	change := models.MSSQLEnvChange{Type: envChanges[typ]}
+	if change.Type == "" {
+		change.Type = strconv.Itoa(int(typ))
+	}
+	switch typ {
+	case 1, 2, 3, envPacketSize, 5, 6, 13, 19:
+		change.NewValue = body.bVarChar()
+		change.OldValue = body.bVarChar()
+	case 7, 8, 9, 10, 11, 12, 17, 18:
+		change.NewValue = hex.EncodeToString(body.bVarByte())
+		change.OldValue = hex.EncodeToString(body.bVarByte())
+	default:
+		change.NewValue = hex.EncodeToString(body.buf[body.off:])
+	}
+	return change, body.err == nil && r.err == nil
+}
+
+// PacketSizeOf returns the size of the packets acknowledged by the server in the reply to the LOGIN7.
+func PacketSizeOf(resp *models.MSSQLResponse) int {
+	for _, change := range resp.EnvChanges {
+		if change.Type == envChanges[envPacketSize] {
+			size, err := strconv.Atoi(change.NewValue)
+			if err == nil {
+				return size
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,593 @@
+//go:build linux
+
+package wire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ids of the data types
+const (
+	typeNull           = 0x1F
+	typeInt1           = 0x30
+	typeBit            = 0x32
+	typeInt2           = 0x34
+	typeInt4           = 0x38
+	typeDateTime4      = 0x3A
+	typeFloat4         = 0x3B
+	typeMoney          = 0x3C
+	typeDateTime       = 0x3D
+	typeFloat8         = 0x3E
+	typeMoney4         = 0x7A
+	typeInt8           = 0x7F
+	typeGUID           = 0x24
+	typeIntN           = 0x26
+	typeBitN           = 0x68
+	typeDecimalN       = 0x6A
+	typeNumericN       = 0x6C
+	typeFloatN         = 0x6D
+	typeMoneyN         = 0x6E
+	typeDateTimeN      = 0x6F
+	typeDate           = 0x28
+	typeTime           = 0x29
+	typeDateTime2      = 0x2A
+	typeDateTimeOffset = 0x2B
+	typeChar           = 0x2F
+	typeVarChar        = 0x27
+	typeBinary         = 0x2D
+	typeVarBinary      = 0x25
+	typeBigVarBinary   = 0xA5
+	typeBigVarChar     = 0xA7
+	typeBigBinary      = 0xAD
+	typeBigChar        = 0xAF
+	typeNVarChar       = 0xE7
+	typeNChar          = 0xEF
+	typeText           = 0x23
+	typeImage          = 0x22
+	typeNText          = 0x63
+	typeXML            = 0xF1
+	typeUDT            = 0xF0
+	typeVariant        = 0x62
+)
+
+// sizes of the values of the fixed length types
+var fixedSizes = map[byte]int{
+	typeNull:      0,
+	typeInt1:      1,
+	typeBit:       1,
+	typeInt2:      2,
+	typeInt4:      4,
+	typeDateTime4: 4,
+	typeFloat4:    4,
+	typeMoney:     8,
+	typeDateTime:  8,
+	typeFloat8:    8,
+	typeMoney4:    4,
+	typeInt8:      8,
+}
+
+var fixedNames = map[byte]string{
+	typeNull:      "null",
+	typeInt1:      "tinyint",
+	typeBit:       "bit",
+	typeInt2:      "smallint",
+	typeInt4:      "int",
+	typeDateTime4: "smalldatetime",
+	typeFloat4:    "real",
+	typeMoney:     "money",
+	typeDateTime:  "datetime",
+	typeFloat8:    "float",
+	typeMoney4:    "smallmoney",
+	typeInt8:      "bigint",
+}
+
+// plpNull is the length of a null value of the types whose values are sent in chunks
+const plpNull = math.MaxUint64
+
+const maxLength = 0xFFFF
+
+// typeInfo is the TYPE_INFO of a column or a parameter.
+type typeInfo struct {
+	id byte
+	// size is the maximum length of the values of the variable length types
+	size      int
+	precision byte
+	scale     byte
+	// name is the name of the user-defined types
+	name string
+}
+
+// readTypeInfo reads the TYPE_INFO of a column of a COLMETADATA or of a parameter, the user-defined types are
+// described differently by the two. The name of the table of the text, ntext and image columns is skipped.
+func (r *bodyReader) readTypeInfo(column bool) typeInfo {
+	ti := typeInfo{id: r.byte()}
+	if _, ok := fixedSizes[ti.id]; ok {
+		return ti
+	}
+	switch ti.id {
+	case typeGUID, typeIntN, typeBitN, typeFloatN, typeMoneyN, typeDateTimeN, typeChar, typeVarChar, typeBinary, typeVarBinary:
+		ti.size = int(r.byte())
+	case typeDecimalN, typeNumericN:
+		ti.size = int(r.byte())
+		ti.precision = r.byte()
+		ti.scale = r.byte()
+	case typeDate:
+	case typeTime, typeDateTime2, typeDateTimeOffset:
+		ti.scale = r.byte()
+	case typeBigVarBinary, typeBigBinary:
+		ti.size = int(r.uint16())
+	case typeBigVarChar, typeBigChar, typeNVarChar, typeNChar:
+		ti.size = int(r.uint16())
+		r.next(5) // collation
+	case typeText, typeNText, typeImage:
+		ti.size = int(r.uint32())
+		if ti.id != typeImage {
+			r.next(5)
+		}
+		if column {
+			parts := int(r.byte())
+			for i := 0; i < parts; i++ {
+				r.usVarChar()
+			}
+		}
+	case typeXML:
+		if r.byte() != 0 {
+			r.bVarChar()
+			r.bVarChar()
+			r.usVarChar()
+		}
+	case typeUDT:
+		if column {
+			ti.size = int(r.uint16())
+		}
+		r.bVarChar()
+		r.bVarChar()
+		ti.name = r.bVarChar()
+		if column {
+			r.usVarChar()
+		}
+	case typeVariant:
+		ti.size = int(r.uint32())
+	default:
+		r.fail(fmt.Errorf("unsupported tds data type 0x%02X", ti.id))
+	}
+	return ti
+}
+
+// plp reports whether the values of the type are sent in chunks.
+func (ti typeInfo) plp() bool {
+	switch ti.id {
+	case typeXML, typeUDT:
+		return true
+	case typeBigVarBinary, typeBigVarChar, typeNVarChar:
+		return ti.size == maxLength
+	}
+	return false
+}
+
+// readValue reads a value of the type, the values of the text, ntext and image columns are preceded by a
+// text pointer in the rows unlike in the parameters.
+func (r *bodyReader) readValue(ti typeInfo, row bool) ([]byte, bool) {
+	if size, ok := fixedSizes[ti.id]; ok {
+		return r.next(size), ti.id == typeNull
+	}
+	if ti.plp() {
+		return r.readPLP()
+	}
+	switch ti.id {
+	case typeBigVarBinary, typeBigBinary, typeBigVarChar, typeBigChar, typeNVarChar, typeNChar:
+		n := r.uint16()
+		if n == maxLength {
+			return nil, true
+		}
+		return r.next(int(n)), false
+	case typeText, typeNText, typeImage:
+		if row {
+			ptr := r.byte()
+			if ptr == 0 {
+				return nil, true
+			}
+			r.next(int(ptr) + 8) // the text pointer and the timestamp
+			return r.next(int(r.uint32())), false
+		}
+		n := r.uint32()
+		if n == math.MaxUint32 {
+			return nil, true
+		}
+		return r.next(int(n)), false
+	case typeVariant:
+		n := r.uint32()
+		if n == 0 {
+			return nil, true
+		}
+		return r.next(int(n)), false
+	}
+	n := r.byte()
+	if n == 0 {
+		return nil, true
+	}
+	return r.next(int(n)), false
+}
+
+// readPLP reads the chunks of a partially length-prefixed value.
+func (r *bodyReader) readPLP() ([]byte, bool) {
+	if r.uint64() == plpNull {
+		return nil, true
+	}
+	data := []byte{}
+	for r.err == nil {
+		n := r.uint32()
+		if n == 0 {
+			break
+		}
+		data = append(data, r.next(int(n))...)
+	}
+	return data, false
+}
+
+// typeName returns the name of the type as it is declared in SQL, e.g. "nvarchar(50)".
+func typeName(ti typeInfo) string {
+	if name, ok := fixedNames[ti.id]; ok {
+		return name
+	}
+	switch ti.id {
+	case typeIntN:
+		return map[int]string{1: "tinyint", 2: "smallint", 4: "int", 8: "bigint"}[ti.size]
+	case typeFloatN:
+		if ti.size == 4 {
+			return "real"
+		}
+		return "float"
+	case typeMoneyN:
+		if ti.size == 4 {
+			return "smallmoney"
+		}
+		return "money"
+	case typeDateTimeN:
+		if ti.size == 4 {
+			return "smalldatetime"
+		}
+		return "datetime"
+	case typeBitN:
+		return "bit"
+	case typeGUID:
+		return "uniqueidentifier"
+	case typeDecimalN:
+		return fmt.Sprintf("decimal(%d,%d)", ti.precision, ti.scale)
+	case typeNumericN:
+		return fmt.Sprintf("numeric(%d,%d)", ti.precision, ti.scale)
+	case typeDate:
+		return "date"
+	case typeTime:
+		return fmt.Sprintf("time(%d)", ti.scale)
+	case typeDateTime2:
+		return fmt.Sprintf("datetime2(%d)", ti.scale)
+	case typeDateTimeOffset:
+		return fmt.Sprintf("datetimeoffset(%d)", ti.scale)
+	case typeChar, typeBigChar:
+		return fmt.Sprintf("char(%d)", ti.size)
+	case typeVarChar, typeBigVarChar:
+		return sizedName("varchar", ti.size, 1)
+	case typeBinary, typeBigBinary:
+		return fmt.Sprintf("binary(%d)", ti.size)
+	case typeVarBinary, typeBigVarBinary:
+		return sizedName("varbinary", ti.size, 1)
+	case typeNChar:
+		return fmt.Sprintf("nchar(%d)", ti.size/2)
+	case typeNVarChar:
+		return sizedName("nvarchar", ti.size, 2)
+	case typeText:
+		return "text"
+	case typeNText:
+		return "ntext"
+	case typeImage:
+		return "image"
+	case typeXML:
+		return "xml"
+	case typeUDT:
+		return ti.name
+	case typeVariant:
+		return "sql_variant"
+	}
+	return fmt.Sprintf("0x%02X", ti.id)
+}
+
+func sizedName(name string, size, charSize int) string {
+	if size == maxLength {
+		return name + "(max)"
+	}
+	return fmt.Sprintf("%s(%d)", name, size/charSize)
+}
+
+// formatValue formats the value as SQL Server prints it, the binary values are given in hex.
+func formatValue(ti typeInfo, data []byte) string {
+	switch ti.id {
+	case typeInt1, typeInt2, typeInt4, typeInt8, typeIntN:
+		return formatInt(data)
+	case typeBit, typeBitN:
+		if len(data) > 0 && data[0] != 0 {
+			return "1"
+		}
+		return "0"
+	case typeFloat4, typeFloat8, typeFloatN:
+		if len(data) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), 'g', -1, 32)
+		}
+		if len(data) == 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(data)), 'g', -1, 64)
+		}
+	case typeMoney, typeMoney4, typeMoneyN:
+		if len(data) == 4 {
+			return formatScaled(big.NewInt(int64(int32(binary.LittleEndian.Uint32(data)))), 4)
+		}
+		if len(data) == 8 {
+			v := int64(int32(binary.LittleEndian.Uint32(data)))<<32 | int64(binary.LittleEndian.Uint32(data[4:]))
+			return formatScaled(big.NewInt(v), 4)
+		}
+	case typeDateTime, typeDateTime4, typeDateTimeN:
+		return formatDateTime(data)
+	case typeDecimalN, typeNumericN:
+		if len(data) > 1 {
+			v := new(big.Int).SetBytes(reverse(data[1:]))
+			if data[0] == 0 {
+				v.Neg(v)
+			}
+			return formatScaled(v, int(ti.scale))
+		}
+	case typeGUID:
+		if len(data) == 16 {
+			b := append(reverse(data[0:4]), reverse(data[4:6])...)
+			b = append(b, reverse(data[6:8])...)
+			b = append(b, data[8:]...)
+			h := strings.ToUpper(hex.EncodeToString(b))
+			return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+		}
+	case typeDate:
+		if len(data) == 3 {
+			return dateOf(data).Format("2006-01-02")
+		}
+	case typeTime:
+		return formatTime(data, int(ti.scale))
+	case typeDateTime2:
+		if len(data) > 3 {
+			n := len(data) - 3
+			return dateOf(data[n:]).Format("2006-01-02") + " " + formatTime(data[:n], int(ti.scale))
+		}
+	case typeDateTimeOffset:
+		if len(data) > 5 {
+			n := len(data) - 5
+			offset := int(int16(binary.LittleEndian.Uint16(data[n+3:])))
+			utc := dateOf(data[n : n+3]).Add(timeOf(data[:n], int(ti.scale)))
+			local := utc.Add(time.Duration(offset) * time.Minute)
+			sign := "+"
+			if offset < 0 {
+				sign = "-"
+				offset = -offset
+			}
+			return local.Format("2006-01-02 15:04:05") + fraction(data[:n], int(ti.scale)) + fmt.Sprintf(" %s%02d:%02d", sign, offset/60, offset%60)
+		}
+	case typeChar, typeVarChar, typeBigChar, typeBigVarChar, typeText:
+		return string(data)
+	case typeNChar, typeNVarChar, typeNText, typeXML:
+		return ucs2(data)
+	case typeVariant:
+		return formatVariant(data)
+	}
+	return "0x" + strings.ToUpper(hex.EncodeToString(data))
+}
+
+// formatVariant formats a sql_variant value by its base type, which precedes it along with its properties.
+func formatVariant(data []byte) string {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return "0x" + strings.ToUpper(hex.EncodeToString(data))
+	}
+	ti := typeInfo{id: data[0]}
+	props := data[2 : 2+int(data[1])]
+	value := data[2+int(data[1]):]
+	switch ti.id {
+	case typeDecimalN, typeNumericN:
+		if len(props) == 2 {
+			ti.precision, ti.scale = props[0], props[1]
+		}
+	case typeTime, typeDateTime2, typeDateTimeOffset:
+		if len(props) == 1 {
+			ti.scale = props[0]
+		}
+	}
+	return formatValue(ti, value)
+}
+
+func formatInt(data []byte) string {
+	switch len(data) {
+	case 1:
+		return strconv.Itoa(int(data[0]))
+	case 2:
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(data))))
+	case 4:
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(data))))
+	case 8:
+		return strconv.FormatInt(int64(binary.LittleEndian.Uint64(data)), 10)
+	}
+	return "0x" + strings.ToUpper(hex.EncodeToString(data))
+}
+
+// formatScaled formats the integer with the given number of decimal places.
+func formatScaled(v *big.Int, scale int) string {
+	s := new(big.Int).Abs(v).String()
+	if scale > 0 {
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale-len(s)+1) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	}
+	if v.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+var epoch1900 = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// formatDateTime formats the datetime, as days since 1900 and 1/300 seconds, and the smalldatetime, as days
+// since 1900 and minutes.
+func formatDateTime(data []byte) string {
+	switch len(data) {
+	case 4:
+		days := int(binary.LittleEndian.Uint16(data))
+		minutes := int(binary.LittleEndian.Uint16(data[2:]))
+		return epoch1900.AddDate(0, 0, days).Add(time.Duration(minutes) * time.Minute).Format("2006-01-02 15:04:05")
+	case 8:
+		days := int(int32(binary.LittleEndian.Uint32(data)))
+		ticks := int64(binary.LittleEndian.Uint32(data[4:]))
+		ms := (ticks*10 + 1) / 3
+		return epoch1900.AddDate(0, 0, days).Add(time.Duration(ms) * time.Millisecond).Format("2006-01-02 15:04:05.000")
+	}
+	return "0x" + strings.ToUpper(hex.EncodeToString(data))
+}
+
+// dateOf returns the date given as days since 0001-01-01.
+func dateOf(data []byte) time.Time {
+	days := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+	return time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+}
+
+// timeOf returns the time of the day given in units of 10^-scale seconds.
+func timeOf(data []byte, scale int) time.Duration {
+	var units uint64
+	for i := len(data) - 1; i >= 0; i-- {
+		units = units<<8 | uint64(data[i])
+	}
+	for i := scale; i < 9; i++ {
+		units *= 10
+	}
+	return time.Duration(units)
+}
+
+func formatTime(data []byte, scale int) string {
+	t := time.Time{}.Add(timeOf(data, scale))
+	return t.Format("15:04:05") + fraction(data, scale)
+}
+
+// fraction returns the fractional seconds of the time with the digits of its scale.
+func fraction(data []byte, scale int) string {
+	if scale == 0 {
+		return ""
+	}
+	// the scale is at most 7, the invalid ones are cut to the nanoseconds
+	scale = min(scale, 9)
+	ns := int64(timeOf(data, scale) % time.Second)
+	return "." + fmt.Sprintf("%09d", ns)[:scale]
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// ucs2 decodes the UTF-16LE text of the protocol.
+func ucs2(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// encodeUCS2 encodes the text as UTF-16LE.
+func encodeUCS2(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+// bodyReader reads the little-endian fields of a message, the first error is kept and the following reads
+// return zero values.
+type bodyReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *bodyReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *bodyReader) remaining() int {
+	return len(r.buf) - r.off
+}
+
+func (r *bodyReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.off+n > len(r.buf) {
+		r.err = ErrMalformed
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *bodyReader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *bodyReader) uint16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *bodyReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *bodyReader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// bVarChar reads a text whose length in characters is given by a byte.
+func (r *bodyReader) bVarChar() string {
+	return ucs2(r.next(2 * int(r.byte())))
+}
+
+// usVarChar reads a text whose length in characters is given by two bytes.
+func (r *bodyReader) usVarChar() string {
+	return ucs2(r.next(2 * int(r.uint16())))
+}
+
+// bVarByte reads the bytes whose length is given by a byte.
+func (r *bodyReader) bVarByte() []byte {
+	return r.next(int(r.byte()))
+}
@@ -0,0 +1,538 @@
+//go:build linux
+
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+var collation = []byte{0x09, 0x04, 0xD0, 0x00, 0x34}
+
+func appendBVarChar(b []byte, s string) []byte {
+	text := encodeUCS2(s)
+	return append(append(b, byte(len(text)/2)), text...)
+}
+
+// withHeaders prepends the ALL_HEADERS of a transaction descriptor to the body.
+func withHeaders(body []byte) []byte {
+	headers := binary.LittleEndian.AppendUint32(nil, 22)
+	headers = binary.LittleEndian.AppendUint32(headers, 18)
+	headers = binary.LittleEndian.AppendUint16(headers, 2)
+	headers = append(headers, make([]byte, 12)...)
+	return append(headers, body...)
+}
+
+func login7() []byte {
+	texts := []string{"host", "sa", "", "app", "server", "", "go-mssqldb", "us_english", "shop"}
+	payload := make([]byte, login7FixedLength, login7FixedLength+9*4)
+	binary.LittleEndian.PutUint32(payload[4:], 0x74000004)
+	binary.LittleEndian.PutUint32(payload[8:], 4096)
+	off := login7FixedLength + 9*4
+	var data []byte
+	for _, text := range texts {
+		payload = binary.LittleEndian.AppendUint16(payload, uint16(off+len(data)))
+		payload = binary.LittleEndian.AppendUint16(payload, uint16(len(text)))
+		data = append(data, encodeUCS2(text)...)
+	}
+	binary.LittleEndian.PutUint32(payload, uint32(len(payload)+len(data)))
+	return append(payload, data...)
+}
+
+func nvarcharParam(b []byte, name, value string) []byte {
+	b = appendBVarChar(b, name)
+	b = append(b, 0, typeNVarChar)
+	b = binary.LittleEndian.AppendUint16(b, 8000)
+	b = append(b, collation...)
+	text := encodeUCS2(value)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(text)))
+	return append(b, text...)
+}
+
+func intParam(b []byte, name string, status byte, value int32) []byte {
+	b = appendBVarChar(b, name)
+	b = append(b, status, typeIntN, 4, 4)
+	return binary.LittleEndian.AppendUint32(b, uint32(value))
+}
+
+func rpcCall(id uint16) []byte {
+	b := binary.LittleEndian.AppendUint16(nil, 0xFFFF)
+	b = binary.LittleEndian.AppendUint16(b, id)
+	return binary.LittleEndian.AppendUint16(b, 0)
+}
+
+// executeSQL calls sp_executesql with a statement taking an int, followed by sp_execute of the handle 7.
+func executeSQL() []byte {
+	b := rpcCall(procExecuteSQL)
+	b = nvarcharParam(b, "", "select @p1")
+	b = nvarcharParam(b, "", "@p1 int")
+	b = intParam(b, "@p1", 0, 42)
+	b = append(b, rpcBatchFlag)
+	b = append(b, rpcCall(procExecute)...)
+	return intParam(b, "", 0, 7)
+}
+
+// prepare calls sp_prepare of a statement taking an int, the handle of the statement is an output parameter.
+func prepare() []byte {
+	b := rpcCall(procPrepare)
+	b = intParam(b, "", rpcOutput, 0)
+	b = nvarcharParam(b, "", "@p1 int")
+	return nvarcharParam(b, "", "select @p1")
+}
+
+func doneToken(status uint16, count uint64) []byte {
+	b := []byte{tokenDone}
+	b = binary.LittleEndian.AppendUint16(b, status)
+	b = binary.LittleEndian.AppendUint16(b, 0xC1)
+	return binary.LittleEndian.AppendUint64(b, count)
+}
+
+func token16(token byte, body []byte) []byte {
+	b := binary.LittleEndian.AppendUint16([]byte{token}, uint16(len(body)))
+	return append(b, body...)
+}
+
+// resultSet returns the tokens of a result set of an int and an nvarchar column, the second row is sent as an
+// NBCROW whose name is null.
+func resultSet() []byte {
+	b := []byte{tokenColMetadata}
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = append(b, 0, 0, 0, 0, 0, 0, typeIntN, 4)
+	b = appendBVarChar(b, "id")
+	b = append(b, 0, 0, 0, 0, 0, 0, typeNVarChar)
+	b = binary.LittleEndian.AppendUint16(b, 100)
+	b = append(b, collation...)
+	b = appendBVarChar(b, "name")
+
+	b = append(b, tokenRow, 4)
+	b = binary.LittleEndian.AppendUint32(b, 1)
+	b = binary.LittleEndian.AppendUint16(b, 6)
+	b = append(b, encodeUCS2("ada")...)
+
+	b = append(b, tokenNBCRow, 0x02, 4)
+	b = binary.LittleEndian.AppendUint32(b, 2)
+	return append(b, doneToken(doneCount, 2)...)
+}
+
+func loginReply() []byte {
+	ack := []byte{1, 0x74, 0, 0, 0x04}
+	ack = appendBVarChar(ack, "Microsoft SQL Server")
+	ack = append(ack, 15, 0, 0x07, 0xD0)
+
+	env := appendBVarChar([]byte{envPacketSize}, "8192")
+	env = appendBVarChar(env, "4096")
+
+	b := token16(tokenLoginAck, ack)
+	b = append(b, token16(tokenEnvChange, env)...)
+	return append(b, doneToken(0, 0)...)
+}
+
+func prepareReply() []byte {
+	b := []byte{tokenReturnStatus, 0, 0, 0, 0}
+	b = append(b, tokenReturnValue, 0, 0)
+	b = appendBVarChar(b, "@handle")
+	b = append(b, rpcOutput, 0, 0, 0, 0, 0, 0, typeIntN, 4, 4)
+	b = binary.LittleEndian.AppendUint32(b, 3)
+	return append(b, tokenDoneProc, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    []byte
+		packetSize int
+		packets    int
+	}{
+		{name: "empty", payload: nil, packetSize: DefaultPacketSize, packets: 1},
+		{name: "single packet", payload: []byte("select 1"), packetSize: DefaultPacketSize, packets: 1},
+		{name: "split payload", payload: bytes.Repeat([]byte{0xAB}, 100), packetSize: 40, packets: 4},
+		{name: "invalid packet size", payload: bytes.Repeat([]byte{1}, 5000), packetSize: 4, packets: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := EncodeMessage(PacketSQLBatch, tt.payload, tt.packetSize)
+			msg, err := ReadMessage(bytes.NewReader(encoded))
+			if err != nil {
+				t.Fatalf("failed to read the message: %v", err)
+			}
+			if msg.Type != PacketSQLBatch || !bytes.Equal(msg.Payload, tt.payload) || !bytes.Equal(msg.Raw, encoded) {
+				t.Fatalf("got the message %+v", msg)
+			}
+			if packets := len(encoded) - len(tt.payload); packets != tt.packets*HeaderLen {
+				t.Errorf("got %d packets, want %d", packets/HeaderLen, tt.packets)
+			}
+		})
+	}
+}
+
+func TestReadMessageMalformed(t *testing.T) {
+	split := EncodeMessage(PacketSQLBatch, bytes.Repeat([]byte{1}, 100), 40)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr error
+	}{
+		{name: "empty", input: nil, wantErr: io.EOF},
+		{name: "length shorter than the header", input: []byte{PacketSQLBatch, StatusEOM, 0, 4, 0, 0, 1, 0}, wantErr: ErrMalformed},
+		{name: "length longer than a packet", input: []byte{PacketSQLBatch, StatusEOM, 0xFF, 0xFF, 0, 0, 1, 0}, wantErr: ErrMalformed},
+		{name: "truncated header", input: split[:4], wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated body", input: split[:20], wantErr: io.ErrUnexpectedEOF},
+		{name: "missing last packet", input: split[:40], wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadMessage(bytes.NewReader(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got the error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPreLoginRoundTrip(t *testing.T) {
+	options := DefaultPreLogin()
+	options["0x42"] = "ff"
+	payload, err := EncodePreLogin(options)
+	if err != nil {
+		t.Fatalf("failed to encode the prelogin: %v", err)
+	}
+	decoded, err := DecodePreLogin(payload)
+	if err != nil {
+		t.Fatalf("failed to decode the prelogin: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, options) {
+		t.Fatalf("got the options %v, want %v", decoded, options)
+	}
+	if !IsPreLogin(EncodeMessage(PacketPreLogin, payload, DefaultPacketSize)) {
+		t.Errorf("the encoded prelogin isn't detected")
+	}
+	if Negotiate(EncryptionOf(decoded)) != EncryptNone {
+		t.Errorf("the default prelogin doesn't leave the connection in the clear")
+	}
+}
+
+func TestDecodePreLoginMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{name: "empty", payload: nil},
+		{name: "missing terminator", payload: []byte{optionVersion, 0, 5, 0, 0}},
+		{name: "truncated option", payload: []byte{optionVersion, 0, 5}},
+		{name: "value out of bounds", payload: []byte{optionVersion, 0, 6, 0, 6, optionTerminator}},
+		{name: "offset overflowing the payload", payload: []byte{optionVersion, 0xFF, 0xFF, 0xFF, 0xFF, optionTerminator}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodePreLogin(tt.payload)
+			if !errors.Is(err, ErrMalformed) {
+				t.Fatalf("got the error %v, want %v", err, ErrMalformed)
+			}
+			if IsPreLogin(EncodeMessage(PacketPreLogin, tt.payload, DefaultPacketSize)) {
+				t.Errorf("the malformed prelogin is detected")
+			}
+		})
+	}
+}
+
+func TestDecodeRequest(t *testing.T) {
+	lookup := func(handle int32) string {
+		if handle == 7 {
+			return "select 1"
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name string
+		msg  *Message
+		want *models.MSSQLRequest
+	}{
+		{
+			name: "login",
+			msg:  &Message{Type: PacketLogin7, Payload: login7()},
+			want: &models.MSSQLRequest{
+				Type: "Login7",
+				Login: &models.MSSQLLogin{
+					TDSVersion: "0x74000004",
+					PacketSize: 4096,
+					HostName:   "host",
+					UserName:   "sa",
+					AppName:    "app",
+					ServerName: "server",
+					Library:    "go-mssqldb",
+					Language:   "us_english",
+					Database:   "shop",
+				},
+			},
+		},
+		{
+			name: "batch",
+			msg:  &Message{Type: PacketSQLBatch, Payload: withHeaders(encodeUCS2("select * from users"))},
+			want: &models.MSSQLRequest{Type: "SQLBatch", Query: "select * from users"},
+		},
+		{
+			name: "rpc",
+			msg:  &Message{Type: PacketRPC, Payload: withHeaders(executeSQL())},
+			want: &models.MSSQLRequest{
+				Type: "RPC",
+				RPCs: []models.MSSQLRPC{
+					{
+						Procedure: "sp_executesql",
+						Query:     "select @p1",
+						Parameters: []models.MSSQLParameter{
+							{Type: "nvarchar(4000)", Value: "select @p1"},
+							{Type: "nvarchar(4000)", Value: "@p1 int"},
+							{Name: "@p1", Type: "int", Value: "42"},
+						},
+					},
+					{Procedure: "sp_execute", Query: "select 1", Handle: 7, Parameters: []models.MSSQLParameter{}},
+				},
+			},
+		},
+		{
+			name: "transaction",
+			msg:  &Message{Type: PacketTransaction, Payload: withHeaders([]byte{5, 0, 0})},
+			want: &models.MSSQLRequest{Type: "TransactionManager", Query: "TM_BEGIN_XACT", Payload: "BQAA"},
+		},
+		{
+			name: "attention",
+			msg:  &Message{Type: PacketAttention},
+			want: &models.MSSQLRequest{Type: "Attention"},
+		},
+		{
+			name: "other",
+			msg:  &Message{Type: PacketBulkLoad, Payload: []byte{1, 2, 3}},
+			want: &models.MSSQLRequest{Type: "BulkLoad", Payload: "AQID"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := DecodeRequest(tt.msg, lookup)
+			if err != nil {
+				t.Fatalf("failed to decode the request: %v", err)
+			}
+			if !reflect.DeepEqual(req, tt.want) {
+				t.Fatalf("got the request %+v, want %+v", req, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRequestMalformed(t *testing.T) {
+	longName := binary.LittleEndian.AppendUint16(nil, 0x7FFF)
+
+	unsupportedType := rpcCall(procExecuteSQL)
+	unsupportedType = append(unsupportedType, 0, 0, 0x01)
+
+	tests := []struct {
+		name string
+		msg  *Message
+	}{
+		{name: "short login", msg: &Message{Type: PacketLogin7, Payload: make([]byte, 40)}},
+		{name: "login text out of bounds", msg: &Message{Type: PacketLogin7, Payload: append(login7()[:login7FixedLength+8*4], 0xFF, 0xFF, 0xFF, 0xFF)}},
+		{name: "batch of an odd length", msg: &Message{Type: PacketSQLBatch, Payload: []byte("abc")}},
+		{name: "procedure name out of bounds", msg: &Message{Type: PacketRPC, Payload: longName}},
+		{name: "unsupported parameter type", msg: &Message{Type: PacketRPC, Payload: unsupportedType}},
+		{name: "truncated prelogin", msg: &Message{Type: PacketPreLogin, Payload: []byte{optionVersion}}},
+		{name: "no procedure call", msg: &Message{Type: PacketRPC, Payload: withHeaders(nil)}},
+		{name: "batch ending with a separator", msg: &Message{Type: PacketRPC, Payload: append(executeSQL(), rpcBatchFlag)}},
+		{name: "missing parameters of the procedure", msg: &Message{Type: PacketRPC, Payload: rpcCall(procPrepare)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeRequest(tt.msg, nil)
+			if err == nil {
+				t.Fatalf("the malformed request is decoded")
+			}
+		})
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       []byte
+		want          *models.MSSQLResponse
+		wantAttention bool
+	}{
+		{
+			name:    "result set",
+			payload: resultSet(),
+			want: &models.MSSQLResponse{
+				ResultSets: []models.MSSQLResultSet{{
+					Columns: []models.MSSQLColumn{{Name: "id", Type: "int"}, {Name: "name", Type: "nvarchar(50)"}},
+					Rows:    [][]string{{"1", "ada"}, {"2", "NULL"}},
+				}},
+				RowCounts: []uint64{2},
+			},
+		},
+		{
+			name:    "login",
+			payload: loginReply(),
+			want: &models.MSSQLResponse{
+				LoginAck:   "Microsoft SQL Server 15.0.2000 (TDS 0x74000004)",
+				EnvChanges: []models.MSSQLEnvChange{{Type: "PacketSize", NewValue: "8192", OldValue: "4096"}},
+			},
+		},
+		{
+			name:    "prepare",
+			payload: prepareReply(),
+			want: &models.MSSQLResponse{
+				ReturnStatus: []int32{0},
+				ReturnValues: []models.MSSQLParameter{{Name: "@handle", Type: "int", Value: "3", Output: true}},
+			},
+		},
+		{
+			name:    "error",
+			payload: EncodeError("no mock matches"),
+			want: &models.MSSQLResponse{
+				Messages: []models.MSSQLMessage{{Kind: "ERROR", Number: errorNumber, State: 1, Class: errorClass, Message: "no mock matches", Line: 1}},
+			},
+		},
+		{
+			name:    "truncated login ack",
+			payload: append(token16(tokenLoginAck, []byte{1, 0x74}), doneToken(0, 0)...),
+			want:    &models.MSSQLResponse{},
+		},
+		{
+			name:          "attention",
+			payload:       EncodeAttentionAck(),
+			want:          &models.MSSQLResponse{},
+			wantAttention: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, attention, err := DecodeResponse(tt.payload)
+			if err != nil {
+				t.Fatalf("failed to decode the response: %v", err)
+			}
+			resp.Payload = ""
+			if !reflect.DeepEqual(resp, tt.want) || attention != tt.wantAttention {
+				t.Fatalf("got the response %+v (attention: %t), want %+v", resp, attention, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeResponseMalformed(t *testing.T) {
+	manyColumns := []byte{tokenColMetadata, 0xFE, 0xFF}
+
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{name: "unsupported token", payload: []byte{0x01}},
+		{name: "column count beyond the payload", payload: manyColumns},
+		{name: "token length beyond the payload", payload: []byte{tokenSessionState, 0xFF, 0xFF, 0xFF, 0x7F}},
+		{name: "unterminated feature extension", payload: []byte{tokenFeatureExt, 1, 0, 0, 0, 0}},
+		{name: "no final done", payload: doneToken(doneMore|doneCount, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := DecodeResponse(tt.payload)
+			if err == nil {
+				t.Fatalf("the malformed response is decoded")
+			}
+		})
+	}
+}
+
+// TestDecodeTruncated decodes every prefix of the messages, a message cut by a client or a server must not
+// panic the proxy and must not be decoded.
+func TestDecodeTruncated(t *testing.T) {
+	requests := map[string]*Message{
+		"login": {Type: PacketLogin7, Payload: login7()},
+		// the calls batched by executeSQL can be cut between two of them, the call of sp_prepare can't
+		"rpc": {Type: PacketRPC, Payload: withHeaders(prepare())},
+	}
+	for name, msg := range requests {
+		t.Run(name, func(t *testing.T) {
+			for i := range msg.Payload {
+				_, err := DecodeRequest(&Message{Type: msg.Type, Payload: msg.Payload[:i]}, nil)
+				if err == nil {
+					t.Fatalf("expected an error for the payload truncated to %d bytes", i)
+				}
+			}
+		})
+	}
+
+	responses := map[string][]byte{
+		"result set": resultSet(),
+		"login":      loginReply(),
+		"prepare":    prepareReply(),
+		"error":      EncodeError("no mock matches"),
+	}
+	for name, payload := range responses {
+		t.Run(name, func(t *testing.T) {
+			for i := range payload {
+				_, _, err := DecodeResponse(payload[:i])
+				if err == nil {
+					t.Fatalf("expected an error for the payload truncated to %d bytes", i)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeErrorLongMessage(t *testing.T) {
+	message := strings.Repeat("x", 100000)
+	resp, _, err := DecodeResponse(EncodeError(message))
+	if err != nil {
+		t.Fatalf("failed to decode the error: %v", err)
+	}
+	if len(resp.Messages) != 1 || !strings.HasPrefix(message, resp.Messages[0].Message) || resp.Messages[0].Message == "" {
+		t.Fatalf("got the messages %+v", resp.Messages)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		name string
+		ti   typeInfo
+		data []byte
+		want string
+	}{
+		{name: "int", ti: typeInfo{id: typeIntN}, data: []byte{0xFE, 0xFF, 0xFF, 0xFF}, want: "-2"},
+		{name: "bigint", ti: typeInfo{id: typeInt8}, data: []byte{1, 0, 0, 0, 0, 0, 0, 0}, want: "1"},
+		{name: "bit", ti: typeInfo{id: typeBitN}, data: []byte{1}, want: "1"},
+		{name: "float", ti: typeInfo{id: typeFloatN}, data: binary.LittleEndian.AppendUint64(nil, 0x3FF8000000000000), want: "1.5"},
+		{name: "money", ti: typeInfo{id: typeMoney4}, data: binary.LittleEndian.AppendUint32(nil, 12345), want: "1.2345"},
+		{name: "decimal", ti: typeInfo{id: typeDecimalN, scale: 2}, data: []byte{0, 0x39, 0x30, 0, 0}, want: "-123.45"},
+		{name: "guid", ti: typeInfo{id: typeGUID}, data: []byte{3, 2, 1, 0, 5, 4, 7, 6, 8, 9, 10, 11, 12, 13, 14, 15}, want: "00010203-0405-0607-0809-0A0B0C0D0E0F"},
+		{name: "date", ti: typeInfo{id: typeDate}, data: []byte{0, 0, 0}, want: "0001-01-01"},
+		{name: "time", ti: typeInfo{id: typeTime, scale: 1}, data: []byte{10, 0, 0}, want: "00:00:01.0"},
+		{name: "time of an invalid scale", ti: typeInfo{id: typeTime, scale: 200}, data: []byte{1, 0, 0}, want: "00:00:00.000000001"},
+		{name: "datetime", ti: typeInfo{id: typeDateTimeN}, data: []byte{0, 0, 0, 0, 0, 0, 0, 0}, want: "1900-01-01 00:00:00.000"},
+		{name: "nvarchar", ti: typeInfo{id: typeNVarChar}, data: encodeUCS2("héllo"), want: "héllo"},
+		{name: "varbinary", ti: typeInfo{id: typeBigVarBinary}, data: []byte{0xCA, 0xFE}, want: "0xCAFE"},
+		{name: "variant", ti: typeInfo{id: typeVariant}, data: []byte{typeInt4, 0, 7, 0, 0, 0}, want: "7"},
+		{name: "truncated variant", ti: typeInfo{id: typeVariant}, data: []byte{typeInt4, 5}, want: "0x3805"},
+		{name: "truncated int", ti: typeInfo{id: typeIntN}, data: []byte{1, 2, 3}, want: "0x010203"},
+		{name: "truncated datetimeoffset", ti: typeInfo{id: typeDateTimeOffset}, data: []byte{1, 2}, want: "0x0102"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatValue(tt.ti, tt.data); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
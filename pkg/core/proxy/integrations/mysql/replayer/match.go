@@ -11,7 +11,6 @@ import (
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
-	intgUtil "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
 	"go.keploy.io/server/v2/utils"
@@ -117,7 +116,7 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 		}
 
 		// Get the tcs mocks from the mockDb
-		unfiltered, err := mockDb.GetUnFilteredMocks()
+		mocks, err := mockDb.GetUnFilteredMocksByKind(models.MySQL)
 		if err != nil {
 			if ctx.Err() != nil {
 				return nil, false, ctx.Err()
@@ -126,9 +125,6 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 			return nil, false, err
 		}
 
-		// Get the mysql mocks
-		mocks := intgUtil.GetMockByKind(unfiltered, "MySQL")
-
 		if len(mocks) == 0 {
 			if ctx.Err() != nil {
 				return nil, false, ctx.Err()
@@ -18,7 +18,7 @@ import (
 	"go.uber.org/zap"
 )
 
-func simulateCommandPhase(ctx context.Context, logger *zap.Logger, clientConn net.Conn, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext, opts models.OutgoingOptions) error {
+func simulateCommandPhase(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext, opts models.OutgoingOptions) error {
 
 	for {
 		select {
@@ -77,6 +77,11 @@ func simulateCommandPhase(ctx context.Context, logger *zap.Logger, clientConn ne
 
 			if !ok {
 				utils.LogError(logger, nil, "No matching mock found for the command", zap.Any("command", command))
+				mockDb.RecordUnmatched(models.UnmatchedCall{
+					Kind:        models.MySQL,
+					Destination: dstCfg.Addr,
+					Summary:     summarizeCommand(commandPkt),
+				})
 				return fmt.Errorf("error while simulating the command phase due to no matching mock found")
 			}
 
@@ -110,3 +115,19 @@ func simulateCommandPhase(ctx context.Context, logger *zap.Logger, clientConn ne
 		}
 	}
 }
+
+// summarizeCommand returns the type of the command along with its query if any, for the report of the unmatched calls.
+func summarizeCommand(pkt *mysql.PacketBundle) string {
+	if pkt == nil || pkt.Header == nil {
+		return ""
+	}
+	switch msg := pkt.Message.(type) {
+	case *mysql.QueryPacket:
+		return pkt.Header.Type + " " + msg.Query
+	case *mysql.StmtPreparePacket:
+		return pkt.Header.Type + " " + msg.Query
+	case *mysql.StmtExecutePacket:
+		return fmt.Sprintf("%s statement %d", pkt.Header.Type, msg.StatementID)
+	}
+	return pkt.Header.Type
+}
@@ -10,7 +10,6 @@ import (
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
-	intgUtil "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
 	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
@@ -20,18 +19,15 @@ import (
 
 // Mock Yaml to Binary
 
-func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
 	errCh := make(chan error, 1)
 
-	unfiltered, err := mockDb.GetUnFilteredMocks()
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.MySQL)
 	if err != nil {
 		utils.LogError(logger, err, "failed to get unfiltered mocks")
 		return err
 	}
 
-	// Get the mysql mocks
-	mocks := intgUtil.GetMockByKind(unfiltered, "MySQL")
-
 	if len(mocks) == 0 {
 		utils.LogError(logger, nil, "no mysql mocks found")
 		return nil
@@ -78,7 +74,7 @@ func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, _ *int
 		logger.Debug("Initial handshake completed successfully")
 
 		// Simulate the client-server interaction (command phase)
-		err = simulateCommandPhase(ctx, logger, clientConn, mockDb, decodeCtx, opts)
+		err = simulateCommandPhase(ctx, logger, clientConn, dstCfg, mockDb, decodeCtx, opts)
 		if err != nil {
 			if err != io.EOF {
 				utils.LogError(logger, err, "failed to simulate command phase")
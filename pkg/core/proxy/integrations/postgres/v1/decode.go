@@ -68,6 +68,11 @@ func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 
 			if !matched {
 				logger.Debug("MISMATCHED REQ is" + string(pgRequests[0]))
+				mockDb.RecordUnmatched(models.UnmatchedCall{
+					Kind:        models.Postgres,
+					Destination: dstCfg.Addr,
+					Summary:     pUtil.Summarize(pgRequests),
+				})
 				_, err = pUtil.PassThrough(ctx, logger, clientConn, dstCfg, pgRequests)
 				if err != nil {
 					utils.LogError(logger, err, "failed to pass the request", zap.Any("request packets", len(pgRequests)))
@@ -74,15 +74,7 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 			return false, nil, ctx.Err()
 		default:
 
-			mocks, err := mockDb.GetUnFilteredMocks()
-			var tcsMocks []*models.Mock
-
-			for _, mock := range mocks {
-				if mock.Kind != "Postgres" {
-					continue
-				}
-				tcsMocks = append(tcsMocks, mock)
-			}
+			tcsMocks, err := mockDb.GetUnFilteredMocksByKind(models.Postgres)
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting tcs mocks %v", err)
 			}
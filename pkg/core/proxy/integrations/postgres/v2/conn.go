@@ -0,0 +1,60 @@
+//go:build linux
+
+package v2
+
+import (
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type portal struct {
+	query      string
+	parameters []*string
+}
+
+// connState is the state of a postgres connection, i.e. the prepared statements and the portals
+// referred to by name in the later messages. It is kept per connection, the names given by the
+// drivers are only unique within a connection.
+type connState struct {
+	statements map[string]string
+	portals    map[string]portal
+}
+
+func newConnState() *connState {
+	return &connState{
+		statements: map[string]string{},
+		portals:    map[string]portal{},
+	}
+}
+
+// resolve fills the query and the bound parameters of the request from the statements and the
+// portals of the connection, and keeps track of the statements and the portals created or closed.
+func (c *connState) resolve(req *models.PostgresV2Request) {
+	switch req.Type {
+	case "Query":
+		// a simple query replaces the unnamed statement and portal
+		delete(c.statements, "")
+		delete(c.portals, "")
+	case "Parse":
+		c.statements[req.Statement] = req.Query
+	case "Bind":
+		req.Query = c.statements[req.Statement]
+		c.portals[req.Portal] = portal{query: req.Query, parameters: req.Parameters}
+	case "Execute":
+		// the portal can be executed several times when the rows are fetched in batches
+		p := c.portals[req.Portal]
+		req.Query = p.query
+		req.Parameters = p.parameters
+	case "Describe", "Close":
+		if req.ObjectType == "P" {
+			req.Query = c.portals[req.Portal].query
+			if req.Type == "Close" {
+				delete(c.portals, req.Portal)
+			}
+			return
+		}
+		req.Query = c.statements[req.Statement]
+		if req.Type == "Close" {
+			delete(c.statements, req.Statement)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+//go:build linux
+
+package v2
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func decodePostgres(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	errCh := make(chan error, 1)
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		errCh <- replay(ctx, logger, clientConn, dstCfg, mockDb, opts)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
+
+// replay answers the messages of the client with the responses of the matching mocks until the
+// connection is closed.
+func replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	clientConn, reader, err := negotiateMock(ctx, logger, clientConn)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	sess := newSession()
+	startup := true
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		msg, err := readMessage(reader, !startup)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		req, err := decodeRequest(msg)
+		if err != nil {
+			logger.Debug("failed to decode the postgres message of the client, the mock will be matched on the raw bytes", zap.Error(err))
+			req = models.PostgresV2Request{Type: "Unknown", Raw: base64.StdEncoding.EncodeToString(msg.raw)}
+		}
+		sess.state.resolve(&req)
+
+		switch req.Type {
+		case "CancelRequest", "Terminate":
+			return nil
+		case "StartupMessage":
+			startup = false
+		}
+
+		if sess.failed {
+			// the server discards the messages until the next Sync after an error
+			if req.Type == "Sync" {
+				sess.failed = false
+				if err := writeMessages(clientConn, readyForQuery()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		sess.pending = append(sess.pending, req)
+		if !isSyncPoint(req.Type) {
+			continue
+		}
+
+		pending := sess.pending
+		responses, ok, err := sess.match(ctx, mockDb)
+		if err != nil {
+			utils.LogError(logger, err, "failed to match the postgres mocks")
+			return err
+		}
+		if !ok {
+			logger.Error("no postgres mock matched the query of the application", zap.Any("messages", pending))
+			mockDb.RecordUnmatched(models.UnmatchedCall{
+				Kind:        models.PostgresV2,
+				Destination: dstCfg.Addr,
+				Summary:     summarizeRequests(pending),
+			})
+			err := sess.fail(clientConn, req.Type)
+			if err != nil || req.Type == "StartupMessage" {
+				return err
+			}
+			continue
+		}
+
+		var buf []byte
+		for _, resp := range responses {
+			encoded, err := encodeResponse(resp)
+			if err != nil {
+				utils.LogError(logger, err, "failed to encode the postgres response of the mock")
+				return err
+			}
+			buf = append(buf, encoded...)
+		}
+		_, err = clientConn.Write(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			utils.LogError(logger, err, "failed to write the postgres response to the client application")
+			return err
+		}
+	}
+}
+
+// fail sends an error to the client when no mock is found, so that the application gets an error
+// instead of waiting for the response.
+func (s *session) fail(conn net.Conn, reqType string) error {
+	errResp := &pgproto3.ErrorResponse{
+		Severity: "ERROR",
+		Code:     "XX000",
+		Message:  "keploy: no mock found for the query",
+	}
+	switch reqType {
+	case "StartupMessage":
+		errResp.Severity = "FATAL"
+		return writeMessages(conn, errResp.Encode(nil))
+	case "Flush":
+		s.failed = true
+		return writeMessages(conn, errResp.Encode(nil))
+	}
+	return writeMessages(conn, errResp.Encode(nil), readyForQuery())
+}
+
+func readyForQuery() []byte {
+	return []byte{'Z', 0, 0, 0, 5, 'I'}
+}
+
+func writeMessages(conn net.Conn, msgs ...[]byte) error {
+	var buf []byte
+	for _, msg := range msgs {
+		buf = append(buf, msg...)
+	}
+	_, err := conn.Write(buf)
+	return err
+}
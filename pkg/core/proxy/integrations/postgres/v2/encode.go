@@ -0,0 +1,199 @@
+//go:build linux
+
+package v2
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// exchange holds the messages sent by the client until the server is ready for the next query and the
+// messages sent by the server in return, which are saved together as a single mock.
+type exchange struct {
+	mu               sync.Mutex
+	requests         []models.PostgresV2Request
+	responses        []models.PostgresV2Response
+	expected         int // number of ReadyForQuery messages expected from the server
+	ready            int
+	reqTimestampMock time.Time
+	resTimestampMock time.Time
+}
+
+func encodePostgres(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+	ex := &exchange{}
+	state := newConnState()
+	errCh := make(chan error, 2)
+
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+
+	clientConn, destConn, reader, err := negotiateRecord(ctx, logger, clientConn, destConn)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	// Read and process the messages from the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		startup := true
+		for {
+			msg, err := readMessage(reader, !startup)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the postgres message from the client")
+				}
+				ex.mu.Lock()
+				ex.flush(mocks, connID)
+				ex.mu.Unlock()
+				errCh <- err
+				return nil
+			}
+
+			req, err := decodeRequest(msg)
+			if err != nil {
+				logger.Debug("failed to decode the postgres message of the client, the mock will be matched on the raw bytes", zap.Error(err))
+				req = models.PostgresV2Request{Type: "Unknown", Raw: base64.StdEncoding.EncodeToString(msg.raw)}
+			}
+			state.resolve(&req)
+
+			switch req.Type {
+			case "StartupMessage":
+				startup = false
+			case "CancelRequest":
+				startup = false
+			}
+
+			if req.Type != "CancelRequest" {
+				ex.mu.Lock()
+				// a new message marks the end of the previous exchange, once the server is ready for the next query.
+				// The notifications sent by the server in between are kept with the previous exchange.
+				if ex.complete() {
+					ex.flush(mocks, connID)
+				}
+				ex.addRequest(req)
+				ex.mu.Unlock()
+			}
+
+			_, err = destConn.Write(msg.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the postgres message to the destination server")
+				errCh <- err
+				return nil
+			}
+		}
+	})
+
+	// Read and process the messages from the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		reader := bufio.NewReader(destConn)
+		for {
+			msg, err := readMessage(reader, true)
+			if err != nil {
+				if err == io.EOF {
+					logger.Debug("the destination server closed the postgres connection")
+				} else {
+					utils.LogError(logger, err, "failed to read the postgres message from the destination server")
+				}
+				ex.mu.Lock()
+				ex.flush(mocks, connID)
+				ex.mu.Unlock()
+				errCh <- err
+				return nil
+			}
+
+			_, err = clientConn.Write(msg.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the postgres message to the client")
+				errCh <- err
+				return nil
+			}
+
+			resp, err := decodeResponse(msg)
+			if err != nil {
+				logger.Debug("failed to decode the postgres message of the server, it will be replayed as it is", zap.Error(err))
+				resp = models.PostgresV2Response{Type: "Raw", Raw: base64.StdEncoding.EncodeToString(msg.raw)}
+			}
+			ex.mu.Lock()
+			ex.addResponse(resp)
+			ex.mu.Unlock()
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func (ex *exchange) addRequest(req models.PostgresV2Request) {
+	if len(ex.requests) == 0 {
+		ex.reqTimestampMock = time.Now()
+	}
+	ex.requests = append(ex.requests, req)
+	switch req.Type {
+	case "StartupMessage", "Query", "Sync", "FunctionCall":
+		ex.expected++
+	}
+}
+
+func (ex *exchange) addResponse(resp models.PostgresV2Response) {
+	ex.resTimestampMock = time.Now()
+	resp.AfterRequest = len(ex.requests)
+	ex.responses = append(ex.responses, resp)
+	if resp.Type == "ReadyForQuery" {
+		ex.ready++
+	}
+}
+
+// complete reports whether the server is ready for the next query after all the requests of the exchange.
+func (ex *exchange) complete() bool {
+	return len(ex.requests) > 0 && ex.expected > 0 && ex.ready >= ex.expected
+}
+
+// flush saves the exchange as a mock and resets it for the next messages.
+func (ex *exchange) flush(mocks chan<- *models.Mock, connID string) {
+	if len(ex.requests) > 0 && len(ex.responses) > 0 {
+		mocks <- &models.Mock{
+			Version: models.GetVersion(),
+			Name:    "mocks",
+			Kind:    models.PostgresV2,
+			Spec: models.MockSpec{
+				PostgresV2Requests:  ex.requests,
+				PostgresV2Responses: ex.responses,
+				ReqTimestampMock:    ex.reqTimestampMock,
+				ResTimestampMock:    ex.resTimestampMock,
+				Metadata:            map[string]string{"type": "config"},
+			},
+			ConnectionID: connID,
+		}
+	}
+	ex.requests = nil
+	ex.responses = nil
+	ex.expected = 0
+	ex.ready = 0
+}
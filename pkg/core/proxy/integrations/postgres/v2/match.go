@@ -0,0 +1,350 @@
+//go:build linux
+
+package v2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// session tracks the mock being replayed on a connection. An exchange is answered in several steps
+// when the client waits for the server in between, e.g. on a Flush or during a COPY.
+type session struct {
+	state   *connState
+	pending []models.PostgresV2Request
+	mock    *models.Mock
+	reqPos  int
+	respPos int
+	// failed is set when no mock was found in the middle of an extended query, the messages of the
+	// client are then discarded until the next Sync as done by the server after an error.
+	failed bool
+}
+
+func newSession() *session {
+	return &session{state: newConnState()}
+}
+
+// match finds the responses for the pending requests of the session. The mock being replayed is
+// continued when it expects the requests, otherwise the best matching mock is looked up.
+func (s *session) match(ctx context.Context, mockDb integrations.MockMemDb) ([]models.PostgresV2Response, bool, error) {
+	defer func() {
+		s.pending = nil
+	}()
+
+	if s.mock != nil {
+		if next, _, ok := matchRequests(s.mock.Spec.PostgresV2Requests, s.reqPos, s.pending); ok {
+			s.reqPos = next
+			return s.responses(), true, nil
+		}
+		s.mock = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		default:
+		}
+
+		filteredMocks, unfilteredMocks, err := getPostgresMocks(mockDb, statementKey(s.pending))
+		if err != nil {
+			return nil, false, err
+		}
+
+		maxScore := requestsScore(s.pending)
+		mock, next := findMatch(filteredMocks, s.pending, maxScore, true)
+		if mock == nil {
+			mock, next = findMatch(unfilteredMocks, s.pending, maxScore, true)
+		}
+		if mock == nil {
+			// the statements have to be the same, only the bound values are allowed to differ
+			mock, next = findMatch(append(filteredMocks, unfilteredMocks...), s.pending, maxScore, false)
+		}
+		if mock == nil {
+			return nil, false, nil
+		}
+
+		// a filtered mock is moved behind the others, so that the next identical query gets the next recorded rows
+		if !mockDb.ConsumeMock(mock) {
+			continue
+		}
+
+		s.mock = mock
+		s.reqPos = next
+		s.respPos = 0
+		return s.responses(), true, nil
+	}
+}
+
+// responses returns the responses of the mock sent by the server after the requests received so far.
+// The authentication is skipped, the client is authenticated right away.
+func (s *session) responses() []models.PostgresV2Response {
+	reqs := s.mock.Spec.PostgresV2Requests
+	resps := s.mock.Spec.PostgresV2Responses
+	for s.reqPos < len(reqs) && reqs[s.reqPos].Type == "PasswordMessage" {
+		s.reqPos++
+	}
+
+	var out []models.PostgresV2Response
+	for ; s.respPos < len(resps) && resps[s.respPos].AfterRequest <= s.reqPos; s.respPos++ {
+		if resps[s.respPos].Type == "Authentication" {
+			continue
+		}
+		out = append(out, resps[s.respPos])
+	}
+	if s.reqPos >= len(reqs) {
+		// the notifications received after the exchange are sent along with the last responses
+		for ; s.respPos < len(resps); s.respPos++ {
+			out = append(out, resps[s.respPos])
+		}
+		s.mock = nil
+	}
+	return out
+}
+
+// getPostgresMocks returns the filtered and the unfiltered postgres mocks. The statements of the mocks
+// have to be the same as the requests, hence only the mocks having the key of the first statement are
+// looked up when the requests hold a statement.
+func getPostgresMocks(mockDb integrations.MockMemDb, key string) ([]*models.Mock, []*models.Mock, error) {
+	var mocks []*models.Mock
+	var err error
+	if key != "" {
+		mocks, err = mockDb.GetUnFilteredMocksByKey(models.PostgresV2, key)
+	} else {
+		mocks, err = mockDb.GetUnFilteredMocksByKind(models.PostgresV2)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var filteredMocks []*models.Mock
+	var unfilteredMocks []*models.Mock
+
+	for _, mock := range mocks {
+		if mock.TestModeInfo.IsFiltered {
+			filteredMocks = append(filteredMocks, mock)
+		} else {
+			unfilteredMocks = append(unfilteredMocks, mock)
+		}
+	}
+	return filteredMocks, unfilteredMocks, nil
+}
+
+// mockKey returns the key of the first statement of the mock, on which the postgres mocks are indexed.
+func mockKey(mock *models.Mock) string {
+	return statementKey(mock.Spec.PostgresV2Requests)
+}
+
+// statementKey returns the hash of the first statement of the requests, it is empty if there is none.
+func statementKey(reqs []models.PostgresV2Request) string {
+	for _, req := range reqs {
+		switch req.Type {
+		case "Query", "Parse", "Describe", "Close", "Bind", "Execute":
+			if req.Query != "" {
+				sum := sha256.Sum256([]byte(normalizeQuery(req.Query)))
+				return hex.EncodeToString(sum[:8])
+			}
+		}
+	}
+	return ""
+}
+
+// summarizeRequests returns the type of the requests, with their query and bound values if any,
+// for the report of the unmatched calls.
+func summarizeRequests(reqs []models.PostgresV2Request) string {
+	parts := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		part := req.Type
+		if req.Query != "" {
+			part += " " + req.Query
+		}
+		if len(req.Parameters) > 0 {
+			params := make([]string, 0, len(req.Parameters))
+			for _, p := range req.Parameters {
+				if p == nil {
+					params = append(params, "NULL")
+					continue
+				}
+				params = append(params, *p)
+			}
+			part += " [" + strings.Join(params, ", ") + "]"
+		}
+		parts = append(parts, part)
+	}
+	return pUtil.Summarize([][]byte{[]byte(strings.Join(parts, "; "))})
+}
+
+// findMatch returns the mock whose requests start with the given requests and the position of the
+// next request of the mock. With exact set, the bound values and the copied data have to be the same,
+// otherwise the mock matching the most values is returned.
+func findMatch(mocks []*models.Mock, reqs []models.PostgresV2Request, maxScore int, exact bool) (*models.Mock, int) {
+	var (
+		best      *models.Mock
+		bestNext  int
+		bestScore = -1
+	)
+	for _, mock := range mocks {
+		next, score, ok := matchRequests(mock.Spec.PostgresV2Requests, 0, reqs)
+		if !ok {
+			continue
+		}
+		if exact {
+			if score == maxScore {
+				return mock, next
+			}
+			continue
+		}
+		if score > bestScore {
+			best, bestNext, bestScore = mock, next, score
+		}
+	}
+	return best, bestNext
+}
+
+// matchRequests matches the requests of the client with the recorded requests from the given position.
+// It returns the position of the next recorded request and the score of the match. The data of a COPY
+// may be split differently, hence the number of CopyData messages is not compared.
+func matchRequests(recorded []models.PostgresV2Request, from int, reqs []models.PostgresV2Request) (int, int, bool) {
+	i, total := from, 0
+	for _, req := range reqs {
+		if req.Type == "CopyData" {
+			if i < len(recorded) && recorded[i].Type == "CopyData" {
+				if recorded[i].Data == req.Data {
+					total++
+				}
+				i++
+			}
+			continue
+		}
+		for i < len(recorded) && (recorded[i].Type == "CopyData" || recorded[i].Type == "PasswordMessage") {
+			i++
+		}
+		if i >= len(recorded) {
+			return 0, 0, false
+		}
+		score, ok := requestScore(recorded[i], req)
+		if !ok {
+			return 0, 0, false
+		}
+		total += score
+		i++
+	}
+	return i, total, true
+}
+
+// requestsScore returns the score of the requests matched with themselves.
+func requestsScore(reqs []models.PostgresV2Request) int {
+	_, score, _ := matchRequests(reqs, 0, reqs)
+	return score
+}
+
+// requestScore compares a recorded request with the request of the client. The type and the statement have
+// to be the same, the score counts the bound values which are the same.
+func requestScore(recorded, req models.PostgresV2Request) (int, bool) {
+	if recorded.Type != req.Type {
+		return 0, false
+	}
+	switch req.Type {
+	case "StartupMessage":
+		return 0, recorded.StartupParams["user"] == req.StartupParams["user"] && recorded.StartupParams["database"] == req.StartupParams["database"]
+	case "Query", "Parse":
+		return 0, normalizeQuery(recorded.Query) == normalizeQuery(req.Query)
+	case "Describe", "Close":
+		return 0, recorded.ObjectType == req.ObjectType && normalizeQuery(recorded.Query) == normalizeQuery(req.Query)
+	case "Bind", "Execute":
+		if normalizeQuery(recorded.Query) != normalizeQuery(req.Query) || len(recorded.Parameters) != len(req.Parameters) {
+			return 0, false
+		}
+		score := 1
+		for i := range req.Parameters {
+			if valueEqual(recorded.Parameters[i], req.Parameters[i]) {
+				score++
+			}
+		}
+		if req.Type == "Execute" && recorded.MaxRows != req.MaxRows {
+			return 0, false
+		}
+		return score, true
+	case "CopyFail":
+		return 0, true
+	case "FunctionCall", "Unknown":
+		return 0, recorded.Raw == req.Raw
+	}
+	return 0, true
+}
+
+func valueEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// normalizeQuery removes the comments, the redundant whitespace and the trailing semicolon of a query
+// and lowercases it, leaving the quoted literals and identifiers untouched.
+func normalizeQuery(query string) string {
+	var sb strings.Builder
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(query) {
+				if query[end] == c {
+					// a doubled quote is an escaped quote
+					if end+1 < len(query) && query[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(query) {
+				end = len(query) - 1
+			}
+			if space && sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			space = false
+			sb.WriteString(query[i : end+1])
+			i = end
+			continue
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+			space = true
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		}
+		if space && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		space = false
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		sb.WriteByte(c)
+	}
+	return strings.TrimRight(sb.String(), "; ")
+}
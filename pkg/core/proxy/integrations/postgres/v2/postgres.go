@@ -0,0 +1,60 @@
+//go:build linux
+
+// Package v2 provides the postgres integration decoding the messages of the extended query protocol
+// into readable mocks, which are matched on the normalized sql and the bound parameters.
+package v2
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register(string(integrations.POSTGRES_V2), NewPostgresV2)
+	integrations.RegisterMockKey(models.PostgresV2, mockKey)
+}
+
+type PostgresV2 struct {
+	logger *zap.Logger
+}
+
+func NewPostgresV2(logger *zap.Logger) integrations.Integrations {
+	return &PostgresV2{
+		logger: logger,
+	}
+}
+
+// MatchType returns false as the v2 parser is opt-in. The startup message is matched by the postgres v1
+// parser, so that the existing mocks are still served, and both parsers would otherwise handle the same
+// connection. The v2 parser is used for the ports configured with the postgres_v2 protocol in the protocol rules.
+func (p *PostgresV2) MatchType(_ context.Context, _ []byte) bool {
+	return false
+}
+
+func (p *PostgresV2) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := p.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := encodePostgres(ctx, logger, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the postgres message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresV2) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := p.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	err := decodePostgres(ctx, logger, src, dstCfg, mockDb, opts)
+	if err != nil && err != io.EOF {
+		utils.LogError(logger, err, "failed to decode the postgres message from the yaml")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+//go:build linux
+
+package v2
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// encryptionRequestLen is the length of the SSLRequest and the GSSEncRequest messages.
+const encryptionRequestLen = 8
+
+// bufferedConn reads the connection through the reader holding the bytes already read from it.
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// nextEncryptionRequest consumes the next message of the client if it is an SSLRequest or a GSSEncRequest
+// and returns its code, otherwise the message is left in the reader and 0 is returned.
+func nextEncryptionRequest(reader *bufio.Reader) (uint32, error) {
+	hdr, err := reader.Peek(encryptionRequestLen)
+	if err != nil {
+		return 0, err
+	}
+	length := binary.BigEndian.Uint32(hdr[:4])
+	code := binary.BigEndian.Uint32(hdr[4:])
+	if length != encryptionRequestLen || (code != sslRequestCode && code != gssEncReqCode) {
+		return 0, nil
+	}
+	_, err = reader.Discard(encryptionRequestLen)
+	return code, err
+}
+
+// negotiateRecord handles the encryption requests of the client before its startup message. The SSLRequest is
+// passed to the server, and once the server accepts it the proxy terminates the TLS of both the client and the
+// server, so that the messages can still be decoded. The GSS encryption is declined, the client then asks for
+// TLS or continues in the clear. It returns the connections and the reader of the client to continue with.
+func negotiateRecord(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn) (net.Conn, net.Conn, *bufio.Reader, error) {
+	reader := bufio.NewReader(clientConn)
+	for {
+		code, err := nextEncryptionRequest(reader)
+		if err != nil || code == 0 {
+			return clientConn, destConn, reader, err
+		}
+
+		if code == gssEncReqCode {
+			_, err = clientConn.Write([]byte{'N'})
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the response of the gss encryption request to the client")
+				return nil, nil, nil, err
+			}
+			continue
+		}
+
+		req := make([]byte, encryptionRequestLen)
+		binary.BigEndian.PutUint32(req, encryptionRequestLen)
+		binary.BigEndian.PutUint32(req[4:], sslRequestCode)
+		_, err = destConn.Write(req)
+		if err != nil {
+			utils.LogError(logger, err, "failed to write the ssl request to the destination server")
+			return nil, nil, nil, err
+		}
+		reply := make([]byte, 1)
+		_, err = io.ReadFull(destConn, reply)
+		if err != nil {
+			utils.LogError(logger, err, "failed to read the response of the ssl request from the destination server")
+			return nil, nil, nil, err
+		}
+		_, err = clientConn.Write(reply)
+		if err != nil {
+			utils.LogError(logger, err, "failed to write the response of the ssl request to the client")
+			return nil, nil, nil, err
+		}
+		if reply[0] != 'S' {
+			// the server declined the encryption, the client decides whether to continue in the clear
+			continue
+		}
+
+		host, _, err := net.SplitHostPort(destConn.RemoteAddr().String())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		serverConn := tls.Client(destConn, &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         host,
+		})
+		err = serverConn.HandshakeContext(ctx)
+		if err != nil {
+			utils.LogError(logger, err, "failed to complete the tls handshake with the postgres server")
+			return nil, nil, nil, err
+		}
+
+		tlsConn, err := serveTLS(ctx, logger, clientConn, reader)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return tlsConn, serverConn, bufio.NewReader(tlsConn), nil
+	}
+}
+
+// negotiateMock accepts the SSLRequest of the client in place of the server and terminates its TLS, the GSS
+// encryption is declined. It returns the connection and the reader of the client to continue with.
+func negotiateMock(ctx context.Context, logger *zap.Logger, clientConn net.Conn) (net.Conn, *bufio.Reader, error) {
+	reader := bufio.NewReader(clientConn)
+	for {
+		code, err := nextEncryptionRequest(reader)
+		if err != nil || code == 0 {
+			return clientConn, reader, err
+		}
+
+		if code == gssEncReqCode {
+			_, err = clientConn.Write([]byte{'N'})
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		_, err = clientConn.Write([]byte{'S'})
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsConn, err := serveTLS(ctx, logger, clientConn, reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tlsConn, bufio.NewReader(tlsConn), nil
+	}
+}
+
+// serveTLS completes the tls handshake with the client using the certificates of the proxy.
+func serveTLS(ctx context.Context, logger *zap.Logger, clientConn net.Conn, reader *bufio.Reader) (net.Conn, error) {
+	cfg, ok := ctx.Value(models.TLSConfigKey).(*tls.Config)
+	if !ok {
+		return nil, errors.New("failed to get the tls config from the context")
+	}
+	tlsConn := tls.Server(&bufferedConn{Conn: clientConn, r: reader}, cfg)
+	err := tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		utils.LogError(logger, err, "failed to complete the tls handshake with the postgres client")
+		return nil, err
+	}
+	return tlsConn, nil
+}
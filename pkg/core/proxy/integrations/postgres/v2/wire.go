@@ -0,0 +1,501 @@
+//go:build linux
+
+package v2
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// codes of the untyped messages sent by the client at the start of a connection
+const (
+	protocolVersion = 196608
+	sslRequestCode  = 80877103
+	gssEncReqCode   = 80877104
+	cancelReqCode   = 80877102
+	maxMessageSize  = 1 << 30
+)
+
+const b64Prefix = "b64:"
+
+var errMalformed = errors.New("malformed postgres message")
+
+// message is a message of the postgres wire protocol. The untyped messages sent by the client at the
+// start of a connection have a zero type.
+type message struct {
+	typ  byte
+	raw  []byte // the complete message, including the type and the length
+	body []byte
+}
+
+// readMessage reads the next message from the reader, typed is false for the startup messages.
+func readMessage(r *bufio.Reader, typed bool) (*message, error) {
+	hdrLen := 5
+	if !typed {
+		hdrLen = 4
+	}
+	hdr := make([]byte, hdrLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint32(hdr[hdrLen-4:]))
+	if length < 4 || length > maxMessageSize {
+		return nil, fmt.Errorf("%w: invalid length %d", errMalformed, length)
+	}
+	raw := make([]byte, hdrLen+length-4)
+	copy(raw, hdr)
+	if _, err := io.ReadFull(r, raw[hdrLen:]); err != nil {
+		return nil, err
+	}
+	msg := &message{raw: raw, body: raw[hdrLen:]}
+	if typed {
+		msg.typ = hdr[0]
+	}
+	return msg, nil
+}
+
+// startupCode returns the protocol version or the request code of an untyped message.
+func (m *message) startupCode() uint32 {
+	if m.typ != 0 || len(m.body) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(m.body)
+}
+
+type bodyReader struct {
+	buf []byte
+	err error
+}
+
+func (b *bodyReader) next(n int) []byte {
+	if b.err != nil || n < 0 || len(b.buf) < n {
+		b.err = errMalformed
+		return nil
+	}
+	v := b.buf[:n]
+	b.buf = b.buf[n:]
+	return v
+}
+
+func (b *bodyReader) byte() byte {
+	v := b.next(1)
+	if v == nil {
+		return 0
+	}
+	return v[0]
+}
+
+func (b *bodyReader) int16() int16 {
+	v := b.next(2)
+	if v == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(v))
+}
+
+// count reads the count of the items that follow, a negative count or one larger than the rest of the
+// body is malformed.
+func (b *bodyReader) count() int {
+	n := int(b.int16())
+	if n < 0 || n > len(b.buf) {
+		b.err = errMalformed
+		return 0
+	}
+	return n
+}
+
+func (b *bodyReader) int32() int32 {
+	v := b.next(4)
+	if v == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(v))
+}
+
+func (b *bodyReader) cstring() string {
+	if b.err != nil {
+		return ""
+	}
+	for i, c := range b.buf {
+		if c == 0 {
+			s := string(b.buf[:i])
+			b.buf = b.buf[i+1:]
+			return s
+		}
+	}
+	b.err = errMalformed
+	return ""
+}
+
+// value reads a length prefixed value, a length of -1 is a null value.
+func (b *bodyReader) value() []byte {
+	n := b.int32()
+	if n == -1 || b.err != nil {
+		return nil
+	}
+	v := b.next(int(n))
+	if v == nil {
+		return nil
+	}
+	return append([]byte{}, v...)
+}
+
+// decodeRequest decodes a message of the client. The statement and the portal of the message are
+// resolved by the caller from the state of the connection.
+func decodeRequest(msg *message) (models.PostgresV2Request, error) {
+	b := &bodyReader{buf: msg.body}
+	var req models.PostgresV2Request
+
+	if msg.typ == 0 {
+		switch code := msg.startupCode(); code {
+		case protocolVersion:
+			req.Type = "StartupMessage"
+			b.int32()
+			req.StartupParams = map[string]string{}
+			for len(b.buf) > 1 && b.err == nil {
+				key := b.cstring()
+				req.StartupParams[key] = b.cstring()
+			}
+		case sslRequestCode:
+			req.Type = "SSLRequest"
+		case gssEncReqCode:
+			req.Type = "GSSEncRequest"
+		case cancelReqCode:
+			req.Type = "CancelRequest"
+		default:
+			return req, fmt.Errorf("%w: unknown startup code %d", errMalformed, code)
+		}
+		return req, b.err
+	}
+
+	switch msg.typ {
+	case 'Q':
+		req.Type = "Query"
+		req.Query = b.cstring()
+	case 'P':
+		req.Type = "Parse"
+		req.Statement = b.cstring()
+		req.Query = b.cstring()
+		n := b.count()
+		for i := 0; i < n && b.err == nil; i++ {
+			req.ParameterOIDs = append(req.ParameterOIDs, uint32(b.int32()))
+		}
+	case 'B':
+		req.Type = "Bind"
+		req.Portal = b.cstring()
+		req.Statement = b.cstring()
+		n := b.count()
+		for i := 0; i < n && b.err == nil; i++ {
+			req.ParameterFormats = append(req.ParameterFormats, b.int16())
+		}
+		n = b.count()
+		for i := 0; i < n && b.err == nil; i++ {
+			req.Parameters = append(req.Parameters, encodeValue(b.value()))
+		}
+		n = b.count()
+		for i := 0; i < n && b.err == nil; i++ {
+			req.ResultFormats = append(req.ResultFormats, b.int16())
+		}
+	case 'D', 'C':
+		req.Type = "Describe"
+		if msg.typ == 'C' {
+			req.Type = "Close"
+		}
+		req.ObjectType = string(b.byte())
+		if req.ObjectType == "P" {
+			req.Portal = b.cstring()
+		} else {
+			req.Statement = b.cstring()
+		}
+	case 'E':
+		req.Type = "Execute"
+		req.Portal = b.cstring()
+		req.MaxRows = uint32(b.int32())
+	case 'S':
+		req.Type = "Sync"
+	case 'H':
+		req.Type = "Flush"
+	case 'X':
+		req.Type = "Terminate"
+	case 'd':
+		req.Type = "CopyData"
+		req.Data = *encodeValue(msg.body)
+	case 'c':
+		req.Type = "CopyDone"
+	case 'f':
+		req.Type = "CopyFail"
+		req.Data = b.cstring()
+	case 'F':
+		req.Type = "FunctionCall"
+		req.Raw = base64.StdEncoding.EncodeToString(msg.raw)
+	case 'p':
+		// password, SASL and GSS responses share the type, the content is not needed for the replay
+		req.Type = "PasswordMessage"
+		req.Raw = base64.StdEncoding.EncodeToString(msg.raw)
+	default:
+		return req, fmt.Errorf("%w: unknown message type %q", errMalformed, msg.typ)
+	}
+	return req, b.err
+}
+
+// isSyncPoint reports whether the client waits for the response of the server after the message.
+func isSyncPoint(typ string) bool {
+	switch typ {
+	case "StartupMessage", "SSLRequest", "GSSEncRequest", "Query", "Sync", "Flush", "CopyDone", "CopyFail", "FunctionCall", "PasswordMessage":
+		return true
+	}
+	return false
+}
+
+// decodeResponse decodes a message of the server.
+func decodeResponse(msg *message) (models.PostgresV2Response, error) {
+	b := &bodyReader{buf: msg.body}
+	var resp models.PostgresV2Response
+
+	switch msg.typ {
+	case 'R':
+		if len(msg.body) >= 4 && binary.BigEndian.Uint32(msg.body) == 0 {
+			resp.Type = "AuthenticationOk"
+			return resp, nil
+		}
+		resp.Type = "Authentication"
+		resp.Raw = base64.StdEncoding.EncodeToString(msg.raw)
+	case 'S':
+		resp.Type = "ParameterStatus"
+		resp.Name = b.cstring()
+		resp.Value = b.cstring()
+	case 'K':
+		resp.Type = "BackendKeyData"
+		resp.ProcessID = uint32(b.int32())
+		resp.SecretKey = uint32(b.int32())
+	case 'Z':
+		resp.Type = "ReadyForQuery"
+		resp.TxStatus = string(b.byte())
+	case 'T':
+		resp.Type = "RowDescription"
+		n := b.count()
+		for i := 0; i < n && b.err == nil; i++ {
+			resp.Fields = append(resp.Fields, models.PostgresV2Field{
+				Name:                 b.cstring(),
+				TableOID:             uint32(b.int32()),
+				TableAttributeNumber: uint16(b.int16()),
+				DataTypeOID:          uint32(b.int32()),
+				DataTypeSize:         b.int16(),
+				TypeModifier:         b.int32(),
+				Format:               b.int16(),
+			})
+		}
+	case 'D':
+		resp.Type = "DataRow"
+		n := b.count()
+		resp.Values = make([]*string, 0, n)
+		for i := 0; i < n && b.err == nil; i++ {
+			resp.Values = append(resp.Values, encodeValue(b.value()))
+		}
+	case 'C':
+		resp.Type = "CommandComplete"
+		resp.Tag = b.cstring()
+	case 'E', 'N':
+		resp.Type = "ErrorResponse"
+		if msg.typ == 'N' {
+			resp.Type = "NoticeResponse"
+		}
+		errResp := &pgproto3.ErrorResponse{}
+		if err := errResp.Decode(msg.body); err != nil {
+			return resp, err
+		}
+		resp.Error = errResp
+	case 'A':
+		resp.Type = "NotificationResponse"
+		resp.ProcessID = uint32(b.int32())
+		resp.Channel = b.cstring()
+		resp.Payload = b.cstring()
+	case 't':
+		resp.Type = "ParameterDescription"
+		n := b.count()
+		for i := 0; i < n && b.err == nil; i++ {
+			resp.ParameterOIDs = append(resp.ParameterOIDs, uint32(b.int32()))
+		}
+	case 'G', 'H', 'W':
+		resp.Type = map[byte]string{'G': "CopyInResponse", 'H': "CopyOutResponse", 'W': "CopyBothResponse"}[msg.typ]
+		resp.OverallFormat = b.byte()
+		n := b.count()
+		for i := 0; i < n && b.err == nil; i++ {
+			resp.ColumnFormatCodes = append(resp.ColumnFormatCodes, uint16(b.int16()))
+		}
+	case 'd':
+		resp.Type = "CopyData"
+		resp.Data = *encodeValue(msg.body)
+	default:
+		if name, ok := emptyResponses[msg.typ]; ok && len(msg.body) == 0 {
+			resp.Type = name
+			return resp, nil
+		}
+		resp.Type = "Raw"
+		resp.Raw = base64.StdEncoding.EncodeToString(msg.raw)
+	}
+	return resp, b.err
+}
+
+// emptyResponses are the messages of the server without any content
+var emptyResponses = map[byte]string{
+	'1': "ParseComplete",
+	'2': "BindComplete",
+	'3': "CloseComplete",
+	'n': "NoData",
+	'I': "EmptyQueryResponse",
+	's': "PortalSuspended",
+	'c': "CopyDone",
+}
+
+// encodeResponse encodes a recorded message of the server into the wire format.
+func encodeResponse(resp models.PostgresV2Response) ([]byte, error) {
+	var body []byte
+	var typ byte
+
+	switch resp.Type {
+	case "Authentication", "Raw":
+		return base64.StdEncoding.DecodeString(resp.Raw)
+	case "AuthenticationOk":
+		typ, body = 'R', binary.BigEndian.AppendUint32(nil, 0)
+	case "ParameterStatus":
+		typ = 'S'
+		body = appendCString(appendCString(body, resp.Name), resp.Value)
+	case "BackendKeyData":
+		typ = 'K'
+		body = binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(body, resp.ProcessID), resp.SecretKey)
+	case "ReadyForQuery":
+		typ = 'Z'
+		body = []byte(resp.TxStatus)
+		if len(body) != 1 {
+			body = []byte{'I'}
+		}
+	case "RowDescription":
+		typ = 'T'
+		body = binary.BigEndian.AppendUint16(body, uint16(len(resp.Fields)))
+		for _, f := range resp.Fields {
+			body = appendCString(body, f.Name)
+			body = binary.BigEndian.AppendUint32(body, f.TableOID)
+			body = binary.BigEndian.AppendUint16(body, f.TableAttributeNumber)
+			body = binary.BigEndian.AppendUint32(body, f.DataTypeOID)
+			body = binary.BigEndian.AppendUint16(body, uint16(f.DataTypeSize))
+			body = binary.BigEndian.AppendUint32(body, uint32(f.TypeModifier))
+			body = binary.BigEndian.AppendUint16(body, uint16(f.Format))
+		}
+	case "DataRow":
+		typ = 'D'
+		body = binary.BigEndian.AppendUint16(body, uint16(len(resp.Values)))
+		for _, v := range resp.Values {
+			value, err := decodeValue(v)
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				body = binary.BigEndian.AppendUint32(body, 0xffffffff)
+				continue
+			}
+			body = binary.BigEndian.AppendUint32(body, uint32(len(value)))
+			body = append(body, value...)
+		}
+	case "CommandComplete":
+		typ = 'C'
+		body = appendCString(body, resp.Tag)
+	case "ErrorResponse", "NoticeResponse":
+		if resp.Error == nil {
+			return nil, fmt.Errorf("%w: %s without the error fields", errMalformed, resp.Type)
+		}
+		if resp.Type == "NoticeResponse" {
+			notice := pgproto3.NoticeResponse(*resp.Error)
+			return notice.Encode(nil), nil
+		}
+		return resp.Error.Encode(nil), nil
+	case "NotificationResponse":
+		typ = 'A'
+		body = binary.BigEndian.AppendUint32(body, resp.ProcessID)
+		body = appendCString(appendCString(body, resp.Channel), resp.Payload)
+	case "ParameterDescription":
+		typ = 't'
+		body = binary.BigEndian.AppendUint16(body, uint16(len(resp.ParameterOIDs)))
+		for _, oid := range resp.ParameterOIDs {
+			body = binary.BigEndian.AppendUint32(body, oid)
+		}
+	case "CopyInResponse", "CopyOutResponse", "CopyBothResponse":
+		typ = map[string]byte{"CopyInResponse": 'G', "CopyOutResponse": 'H', "CopyBothResponse": 'W'}[resp.Type]
+		body = append(body, resp.OverallFormat)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(resp.ColumnFormatCodes)))
+		for _, code := range resp.ColumnFormatCodes {
+			body = binary.BigEndian.AppendUint16(body, code)
+		}
+	case "CopyData":
+		typ = 'd'
+		data, err := decodeValue(&resp.Data)
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	default:
+		found := false
+		for t, name := range emptyResponses {
+			if name == resp.Type {
+				typ, found = t, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unknown response type %s", errMalformed, resp.Type)
+		}
+	}
+
+	buf := make([]byte, 0, 5+len(body))
+	buf = append(buf, typ)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(body)+4))
+	return append(buf, body...), nil
+}
+
+func appendCString(buf []byte, s string) []byte {
+	return append(append(buf, s...), 0)
+}
+
+// encodeValue returns the readable form of a value, the binary values are base64 encoded with a prefix.
+func encodeValue(v []byte) *string {
+	if v == nil {
+		return nil
+	}
+	s := string(v)
+	if !isPrintable(s) || strings.HasPrefix(s, b64Prefix) {
+		s = b64Prefix + base64.StdEncoding.EncodeToString(v)
+	}
+	return &s
+}
+
+func decodeValue(v *string) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if strings.HasPrefix(*v, b64Prefix) {
+		return base64.StdEncoding.DecodeString(strings.TrimPrefix(*v, b64Prefix))
+	}
+	return []byte(*v), nil
+}
+
+func isPrintable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
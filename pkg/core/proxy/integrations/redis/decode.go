@@ -4,9 +4,11 @@
 package redis
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"net"
+	"strings"
 	"time"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
@@ -17,11 +19,18 @@ import (
 	"go.uber.org/zap"
 )
 
-func decodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+func decodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
 	redisRequests := [][]byte{reqBuf}
 	logger.Debug("Into the redis parser in test mode")
 	errCh := make(chan error, 1)
 
+	noise := make(map[string][]string, len(opts.RedisNoise))
+	for cmd, args := range opts.RedisNoise {
+		noise[strings.ToUpper(cmd)] = args
+	}
+	// session of the mock being replayed on this connection
+	sess := &session{}
+
 	go func(errCh chan error, redisRequests [][]byte) {
 		defer pUtil.Recover(logger, clientConn, nil)
 		defer close(errCh)
@@ -29,7 +38,7 @@ func decodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 
 			// Read the stream of request packets from the client
 			for {
-				if len(redisRequests) > 0 {
+				if len(redisRequests) > 0 && !isPartial(redisRequests) {
 					break
 				}
 				err := clientConn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
@@ -43,7 +52,14 @@ func decodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 					return
 				}
 				if netErr, ok := err.(net.Error); (ok && netErr.Timeout()) || (err != nil && err.Error() == "EOF") {
+					if netErr, ok := err.(net.Error); ok && netErr.Timeout() && len(buffer) == 0 && isPartial(redisRequests) {
+						// the rest of the command is yet to be sent by the client
+						continue
+					}
 					logger.Debug("timeout for client read in redis or EOF")
+					if len(buffer) > 0 {
+						redisRequests = append(redisRequests, buffer)
+					}
 					break
 				}
 				if len(buffer) > 0 {
@@ -58,18 +74,31 @@ func decodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 			}
 
 			// Fuzzy match to get the best matched redis mock
-			matched, redisResponses, err := fuzzyMatch(ctx, redisRequests, mockDb)
+			matched, redisResponses, err := fuzzyMatch(ctx, redisRequests, mockDb, sess, noise)
 			if err != nil {
 				utils.LogError(logger, err, "error while matching redis mocks")
 			}
 
 			if !matched {
+				mockDb.RecordUnmatched(models.UnmatchedCall{
+					Kind:        models.REDIS,
+					Destination: dstCfg.Addr,
+					Summary:     summarizeCommands(redisRequests),
+				})
 				err := clientConn.SetReadDeadline(time.Time{})
 				if err != nil {
 					utils.LogError(logger, err, "failed to set the read deadline for the client conn")
 					return
 				}
 
+				if opts.RecordOnMiss {
+					err = pUtil.RecordOnMiss(ctx, logger, clientConn, dstCfg, redisRequests, mockDb, opts, encodeRedis)
+					if err != nil {
+						utils.LogError(logger, err, "failed to record the redis requests on a miss")
+					}
+					return
+				}
+
 				logger.Debug("redisRequests before pass through:", zap.Any("length", len(redisRequests)))
 				for _, redReq := range redisRequests {
 					logger.Debug("redisRequests:", zap.Any("h", string(redReq)))
@@ -124,3 +153,23 @@ func decodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 		return err
 	}
 }
+
+// isPartial checks whether the requests end in the middle of a RESP command.
+func isPartial(redisRequests [][]byte) bool {
+	_, _, err := parseCommands(bytes.Join(redisRequests, nil))
+	return err == errIncomplete
+}
+
+// summarizeCommands returns the commands of the requests as typed in redis-cli, e.g. "GET key; INCR hits".
+// The requests which can't be decoded are summarized from their raw bytes.
+func summarizeCommands(redisRequests [][]byte) string {
+	cmds, n, err := parseCommands(bytes.Join(redisRequests, nil))
+	if err != nil || n == 0 {
+		return pUtil.Summarize(redisRequests)
+	}
+	parts := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		parts = append(parts, strings.TrimSpace(cmd.Name+" "+strings.Join(cmd.Args, " ")))
+	}
+	return pUtil.Summarize([][]byte{[]byte(strings.Join(parts, "; "))})
+}
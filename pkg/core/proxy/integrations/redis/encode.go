@@ -7,6 +7,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -17,122 +18,108 @@ import (
 	"go.uber.org/zap"
 )
 
-func encodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
-
-	var redisRequests []models.Payload
-	var redisResponses []models.Payload
+// exchange holds the commands sent by the client and the replies of the server, which are
+// saved together as a single mock. A transaction (MULTI ... EXEC) and a subscription along with
+// the messages pushed by the server are kept in the same exchange.
+type exchange struct {
+	mu               sync.Mutex
+	requests         []models.Payload
+	responses        []models.Payload
+	commands         []models.RedisCommand
+	reqTail          []byte // incomplete command at the end of the last request
+	respTail         []byte // incomplete reply at the end of the last response
+	replies          int
+	expected         int
+	inTx             bool
+	subscribed       bool
+	raw              bool // set when the requests could not be decoded, the mock is then matched on the raw bytes
+	reqTimestampMock time.Time
+	resTimestampMock time.Time
+}
 
-	bufStr := string(reqBuf)
-	dataType := models.String
+func encodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	ex := &exchange{}
+	ex.addRequest(logger, reqBuf)
 
-	if bufStr != "" {
-		redisRequests = append(redisRequests, models.Payload{
-			Origin: models.FromClient,
-			Message: []models.OutputBinary{
-				{
-					Type: dataType,
-					Data: bufStr,
-				},
-			},
-		})
-	}
 	_, err := destConn.Write(reqBuf)
 	if err != nil {
 		utils.LogError(logger, err, "failed to write request message to the destination server")
 		return err
 	}
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
 
 	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
 	if !ok {
 		return errors.New("failed to get the error group from the context")
 	}
 
-	reqTimestampMock := time.Now()
-
-	// Read and process responses from the destination server
+	// Read and process the requests from the client. Requests and responses are handled
+	// independently since the server can push messages (pub/sub) without any request.
 	g.Go(func() error {
 		defer pUtil.Recover(logger, clientConn, destConn)
-		defer close(errCh)
-
 		for {
-			// Read the response from the destination server
-			resp, err := pUtil.ReadBytes(ctx, logger, destConn)
-			if err != nil {
-				if err == io.EOF {
-					logger.Debug("Response complete, exiting the loop.")
-					// if there is any buffer left before EOF, we must send it to the client and save this as mock
-					if len(resp) != 0 {
-						resTimestampMock := time.Now()
-						_, err = clientConn.Write(resp)
-						if err != nil {
-							utils.LogError(logger, err, "failed to write response message to the client")
-							errCh <- err
-							return nil
-						}
-						processBuffer(resp, models.FromServer, &redisResponses)
-						saveMock(redisRequests, redisResponses, reqTimestampMock, resTimestampMock, mocks)
-					}
-					break
+			reqBuf, err := pUtil.ReadBytes(ctx, logger, clientConn)
+			if len(reqBuf) > 0 {
+				ex.mu.Lock()
+				// a new request marks the end of the previous exchange, unless a transaction is in progress
+				if len(ex.responses) > 0 && !ex.inTx {
+					ex.flush(mocks)
 				}
-				utils.LogError(logger, err, "failed to read the response message from the destination server")
-				errCh <- err
-				return nil
-			}
-
-			// Write the response message to the client
-			_, err = clientConn.Write(resp)
-			if err != nil {
-				utils.LogError(logger, err, "failed to write response message to the client")
-				errCh <- err
-				return nil
-			}
+				ex.addRequest(logger, reqBuf)
+				ex.mu.Unlock()
 
-			resTimestampMock := time.Now()
-			processBuffer(resp, models.FromServer, &redisResponses)
-
-			// Save the mock with both request and response
-			if len(redisRequests) > 0 && len(redisResponses) > 0 {
-				saveMock(redisRequests, redisResponses, reqTimestampMock, resTimestampMock, mocks)
-				redisRequests = []models.Payload{}
-				redisResponses = []models.Payload{}
+				_, werr := destConn.Write(reqBuf)
+				if werr != nil {
+					utils.LogError(logger, werr, "failed to write request message to the destination server")
+					errCh <- werr
+					return nil
+				}
 			}
-
-			// Read the next request from the client
-			reqBuf, err = pUtil.ReadBytes(ctx, logger, clientConn)
 			if err != nil {
 				if err != io.EOF {
 					utils.LogError(logger, err, "failed to read the request message from the client")
-					errCh <- err
-					return nil
 				}
+				ex.mu.Lock()
+				ex.flush(mocks)
+				ex.mu.Unlock()
 				errCh <- err
 				return nil
 			}
+		}
+	})
 
-			bufStr := string(reqBuf)
-			dataType := models.String
-
-			if bufStr != "" {
-				redisRequests = append(redisRequests, models.Payload{
-					Origin: models.FromClient,
-					Message: []models.OutputBinary{
-						{
-							Type: dataType,
-							Data: bufStr,
-						},
-					},
-				})
+	// Read and process the responses from the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		for {
+			resp, err := pUtil.ReadBytes(ctx, logger, destConn)
+			if len(resp) > 0 {
+				_, werr := clientConn.Write(resp)
+				if werr != nil {
+					utils.LogError(logger, werr, "failed to write response message to the client")
+					errCh <- werr
+					return nil
+				}
+				ex.mu.Lock()
+				ex.addResponse(logger, resp)
+				if ex.complete() {
+					ex.flush(mocks)
+				}
+				ex.mu.Unlock()
 			}
-			_, err = destConn.Write(reqBuf)
 			if err != nil {
-				utils.LogError(logger, err, "failed to write request message to the destination server")
+				if err == io.EOF {
+					logger.Debug("Response complete, exiting the loop.")
+				} else {
+					utils.LogError(logger, err, "failed to read the response message from the destination server")
+				}
+				ex.mu.Lock()
+				ex.flush(mocks)
+				ex.mu.Unlock()
 				errCh <- err
 				return nil
 			}
-			reqTimestampMock = time.Now()
 		}
-		return nil
 	})
 
 	select {
@@ -146,6 +133,82 @@ func encodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 	}
 }
 
+// addRequest stores the request and decodes the commands in it.
+func (ex *exchange) addRequest(logger *zap.Logger, buf []byte) {
+	if len(ex.requests) == 0 {
+		ex.reqTimestampMock = time.Now()
+	}
+	processBuffer(buf, models.FromClient, &ex.requests)
+
+	data := append(ex.reqTail, buf...)
+	cmds, n, err := parseCommands(data)
+	if err != nil && err != errIncomplete {
+		logger.Debug("failed to decode the redis command, the mock will be matched on the raw bytes", zap.Error(err))
+		ex.reqTail = nil
+		ex.raw = true
+		ex.expected++
+		return
+	}
+	ex.reqTail = append([]byte{}, data[n:]...)
+
+	for _, cmd := range cmds {
+		ex.commands = append(ex.commands, cmd)
+		ex.expected += expectedReplies(cmd)
+		switch cmd.Name {
+		case "MULTI":
+			ex.inTx = true
+		case "EXEC", "DISCARD":
+			ex.inTx = false
+		case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE":
+			ex.subscribed = true
+		case "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE", "RESET":
+			ex.subscribed = false
+		}
+	}
+}
+
+// addResponse stores the response and counts the replies in it.
+func (ex *exchange) addResponse(logger *zap.Logger, buf []byte) {
+	ex.resTimestampMock = time.Now()
+	processBuffer(buf, models.FromServer, &ex.responses)
+
+	data := append(ex.respTail, buf...)
+	replies, n, err := splitReplies(data)
+	if err != nil && err != errIncomplete {
+		logger.Debug("failed to decode the redis reply", zap.Error(err))
+		ex.respTail = nil
+		ex.replies = ex.expected
+		return
+	}
+	ex.respTail = append([]byte{}, data[n:]...)
+	ex.replies += len(replies)
+}
+
+// complete reports whether all the commands of the exchange have been answered. A subscription
+// is kept open to collect the pushed messages until the client sends the next command.
+func (ex *exchange) complete() bool {
+	return len(ex.requests) > 0 && ex.replies >= ex.expected && len(ex.respTail) == 0 && !ex.inTx && !ex.subscribed
+}
+
+// flush saves the exchange as a mock and resets it for the next commands.
+func (ex *exchange) flush(mocks chan<- *models.Mock) {
+	if len(ex.requests) > 0 && len(ex.responses) > 0 {
+		commands := ex.commands
+		if ex.raw {
+			commands = nil
+		}
+		saveMock(ex.requests, ex.responses, commands, ex.reqTimestampMock, ex.resTimestampMock, mocks)
+	}
+	ex.requests = nil
+	ex.responses = nil
+	ex.commands = nil
+	ex.reqTail = nil
+	ex.respTail = nil
+	ex.replies = 0
+	ex.expected = 0
+	ex.raw = false
+}
+
 func processBuffer(buffer []byte, origin models.OriginType, payloads *[]models.Payload) {
 	bufStr := string(buffer)
 	buffDataType := models.String
@@ -163,11 +226,13 @@ func processBuffer(buffer []byte, origin models.OriginType, payloads *[]models.P
 	}
 }
 
-func saveMock(requests, responses []models.Payload, reqTimestampMock, resTimestampMock time.Time, mocks chan<- *models.Mock) {
+func saveMock(requests, responses []models.Payload, commands []models.RedisCommand, reqTimestampMock, resTimestampMock time.Time, mocks chan<- *models.Mock) {
 	redisRequestsCopy := make([]models.Payload, len(requests))
 	redisResponsesCopy := make([]models.Payload, len(responses))
+	redisCommandsCopy := make([]models.RedisCommand, len(commands))
 	copy(redisResponsesCopy, responses)
 	copy(redisRequestsCopy, requests)
+	copy(redisCommandsCopy, commands)
 
 	metadata := make(map[string]string)
 	metadata["type"] = "config"
@@ -179,6 +244,7 @@ func saveMock(requests, responses []models.Payload, reqTimestampMock, resTimesta
 		Spec: models.MockSpec{
 			RedisRequests:    redisRequestsCopy,
 			RedisResponses:   redisResponsesCopy,
+			RedisCommands:    redisCommandsCopy,
 			ReqTimestampMock: reqTimestampMock,
 			ResTimestampMock: resTimestampMock,
 			Metadata:         metadata,
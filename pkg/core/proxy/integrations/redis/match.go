@@ -3,9 +3,12 @@
 package redis
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 
@@ -13,35 +16,292 @@ import (
 	"go.keploy.io/server/v2/pkg/models"
 )
 
-// fuzzyMatch performs a fuzzy matching algorithm to find the best matching mock for the given request.
-// It takes a context, a request buffer, and a mock database as input parameters.
-// The function iterates over the mocks in the database and applies the fuzzy matching algorithm to find the best match.
+// defaultNoise holds the volatile args of the commonly used commands. The entries are either
+// the index of the arg or the name of an option whose value is ignored.
+var defaultNoise = map[string][]string{
+	"SET":       {"EX", "PX", "EXAT", "PXAT"},
+	"GETEX":     {"EX", "PX", "EXAT", "PXAT"},
+	"SETEX":     {"1"},
+	"PSETEX":    {"1"},
+	"EXPIRE":    {"1"},
+	"PEXPIRE":   {"1"},
+	"EXPIREAT":  {"1"},
+	"PEXPIREAT": {"1"},
+}
+
+// session tracks a mock which has been partially replayed on a connection, e.g. a transaction
+// whose commands are sent one at a time by the client.
+type session struct {
+	mock     *models.Mock
+	commands []models.RedisCommand
+	replies  [][]byte
+	next     int
+}
+
+// fuzzyMatch finds the best matching mock for the given request.
+// The requests are decoded into commands which are matched on the command names and the args,
+// ignoring the noisy args. When the commands cannot be decoded, the mocks are matched on the raw
+// bytes. A mock whose commands are only partly sent by the client is kept in the session, so that
+// the next commands are answered from the same mock.
 // If a match is found, it returns the corresponding response mock and a boolean value indicating success.
 // If no match is found, it returns false and a nil response.
 // If an error occurs during the matching process, it returns an error.
-func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockMemDb) (bool, []models.Payload, error) {
+func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockMemDb, sess *session, noise map[string][]string) (bool, []models.Payload, error) {
+	cmds, n, err := parseCommands(bytes.Join(reqBuff, nil))
+	if err != nil || n == 0 || len(cmds) == 0 {
+		sess.reset()
+		return matchRaw(ctx, reqBuff, mockDb)
+	}
+
+	if sess.mock != nil {
+		if sess.next+len(cmds) <= len(sess.commands) && commandsEqual(sess.commands[sess.next:sess.next+len(cmds)], cmds, noise) {
+			return true, sess.advance(len(cmds)), nil
+		}
+		sess.reset()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetUnFilteredMocks()
+			filteredMocks, unfilteredMocks, err := getRedisMocks(mockDb)
 			if err != nil {
-				return false, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+				return false, nil, err
 			}
 
-			var filteredMocks []*models.Mock
-			var unfilteredMocks []*models.Mock
+			mock, mockCmds := findCommandMatch(filteredMocks, cmds, noise, commandsEqual)
+			if mock == nil {
+				mock, mockCmds = findCommandMatch(unfilteredMocks, cmds, noise, commandsEqual)
+			}
+			if mock == nil {
+				// the keys have to be the same, only the values of the args are allowed to differ
+				mock, mockCmds = findCommandMatch(append(filteredMocks, unfilteredMocks...), cmds, noise, keysEqual)
+			}
+			if mock == nil {
+				return false, nil, nil
+			}
 
-			for _, mock := range mocks {
-				if mock.Kind != "Redis" {
-					continue
-				}
-				if mock.TestModeInfo.IsFiltered {
-					filteredMocks = append(filteredMocks, mock)
-				} else {
-					unfilteredMocks = append(unfilteredMocks, mock)
-				}
+			if !mockDb.ConsumeMock(mock) {
+				continue
+			}
+
+			replies, ok := splitMockReplies(mock, mockCmds)
+			if !ok {
+				// the replies could not be attributed to the commands, send the whole response
+				responseMock := make([]models.Payload, len(mock.Spec.RedisResponses))
+				copy(responseMock, mock.Spec.RedisResponses)
+				return true, responseMock, nil
+			}
+			sess.mock = mock
+			sess.commands = mockCmds
+			sess.replies = replies
+			sess.next = 0
+			return true, sess.advance(len(cmds)), nil
+		}
+	}
+}
+
+// getRedisMocks returns the filtered and the unfiltered redis mocks.
+func getRedisMocks(mockDb integrations.MockMemDb) ([]*models.Mock, []*models.Mock, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKind(models.REDIS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var filteredMocks []*models.Mock
+	var unfilteredMocks []*models.Mock
+
+	for _, mock := range mocks {
+		if mock.TestModeInfo.IsFiltered {
+			filteredMocks = append(filteredMocks, mock)
+		} else {
+			unfilteredMocks = append(unfilteredMocks, mock)
+		}
+	}
+	return filteredMocks, unfilteredMocks, nil
+}
+
+// findCommandMatch returns the first mock whose commands start with the given commands, preferring
+// the mocks which have exactly the same number of commands. The raw mocks are skipped.
+func findCommandMatch(tcsMocks []*models.Mock, cmds []models.RedisCommand, noise map[string][]string, equal func(a, b []models.RedisCommand, noise map[string][]string) bool) (*models.Mock, []models.RedisCommand) {
+	var (
+		prefixMock *models.Mock
+		prefixCmds []models.RedisCommand
+	)
+	for _, mock := range tcsMocks {
+		mockCmds := mockCommands(mock)
+		if len(mockCmds) < len(cmds) || !equal(mockCmds[:len(cmds)], cmds, noise) {
+			continue
+		}
+		if len(mockCmds) == len(cmds) {
+			return mock, mockCmds
+		}
+		if prefixMock == nil {
+			prefixMock, prefixCmds = mock, mockCmds
+		}
+	}
+	return prefixMock, prefixCmds
+}
+
+// mockCommands returns the recorded commands of the mock. The mocks recorded before the commands
+// were stored in the yaml are decoded from the raw requests.
+func mockCommands(mock *models.Mock) []models.RedisCommand {
+	if len(mock.Spec.RedisCommands) > 0 {
+		return mock.Spec.RedisCommands
+	}
+	var buf []byte
+	for _, req := range mock.Spec.RedisRequests {
+		buf = append(buf, payloadBytes(req)...)
+	}
+	cmds, n, err := parseCommands(buf)
+	if err != nil || n != len(buf) {
+		return nil
+	}
+	return cmds
+}
+
+// splitMockReplies groups the replies of the mock per command. The messages pushed by the server
+// after the replies (e.g. for a subscription) are attributed to the last command.
+func splitMockReplies(mock *models.Mock, cmds []models.RedisCommand) ([][]byte, bool) {
+	var buf []byte
+	for _, resp := range mock.Spec.RedisResponses {
+		buf = append(buf, payloadBytes(resp)...)
+	}
+	values, n, err := splitReplies(buf)
+	if err != nil || n != len(buf) {
+		return nil, false
+	}
+
+	replies := make([][]byte, len(cmds))
+	idx := 0
+	for i, cmd := range cmds {
+		for j := 0; j < expectedReplies(cmd) && idx < len(values); j++ {
+			replies[i] = append(replies[i], values[idx]...)
+			idx++
+		}
+	}
+	if len(cmds) > 0 {
+		for ; idx < len(values); idx++ {
+			replies[len(cmds)-1] = append(replies[len(cmds)-1], values[idx]...)
+		}
+	}
+	return replies, true
+}
+
+// advance returns the replies of the next n commands of the session mock.
+func (s *session) advance(n int) []models.Payload {
+	var resp []byte
+	for _, reply := range s.replies[s.next : s.next+n] {
+		resp = append(resp, reply...)
+	}
+	s.next += n
+	if s.next >= len(s.commands) {
+		s.reset()
+	}
+	return []models.Payload{{
+		Origin: models.FromServer,
+		Message: []models.OutputBinary{{
+			Type: models.String,
+			Data: string(resp),
+		}},
+	}}
+}
+
+func (s *session) reset() {
+	s.mock = nil
+	s.commands = nil
+	s.replies = nil
+	s.next = 0
+}
+
+// commandsEqual checks whether the commands have the same name and args, ignoring the noisy args.
+func commandsEqual(expected, actual []models.RedisCommand, noise map[string][]string) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if expected[i].Name != actual[i].Name || len(expected[i].Args) != len(actual[i].Args) {
+			return false
+		}
+		noisy := noisyArgs(actual[i], noise)
+		for j := range expected[i].Args {
+			if !noisy[j] && expected[i].Args[j] != actual[i].Args[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// keysEqual checks whether the commands have the same name and operate on the same keys.
+func keysEqual(expected, actual []models.RedisCommand, _ map[string][]string) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if expected[i].Name != actual[i].Name || len(expected[i].Args) != len(actual[i].Args) {
+			return false
+		}
+		for _, k := range keyIndexes(actual[i]) {
+			if expected[i].Args[k] != actual[i].Args[k] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// noisyArgs returns the indexes of the args of the command which are ignored while matching.
+func noisyArgs(cmd models.RedisCommand, noise map[string][]string) map[int]bool {
+	noisy := map[int]bool{}
+	rules := append(append([]string{}, defaultNoise[cmd.Name]...), noise[cmd.Name]...)
+	for _, rule := range rules {
+		if rule == "*" {
+			for i := range cmd.Args {
+				noisy[i] = true
+			}
+			continue
+		}
+		if idx, err := strconv.Atoi(rule); err == nil {
+			noisy[idx] = true
+			continue
+		}
+		// the value following the option is ignored, e.g. SET key value EX <ttl>
+		for i, arg := range cmd.Args {
+			if strings.EqualFold(arg, rule) {
+				noisy[i+1] = true
+			}
+		}
+	}
+	return noisy
+}
+
+// payloadBytes returns the raw bytes of the payload, decoding the base64 data if required.
+func payloadBytes(p models.Payload) []byte {
+	if len(p.Message) == 0 {
+		return nil
+	}
+	if p.Message[0].Type == models.String {
+		return []byte(p.Message[0].Data)
+	}
+	data, err := util.DecodeBase64(p.Message[0].Data)
+	if err != nil {
+		return []byte(p.Message[0].Data)
+	}
+	return data
+}
+
+// matchRaw matches the requests which are not valid RESP against the raw bytes of the mocks.
+func matchRaw(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockMemDb) (bool, []models.Payload, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil, ctx.Err()
+		default:
+			filteredMocks, unfilteredMocks, err := getRedisMocks(mockDb)
+			if err != nil {
+				return false, nil, err
 			}
 
 			index := findExactMatch(filteredMocks, reqBuff)
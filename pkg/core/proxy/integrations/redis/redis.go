@@ -15,6 +15,7 @@ import (
 
 func init() {
 	integrations.Register("redis", NewRedis)
+	integrations.RegisterMissRecorder("redis")
 }
 
 type Redis struct {
@@ -0,0 +1,263 @@
+//go:build linux
+
+package redis
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// errIncomplete is returned when the buffer ends in the middle of a RESP value,
+// the remaining bytes are expected in the next read.
+var errIncomplete = errors.New("incomplete RESP value")
+
+// the limits of the redis server on the commands of its clients, the larger ones are taken as a corrupted stream
+const (
+	maxArgs    = 1024 * 1024
+	maxBulkLen = 512 * 1024 * 1024
+	// maxDepth bounds the nesting of the aggregates of the replies
+	maxDepth = 128
+)
+
+// parseCommands decodes all the complete commands present in the buffer. A buffer can hold
+// several commands when the client pipelines them. Both the RESP arrays of bulk strings and
+// the inline commands (e.g. "PING\r\n") are supported.
+// It returns the decoded commands and the number of bytes consumed from the buffer.
+func parseCommands(buf []byte) ([]models.RedisCommand, int, error) {
+	var cmds []models.RedisCommand
+	pos := 0
+	for pos < len(buf) {
+		var (
+			args []string
+			end  int
+			err  error
+		)
+		if buf[pos] == '*' {
+			args, end, err = readCommandArray(buf, pos)
+		} else {
+			args, end, err = readInlineCommand(buf, pos)
+		}
+		if err != nil {
+			return cmds, pos, err
+		}
+		pos = end
+		if len(args) == 0 {
+			continue
+		}
+		cmds = append(cmds, models.RedisCommand{
+			Name: strings.ToUpper(args[0]),
+			Args: args[1:],
+		})
+	}
+	return cmds, pos, nil
+}
+
+// readCommandArray decodes a command sent as a RESP array, e.g. *2\r\n$3\r\nGET\r\n$3\r\nkey\r\n
+func readCommandArray(buf []byte, pos int) ([]string, int, error) {
+	n, pos, err := readLength(buf, pos+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n > maxArgs {
+		return nil, 0, fmt.Errorf("invalid RESP array length %d", n)
+	}
+	// each argument takes at least 3 bytes, the capacity isn't trusted beyond the buffer
+	args := make([]string, 0, min(max(n, 0), (len(buf)-pos)/3))
+	for i := 0; i < n; i++ {
+		if pos >= len(buf) {
+			return nil, 0, errIncomplete
+		}
+		switch buf[pos] {
+		case '$':
+			var size int
+			size, pos, err = readBulkLength(buf, pos+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			if size < 0 {
+				args = append(args, "")
+				continue
+			}
+			if size > len(buf)-pos-2 {
+				return nil, 0, errIncomplete
+			}
+			args = append(args, string(buf[pos:pos+size]))
+			pos += size + 2
+		case '+', ':':
+			var line []byte
+			line, pos, err = readLine(buf, pos+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			args = append(args, string(line))
+		default:
+			return nil, 0, fmt.Errorf("unexpected RESP type %q in the command", buf[pos])
+		}
+	}
+	return args, pos, nil
+}
+
+// readInlineCommand decodes a command sent as a plain line of space separated arguments.
+func readInlineCommand(buf []byte, pos int) ([]string, int, error) {
+	line, end, err := readLine(buf, pos)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, c := range line {
+		if c < 0x20 && c != '\t' {
+			return nil, 0, fmt.Errorf("invalid inline redis command")
+		}
+	}
+	return strings.Fields(string(line)), end, nil
+}
+
+// splitReplies splits the buffer into the raw bytes of every complete top level RESP value.
+// It returns the values and the number of bytes consumed from the buffer.
+func splitReplies(buf []byte) ([][]byte, int, error) {
+	var replies [][]byte
+	pos := 0
+	for pos < len(buf) {
+		end, err := skipValue(buf, pos)
+		if err != nil {
+			return replies, pos, err
+		}
+		replies = append(replies, buf[pos:end])
+		pos = end
+	}
+	return replies, pos, nil
+}
+
+// skipValue returns the position right after the RESP2/RESP3 value starting at pos.
+func skipValue(buf []byte, pos int) (int, error) {
+	return skipNested(buf, pos, 0)
+}
+
+func skipNested(buf []byte, pos, depth int) (int, error) {
+	if pos >= len(buf) {
+		return 0, errIncomplete
+	}
+	if depth > maxDepth {
+		return 0, fmt.Errorf("RESP value nested deeper than %d levels", maxDepth)
+	}
+	switch buf[pos] {
+	// simple strings, errors, integers, null, booleans, doubles and big numbers
+	case '+', '-', ':', '_', '#', ',', '(':
+		_, end, err := readLine(buf, pos+1)
+		return end, err
+	// bulk strings, bulk errors and verbatim strings
+	case '$', '!', '=':
+		size, end, err := readBulkLength(buf, pos+1)
+		if err != nil {
+			return 0, err
+		}
+		if size < 0 {
+			return end, nil
+		}
+		if size > len(buf)-end-2 {
+			return 0, errIncomplete
+		}
+		return end + size + 2, nil
+	// arrays, sets, pushes, maps and attributes
+	case '*', '~', '>', '%', '|':
+		n, end, err := readLength(buf, pos+1)
+		if err != nil {
+			return 0, err
+		}
+		if n < -1 {
+			return 0, fmt.Errorf("invalid RESP aggregate length %d", n)
+		}
+		if buf[pos] == '%' || buf[pos] == '|' {
+			// the length of the maps counts their pairs, checked before it is doubled so that it can't overflow
+			if n > (len(buf)-end)/2 {
+				return 0, errIncomplete
+			}
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			end, err = skipNested(buf, end, depth+1)
+			if err != nil {
+				return 0, err
+			}
+		}
+		// attributes are sent right before the value they describe
+		if buf[pos] == '|' {
+			return skipNested(buf, end, depth+1)
+		}
+		return end, nil
+	default:
+		return 0, fmt.Errorf("unexpected RESP type %q", buf[pos])
+	}
+}
+
+func readLength(buf []byte, pos int) (int, int, error) {
+	line, end, err := readLine(buf, pos)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := strconv.Atoi(string(line))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid RESP length %q", line)
+	}
+	return n, end, nil
+}
+
+// readBulkLength reads the length of a bulk string, -1 being the null one.
+func readBulkLength(buf []byte, pos int) (int, int, error) {
+	n, end, err := readLength(buf, pos)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n < -1 || n > maxBulkLen {
+		return 0, 0, fmt.Errorf("invalid RESP bulk length %d", n)
+	}
+	return n, end, nil
+}
+
+func readLine(buf []byte, pos int) ([]byte, int, error) {
+	idx := bytes.Index(buf[pos:], []byte("\r\n"))
+	if idx == -1 {
+		return nil, 0, errIncomplete
+	}
+	return buf[pos : pos+idx], pos + idx + 2, nil
+}
+
+// expectedReplies returns the number of replies sent by the server for the command. The
+// (un)subscribe commands are acknowledged once per channel.
+func expectedReplies(cmd models.RedisCommand) int {
+	switch cmd.Name {
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE":
+		if len(cmd.Args) > 0 {
+			return len(cmd.Args)
+		}
+	}
+	return 1
+}
+
+// keyIndexes returns the positions of the keys in the args of the command.
+func keyIndexes(cmd models.RedisCommand) []int {
+	var idx []int
+	switch cmd.Name {
+	case "PING", "ECHO", "AUTH", "HELLO", "SELECT", "MULTI", "EXEC", "DISCARD", "UNWATCH", "RESET", "QUIT",
+		"INFO", "CLIENT", "CONFIG", "COMMAND", "DBSIZE", "FLUSHDB", "FLUSHALL", "TIME", "PUBLISH", "SPUBLISH",
+		"SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE", "SCAN", "KEYS":
+		return nil
+	case "MSET", "MSETNX":
+		for i := 0; i < len(cmd.Args); i += 2 {
+			idx = append(idx, i)
+		}
+	case "MGET", "DEL", "UNLINK", "EXISTS", "TOUCH", "WATCH":
+		for i := range cmd.Args {
+			idx = append(idx, i)
+		}
+	default:
+		if len(cmd.Args) > 0 {
+			idx = append(idx, 0)
+		}
+	}
+	return idx
+}
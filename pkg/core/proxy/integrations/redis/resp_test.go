@@ -0,0 +1,178 @@
+//go:build linux
+
+package redis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  string
+		want []models.RedisCommand
+	}{
+		{
+			name: "array",
+			buf:  "*3\r\n$3\r\nset\r\n$3\r\nkey\r\n$5\r\nvalue\r\n",
+			want: []models.RedisCommand{{Name: "SET", Args: []string{"key", "value"}}},
+		},
+		{
+			name: "inline",
+			buf:  "PING hello\r\n",
+			want: []models.RedisCommand{{Name: "PING", Args: []string{"hello"}}},
+		},
+		{
+			name: "pipeline",
+			buf:  "*1\r\n$5\r\nMULTI\r\n*2\r\n$4\r\nINCR\r\n$1\r\nn\r\n*1\r\n$4\r\nEXEC\r\n",
+			want: []models.RedisCommand{{Name: "MULTI", Args: []string{}}, {Name: "INCR", Args: []string{"n"}}, {Name: "EXEC", Args: []string{}}},
+		},
+		{
+			name: "null bulk and simple values",
+			buf:  "*4\r\n$4\r\nECHO\r\n$-1\r\n+ok\r\n:42\r\n",
+			want: []models.RedisCommand{{Name: "ECHO", Args: []string{"", "ok", "42"}}},
+		},
+		{
+			name: "binary value",
+			buf:  "*2\r\n$4\r\nECHO\r\n$4\r\na\r\nb\r\n",
+			want: []models.RedisCommand{{Name: "ECHO", Args: []string{"a\r\nb"}}},
+		},
+		{name: "empty line", buf: "\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, n, err := parseCommands([]byte(tt.buf))
+			if err != nil {
+				t.Fatalf("failed to parse the commands: %v", err)
+			}
+			if n != len(tt.buf) {
+				t.Fatalf("consumed %d bytes, want %d", n, len(tt.buf))
+			}
+			if !reflect.DeepEqual(cmds, tt.want) {
+				t.Fatalf("got the commands %+v, want %+v", cmds, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommandsMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		buf     string
+		wantErr error
+	}{
+		{name: "bulk length overflowing the buffer", buf: "*1\r\n$9223372036854775806\r\nab\r\n"},
+		{name: "bulk length above the limit", buf: "*1\r\n$536870913\r\n"},
+		{name: "negative bulk length", buf: "*1\r\n$-2\r\nab\r\n"},
+		{name: "array length above the limit", buf: "*99999999999\r\n"},
+		{name: "array length out of range", buf: "*99999999999999999999\r\n"},
+		{name: "invalid length", buf: "*x\r\n"},
+		{name: "nested array", buf: "*1\r\n*1\r\n"},
+		{name: "control character in an inline command", buf: "PI\x01NG\r\n"},
+		{name: "large array of a short buffer", buf: "*1048576\r\n$3\r\nGET\r\n", wantErr: errIncomplete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, n, err := parseCommands([]byte(tt.buf))
+			if err == nil {
+				t.Fatalf("the malformed command is parsed as %+v", cmds)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got the error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && errors.Is(err, errIncomplete) {
+				t.Fatalf("the malformed command is taken as incomplete")
+			}
+			if n != 0 || len(cmds) != 0 {
+				t.Fatalf("consumed %d bytes of the malformed command", n)
+			}
+		})
+	}
+}
+
+func TestSplitReplies(t *testing.T) {
+	replies := []string{
+		"+OK\r\n",
+		"-ERR unknown command\r\n",
+		":1000\r\n",
+		"$5\r\nhello\r\n",
+		"$-1\r\n",
+		"*-1\r\n",
+		"*2\r\n$1\r\na\r\n*1\r\n:1\r\n",
+		"%1\r\n+key\r\n~2\r\n#t\r\n,1.5\r\n",
+		"|1\r\n+ttl\r\n:10\r\n$1\r\nv\r\n",
+		">2\r\n+message\r\n=7\r\ntxt:abc\r\n",
+		"_\r\n",
+		"(12345678901234567890\r\n",
+		"!3\r\nerr\r\n",
+	}
+
+	buf := strings.Join(replies, "")
+	got, n, err := splitReplies([]byte(buf))
+	if err != nil {
+		t.Fatalf("failed to split the replies: %v", err)
+	}
+	if n != len(buf) || len(got) != len(replies) {
+		t.Fatalf("got %d replies of %d bytes, want %d of %d bytes", len(got), n, len(replies), len(buf))
+	}
+	for i, reply := range got {
+		if string(reply) != replies[i] {
+			t.Errorf("got the reply %q, want %q", reply, replies[i])
+		}
+	}
+}
+
+func TestSplitRepliesMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		buf     string
+		wantErr error
+	}{
+		{name: "unknown type", buf: "?1\r\n"},
+		{name: "bulk length overflowing the buffer", buf: "$9223372036854775806\r\nab\r\n"},
+		{name: "negative aggregate length", buf: "*-2\r\n"},
+		{name: "nesting deeper than the limit", buf: strings.Repeat("*1\r\n", maxDepth+2) + ":1\r\n"},
+		{name: "map length overflowing when doubled", buf: "%4611686018427387904\r\n", wantErr: errIncomplete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, n, err := splitReplies([]byte(tt.buf))
+			if err == nil || n != 0 {
+				t.Fatalf("the malformed reply is split (%d bytes, error: %v)", n, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got the error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && errors.Is(err, errIncomplete) {
+				t.Fatalf("the malformed reply is taken as incomplete")
+			}
+		})
+	}
+}
+
+// TestTruncated parses every prefix of a command and of a reply, the parsers wait for the rest of the value.
+func TestTruncated(t *testing.T) {
+	command := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n"
+	for i := 1; i < len(command); i++ {
+		cmds, n, err := parseCommands([]byte(command[:i]))
+		if !errors.Is(err, errIncomplete) || n != 0 || len(cmds) != 0 {
+			t.Fatalf("got %d commands of %d bytes (error: %v) for the first %d bytes", len(cmds), n, err, i)
+		}
+	}
+
+	reply := "*2\r\n%1\r\n+key\r\n$5\r\nvalue\r\n|1\r\n+a\r\n:1\r\n_\r\n"
+	for i := 1; i < len(reply); i++ {
+		replies, n, err := splitReplies([]byte(reply[:i]))
+		if !errors.Is(err, errIncomplete) || n != 0 || len(replies) != 0 {
+			t.Fatalf("got %d replies of %d bytes (error: %v) for the first %d bytes", len(replies), n, err, i)
+		}
+	}
+}
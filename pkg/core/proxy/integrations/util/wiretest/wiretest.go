@@ -0,0 +1,22 @@
+//go:build linux
+
+// Package wiretest builds the big-endian fields the fixtures of the wire tests of the integrations share.
+package wiretest
+
+import "encoding/binary"
+
+// AppendUint16 appends v as a 16-bit big-endian integer, the short of AMQP and CQL.
+func AppendUint16(b []byte, v uint16) []byte {
+	return binary.BigEndian.AppendUint16(b, v)
+}
+
+// AppendUint32 appends v as a 32-bit big-endian integer, the long of AMQP and the int of CQL.
+func AppendUint32(b []byte, v uint32) []byte {
+	return binary.BigEndian.AppendUint32(b, v)
+}
+
+// AppendLongBytes appends v after its length as a 32-bit big-endian integer, the long string and table of AMQP
+// and the long string and bytes of CQL.
+func AppendLongBytes(b []byte, v []byte) []byte {
+	return append(AppendUint32(b, uint32(len(v))), v...)
+}
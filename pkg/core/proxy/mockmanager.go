@@ -4,6 +4,7 @@ package proxy
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,13 +15,20 @@ import (
 )
 
 type MockManager struct {
-	filtered      *TreeDb
-	unfiltered    *TreeDb
+	filtered      *MockStore
+	unfiltered    *MockStore
 	logger        *zap.Logger
 	consumedMocks sync.Map
+	// calls that no mock matched since they were last fetched
+	unmatchedMu sync.Mutex
+	unmatched   []models.UnmatchedCall
+	// mocks recorded on a miss since they were last fetched
+	recordedMu    sync.Mutex
+	recorded      []*models.Mock
+	recordedCount int
 }
 
-func NewMockManager(filtered, unfiltered *TreeDb, logger *zap.Logger) *MockManager {
+func NewMockManager(filtered, unfiltered *MockStore, logger *zap.Logger) *MockManager {
 	return &MockManager{
 		filtered:      filtered,
 		unfiltered:    unfiltered,
@@ -30,53 +38,49 @@ func NewMockManager(filtered, unfiltered *TreeDb, logger *zap.Logger) *MockManag
 }
 
 func (m *MockManager) SetFilteredMocks(mocks []*models.Mock) {
-	m.filtered.deleteAll()
 	for index, mock := range mocks {
 		mock.TestModeInfo.SortOrder = index
 		mock.TestModeInfo.ID = index
-		m.filtered.insert(mock.TestModeInfo, mock)
 	}
+	m.filtered.setAll(mocks)
 }
 
 func (m *MockManager) SetUnFilteredMocks(mocks []*models.Mock) {
-	m.unfiltered.deleteAll()
 	for index, mock := range mocks {
 		mock.TestModeInfo.SortOrder = index
 		mock.TestModeInfo.ID = index
-		m.unfiltered.insert(mock.TestModeInfo, mock)
 	}
+	m.unfiltered.setAll(mocks)
 }
 
 func (m *MockManager) GetFilteredMocks() ([]*models.Mock, error) {
-	var tcsMocks []*models.Mock
-	mocks := m.filtered.getAll()
 	//sending copy of mocks instead of actual mocks
-	mockCopy, err := localMock(mocks)
-	if err != nil {
-		return nil, fmt.Errorf("expected mock instance, got %v", m)
-	}
-	for _, m := range mockCopy {
-		tcsMocks = append(tcsMocks, &m)
-	}
-	return tcsMocks, nil
+	return copyMocks(m.filtered.getAll()), nil
 }
 
 func (m *MockManager) GetUnFilteredMocks() ([]*models.Mock, error) {
-	var configMocks []*models.Mock
-	mocks := m.unfiltered.getAll()
 	//sending copy of mocks instead of actual mocks
-	mockCopy, err := localMock(mocks)
-	if err != nil {
-		return nil, fmt.Errorf("expected mock instance, got %v", m)
-	}
-	for _, m := range mockCopy {
-		configMocks = append(configMocks, &m)
-	}
-	return configMocks, nil
+	return copyMocks(m.unfiltered.getAll()), nil
+}
+
+func (m *MockManager) GetFilteredMocksByKind(kind models.Kind) ([]*models.Mock, error) {
+	return copyMocks(m.filtered.getByKind(kind)), nil
+}
+
+func (m *MockManager) GetUnFilteredMocksByKind(kind models.Kind) ([]*models.Mock, error) {
+	return copyMocks(m.unfiltered.getByKind(kind)), nil
+}
+
+func (m *MockManager) GetFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error) {
+	return copyMocks(m.filtered.getByKey(kind, key)), nil
+}
+
+func (m *MockManager) GetUnFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error) {
+	return copyMocks(m.unfiltered.getByKey(kind, key)), nil
 }
 
 func (m *MockManager) UpdateUnFilteredMock(old *models.Mock, new *models.Mock) bool {
-	updated := m.unfiltered.update(old.TestModeInfo, new.TestModeInfo, new)
+	updated := m.unfiltered.update(old.TestModeInfo, new)
 	if updated {
 		// mark the unfiltered mock as used for the current simulated test-case
 		go func() {
@@ -88,6 +92,22 @@ func (m *MockManager) UpdateUnFilteredMock(old *models.Mock, new *models.Mock) b
 	return updated
 }
 
+// ConsumeMock flags the unfiltered mock as used. A filtered mock is moved behind the other mocks, so that
+// the next identical request gets the next recorded response. The move fails when the mock has already
+// been consumed by another request.
+func (m *MockManager) ConsumeMock(mock *models.Mock) bool {
+	if !mock.TestModeInfo.IsFiltered {
+		if err := m.FlagMockAsUsed(*mock); err != nil {
+			m.logger.Error("failed to flag mock as used", zap.Error(err))
+		}
+		return true
+	}
+	consumed := *mock
+	consumed.TestModeInfo.IsFiltered = false
+	consumed.TestModeInfo.SortOrder = math.MaxInt64
+	return m.UpdateUnFilteredMock(mock, &consumed)
+}
+
 func (m *MockManager) FlagMockAsUsed(mock models.Mock) error {
 	if mock.Name == "" {
 		return fmt.Errorf("mock is empty")
@@ -130,12 +150,60 @@ func (m *MockManager) GetConsumedMocks() []string {
 		return true
 	})
 	sort.Slice(keys, func(i, j int) bool {
-		numI, _ := strconv.Atoi(strings.Split(keys[i], "-")[1])
-		numJ, _ := strconv.Atoi(strings.Split(keys[j], "-")[1])
-		return numI < numJ
+		numI, numJ := mockNumber(keys[i]), mockNumber(keys[j])
+		if numI != numJ {
+			return numI < numJ
+		}
+		return keys[i] < keys[j]
 	})
 	for key := range keys {
 		m.consumedMocks.Delete(key)
 	}
 	return keys
 }
+
+// mockNumber returns the count a mock is named after e.g. 3 for mock-3, the names without a count
+// e.g. the ones given by the users return 0.
+func mockNumber(name string) int {
+	num, _ := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+	return num
+}
+
+func (m *MockManager) RecordUnmatched(call models.UnmatchedCall) {
+	m.unmatchedMu.Lock()
+	defer m.unmatchedMu.Unlock()
+	m.unmatched = append(m.unmatched, call)
+}
+
+// GetUnmatchedCalls returns the calls that no mock matched and forgets them, like the consumed mocks
+// they are fetched after each test case.
+func (m *MockManager) GetUnmatchedCalls() []models.UnmatchedCall {
+	m.unmatchedMu.Lock()
+	defer m.unmatchedMu.Unlock()
+	calls := m.unmatched
+	m.unmatched = nil
+	return calls
+}
+
+// AddRecordedMock keeps the mock recorded on a miss to be appended to the test-set. The next requests are
+// matched against it right away, it is named after the order it was recorded in until it is appended.
+func (m *MockManager) AddRecordedMock(mock *models.Mock) {
+	m.recordedMu.Lock()
+	m.recorded = append(m.recorded, mock)
+	m.recordedCount++
+	name := fmt.Sprintf("recorded-%d", m.recordedCount)
+	m.recordedMu.Unlock()
+
+	stored := *mock
+	stored.Name = name
+	m.unfiltered.insert(&stored)
+}
+
+// GetRecordedMocks returns the mocks recorded on a miss and forgets them.
+func (m *MockManager) GetRecordedMocks() []*models.Mock {
+	m.recordedMu.Lock()
+	defer m.recordedMu.Unlock()
+	mocks := m.recorded
+	m.recorded = nil
+	return mocks
+}
@@ -0,0 +1,279 @@
+//go:build linux
+
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/pkg/core"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// mockServerID is the id of the session of the mock server, no app is instrumented by the mock server.
+const mockServerID uint64 = 0
+
+// ServeMocks serves the given mocks without eBPF, the calls are received on the ports of the listeners
+// and on the http forward proxy instead of being redirected from the sockets of the app. It blocks
+// until the context is cancelled.
+func (p *Proxy) ServeMocks(ctx context.Context, opts models.MockServerOptions, filtered, unfiltered []*models.Mock) error {
+	if len(opts.Listeners) == 0 && opts.HTTPProxy == "" {
+		return errors.New("neither a port nor the http proxy is given to serve the mocks on")
+	}
+
+	err := p.InitIntegrations(ctx)
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to initialize the integrations")
+		return err
+	}
+
+	if opts.HTTPProxy != "" {
+		// the https calls made through the proxy are decrypted with the keploy CA
+		err = SetupCA(ctx, p.logger)
+		if err != nil {
+			p.logger.Warn("failed to install the keploy CA, the https calls made through the proxy will fail unless the client trusts it", zap.Error(err))
+			p.writeCA()
+		}
+	}
+
+	protocols := make(map[uint32]string, len(opts.Protocols)+len(opts.Listeners))
+	for port, protocol := range opts.Protocols {
+		protocols[port] = protocol
+	}
+	for _, l := range opts.Listeners {
+		if l.Protocol != "" {
+			protocols[l.Port] = l.Protocol
+		}
+	}
+
+	p.sessions.Set(mockServerID, &core.Session{
+		ID:              mockServerID,
+		Mode:            models.MODE_TEST,
+		OutgoingOptions: opts.OutgoingOptions,
+	})
+	p.setProtocols(mockServerID, protocols)
+	m := NewMockManager(NewMockStore(), NewMockStore(), p.logger)
+	m.SetFilteredMocks(filtered)
+	m.SetUnFilteredMocks(unfiltered)
+	p.MockManagers.Store(mockServerID, m)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	// the connections are not part of the group, a failed connection does not stop the server
+	connGrp := &errgroup.Group{}
+	defer func() {
+		err := connGrp.Wait()
+		if err != nil {
+			p.logger.Debug("failed to handle the client connection", zap.Error(err))
+		}
+	}()
+
+	for _, l := range opts.Listeners {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%v", l.Port))
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to listen on the port of the dependency", zap.Any("port", l.Port))
+			return err
+		}
+		port, upstream := l.Port, l.Upstream
+		p.logger.Info("serving the mocks", zap.Any("port", port), zap.Any("protocol", l.Protocol))
+		g.Go(func() error {
+			defer utils.Recover(p.logger)
+			return p.acceptMockConns(gCtx, connGrp, listener, func(ctx context.Context, conn net.Conn) error {
+				return p.serveConnection(ctx, conn, mockServerID, port, upstream)
+			})
+		})
+	}
+
+	if opts.HTTPProxy != "" {
+		listener, err := net.Listen("tcp", opts.HTTPProxy)
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to listen on the address of the http proxy", zap.Any("address", opts.HTTPProxy))
+			return err
+		}
+		p.logger.Info(fmt.Sprintf("serving the http mocks as a proxy, set HTTP_PROXY and HTTPS_PROXY to http://%v", listener.Addr()))
+		g.Go(func() error {
+			defer utils.Recover(p.logger)
+			return p.acceptMockConns(gCtx, connGrp, listener, p.handleProxyConn)
+		})
+	}
+
+	return g.Wait()
+}
+
+// acceptMockConns accepts the connections on the listener until the context is cancelled.
+func (p *Proxy) acceptMockConns(ctx context.Context, connGrp *errgroup.Group, listener net.Listener, handle func(context.Context, net.Conn) error) error {
+	go func() {
+		defer utils.Recover(p.logger)
+		<-ctx.Done()
+		err := listener.Close()
+		if err != nil {
+			p.logger.Debug("failed to close the listener", zap.Error(err))
+		}
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			utils.LogError(p.logger, err, "failed to accept connection to the mock server")
+			return err
+		}
+		connGrp.Go(func() error {
+			defer util.Recover(p.logger, conn, nil)
+			err := handle(ctx, conn)
+			if err != nil && err != io.EOF {
+				utils.LogError(p.logger, err, "failed to handle the client connection")
+			}
+			return nil
+		})
+	}
+}
+
+// handleProxyConn serves a connection made to the http forward proxy. A CONNECT request opens a tunnel
+// to the destination, whose calls (e.g. https) are served as if they were made to the destination.
+// The plain http requests are sent on to the http parser in the origin form.
+func (p *Proxy) handleProxyConn(ctx context.Context, conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	method, target, ok := parseRequestLine(line)
+	if !ok {
+		return fmt.Errorf("malformed request line of the proxy request: %q", line)
+	}
+
+	if method == http.MethodConnect {
+		// the headers of the CONNECT request are of no use
+		_, err = textproto.NewReader(reader).ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		_, err = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		if err != nil {
+			return err
+		}
+		port, err := targetPort(target, "443")
+		if err != nil {
+			return err
+		}
+		tunnel := &Conn{
+			Conn:   conn,
+			r:      reader,
+			logger: p.logger,
+		}
+		return p.serveConnection(ctx, tunnel, mockServerID, port, target)
+	}
+
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil || req.URL.Host == "" {
+		return fmt.Errorf("the proxy request is not in the absolute form: %q", line)
+	}
+	host := req.URL.Host
+	if req.URL.Port() == "" {
+		host = net.JoinHostPort(req.URL.Hostname(), "80")
+	}
+	port, err := targetPort(host, "80")
+	if err != nil {
+		return err
+	}
+
+	// the requests are rewritten into a pipe served by the http parser
+	client, server := net.Pipe()
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		defer utils.Recover(p.logger)
+		return p.serveConnection(gCtx, &pipeConn{Conn: server, remote: conn.RemoteAddr(), local: conn.LocalAddr()}, mockServerID, port, host)
+	})
+	g.Go(func() error {
+		defer utils.Recover(p.logger)
+		_, err := io.Copy(conn, client)
+		if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			return err
+		}
+		return nil
+	})
+
+	reqReader := bufio.NewReader(io.MultiReader(strings.NewReader(line), reader))
+	for {
+		req, err := http.ReadRequest(reqReader)
+		if err != nil {
+			if err == io.EOF || errors.Is(err, net.ErrClosed) {
+				break
+			}
+			utils.LogError(p.logger, err, "failed to read the request made to the proxy")
+			break
+		}
+		req.Header.Del("Proxy-Connection")
+		req.Header.Del("Proxy-Authorization")
+		// the request line is written with the path only
+		err = req.Write(client)
+		if err != nil {
+			p.logger.Debug("failed to forward the request made to the proxy", zap.Error(err))
+			break
+		}
+	}
+	err = client.Close()
+	if err != nil {
+		p.logger.Debug("failed to close the pipe of the proxy connection", zap.Error(err))
+	}
+	return g.Wait()
+}
+
+// writeCA writes the keploy CA to a file, so that the clients can be configured to trust it.
+func (p *Proxy) writeCA() {
+	path := filepath.Join(os.TempDir(), "keploy-ca.crt")
+	err := os.WriteFile(path, caCrt, 0644)
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to write the keploy CA")
+		return
+	}
+	p.logger.Info("the keploy CA has been written, add it to the trusted certificates of the client (e.g. SSL_CERT_FILE, NODE_EXTRA_CA_CERTS)", zap.Any("path", path))
+}
+
+func parseRequestLine(line string) (string, string, bool) {
+	parts := strings.Fields(line)
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "HTTP/") {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func targetPort(target, defaultPort string) (uint32, error) {
+	_, port, err := net.SplitHostPort(target)
+	if err != nil {
+		port = defaultPort
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port of the proxy request: %q", target)
+	}
+	return uint32(n), nil
+}
+
+// pipeConn reports the addresses of the proxy connection for the pipe served by the parsers.
+type pipeConn struct {
+	net.Conn
+	remote net.Addr
+	local  net.Addr
+}
+
+func (c *pipeConn) RemoteAddr() net.Addr { return c.remote }
+func (c *pipeConn) LocalAddr() net.Addr  { return c.local }
@@ -0,0 +1,223 @@
+//go:build linux
+
+package proxy
+
+// MockStore holds the mocks of a session indexed by their kind and by the key computed for their kind
+// (integrations.MockKey), so that the parsers only go through the mocks which can match a request.
+// The indexes are copied on write: a slice once handed to a reader is never modified, hence the
+// readers only hold the lock while picking the slice.
+
+import (
+	"sort"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type indexKey struct {
+	kind models.Kind
+	key  string
+}
+
+type MockStore struct {
+	mu     sync.RWMutex
+	byID   map[int]*models.Mock
+	keys   map[int]string
+	byKind map[models.Kind][]*models.Mock
+	byKey  map[indexKey][]*models.Mock
+	all    []*models.Mock // sorted on demand, nil once a mock is inserted or removed
+	nextID int            // the id of the next mock inserted into the store
+}
+
+func NewMockStore() *MockStore {
+	return &MockStore{
+		byID:   make(map[int]*models.Mock),
+		keys:   make(map[int]string),
+		byKind: make(map[models.Kind][]*models.Mock),
+		byKey:  make(map[indexKey][]*models.Mock),
+	}
+}
+
+// less orders the mocks on their sort order and then on their id, as they were recorded.
+func less(a, b models.TestModeInfo) bool {
+	if a.SortOrder != b.SortOrder {
+		return a.SortOrder < b.SortOrder
+	}
+	return a.ID < b.ID
+}
+
+// setAll replaces the mocks of the store, the indexes are built at once.
+func (s *MockStore) setAll(mocks []*models.Mock) {
+	byID := make(map[int]*models.Mock, len(mocks))
+	keys := make(map[int]string, len(mocks))
+	byKind := make(map[models.Kind][]*models.Mock)
+	byKey := make(map[indexKey][]*models.Mock)
+
+	sorted := make([]*models.Mock, 0, len(mocks))
+	for _, mock := range mocks {
+		m := *mock
+		sorted = append(sorted, &m)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i].TestModeInfo, sorted[j].TestModeInfo)
+	})
+	nextID := 0
+	for _, mock := range sorted {
+		nextID = max(nextID, mock.TestModeInfo.ID+1)
+		byID[mock.TestModeInfo.ID] = mock
+		byKind[mock.Kind] = append(byKind[mock.Kind], mock)
+		if key := integrations.MockKey(mock); key != "" {
+			keys[mock.TestModeInfo.ID] = key
+			k := indexKey{kind: mock.Kind, key: key}
+			byKey[k] = append(byKey[k], mock)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byID = byID
+	s.keys = keys
+	s.byKind = byKind
+	s.byKey = byKey
+	s.all = sorted
+	s.nextID = nextID
+}
+
+// insert adds the mock behind the mocks of the store under an id of its own.
+func (s *MockStore) insert(mock *models.Mock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := *mock
+	m.TestModeInfo = models.TestModeInfo{ID: s.nextID, SortOrder: s.nextID}
+	s.nextID++
+	s.add(&m)
+}
+
+// update replaces the mock having the old test mode info with the new mock. It returns false if the
+// mock is no longer in the store, e.g. when it has been consumed by another request.
+func (s *MockStore) update(old models.TestModeInfo, new *models.Mock) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.remove(old) {
+		return false
+	}
+	m := *new
+	s.add(&m)
+	return true
+}
+
+// delete removes the mock having the given test mode info.
+func (s *MockStore) delete(info models.TestModeInfo) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(info)
+}
+
+// remove has to be called with the lock held.
+func (s *MockStore) remove(info models.TestModeInfo) bool {
+	mock, ok := s.byID[info.ID]
+	if !ok || mock.TestModeInfo.SortOrder != info.SortOrder {
+		return false
+	}
+	delete(s.byID, info.ID)
+	s.byKind[mock.Kind] = without(s.byKind[mock.Kind], mock)
+	if key, ok := s.keys[info.ID]; ok {
+		delete(s.keys, info.ID)
+		k := indexKey{kind: mock.Kind, key: key}
+		if bucket := without(s.byKey[k], mock); len(bucket) > 0 {
+			s.byKey[k] = bucket
+		} else {
+			delete(s.byKey, k)
+		}
+	}
+	s.all = nil
+	return true
+}
+
+// add has to be called with the lock held.
+func (s *MockStore) add(mock *models.Mock) {
+	s.byID[mock.TestModeInfo.ID] = mock
+	s.byKind[mock.Kind] = with(s.byKind[mock.Kind], mock)
+	if key := integrations.MockKey(mock); key != "" {
+		s.keys[mock.TestModeInfo.ID] = key
+		k := indexKey{kind: mock.Kind, key: key}
+		s.byKey[k] = with(s.byKey[k], mock)
+	}
+	s.all = nil
+}
+
+func (s *MockStore) deleteAll() {
+	s.setAll(nil)
+}
+
+// getAll returns all the mocks in their order.
+func (s *MockStore) getAll() []*models.Mock {
+	s.mu.RLock()
+	all := s.all
+	s.mu.RUnlock()
+	if all != nil {
+		return all
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.all == nil {
+		all := make([]*models.Mock, 0, len(s.byID))
+		for _, mock := range s.byID {
+			all = append(all, mock)
+		}
+		sort.Slice(all, func(i, j int) bool {
+			return less(all[i].TestModeInfo, all[j].TestModeInfo)
+		})
+		s.all = all
+	}
+	return s.all
+}
+
+func (s *MockStore) getByKind(kind models.Kind) []*models.Mock {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byKind[kind]
+}
+
+func (s *MockStore) getByKey(kind models.Kind, key string) []*models.Mock {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byKey[indexKey{kind: kind, key: key}]
+}
+
+// with returns a copy of the sorted mocks having the given mock inserted in its place.
+func with(mocks []*models.Mock, mock *models.Mock) []*models.Mock {
+	i := sort.Search(len(mocks), func(i int) bool {
+		return !less(mocks[i].TestModeInfo, mock.TestModeInfo)
+	})
+	out := make([]*models.Mock, 0, len(mocks)+1)
+	out = append(out, mocks[:i]...)
+	out = append(out, mock)
+	return append(out, mocks[i:]...)
+}
+
+// without returns a copy of the sorted mocks without the given mock.
+func without(mocks []*models.Mock, mock *models.Mock) []*models.Mock {
+	i := sort.Search(len(mocks), func(i int) bool {
+		return !less(mocks[i].TestModeInfo, mock.TestModeInfo)
+	})
+	if i == len(mocks) || mocks[i] != mock {
+		return mocks
+	}
+	out := make([]*models.Mock, 0, len(mocks)-1)
+	out = append(out, mocks[:i]...)
+	return append(out, mocks[i+1:]...)
+}
+
+// copyMocks returns copies of the mocks, so that the changes made by the parsers do not reach the store.
+func copyMocks(mocks []*models.Mock) []*models.Mock {
+	copied := make([]models.Mock, len(mocks))
+	out := make([]*models.Mock, len(mocks))
+	for i, mock := range mocks {
+		copied[i] = *mock
+		out[i] = &copied[i]
+	}
+	return out
+}
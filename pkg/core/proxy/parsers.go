@@ -4,11 +4,17 @@ package proxy
 
 import (
 	// import all the integrations
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/amqp"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/cassandra"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/generic"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/grpc"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/http"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/kafka"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/memcached"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/mongo"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/mssql"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/postgres/v1"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/postgres/v2"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/redis"
 )
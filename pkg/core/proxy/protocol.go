@@ -0,0 +1,154 @@
+//go:build linux
+
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// clientFirstTimeout is the time for which the proxy waits for the client to send the first message.
+	// The clients of the server-first protocols (e.g. mysql) wait for the greeting of the server instead.
+	clientFirstTimeout = 100 * time.Millisecond
+	// serverGreetingTimeout is the time for which the proxy waits for the greeting of the server.
+	serverGreetingTimeout = 2 * time.Second
+)
+
+type protocolKey struct {
+	appID uint64
+	port  uint32
+}
+
+// getProtocol returns the protocol configured or detected on the destination port, if any.
+func (p *Proxy) getProtocol(id uint64, port uint32) string {
+	if protocol, ok := p.protocols.Load(protocolKey{appID: id, port: port}); ok {
+		return protocol.(string)
+	}
+	// mysql is expected on its default port unless configured otherwise
+	if port == 3306 {
+		return "mysql"
+	}
+	return ""
+}
+
+func (p *Proxy) setProtocols(id uint64, protocols map[uint32]string) {
+	for port, protocol := range protocols {
+		p.protocols.Store(protocolKey{appID: id, port: port}, protocol)
+	}
+}
+
+// isClientFirst reports whether the destination port of the app was already found not to be server-first.
+func (p *Proxy) isClientFirst(id uint64, port uint32) bool {
+	_, ok := p.clientFirst.Load(protocolKey{appID: id, port: port})
+	return ok
+}
+
+// startProtocols sets the protocols configured for a new session of the app,
+// the protocols of the session are cleared once it ends.
+func (p *Proxy) startProtocols(ctx context.Context, session *core.Session) {
+	p.clearProtocols(session.ID)
+	p.setProtocols(session.ID, session.OutgoingOptions.Protocols)
+	context.AfterFunc(ctx, func() {
+		// a newer session of the app keeps its own protocols
+		if current, ok := p.sessions.Get(session.ID); ok && current == session {
+			p.clearProtocols(session.ID)
+		}
+	})
+}
+
+// clearProtocols removes the protocols configured or detected on the destination ports of the app.
+func (p *Proxy) clearProtocols(id uint64) {
+	for _, m := range []*sync.Map{&p.protocols, &p.clientFirst} {
+		m.Range(func(k, _ interface{}) bool {
+			if k.(protocolKey).appID == id {
+				m.Delete(k)
+			}
+			return true
+		})
+	}
+}
+
+// GetProtocols returns the protocols configured or detected on the destination ports for a given app id
+func (p *Proxy) GetProtocols(_ context.Context, id uint64) (map[uint32]string, error) {
+	protocols := map[uint32]string{}
+	p.protocols.Range(func(k, v interface{}) bool {
+		if key := k.(protocolKey); key.appID == id {
+			protocols[key.port] = v.(string)
+		}
+		return true
+	})
+	return protocols, nil
+}
+
+// detectServerFirst checks whether the destination speaks first, by waiting for the greeting of the server
+// when the client does not send anything. It returns the protocol identified from the greeting and the
+// connection to the destination, if it had to be dialed.
+func detectServerFirst(logger *zap.Logger, srcConn net.Conn, reader *bufio.Reader, dstAddr string) (string, net.Conn, error) {
+	err := srcConn.SetReadDeadline(time.Now().Add(clientFirstTimeout))
+	if err != nil {
+		return "", nil, err
+	}
+	_, err = reader.Peek(1)
+	if dErr := srcConn.SetReadDeadline(time.Time{}); dErr != nil {
+		return "", nil, dErr
+	}
+	if err == nil || !errors.Is(err, os.ErrDeadlineExceeded) {
+		// the client spoke first (or the connection is closed), which is handled by the client-first flow
+		return "", nil, nil
+	}
+
+	dstConn, err := net.Dial("tcp", dstAddr)
+	if err != nil {
+		utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("server address", dstAddr))
+		return "", nil, err
+	}
+	dstReader := bufio.NewReader(dstConn)
+	// the greeting of the server (if any) stays in the reader for the parser
+	dstConn = &Conn{
+		Conn:   dstConn,
+		r:      dstReader,
+		logger: logger,
+	}
+
+	err = dstConn.SetReadDeadline(time.Now().Add(serverGreetingTimeout))
+	if err != nil {
+		return "", dstConn, err
+	}
+	greeting, err := dstReader.Peek(5)
+	if dErr := dstConn.SetReadDeadline(time.Time{}); dErr != nil {
+		return "", dstConn, dErr
+	}
+	if err != nil {
+		logger.Debug("no greeting received from the destination server", zap.Any("server address", dstAddr), zap.Error(err))
+		return "", dstConn, nil
+	}
+
+	protocol := identifyServerGreeting(greeting)
+	if protocol == "" {
+		logger.Debug("the destination server sent an unknown greeting", zap.Any("server address", dstAddr))
+	}
+	return protocol, dstConn, nil
+}
+
+// identifyServerGreeting returns the protocol of the first message sent by the server.
+func identifyServerGreeting(buf []byte) string {
+	if len(buf) < 5 {
+		return ""
+	}
+	// mysql packet header: 3 bytes of payload length and the sequence id 0, followed by
+	// either the protocol version 10 of the handshake or an error packet (0xff)
+	if buf[3] == 0 && (buf[4] == 0x0a || buf[4] == 0xff) {
+		return "mysql"
+	}
+	return ""
+}
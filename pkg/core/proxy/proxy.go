@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -43,6 +44,19 @@ type Proxy struct {
 
 	sessions *core.Sessions
 
+	// protocols stores the protocol used on the destination ports of the apps
+	protocols sync.Map
+	// clientFirst stores the destination ports of the apps already found not to be server-first
+	clientFirst sync.Map
+
+	// dnsCache stores the answers resolved while recording, each question is recorded only once
+	dnsCache sync.Map
+	// appIPs stores the app of each app container ip, the dns queries are attributed by their source ip
+	appIPs sync.Map
+
+	// the parsers not recording on a miss are reported once
+	recordOnMissWarning sync.Once
+
 	connMutex *sync.Mutex
 	ipMutex   *sync.Mutex
 
@@ -269,14 +283,6 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		p.logger.Debug("time taken by proxy to execute the flow", zap.Any("Duration(ms)", duration.Milliseconds()))
 	}(start)
 
-	// making a new client connection id for each client connection
-	clientConnID := util.GetNextID()
-	// dstConn stores conn with actual destination for the outgoing network call
-	var dstConn net.Conn
-
-	//Dialing for tls conn
-	destConnID := util.GetNextID()
-
 	remoteAddr := srcConn.RemoteAddr().(*net.TCPAddr)
 	sourcePort := remoteAddr.Port
 
@@ -295,13 +301,6 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return err
 	}
 
-	//get the session rule
-	rule, ok := p.sessions.Get(destInfo.AppID)
-	if !ok {
-		utils.LogError(p.logger, nil, "failed to fetch the session rule", zap.Any("AppID", destInfo.AppID))
-		return err
-	}
-
 	var dstAddr string
 
 	if destInfo.Version == 4 {
@@ -312,11 +311,34 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		p.logger.Debug("", zap.Any("DestIp6", destInfo.IPv6Addr), zap.Any("DestPort", destInfo.Port))
 	}
 
+	return p.serveConnection(ctx, srcConn, destInfo.AppID, destInfo.Port, dstAddr)
+}
+
+// serveConnection records or mocks the calls made by the app on the client connection to the destination.
+func (p *Proxy) serveConnection(ctx context.Context, srcConn net.Conn, appID uint64, port uint32, dstAddr string) error {
+	// making a new client connection id for each client connection
+	clientConnID := util.GetNextID()
+	// dstConn stores conn with actual destination for the outgoing network call
+	var dstConn net.Conn
+
+	//Dialing for tls conn
+	destConnID := util.GetNextID()
+
+	//get the session rule
+	rule, ok := p.sessions.Get(appID)
+	if !ok {
+		utils.LogError(p.logger, nil, "failed to fetch the session rule", zap.Any("AppID", appID))
+		return nil
+	}
+
+	var err error
+
 	// This is used to handle the parser errors
 	parserErrGrp, parserCtx := errgroup.WithContext(ctx)
 	parserCtx = context.WithValue(parserCtx, models.ErrGroupKey, parserErrGrp)
 	parserCtx = context.WithValue(parserCtx, models.ClientConnectionIDKey, fmt.Sprint(clientConnID))
 	parserCtx = context.WithValue(parserCtx, models.DestConnectionIDKey, fmt.Sprint(destConnID))
+	parserCtx = context.WithValue(parserCtx, models.TLSConfigKey, p.tlsConfig())
 	parserCtx, parserCtxCancel := context.WithCancel(parserCtx)
 	defer func() {
 		parserCtxCancel()
@@ -362,16 +384,45 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return nil
 	}
 
-	//checking for the destination port of "mysql"
-	if destInfo.Port == 3306 {
+	reader := bufio.NewReader(srcConn)
+
+	// the server-first protocols (e.g. mysql) can not be detected from the message of the client
+	protocol := p.getProtocol(appID, port)
+	if protocol == "" && rule.Mode == models.MODE_RECORD && !p.isClientFirst(appID, port) {
+		protocol, dstConn, err = detectServerFirst(p.logger, srcConn, reader, dstAddr)
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to detect the protocol of the destination server", zap.Any("server address", dstAddr))
+			return err
+		}
+		if protocol != "" {
+			p.logger.Debug("detected a server-first protocol", zap.Any("protocol", protocol), zap.Any("port", port))
+			p.setProtocols(appID, map[uint32]string{port: protocol})
+		} else {
+			// the destination port is not probed again for the rest of the session
+			p.clientFirst.Store(protocolKey{appID: appID, port: port}, true)
+		}
+	}
+
+	if protocol != "" && p.Integrations[protocol] == nil {
+		p.logger.Warn("no parser found for the protocol of the destination port", zap.Any("protocol", protocol), zap.Any("port", port))
+	}
+
+	if parser, ok := p.Integrations[protocol]; ok {
+		srcConn = &Conn{
+			Conn:   srcConn,
+			r:      reader,
+			logger: p.logger,
+		}
 		if rule.Mode != models.MODE_TEST {
-			dstConn, err = net.Dial("tcp", dstAddr)
-			if err != nil {
-				utils.LogError(p.logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
-				return err
+			if dstConn == nil {
+				dstConn, err = net.Dial("tcp", dstAddr)
+				if err != nil {
+					utils.LogError(p.logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
+					return err
+				}
 			}
 			// Record the outgoing message into a mock
-			err := p.Integrations["mysql"].RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
+			err := parser.RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
 			if err != nil {
 				utils.LogError(p.logger, err, "failed to record the outgoing message")
 				return err
@@ -379,14 +430,14 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 			return nil
 		}
 
-		m, ok := p.MockManagers.Load(destInfo.AppID)
+		m, ok := p.MockManagers.Load(appID)
 		if !ok {
-			utils.LogError(p.logger, nil, "failed to fetch the mock manager", zap.Any("AppID", destInfo.AppID))
+			utils.LogError(p.logger, nil, "failed to fetch the mock manager", zap.Any("AppID", appID))
 			return err
 		}
 
 		//mock the outgoing message
-		err := p.Integrations["mysql"].MockOutgoing(parserCtx, srcConn, &integrations.ConditionalDstCfg{Addr: dstAddr}, m.(*MockManager), rule.OutgoingOptions)
+		err := parser.MockOutgoing(parserCtx, srcConn, &integrations.ConditionalDstCfg{Addr: dstAddr}, m.(*MockManager), rule.OutgoingOptions)
 		if err != nil {
 			utils.LogError(p.logger, err, "failed to mock the outgoing message")
 			return err
@@ -394,7 +445,6 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return nil
 	}
 
-	reader := bufio.NewReader(srcConn)
 	initialData := make([]byte, 5)
 	// reading the initial data from the client connection to determine if the connection is a TLS handshake
 	testBuffer, err := reader.Peek(len(initialData))
@@ -450,7 +500,7 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 
 	logger := p.logger.With(zap.Any("Client IP Address", srcConn.RemoteAddr().String()), zap.Any("Client ConnectionID", clientID), zap.Any("Destination IP Address", dstAddr), zap.Any("Destination ConnectionID", destID))
 	dstCfg := &integrations.ConditionalDstCfg{
-		Port: uint(destInfo.Port),
+		Port: uint(port),
 	}
 
 	//make new connection to the destination server
@@ -461,8 +511,15 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 			ServerName:         dstURL,
 		}
 
-		addr := fmt.Sprintf("%v:%v", dstURL, destInfo.Port)
+		addr := fmt.Sprintf("%v:%v", dstURL, port)
 		if rule.Mode != models.MODE_TEST {
+			// the plain connection dialed while detecting the protocol is of no use here
+			if dstConn != nil {
+				err = dstConn.Close()
+				if err != nil {
+					utils.LogError(logger, err, "failed to close the destination connection")
+				}
+			}
 			dstConn, err = tls.Dial("tcp", addr, cfg)
 			if err != nil {
 				utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
@@ -474,7 +531,7 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		dstCfg.Addr = addr
 
 	} else {
-		if rule.Mode != models.MODE_TEST {
+		if rule.Mode != models.MODE_TEST && dstConn == nil {
 			dstConn, err = net.Dial("tcp", dstAddr)
 			if err != nil {
 				utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
@@ -485,9 +542,9 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 	}
 
 	// get the mock manager for the current app
-	m, ok := p.MockManagers.Load(destInfo.AppID)
+	m, ok := p.MockManagers.Load(appID)
 	if !ok {
-		utils.LogError(logger, err, "failed to fetch the mock manager", zap.Any("AppID", destInfo.AppID))
+		utils.LogError(logger, err, "failed to fetch the mock manager", zap.Any("AppID", appID))
 		return err
 	}
 
@@ -563,15 +620,23 @@ func (p *Proxy) StopProxyServer(ctx context.Context) {
 	p.logger.Info("proxy stopped...")
 }
 
-func (p *Proxy) Record(_ context.Context, id uint64, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
-	p.sessions.Set(id, &core.Session{
+func (p *Proxy) Record(ctx context.Context, id uint64, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	session := &core.Session{
 		ID:              id,
 		Mode:            models.MODE_RECORD,
 		MC:              mocks,
 		OutgoingOptions: opts,
+	}
+	p.sessions.Set(id, session)
+	p.startProtocols(ctx, session)
+
+	// the dns answers are recorded again for every recording session
+	p.dnsCache.Range(func(k, _ interface{}) bool {
+		p.dnsCache.Delete(k)
+		return true
 	})
 
-	p.MockManagers.Store(id, NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), p.logger))
+	p.MockManagers.Store(id, NewMockManager(NewMockStore(), NewMockStore(), p.logger))
 
 	////set the new proxy ip:port for a new session
 	//err := p.setProxyIP(opts.DnsIPv4Addr, opts.DnsIPv6Addr)
@@ -582,17 +647,22 @@ func (p *Proxy) Record(_ context.Context, id uint64, mocks chan<- *models.Mock,
 	return nil
 }
 
-func (p *Proxy) Mock(_ context.Context, id uint64, opts models.OutgoingOptions) error {
-	p.sessions.Set(id, &core.Session{
+func (p *Proxy) Mock(ctx context.Context, id uint64, opts models.OutgoingOptions) error {
+	session := &core.Session{
 		ID:              id,
 		Mode:            models.MODE_TEST,
 		OutgoingOptions: opts,
-	})
-	p.MockManagers.Store(id, NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), p.logger))
+	}
+	p.sessions.Set(id, session)
+	p.startProtocols(ctx, session)
+	p.MockManagers.Store(id, NewMockManager(NewMockStore(), NewMockStore(), p.logger))
 
 	if !opts.Mocking {
 		p.logger.Info("🔀 Mocking is disabled, the response will be fetched from the actual service")
 	}
+	if opts.RecordOnMiss {
+		p.recordOnMissWarning.Do(p.warnRecordOnMiss)
+	}
 
 	if string(p.nsswitchData) == "" {
 		// setup the nsswitch config to redirect the DNS queries to the proxy
@@ -612,6 +682,22 @@ func (p *Proxy) Mock(_ context.Context, id uint64, opts models.OutgoingOptions)
 	return nil
 }
 
+// warnRecordOnMiss reports the parsers which keep failing the requests no mock matched, the ones of the
+// stateful protocols can't hand a connection over to the destination server once its handshake was mocked.
+func (p *Proxy) warnRecordOnMiss() {
+	var unsupported []string
+	for name := range p.Integrations {
+		if !integrations.RecordsOnMiss(name) {
+			unsupported = append(unsupported, name)
+		}
+	}
+	if len(unsupported) == 0 {
+		return
+	}
+	sort.Strings(unsupported)
+	p.logger.Warn("--record-on-miss is not supported by some of the parsers, the requests they find no mock for are not recorded", zap.Strings("parsers", unsupported))
+}
+
 func (p *Proxy) SetMocks(_ context.Context, id uint64, filtered []*models.Mock, unFiltered []*models.Mock) error {
 	//session, ok := p.sessions.Get(id)
 	//if !ok {
@@ -634,3 +720,21 @@ func (p *Proxy) GetConsumedMocks(_ context.Context, id uint64) ([]string, error)
 	}
 	return m.(*MockManager).GetConsumedMocks(), nil
 }
+
+// GetUnmatchedCalls returns the outgoing calls that no mock matched since the last call, for a given app id
+func (p *Proxy) GetUnmatchedCalls(_ context.Context, id uint64) ([]models.UnmatchedCall, error) {
+	m, ok := p.MockManagers.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("mock manager not found to get the unmatched calls")
+	}
+	return m.(*MockManager).GetUnmatchedCalls(), nil
+}
+
+// GetRecordedMocks returns the mocks recorded on a miss since the last call, for a given app id
+func (p *Proxy) GetRecordedMocks(_ context.Context, id uint64) ([]*models.Mock, error) {
+	m, ok := p.MockManagers.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("mock manager not found to get the recorded mocks")
+	}
+	return m.(*MockManager).GetRecordedMocks(), nil
+}
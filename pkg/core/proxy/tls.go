@@ -19,16 +19,8 @@ func isTLSHandshake(data []byte) bool {
 
 func (p *Proxy) handleTLSConnection(conn net.Conn) (net.Conn, error) {
 	//Load the CA certificate and private key
-
-	var err error
-	caPrivKey, err = helpers.ParsePrivateKeyPEM(caPKey)
-	if err != nil {
-		utils.LogError(p.logger, err, "Failed to parse CA private key")
-		return nil, err
-	}
-	caCertParsed, err = helpers.ParseCertificatePEM(caCrt)
+	err := p.loadCA()
 	if err != nil {
-		utils.LogError(p.logger, err, "Failed to parse CA certificate")
 		return nil, err
 	}
 
@@ -52,3 +44,33 @@ func (p *Proxy) handleTLSConnection(conn net.Conn) (net.Conn, error) {
 	// Here, we simply close the conn
 	return tlsConn, nil
 }
+
+// loadCA parses the CA certificate and private key signing the certificates of the servers.
+func (p *Proxy) loadCA() error {
+	var err error
+	caPrivKey, err = helpers.ParsePrivateKeyPEM(caPKey)
+	if err != nil {
+		utils.LogError(p.logger, err, "Failed to parse CA private key")
+		return err
+	}
+	caCertParsed, err = helpers.ParseCertificatePEM(caCrt)
+	if err != nil {
+		utils.LogError(p.logger, err, "Failed to parse CA certificate")
+		return err
+	}
+	return nil
+}
+
+// tlsConfig returns the config terminating the TLS of the client, for the integrations negotiating the TLS
+// within their protocol (e.g. the PRELOGIN of mssql). The CA is loaded once the client starts the handshake.
+func (p *Proxy) tlsConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			err := p.loadCA()
+			if err != nil {
+				return nil, err
+			}
+			return certForClient(clientHello)
+		},
+	}
+}
@@ -276,26 +276,9 @@ func ReadFromPeer(ctx context.Context, logger *zap.Logger, conn net.Conn, buffCh
 // It also closes the destination connection if the function returns an error.
 func PassThrough(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, requestBuffer [][]byte) ([]byte, error) {
 	logger.Debug("passing through the network traffic to the destination server", zap.Any("Destination Addr", dstCfg.Addr))
-	// making destConn
-	var destConn net.Conn
-	var err error
-	if dstCfg.TLSCfg != nil {
-		logger.Debug("trying to establish a TLS connection with the destination server", zap.Any("Destination Addr", dstCfg.Addr))
-
-		destConn, err = tls.Dial("tcp", dstCfg.Addr, dstCfg.TLSCfg)
-		if err != nil {
-			utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("server address", dstCfg.Addr))
-			return nil, err
-		}
-		logger.Debug("TLS connection established with the destination server", zap.Any("Destination Addr", destConn.RemoteAddr().String()))
-	} else {
-		logger.Debug("trying to establish a connection with the destination server", zap.Any("Destination Addr", dstCfg.Addr))
-		destConn, err = net.Dial("tcp", dstCfg.Addr)
-		if err != nil {
-			utils.LogError(logger, err, "failed to dial the destination server")
-			return nil, err
-		}
-		logger.Debug("connection established with the destination server", zap.Any("Destination Addr", destConn.RemoteAddr().String()))
+	destConn, err := dialDst(logger, dstCfg)
+	if err != nil {
+		return nil, err
 	}
 
 	logger.Debug("trying to forward requests to target", zap.Any("Destination Addr", destConn.RemoteAddr().String()))
@@ -351,6 +334,90 @@ func PassThrough(ctx context.Context, logger *zap.Logger, clientConn net.Conn, d
 	return nil, nil
 }
 
+func dialDst(logger *zap.Logger, dstCfg *integrations.ConditionalDstCfg) (net.Conn, error) {
+	if dstCfg.TLSCfg != nil {
+		logger.Debug("trying to establish a TLS connection with the destination server", zap.Any("Destination Addr", dstCfg.Addr))
+
+		destConn, err := tls.Dial("tcp", dstCfg.Addr, dstCfg.TLSCfg)
+		if err != nil {
+			utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("server address", dstCfg.Addr))
+			return nil, err
+		}
+		logger.Debug("TLS connection established with the destination server", zap.Any("Destination Addr", destConn.RemoteAddr().String()))
+		return destConn, nil
+	}
+	logger.Debug("trying to establish a connection with the destination server", zap.Any("Destination Addr", dstCfg.Addr))
+	destConn, err := net.Dial("tcp", dstCfg.Addr)
+	if err != nil {
+		utils.LogError(logger, err, "failed to dial the destination server")
+		return nil, err
+	}
+	logger.Debug("connection established with the destination server", zap.Any("Destination Addr", destConn.RemoteAddr().String()))
+	return destConn, nil
+}
+
+// EncodeFunc is the record path of an integration, it encodes the calls on the connection into mocks
+// starting with the request already read from the client.
+type EncodeFunc func(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error
+
+// AutoAddedKey is the metadata key of the mocks recorded on a miss during a test run
+const AutoAddedKey = "autoAdded"
+
+// RecordOnMiss hands the connection over to the record path of the integration once no mock matched
+// a request of it in test mode. The request is sent to the destination server and the rest of the
+// connection is recorded, the mocks are tagged as auto-added and kept by the mock db to be appended
+// to the test-set.
+func RecordOnMiss(ctx context.Context, logger *zap.Logger, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, requestBuffer [][]byte, mockDb integrations.MockMemDb, opts models.OutgoingOptions, encode EncodeFunc) error {
+	logger.Debug("recording the connection on a mock miss", zap.Any("Destination Addr", dstCfg.Addr))
+	destConn, err := dialDst(logger, dstCfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := destConn.Close()
+		if err != nil {
+			utils.LogError(logger, err, "failed to close the destination connection")
+		}
+	}()
+
+	mocks := make(chan *models.Mock, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	add := func(mock *models.Mock) {
+		if mock.Spec.Metadata == nil {
+			mock.Spec.Metadata = map[string]string{}
+		}
+		mock.Spec.Metadata[AutoAddedKey] = "true"
+		mockDb.AddRecordedMock(mock)
+	}
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case mock := <-mocks:
+				add(mock)
+			case <-stop:
+				// the mocks sent just before the record path returned
+				for {
+					select {
+					case mock := <-mocks:
+						add(mock)
+					default:
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	err = encode(ctx, logger, bytes.Join(requestBuffer, nil), clientConn, destConn, mocks, opts)
+	close(stop)
+	<-done
+	return err
+}
+
 // ToIP4AddressStr converts the integer IP4 Address to the octet format
 func ToIP4AddressStr(ip uint32) string {
 	// convert the IP address to a 32-bit binary number
@@ -546,3 +613,24 @@ func Recover(logger *zap.Logger, client, dest net.Conn) {
 		sentry.Flush(time.Second * 2)
 	}
 }
+
+// summaryLimit is the length of the summaries of the requests that no mock matched
+const summaryLimit = 256
+
+// Summarize returns a readable summary of a request given by its raw buffers, the bytes which aren't
+// printable are replaced by dots and the summary is cut at summaryLimit bytes.
+func Summarize(buffers [][]byte) string {
+	var sb strings.Builder
+	for _, buf := range buffers {
+		for _, b := range buf {
+			if sb.Len() >= summaryLimit {
+				return sb.String() + "..."
+			}
+			if b < 0x20 || b > 0x7e {
+				b = '.'
+			}
+			sb.WriteByte(b)
+		}
+	}
+	return sb.String()
+}
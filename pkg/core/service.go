@@ -20,6 +20,8 @@ type Hooks interface {
 	OutgoingInfo
 	Load(ctx context.Context, id uint64, cfg HookCfg) error
 	Record(ctx context.Context, id uint64, opts models.IncomingOptions) (<-chan *models.TestCase, error)
+	// CaptureIncoming assembles the calls received by the app on a connection of a packet capture into test cases
+	CaptureIncoming(ctx context.Context, captured *models.CapturedConn, opts models.IncomingOptions) <-chan *models.TestCase
 }
 
 type HookCfg struct {
@@ -45,6 +47,14 @@ type Proxy interface {
 	Mock(ctx context.Context, id uint64, opts models.OutgoingOptions) error
 	SetMocks(ctx context.Context, id uint64, filtered []*models.Mock, unFiltered []*models.Mock) error
 	GetConsumedMocks(ctx context.Context, id uint64) ([]string, error)
+	GetUnmatchedCalls(ctx context.Context, id uint64) ([]models.UnmatchedCall, error)
+	GetRecordedMocks(ctx context.Context, id uint64) ([]*models.Mock, error)
+	GetProtocols(ctx context.Context, id uint64) (map[uint32]string, error)
+	// SetAppIP sets the ip of the app container, the dns queries sent from it are attributed to the app
+	SetAppIP(id uint64, ip string)
+	ServeMocks(ctx context.Context, opts models.MockServerOptions, filtered []*models.Mock, unFiltered []*models.Mock) error
+	// CaptureOutgoing decodes the calls made by the app on a connection of a packet capture into mocks
+	CaptureOutgoing(ctx context.Context, captured *models.CapturedConn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error
 }
 
 type ProxyOptions struct {
@@ -121,6 +131,14 @@ func (s *Sessions) getAll() map[uint64]*Session {
 	return sessions
 }
 
+func (s *Sessions) GetAll() []*Session {
+	var sessions []*Session
+	for _, session := range s.getAll() {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
 func (s *Sessions) GetAllMC() []chan<- *models.Mock {
 	sessions := s.getAll()
 	var mc []chan<- *models.Mock
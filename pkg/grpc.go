@@ -0,0 +1,163 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/protocolbuffers/protoscope"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+)
+
+// constants for the grpc pseudo headers
+const (
+	GrpcAuthority = ":authority"
+	GrpcMethod    = ":method"
+	GrpcPath      = ":path"
+	GrpcScheme    = ":scheme"
+	GrpcStatus    = ":status"
+)
+
+// CreateLengthPrefixedMessage decodes the body of a DATA frame into a grpc length prefixed message.
+func CreateLengthPrefixedMessage(data []byte) models.GrpcLengthPrefixedMessage {
+	msg := models.GrpcLengthPrefixedMessage{}
+
+	// If the body is not length prefixed, we return the default value.
+	if len(data) < 5 {
+		return msg
+	}
+
+	// The first byte is the compression flag.
+	msg.CompressionFlag = uint(data[0])
+
+	// The next 4 bytes are message length.
+	msg.MessageLength = binary.BigEndian.Uint32(data[1:5])
+
+	// Use protoscope to decode the message.
+	msg.DecodedData = protoscope.Write(data[5:], protoscope.WriterOptions{})
+
+	return msg
+}
+
+// CreatePayloadFromLengthPrefixedMessage encodes the grpc length prefixed message back into the body of a DATA frame.
+func CreatePayloadFromLengthPrefixedMessage(msg models.GrpcLengthPrefixedMessage) ([]byte, error) {
+	scanner := protoscope.NewScanner(msg.DecodedData)
+	encodedData, err := scanner.Exec()
+	if err != nil {
+		return nil, fmt.Errorf("could not encode grpc msg using protoscope: %v", err)
+	}
+
+	// Note that the encoded length is present in the msg, but it is also equal to the len of encodedData.
+	// We should give the preference to the length of encodedData, since the mocks might have been altered.
+
+	// Reserve 1 byte for compression flag, 4 bytes for length capture.
+	payload := make([]byte, 1+4)
+	payload[0] = uint8(msg.CompressionFlag)
+	binary.BigEndian.PutUint32(payload[1:5], uint32(len(encodedData)))
+	payload = append(payload, encodedData...)
+
+	return payload, nil
+}
+
+// SimulateGRPC replays the recorded grpc request of the test case over HTTP/2 (h2c) against the user application.
+func SimulateGRPC(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64) (*models.GrpcResp, error) {
+	err := renderTestCaseTemplate(tc, testSet, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
+
+	authority := tc.GrpcReq.Headers.PseudoHeaders[GrpcAuthority]
+	path := tc.GrpcReq.Headers.PseudoHeaders[GrpcPath]
+	if authority == "" || path == "" {
+		return nil, fmt.Errorf("grpc testcase %s has no :authority or :path pseudo header", tc.Name)
+	}
+
+	payload, err := CreatePayloadFromLengthPrefixedMessage(tc.GrpcReq.Body)
+	if err != nil {
+		utils.LogError(logger, err, "failed to create the grpc payload from the yaml document")
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+authority+path, bytes.NewReader(payload))
+	if err != nil {
+		utils.LogError(logger, err, "failed to create a grpc request from the yaml document")
+		return nil, err
+	}
+	for k, v := range tc.GrpcReq.Headers.OrdinaryHeaders {
+		// hop-by-hop and framing headers are managed by the http2 transport itself
+		if strings.EqualFold(k, "content-length") || strings.EqualFold(k, "te") {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("te", "trailers")
+	req.Header.Set("KEPLOY-TEST-ID", tc.Name)
+	req.Header.Set("KEPLOY-TEST-SET-ID", testSet)
+	logger.Debug(fmt.Sprintf("Sending grpc request to user app:%v", req))
+
+	// grpc servers which are recorded through keploy are served in plain text (h2c)
+	client := &http.Client{
+		Timeout: time.Second * time.Duration(apiTimeout),
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	grpcResp, err := client.Do(req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to send testcase request to app")
+		return nil, err
+	}
+	defer func() {
+		err := grpcResp.Body.Close()
+		if err != nil {
+			utils.LogError(logger, err, "failed to close the grpc response body")
+		}
+	}()
+
+	respBody, err := io.ReadAll(grpcResp.Body)
+	if err != nil {
+		utils.LogError(logger, err, "failed reading grpc response body")
+		return nil, err
+	}
+
+	resp := models.NewGrpcStream(0).GrpcResp
+	resp.Timestamp = time.Now()
+	resp.Body = CreateLengthPrefixedMessage(respBody)
+
+	// A "trailers-only" response (usually an error) is sent as a single HEADERS frame which
+	// is recorded as trailers, hence it is mapped the same way here.
+	if len(respBody) == 0 && len(grpcResp.Trailer) == 0 && grpcResp.Header.Get("grpc-status") != "" {
+		resp.Trailers.PseudoHeaders[GrpcStatus] = strconv.Itoa(grpcResp.StatusCode)
+		for k, v := range grpcResp.Header {
+			resp.Trailers.OrdinaryHeaders[strings.ToLower(k)] = strings.Join(v, ",")
+		}
+		return &resp, nil
+	}
+
+	resp.Headers.PseudoHeaders[GrpcStatus] = strconv.Itoa(grpcResp.StatusCode)
+	for k, v := range grpcResp.Header {
+		resp.Headers.OrdinaryHeaders[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	for k, v := range grpcResp.Trailer {
+		resp.Trailers.OrdinaryHeaders[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	return &resp, nil
+}
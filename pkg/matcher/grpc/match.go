@@ -0,0 +1,223 @@
+// Package grpc for grpc matching
+package grpc
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/k0kubun/pp/v3"
+	"go.keploy.io/server/v2/pkg"
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// fieldRegex matches a single field of the protoscope text format, e.g. `1: 150` or `2: {`.
+var fieldRegex = regexp.MustCompile(`^(\d+):\s*(.*)$`)
+
+// Match compares the recorded grpc response of the test case with the actual response, field by field.
+// The noise of the test case and the global noise are applied on the headers, the trailers and the body fields.
+func Match(tc *models.TestCase, actualResponse *models.GrpcResp, noiseConfig map[string]map[string][]string, logger *zap.Logger) (bool, *models.Result) {
+	pass := true
+	hRes := &[]models.HeaderResult{}
+	tRes := &[]models.HeaderResult{}
+
+	expStatus, _ := strconv.Atoi(grpcStatus(tc.GrpcResp))
+	actStatus, _ := strconv.Atoi(grpcStatus(*actualResponse))
+	res := &models.Result{
+		StatusCode: models.IntResult{
+			Normal:   expStatus == actStatus,
+			Expected: expStatus,
+			Actual:   actStatus,
+		},
+		BodyResult: []models.BodyResult{{
+			Normal:   false,
+			Type:     models.BodyTypePlain,
+			Expected: tc.GrpcResp.Body.DecodedData,
+			Actual:   actualResponse.Body.DecodedData,
+		}},
+	}
+
+	var (
+		bodyNoise   = map[string][]string{}
+		headerNoise = map[string][]string{}
+	)
+	for field, regexArr := range noiseConfig["body"] {
+		bodyNoise[strings.ToLower(field)] = regexArr
+	}
+	for field, regexArr := range noiseConfig["header"] {
+		headerNoise[strings.ToLower(field)] = regexArr
+	}
+
+	for field, regexArr := range tc.Noise {
+		a := strings.Split(field, ".")
+		if len(a) > 1 && a[0] == "body" {
+			bodyNoise[strings.ToLower(strings.Join(a[1:], "."))] = regexArr
+		} else if a[0] == "header" || a[0] == "trailer" {
+			headerNoise[strings.ToLower(a[len(a)-1])] = regexArr
+		}
+	}
+
+	if !res.StatusCode.Normal {
+		pass = false
+	}
+
+	bodyPass := true
+	if _, ok := tc.Noise["body"]; !ok {
+		bodyPass = tc.GrpcResp.Body.CompressionFlag == actualResponse.Body.CompressionFlag &&
+			compareFields(FlattenMessage(tc.GrpcResp.Body.DecodedData), FlattenMessage(actualResponse.Body.DecodedData), bodyNoise)
+	}
+	res.BodyResult[0].Normal = bodyPass
+	pass = pass && bodyPass
+
+	if !matcherUtils.CompareHeaders(pkg.ToHTTPHeader(tc.GrpcResp.Headers.OrdinaryHeaders), pkg.ToHTTPHeader(actualResponse.Headers.OrdinaryHeaders), hRes, headerNoise) {
+		pass = false
+	}
+	res.HeadersResult = *hRes
+
+	if !matcherUtils.CompareHeaders(pkg.ToHTTPHeader(tc.GrpcResp.Trailers.OrdinaryHeaders), pkg.ToHTTPHeader(actualResponse.Trailers.OrdinaryHeaders), tRes, headerNoise) {
+		pass = false
+	}
+	res.TrailerResult = *tRes
+
+	newLogger := pp.New()
+	newLogger.WithLineInfo = false
+	if !pass {
+		logDiffs := matcherUtils.NewDiffsPrinter(tc.Name)
+		newLogger.SetColorScheme(models.GetFailingColorScheme())
+		logs := newLogger.Sprintf("Testrun failed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name)
+
+		if !res.StatusCode.Normal {
+			logDiffs.PushStatusDiff(fmt.Sprint(res.StatusCode.Expected), fmt.Sprint(res.StatusCode.Actual))
+		}
+		for _, results := range [][]models.HeaderResult{res.HeadersResult, res.TrailerResult} {
+			for _, j := range results {
+				if !j.Normal {
+					logDiffs.PushHeaderDiff(fmt.Sprint(j.Expected.Value), fmt.Sprint(j.Actual.Value), j.Expected.Key+j.Actual.Key, headerNoise)
+				}
+			}
+		}
+		if !res.BodyResult[0].Normal {
+			logDiffs.PushBodyDiff(tc.GrpcResp.Body.DecodedData, actualResponse.Body.DecodedData, bodyNoise)
+		}
+
+		_, err := newLogger.Printf(logs)
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+		err = logDiffs.Render()
+		if err != nil {
+			utils.LogError(logger, err, "failed to render the diffs")
+		}
+	} else {
+		newLogger.SetColorScheme(models.GetPassingColorScheme())
+		_, err := newLogger.Printf(newLogger.Sprintf("Testrun passed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name))
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+	}
+	return pass, res
+}
+
+// grpcStatus returns the status of the grpc call. The grpc-status trailer is preferred over
+// the http :status, since grpc errors are sent with a 200 http status.
+func grpcStatus(resp models.GrpcResp) string {
+	if status, ok := resp.Trailers.OrdinaryHeaders["grpc-status"]; ok {
+		return status
+	}
+	if status, ok := resp.Headers.OrdinaryHeaders["grpc-status"]; ok {
+		return status
+	}
+	return "0"
+}
+
+// FlattenMessage converts a protoscope encoded message into a map where the nested fields are
+// replaced by dot-delimited field numbers, e.g. `3: {1: 5}` becomes {"3.1": ["5"]}.
+// Repeated fields get all of their values in the same key.
+func FlattenMessage(decoded string) map[string][]string {
+	fields := map[string][]string{}
+	var path []string
+	for _, line := range strings.Split(decoded, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "}" {
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+			continue
+		}
+		m := fieldRegex.FindStringSubmatch(line)
+		if m == nil {
+			// continuation of the value of the current field (e.g. a long string or bytes)
+			key := strings.Join(path, ".")
+			fields[key] = append(fields[key], line)
+			continue
+		}
+		key := strings.Join(append(path, m[1]), ".")
+		if m[2] == "{" {
+			path = append(path, m[1])
+			continue
+		}
+		fields[key] = append(fields[key], m[2])
+	}
+	return fields
+}
+
+// compareFields compares the flattened fields of both the messages, ignoring the noisy ones.
+func compareFields(expected, actual map[string][]string, noise map[string][]string) bool {
+	keys := map[string]bool{}
+	for k := range expected {
+		keys[k] = true
+	}
+	for k := range actual {
+		keys[k] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, k := range sortedKeys {
+		if isNoisyField(k, actual[k], noise) {
+			continue
+		}
+		exp, act := expected[k], actual[k]
+		if len(exp) != len(act) {
+			return false
+		}
+		for i := range exp {
+			if exp[i] != act[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isNoisyField checks whether the field, or any of its parent messages, is marked as noise.
+func isNoisyField(key string, values []string, noise map[string][]string) bool {
+	parts := strings.Split(key, ".")
+	for i := len(parts); i > 0; i-- {
+		regexArr, ok := noise[strings.Join(parts[:i], ".")]
+		if !ok {
+			continue
+		}
+		if len(regexArr) == 0 {
+			return true
+		}
+		for _, v := range values {
+			if isNoisy, _ := matcherUtils.MatchesAnyRegex(v, regexArr); !isNoisy {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+// Package websocket for websocket matching
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/k0kubun/pp/v3"
+	"go.keploy.io/server/v2/pkg"
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// Match compares the recorded handshake and messages of the websocket test case with the ones of the application.
+// The messages sent by the server are compared in order, at their index in the log. The noise "websocket.<index>"
+// ignores a message, "websocket.<index>.<field>" a field of its json data; the global body noise applies to the
+// json data of every message, the header noise to the handshake.
+func Match(tc *models.TestCase, actualResponse *models.WebSocketResp, noiseConfig map[string]map[string][]string, ignoreOrdering bool, logger *zap.Logger) (bool, *models.Result) {
+	pass := true
+	hRes := &[]models.HeaderResult{}
+	res := &models.Result{
+		StatusCode: models.IntResult{
+			Normal:   tc.HTTPResp.StatusCode == actualResponse.Handshake.StatusCode,
+			Expected: tc.HTTPResp.StatusCode,
+			Actual:   actualResponse.Handshake.StatusCode,
+		},
+	}
+
+	var (
+		bodyNoise    = map[string][]string{}
+		headerNoise  = map[string][]string{}
+		messageNoise = map[int]map[string][]string{}
+	)
+	for field, regexArr := range noiseConfig["body"] {
+		bodyNoise[strings.ToLower(field)] = regexArr
+	}
+	for field, regexArr := range noiseConfig["header"] {
+		headerNoise[strings.ToLower(field)] = regexArr
+	}
+	for field, regexArr := range tc.Noise {
+		a := strings.Split(field, ".")
+		if a[0] == "header" {
+			headerNoise[strings.ToLower(a[len(a)-1])] = regexArr
+			continue
+		}
+		if a[0] != "websocket" || len(a) < 2 {
+			continue
+		}
+		index, err := strconv.Atoi(a[1])
+		if err != nil {
+			continue
+		}
+		if messageNoise[index] == nil {
+			messageNoise[index] = map[string][]string{}
+		}
+		// the whole message is noisy when no field is given
+		messageNoise[index][strings.ToLower(strings.Join(a[2:], "."))] = regexArr
+	}
+
+	if !res.StatusCode.Normal {
+		pass = false
+	}
+	if !matcherUtils.CompareHeaders(pkg.ToHTTPHeader(tc.HTTPResp.Header), pkg.ToHTTPHeader(actualResponse.Handshake.Header), hRes, headerNoise) {
+		pass = false
+	}
+	res.HeadersResult = *hRes
+
+	for i, expected := range tc.WebSocket {
+		if expected.From != models.WebSocketFromServer {
+			continue
+		}
+		result := models.WebSocketResult{Index: i, Expected: expected}
+		if i < len(actualResponse.Messages) {
+			result.Actual = actualResponse.Messages[i]
+		}
+		noise := map[string][]string{}
+		for field, regexArr := range bodyNoise {
+			noise[field] = regexArr
+		}
+		for field, regexArr := range messageNoise[i] {
+			noise[field] = regexArr
+		}
+		if _, ok := messageNoise[i][""]; ok {
+			result.Normal = true
+		} else {
+			result.Normal = matchMessage(logger, expected, result.Actual, noise, ignoreOrdering)
+		}
+		res.WebSocketResult = append(res.WebSocketResult, result)
+		pass = pass && result.Normal
+	}
+
+	newLogger := pp.New()
+	newLogger.WithLineInfo = false
+	if !pass {
+		logDiffs := matcherUtils.NewDiffsPrinter(tc.Name)
+		newLogger.SetColorScheme(models.GetFailingColorScheme())
+		logs := newLogger.Sprintf("Testrun failed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name)
+
+		if !res.StatusCode.Normal {
+			logDiffs.PushStatusDiff(fmt.Sprint(res.StatusCode.Expected), fmt.Sprint(res.StatusCode.Actual))
+		}
+		for _, j := range res.HeadersResult {
+			if !j.Normal {
+				logDiffs.PushHeaderDiff(fmt.Sprint(j.Expected.Value), fmt.Sprint(j.Actual.Value), j.Expected.Key+j.Actual.Key, headerNoise)
+			}
+		}
+		// the diff of the first unmatched message is rendered, the messages following it often differ because of it
+		for _, result := range res.WebSocketResult {
+			if result.Normal {
+				continue
+			}
+			logs += fmt.Sprintf("websocket message %d sent by the server didn't match\n\n", result.Index)
+			if result.Expected.Type == models.WebSocketText && result.Actual.Type == models.WebSocketText {
+				logDiffs.PushBodyDiff(result.Expected.Data, result.Actual.Data, messageNoise[result.Index])
+			} else {
+				logDiffs.PushBodyDiff(messageSummary(result.Expected), messageSummary(result.Actual), messageNoise[result.Index])
+			}
+			break
+		}
+
+		_, err := newLogger.Printf(logs)
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+		err = logDiffs.Render()
+		if err != nil {
+			utils.LogError(logger, err, "failed to render the diffs")
+		}
+	} else {
+		newLogger.SetColorScheme(models.GetPassingColorScheme())
+		_, err := newLogger.Printf(newLogger.Sprintf("Testrun passed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name))
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+	}
+	return pass, res
+}
+
+// matchMessage compares a message sent by the server with the recorded one. The json data of the text messages
+// is compared field by field, ignoring the noisy fields.
+func matchMessage(logger *zap.Logger, expected, actual models.WebSocketMessage, noise map[string][]string, ignoreOrdering bool) bool {
+	if expected.Type != models.WebSocketText || actual.Type != models.WebSocketText ||
+		!json.Valid([]byte(expected.Data)) || !json.Valid([]byte(actual.Data)) {
+		return pkg.SameWebSocketMessage(expected, actual)
+	}
+	exp, act := expected.Data, actual.Data
+	validatedJSON, err := matcherUtils.ValidateAndMarshalJSON(logger, &exp, &act)
+	if err != nil || !validatedJSON.IsIdentical() {
+		return false
+	}
+	jsonComparisonResult, err := matcherUtils.JSONDiffWithNoiseControl(validatedJSON, noise, ignoreOrdering)
+	if err != nil {
+		return false
+	}
+	return jsonComparisonResult.IsExact()
+}
+
+// messageSummary describes a message for the diffs, a message missing from the log is empty.
+func messageSummary(msg models.WebSocketMessage) string {
+	if msg.Type == "" {
+		return ""
+	}
+	if msg.Type == models.WebSocketClose {
+		return fmt.Sprintf("%s %d %s", msg.Type, msg.CloseCode, msg.Data)
+	}
+	return fmt.Sprintf("%s %s", msg.Type, msg.Data)
+}
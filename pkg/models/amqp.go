@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type AMQPSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Request          AMQPCommand       `json:"request" yaml:"request"`
+	Response         []AMQPCommand     `json:"response,omitempty" yaml:"response,omitempty"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// AMQPCommand is a method frame of AMQP 0-9-1, along with the content header and the body frames of the
+// methods carrying a message (basic.publish, basic.deliver, basic.get-ok and basic.return). The arguments
+// of the method and the properties of the message are decoded to match the mocks, the raw arguments and
+// content header are stored as base64 to replay the commands of the broker.
+type AMQPCommand struct {
+	Channel    uint16                 `json:"channel" yaml:"channel"`
+	Method     string                 `json:"method" yaml:"method"`
+	ClassID    uint16                 `json:"classId" yaml:"classId"`
+	MethodID   uint16                 `json:"methodId" yaml:"methodId"`
+	Fields     map[string]interface{} `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Arguments  string                 `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Header     string                 `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       string                 `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyType   BodyType               `json:"bodyType,omitempty" yaml:"bodyType,omitempty"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+type CassandraSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Request          CassandraRequest  `json:"request" yaml:"request"`
+	Response         CassandraResponse `json:"response" yaml:"response"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// CassandraRequest is a message sent by a client of the CQL native protocol. The statements of the QUERY,
+// PREPARE, EXECUTE and BATCH messages are stored along with their consistency and their bound values to
+// match the mocks, an EXECUTE holds the statement it was prepared from. The body of the other messages is
+// stored as base64.
+type CassandraRequest struct {
+	Version           uint8                `json:"version" yaml:"version"`
+	Opcode            string               `json:"opcode" yaml:"opcode"`
+	StreamID          int16                `json:"streamId" yaml:"streamId"`
+	Query             string               `json:"query,omitempty" yaml:"query,omitempty"`
+	PreparedID        string               `json:"preparedId,omitempty" yaml:"preparedId,omitempty"`
+	Keyspace          string               `json:"keyspace,omitempty" yaml:"keyspace,omitempty"`
+	Consistency       string               `json:"consistency,omitempty" yaml:"consistency,omitempty"`
+	SerialConsistency string               `json:"serialConsistency,omitempty" yaml:"serialConsistency,omitempty"`
+	Values            []CassandraValue     `json:"values,omitempty" yaml:"values,omitempty"`
+	PageSize          int32                `json:"pageSize,omitempty" yaml:"pageSize,omitempty"`
+	PagingState       string               `json:"pagingState,omitempty" yaml:"pagingState,omitempty"`
+	BatchType         string               `json:"batchType,omitempty" yaml:"batchType,omitempty"`
+	Statements        []CassandraStatement `json:"statements,omitempty" yaml:"statements,omitempty"`
+	Options           map[string]string    `json:"options,omitempty" yaml:"options,omitempty"`
+	Body              string               `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// CassandraStatement is a statement of a BATCH, either a query or a prepared statement.
+type CassandraStatement struct {
+	Query      string           `json:"query" yaml:"query"`
+	PreparedID string           `json:"preparedId,omitempty" yaml:"preparedId,omitempty"`
+	Values     []CassandraValue `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// CassandraValue is a bound value, decoded by the type of its variable when the statement was prepared. The
+// values of the unknown types are stored as base64.
+type CassandraValue struct {
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Type  string `json:"type,omitempty" yaml:"type,omitempty"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	Null  bool   `json:"null,omitempty" yaml:"null,omitempty"`
+	Unset bool   `json:"unset,omitempty" yaml:"unset,omitempty"`
+}
+
+// CassandraResponse is the message the server replied with. The kind of the results, the prepared ids and
+// the rows are decoded for the readers of the mocks, the body is replayed as it is with the stream id of the
+// request.
+type CassandraResponse struct {
+	Opcode     string            `json:"opcode" yaml:"opcode"`
+	Flags      uint8             `json:"flags,omitempty" yaml:"flags,omitempty"`
+	Kind       string            `json:"kind,omitempty" yaml:"kind,omitempty"`
+	PreparedID string            `json:"preparedId,omitempty" yaml:"preparedId,omitempty"`
+	Variables  []CassandraColumn `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Columns    []CassandraColumn `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Rows       [][]string        `json:"rows,omitempty" yaml:"rows,omitempty"`
+	ErrorCode  int32             `json:"errorCode,omitempty" yaml:"errorCode,omitempty"`
+	Message    string            `json:"message,omitempty" yaml:"message,omitempty"`
+	Body       string            `json:"body" yaml:"body"`
+}
+
+type CassandraColumn struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
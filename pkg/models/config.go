@@ -6,6 +6,7 @@ type TestSet struct {
 	PostScript   string                 `json:"post_script" bson:"post_script" yaml:"postScript"`
 	Template     map[string]interface{} `json:"template" bson:"template" yaml:"template"`
 	MockRegistry *MockRegistry          `yaml:"mockRegistry" bson:"mock_registry" json:"mockRegistry,omitempty"`
+	Protocols    map[uint32]string      `json:"protocols,omitempty" bson:"protocols,omitempty" yaml:"protocols,omitempty"` // protocols detected on the destination ports while recording
 }
 
 type MockRegistry struct {
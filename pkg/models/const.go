@@ -126,3 +126,7 @@ type contextKey string
 const ErrGroupKey contextKey = "errGroup"
 const ClientConnectionIDKey contextKey = "clientConnectionId"
 const DestConnectionIDKey contextKey = "destConnectionId"
+
+// TLSConfigKey holds the *tls.Config terminating the TLS of the client, for the protocols negotiating it
+// after their first messages
+const TLSConfigKey contextKey = "tlsConfig"
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+type DNSSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Request          DNSReq            `json:"request" yaml:"request"`
+	Response         DNSResp           `json:"response" yaml:"response"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// DNSReq is a question of a DNS query, e.g. _grpc._tcp.example.com. SRV IN
+type DNSReq struct {
+	Name   string `json:"name" yaml:"name"`
+	Qtype  string `json:"qtype" yaml:"qtype"`
+	Qclass string `json:"qclass" yaml:"qclass"`
+}
+
+// DNSResp holds the answers of a question in the zone file format, e.g. "example.com. 3600 IN A 93.184.216.34"
+type DNSResp struct {
+	Rcode   int      `json:"rcode" yaml:"rcode"`
+	Answers []string `json:"answers,omitempty" yaml:"answers,omitempty"`
+}
@@ -24,6 +24,7 @@ type HTTPSchema struct {
 	Request          HTTPReq                `json:"req" yaml:"req"`
 	Response         HTTPResp               `json:"resp" yaml:"resp"`
 	Objects          []*OutputBinary        `json:"objects" yaml:"objects"`
+	WebSocket        []WebSocketMessage     `json:"websocket" yaml:"websocket,omitempty"`
 	Assertions       map[string]interface{} `json:"assertions" yaml:"assertions,omitempty"`
 	Created          int64                  `json:"created" yaml:"created,omitempty"`
 	ReqTimestampMock time.Time              `json:"reqTimestampMock" yaml:"reqTimestampMock,omitempty"`
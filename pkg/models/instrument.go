@@ -16,9 +16,20 @@ type OutgoingOptions struct {
 	Rules         []config.BypassRule
 	MongoPassword string
 	// TODO: role of SQLDelay should be mentioned in the comments.
-	SQLDelay       time.Duration // This is the same as Application delay.
-	FallBackOnMiss bool          // this enables to pass the request to the actual server if no mock is found during test mode.
-	Mocking        bool          // used to enable/disable mocking
+	SQLDelay       time.Duration       // This is the same as Application delay.
+	FallBackOnMiss bool                // this enables to pass the request to the actual server if no mock is found during test mode.
+	RecordOnMiss   bool                // records the rest of the connection into new mocks when no mock is found during test mode.
+	Mocking        bool                // used to enable/disable mocking
+	Protocols      map[uint32]string   // protocol of the server-first dependencies (e.g. mysql) on each destination port
+	RedisNoise     map[string][]string // args of the redis commands which are ignored while matching the mocks, e.g. {"SET": ["EX"]}
+}
+
+// MockServerOptions are the options of the mock server serving the mocks without eBPF. The calls are
+// received on the listeners and on the http forward proxy (HTTP_PROXY/HTTPS_PROXY), if any.
+type MockServerOptions struct {
+	Listeners []config.MockListener
+	HTTPProxy string
+	OutgoingOptions
 }
 
 type IncomingOptions struct {
@@ -31,6 +42,15 @@ type SetupOptions struct {
 	DockerDelay   uint64
 }
 
+// RecordOptions are the options of a record session.
+type RecordOptions struct {
+	ReRecord bool // keploy keeps running once the recording is stopped
+	// Instrumented reuses the hooks and the proxy of the app already instrumented for recording (see AppID)
+	// instead of setting up and hooking the app again, e.g. for the sessions of the agent.
+	Instrumented bool
+	AppID        uint64
+}
+
 type RunOptions struct {
 	//IgnoreErrors bool
 }
@@ -48,3 +68,18 @@ const (
 type TestingOptions struct {
 	Mode Mode
 }
+
+// CapturedConn is a tcp connection read from a packet capture (e.g. a pcap file of tcpdump). The chunks
+// are the data sent on the connection in the order it was sent, consecutive chunks are of different sides.
+type CapturedConn struct {
+	ClientAddr string
+	ServerAddr string
+	ServerPort uint32
+	Chunks     []CapturedChunk
+}
+
+type CapturedChunk struct {
+	FromClient bool
+	Data       []byte
+	Timestamp  time.Time
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+type KafkaSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Request          KafkaRequest      `json:"request" yaml:"request"`
+	Response         KafkaResponse     `json:"response" yaml:"response"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// KafkaRequest is a request sent by a kafka client. The topics, the group and the coordinator keys are
+// decoded from the body to match the mocks, the body is stored as base64.
+type KafkaRequest struct {
+	APIKey          int16        `json:"apiKey" yaml:"apiKey"`
+	APIName         string       `json:"apiName" yaml:"apiName"`
+	APIVersion      int16        `json:"apiVersion" yaml:"apiVersion"`
+	CorrelationID   int32        `json:"correlationId" yaml:"correlationId"`
+	ClientID        string       `json:"clientId,omitempty" yaml:"clientId,omitempty"`
+	GroupID         string       `json:"groupId,omitempty" yaml:"groupId,omitempty"`
+	CoordinatorKeys []string     `json:"coordinatorKeys,omitempty" yaml:"coordinatorKeys,omitempty"`
+	Acks            int16        `json:"acks,omitempty" yaml:"acks,omitempty"`
+	Topics          []KafkaTopic `json:"topics,omitempty" yaml:"topics,omitempty"`
+	Body            string       `json:"body" yaml:"body"`
+}
+
+// KafkaTopic is a topic referred to by a request along with its partitions, and the offsets for the
+// requests fetching or committing the offsets. The topics are identified by their ids in the recent
+// versions of the Fetch requests.
+type KafkaTopic struct {
+	Name       string           `json:"name" yaml:"name"`
+	Partitions []KafkaPartition `json:"partitions,omitempty" yaml:"partitions,omitempty,flow"`
+}
+
+type KafkaPartition struct {
+	Index  int32 `json:"index" yaml:"index"`
+	Offset int64 `json:"offset,omitempty" yaml:"offset,omitempty"`
+}
+
+// KafkaResponse holds the response of the broker after the correlation id, which is rewritten with the
+// correlation id of the request in the test mode.
+type KafkaResponse struct {
+	CorrelationID int32  `json:"correlationId" yaml:"correlationId"`
+	Body          string `json:"body" yaml:"body"`
+}
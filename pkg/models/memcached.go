@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+type MemcachedSchema struct {
+	Metadata         map[string]string   `json:"metadata" yaml:"metadata"`
+	Request          MemcachedRequest    `json:"request" yaml:"request"`
+	Responses        []MemcachedResponse `json:"responses,omitempty" yaml:"responses,omitempty"`
+	ReqTimestampMock time.Time           `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time           `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// MemcachedRequest is a command of the text or the binary protocol of memcached. The mocks are matched on
+// the command and the keys, the cas tokens and the opaque values which change between the runs are ignored.
+// The arguments of the text commands which aren't decoded into the other fields are kept in Args.
+type MemcachedRequest struct {
+	Protocol  string   `json:"protocol" yaml:"protocol"`
+	Command   string   `json:"command" yaml:"command"`
+	Keys      []string `json:"keys,omitempty" yaml:"keys,omitempty,flow"`
+	Args      []string `json:"args,omitempty" yaml:"args,omitempty,flow"`
+	Flags     uint32   `json:"flags,omitempty" yaml:"flags,omitempty"`
+	Exptime   int64    `json:"exptime,omitempty" yaml:"exptime,omitempty"`
+	Delta     uint64   `json:"delta,omitempty" yaml:"delta,omitempty"`
+	Initial   uint64   `json:"initial,omitempty" yaml:"initial,omitempty"`
+	CAS       uint64   `json:"cas,omitempty" yaml:"cas,omitempty"`
+	Opaque    uint32   `json:"opaque,omitempty" yaml:"opaque,omitempty"`
+	NoReply   bool     `json:"noReply,omitempty" yaml:"noReply,omitempty"`
+	Extras    string   `json:"extras,omitempty" yaml:"extras,omitempty"`
+	Value     string   `json:"value,omitempty" yaml:"value,omitempty"`
+	ValueType BodyType `json:"valueType,omitempty" yaml:"valueType,omitempty"`
+}
+
+// MemcachedResponse is a reply of the server, it is encoded again from these fields in the test mode. The
+// status is the last line of a reply of the text protocol (e.g. END, STORED or the value of an incr) or the
+// name of the status of a binary packet. The items are the values of a retrieval and the stats, a binary
+// packet holds a single item.
+type MemcachedResponse struct {
+	Status string          `json:"status" yaml:"status"`
+	Items  []MemcachedItem `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+type MemcachedItem struct {
+	Key       string   `json:"key,omitempty" yaml:"key,omitempty"`
+	Flags     uint32   `json:"flags,omitempty" yaml:"flags,omitempty"`
+	CAS       uint64   `json:"cas,omitempty" yaml:"cas,omitempty"`
+	Value     string   `json:"value,omitempty" yaml:"value,omitempty"`
+	ValueType BodyType `json:"valueType,omitempty" yaml:"valueType,omitempty"`
+}
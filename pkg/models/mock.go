@@ -26,24 +26,40 @@ func (m *Mock) GetKind() string {
 }
 
 type MockSpec struct {
-	Metadata          map[string]string `json:"Metadata,omitempty" bson:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	GenericRequests   []Payload         `json:"RequestBin,omitempty" bson:"generic_requests,omitempty"`
-	GenericResponses  []Payload         `json:"ResponseBin,omitempty" bson:"generic_responses,omitempty"`
-	RedisRequests     []Payload         `json:"redisRequests,omitempty" bson:"redis_requests,omitempty"`
-	RedisResponses    []Payload         `json:"redisResponses,omitempty" bson:"redis_responses,omitempty"`
-	HTTPReq           *HTTPReq          `json:"Req,omitempty" bson:"http_req,omitempty"`
-	HTTPResp          *HTTPResp         `json:"Res,omitempty" bson:"http_resp,omitempty"`
-	Created           int64             `json:"Created,omitempty" bson:"created,omitempty"`
-	MongoRequests     []MongoRequest    `json:"MongoRequests,omitempty" bson:"mongo_requests,omitempty"`
-	MongoResponses    []MongoResponse   `json:"MongoResponses,omitempty" bson:"mongo_responses,omitempty"`
-	PostgresRequests  []Backend         `json:"postgresRequests,omitempty" bson:"postgres_requests,omitempty"`
-	PostgresResponses []Frontend        `json:"postgresResponses,omitempty" bson:"postgres_responses,omitempty"`
-	GRPCReq           *GrpcReq          `json:"gRPCRequest,omitempty" bson:"grpc_req,omitempty"`
-	GRPCResp          *GrpcResp         `json:"grpcResponse,omitempty" bson:"grpc_resp,omitempty"`
-	MySQLRequests     []mysql.Request   `json:"MySqlRequests,omitempty" bson:"my_sql_requests,omitempty"`
-	MySQLResponses    []mysql.Response  `json:"MySqlResponses,omitempty" bson:"my_sql_responses,omitempty"`
-	ReqTimestampMock  time.Time         `json:"ReqTimestampMock,omitempty" bson:"req_timestamp_mock,omitempty"`
-	ResTimestampMock  time.Time         `json:"ResTimestampMock,omitempty" bson:"res_timestamp_mock,omitempty"`
+	Metadata            map[string]string    `json:"Metadata,omitempty" bson:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	GenericRequests     []Payload            `json:"RequestBin,omitempty" bson:"generic_requests,omitempty"`
+	GenericResponses    []Payload            `json:"ResponseBin,omitempty" bson:"generic_responses,omitempty"`
+	RedisRequests       []Payload            `json:"redisRequests,omitempty" bson:"redis_requests,omitempty"`
+	RedisResponses      []Payload            `json:"redisResponses,omitempty" bson:"redis_responses,omitempty"`
+	RedisCommands       []RedisCommand       `json:"redisCommands,omitempty" bson:"redis_commands,omitempty"`
+	HTTPReq             *HTTPReq             `json:"Req,omitempty" bson:"http_req,omitempty"`
+	HTTPResp            *HTTPResp            `json:"Res,omitempty" bson:"http_resp,omitempty"`
+	WebSocket           []WebSocketMessage   `json:"webSocket,omitempty" bson:"web_socket,omitempty"`
+	Created             int64                `json:"Created,omitempty" bson:"created,omitempty"`
+	MongoRequests       []MongoRequest       `json:"MongoRequests,omitempty" bson:"mongo_requests,omitempty"`
+	MongoResponses      []MongoResponse      `json:"MongoResponses,omitempty" bson:"mongo_responses,omitempty"`
+	PostgresRequests    []Backend            `json:"postgresRequests,omitempty" bson:"postgres_requests,omitempty"`
+	PostgresResponses   []Frontend           `json:"postgresResponses,omitempty" bson:"postgres_responses,omitempty"`
+	PostgresV2Requests  []PostgresV2Request  `json:"postgresV2Requests,omitempty" bson:"postgres_v2_requests,omitempty"`
+	PostgresV2Responses []PostgresV2Response `json:"postgresV2Responses,omitempty" bson:"postgres_v2_responses,omitempty"`
+	GRPCReq             *GrpcReq             `json:"gRPCRequest,omitempty" bson:"grpc_req,omitempty"`
+	GRPCResp            *GrpcResp            `json:"grpcResponse,omitempty" bson:"grpc_resp,omitempty"`
+	MySQLRequests       []mysql.Request      `json:"MySqlRequests,omitempty" bson:"my_sql_requests,omitempty"`
+	MySQLResponses      []mysql.Response     `json:"MySqlResponses,omitempty" bson:"my_sql_responses,omitempty"`
+	DNSReq              *DNSReq              `json:"dnsRequest,omitempty" bson:"dns_req,omitempty"`
+	DNSResp             *DNSResp             `json:"dnsResponse,omitempty" bson:"dns_resp,omitempty"`
+	KafkaReq            *KafkaRequest        `json:"kafkaRequest,omitempty" bson:"kafka_req,omitempty"`
+	KafkaResp           *KafkaResponse       `json:"kafkaResponse,omitempty" bson:"kafka_resp,omitempty"`
+	AMQPReq             *AMQPCommand         `json:"amqpRequest,omitempty" bson:"amqp_req,omitempty"`
+	AMQPResp            []AMQPCommand        `json:"amqpResponse,omitempty" bson:"amqp_resp,omitempty"`
+	CassandraReq        *CassandraRequest    `json:"cassandraRequest,omitempty" bson:"cassandra_req,omitempty"`
+	CassandraResp       *CassandraResponse   `json:"cassandraResponse,omitempty" bson:"cassandra_resp,omitempty"`
+	MemcachedReq        *MemcachedRequest    `json:"memcachedRequest,omitempty" bson:"memcached_req,omitempty"`
+	MemcachedResp       []MemcachedResponse  `json:"memcachedResponse,omitempty" bson:"memcached_resp,omitempty"`
+	MSSQLReq            *MSSQLRequest        `json:"mssqlRequest,omitempty" bson:"mssql_req,omitempty"`
+	MSSQLResp           *MSSQLResponse       `json:"mssqlResponse,omitempty" bson:"mssql_resp,omitempty"`
+	ReqTimestampMock    time.Time            `json:"ReqTimestampMock,omitempty" bson:"req_timestamp_mock,omitempty"`
+	ResTimestampMock    time.Time            `json:"ResTimestampMock,omitempty" bson:"res_timestamp_mock,omitempty"`
 }
 
 // OutputBinary store the encoded binary output of the egress calls as base64-encoded strings
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+)
+
+type MSSQLSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Request          MSSQLRequest      `json:"request" yaml:"request"`
+	Response         MSSQLResponse     `json:"response" yaml:"response"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// MSSQLRequest is a message of the TDS client. The statements of the batches and the procedures called by
+// the RPCs are decoded along with their parameters to match the mocks. The password of the login isn't
+// stored, the other messages are stored as base64.
+type MSSQLRequest struct {
+	Type     string            `json:"type" yaml:"type"`
+	PreLogin map[string]string `json:"preLogin,omitempty" yaml:"preLogin,omitempty"`
+	Login    *MSSQLLogin       `json:"login,omitempty" yaml:"login,omitempty"`
+	Query    string            `json:"query,omitempty" yaml:"query,omitempty"`
+	RPCs     []MSSQLRPC        `json:"rpcs,omitempty" yaml:"rpcs,omitempty"`
+	Payload  string            `json:"payload,omitempty" yaml:"payload,omitempty"`
+}
+
+type MSSQLLogin struct {
+	TDSVersion         string `json:"tdsVersion" yaml:"tdsVersion"`
+	PacketSize         uint32 `json:"packetSize" yaml:"packetSize"`
+	HostName           string `json:"hostName,omitempty" yaml:"hostName,omitempty"`
+	UserName           string `json:"userName,omitempty" yaml:"userName,omitempty"`
+	AppName            string `json:"appName,omitempty" yaml:"appName,omitempty"`
+	ServerName         string `json:"serverName,omitempty" yaml:"serverName,omitempty"`
+	Library            string `json:"library,omitempty" yaml:"library,omitempty"`
+	Language           string `json:"language,omitempty" yaml:"language,omitempty"`
+	Database           string `json:"database,omitempty" yaml:"database,omitempty"`
+	IntegratedSecurity bool   `json:"integratedSecurity,omitempty" yaml:"integratedSecurity,omitempty"`
+}
+
+// MSSQLRPC is a call of a stored procedure. The statement run by sp_executesql, sp_prepare and sp_prepexec is
+// kept in Query, along with the statement prepared with the handle given to sp_execute.
+type MSSQLRPC struct {
+	Procedure  string           `json:"procedure" yaml:"procedure"`
+	Query      string           `json:"query,omitempty" yaml:"query,omitempty"`
+	Handle     int32            `json:"handle,omitempty" yaml:"handle,omitempty"`
+	Parameters []MSSQLParameter `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+type MSSQLParameter struct {
+	Name   string `json:"name,omitempty" yaml:"name,omitempty"`
+	Type   string `json:"type" yaml:"type"`
+	Value  string `json:"value,omitempty" yaml:"value,omitempty"`
+	Null   bool   `json:"null,omitempty" yaml:"null,omitempty"`
+	Output bool   `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+// MSSQLResponse is the reply of the server. The tokens are decoded into the result sets, the messages and the
+// values returned by the procedures, the payload holding the tokens is replayed in the test mode.
+type MSSQLResponse struct {
+	PreLogin     map[string]string `json:"preLogin,omitempty" yaml:"preLogin,omitempty"`
+	LoginAck     string            `json:"loginAck,omitempty" yaml:"loginAck,omitempty"`
+	EnvChanges   []MSSQLEnvChange  `json:"envChanges,omitempty" yaml:"envChanges,omitempty"`
+	ResultSets   []MSSQLResultSet  `json:"resultSets,omitempty" yaml:"resultSets,omitempty"`
+	ReturnStatus []int32           `json:"returnStatus,omitempty" yaml:"returnStatus,omitempty,flow"`
+	ReturnValues []MSSQLParameter  `json:"returnValues,omitempty" yaml:"returnValues,omitempty"`
+	RowCounts    []uint64          `json:"rowCounts,omitempty" yaml:"rowCounts,omitempty,flow"`
+	Messages     []MSSQLMessage    `json:"messages,omitempty" yaml:"messages,omitempty"`
+	Payload      string            `json:"payload" yaml:"payload"`
+}
+
+type MSSQLEnvChange struct {
+	Type     string `json:"type" yaml:"type"`
+	NewValue string `json:"newValue,omitempty" yaml:"newValue,omitempty"`
+	OldValue string `json:"oldValue,omitempty" yaml:"oldValue,omitempty"`
+}
+
+type MSSQLResultSet struct {
+	Columns []MSSQLColumn `json:"columns" yaml:"columns"`
+	Rows    [][]string    `json:"rows,omitempty" yaml:"rows,omitempty,flow"`
+}
+
+type MSSQLColumn struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// MSSQLMessage is an ERROR or an INFO token of the server.
+type MSSQLMessage struct {
+	Kind      string `json:"kind" yaml:"kind"`
+	Number    int32  `json:"number" yaml:"number"`
+	State     uint8  `json:"state" yaml:"state"`
+	Class     uint8  `json:"class" yaml:"class"`
+	Message   string `json:"message" yaml:"message"`
+	Procedure string `json:"procedure,omitempty" yaml:"procedure,omitempty"`
+	Line      int32  `json:"line,omitempty" yaml:"line,omitempty"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jackc/pgproto3/v2"
+)
+
+// PostgresV2Schema is the mock of an exchange on a postgres connection, i.e. the messages sent by the client
+// until the server is ready for the next query, decoded into readable fields.
+type PostgresV2Schema struct {
+	Metadata         map[string]string    `json:"metadata" yaml:"metadata"`
+	Requests         []PostgresV2Request  `json:"requests" yaml:"requests"`
+	Responses        []PostgresV2Response `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time            `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time            `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// PostgresV2Request is a message sent by the client. The statement and the parameters of the
+// Bind, Execute, Describe and Close messages are resolved from the connection, so that the mocks
+// are matched on the sql and the bound values rather than on the names given by the driver.
+type PostgresV2Request struct {
+	Type             string            `json:"type" yaml:"type"`
+	Query            string            `json:"query,omitempty" yaml:"query,omitempty"`
+	Statement        string            `json:"statement,omitempty" yaml:"statement,omitempty"`
+	Portal           string            `json:"portal,omitempty" yaml:"portal,omitempty"`
+	ObjectType       string            `json:"objectType,omitempty" yaml:"objectType,omitempty"`
+	ParameterOIDs    []uint32          `json:"parameterOids,omitempty" yaml:"parameterOids,omitempty,flow"`
+	ParameterFormats []int16           `json:"parameterFormats,omitempty" yaml:"parameterFormats,omitempty,flow"`
+	Parameters       []*string         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	ResultFormats    []int16           `json:"resultFormats,omitempty" yaml:"resultFormats,omitempty,flow"`
+	MaxRows          uint32            `json:"maxRows,omitempty" yaml:"maxRows,omitempty"`
+	StartupParams    map[string]string `json:"startupParams,omitempty" yaml:"startupParams,omitempty"`
+	Data             string            `json:"data,omitempty" yaml:"data,omitempty"`
+	// Raw holds the base64 encoded message when it is not decoded, e.g. the authentication messages
+	Raw string `json:"raw,omitempty" yaml:"raw,omitempty"`
+}
+
+// PostgresV2Response is a message sent by the server. AfterRequest is the number of requests of the
+// exchange received before the message, which is used to replay the responses at the same point.
+type PostgresV2Response struct {
+	Type              string                  `json:"type" yaml:"type"`
+	AfterRequest      int                     `json:"afterRequest" yaml:"afterRequest"`
+	Fields            []PostgresV2Field       `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Values            []*string               `json:"values,omitempty" yaml:"values,omitempty,flow"`
+	Tag               string                  `json:"tag,omitempty" yaml:"tag,omitempty"`
+	TxStatus          string                  `json:"txStatus,omitempty" yaml:"txStatus,omitempty"`
+	Error             *pgproto3.ErrorResponse `json:"error,omitempty" yaml:"error,omitempty"`
+	Name              string                  `json:"name,omitempty" yaml:"name,omitempty"`
+	Value             string                  `json:"value,omitempty" yaml:"value,omitempty"`
+	ProcessID         uint32                  `json:"processId,omitempty" yaml:"processId,omitempty"`
+	SecretKey         uint32                  `json:"secretKey,omitempty" yaml:"secretKey,omitempty"`
+	Channel           string                  `json:"channel,omitempty" yaml:"channel,omitempty"`
+	Payload           string                  `json:"payload,omitempty" yaml:"payload,omitempty"`
+	ParameterOIDs     []uint32                `json:"parameterOids,omitempty" yaml:"parameterOids,omitempty,flow"`
+	OverallFormat     byte                    `json:"overallFormat,omitempty" yaml:"overallFormat,omitempty"`
+	ColumnFormatCodes []uint16                `json:"columnFormatCodes,omitempty" yaml:"columnFormatCodes,omitempty,flow"`
+	Data              string                  `json:"data,omitempty" yaml:"data,omitempty"`
+	Raw               string                  `json:"raw,omitempty" yaml:"raw,omitempty"`
+}
+
+// PostgresV2Field describes a column of the rows returned by the server
+type PostgresV2Field struct {
+	Name                 string `json:"name" yaml:"name"`
+	TableOID             uint32 `json:"tableOid" yaml:"tableOid"`
+	TableAttributeNumber uint16 `json:"tableAttributeNumber" yaml:"tableAttributeNumber"`
+	DataTypeOID          uint32 `json:"dataTypeOid" yaml:"dataTypeOid"`
+	DataTypeSize         int16  `json:"dataTypeSize" yaml:"dataTypeSize"`
+	TypeModifier         int32  `json:"typeModifier" yaml:"typeModifier"`
+	Format               int16  `json:"format" yaml:"format"`
+}
@@ -8,6 +8,13 @@ type RedisSchema struct {
 	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
 	RedisRequests    []Payload         `json:"RequestBin,omitempty"`
 	RedisResponses   []Payload         `json:"ResponseBin,omitempty"`
+	RedisCommands    []RedisCommand    `json:"commands,omitempty" yaml:"commands,omitempty"`
 	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty"`
 	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty"`
 }
+
+// RedisCommand is a RESP decoded command sent by the client, e.g. SET key value EX 10
+type RedisCommand struct {
+	Name string   `json:"name" yaml:"name"`
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+}
@@ -27,8 +27,16 @@ const (
 	REDIS          Kind     = "Redis"
 	MySQL          Kind     = "MySQL"
 	Postgres       Kind     = "Postgres"
+	PostgresV2     Kind     = "PostgresV2"
 	GRPC_EXPORT    Kind     = "gRPC"
 	Mongo          Kind     = "Mongo"
+	DNS            Kind     = "DNS"
+	Kafka          Kind     = "Kafka"
+	AMQP           Kind     = "AMQP"
+	Cassandra      Kind     = "Cassandra"
+	Memcached      Kind     = "Memcached"
+	MSSQL          Kind     = "MSSQL"
+	WebSocket      Kind     = "WebSocket"
 	BodyTypeUtf8   BodyType = "utf-8"
 	BodyTypeBinary BodyType = "binary"
 	BodyTypePlain  BodyType = "PLAIN"
@@ -48,11 +56,13 @@ type TestCase struct {
 	AllKeys  map[string][]string `json:"all_keys" bson:"all_keys"`
 	GrpcResp GrpcResp            `json:"grpcResp" bson:"grpcResp"`
 	GrpcReq  GrpcReq             `json:"grpcReq" bson:"grpcReq"`
-	Anchors  map[string][]string `json:"anchors" bson:"anchors"`
-	Noise    map[string][]string `json:"noise" bson:"noise"`
-	Mocks    []*Mock             `json:"mocks" bson:"mocks"`
-	Type     string              `json:"type" bson:"type"`
-	Curl     string              `json:"curl" bson:"curl"`
+	// WebSocket is the log of the messages exchanged after the upgrade of a websocket testcase
+	WebSocket []WebSocketMessage  `json:"websocket" bson:"websocket"`
+	Anchors   map[string][]string `json:"anchors" bson:"anchors"`
+	Noise     map[string][]string `json:"noise" bson:"noise"`
+	Mocks     []*Mock             `json:"mocks" bson:"mocks"`
+	Type      string              `json:"type" bson:"type"`
+	Curl      string              `json:"curl" bson:"curl"`
 }
 
 func (tc *TestCase) GetKind() string {
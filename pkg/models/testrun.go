@@ -26,18 +26,21 @@ func (tr *TestReport) GetKind() string {
 }
 
 type TestResult struct {
-	Kind         Kind       `json:"kind" yaml:"kind"`
-	Name         string     `json:"name" yaml:"name"`
-	Status       TestStatus `json:"status" yaml:"status"`
-	Started      int64      `json:"started" yaml:"started"`
-	Completed    int64      `json:"completed" yaml:"completed"`
-	TestCasePath string     `json:"testCasePath" yaml:"test_case_path"`
-	MockPath     string     `json:"mockPath" yaml:"mock_path"`
-	TestCaseID   string     `json:"testCaseID" yaml:"test_case_id"`
-	Req          HTTPReq    `json:"req" yaml:"req,omitempty"`
-	Res          HTTPResp   `json:"resp" yaml:"resp,omitempty"`
-	Noise        Noise      `json:"noise" yaml:"noise,omitempty"`
-	Result       Result     `json:"result" yaml:"result"`
+	Kind         Kind               `json:"kind" yaml:"kind"`
+	Name         string             `json:"name" yaml:"name"`
+	Status       TestStatus         `json:"status" yaml:"status"`
+	Started      int64              `json:"started" yaml:"started"`
+	Completed    int64              `json:"completed" yaml:"completed"`
+	TestCasePath string             `json:"testCasePath" yaml:"test_case_path"`
+	MockPath     string             `json:"mockPath" yaml:"mock_path"`
+	TestCaseID   string             `json:"testCaseID" yaml:"test_case_id"`
+	Req          HTTPReq            `json:"req" yaml:"req,omitempty"`
+	Res          HTTPResp           `json:"resp" yaml:"resp,omitempty"`
+	GrpcReq      GrpcReq            `json:"grpcReq" yaml:"grpc_req,omitempty"`
+	GrpcRes      GrpcResp           `json:"grpcResp" yaml:"grpc_resp,omitempty"`
+	WebSocket    []WebSocketMessage `json:"websocket" yaml:"websocket,omitempty"`
+	Noise        Noise              `json:"noise" yaml:"noise,omitempty"`
+	Result       Result             `json:"result" yaml:"result"`
 }
 
 func (tr *TestResult) GetKind() string {
@@ -81,10 +84,22 @@ func StringToTestSetStatus(s string) (TestSetStatus, error) {
 }
 
 type Result struct {
-	StatusCode    IntResult      `json:"status_code" bson:"status_code" yaml:"status_code"`
-	HeadersResult []HeaderResult `json:"headers_result" bson:"headers_result" yaml:"headers_result"`
-	BodyResult    []BodyResult   `json:"body_result" bson:"body_result" yaml:"body_result"`
-	DepResult     []DepResult    `json:"dep_result" bson:"dep_result" yaml:"dep_result"`
+	StatusCode      IntResult         `json:"status_code" bson:"status_code" yaml:"status_code"`
+	HeadersResult   []HeaderResult    `json:"headers_result" bson:"headers_result" yaml:"headers_result"`
+	TrailerResult   []HeaderResult    `json:"trailer_result" bson:"trailer_result" yaml:"trailer_result,omitempty"`
+	WebSocketResult []WebSocketResult `json:"websocket_result" bson:"websocket_result" yaml:"websocket_result,omitempty"`
+	BodyResult      []BodyResult      `json:"body_result" bson:"body_result" yaml:"body_result"`
+	DepResult       []DepResult       `json:"dep_result" bson:"dep_result" yaml:"dep_result"`
+	LatencyResult   *LatencyResult    `json:"latency_result,omitempty" bson:"latency_result,omitempty" yaml:"latency_result,omitempty"`
+}
+
+// LatencyResult compares the response time of the test run with the latency recorded for the test case,
+// all in milliseconds. It is only set when a latency threshold is configured.
+type LatencyResult struct {
+	Normal   bool  `json:"normal" bson:"normal" yaml:"normal"`
+	Expected int64 `json:"expected" bson:"expected" yaml:"expected"`
+	Actual   int64 `json:"actual" bson:"actual" yaml:"actual"`
+	Allowed  int64 `json:"allowed" bson:"allowed" yaml:"allowed"`
 }
 
 type DepResult struct {
@@ -100,6 +115,13 @@ type DepMetaResult struct {
 	Actual   string `json:"actual" bson:"actual" yaml:"actual"`
 }
 
+// UnmatchedCall is an outgoing call of the app that no mock matched during a test case
+type UnmatchedCall struct {
+	Kind        Kind   `json:"kind" yaml:"kind"`
+	Destination string `json:"destination" yaml:"destination"`
+	Summary     string `json:"summary" yaml:"summary"` // decoded request, e.g. the method and the url of a http call
+}
+
 type IntResult struct {
 	Normal   bool `json:"normal" bson:"normal" yaml:"normal"`
 	Expected int  `json:"expected" bson:"expected" yaml:"expected"`
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+type WebSocketMessageType string
+
+// the types of the messages of a websocket connection, the fragmented messages are recorded once joined
+const (
+	WebSocketText   WebSocketMessageType = "text"
+	WebSocketBinary WebSocketMessageType = "binary"
+	WebSocketPing   WebSocketMessageType = "ping"
+	WebSocketPong   WebSocketMessageType = "pong"
+	WebSocketClose  WebSocketMessageType = "close"
+)
+
+// the sides of a websocket connection sending the messages
+const (
+	WebSocketFromClient = "client"
+	WebSocketFromServer = "server"
+)
+
+// WebSocketMessage is a message of the log of a websocket connection. The data of the binary messages is
+// base64 encoded, the data of a close message is its reason.
+type WebSocketMessage struct {
+	From      string               `json:"from" yaml:"from"`
+	Type      WebSocketMessageType `json:"type" yaml:"type"`
+	Data      string               `json:"data" yaml:"data,omitempty"`
+	CloseCode int                  `json:"close_code,omitempty" yaml:"close_code,omitempty"`
+}
+
+// WebSocketSchema is the yaml spec of a websocket testcase, the upgrade request and its response along with
+// the messages exchanged after it, in order.
+type WebSocketSchema struct {
+	Request    HTTPReq                `json:"req" yaml:"req"`
+	Response   HTTPResp               `json:"resp" yaml:"resp"`
+	Messages   []WebSocketMessage     `json:"messages" yaml:"messages"`
+	Assertions map[string]interface{} `json:"assertions" yaml:"assertions,omitempty"`
+	Created    int64                  `json:"created" yaml:"created,omitempty"`
+}
+
+// WebSocketResp is the response of the application to a websocket testcase.
+type WebSocketResp struct {
+	Handshake HTTPResp
+	Messages  []WebSocketMessage
+	Timestamp time.Time
+}
+
+// WebSocketResult compares a message the server was expected to send with the one it sent.
+type WebSocketResult struct {
+	Index    int              `json:"index" bson:"index" yaml:"index"`
+	Normal   bool             `json:"normal" bson:"normal" yaml:"normal"`
+	Expected WebSocketMessage `json:"expected" bson:"expected" yaml:"expected"`
+	Actual   WebSocketMessage `json:"actual" bson:"actual" yaml:"actual"`
+}
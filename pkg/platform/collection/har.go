@@ -0,0 +1,352 @@
+package collection
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// HAR 1.2, refer: http://www.softwareishard.com/blog/har-12-spec/
+
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string     `json:"mimeType"`
+	Text     string     `json:"text,omitempty"`
+	Params   []harParam `json:"params,omitempty"`
+}
+
+type harParam struct {
+	Name     string `json:"name"`
+	Value    string `json:"value,omitempty"`
+	FileName string `json:"fileName,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// ReadHarFile returns the test cases of the entries of a HAR file.
+func ReadHarFile(logger *zap.Logger, path string) ([]*models.TestCase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			utils.LogError(logger, err, "failed to close the har file")
+		}
+	}()
+	return ReadHar(logger, f)
+}
+
+// ReadHar returns the test cases of the entries of a HAR document. The entries without a response, e.g. the
+// requests blocked or cancelled by the browser, are skipped.
+func ReadHar(logger *zap.Logger, r io.Reader) ([]*models.TestCase, error) {
+	var doc har
+	err := json.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the har document: %w", err)
+	}
+
+	var tcs []*models.TestCase
+	for i, entry := range doc.Log.Entries {
+		if entry.Response.Status == 0 {
+			logger.Debug("skipping the har entry without a response", zap.Any("url", entry.Request.URL))
+			continue
+		}
+		tc, err := harEntryToTestCase(entry)
+		if err != nil {
+			logger.Warn("skipping the har entry", zap.Any("entry", i), zap.Any("url", entry.Request.URL), zap.Error(err))
+			continue
+		}
+		tcs = append(tcs, tc)
+	}
+	return tcs, nil
+}
+
+func harEntryToTestCase(entry harEntry) (*models.TestCase, error) {
+	reqURL, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	started, err := time.Parse(time.RFC3339Nano, entry.StartedDateTime)
+	if err != nil {
+		started = time.Now()
+	}
+	ended := started.Add(time.Duration(entry.Time * float64(time.Millisecond)))
+
+	var reqBody string
+	if entry.Request.PostData != nil {
+		reqBody = entry.Request.PostData.Text
+		if reqBody == "" && len(entry.Request.PostData.Params) > 0 {
+			form := url.Values{}
+			for _, p := range entry.Request.PostData.Params {
+				form.Add(p.Name, p.Value)
+			}
+			reqBody = form.Encode()
+		}
+	}
+
+	respBody := entry.Response.Content.Text
+	if entry.Response.Content.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(respBody)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 response body: %w", err)
+		}
+		respBody = string(decoded)
+	}
+
+	urlParams := map[string]string{}
+	for key, values := range reqURL.Query() {
+		urlParams[key] = strings.Join(values, ", ")
+	}
+
+	protoMajor, protoMinor := parseHTTPVersion(entry.Request.HTTPVersion)
+	respHeader := fromNameValues(entry.Response.Headers)
+	// the body of the har is decoded, its length may not be the recorded one
+	delete(respHeader, "Content-Length")
+	return &models.TestCase{
+		Version: models.GetVersion(),
+		Kind:    models.HTTP,
+		Created: time.Now().Unix(),
+		HTTPReq: models.HTTPReq{
+			Method:     models.Method(strings.ToUpper(entry.Request.Method)),
+			ProtoMajor: protoMajor,
+			ProtoMinor: protoMinor,
+			URL:        entry.Request.URL,
+			URLParams:  urlParams,
+			Header:     fromNameValues(entry.Request.Headers),
+			Body:       reqBody,
+			Timestamp:  started,
+		},
+		HTTPResp: models.HTTPResp{
+			StatusCode:    entry.Response.Status,
+			Header:        respHeader,
+			Body:          respBody,
+			StatusMessage: statusText(entry.Response.Status, entry.Response.StatusText),
+			ProtoMajor:    protoMajor,
+			ProtoMinor:    protoMinor,
+			Timestamp:     ended,
+		},
+		Noise: map[string][]string{},
+	}, nil
+}
+
+// WriteHar writes the http test cases as the entries of a HAR document. HAR has no variables, the
+// templatized values are replaced with the values of the test-set.
+func WriteHar(w io.Writer, tcs []*models.TestCase, values map[string]interface{}) error {
+	doc := har{
+		Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "keploy", Version: utils.Version},
+			Entries: []harEntry{},
+		},
+	}
+	for _, tc := range tcs {
+		if tc.Kind != models.HTTP {
+			continue
+		}
+		doc.Log.Entries = append(doc.Log.Entries, testCaseToHarEntry(tc, values))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func testCaseToHarEntry(tc *models.TestCase, values map[string]interface{}) harEntry {
+	reqURL := render(tc.HTTPReq.URL, values)
+	httpVersion := fmt.Sprintf("HTTP/%d.%d", tc.HTTPReq.ProtoMajor, tc.HTTPReq.ProtoMinor)
+	if tc.HTTPReq.ProtoMajor == 0 {
+		httpVersion = "HTTP/1.1"
+	}
+
+	query := []harNameValue{}
+	if u, err := url.Parse(reqURL); err == nil {
+		for key, vals := range u.Query() {
+			for _, val := range vals {
+				query = append(query, harNameValue{Name: key, Value: val})
+			}
+		}
+		sort.Slice(query, func(i, j int) bool { return query[i].Name < query[j].Name })
+	}
+
+	reqHeader := renderHeader(tc.HTTPReq.Header, values)
+	reqBody := render(tc.HTTPReq.Body, values)
+	var postData *harPostData
+	if reqBody != "" {
+		postData = &harPostData{
+			MimeType: headerValue(reqHeader, "Content-Type"),
+			Text:     reqBody,
+		}
+	}
+
+	respHeader := renderHeader(tc.HTTPResp.Header, values)
+	respBody := render(tc.HTTPResp.Body, values)
+	elapsed := tc.HTTPResp.Timestamp.Sub(tc.HTTPReq.Timestamp)
+	if elapsed < 0 || tc.HTTPResp.Timestamp.IsZero() {
+		elapsed = 0
+	}
+	ms := float64(elapsed) / float64(time.Millisecond)
+
+	return harEntry{
+		StartedDateTime: tc.HTTPReq.Timestamp.Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: harRequest{
+			Method:      string(tc.HTTPReq.Method),
+			URL:         reqURL,
+			HTTPVersion: httpVersion,
+			Cookies:     []harNameValue{},
+			Headers:     toNameValues(reqHeader),
+			QueryString: query,
+			PostData:    postData,
+			HeadersSize: -1,
+			BodySize:    len(reqBody),
+		},
+		Response: harResponse{
+			Status:      tc.HTTPResp.StatusCode,
+			StatusText:  statusText(tc.HTTPResp.StatusCode, tc.HTTPResp.StatusMessage),
+			HTTPVersion: httpVersion,
+			Cookies:     []harNameValue{},
+			Headers:     toNameValues(respHeader),
+			Content: harContent{
+				Size:     len(respBody),
+				MimeType: headerValue(respHeader, "Content-Type"),
+				Text:     respBody,
+			},
+			HeadersSize: -1,
+			BodySize:    len(respBody),
+		},
+		Timings: harTimings{Wait: ms},
+	}
+}
+
+func parseHTTPVersion(version string) (int, int) {
+	major, minor, ok := http.ParseHTTPVersion(strings.ToUpper(version))
+	if !ok || major > 1 {
+		// the http/2 and http/3 requests of the browsers are replayed over http/1.1
+		return 1, 1
+	}
+	return major, minor
+}
+
+func statusText(code int, text string) string {
+	if text != "" {
+		return text
+	}
+	return http.StatusText(code)
+}
+
+// fromNameValues returns the headers of the har as the headers of a test case, the pseudo headers of
+// http/2 are dropped and the repeated headers are joined.
+func fromNameValues(nvs []harNameValue) map[string]string {
+	header := http.Header{}
+	for _, nv := range nvs {
+		if strings.HasPrefix(nv.Name, ":") {
+			continue
+		}
+		header.Add(nv.Name, nv.Value)
+	}
+	out := make(map[string]string, len(header))
+	for key, values := range header {
+		out[key] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+func toNameValues(header map[string]string) []harNameValue {
+	nvs := make([]harNameValue, 0, len(header))
+	for key, value := range header {
+		nvs = append(nvs, harNameValue{Name: key, Value: value})
+	}
+	sort.Slice(nvs, func(i, j int) bool { return nvs[i].Name < nvs[j].Name })
+	return nvs
+}
+
+func renderHeader(header map[string]string, values map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(header))
+	for key, value := range header {
+		out[key] = render(value, values)
+	}
+	return out
+}
+
+func headerValue(header map[string]string, key string) string {
+	for k, v := range header {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,425 @@
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// Postman collection v2.1, refer: https://schema.postman.com/collection/json/v2.1.0/draft-07/docs/index.html
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable,omitempty"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// postmanItem is either a request along with its saved responses, or a folder of items.
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item,omitempty"`
+	Request  *postmanRequest   `json:"request,omitempty"`
+	Response []postmanResponse `json:"response,omitempty"`
+}
+
+type postmanRequest struct {
+	Method string       `json:"method"`
+	Header []postmanKV  `json:"header"`
+	URL    postmanURL   `json:"url"`
+	Body   *postmanBody `json:"body,omitempty"`
+}
+
+type postmanKV struct {
+	Key      string      `json:"key"`
+	Value    string      `json:"value"`
+	Type     string      `json:"type,omitempty"`
+	Src      interface{} `json:"src,omitempty"`
+	Disabled bool        `json:"disabled,omitempty"`
+}
+
+type postmanURL struct {
+	Raw string `json:"raw"`
+}
+
+type postmanBody struct {
+	Mode       string              `json:"mode"`
+	Raw        string              `json:"raw,omitempty"`
+	URLEncoded []postmanKV         `json:"urlencoded,omitempty"`
+	FormData   []postmanKV         `json:"formdata,omitempty"`
+	GraphQL    *postmanGraphQL     `json:"graphql,omitempty"`
+	Options    *postmanBodyOptions `json:"options,omitempty"`
+}
+
+type postmanGraphQL struct {
+	Query     string `json:"query"`
+	Variables string `json:"variables,omitempty"`
+}
+
+type postmanBodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+type postmanResponse struct {
+	Name            string          `json:"name"`
+	OriginalRequest *postmanRequest `json:"originalRequest,omitempty"`
+	Status          string          `json:"status"`
+	Code            int             `json:"code"`
+	Header          []postmanKV     `json:"header"`
+	Body            string          `json:"body"`
+}
+
+type postmanVariable struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// UnmarshalJSON accepts the requests given as a plain url, as the collections v2.0 allow.
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	var rawURL string
+	if err := json.Unmarshal(data, &rawURL); err == nil {
+		*r = postmanRequest{Method: http.MethodGet, URL: postmanURL{Raw: rawURL}}
+		return nil
+	}
+	type request postmanRequest
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	*r = postmanRequest(req)
+	return nil
+}
+
+// UnmarshalJSON accepts the urls given as a string or as an object.
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+	var obj struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	u.Raw = obj.Raw
+	return nil
+}
+
+// PostmanImport is the result of the import of a Postman collection.
+type PostmanImport struct {
+	TestCases []*models.TestCase
+	// Variables are the variables of the collection, they are the templatized values of the test-set
+	Variables map[string]interface{}
+	// NoResponse counts the requests without a saved response, their tests need to be recorded again
+	NoResponse int
+}
+
+// ReadPostmanFile returns the test cases of the requests of a Postman collection file.
+func ReadPostmanFile(logger *zap.Logger, path string) (*PostmanImport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			utils.LogError(logger, err, "failed to close the postman collection file")
+		}
+	}()
+	return ReadPostman(logger, f)
+}
+
+// ReadPostman returns the test cases of the requests of a Postman collection, walking through its folders.
+// The first saved response of a request is its expected response. The variables of the requests are turned
+// into the templatized values of the test cases.
+func ReadPostman(logger *zap.Logger, r io.Reader) (*PostmanImport, error) {
+	var coll postmanCollection
+	err := json.NewDecoder(r).Decode(&coll)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the postman collection: %w", err)
+	}
+
+	res := &PostmanImport{Variables: map[string]interface{}{}}
+	for _, v := range coll.Variable {
+		if !templateKey.MatchString(v.Key) {
+			logger.Warn("skipping the collection variable, its name can't be used in the templates", zap.Any("variable", v.Key))
+			continue
+		}
+		res.Variables[v.Key] = v.Value
+	}
+
+	var skipped []string
+	seen := map[string]bool{}
+	var walk func(items []postmanItem, folder string)
+	walk = func(items []postmanItem, folder string) {
+		for _, item := range items {
+			name := strings.TrimPrefix(folder+"/"+item.Name, "/")
+			if item.Request == nil {
+				walk(item.Item, name)
+				continue
+			}
+			tc, names, err := postmanItemToTestCase(item)
+			if err != nil {
+				logger.Warn("skipping the postman request", zap.Any("request", name), zap.Error(err))
+				continue
+			}
+			for _, n := range names {
+				if !seen[n] {
+					seen[n] = true
+					skipped = append(skipped, n)
+				}
+			}
+			if len(item.Response) == 0 {
+				res.NoResponse++
+			}
+			res.TestCases = append(res.TestCases, tc)
+		}
+	}
+	walk(coll.Item, "")
+
+	if len(skipped) > 0 {
+		logger.Warn("the dynamic variables and the variables whose name can't be used in the templates are left as they are", zap.Any("variables", skipped))
+	}
+	return res, nil
+}
+
+func postmanItemToTestCase(item postmanItem) (*models.TestCase, []string, error) {
+	var skipped []string
+	convert := func(s string) string {
+		out, names := fromPostmanVars(s)
+		skipped = append(skipped, names...)
+		return out
+	}
+
+	req := item.Request
+	rawURL := convert(req.URL.Raw)
+	if rawURL == "" {
+		return nil, nil, fmt.Errorf("the request has no url")
+	}
+	// postman accepts the urls without a scheme, unless the scheme comes from a variable e.g. {{baseUrl}}/users
+	if !strings.Contains(rawURL, "://") && !strings.HasPrefix(rawURL, "{{") {
+		rawURL = "http://" + rawURL
+	}
+
+	header := map[string]string{}
+	for _, h := range req.Header {
+		if h.Disabled {
+			continue
+		}
+		header[http.CanonicalHeaderKey(h.Key)] = convert(h.Value)
+	}
+
+	var body string
+	var form []models.FormData
+	if req.Body != nil {
+		switch req.Body.Mode {
+		case "raw":
+			body = convert(req.Body.Raw)
+			if req.Body.Options != nil && req.Body.Options.Raw.Language == "json" && headerValue(header, "Content-Type") == "" {
+				header["Content-Type"] = "application/json"
+			}
+		case "urlencoded":
+			var pairs []string
+			for _, kv := range req.Body.URLEncoded {
+				if !kv.Disabled {
+					pairs = append(pairs, queryEscape(kv.Key)+"="+queryEscape(convert(kv.Value)))
+				}
+			}
+			body = strings.Join(pairs, "&")
+			if headerValue(header, "Content-Type") == "" {
+				header["Content-Type"] = "application/x-www-form-urlencoded"
+			}
+		case "formdata":
+			for _, kv := range req.Body.FormData {
+				if kv.Disabled {
+					continue
+				}
+				fd := models.FormData{Key: kv.Key}
+				if kv.Type == "file" {
+					fd.Paths = formSrc(kv.Src)
+				} else {
+					fd.Values = []string{convert(kv.Value)}
+				}
+				form = append(form, fd)
+			}
+		case "graphql":
+			if req.Body.GraphQL != nil {
+				gql := map[string]interface{}{"query": convert(req.Body.GraphQL.Query)}
+				if vars := strings.TrimSpace(req.Body.GraphQL.Variables); vars != "" {
+					gql["variables"] = json.RawMessage(convert(vars))
+				}
+				encoded, err := json.Marshal(gql)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid graphql variables: %w", err)
+				}
+				body = string(encoded)
+			}
+			if headerValue(header, "Content-Type") == "" {
+				header["Content-Type"] = "application/json"
+			}
+		}
+	}
+
+	urlParams := map[string]string{}
+	if u, err := url.Parse(rawURL); err == nil {
+		for key, values := range u.Query() {
+			urlParams[key] = strings.Join(values, ", ")
+		}
+	}
+
+	now := time.Now()
+	tc := &models.TestCase{
+		Version: models.GetVersion(),
+		Kind:    models.HTTP,
+		Created: now.Unix(),
+		HTTPReq: models.HTTPReq{
+			Method:     models.Method(strings.ToUpper(req.Method)),
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			URL:        rawURL,
+			URLParams:  urlParams,
+			Header:     header,
+			Body:       body,
+			Form:       form,
+			Timestamp:  now,
+		},
+		HTTPResp: models.HTTPResp{
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     map[string]string{},
+			Timestamp:  now,
+		},
+		Noise: map[string][]string{},
+	}
+	if tc.HTTPReq.Method == "" {
+		tc.HTTPReq.Method = http.MethodGet
+	}
+	if len(item.Response) > 0 {
+		resp := item.Response[0]
+		tc.HTTPResp.StatusCode = resp.Code
+		tc.HTTPResp.StatusMessage = statusText(resp.Code, "")
+		for _, h := range resp.Header {
+			if !h.Disabled {
+				tc.HTTPResp.Header[http.CanonicalHeaderKey(h.Key)] = convert(h.Value)
+			}
+		}
+		tc.HTTPResp.Body = convert(resp.Body)
+	}
+	return tc, skipped, nil
+}
+
+// formSrc returns the paths of a file of a form, postman gives either a path or a list of paths.
+func formSrc(src interface{}) []string {
+	switch s := src.(type) {
+	case string:
+		return []string{s}
+	case []interface{}:
+		var paths []string
+		for _, p := range s {
+			if path, ok := p.(string); ok {
+				paths = append(paths, path)
+			}
+		}
+		return paths
+	}
+	return nil
+}
+
+// WritePostman writes the http test cases as the requests of a Postman collection, along with their
+// responses as the saved examples. The templatized values of the test cases are written as the variables
+// of the collection.
+func WritePostman(w io.Writer, name string, tcs []*models.TestCase, values map[string]interface{}) error {
+	coll := postmanCollection{
+		Info: postmanInfo{Name: name, Schema: postmanSchema},
+		Item: []postmanItem{},
+	}
+	for _, tc := range tcs {
+		if tc.Kind != models.HTTP {
+			continue
+		}
+		req := testCaseToPostmanRequest(tc)
+		coll.Item = append(coll.Item, postmanItem{
+			Name:    tc.Name,
+			Request: req,
+			Response: []postmanResponse{{
+				Name:            tc.Name,
+				OriginalRequest: req,
+				Status:          statusText(tc.HTTPResp.StatusCode, tc.HTTPResp.StatusMessage),
+				Code:            tc.HTTPResp.StatusCode,
+				Header:          toPostmanKVs(tc.HTTPResp.Header),
+				Body:            toPostmanVars(tc.HTTPResp.Body),
+			}},
+		})
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		coll.Variable = append(coll.Variable, postmanVariable{Key: key, Value: fmt.Sprint(values[key])})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(coll)
+}
+
+func testCaseToPostmanRequest(tc *models.TestCase) *postmanRequest {
+	req := &postmanRequest{
+		Method: string(tc.HTTPReq.Method),
+		Header: toPostmanKVs(tc.HTTPReq.Header),
+		URL:    postmanURL{Raw: toPostmanVars(tc.HTTPReq.URL)},
+	}
+	switch {
+	case len(tc.HTTPReq.Form) > 0:
+		body := &postmanBody{Mode: "formdata"}
+		for _, fd := range tc.HTTPReq.Form {
+			if len(fd.Paths) > 0 {
+				body.FormData = append(body.FormData, postmanKV{Key: fd.Key, Type: "file", Src: fd.Paths})
+				continue
+			}
+			for _, v := range fd.Values {
+				body.FormData = append(body.FormData, postmanKV{Key: fd.Key, Value: toPostmanVars(v), Type: "text"})
+			}
+		}
+		req.Body = body
+	case tc.HTTPReq.Body != "":
+		body := &postmanBody{Mode: "raw", Raw: toPostmanVars(tc.HTTPReq.Body)}
+		if strings.Contains(headerValue(tc.HTTPReq.Header, "Content-Type"), "json") {
+			body.Options = &postmanBodyOptions{}
+			body.Options.Raw.Language = "json"
+		}
+		req.Body = body
+	}
+	return req
+}
+
+func toPostmanKVs(header map[string]string) []postmanKV {
+	kvs := make([]postmanKV, 0, len(header))
+	for key, value := range header {
+		kvs = append(kvs, postmanKV{Key: key, Value: toPostmanVars(value)})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
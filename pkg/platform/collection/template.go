@@ -0,0 +1,69 @@
+// Package collection converts the test cases from and to the collections of requests shared by the
+// other http tools, the HAR files of the browsers and the Postman collections.
+package collection
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// keployVar matches the templatized values of the test cases, e.g. {{string .token}} or {{int .id }}
+var keployVar = regexp.MustCompile(`\{\{\s*(?:(?:string|int|float)\s+)?\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// postmanVar matches the variables of the Postman requests, e.g. {{token}}
+var postmanVar = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// templateKey is the form of the names the templates of the test cases can refer to
+var templateKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// toPostmanVars replaces the templatized values of the test case with the Postman variables of the same name.
+func toPostmanVars(s string) string {
+	return keployVar.ReplaceAllString(s, "{{$1}}")
+}
+
+// fromPostmanVars replaces the Postman variables with the templatized values of the test case. The dynamic
+// variables (e.g. {{$guid}}) and the names which can't be referred to in a template are left as they are,
+// they are returned to be reported.
+func fromPostmanVars(s string) (string, []string) {
+	var skipped []string
+	out := postmanVar.ReplaceAllStringFunc(s, func(match string) string {
+		name := postmanVar.FindStringSubmatch(match)[1]
+		if !templateKey.MatchString(name) {
+			skipped = append(skipped, name)
+			return match
+		}
+		return "{{string ." + name + "}}"
+	})
+	return out, skipped
+}
+
+// render replaces the templatized values of the test case with their values in the test-set config, for the
+// formats which have no variables.
+func render(s string, values map[string]interface{}) string {
+	if len(values) == 0 || !strings.Contains(s, "{{") {
+		return s
+	}
+	return keployVar.ReplaceAllStringFunc(s, func(match string) string {
+		name := keployVar.FindStringSubmatch(match)[1]
+		val, ok := values[name]
+		if !ok {
+			return match
+		}
+		return fmt.Sprint(val)
+	})
+}
+
+// queryEscape escapes the value for a form, leaving its templatized values as they are.
+func queryEscape(s string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range keployVar.FindAllStringIndex(s, -1) {
+		sb.WriteString(url.QueryEscape(s[last:loc[0]]))
+		sb.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(url.QueryEscape(s[last:]))
+	return sb.String()
+}
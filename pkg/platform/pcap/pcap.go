@@ -0,0 +1,292 @@
+// Package pcap reads the packet captures of tcpdump and wireshark (pcap and pcapng) and reassembles
+// the tcp connections found in them.
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// magic numbers of the capture files
+const (
+	pcapMicros   = 0xa1b2c3d4
+	pcapNanos    = 0xa1b23c4d
+	pcapngHeader = 0x0a0d0d0a
+	pcapngMagic  = 0x1a2b3c4d
+)
+
+// pcapng block types
+const (
+	blockInterface      = 0x00000001
+	blockSimplePacket   = 0x00000003
+	blockEnhancedPacket = 0x00000006
+)
+
+// maxSnapLen bounds the size of a packet read from the file, a larger size means a corrupted file
+const maxSnapLen = 1 << 18
+
+type packet struct {
+	linkType  uint32
+	timestamp time.Time
+	data      []byte
+}
+
+// ReadFile reads the capture file and returns its tcp connections in the order they were opened.
+func ReadFile(logger *zap.Logger, path string) ([]*models.CapturedConn, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := f.Close()
+		if err != nil {
+			utils.LogError(logger, err, "failed to close the capture file")
+		}
+	}()
+	return Read(logger, f)
+}
+
+// Read reads a capture and returns its tcp connections in the order they were opened.
+func Read(logger *zap.Logger, r io.Reader) ([]*models.CapturedConn, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header of the capture: %w", err)
+	}
+
+	a := newAssembler(logger)
+	handle := func(p packet) {
+		err := a.add(p)
+		if err != nil {
+			logger.Debug("skipping the packet", zap.Error(err))
+		}
+	}
+
+	switch {
+	case binary.LittleEndian.Uint32(magic) == pcapngHeader:
+		err = readPcapng(br, handle)
+	case binary.LittleEndian.Uint32(magic) == pcapMicros || binary.LittleEndian.Uint32(magic) == pcapNanos,
+		binary.BigEndian.Uint32(magic) == pcapMicros || binary.BigEndian.Uint32(magic) == pcapNanos:
+		err = readPcap(br, handle)
+	default:
+		return nil, errors.New("the file is neither a pcap nor a pcapng capture")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a.conns(), nil
+}
+
+// readPcap reads the classic pcap format, a global header followed by the records of the packets.
+func readPcap(r io.Reader, handle func(packet)) error {
+	header := make([]byte, 24)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return fmt.Errorf("failed to read the pcap header: %w", err)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	magic := order.Uint32(header)
+	if magic != pcapMicros && magic != pcapNanos {
+		order = binary.BigEndian
+		magic = order.Uint32(header)
+	}
+	resolution := time.Microsecond
+	if magic == pcapNanos {
+		resolution = time.Nanosecond
+	}
+	linkType := order.Uint32(header[20:]) & 0x0fffffff
+
+	record := make([]byte, 16)
+	for {
+		_, err := io.ReadFull(r, record)
+		if truncated(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the pcap record: %w", err)
+		}
+		inclLen := order.Uint32(record[8:])
+		if inclLen > maxSnapLen {
+			return fmt.Errorf("invalid length of the pcap record: %d", inclLen)
+		}
+		data := make([]byte, inclLen)
+		_, err = io.ReadFull(r, data)
+		if truncated(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the pcap record: %w", err)
+		}
+		sec, frac := order.Uint32(record), order.Uint32(record[4:])
+		handle(packet{
+			linkType:  linkType,
+			timestamp: time.Unix(int64(sec), int64(frac)*int64(resolution)),
+			data:      data,
+		})
+	}
+}
+
+type pcapngInterface struct {
+	linkType uint32
+	// resolution is the unit of the timestamps of the interface
+	resolution time.Duration
+}
+
+// readPcapng reads the pcapng format, a sequence of blocks. Only the blocks of the interfaces and the
+// packets are used, the others (e.g. name resolution, statistics) are skipped.
+func readPcapng(r io.Reader, handle func(packet)) error {
+	var order binary.ByteOrder = binary.LittleEndian
+	var interfaces []pcapngInterface
+	// the capture is only cut while being written after its first section header
+	started := false
+
+	head := make([]byte, 8)
+	for {
+		_, err := io.ReadFull(r, head)
+		if started && truncated(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the pcapng block: %w", err)
+		}
+
+		blockType := order.Uint32(head)
+		if blockType == pcapngHeader {
+			// every section has its own byte order and interfaces
+			bom := make([]byte, 4)
+			_, err = io.ReadFull(r, bom)
+			if started && truncated(err) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read the pcapng section header: %w", err)
+			}
+			switch {
+			case binary.LittleEndian.Uint32(bom) == pcapngMagic:
+				order = binary.LittleEndian
+			case binary.BigEndian.Uint32(bom) == pcapngMagic:
+				order = binary.BigEndian
+			default:
+				return errors.New("invalid byte order magic of the pcapng section")
+			}
+			interfaces = nil
+			length := order.Uint32(head[4:])
+			if length < 16 || length > maxSnapLen {
+				return fmt.Errorf("invalid length of the pcapng section header: %d", length)
+			}
+			_, err = io.CopyN(io.Discard, r, int64(length-12))
+			if started && truncated(err) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read the pcapng section header: %w", err)
+			}
+			started = true
+			continue
+		}
+
+		length := order.Uint32(head[4:])
+		if length < 12 || length%4 != 0 || length > maxSnapLen {
+			return fmt.Errorf("invalid length of the pcapng block: %d", length)
+		}
+		body := make([]byte, length-8)
+		_, err = io.ReadFull(r, body)
+		if started && truncated(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the pcapng block: %w", err)
+		}
+		// the length of the block is repeated at its end
+		body = body[:len(body)-4]
+
+		switch blockType {
+		case blockInterface:
+			if len(body) < 8 {
+				return errors.New("truncated pcapng interface block")
+			}
+			interfaces = append(interfaces, pcapngInterface{
+				linkType:   uint32(order.Uint16(body)),
+				resolution: interfaceResolution(order, body[8:]),
+			})
+		case blockEnhancedPacket:
+			if len(body) < 20 {
+				return errors.New("truncated pcapng packet block")
+			}
+			id := order.Uint32(body)
+			if int(id) >= len(interfaces) {
+				return fmt.Errorf("unknown interface of the pcapng packet: %d", id)
+			}
+			capLen := order.Uint32(body[12:])
+			if int(capLen) > len(body)-20 {
+				return errors.New("truncated pcapng packet block")
+			}
+			ts := uint64(order.Uint32(body[4:]))<<32 | uint64(order.Uint32(body[8:]))
+			handle(packet{
+				linkType:  interfaces[id].linkType,
+				timestamp: unitsToTime(ts, interfaces[id].resolution),
+				data:      body[20 : 20+capLen],
+			})
+		case blockSimplePacket:
+			// the simple packets belong to the first interface and have no timestamp
+			if len(interfaces) == 0 || len(body) < 4 {
+				continue
+			}
+			origLen := order.Uint32(body)
+			data := body[4:]
+			if int(origLen) < len(data) {
+				data = data[:origLen]
+			}
+			handle(packet{linkType: interfaces[0].linkType, data: data})
+		}
+	}
+}
+
+// truncated reports whether the error is the end of a capture cut while being written, the packets read
+// before the partial record are kept.
+func truncated(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// interfaceResolution reads the if_tsresol option of the interface, the timestamps are in microseconds by default.
+func interfaceResolution(order binary.ByteOrder, options []byte) time.Duration {
+	for len(options) >= 4 {
+		code, length := order.Uint16(options), int(order.Uint16(options[2:]))
+		if code == 0 || 4+length > len(options) {
+			break
+		}
+		if code == 9 && length >= 1 {
+			v := options[4]
+			if v&0x80 != 0 {
+				// the resolution is a negative power of 2, it is rounded to the nanosecond
+				return max(time.Duration(math.Pow(2, -float64(v&0x7f))*float64(time.Second)), time.Nanosecond)
+			}
+			res := time.Second
+			for i := byte(0); i < v && res > time.Nanosecond; i++ {
+				res /= 10
+			}
+			return res
+		}
+		options = options[4+(length+3)/4*4:]
+	}
+	return time.Microsecond
+}
+
+func unitsToTime(units uint64, resolution time.Duration) time.Time {
+	perSecond := uint64(time.Second / resolution)
+	if perSecond == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(units/perSecond), int64(units%perSecond)*int64(resolution))
+}
@@ -0,0 +1,271 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+var (
+	clientIP = []byte{10, 0, 0, 1}
+	serverIP = []byte{10, 0, 0, 2}
+)
+
+const (
+	clientPort = 40000
+	serverPort = 5432
+)
+
+// tcpPacket builds an ethernet frame of an ipv4 tcp segment.
+func tcpPacket(fromClient bool, seq uint32, flags byte, payload []byte) []byte {
+	src, dst := clientIP, serverIP
+	sport, dport := uint16(clientPort), uint16(serverPort)
+	if !fromClient {
+		src, dst = dst, src
+		sport, dport = dport, sport
+	}
+
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp, sport)
+	binary.BigEndian.PutUint16(tcp[2:], dport)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	tcp = append(tcp, payload...)
+
+	ip := make([]byte, 20, 20+len(tcp))
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+len(tcp)))
+	ip[8] = 64
+	ip[9] = 6
+	copy(ip[12:], src)
+	copy(ip[16:], dst)
+	ip = append(ip, tcp...)
+
+	eth := make([]byte, 14, 14+len(ip))
+	binary.BigEndian.PutUint16(eth[12:], etherTypeIPv4)
+	return append(eth, ip...)
+}
+
+// conversation returns the packets of a connection in which the client sends a query and the server replies.
+func conversation() [][]byte {
+	return [][]byte{
+		tcpPacket(true, 100, tcpSyn, nil),
+		tcpPacket(false, 500, tcpSyn|tcpAck, nil),
+		tcpPacket(true, 101, tcpAck, []byte("query")),
+		tcpPacket(false, 501, tcpAck, []byte("reply")),
+		tcpPacket(true, 106, tcpFin|tcpAck, nil),
+	}
+}
+
+func pcapFile(packets [][]byte) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, pcapMicros)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], maxSnapLen)
+	binary.LittleEndian.PutUint32(header[20:], linkEthernet)
+	buf.Write(header)
+	for i, p := range packets {
+		record := make([]byte, 16)
+		binary.LittleEndian.PutUint32(record, uint32(1700000000+i))
+		binary.LittleEndian.PutUint32(record[8:], uint32(len(p)))
+		binary.LittleEndian.PutUint32(record[12:], uint32(len(p)))
+		buf.Write(record)
+		buf.Write(p)
+	}
+	return buf.Bytes()
+}
+
+func pcapngBlock(blockType uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	block := make([]byte, 8, 12+len(body))
+	binary.LittleEndian.PutUint32(block, blockType)
+	binary.LittleEndian.PutUint32(block[4:], uint32(12+len(body)))
+	block = append(block, body...)
+	return binary.LittleEndian.AppendUint32(block, uint32(12+len(body)))
+}
+
+func pcapngSection() []byte {
+	section := make([]byte, 16)
+	binary.LittleEndian.PutUint32(section, pcapngMagic)
+	binary.LittleEndian.PutUint16(section[4:], 1)
+	binary.LittleEndian.PutUint64(section[8:], ^uint64(0))
+	return pcapngBlock(pcapngHeader, section)
+}
+
+func pcapngFile(packets [][]byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(pcapngSection())
+
+	iface := make([]byte, 8)
+	binary.LittleEndian.PutUint16(iface, linkEthernet)
+	binary.LittleEndian.PutUint32(iface[4:], maxSnapLen)
+	// if_tsresol of nanoseconds
+	iface = append(iface, 9, 0, 1, 0, 9, 0, 0, 0)
+	buf.Write(pcapngBlock(blockInterface, iface))
+
+	for i, p := range packets {
+		body := make([]byte, 20, 20+len(p))
+		ts := uint64(1700000000+i) * 1e9
+		binary.LittleEndian.PutUint32(body[4:], uint32(ts>>32))
+		binary.LittleEndian.PutUint32(body[8:], uint32(ts))
+		binary.LittleEndian.PutUint32(body[12:], uint32(len(p)))
+		binary.LittleEndian.PutUint32(body[16:], uint32(len(p)))
+		buf.Write(pcapngBlock(blockEnhancedPacket, append(body, p...)))
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture []byte
+	}{
+		{name: "pcap", capture: pcapFile(conversation())},
+		{name: "pcapng", capture: pcapngFile(conversation())},
+		{
+			name: "retransmitted and reordered segments",
+			capture: pcapFile([][]byte{
+				tcpPacket(true, 100, tcpSyn, nil),
+				tcpPacket(false, 500, tcpSyn|tcpAck, nil),
+				tcpPacket(true, 104, tcpAck, []byte("ry")),
+				tcpPacket(true, 101, tcpAck, []byte("que")),
+				tcpPacket(true, 101, tcpAck, []byte("query")),
+				tcpPacket(false, 501, tcpAck, []byte("reply")),
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conns, err := Read(zap.NewNop(), bytes.NewReader(tt.capture))
+			if err != nil {
+				t.Fatalf("failed to read the capture: %v", err)
+			}
+			if len(conns) != 1 {
+				t.Fatalf("got %d connections, want 1", len(conns))
+			}
+			conn := conns[0]
+			if conn.ClientAddr != "10.0.0.1:40000" || conn.ServerAddr != "10.0.0.2:5432" || conn.ServerPort != serverPort {
+				t.Fatalf("got the connection %s -> %s (%d)", conn.ClientAddr, conn.ServerAddr, conn.ServerPort)
+			}
+			if len(conn.Chunks) != 2 {
+				t.Fatalf("got %d chunks, want 2", len(conn.Chunks))
+			}
+			if !conn.Chunks[0].FromClient || string(conn.Chunks[0].Data) != "query" {
+				t.Errorf("got the first chunk %q (from client: %t)", conn.Chunks[0].Data, conn.Chunks[0].FromClient)
+			}
+			if conn.Chunks[1].FromClient || string(conn.Chunks[1].Data) != "reply" {
+				t.Errorf("got the second chunk %q (from client: %t)", conn.Chunks[1].Data, conn.Chunks[1].FromClient)
+			}
+		})
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	badIHL := tcpPacket(true, 101, tcpAck, []byte("query"))
+	badIHL[14] = 0x41
+
+	badOffset := tcpPacket(true, 101, tcpAck, []byte("query"))
+	badOffset[14+20+12] = 0xf0
+
+	hugeRecord := pcapFile(nil)
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record[8:], maxSnapLen+1)
+	hugeRecord = append(hugeRecord, record...)
+
+	unknownInterface := make([]byte, 20)
+	binary.LittleEndian.PutUint32(unknownInterface, 3)
+
+	// if_tsresol of 2^-127 seconds
+	tinyResolution := pcapngSection()
+	iface := make([]byte, 8)
+	binary.LittleEndian.PutUint16(iface, linkEthernet)
+	iface = append(iface, 9, 0, 1, 0, 0xff, 0, 0, 0)
+	tinyResolution = append(tinyResolution, pcapngBlock(blockInterface, iface)...)
+	packet := make([]byte, 20)
+	binary.LittleEndian.PutUint32(packet[8:], 1)
+	tinyResolution = append(tinyResolution, pcapngBlock(blockEnhancedPacket, packet)...)
+
+	tests := []struct {
+		name    string
+		capture []byte
+		wantErr bool
+	}{
+		{name: "empty", capture: nil, wantErr: true},
+		{name: "unknown magic", capture: []byte("GET / HTTP/1.1\r\n"), wantErr: true},
+		{name: "invalid ipv4 header length", capture: pcapFile([][]byte{badIHL})},
+		{name: "invalid tcp data offset", capture: pcapFile([][]byte{badOffset})},
+		{name: "short packets", capture: pcapFile([][]byte{{}, {1}, make([]byte, 14), make([]byte, 34)})},
+		{name: "record larger than the snap length", capture: hugeRecord, wantErr: true},
+		{name: "packet of an unknown interface", capture: append(pcapngFile(nil), pcapngBlock(blockEnhancedPacket, unknownInterface)...), wantErr: true},
+		{name: "block length not aligned", capture: append(pcapngFile(nil), 6, 0, 0, 0, 13, 0, 0, 0), wantErr: true},
+		{name: "timestamp resolution below the nanosecond", capture: tinyResolution},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(zap.NewNop(), bytes.NewReader(tt.capture))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got the error %v, want an error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestReadTruncated reads every prefix of the captures, a capture cut before the end of its header is
+// invalid and one cut while being written returns the packets before the partial record.
+func TestReadTruncated(t *testing.T) {
+	tests := []struct {
+		name      string
+		capture   []byte
+		headerLen int
+	}{
+		{name: "pcap", capture: pcapFile(conversation()), headerLen: 24},
+		{name: "pcapng", capture: pcapngFile(conversation()), headerLen: len(pcapngSection())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			full, err := Read(zap.NewNop(), bytes.NewReader(tt.capture))
+			if err != nil || len(full) != 1 {
+				t.Fatalf("failed to read the capture: %v", err)
+			}
+			for i := range tt.capture {
+				conns, err := Read(zap.NewNop(), bytes.NewReader(tt.capture[:i]))
+				if i < tt.headerLen {
+					if err == nil {
+						t.Fatalf("got no error for the capture cut at %d in its header", i)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("got the error %v for the capture cut at %d", err, i)
+				}
+				if len(conns) > 1 {
+					t.Fatalf("got %d connections for the capture cut at %d, want at most 1", len(conns), i)
+				}
+				if len(conns) == 0 {
+					continue
+				}
+				chunks := conns[0].Chunks
+				if len(chunks) > len(full[0].Chunks) {
+					t.Fatalf("got %d chunks for the capture cut at %d", len(chunks), i)
+				}
+				for j, chunk := range chunks {
+					want := full[0].Chunks[j]
+					if chunk.FromClient != want.FromClient || !bytes.Equal(chunk.Data, want.Data) {
+						t.Fatalf("got the chunk %q (from client: %t) for the capture cut at %d, want %q (from client: %t)",
+							chunk.Data, chunk.FromClient, i, want.Data, want.FromClient)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// link types of the captures
+const (
+	linkNull     = 0
+	linkEthernet = 1
+	linkRaw      = 101
+	linkLoop     = 108
+	linkLinuxSLL = 113
+	linkIPv4     = 228
+	linkIPv6     = 229
+	linkSLL2     = 276
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+)
+
+const (
+	tcpFin = 0x01
+	tcpSyn = 0x02
+	tcpRst = 0x04
+	tcpAck = 0x10
+)
+
+type endpoint struct {
+	ip   string
+	port uint16
+}
+
+func (e endpoint) String() string {
+	return net.JoinHostPort(e.ip, strconv.Itoa(int(e.port)))
+}
+
+// flowKey identifies a connection whatever the direction of the packet.
+type flowKey struct {
+	a, b endpoint
+}
+
+func newFlowKey(src, dst endpoint) flowKey {
+	if src.ip < dst.ip || (src.ip == dst.ip && src.port < dst.port) {
+		return flowKey{a: src, b: dst}
+	}
+	return flowKey{a: dst, b: src}
+}
+
+type segment struct {
+	seq   uint32
+	data  []byte
+	order int // order of the packet in the capture
+	time  time.Time
+}
+
+// half is a direction of a connection.
+type half struct {
+	isn      uint32
+	isnKnown bool
+	segments []segment
+}
+
+type flow struct {
+	client, server endpoint
+	// clientKnown is set once the handshake tells which side opened the connection
+	clientKnown bool
+	order       int
+	halves      map[endpoint]*half
+	closed      bool
+}
+
+type assembler struct {
+	logger *zap.Logger
+	flows  map[flowKey]*flow
+	done   []*flow
+	count  int
+}
+
+func newAssembler(logger *zap.Logger) *assembler {
+	return &assembler{
+		logger: logger,
+		flows:  make(map[flowKey]*flow),
+	}
+}
+
+// add decodes the packet down to its tcp segment and adds the segment to its connection.
+func (a *assembler) add(p packet) error {
+	a.count++
+	src, dst, tcp, err := decode(p.linkType, p.data)
+	if err != nil {
+		return err
+	}
+	if len(tcp) < 20 {
+		return errors.New("truncated tcp header")
+	}
+	src.port = binary.BigEndian.Uint16(tcp)
+	dst.port = binary.BigEndian.Uint16(tcp[2:])
+	seq := binary.BigEndian.Uint32(tcp[4:])
+	offset := int(tcp[12]>>4) * 4
+	flags := tcp[13]
+	if offset < 20 || offset > len(tcp) {
+		return errors.New("invalid tcp data offset")
+	}
+	payload := tcp[offset:]
+
+	key := newFlowKey(src, dst)
+	f, ok := a.flows[key]
+	// a new handshake on the addresses of a closed connection opens another connection
+	if ok && flags&tcpSyn != 0 && flags&tcpAck == 0 && (f.closed || f.hasData()) {
+		a.done = append(a.done, f)
+		ok = false
+	}
+	if !ok {
+		f = &flow{
+			client: src,
+			server: dst,
+			order:  a.count,
+			halves: make(map[endpoint]*half),
+		}
+		// without the handshake, the well-known port is taken as the one of the server
+		if flags&tcpSyn == 0 && src.port < dst.port {
+			f.client, f.server = dst, src
+		}
+		a.flows[key] = f
+	}
+
+	h := f.halves[src]
+	if h == nil {
+		h = &half{}
+		f.halves[src] = h
+	}
+	if flags&tcpSyn != 0 {
+		// the syn takes a sequence number
+		h.isn, h.isnKnown = seq+1, true
+		if !f.clientKnown {
+			f.clientKnown = true
+			if flags&tcpAck == 0 {
+				f.client, f.server = src, dst
+			} else {
+				f.client, f.server = dst, src
+			}
+		}
+	}
+	if flags&(tcpFin|tcpRst) != 0 {
+		f.closed = true
+	}
+	if len(payload) > 0 {
+		h.segments = append(h.segments, segment{
+			seq:   seq,
+			data:  payload,
+			order: a.count,
+			time:  p.timestamp,
+		})
+	}
+	return nil
+}
+
+func (f *flow) hasData() bool {
+	for _, h := range f.halves {
+		if len(h.segments) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// conns returns the connections having data, in the order they were opened.
+func (a *assembler) conns() []*models.CapturedConn {
+	flows := append([]*flow{}, a.done...)
+	for _, f := range a.flows {
+		flows = append(flows, f)
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].order < flows[j].order
+	})
+
+	var conns []*models.CapturedConn
+	for _, f := range flows {
+		chunks := f.chunks(a.logger)
+		if len(chunks) == 0 {
+			continue
+		}
+		conns = append(conns, &models.CapturedConn{
+			ClientAddr: f.client.String(),
+			ServerAddr: f.server.String(),
+			ServerPort: uint32(f.server.port),
+			Chunks:     chunks,
+		})
+	}
+	return conns
+}
+
+type orderedChunk struct {
+	models.CapturedChunk
+	order int
+}
+
+// chunks reassembles both directions of the connection and interleaves them in the order of the capture.
+func (f *flow) chunks(logger *zap.Logger) []models.CapturedChunk {
+	var all []orderedChunk
+	for ep, h := range f.halves {
+		all = append(all, h.reassemble(logger, ep == f.client, f)...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].order < all[j].order
+	})
+
+	var chunks []models.CapturedChunk
+	for _, c := range all {
+		if n := len(chunks); n > 0 && chunks[n-1].FromClient == c.FromClient {
+			chunks[n-1].Data = append(chunks[n-1].Data, c.Data...)
+			continue
+		}
+		chunks = append(chunks, c.CapturedChunk)
+	}
+	return chunks
+}
+
+// reassemble orders the segments of the direction on their sequence number, dropping the retransmitted data.
+func (h *half) reassemble(logger *zap.Logger, fromClient bool, f *flow) []orderedChunk {
+	if len(h.segments) == 0 {
+		return nil
+	}
+	base := h.isn
+	if !h.isnKnown {
+		// the capture started after the handshake, the lowest sequence number is taken as the start
+		base = h.segments[0].seq
+		for _, s := range h.segments[1:] {
+			if int32(s.seq-base) < 0 {
+				base = s.seq
+			}
+		}
+	}
+	segments := append([]segment{}, h.segments...)
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].seq-base < segments[j].seq-base
+	})
+
+	var out []orderedChunk
+	var next uint32
+	order := 0
+	for _, s := range segments {
+		start := s.seq - base
+		end := start + uint32(len(s.data))
+		if end <= next {
+			continue
+		}
+		data := s.data
+		if start < next {
+			data = data[next-start:]
+		} else if start > next {
+			logger.Debug("missing data in the captured connection", zap.Any("client", f.client.String()), zap.Any("server", f.server.String()), zap.Any("bytes", start-next))
+		}
+		next = end
+		// the data is sent after the data before it, even when its packet was captured earlier
+		if s.order > order {
+			order = s.order
+		}
+		out = append(out, orderedChunk{
+			CapturedChunk: models.CapturedChunk{
+				FromClient: fromClient,
+				Data:       data,
+				Timestamp:  s.time,
+			},
+			order: order,
+		})
+	}
+	return out
+}
+
+// decode returns the addresses of the packet and its tcp segment.
+func decode(linkType uint32, data []byte) (endpoint, endpoint, []byte, error) {
+	var etherType uint16
+	switch linkType {
+	case linkEthernet:
+		if len(data) < 14 {
+			return endpoint{}, endpoint{}, nil, errors.New("truncated ethernet frame")
+		}
+		etherType, data = binary.BigEndian.Uint16(data[12:]), data[14:]
+		for etherType == etherTypeVLAN && len(data) >= 4 {
+			etherType, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+		}
+	case linkLinuxSLL:
+		if len(data) < 16 {
+			return endpoint{}, endpoint{}, nil, errors.New("truncated linux cooked header")
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:]), data[16:]
+	case linkSLL2:
+		if len(data) < 20 {
+			return endpoint{}, endpoint{}, nil, errors.New("truncated linux cooked header")
+		}
+		etherType, data = binary.BigEndian.Uint16(data), data[20:]
+	case linkNull, linkLoop:
+		if len(data) < 4 {
+			return endpoint{}, endpoint{}, nil, errors.New("truncated loopback header")
+		}
+		data = data[4:]
+	case linkRaw, linkIPv4, linkIPv6:
+	default:
+		return endpoint{}, endpoint{}, nil, fmt.Errorf("unsupported link type: %d", linkType)
+	}
+	if len(data) == 0 {
+		return endpoint{}, endpoint{}, nil, errors.New("empty packet")
+	}
+	if etherType == 0 {
+		// the version of the ip header tells the type of the packet
+		switch data[0] >> 4 {
+		case 4:
+			etherType = etherTypeIPv4
+		case 6:
+			etherType = etherTypeIPv6
+		}
+	}
+
+	switch etherType {
+	case etherTypeIPv4:
+		if len(data) < 20 {
+			return endpoint{}, endpoint{}, nil, errors.New("truncated ipv4 header")
+		}
+		ihl := int(data[0]&0x0f) * 4
+		if ihl < 20 {
+			return endpoint{}, endpoint{}, nil, errors.New("invalid ipv4 header length")
+		}
+		total := int(binary.BigEndian.Uint16(data[2:]))
+		if data[9] != 6 {
+			return endpoint{}, endpoint{}, nil, errors.New("not a tcp packet")
+		}
+		// the fragments are not reassembled, tcp avoids fragmentation anyway
+		if binary.BigEndian.Uint16(data[6:])&0x3fff != 0 {
+			return endpoint{}, endpoint{}, nil, errors.New("fragmented ipv4 packet")
+		}
+		if total < ihl || total > len(data) {
+			// the capture may be truncated by the snap length or padded by the link
+			total = min(len(data), max(total, ihl))
+		}
+		if ihl > total {
+			return endpoint{}, endpoint{}, nil, errors.New("invalid ipv4 header length")
+		}
+		src := endpoint{ip: net.IP(data[12:16]).String()}
+		dst := endpoint{ip: net.IP(data[16:20]).String()}
+		return src, dst, data[ihl:total], nil
+	case etherTypeIPv6:
+		if len(data) < 40 {
+			return endpoint{}, endpoint{}, nil, errors.New("truncated ipv6 header")
+		}
+		if data[6] != 6 {
+			// the extension headers are not walked through
+			return endpoint{}, endpoint{}, nil, errors.New("not a tcp packet")
+		}
+		total := min(40+int(binary.BigEndian.Uint16(data[4:])), len(data))
+		src := endpoint{ip: net.IP(data[8:24]).String()}
+		dst := endpoint{ip: net.IP(data[24:40]).String()}
+		return src, dst, data[40:total], nil
+	default:
+		return endpoint{}, endpoint{}, nil, fmt.Errorf("unsupported ether type: %#x", etherType)
+	}
+}
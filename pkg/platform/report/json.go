@@ -0,0 +1,80 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// JSON exports the test run as a single machine-readable JSON document.
+type JSON struct {
+	logger *zap.Logger
+	path   string
+}
+
+func NewJSON(logger *zap.Logger, path string) *JSON {
+	return &JSON{
+		logger: logger,
+		path:   path,
+	}
+}
+
+type jsonTestRun struct {
+	TestRunID string        `json:"testRunId"`
+	Status    string        `json:"status"`
+	Total     int           `json:"total"`
+	Success   int           `json:"success"`
+	Failure   int           `json:"failure"`
+	Ignored   int           `json:"ignored"`
+	TestSets  []jsonTestSet `json:"testSets"`
+}
+
+type jsonTestSet struct {
+	*models.TestReport
+	Failures []jsonFailure `json:"failures,omitempty"`
+}
+
+type jsonFailure struct {
+	TestCaseID string `json:"testCaseID"`
+	Diff       string `json:"diff"`
+}
+
+// Export writes the report of the test run to <path>/<testRunID>/report.json
+func (j *JSON) Export(_ context.Context, testRunID string, reports []*models.TestReport) error {
+	run := jsonTestRun{
+		TestRunID: testRunID,
+		Status:    string(models.TestSetStatusPassed),
+		TestSets:  []jsonTestSet{},
+	}
+	for _, report := range reports {
+		run.Total += report.Total
+		run.Success += report.Success
+		run.Failure += report.Failure
+		run.Ignored += report.Ignored
+		switch models.TestSetStatus(report.Status) {
+		case models.TestSetStatusPassed, models.TestSetStatusIgnored:
+		default:
+			run.Status = string(models.TestSetStatusFailed)
+		}
+
+		view := jsonTestSet{TestReport: report}
+		for _, test := range report.Tests {
+			if test.Status == models.TestStatusFailed {
+				view.Failures = append(view.Failures, jsonFailure{TestCaseID: test.TestCaseID, Diff: FailureDiff(test.Result)})
+			}
+		}
+		run.TestSets = append(run.TestSets, view)
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		utils.LogError(j.logger, err, "failed to marshal the json report")
+		return err
+	}
+
+	return writeReport(j.logger, filepath.Join(j.path, testRunID), "report.json", data)
+}
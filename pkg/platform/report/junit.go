@@ -0,0 +1,203 @@
+// Package report provides the exporters of the test run reports in the formats understood by other tools (e.g. CI systems)
+package report
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// JUnit exports the test run as a JUnit XML report, with a testsuite per test-set and a testcase per test.
+type JUnit struct {
+	logger *zap.Logger
+	path   string
+}
+
+func NewJUnit(logger *zap.Logger, path string) *JUnit {
+	return &JUnit{
+		logger: logger,
+		path:   path,
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+	SystemErr string          `xml:"system-err,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+// Export writes the report of the test run to <path>/<testRunID>/junit.xml
+func (j *JUnit) Export(_ context.Context, testRunID string, reports []*models.TestReport) error {
+	suites := junitTestSuites{
+		Name: "keploy-" + testRunID,
+	}
+
+	var total int64
+	for _, report := range reports {
+		suite := junitTestSuite{
+			Name:     report.TestSet,
+			Tests:    report.Total,
+			Failures: report.Failure,
+			Skipped:  report.Ignored,
+		}
+
+		var suiteTime, started int64
+		for _, test := range report.Tests {
+			duration := testDuration(test)
+			suiteTime += duration
+			if started == 0 || (test.Started != 0 && test.Started < started) {
+				started = test.Started
+			}
+
+			tc := junitTestCase{
+				Name:      test.TestCaseID,
+				ClassName: report.TestSet,
+				File:      test.TestCasePath,
+				Time:      fmt.Sprintf("%d", duration),
+			}
+			switch test.Status {
+			case models.TestStatusFailed:
+				tc.Failure = &junitFailure{
+					Message: "the response of the application did not match the recorded response",
+					Type:    string(test.Status),
+					Content: FailureDiff(test.Result),
+				}
+			case models.TestStatusIgnored:
+				tc.Skipped = &struct{}{}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		// the test-set could not be run till the end
+		switch models.TestSetStatus(report.Status) {
+		case models.TestSetStatusPassed, models.TestSetStatusFailed, models.TestSetStatusIgnored:
+		default:
+			suite.Errors = 1
+			suite.SystemErr = fmt.Sprintf("test-set finished with the status %s", report.Status)
+		}
+
+		suite.Time = fmt.Sprintf("%d", suiteTime)
+		if started != 0 {
+			suite.Timestamp = unixToRFC3339(started)
+		}
+		total += suiteTime
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = fmt.Sprintf("%d", total)
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		utils.LogError(j.logger, err, "failed to marshal the junit report")
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+
+	return writeReport(j.logger, filepath.Join(j.path, testRunID), "junit.xml", data)
+}
+
+// FailureDiff describes the differences between the expected and the actual response of a failed test.
+func FailureDiff(result models.Result) string {
+	var diff strings.Builder
+	if !result.StatusCode.Normal {
+		diff.WriteString(fmt.Sprintf("status code: expected %d, actual %d\n", result.StatusCode.Expected, result.StatusCode.Actual))
+	}
+	for _, header := range result.HeadersResult {
+		if header.Normal {
+			continue
+		}
+		diff.WriteString(fmt.Sprintf("header %s: expected %v, actual %v\n", header.Expected.Key, header.Expected.Value, header.Actual.Value))
+	}
+	for _, trailer := range result.TrailerResult {
+		if trailer.Normal {
+			continue
+		}
+		diff.WriteString(fmt.Sprintf("trailer %s: expected %v, actual %v\n", trailer.Expected.Key, trailer.Expected.Value, trailer.Actual.Value))
+	}
+	for _, body := range result.BodyResult {
+		if body.Normal {
+			continue
+		}
+		diff.WriteString(fmt.Sprintf("body (%s):\n  expected: %s\n  actual: %s\n", body.Type, body.Expected, body.Actual))
+	}
+	for _, dep := range result.DepResult {
+		for _, meta := range dep.Meta {
+			if meta.Normal {
+				continue
+			}
+			diff.WriteString(fmt.Sprintf("dependency %s (%s) %s: expected %s, actual %s\n", dep.Name, dep.Type, meta.Key, meta.Expected, meta.Actual))
+		}
+	}
+	if result.LatencyResult != nil && !result.LatencyResult.Normal {
+		diff.WriteString(fmt.Sprintf("latency: recorded %dms, allowed %dms, actual %dms\n", result.LatencyResult.Expected, result.LatencyResult.Allowed, result.LatencyResult.Actual))
+	}
+	return diff.String()
+}
+
+func testDuration(test models.TestResult) int64 {
+	if test.Completed < test.Started {
+		return 0
+	}
+	return test.Completed - test.Started
+}
+
+func unixToRFC3339(sec int64) string {
+	return time.Unix(sec, 0).UTC().Format(time.RFC3339)
+}
+
+func writeReport(logger *zap.Logger, dir, name string, data []byte) error {
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		utils.LogError(logger, err, "failed to create the directory of the report", zap.String("path", dir))
+		return err
+	}
+	err = os.WriteFile(filepath.Join(dir, name), data, 0777)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the report", zap.String("path", filepath.Join(dir, name)))
+		return err
+	}
+	logger.Info("report exported", zap.String("path", filepath.Join(dir, name)))
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package sqlite stores the test cases, the mocks, the test-set configs and the reports in an embedded
+// sqlite database instead of the yaml files, for the large suites which are slow to load from thousands of
+// files. The records are kept in the same yaml documents as the files, next to the columns they are queried by.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	// the pure go driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// FileName is the name of the database file in the keploy directory.
+const FileName = "keploy.db"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS test_cases (
+	test_set_id   TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	kind          TEXT NOT NULL,
+	method        TEXT NOT NULL DEFAULT '',
+	url           TEXT NOT NULL DEFAULT '',
+	status_code   INTEGER NOT NULL DEFAULT 0,
+	req_timestamp INTEGER NOT NULL DEFAULT 0,
+	doc           TEXT NOT NULL,
+	PRIMARY KEY (test_set_id, name)
+);
+CREATE INDEX IF NOT EXISTS test_cases_url ON test_cases (url);
+
+CREATE TABLE IF NOT EXISTS mocks (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	test_set_id   TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	kind          TEXT NOT NULL,
+	unfiltered    INTEGER NOT NULL DEFAULT 0,
+	req_timestamp INTEGER NOT NULL DEFAULT 0,
+	res_timestamp INTEGER NOT NULL DEFAULT 0,
+	doc           TEXT NOT NULL,
+	UNIQUE (test_set_id, name)
+);
+CREATE INDEX IF NOT EXISTS mocks_test_set ON mocks (test_set_id, unfiltered, req_timestamp);
+
+CREATE TABLE IF NOT EXISTS test_set_configs (
+	test_set_id TEXT PRIMARY KEY,
+	doc         TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS test_results (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	test_run_id TEXT NOT NULL,
+	test_set_id TEXT NOT NULL,
+	test_case_id TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	doc         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS test_results_run ON test_results (test_run_id, test_set_id);
+
+CREATE TABLE IF NOT EXISTS reports (
+	test_run_id TEXT NOT NULL,
+	test_set_id TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	doc         TEXT NOT NULL,
+	PRIMARY KEY (test_run_id, test_set_id)
+);
+
+CREATE TABLE IF NOT EXISTS coverage (
+	test_run_id TEXT PRIMARY KEY,
+	doc         TEXT NOT NULL
+);
+`
+
+// DB is the sqlite database of a keploy directory.
+type DB struct {
+	db     *sql.DB
+	logger *zap.Logger
+	path   string
+}
+
+// Open opens the database of the keploy directory at path, creating it and its tables if needed.
+func Open(logger *zap.Logger, path string) (*DB, error) {
+	err := os.MkdirAll(path, 0777)
+	if err != nil {
+		utils.LogError(logger, err, "failed to create the keploy directory", zap.String("path", path))
+		return nil, err
+	}
+	dbPath := filepath.Join(path, FileName)
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		utils.LogError(logger, err, "failed to open the sqlite database", zap.String("path", dbPath))
+		return nil, err
+	}
+	// sqlite has a single writer, the writes of the services are serialized here instead of failing as busy
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		utils.LogError(logger, err, "failed to create the tables of the sqlite database", zap.String("path", dbPath))
+		if cerr := db.Close(); cerr != nil {
+			utils.LogError(logger, cerr, "failed to close the sqlite database")
+		}
+		return nil, err
+	}
+	return &DB{db: db, logger: logger, path: dbPath}, nil
+}
+
+// Path returns the path of the database file.
+func (d *DB) Path() string {
+	return d.path
+}
+
+// Close closes the database.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// withTx runs fn in a transaction, committed if fn returns no error.
+func (d *DB) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin the transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			utils.LogError(d.logger, rerr, "failed to rollback the transaction")
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// unixNano returns the timestamp as stored in the tables, 0 for a missing timestamp.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
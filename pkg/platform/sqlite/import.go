@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// ImportTestSet replaces the test cases, the mocks and the config of the test-set in a single transaction.
+// Unlike the inserts of a recording, the names of the test cases and the mocks are kept.
+func (d *DB) ImportTestSet(ctx context.Context, testSetID string, tcs []*models.TestCase, mocks []*models.Mock, conf *models.TestSet) error {
+	tests := NewTestDB(d)
+	mockDB := NewMockDB(d)
+	return d.withTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"test_cases", "mocks", "test_set_configs"} {
+			_, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE test_set_id = ?", testSetID)
+			if err != nil {
+				return err
+			}
+		}
+		for _, tc := range tcs {
+			if _, err := tests.upsert(ctx, tx, testSetID, tc); err != nil {
+				return err
+			}
+		}
+		for _, mock := range mocks {
+			if err := mockDB.insert(ctx, tx, testSetID, mock); err != nil {
+				return err
+			}
+		}
+		if conf != nil {
+			return writeConfig(ctx, tx, d.logger, testSetID, conf)
+		}
+		return nil
+	})
+}
+
+// ImportReport writes the report of the test-set in the test run, with its test results.
+func (d *DB) ImportReport(ctx context.Context, testRunID string, testSetID string, report *models.TestReport) error {
+	reports := NewReportDB(d)
+	_, err := d.db.ExecContext(ctx, `DELETE FROM test_results WHERE test_run_id = ? AND test_set_id = ?`, testRunID, testSetID)
+	if err != nil {
+		return err
+	}
+	for i := range report.Tests {
+		if err := reports.InsertTestCaseResult(ctx, testRunID, testSetID, &report.Tests[i]); err != nil {
+			return err
+		}
+	}
+	return reports.InsertReport(ctx, testRunID, testSetID, report)
+}
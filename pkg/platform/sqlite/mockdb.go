@@ -0,0 +1,185 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
+	"go.keploy.io/server/v2/pkg/platform/yaml/mockdb"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// MockDB stores the mocks of the test-sets, the mocks of a test case are filtered by their timestamps in
+// the query instead of decoding all the mocks of the test-set.
+type MockDB struct {
+	*DB
+}
+
+func NewMockDB(db *DB) *MockDB {
+	return &MockDB{DB: db}
+}
+
+func (m *MockDB) InsertMock(ctx context.Context, mock *models.Mock, testSetID string) error {
+	return m.AppendMocks(ctx, testSetID, []*models.Mock{mock})
+}
+
+// AppendMocks appends the mocks to the mocks of the test-set, they are numbered after the last mock of the
+// test-set so that their names stay unique.
+func (m *MockDB) AppendMocks(ctx context.Context, testSetID string, mocks []*models.Mock) error {
+	return m.withTx(ctx, func(tx *sql.Tx) error {
+		var next int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(MAX(CAST(SUBSTR(name, 6) AS INTEGER)) + 1, 0) FROM mocks
+			WHERE test_set_id = ? AND name GLOB 'mock-[0-9]*'`, testSetID).Scan(&next)
+		if err != nil {
+			return err
+		}
+		for _, mock := range mocks {
+			mock.Name = fmt.Sprint("mock-", next)
+			next++
+			if err := m.insert(ctx, tx, testSetID, mock); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// insert writes the mock under its name.
+func (m *MockDB) insert(ctx context.Context, ex execer, testSetID string, mock *models.Mock) error {
+	mockYaml, err := mockdb.EncodeMock(mock, m.logger)
+	if err != nil {
+		return err
+	}
+	data, err := yamlLib.Marshal(&mockYaml)
+	if err != nil {
+		return err
+	}
+	unfiltered := 0
+	if mockdb.IsUnFiltered(mock) {
+		unfiltered = 1
+	}
+	_, err = ex.ExecContext(ctx, `
+		INSERT INTO mocks (test_set_id, name, kind, unfiltered, req_timestamp, res_timestamp, doc)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		testSetID, mock.Name, string(mock.Kind), unfiltered, unixNano(mock.Spec.ReqTimestampMock), unixNano(mock.Spec.ResTimestampMock), string(data))
+	if err != nil {
+		utils.LogError(m.logger, err, "failed to write the mock to the sqlite database", zap.Any("mock", mock.Name), zap.Any("for testset", testSetID))
+		return err
+	}
+	return nil
+}
+
+// UpdateMocks deletes the mocks of the test-set which are not in mockNames
+func (m *MockDB) UpdateMocks(ctx context.Context, testSetID string, mockNames map[string]bool) error {
+	m.logger.Debug("logging the names of the used mocks", zap.Any("mockNames", mockNames), zap.Any("for testset", testSetID))
+	return m.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT name FROM mocks WHERE test_set_id = ?`, testSetID)
+		if err != nil {
+			return err
+		}
+		names, err := scanStrings(rows)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if _, ok := mockNames[name]; ok {
+				continue
+			}
+			_, err := tx.ExecContext(ctx, `DELETE FROM mocks WHERE test_set_id = ? AND name = ?`, testSetID, name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetFilteredMocks returns the mocks which are matched only for the test cases recorded around them, those
+// between afterTime and beforeTime. All of them are returned when the window isn't set.
+func (m *MockDB) GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error) {
+	if afterTime.IsZero() || beforeTime.IsZero() {
+		mocks, _, err := m.query(ctx, `
+			SELECT doc, 0 FROM mocks WHERE test_set_id = ? AND unfiltered = 0
+			ORDER BY req_timestamp, id`, testSetID)
+		return mocks, err
+	}
+	// the mocks missing a timestamp are kept, like in the yaml mock db
+	mocks, _, err := m.query(ctx, `
+		SELECT doc, 1 FROM mocks WHERE test_set_id = ? AND unfiltered = 0
+			AND (req_timestamp = 0 OR res_timestamp = 0 OR (req_timestamp > ? AND res_timestamp < ?))
+		ORDER BY req_timestamp, id`, testSetID, afterTime.UnixNano(), beforeTime.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	for _, mock := range mocks {
+		mock.TestModeInfo.IsFiltered = true
+	}
+	return mocks, nil
+}
+
+// GetUnFilteredMocks returns the mocks which can be matched by any test case of the test-set, those between
+// afterTime and beforeTime first.
+func (m *MockDB) GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error) {
+	if afterTime.IsZero() || beforeTime.IsZero() {
+		mocks, _, err := m.query(ctx, `
+			SELECT doc, 0 FROM mocks WHERE test_set_id = ? AND unfiltered = 1
+			ORDER BY req_timestamp, id`, testSetID)
+		return mocks, err
+	}
+	mocks, inWindow, err := m.query(ctx, `
+		SELECT doc, in_window FROM (
+			SELECT id, doc, req_timestamp,
+				(req_timestamp = 0 OR res_timestamp = 0 OR (req_timestamp > ? AND res_timestamp < ?)) AS in_window
+			FROM mocks WHERE test_set_id = ? AND unfiltered = 1
+		) ORDER BY in_window DESC, req_timestamp, id`, afterTime.UnixNano(), beforeTime.UnixNano(), testSetID)
+	if err != nil {
+		return nil, err
+	}
+	for i, mock := range mocks {
+		mock.TestModeInfo.IsFiltered = inWindow[i]
+	}
+	return mocks, nil
+}
+
+// query decodes the mocks of the rows of (doc, flag).
+func (m *MockDB) query(ctx context.Context, query string, args ...any) ([]*models.Mock, []bool, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		utils.LogError(m.logger, err, "failed to read the mocks from the sqlite database")
+		return nil, nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var mocks []*models.Mock
+	var flags []bool
+	for rows.Next() {
+		var data string
+		var flag bool
+		if err := rows.Scan(&data, &flag); err != nil {
+			return nil, nil, err
+		}
+		var doc *yaml.NetworkTrafficDoc
+		if err := yamlLib.Unmarshal([]byte(data), &doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode the yaml document of the mock. error: %v", err.Error())
+		}
+		// the mocks of the kinds which don't belong to the open source version are skipped while decoding
+		decoded, err := mockdb.DecodeMocks([]*yaml.NetworkTrafficDoc{doc}, m.logger)
+		if err != nil {
+			utils.LogError(m.logger, err, "failed to decode the mocks from yaml docs")
+			return nil, nil, err
+		}
+		for _, mock := range decoded {
+			mocks = append(mocks, mock)
+			flags = append(flags, flag)
+		}
+	}
+	return mocks, flags, rows.Err()
+}
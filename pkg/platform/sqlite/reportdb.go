@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// ReportDB stores the results of the test runs.
+type ReportDB struct {
+	*DB
+}
+
+func NewReportDB(db *DB) *ReportDB {
+	return &ReportDB{DB: db}
+}
+
+func (r *ReportDB) GetAllTestRunIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT test_run_id FROM reports
+		UNION SELECT test_run_id FROM test_results
+		UNION SELECT test_run_id FROM coverage
+		ORDER BY test_run_id`)
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to read the test runs from the sqlite database")
+		return nil, err
+	}
+	return scanStrings(rows)
+}
+
+func (r *ReportDB) InsertTestCaseResult(ctx context.Context, testRunID string, testSetID string, result *models.TestResult) error {
+	data, err := yamlLib.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("%s failed to marshal document to yaml. error: %s", utils.Emoji, err.Error())
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO test_results (test_run_id, test_set_id, test_case_id, status, doc) VALUES (?, ?, ?, ?, ?)`,
+		testRunID, testSetID, result.TestCaseID, string(result.Status), string(data))
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to write the test result to the sqlite database", zap.String("testcase id", result.TestCaseID))
+		return err
+	}
+	return nil
+}
+
+func (r *ReportDB) GetTestCaseResults(ctx context.Context, testRunID string, testSetID string) ([]models.TestResult, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT doc FROM test_results WHERE test_run_id = ? AND test_set_id = ? ORDER BY id`, testRunID, testSetID)
+	if err != nil {
+		return []models.TestResult{}, err
+	}
+	docs, err := scanStrings(rows)
+	if err != nil {
+		return []models.TestResult{}, err
+	}
+	if len(docs) == 0 {
+		return []models.TestResult{}, fmt.Errorf("%s found no test results for test set with id: %s", utils.Emoji, testSetID)
+	}
+	results := make([]models.TestResult, 0, len(docs))
+	for _, doc := range docs {
+		var result models.TestResult
+		if err := yamlLib.Unmarshal([]byte(doc), &result); err != nil {
+			return []models.TestResult{}, fmt.Errorf("%s failed to decode the test result. error: %v", utils.Emoji, err.Error())
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (r *ReportDB) GetReport(ctx context.Context, testRunID string, testSetID string) (*models.TestReport, error) {
+	var data string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT doc FROM reports WHERE test_run_id = ? AND test_set_id = ?`, testRunID, testSetID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s found no report of the test set %s in the test run %s", utils.Emoji, testSetID, testRunID)
+	}
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to read the report from the sqlite database", zap.String("test run", testRunID))
+		return nil, err
+	}
+	var doc models.TestReport
+	if err := yamlLib.Unmarshal([]byte(data), &doc); err != nil {
+		return &models.TestReport{}, fmt.Errorf("%s failed to decode the report. error: %v", utils.Emoji, err.Error())
+	}
+	return &doc, nil
+}
+
+func (r *ReportDB) InsertReport(ctx context.Context, testRunID string, testSetID string, testReport *models.TestReport) error {
+	if testReport.Name == "" {
+		testReport.Name = testSetID + "-report"
+	}
+	data, err := yamlLib.Marshal(&testReport)
+	if err != nil {
+		return fmt.Errorf("%s failed to marshal document to yaml. error: %s", utils.Emoji, err.Error())
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO reports (test_run_id, test_set_id, status, doc) VALUES (?, ?, ?, ?)
+		ON CONFLICT (test_run_id, test_set_id) DO UPDATE SET status = excluded.status, doc = excluded.doc`,
+		testRunID, testSetID, testReport.Status, string(data))
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to write the report to the sqlite database", zap.String("test run", testRunID))
+		return err
+	}
+	return nil
+}
+
+func (r *ReportDB) UpdateReport(ctx context.Context, testRunID string, coverageReport any) error {
+	data, err := yamlLib.Marshal(&coverageReport)
+	if err != nil {
+		return fmt.Errorf("%s failed to marshal document to yaml. error: %s", utils.Emoji, err.Error())
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO coverage (test_run_id, doc) VALUES (?, ?)
+		ON CONFLICT (test_run_id) DO UPDATE SET doc = excluded.doc`, testRunID, string(data))
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to write the coverage report to the sqlite database", zap.String("test run", testRunID))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
+	"go.keploy.io/server/v2/pkg/platform/yaml/testdb"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// TestDB stores the test cases of the test-sets.
+type TestDB struct {
+	*DB
+}
+
+func NewTestDB(db *DB) *TestDB {
+	return &TestDB{DB: db}
+}
+
+func (t *TestDB) InsertTestCase(ctx context.Context, tc *models.TestCase, testSetID string) error {
+	name, err := t.upsert(ctx, t.db, testSetID, tc)
+	if err != nil {
+		return err
+	}
+	t.logger.Info("🟠 Keploy has captured test cases for the user's application.", zap.String("path", t.path), zap.String("testcase name", name))
+	return nil
+}
+
+func (t *TestDB) UpdateTestCase(ctx context.Context, tc *models.TestCase, testSetID string) error {
+	name, err := t.upsert(ctx, t.db, testSetID, tc)
+	if err != nil {
+		return err
+	}
+	t.logger.Info("🔄 Keploy has updated the test cases for the user's application.", zap.String("path", t.path), zap.String("testcase name", name))
+	return nil
+}
+
+// GetAllTestSetIDs returns the test-sets having any test case, mock or config.
+func (t *TestDB) GetAllTestSetIDs(ctx context.Context) ([]string, error) {
+	rows, err := t.db.QueryContext(ctx, `
+		SELECT test_set_id FROM test_cases
+		UNION SELECT test_set_id FROM mocks
+		UNION SELECT test_set_id FROM test_set_configs
+		ORDER BY test_set_id`)
+	if err != nil {
+		utils.LogError(t.logger, err, "failed to read the test-sets from the sqlite database")
+		return nil, err
+	}
+	return scanStrings(rows)
+}
+
+func (t *TestDB) GetTestCases(ctx context.Context, testSetID string) ([]*models.TestCase, error) {
+	rows, err := t.db.QueryContext(ctx, `SELECT doc FROM test_cases WHERE test_set_id = ? ORDER BY req_timestamp, name`, testSetID)
+	if err != nil {
+		utils.LogError(t.logger, err, "failed to read the testcases from the sqlite database", zap.String("testset id", testSetID))
+		return nil, err
+	}
+	docs, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		t.logger.Debug("no tests are recorded for the session", zap.String("index", testSetID))
+		return nil, nil
+	}
+
+	tcs := make([]*models.TestCase, 0, len(docs))
+	for _, doc := range docs {
+		var testCase *yaml.NetworkTrafficDoc
+		err = yamlLib.Unmarshal([]byte(doc), &testCase)
+		if err != nil {
+			utils.LogError(t.logger, err, "failed to unmarshall YAML data")
+			return nil, err
+		}
+		tc, err := testdb.Decode(testCase, t.logger)
+		if err != nil {
+			utils.LogError(t.logger, err, "failed to decode the testcase")
+			return nil, err
+		}
+		tcs = append(tcs, tc)
+	}
+	return tcs, nil
+}
+
+func (t *TestDB) DeleteTests(ctx context.Context, testSetID string, testCaseIDs []string) error {
+	return t.withTx(ctx, func(tx *sql.Tx) error {
+		for _, testCaseID := range testCaseIDs {
+			_, err := tx.ExecContext(ctx, `DELETE FROM test_cases WHERE test_set_id = ? AND name = ?`, testSetID, testCaseID)
+			if err != nil {
+				t.logger.Error("failed to delete the testcase", zap.String("testcase id", testCaseID), zap.String("testset id", testSetID))
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteTestSet deletes the test cases, the mocks and the config of the test-set.
+func (t *TestDB) DeleteTestSet(ctx context.Context, testSetID string) error {
+	err := t.withTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"test_cases", "mocks", "test_set_configs"} {
+			_, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE test_set_id = ?", testSetID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.logger.Error("failed to delete the testset", zap.String("testset id", testSetID))
+		return err
+	}
+	return nil
+}
+
+// execer is implemented by both the database and its transactions.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// upsert writes the test case, named after the last test case of the test-set when it has no name yet.
+func (t *TestDB) upsert(ctx context.Context, ex execer, testSetID string, tc *models.TestCase) (string, error) {
+	name := tc.Name
+	if name == "" {
+		var last int
+		err := ex.QueryRowContext(ctx, `
+			SELECT COALESCE(MAX(CAST(SUBSTR(name, 6) AS INTEGER)), 0) FROM test_cases
+			WHERE test_set_id = ? AND name GLOB 'test-[0-9]*'`, testSetID).Scan(&last)
+		if err != nil {
+			return "", err
+		}
+		name = fmt.Sprintf("test-%v", last+1)
+	}
+	yamlTc, err := testdb.EncodeTestcase(*tc, t.logger)
+	if err != nil {
+		return name, err
+	}
+	yamlTc.Name = name
+	data, err := yamlLib.Marshal(&yamlTc)
+	if err != nil {
+		return name, err
+	}
+
+	method, url, ts := string(tc.HTTPReq.Method), tc.HTTPReq.URL, tc.HTTPReq.Timestamp
+	statusCode := tc.HTTPResp.StatusCode
+	if tc.Kind == models.GRPC_EXPORT {
+		method, url, ts = "POST", tc.GrpcReq.Headers.PseudoHeaders[":path"], tc.GrpcReq.Timestamp
+		statusCode = 0
+	}
+	_, err = ex.ExecContext(ctx, `
+		INSERT INTO test_cases (test_set_id, name, kind, method, url, status_code, req_timestamp, doc)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (test_set_id, name) DO UPDATE SET
+			kind = excluded.kind, method = excluded.method, url = excluded.url, status_code = excluded.status_code,
+			req_timestamp = excluded.req_timestamp, doc = excluded.doc`,
+		testSetID, name, string(tc.Kind), method, url, statusCode, unixNano(ts), string(data))
+	if err != nil {
+		utils.LogError(t.logger, err, "failed to write the testcase to the sqlite database")
+		return name, err
+	}
+	return name, nil
+}
+
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer func() {
+		_ = rows.Close()
+	}()
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
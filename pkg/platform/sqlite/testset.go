@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// TestSetDB stores the configs of the test-sets, like the yaml configdb it is generic over the config type.
+type TestSetDB[T any] struct {
+	*DB
+}
+
+func NewTestSetDB[T any](db *DB) *TestSetDB[T] {
+	return &TestSetDB[T]{DB: db}
+}
+
+func (t *TestSetDB[T]) Read(ctx context.Context, testSetID string) (T, error) {
+	var config T
+	var data string
+	err := t.db.QueryRowContext(ctx, `SELECT doc FROM test_set_configs WHERE test_set_id = ?`, testSetID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return config, fmt.Errorf("no config found for the test-set %s", testSetID)
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := yamlLib.Unmarshal([]byte(data), &config); err != nil {
+		utils.LogError(t.logger, err, "failed to unmarshal test-set config", zap.String("testSet", testSetID))
+		return config, err
+	}
+	return config, nil
+}
+
+func (t *TestSetDB[T]) Write(ctx context.Context, testSetID string, config T) error {
+	return writeConfig(ctx, t.db, t.logger, testSetID, config)
+}
+
+func writeConfig(ctx context.Context, ex execer, logger *zap.Logger, testSetID string, config any) error {
+	data, err := yamlLib.Marshal(config)
+	if err != nil {
+		utils.LogError(logger, err, "failed to marshal test-set config", zap.String("testSet", testSetID))
+		return err
+	}
+	_, err = ex.ExecContext(ctx, `
+		INSERT INTO test_set_configs (test_set_id, doc) VALUES (?, ?)
+		ON CONFLICT (test_set_id) DO UPDATE SET doc = excluded.doc`, testSetID, string(data))
+	if err != nil {
+		utils.LogError(logger, err, "failed to write test-set configuration in the sqlite database", zap.String("testSet", testSetID))
+		return err
+	}
+	return nil
+}
@@ -78,7 +78,7 @@ func (ys *MockYaml) UpdateMocks(ctx context.Context, testSetID string, mockNames
 		}
 		mockYamls = append(mockYamls, doc)
 	}
-	mocks, err := decodeMocks(mockYamls, ys.Logger)
+	mocks, err := DecodeMocks(mockYamls, ys.Logger)
 	if err != nil {
 		return err
 	}
@@ -140,6 +140,57 @@ func (ys *MockYaml) InsertMock(ctx context.Context, mock *models.Mock, testSetID
 	return nil
 }
 
+// AppendMocks appends the mocks to the mocks of an existing test-set, they are numbered after the last
+// mock of the test-set so that their names stay unique.
+func (ys *MockYaml) AppendMocks(ctx context.Context, testSetID string, mocks []*models.Mock) error {
+	mockPath := filepath.Join(ys.MockPath, testSetID)
+	mockFileName := ys.MockName
+	if mockFileName == "" {
+		mockFileName = "mocks"
+	}
+
+	next := 0
+	if _, err := os.Stat(filepath.Join(mockPath, mockFileName+".yaml")); err == nil {
+		data, err := yaml.ReadFile(ctx, ys.Logger, mockPath, mockFileName)
+		if err != nil {
+			return err
+		}
+		dec := yamlLib.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc yaml.NetworkTrafficDoc
+			err := dec.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
+			}
+			var id int
+			if _, err := fmt.Sscanf(doc.Name, "mock-%d", &id); err == nil && id >= next {
+				next = id + 1
+			}
+		}
+	}
+
+	for _, mock := range mocks {
+		mock.Name = fmt.Sprint("mock-", next)
+		next++
+		mockYaml, err := EncodeMock(mock, ys.Logger)
+		if err != nil {
+			return err
+		}
+		data, err := yamlLib.Marshal(&mockYaml)
+		if err != nil {
+			return err
+		}
+		err = yaml.WriteFile(ctx, ys.Logger, mockPath, mockFileName, data, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (ys *MockYaml) GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error) {
 
 	var tcsMocks = make([]*models.Mock, 0)
@@ -174,27 +225,14 @@ func (ys *MockYaml) GetFilteredMocks(ctx context.Context, testSetID string, afte
 			}
 			mockYamls = append(mockYamls, doc)
 		}
-		mocks, err := decodeMocks(mockYamls, ys.Logger)
+		mocks, err := DecodeMocks(mockYamls, ys.Logger)
 		if err != nil {
 			utils.LogError(ys.Logger, err, "failed to decode the config mocks from yaml docs", zap.Any("session", filepath.Base(path)))
 			return nil, err
 		}
 
 		for _, mock := range mocks {
-			isFilteredMock := true
-			switch mock.Kind {
-			case "Generic":
-				isFilteredMock = false
-			case "Postgres":
-				isFilteredMock = false
-			case "Http":
-				isFilteredMock = false
-			case "Redis":
-				isFilteredMock = false
-			case "MySQL":
-				isFilteredMock = false
-			}
-			if mock.Spec.Metadata["type"] != "config" && isFilteredMock {
+			if !IsUnFiltered(mock) {
 				tcsMocks = append(tcsMocks, mock)
 			}
 		}
@@ -243,26 +281,13 @@ func (ys *MockYaml) GetUnFilteredMocks(ctx context.Context, testSetID string, af
 			}
 			mockYamls = append(mockYamls, doc)
 		}
-		mocks, err := decodeMocks(mockYamls, ys.Logger)
+		mocks, err := DecodeMocks(mockYamls, ys.Logger)
 		if err != nil {
 			utils.LogError(ys.Logger, err, "failed to decode the config mocks from yaml docs", zap.Any("session", filepath.Base(path)))
 			return nil, err
 		}
 		for _, mock := range mocks {
-			isUnFilteredMock := false
-			switch mock.Kind {
-			case "Generic":
-				isUnFilteredMock = true
-			case "Postgres":
-				isUnFilteredMock = true
-			case "Http":
-				isUnFilteredMock = true
-			case "Redis":
-				isUnFilteredMock = true
-			case "MySQL":
-				isUnFilteredMock = true
-			}
-			if mock.Spec.Metadata["type"] == "config" || isUnFilteredMock {
+			if IsUnFiltered(mock) {
 				configMocks = append(configMocks, mock)
 			}
 		}
@@ -287,6 +312,19 @@ func (ys *MockYaml) GetUnFilteredMocks(ctx context.Context, testSetID string, af
 	return mocks, nil
 }
 
+// IsUnFiltered reports whether the mock is served to every test case of the test-set rather than only to the
+// test cases recorded around it, i.e. the config mocks and the mocks of the connection oriented protocols.
+func IsUnFiltered(mock *models.Mock) bool {
+	if mock.Spec.Metadata["type"] == "config" {
+		return true
+	}
+	switch mock.Kind {
+	case "Generic", "Postgres", "PostgresV2", "Http", "Redis", "MySQL", "DNS", "Kafka", "AMQP", "Cassandra", "Memcached", "MSSQL":
+		return true
+	}
+	return false
+}
+
 func (ys *MockYaml) getNextID() int64 {
 	return atomic.AddInt64(&ys.idCounter, 1)
 }
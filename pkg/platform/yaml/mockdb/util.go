@@ -68,6 +68,7 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 			Metadata:         mock.Spec.Metadata,
 			Request:          *mock.Spec.HTTPReq,
 			Response:         *mock.Spec.HTTPResp,
+			WebSocket:        mock.Spec.WebSocket,
 			Created:          mock.Spec.Created,
 			ReqTimestampMock: mock.Spec.ReqTimestampMock,
 			ResTimestampMock: mock.Spec.ResTimestampMock,
@@ -95,6 +96,7 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 			Metadata:         mock.Spec.Metadata,
 			RedisRequests:    mock.Spec.RedisRequests,
 			RedisResponses:   mock.Spec.RedisResponses,
+			RedisCommands:    mock.Spec.RedisCommands,
 			ReqTimestampMock: mock.Spec.ReqTimestampMock,
 			ResTimestampMock: mock.Spec.ResTimestampMock,
 		}
@@ -104,7 +106,6 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 			return nil, err
 		}
 	case models.Postgres:
-		// case models.PostgresV2:
 
 		postgresSpec := models.PostgresSpec{
 			Metadata:          mock.Spec.Metadata,
@@ -119,6 +120,19 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 			utils.LogError(logger, err, "failed to marshal the postgres input-output as yaml")
 			return nil, err
 		}
+	case models.PostgresV2:
+		postgresSpec := models.PostgresV2Schema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.PostgresV2Requests,
+			Responses:        mock.Spec.PostgresV2Responses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(postgresSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the postgres messages as yaml")
+			return nil, err
+		}
 	case models.GRPC_EXPORT:
 		gRPCSpec := models.GrpcSpec{
 			GrpcReq:          *mock.Spec.GRPCReq,
@@ -173,6 +187,84 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 			utils.LogError(logger, err, "failed to marshal the MySQL input-output as yaml")
 			return nil, err
 		}
+	case models.DNS:
+		dnsSpec := models.DNSSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.DNSReq,
+			Response:         *mock.Spec.DNSResp,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(dnsSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the dns query and answers as yaml")
+			return nil, err
+		}
+	case models.Kafka:
+		kafkaSpec := models.KafkaSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.KafkaReq,
+			Response:         *mock.Spec.KafkaResp,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(kafkaSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the kafka request and response as yaml")
+			return nil, err
+		}
+	case models.AMQP:
+		amqpSpec := models.AMQPSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.AMQPReq,
+			Response:         mock.Spec.AMQPResp,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(amqpSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the amqp command and the replies of the broker as yaml")
+			return nil, err
+		}
+	case models.Cassandra:
+		cassandraSpec := models.CassandraSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.CassandraReq,
+			Response:         *mock.Spec.CassandraResp,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(cassandraSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the cassandra request and response as yaml")
+			return nil, err
+		}
+	case models.Memcached:
+		memcachedSpec := models.MemcachedSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.MemcachedReq,
+			Responses:        mock.Spec.MemcachedResp,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(memcachedSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the memcached command and its replies as yaml")
+			return nil, err
+		}
+	case models.MSSQL:
+		mssqlSpec := models.MSSQLSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.MSSQLReq,
+			Response:         *mock.Spec.MSSQLResp,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(mssqlSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the mssql request and response as yaml")
+			return nil, err
+		}
 	default:
 		utils.LogError(logger, nil, "failed to marshal the recorded mock into yaml due to invalid kind of mock")
 		return nil, errors.New("type of mock is invalid")
@@ -181,7 +273,7 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 	return &yamlDoc, nil
 }
 
-func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*models.Mock, error) {
+func DecodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*models.Mock, error) {
 	mocks := []*models.Mock{}
 
 	for _, m := range yamlMocks {
@@ -205,9 +297,10 @@ func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*mo
 				return nil, err
 			}
 			mock.Spec = models.MockSpec{
-				Metadata: httpSpec.Metadata,
-				HTTPReq:  &httpSpec.Request,
-				HTTPResp: &httpSpec.Response,
+				Metadata:  httpSpec.Metadata,
+				HTTPReq:   &httpSpec.Request,
+				HTTPResp:  &httpSpec.Response,
+				WebSocket: httpSpec.WebSocket,
 
 				Created:          httpSpec.Created,
 				ReqTimestampMock: httpSpec.ReqTimestampMock,
@@ -264,12 +357,12 @@ func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*mo
 				Metadata:         redisSpec.Metadata,
 				RedisRequests:    redisSpec.RedisRequests,
 				RedisResponses:   redisSpec.RedisResponses,
+				RedisCommands:    redisSpec.RedisCommands,
 				ReqTimestampMock: redisSpec.ReqTimestampMock,
 				ResTimestampMock: redisSpec.ResTimestampMock,
 			}
 
 		case models.Postgres:
-			// case models.PostgresV2:
 
 			PostSpec := models.PostgresSpec{}
 			err := m.Spec.Decode(&PostSpec)
@@ -286,6 +379,20 @@ func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*mo
 				ReqTimestampMock:  PostSpec.ReqTimestampMock,
 				ResTimestampMock:  PostSpec.ResTimestampMock,
 			}
+		case models.PostgresV2:
+			postgresSpec := models.PostgresV2Schema{}
+			err := m.Spec.Decode(&postgresSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into postgres mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:            postgresSpec.Metadata,
+				PostgresV2Requests:  postgresSpec.Requests,
+				PostgresV2Responses: postgresSpec.Responses,
+				ReqTimestampMock:    postgresSpec.ReqTimestampMock,
+				ResTimestampMock:    postgresSpec.ResTimestampMock,
+			}
 		case models.MySQL:
 			mySQLSpec := mysql.Spec{}
 			err := m.Spec.Decode(&mySQLSpec)
@@ -299,6 +406,90 @@ func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*mo
 				return nil, err
 			}
 			mock.Spec = *mockSpec
+		case models.DNS:
+			dnsSpec := models.DNSSchema{}
+			err := m.Spec.Decode(&dnsSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into dns mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         dnsSpec.Metadata,
+				DNSReq:           &dnsSpec.Request,
+				DNSResp:          &dnsSpec.Response,
+				ReqTimestampMock: dnsSpec.ReqTimestampMock,
+				ResTimestampMock: dnsSpec.ResTimestampMock,
+			}
+		case models.Kafka:
+			kafkaSpec := models.KafkaSchema{}
+			err := m.Spec.Decode(&kafkaSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into kafka mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         kafkaSpec.Metadata,
+				KafkaReq:         &kafkaSpec.Request,
+				KafkaResp:        &kafkaSpec.Response,
+				ReqTimestampMock: kafkaSpec.ReqTimestampMock,
+				ResTimestampMock: kafkaSpec.ResTimestampMock,
+			}
+		case models.AMQP:
+			amqpSpec := models.AMQPSchema{}
+			err := m.Spec.Decode(&amqpSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into amqp mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         amqpSpec.Metadata,
+				AMQPReq:          &amqpSpec.Request,
+				AMQPResp:         amqpSpec.Response,
+				ReqTimestampMock: amqpSpec.ReqTimestampMock,
+				ResTimestampMock: amqpSpec.ResTimestampMock,
+			}
+		case models.Cassandra:
+			cassandraSpec := models.CassandraSchema{}
+			err := m.Spec.Decode(&cassandraSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into cassandra mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         cassandraSpec.Metadata,
+				CassandraReq:     &cassandraSpec.Request,
+				CassandraResp:    &cassandraSpec.Response,
+				ReqTimestampMock: cassandraSpec.ReqTimestampMock,
+				ResTimestampMock: cassandraSpec.ResTimestampMock,
+			}
+		case models.Memcached:
+			memcachedSpec := models.MemcachedSchema{}
+			err := m.Spec.Decode(&memcachedSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into memcached mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         memcachedSpec.Metadata,
+				MemcachedReq:     &memcachedSpec.Request,
+				MemcachedResp:    memcachedSpec.Responses,
+				ReqTimestampMock: memcachedSpec.ReqTimestampMock,
+				ResTimestampMock: memcachedSpec.ResTimestampMock,
+			}
+		case models.MSSQL:
+			mssqlSpec := models.MSSQLSchema{}
+			err := m.Spec.Decode(&mssqlSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into mssql mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         mssqlSpec.Metadata,
+				MSSQLReq:         &mssqlSpec.Request,
+				MSSQLResp:        &mssqlSpec.Response,
+				ReqTimestampMock: mssqlSpec.ReqTimestampMock,
+				ResTimestampMock: mssqlSpec.ResTimestampMock,
+			}
 		default:
 			utils.LogError(logger, nil, "failed to unmarshal a mock yaml doc of unknown type", zap.Any("type", m.Kind))
 			continue
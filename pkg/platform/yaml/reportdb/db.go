@@ -5,7 +5,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"go.keploy.io/server/v2/pkg/models"
@@ -36,6 +38,22 @@ func (fe *TestReport) GetAllTestRunIDs(ctx context.Context) ([]string, error) {
 	return yaml.ReadSessionIndices(ctx, fe.Path, fe.Logger)
 }
 
+// GetReportTestSetIDs returns the test-sets having a report in the test run.
+func (fe *TestReport) GetReportTestSetIDs(_ context.Context, testRunID string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(fe.Path, testRunID))
+	if err != nil {
+		return nil, err
+	}
+	var testSetIDs []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), "-report.yaml") {
+			continue
+		}
+		testSetIDs = append(testSetIDs, strings.TrimSuffix(entry.Name(), "-report.yaml"))
+	}
+	return testSetIDs, nil
+}
+
 func (fe *TestReport) InsertTestCaseResult(_ context.Context, testRunID string, testSetID string, result *models.TestResult) error {
 	fe.m.Lock()
 	defer fe.m.Unlock()
@@ -52,6 +70,9 @@ func (fe *TestReport) InsertTestCaseResult(_ context.Context, testRunID string,
 }
 
 func (fe *TestReport) GetTestCaseResults(_ context.Context, testRunID string, testSetID string) ([]models.TestResult, error) {
+	fe.m.Lock()
+	defer fe.m.Unlock()
+
 	testRun, ok := fe.tests[testRunID]
 	if !ok {
 		return []models.TestResult{}, fmt.Errorf("%s found no test results for test report with id: %s", utils.Emoji, testRunID)
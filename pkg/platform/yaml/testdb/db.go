@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/platform/yaml"
@@ -97,11 +98,19 @@ func (ts *TestYaml) GetTestCases(ctx context.Context, testSetID string) ([]*mode
 		tcs = append(tcs, tc)
 	}
 	sort.SliceStable(tcs, func(i, j int) bool {
-		return tcs[i].HTTPReq.Timestamp.Before(tcs[j].HTTPReq.Timestamp)
+		return reqTimestamp(tcs[i]).Before(reqTimestamp(tcs[j]))
 	})
 	return tcs, nil
 }
 
+// reqTimestamp returns the time at which the request of the testcase was recorded.
+func reqTimestamp(tc *models.TestCase) time.Time {
+	if tc.Kind == models.GRPC_EXPORT {
+		return tc.GrpcReq.Timestamp
+	}
+	return tc.HTTPReq.Timestamp
+}
+
 func (ts *TestYaml) UpdateTestCase(ctx context.Context, tc *models.TestCase, testSetID string) error {
 
 	tcsInfo, err := ts.upsert(ctx, testSetID, tc)
@@ -20,13 +20,14 @@ import (
 
 func EncodeTestcase(tc models.TestCase, logger *zap.Logger) (*yaml.NetworkTrafficDoc, error) {
 
-	header := pkg.ToHTTPHeader(tc.HTTPReq.Header)
-	curl := pkg.MakeCurlCommand(string(tc.HTTPReq.Method), tc.HTTPReq.URL, pkg.ToYamlHTTPHeader(header), tc.HTTPReq.Body)
 	doc := &yaml.NetworkTrafficDoc{
 		Version: tc.Version,
 		Kind:    tc.Kind,
 		Name:    tc.Name,
-		Curl:    curl,
+	}
+	if tc.Kind == models.HTTP {
+		header := pkg.ToHTTPHeader(tc.HTTPReq.Header)
+		doc.Curl = pkg.MakeCurlCommand(string(tc.HTTPReq.Method), tc.HTTPReq.URL, pkg.ToYamlHTTPHeader(header), tc.HTTPReq.Body)
 	}
 	// find noisy fields
 	m, err := FlattenHTTPResponse(pkg.ToHTTPHeader(tc.HTTPResp.Header), tc.HTTPResp.Body)
@@ -68,6 +69,33 @@ func EncodeTestcase(tc models.TestCase, logger *zap.Logger) (*yaml.NetworkTraffi
 			utils.LogError(logger, err, "failed to encode testcase into a yaml doc")
 			return nil, err
 		}
+	case models.GRPC_EXPORT:
+		err := doc.Spec.Encode(models.GrpcSpec{
+			GrpcReq:  tc.GrpcReq,
+			GrpcResp: tc.GrpcResp,
+			Created:  tc.Created,
+			Assertions: map[string]interface{}{
+				"noise": noise,
+			},
+		})
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode testcase into a yaml doc")
+			return nil, err
+		}
+	case models.WebSocket:
+		err := doc.Spec.Encode(models.WebSocketSchema{
+			Request:  tc.HTTPReq,
+			Response: tc.HTTPResp,
+			Messages: tc.WebSocket,
+			Created:  tc.Created,
+			Assertions: map[string]interface{}{
+				"noise": noise,
+			},
+		})
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode testcase into a yaml doc")
+			return nil, err
+		}
 	default:
 		utils.LogError(logger, nil, "failed to marshal the testcase into yaml due to invalid kind of testcase")
 		return nil, errors.New("type of testcases is invalid")
@@ -244,20 +272,7 @@ func Decode(yamlTestcase *yaml.NetworkTrafficDoc, logger *zap.Logger) (*models.T
 		tc.Created = httpSpec.Created
 		tc.HTTPReq = httpSpec.Request
 		tc.HTTPResp = httpSpec.Response
-		tc.Noise = map[string][]string{}
-		switch reflect.ValueOf(httpSpec.Assertions["noise"]).Kind() {
-		case reflect.Map:
-			for k, v := range httpSpec.Assertions["noise"].(map[string]interface{}) {
-				tc.Noise[k] = []string{}
-				for _, val := range v.([]interface{}) {
-					tc.Noise[k] = append(tc.Noise[k], val.(string))
-				}
-			}
-		case reflect.Slice:
-			for _, v := range httpSpec.Assertions["noise"].([]interface{}) {
-				tc.Noise[v.(string)] = []string{}
-			}
-		}
+		tc.Noise = decodeNoise(httpSpec.Assertions)
 	// unmarshal its mocks from yaml docs to go struct
 	case models.GRPC_EXPORT:
 		grpcSpec := models.GrpcSpec{}
@@ -266,11 +281,44 @@ func Decode(yamlTestcase *yaml.NetworkTrafficDoc, logger *zap.Logger) (*models.T
 			utils.LogError(logger, err, "failed to unmarshal a yaml doc into the gRPC testcase")
 			return nil, err
 		}
+		tc.Created = grpcSpec.Created
 		tc.GrpcReq = grpcSpec.GrpcReq
 		tc.GrpcResp = grpcSpec.GrpcResp
+		tc.Noise = decodeNoise(grpcSpec.Assertions)
+	case models.WebSocket:
+		wsSpec := models.WebSocketSchema{}
+		err := yamlTestcase.Spec.Decode(&wsSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to unmarshal a yaml doc into the websocket testcase")
+			return nil, err
+		}
+		tc.Created = wsSpec.Created
+		tc.HTTPReq = wsSpec.Request
+		tc.HTTPResp = wsSpec.Response
+		tc.WebSocket = wsSpec.Messages
+		tc.Noise = decodeNoise(wsSpec.Assertions)
 	default:
 		utils.LogError(logger, nil, "failed to unmarshal yaml doc of unknown type", zap.Any("type of yaml doc", tc.Kind))
 		return nil, errors.New("yaml doc of unknown type")
 	}
 	return &tc, nil
 }
+
+// decodeNoise reads the noise of a testcase from its yaml assertions.
+func decodeNoise(assertions map[string]interface{}) map[string][]string {
+	noise := map[string][]string{}
+	switch reflect.ValueOf(assertions["noise"]).Kind() {
+	case reflect.Map:
+		for k, v := range assertions["noise"].(map[string]interface{}) {
+			noise[k] = []string{}
+			for _, val := range v.([]interface{}) {
+				noise[k] = append(noise[k], val.(string))
+			}
+		}
+	case reflect.Slice:
+		for _, v := range assertions["noise"].([]interface{}) {
+			noise[v.(string)] = []string{}
+		}
+	}
+	return noise
+}
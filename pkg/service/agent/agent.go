@@ -0,0 +1,442 @@
+// Package agent provides a local HTTP and gRPC control API to drive the record and test sessions of keploy.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/service/record"
+	"go.keploy.io/server/v2/pkg/service/replay"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+)
+
+type Agent struct {
+	logger   *zap.Logger
+	config   *config.Config
+	record   record.Service
+	replay   replay.Service
+	reportDB ReportDB
+	injector MockInjector
+
+	mu sync.Mutex
+	// cancel and done of the session (record or test run) in progress, only one session runs at a time
+	session     string
+	cancel      context.CancelFunc
+	done        chan struct{}
+	runStatuses map[string]models.TestSetStatus
+
+	// hooks of the app instrumented for recording, they are kept across the record sessions and
+	// only accessed by the session in progress
+	recordAppID      uint64
+	recordHookCancel context.CancelFunc
+	recordHookGrp    *errgroup.Group
+}
+
+func New(logger *zap.Logger, record record.Service, replay replay.Service, reportDB ReportDB, injector MockInjector, config *config.Config) Service {
+	return &Agent{
+		logger:      logger,
+		config:      config,
+		record:      record,
+		replay:      replay,
+		reportDB:    reportDB,
+		injector:    injector,
+		runStatuses: map[string]models.TestSetStatus{},
+	}
+}
+
+func (a *Agent) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /record/start", a.startRecord(ctx))
+	mux.HandleFunc("POST /record/stop", a.stopRecord)
+	mux.HandleFunc("GET /testsets", a.listTestSets)
+	mux.HandleFunc("POST /testsets/{id}/run", a.runTestSet(ctx))
+	mux.HandleFunc("POST /testsets/{id}/denoise", a.denoiseTestSet)
+	mux.HandleFunc("GET /testruns/{runID}/testsets/{id}/status", a.getTestSetStatus)
+	mux.HandleFunc("GET /testruns/{runID}/testsets/{id}/report", a.getReport)
+	mux.HandleFunc("POST /mocks", a.injectMocks)
+	mux.HandleFunc("DELETE /mocks", a.clearMocks)
+
+	server := &http.Server{
+		Addr:              a.config.Agent.Address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		defer utils.Recover(a.logger)
+		a.logger.Info("keploy agent is listening", zap.String("address", a.config.Agent.Address))
+		err := server.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			utils.LogError(a.logger, err, "failed to serve the agent api")
+			errCh <- err
+		}
+	}()
+
+	var grpcServer *grpc.Server
+	if a.config.Agent.GRPCAddress != "" {
+		lis, err := net.Listen("tcp", a.config.Agent.GRPCAddress)
+		if err != nil {
+			utils.LogError(a.logger, err, "failed to listen for the agent grpc api")
+			_ = server.Close()
+			return err
+		}
+		grpcServer = grpc.NewServer()
+		grpcServer.RegisterService(a.grpcServiceDesc(ctx), a)
+		go func() {
+			defer utils.Recover(a.logger)
+			a.logger.Info("keploy agent grpc api is listening", zap.String("address", a.config.Agent.GRPCAddress))
+			err := grpcServer.Serve(lis)
+			if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				utils.LogError(a.logger, err, "failed to serve the agent grpc api")
+				errCh <- err
+			}
+		}()
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+	case serveErr = <-errCh:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		utils.LogError(a.logger, err, "failed to shutdown the agent api")
+	}
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	a.stopSession()
+	a.unhookRecord()
+	return serveErr
+}
+
+// startSession reserves the agent for a session, it returns false when another session is in progress.
+func (a *Agent) startSession(ctx context.Context, name string) (context.Context, chan struct{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.session != "" {
+		return nil, nil, &apiError{code: http.StatusConflict, err: fmt.Errorf("a %s session is already in progress", a.session)}
+	}
+	ctx, a.cancel = context.WithCancel(ctx)
+	a.done = make(chan struct{})
+	a.session = name
+	return ctx, a.done, nil
+}
+
+func (a *Agent) endSession(done chan struct{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	close(done)
+	if a.done == done {
+		a.session, a.cancel, a.done = "", nil, nil
+	}
+}
+
+// stopSession cancels the session in progress and waits for it to complete.
+func (a *Agent) stopSession() {
+	a.mu.Lock()
+	cancel, done := a.cancel, a.done
+	a.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (a *Agent) startRecord(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		err := a.startRecording(ctx)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "recording"})
+	}
+}
+
+// startRecording starts a record session in the background, it is stopped by stopRecording.
+func (a *Agent) startRecording(ctx context.Context) error {
+	sessionCtx, done, err := a.startSession(ctx, "record")
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer utils.Recover(a.logger)
+		defer a.endSession(done)
+		appID, err := a.hookRecord(sessionCtx)
+		if err != nil {
+			utils.LogError(a.logger, err, "failed to instrument the application for recording")
+			return
+		}
+		err = a.record.Start(sessionCtx, models.RecordOptions{Instrumented: true, AppID: appID})
+		if err != nil {
+			utils.LogError(a.logger, err, "failed to record")
+		}
+	}()
+	return nil
+}
+
+// hookRecord returns the app instrumented for recording, the first record session sets up and hooks the
+// app and the next ones reuse its hooks and proxy.
+func (a *Agent) hookRecord(ctx context.Context) (uint64, error) {
+	if a.recordHookCancel != nil {
+		return a.recordAppID, nil
+	}
+	hookCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	g, _ := errgroup.WithContext(hookCtx)
+	hookCtx = context.WithValue(hookCtx, models.ErrGroupKey, g)
+	appID, err := a.record.Instrument(hookCtx)
+	if err != nil {
+		cancel()
+		_ = g.Wait()
+		return 0, err
+	}
+	a.recordAppID, a.recordHookCancel, a.recordHookGrp = appID, cancel, g
+	return appID, nil
+}
+
+// unhookRecord removes the hooks of the record sessions, e.g. before a test run hooks the app in the test mode.
+func (a *Agent) unhookRecord() {
+	if a.recordHookCancel == nil {
+		return
+	}
+	a.recordHookCancel()
+	err := a.recordHookGrp.Wait()
+	if err != nil {
+		utils.LogError(a.logger, err, "failed to stop the hooks of the record sessions")
+	}
+	a.recordHookCancel, a.recordHookGrp = nil, nil
+}
+
+func (a *Agent) stopRecord(w http.ResponseWriter, _ *http.Request) {
+	err := a.stopRecording()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+}
+
+func (a *Agent) stopRecording() error {
+	a.mu.Lock()
+	session := a.session
+	a.mu.Unlock()
+	if session != "record" {
+		return &apiError{code: http.StatusConflict, err: errors.New("no record session is in progress")}
+	}
+	a.stopSession()
+	return nil
+}
+
+func (a *Agent) listTestSets(w http.ResponseWriter, r *http.Request) {
+	testSetIDs, err := a.replay.GetAllTestSetIDs(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"testSets": testSetIDs})
+}
+
+func (a *Agent) runTestSet(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		testSetID := r.PathValue("id")
+		testRunID, err := a.startTestRun(ctx, r.Context(), testSetID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"testRunId": testRunID, "testSetId": testSetID})
+	}
+}
+
+// startTestRun runs the test set in the background and returns the id of its test run.
+func (a *Agent) startTestRun(ctx, reqCtx context.Context, testSetID string) (string, error) {
+	testSetIDs, err := a.replay.GetAllTestSetIDs(reqCtx)
+	if err != nil {
+		return "", err
+	}
+	found := false
+	for _, id := range testSetIDs {
+		if id == testSetID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", &apiError{code: http.StatusNotFound, err: fmt.Errorf("test set %s not found", testSetID)}
+	}
+
+	testRunID, err := a.replay.GetNextTestRunID(reqCtx)
+	if err != nil {
+		return "", err
+	}
+
+	sessionCtx, done, err := a.startSession(ctx, "test")
+	if err != nil {
+		return "", err
+	}
+	a.setRunStatus(testRunID, testSetID, models.TestSetStatusRunning)
+
+	go func() {
+		defer utils.Recover(a.logger)
+		defer a.endSession(done)
+		status, err := a.run(sessionCtx, testSetID, testRunID)
+		if err != nil {
+			utils.LogError(a.logger, err, "failed to run the test set", zap.String("testSet", testSetID))
+		}
+		a.setRunStatus(testRunID, testSetID, status)
+	}()
+	return testRunID, nil
+}
+
+// run instruments the application and runs the test set, the hooks are removed once the run is complete.
+func (a *Agent) run(ctx context.Context, testSetID, testRunID string) (models.TestSetStatus, error) {
+	a.unhookRecord()
+	g, ctx := errgroup.WithContext(ctx)
+	ctx = context.WithValue(ctx, models.ErrGroupKey, g)
+
+	inst, err := a.replay.Instrument(ctx)
+	if err != nil {
+		return models.TestSetStatusInternalErr, err
+	}
+	defer func() {
+		if inst.HookCancel != nil {
+			inst.HookCancel()
+		}
+		a.injector.Reset()
+		err := g.Wait()
+		if err != nil {
+			utils.LogError(a.logger, err, "failed to stop the test run")
+		}
+	}()
+
+	return a.replay.RunTestSet(ctx, testSetID, testRunID, inst.AppID, false)
+}
+
+func (a *Agent) setRunStatus(testRunID, testSetID string, status models.TestSetStatus) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.runStatuses[testRunID+"/"+testSetID] = status
+}
+
+func (a *Agent) getTestSetStatus(w http.ResponseWriter, r *http.Request) {
+	testRunID, testSetID := r.PathValue("runID"), r.PathValue("id")
+	status, err := a.testSetStatus(r.Context(), testRunID, testSetID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"testRunId": testRunID, "testSetId": testSetID, "status": string(status)})
+}
+
+// testSetStatus returns the status of a test run started by the agent, or else the one of its report.
+func (a *Agent) testSetStatus(ctx context.Context, testRunID, testSetID string) (models.TestSetStatus, error) {
+	a.mu.Lock()
+	status, ok := a.runStatuses[testRunID+"/"+testSetID]
+	a.mu.Unlock()
+	if ok && status != "" {
+		return status, nil
+	}
+	status, err := a.replay.GetTestSetStatus(ctx, testRunID, testSetID)
+	if err != nil {
+		return "", &apiError{code: http.StatusNotFound, err: err}
+	}
+	return status, nil
+}
+
+func (a *Agent) getReport(w http.ResponseWriter, r *http.Request) {
+	report, err := a.reportDB.GetReport(r.Context(), r.PathValue("runID"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, &apiError{code: http.StatusNotFound, err: err})
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (a *Agent) denoiseTestSet(w http.ResponseWriter, r *http.Request) {
+	var noiseParams []*models.NoiseParams
+	err := json.NewDecoder(r.Body).Decode(&noiseParams)
+	if err != nil {
+		writeError(w, &apiError{code: http.StatusBadRequest, err: fmt.Errorf("failed to decode the noise params: %w", err)})
+		return
+	}
+	updated, err := a.replay.DenoiseTestCases(r.Context(), r.PathValue("id"), noiseParams)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (a *Agent) injectMocks(w http.ResponseWriter, r *http.Request) {
+	var mocks []*models.Mock
+	err := json.NewDecoder(r.Body).Decode(&mocks)
+	if err != nil {
+		writeError(w, &apiError{code: http.StatusBadRequest, err: fmt.Errorf("failed to decode the mocks: %w", err)})
+		return
+	}
+	err = a.injector.Inject(r.Context(), mocks)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"injected": len(mocks)})
+}
+
+func (a *Agent) clearMocks(w http.ResponseWriter, r *http.Request) {
+	err := a.injector.Clear(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiError is an error of the control api along with the http status it is served with, the other
+// errors are internal ones.
+type apiError struct {
+	code int
+	err  error
+}
+
+func (e *apiError) Error() string { return e.err.Error() }
+
+func (e *apiError) Unwrap() error { return e.err }
+
+// errorCode returns the http status of the error
+func errorCode(err error) int {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.code
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, errorCode(err), map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// grpcServiceName is the name of the gRPC control API. Its methods take and return a google.protobuf.Struct
+// holding the same JSON as the HTTP API, so that any gRPC client can call it without generated stubs.
+const grpcServiceName = "keploy.agent.v1.Agent"
+
+// grpcServiceDesc describes the gRPC control API, ctx is the context of the agent the sessions run in.
+func (a *Agent) grpcServiceDesc(ctx context.Context) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: grpcServiceName,
+		HandlerType: (*Service)(nil),
+		Methods: []grpc.MethodDesc{
+			a.grpcMethod("StartRecord", func(context.Context, *structpb.Struct) (any, error) {
+				err := a.startRecording(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]string{"status": "recording"}, nil
+			}),
+			a.grpcMethod("StopRecord", func(context.Context, *structpb.Struct) (any, error) {
+				err := a.stopRecording()
+				if err != nil {
+					return nil, err
+				}
+				return map[string]string{"status": "stopped"}, nil
+			}),
+			a.grpcMethod("ListTestSets", func(reqCtx context.Context, _ *structpb.Struct) (any, error) {
+				testSetIDs, err := a.replay.GetAllTestSetIDs(reqCtx)
+				if err != nil {
+					return nil, err
+				}
+				return map[string][]string{"testSets": testSetIDs}, nil
+			}),
+			a.grpcMethod("RunTestSet", func(reqCtx context.Context, in *structpb.Struct) (any, error) {
+				var req struct {
+					TestSetID string `json:"testSetId"`
+				}
+				err := decodeStruct(in, &req)
+				if err != nil {
+					return nil, err
+				}
+				testRunID, err := a.startTestRun(ctx, reqCtx, req.TestSetID)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]string{"testRunId": testRunID, "testSetId": req.TestSetID}, nil
+			}),
+			a.grpcMethod("DenoiseTestSet", func(reqCtx context.Context, in *structpb.Struct) (any, error) {
+				var req struct {
+					TestSetID   string                `json:"testSetId"`
+					NoiseParams []*models.NoiseParams `json:"noiseParams"`
+				}
+				err := decodeStruct(in, &req)
+				if err != nil {
+					return nil, err
+				}
+				updated, err := a.replay.DenoiseTestCases(reqCtx, req.TestSetID, req.NoiseParams)
+				if err != nil {
+					return nil, err
+				}
+				return map[string][]*models.NoiseParams{"noiseParams": updated}, nil
+			}),
+			a.grpcMethod("GetTestSetStatus", func(reqCtx context.Context, in *structpb.Struct) (any, error) {
+				var req struct {
+					TestRunID string `json:"testRunId"`
+					TestSetID string `json:"testSetId"`
+				}
+				err := decodeStruct(in, &req)
+				if err != nil {
+					return nil, err
+				}
+				status, err := a.testSetStatus(reqCtx, req.TestRunID, req.TestSetID)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]string{"testRunId": req.TestRunID, "testSetId": req.TestSetID, "status": string(status)}, nil
+			}),
+			a.grpcMethod("GetReport", func(reqCtx context.Context, in *structpb.Struct) (any, error) {
+				var req struct {
+					TestRunID string `json:"testRunId"`
+					TestSetID string `json:"testSetId"`
+				}
+				err := decodeStruct(in, &req)
+				if err != nil {
+					return nil, err
+				}
+				report, err := a.reportDB.GetReport(reqCtx, req.TestRunID, req.TestSetID)
+				if err != nil {
+					return nil, &apiError{code: http.StatusNotFound, err: err}
+				}
+				return report, nil
+			}),
+			a.grpcMethod("InjectMocks", func(reqCtx context.Context, in *structpb.Struct) (any, error) {
+				var req struct {
+					Mocks []*models.Mock `json:"mocks"`
+				}
+				err := decodeStruct(in, &req)
+				if err != nil {
+					return nil, err
+				}
+				err = a.injector.Inject(reqCtx, req.Mocks)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]int{"injected": len(req.Mocks)}, nil
+			}),
+			a.grpcMethod("ClearMocks", func(reqCtx context.Context, _ *structpb.Struct) (any, error) {
+				return map[string]string{}, a.injector.Clear(reqCtx)
+			}),
+		},
+	}
+}
+
+// grpcMethod serves the call as a unary method of the gRPC control API.
+func (a *Agent) grpcMethod(name string, call func(ctx context.Context, in *structpb.Struct) (any, error)) grpc.MethodDesc {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		res, err := call(ctx, req.(*structpb.Struct))
+		if err != nil {
+			return nil, status.Error(grpcCode(errorCode(err)), err.Error())
+		}
+		out, err := encodeStruct(res)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return out, nil
+	}
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := &structpb.Struct{}
+			err := dec(in)
+			if err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: a, FullMethod: "/" + grpcServiceName + "/" + name}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+// grpcCode maps the http status of an error of the control api to its gRPC code
+func grpcCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+// decodeStruct decodes the request into v through its JSON, as the HTTP API does.
+func decodeStruct(in *structpb.Struct, v any) error {
+	data, err := protojson.Marshal(in)
+	if err != nil {
+		return &apiError{code: http.StatusBadRequest, err: fmt.Errorf("failed to read the request: %w", err)}
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return &apiError{code: http.StatusBadRequest, err: fmt.Errorf("failed to decode the request: %w", err)}
+	}
+	return nil
+}
+
+// encodeStruct encodes the response with its JSON, as the HTTP API does.
+func encodeStruct(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the response: %w", err)
+	}
+	out := &structpb.Struct{}
+	err = protojson.Unmarshal(data, out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the response: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/service/replay"
+)
+
+// Injector wraps the instrumentation used by the replayer so that the injected mocks are added to the
+// unfiltered mocks every time the mocks of the running test-set are set.
+type Injector struct {
+	replay.Instrumentation
+	mu sync.Mutex
+	// injected mocks, they are named after their count so that they don't collide with the mocks of the test-sets
+	mocks    []*models.Mock
+	injected int
+	// last mocks set for the running app, to apply the injected mocks right away
+	appID      uint64
+	filtered   []*models.Mock
+	unFiltered []*models.Mock
+	active     bool
+}
+
+func NewInjector(instrumentation replay.Instrumentation) *Injector {
+	return &Injector{
+		Instrumentation: instrumentation,
+	}
+}
+
+func (i *Injector) SetMocks(ctx context.Context, id uint64, filtered []*models.Mock, unFiltered []*models.Mock) error {
+	i.mu.Lock()
+	i.appID, i.filtered, i.unFiltered, i.active = id, filtered, unFiltered, true
+	mocks := append(append([]*models.Mock{}, unFiltered...), i.mocks...)
+	i.mu.Unlock()
+
+	return i.Instrumentation.SetMocks(ctx, id, filtered, mocks)
+}
+
+func (i *Injector) Inject(ctx context.Context, mocks []*models.Mock) error {
+	i.mu.Lock()
+	for _, mock := range mocks {
+		if mock.Spec.Metadata == nil {
+			mock.Spec.Metadata = map[string]string{}
+		}
+		// the injected mocks are not bound to the timestamps of the test cases
+		mock.Spec.Metadata["type"] = "config"
+		if mock.Version == "" {
+			mock.Version = models.GetVersion()
+		}
+		i.injected++
+		mock.Name = fmt.Sprintf("mock-injected-%d", i.injected)
+	}
+	i.mocks = append(i.mocks, mocks...)
+	i.mu.Unlock()
+	return i.apply(ctx)
+}
+
+func (i *Injector) Clear(ctx context.Context) error {
+	i.mu.Lock()
+	i.mocks = nil
+	i.mu.Unlock()
+	return i.apply(ctx)
+}
+
+// apply sets the mocks of the running app again, along with the injected mocks
+func (i *Injector) apply(ctx context.Context) error {
+	i.mu.Lock()
+	if !i.active {
+		i.mu.Unlock()
+		return nil
+	}
+	id, filtered, unFiltered := i.appID, i.filtered, i.unFiltered
+	i.mu.Unlock()
+	return i.SetMocks(ctx, id, filtered, unFiltered)
+}
+
+// Reset forgets the mocks of the app once its test-set run is complete
+func (i *Injector) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.active = false
+	i.filtered, i.unFiltered = nil, nil
+}
@@ -0,0 +1,23 @@
+package agent
+
+import (
+	"context"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type Service interface {
+	// Start serves the control API until the context is cancelled
+	Start(ctx context.Context) error
+}
+
+type ReportDB interface {
+	GetReport(ctx context.Context, testRunID string, testSetID string) (*models.TestReport, error)
+}
+
+// MockInjector holds the mocks injected through the control API, they are served along with the mocks of the test-set.
+type MockInjector interface {
+	Inject(ctx context.Context, mocks []*models.Mock) error
+	Clear(ctx context.Context) error
+	Reset()
+}
@@ -29,23 +29,21 @@ func New(logger *zap.Logger, config *config.Config) Service {
 func (s *consumer) ValidateSchema(testsMapping map[string]map[string]*models.OpenAPI, mocksMapping []models.MockMapping) error {
 
 	// Retrieve mocks and calculate scores for each service
-	scores, err := s.getMockScores(testsMapping, mocksMapping)
-	if err != nil {
-		return err
-	}
+	scores := GetMockScores(s.logger, testsMapping, mocksMapping)
 	// Compare the scores and generate a summary
-	summary, err := s.ValidateMockAgainstTests(scores, testsMapping)
+	summary, err := ValidateMockAgainstTests(s.logger, models.ConsumerMode, s.config.Contract.Mappings.Self, scores, testsMapping)
 	if err != nil {
 		return err
 	}
 	// Print the summary
-	generateSummaryTable(summary)
+	GenerateSummaryTable(summary)
 
 	return nil
 }
 
-// getMockScores retrieves mocks and compares them with test cases, calculating scores.
-func (s *consumer) getMockScores(testsMapping map[string]map[string]*models.OpenAPI, mocksMapping []models.MockMapping) (map[string]map[string]map[string]models.SchemaInfo, error) {
+// GetMockScores compares the mocks with the test cases, keeping the best matching test of each mock.
+// It is shared by the consumer and the provider validations.
+func GetMockScores(logger *zap.Logger, testsMapping map[string]map[string]*models.OpenAPI, mocksMapping []models.MockMapping) map[string]map[string]map[string]models.SchemaInfo {
 
 	// Initialize a map to store the scores for each service, mock set, and mock.
 	scores := make(map[string]map[string]map[string]models.SchemaInfo)
@@ -62,16 +60,16 @@ func (s *consumer) getMockScores(testsMapping map[string]map[string]*models.Open
 
 		// Compare the mocks with test cases and calculate scores.
 		// The result is stored in the scores map under the respective service and mock set ID.
-		s.scoresForMocks(mapping.Mocks, scores[mapping.Service][mapping.TestSetID], testsMapping, mapping.TestSetID)
+		scoresForMocks(logger, mapping.Mocks, scores[mapping.Service][mapping.TestSetID], testsMapping, mapping.TestSetID)
 
 	}
 
 	// Return the calculated scores.
-	return scores, nil
+	return scores
 }
 
 // scoresForMocks compares mocks to test cases and assigns scores.
-func (s *consumer) scoresForMocks(mocks []*models.OpenAPI, mockSet map[string]models.SchemaInfo, testsMapping map[string]map[string]*models.OpenAPI, mockSetID string) {
+func scoresForMocks(logger *zap.Logger, mocks []*models.OpenAPI, mockSet map[string]models.SchemaInfo, testsMapping map[string]map[string]*models.OpenAPI, mockSetID string) {
 	// Ensure mockSet is initialized before assigning
 	if mockSet == nil {
 		mockSet = make(map[string]models.SchemaInfo)
@@ -92,11 +90,11 @@ func (s *consumer) scoresForMocks(mocks []*models.OpenAPI, mockSet map[string]mo
 			for _, test := range tests {
 				// Call 'match2' to compare the mock with the current test.
 				// This function returns a candidateScore (how well the mock matches the test) and a pass boolean.
-				candidateScore, pass, err := schemaMatcher.Match(*mock, *test, testSetID, mockSetID, s.logger, models.IdentifyMode)
+				candidateScore, pass, err := schemaMatcher.Match(*mock, *test, testSetID, mockSetID, logger, models.IdentifyMode)
 				// Handle any errors encountered during the comparison process.
 				if err != nil {
 					// Log the error and continue with the next iteration, skipping the current comparison.
-					utils.LogError(s.logger, err, "Error in matching the two models")
+					utils.LogError(logger, err, "Error in matching the two models")
 					continue
 				}
 
@@ -117,8 +115,9 @@ func (s *consumer) scoresForMocks(mocks []*models.OpenAPI, mockSet map[string]mo
 	}
 }
 
-// ValidateMockAgainstTests compares mock results with test cases and generates a summary report
-func (s *consumer) ValidateMockAgainstTests(scores map[string]map[string]map[string]models.SchemaInfo, testsMapping map[string]map[string]*models.OpenAPI) (models.Summary, error) {
+// ValidateMockAgainstTests compares mock results with test cases and generates a summary report.
+// In the consumer mode the tests are the ones of the current service, in the provider mode the mocks are.
+func ValidateMockAgainstTests(logger *zap.Logger, driven models.DrivenMode, self string, scores map[string]map[string]map[string]models.SchemaInfo, testsMapping map[string]map[string]*models.OpenAPI) (models.Summary, error) {
 	var summary models.Summary
 
 	// Defining color schemes for success, failure, and other statuses
@@ -196,14 +195,18 @@ func (s *consumer) ValidateMockAgainstTests(scores map[string]map[string]map[str
 
 					fmt.Println()
 
-					// Additional information: Print consumer and current service comparison
-					fmt.Printf("                                    Current %s   ||   Consumer %s\n", serviceColor(s.config.Contract.Mappings.Self), serviceColor(service))
+					// Additional information: Print consumer and current service comparison, the test side first
+					if driven == models.ProviderMode {
+						fmt.Printf("                                    Consumer %s   ||   Current %s\n", serviceColor(service), serviceColor(self))
+					} else {
+						fmt.Printf("                                    Current %s   ||   Consumer %s\n", serviceColor(self), serviceColor(service))
+					}
 
 					// Perform comparison between the mock and test case again
-					_, _, err := schemaMatcher.Match(mockInfo.Data, *testsMapping[mockInfo.TestSetID][mockInfo.Name], mockInfo.TestSetID, mockSetID, s.logger, models.CompareMode)
+					_, _, err := schemaMatcher.Match(mockInfo.Data, *testsMapping[mockInfo.TestSetID][mockInfo.Name], mockInfo.TestSetID, mockSetID, logger, models.CompareMode)
 					if err != nil {
 						// If an error occurs during comparison, return it
-						utils.LogError(s.logger, err, "Error in matching the two models")
+						utils.LogError(logger, err, "Error in matching the two models")
 						return models.Summary{}, err
 					}
 
@@ -234,7 +237,8 @@ func (s *consumer) ValidateMockAgainstTests(scores map[string]map[string]map[str
 	return summary, nil
 }
 
-func generateSummaryTable(summary models.Summary) {
+// GenerateSummaryTable prints the per service summary of the validation.
+func GenerateSummaryTable(summary models.Summary) {
 	notMatchedColor := color.New(color.FgHiRed).SprintFunc()
 	missedColor := color.New(color.FgHiYellow).SprintFunc()
 	successColor := color.New(color.FgHiGreen).SprintFunc()
@@ -615,6 +615,7 @@ func (s *contract) Download(ctx context.Context, checkConfig bool) error {
 	}
 	driven := s.config.Contract.Driven
 	if driven == models.ProviderMode.String() {
+		// the mocks of the current service are validated against the tests of the services it calls
 		err = s.DownloadTests(path)
 		if err != nil {
 			utils.LogError(s.logger, err, "failed to download tests")
@@ -673,12 +674,24 @@ func (s *contract) Validate(ctx context.Context) error {
 			return err
 		}
 	} else if s.config.Contract.Driven == models.ProviderMode.String() {
-		err := s.provider.ValidateSchema(ctx)
+
+		// Retrieve the tests of each service from the download folder
+		testsSchemasDownloaded, err := s.GetAllDownloadedTestsSchemas(ctx)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get downloaded tests schemas")
+			return err
+		}
+		// Retrieve the mocks of the current service from the schema folder
+		mocksMapping, err := s.GetAllMocksSchemas(ctx)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get mocks from schema")
+			return err
+		}
+		err = s.provider.ValidateSchema(testsSchemasDownloaded, mocksMapping)
 		if err != nil {
 			utils.LogError(s.logger, err, "failed to validate schema")
 			return err
 		}
-		fmt.Println("Provider driven validation is not implemented yet")
 	}
 
 	return nil
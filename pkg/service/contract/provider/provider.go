@@ -2,22 +2,22 @@
 package provider
 
 import (
-	"context"
+	"fmt"
 
 	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/service/contract/consumer"
+	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 )
 
-const IDENTIFYMODE = 0
-const COMPAREMODE = 1
-
 type provider struct {
 	logger *zap.Logger
 
 	config *config.Config
 }
 
-// New creates a new instance of the consumer service
+// New creates a new instance of the provider service
 func New(logger *zap.Logger, config *config.Config) Service {
 	return &provider{
 		logger: logger,
@@ -26,102 +26,40 @@ func New(logger *zap.Logger, config *config.Config) Service {
 	}
 }
 
-func (s *provider) ValidateSchema(_ context.Context) error {
-	// 	downloadTestsFolder := filepath.Join("./Download", "Tests")
-	// 	entries, err := os.ReadDir(downloadTestsFolder)
-	// 	if err != nil {
-	// 		s.logger.Error("Failed to read directory", zap.String("directory", downloadTestsFolder), zap.Error(err))
-	// 		return err
-	// 	}
-	// 	mocksFolder := filepath.Join("./keploy", "schema", "mocks")
-	// 	s.openAPIDB.ChangeTcPath(mocksFolder)
-	// 	services, err := os.ReadDir(mocksFolder)
-	// 	if err != nil {
-	// 		s.logger.Error("Failed to read directory", zap.String("directory", mocksFolder), zap.Error(err))
-	// 		return err
-	// 	}
-	// 	var mocksMapping map[string]map[string]map[string]*models.OpenAPI = make(map[string]map[string]map[string]*models.OpenAPI)
-	// 	var mocks []*models.OpenAPI
-	// 	for _, service := range services {
-	// 		if !service.IsDir() {
-	// 			continue
-	// 		}
-	// 		testSetIDs, err := os.ReadDir(filepath.Join(mocksFolder, service.Name()))
-	// 		if err != nil {
-	// 			s.logger.Error("Failed to read directory", zap.String("directory", service.Name()), zap.Error(err))
-	// 			return err
-	// 		}
-	// 		mocksMapping[service.Name()] = make(map[string]map[string]*models.OpenAPI)
-	// 		for _, testSetID := range testSetIDs {
-
-	// 			if !testSetID.IsDir() {
-	// 				continue
-	// 			}
-	// 			mocks, err = s.openAPIDB.GetMocksSchemas(ctx, filepath.Join(service.Name(), testSetID.Name()), mocksFolder, "mocks")
-	// 			if err != nil {
-	// 				s.logger.Error("Failed to get HTTP mocks", zap.String("testSetID", testSetID.Name()), zap.Error(err))
-	// 				return err
-	// 			}
-	// 			mocksMapping[service.Name()][testSetID.Name()] = make(map[string]*models.OpenAPI)
-	// 			for _, mock := range mocks {
-	// 				mocksMapping[service.Name()][testSetID.Name()][mock.Info.Title] = mock
-	// 			}
-	// 		}
-	// 	}
-	// 	var scores map[string]map[string]map[string]models.SchemaInfo = make(map[string]map[string]map[string]models.SchemaInfo)
-	// 	for _, entry := range entries {
-	// 		if entry.IsDir() {
-	// 			serviceFolder := filepath.Join(downloadTestsFolder, entry.Name())
-	// 			testSetIDs, err := os.ReadDir(filepath.Join(serviceFolder, "schema", "tests"))
-	// 			if err != nil {
-	// 				s.logger.Error("Failed to read directory", zap.String("directory", serviceFolder), zap.Error(err))
-	// 				return err
-	// 			}
-	// 			scores[entry.Name()] = make(map[string]map[string]models.SchemaInfo)
-	// 			for _, testSetID := range testSetIDs {
-	// 				if !testSetID.IsDir() {
-	// 					continue
-	// 				}
-	// 				tests, err := s.openAPIDB.GetTestCasesSchema(ctx, testSetID.Name(), filepath.Join(serviceFolder, "schema", "tests"))
-	// 				if err != nil {
-	// 					s.logger.Error("Failed to get test cases", zap.String("testSetID", testSetID.Name()), zap.Error(err))
-	// 					return err
-	// 				}
-	// 				scores[entry.Name()][testSetID.Name()] = make(map[string]models.SchemaInfo)
-	// 				for _, test := range tests {
-	// 					// Take each test and get the ideal mock for it
-	// 					scores[entry.Name()][testSetID.Name()][test.Info.Title] = models.SchemaInfo{Score: 0.0, Data: *test}
-	// 					for providerService, mockSetIDs := range mocksMapping {
-	// 						for mockSetID, mocks := range mockSetIDs {
-	// 							for _, mock := range mocks {
-	// 								candidateScore, pass, err := match2(*test, *mock, mockSetID, testSetID.Name(), s.logger, IDENTIFYMODE)
-	// 								if err != nil {
-	// 									s.logger.Error("Error in matching the two models", zap.Error(err))
-	// 									fmt.Println("test-set-id: ", testSetID.Name(), ", mock-set-id: ", mockSetID)
-	// 									return err
-	// 								}
-	// 								if pass && candidateScore > 0 {
-	// 									if candidateScore > scores[entry.Name()][testSetID.Name()][test.Info.Title].Score {
-	// 										idealMock := models.SchemaInfo{
-	// 											Service:   providerService,
-	// 											TestSetID: mockSetID,
-	// 											Name:      mock.Info.Title,
-	// 											Score:     candidateScore,
-	// 											Data:      *test,
-	// 										}
-	// 										scores[entry.Name()][testSetID.Name()][test.Info.Title] = idealMock
-	// 									}
-	// 								}
+// ValidateSchema matches the mocks of the current service against the tests downloaded from the services
+// they mock, the tests of a service are only compared with the mocks of that service.
+func (s *provider) ValidateSchema(testsMapping map[string]map[string]map[string]*models.OpenAPI, mocksMapping []models.MockMapping) error {
+	servicesMocks := make(map[string][]models.MockMapping)
+	for _, mapping := range mocksMapping {
+		servicesMocks[mapping.Service] = append(servicesMocks[mapping.Service], mapping)
+	}
 
-	// 							}
-	// 						}
-	// 					}
+	var summary models.Summary
+	for service, mappings := range servicesMocks {
+		tests := testsMapping[service]
+		if len(tests) == 0 {
+			s.logger.Warn("No tests downloaded for the service, its mocks can't be validated", zap.String("service", service))
+		}
 
-	// 				}
-	// 			}
-	// 		}
-	// 	}
-	// 	// TODO: Validate the scores and generate a summary
+		// Find the ideal test of the service for each of the mocks of the current service
+		scores := consumer.GetMockScores(s.logger, tests, mappings)
+		serviceSummary, err := consumer.ValidateMockAgainstTests(s.logger, models.ProviderMode, s.config.Contract.Mappings.Self, scores, tests)
+		if err != nil {
+			return err
+		}
+		summary.ServicesSummary = append(summary.ServicesSummary, serviceSummary.ServicesSummary...)
+	}
+	// Print the summary
+	consumer.GenerateSummaryTable(summary)
 
+	failed := 0
+	for _, serviceSummary := range summary.ServicesSummary {
+		failed += serviceSummary.FailedCount
+	}
+	if failed > 0 {
+		// exit with a non-zero code so that the deployment can be gated on the contracts
+		utils.ErrCode = 1
+		return fmt.Errorf("%d contract check(s) failed, the changes are breaking for the consumers", failed)
+	}
 	return nil
 }
@@ -1,10 +1,10 @@
 package provider
 
 import (
-	"context"
+	"go.keploy.io/server/v2/pkg/models"
 )
 
 // Service defines the provider service interface
 type Service interface {
-	ValidateSchema(ctx context.Context) error
+	ValidateSchema(testsMapping map[string]map[string]map[string]*models.OpenAPI, mocksMapping []models.MockMapping) error
 }
@@ -109,3 +109,79 @@ func (s *contract) GetAllDownloadedMocksSchemas(ctx context.Context) ([]models.M
 	}
 	return mocksSchemasMapping, nil
 }
+
+// GetAllDownloadedTestsSchemas retrieves the tests schema of every service from the download folder,
+// mapped by the service, the test set and the test name.
+func (s *contract) GetAllDownloadedTestsSchemas(ctx context.Context) (map[string]map[string]map[string]*models.OpenAPI, error) {
+	downloadTestsFolder := filepath.Join("./Download", "Tests")
+
+	entries, err := os.ReadDir(downloadTestsFolder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tests directory: %w", err)
+	}
+	testsMapping := make(map[string]map[string]map[string]*models.OpenAPI)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// Download/Tests/service-name contains the schema test sets, the keploy tests are kept under "schema"
+		serviceFolder := filepath.Join(downloadTestsFolder, entry.Name())
+		testSetIDs, err := os.ReadDir(serviceFolder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service directory %s: %w", serviceFolder, err)
+		}
+
+		testsMapping[entry.Name()] = make(map[string]map[string]*models.OpenAPI)
+		for _, testSetID := range testSetIDs {
+			if !testSetID.IsDir() || testSetID.Name() == "schema" {
+				continue
+			}
+			tests, err := s.openAPIDB.GetTestCasesSchema(ctx, testSetID.Name(), serviceFolder)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get test cases for testSetID %s: %w", testSetID.Name(), err)
+			}
+			testsMapping[entry.Name()][testSetID.Name()] = make(map[string]*models.OpenAPI)
+			for _, test := range tests {
+				testsMapping[entry.Name()][testSetID.Name()][test.Info.Title] = test
+			}
+		}
+	}
+	return testsMapping, nil
+}
+
+// GetAllMocksSchemas retrieves the mocks schema of the current service for every service it calls.
+func (s *contract) GetAllMocksSchemas(ctx context.Context) ([]models.MockMapping, error) {
+	mocksFolder := filepath.Join(s.config.Path, "schema", "mocks")
+
+	services, err := os.ReadDir(mocksFolder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mocks directory: %w", err)
+	}
+	var mocksSchemasMapping []models.MockMapping
+	for _, service := range services {
+		if !service.IsDir() {
+			continue
+		}
+		serviceFolder := filepath.Join(mocksFolder, service.Name())
+		mockSetIDs, err := os.ReadDir(serviceFolder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service directory %s: %w", serviceFolder, err)
+		}
+
+		for _, mockSetID := range mockSetIDs {
+			if !mockSetID.IsDir() {
+				continue
+			}
+			mocks, err := s.openAPIDB.GetMocksSchemas(ctx, mockSetID.Name(), serviceFolder, "mocks")
+			if err != nil {
+				return nil, fmt.Errorf("failed to get HTTP mocks for mockSetID %s: %w", mockSetID.Name(), err)
+			}
+			mocksSchemasMapping = append(mocksSchemasMapping, models.MockMapping{
+				Service:   service.Name(),
+				TestSetID: mockSetID.Name(),
+				Mocks:     mocks,
+			})
+		}
+	}
+	return mocksSchemasMapping, nil
+}
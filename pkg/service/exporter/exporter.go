@@ -0,0 +1,132 @@
+// Package exporter writes the test-sets in the formats of the other http tools, so that the test cases
+// recorded by keploy can be shared with them.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/collection"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// formats of the export
+const (
+	FormatHar     = "har"
+	FormatPostman = "postman"
+)
+
+type Exporter struct {
+	logger      *zap.Logger
+	testDB      TestDB
+	testSetConf TestSetConfig
+	config      *config.Config
+}
+
+func New(logger *zap.Logger, testDB TestDB, testSetConf TestSetConfig, config *config.Config) Service {
+	return &Exporter{
+		logger:      logger,
+		testDB:      testDB,
+		testSetConf: testSetConf,
+		config:      config,
+	}
+}
+
+// Export writes a file per test-set in the output directory, the templatized values of the test-set are
+// written as the variables of the Postman collection, and replaced with their values in the HAR file.
+func (e *Exporter) Export(ctx context.Context) error {
+	format := e.config.Export.Format
+	if format != FormatHar && format != FormatPostman {
+		return fmt.Errorf("unsupported export format %q, supported formats are %s and %s", format, FormatHar, FormatPostman)
+	}
+
+	testSetIDs := e.config.Export.TestSets
+	if len(testSetIDs) == 0 {
+		var err error
+		testSetIDs, err = e.testDB.GetAllTestSetIDs(ctx)
+		if err != nil {
+			utils.LogError(e.logger, err, "failed to get the test-sets")
+			return err
+		}
+	}
+	if len(testSetIDs) == 0 {
+		return fmt.Errorf("no test-set found in %s", e.config.Path)
+	}
+
+	err := os.MkdirAll(e.config.Export.Output, 0o755)
+	if err != nil {
+		utils.LogError(e.logger, err, "failed to create the output directory", zap.Any("path", e.config.Export.Output))
+		return err
+	}
+
+	for _, testSetID := range testSetIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		path, err := e.exportTestSet(ctx, testSetID, format)
+		if err != nil {
+			utils.LogError(e.logger, err, "failed to export the test-set", zap.Any("testSet", testSetID))
+			return err
+		}
+		if path != "" {
+			e.logger.Info("exported the test-set", zap.Any("testSet", testSetID), zap.Any("path", path))
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) exportTestSet(ctx context.Context, testSetID string, format string) (string, error) {
+	tcs, err := e.testDB.GetTestCases(ctx, testSetID)
+	if err != nil {
+		return "", err
+	}
+	var httpTcs []*models.TestCase
+	for _, tc := range tcs {
+		if tc.Kind == models.HTTP {
+			httpTcs = append(httpTcs, tc)
+		}
+	}
+	if len(httpTcs) == 0 {
+		e.logger.Warn("skipping the test-set, it has no http test case", zap.Any("testSet", testSetID))
+		return "", nil
+	}
+	if len(httpTcs) < len(tcs) {
+		e.logger.Warn("the grpc test cases are not exported", zap.Any("testSet", testSetID), zap.Any("skipped", len(tcs)-len(httpTcs)))
+	}
+
+	var values map[string]interface{}
+	conf, err := e.testSetConf.Read(ctx, testSetID)
+	if err == nil && conf != nil {
+		values = conf.Template
+	}
+
+	name := testSetID + ".har"
+	if format == FormatPostman {
+		name = testSetID + ".postman_collection.json"
+	}
+	path := filepath.Join(e.config.Export.Output, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			utils.LogError(e.logger, err, "failed to close the exported file", zap.Any("path", path))
+		}
+	}()
+
+	if format == FormatPostman {
+		err = collection.WritePostman(f, testSetID, httpTcs, values)
+	} else {
+		err = collection.WriteHar(f, httpTcs, values)
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,21 @@
+package exporter
+
+import (
+	"context"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type Service interface {
+	// Export writes the http test cases of the test-sets as HAR files or Postman collections
+	Export(ctx context.Context) error
+}
+
+type TestDB interface {
+	GetAllTestSetIDs(ctx context.Context) ([]string, error)
+	GetTestCases(ctx context.Context, testSetID string) ([]*models.TestCase, error)
+}
+
+type TestSetConfig interface {
+	Read(ctx context.Context, testSetID string) (*models.TestSet, error)
+}
@@ -0,0 +1,246 @@
+// Package importer turns the traffic captured outside of keploy, and the requests of the other http tools, into test-sets.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/collection"
+	"go.keploy.io/server/v2/pkg/platform/pcap"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+type Importer struct {
+	logger          *zap.Logger
+	testDB          TestDB
+	mockDB          MockDB
+	testSetConf     TestSetConfig
+	instrumentation Instrumentation
+	config          *config.Config
+}
+
+func New(logger *zap.Logger, testDB TestDB, mockDB MockDB, testSetConf TestSetConfig, instrumentation Instrumentation, config *config.Config) Service {
+	return &Importer{
+		logger:          logger,
+		testDB:          testDB,
+		mockDB:          mockDB,
+		testSetConf:     testSetConf,
+		instrumentation: instrumentation,
+		config:          config,
+	}
+}
+
+// ImportPcap reads the tcp connections of the capture. The connections made to the ports of the app are
+// assembled into test cases, the other ones are the calls of the app to its dependencies and are
+// decoded into mocks by the parser of their protocol.
+func (i *Importer) ImportPcap(ctx context.Context, path string) error {
+	if len(i.config.Import.AppPorts) == 0 {
+		return errors.New("the ports of the app are not given, the calls received by the app can't be told from the ones it made")
+	}
+	appPorts := make(map[uint32]bool, len(i.config.Import.AppPorts))
+	for _, port := range i.config.Import.AppPorts {
+		appPorts[port] = true
+	}
+
+	conns, err := pcap.ReadFile(i.logger, path)
+	if err != nil {
+		utils.LogError(i.logger, err, "failed to read the capture", zap.Any("path", path))
+		return err
+	}
+	if len(conns) == 0 {
+		return fmt.Errorf("no tcp connection found in the capture %s", path)
+	}
+
+	testSetID, err := i.nextTestSetID(ctx)
+	if err != nil {
+		return err
+	}
+
+	protocols := config.GetProtocolRules(i.config)
+	outgoingOpts := models.OutgoingOptions{
+		Rules:      i.config.BypassRules,
+		RedisNoise: i.config.Test.RedisNoise,
+		Protocols:  protocols,
+	}
+	incomingOpts := models.IncomingOptions{
+		Filters: i.config.Record.Filters,
+	}
+
+	bypassPorts := make(map[uint]bool)
+	for _, port := range config.GetByPassPorts(i.config) {
+		bypassPorts[port] = true
+	}
+
+	testCount := 0
+	mockCount := 0
+	for _, conn := range conns {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if appPorts[conn.ServerPort] {
+			// the channel is drained even after a failure, so that the capturing routine ends
+			var insertErr error
+			for tc := range i.instrumentation.CaptureIncoming(ctx, conn, incomingOpts) {
+				if insertErr != nil {
+					continue
+				}
+				insertErr = i.testDB.InsertTestCase(ctx, tc, testSetID)
+				if insertErr == nil {
+					testCount++
+				}
+			}
+			if insertErr != nil {
+				utils.LogError(i.logger, insertErr, "failed to insert the test case")
+				return insertErr
+			}
+			continue
+		}
+
+		if bypassPorts[uint(conn.ServerPort)] {
+			continue
+		}
+
+		mocks := make(chan *models.Mock, 100)
+		g, gCtx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			defer utils.Recover(i.logger)
+			defer close(mocks)
+			return i.instrumentation.CaptureOutgoing(gCtx, conn, mocks, outgoingOpts)
+		})
+		var insertErr error
+		for mock := range mocks {
+			if insertErr != nil {
+				continue
+			}
+			insertErr = i.mockDB.InsertMock(ctx, mock, testSetID)
+			if insertErr == nil {
+				mockCount++
+			}
+		}
+		if insertErr != nil {
+			utils.LogError(i.logger, insertErr, "failed to insert the mock")
+			return insertErr
+		}
+		err := g.Wait()
+		if err != nil {
+			utils.LogError(i.logger, err, "failed to decode the outgoing calls of the capture", zap.Any("server", conn.ServerAddr))
+			return err
+		}
+	}
+
+	if testCount == 0 && mockCount == 0 {
+		i.logger.Warn("no call could be imported from the capture, check the ports of the app", zap.Any("appPorts", i.config.Import.AppPorts))
+		return nil
+	}
+
+	// the server-first protocols are routed to their parsers in the test mode from the test-set config
+	if len(protocols) > 0 {
+		conf, err := i.testSetConf.Read(ctx, testSetID)
+		if err != nil || conf == nil {
+			conf = &models.TestSet{}
+		}
+		conf.Protocols = protocols
+		err = i.testSetConf.Write(ctx, testSetID, conf)
+		if err != nil {
+			utils.LogError(i.logger, err, "failed to save the protocols of the dependencies")
+		}
+	}
+
+	i.logger.Info("imported the capture", zap.Any("testSet", testSetID), zap.Any("tests", testCount), zap.Any("mocks", mockCount))
+	return nil
+}
+
+// ImportHar turns the entries of a HAR file, e.g. exported from the network tab of a browser, into the
+// test cases of a new test-set.
+func (i *Importer) ImportHar(ctx context.Context, path string) error {
+	tcs, err := collection.ReadHarFile(i.logger, path)
+	if err != nil {
+		utils.LogError(i.logger, err, "failed to read the har file", zap.Any("path", path))
+		return err
+	}
+	if len(tcs) == 0 {
+		return fmt.Errorf("no request with a response found in the har file %s", path)
+	}
+	testSetID, err := i.insertTestCases(ctx, tcs)
+	if err != nil {
+		return err
+	}
+	i.logger.Info("imported the har file", zap.Any("testSet", testSetID), zap.Any("tests", len(tcs)))
+	return nil
+}
+
+// ImportPostman turns the requests of a Postman collection into the test cases of a new test-set. The saved
+// responses of the requests are the expected ones and the variables of the collection are the templatized
+// values of the test-set.
+func (i *Importer) ImportPostman(ctx context.Context, path string) error {
+	res, err := collection.ReadPostmanFile(i.logger, path)
+	if err != nil {
+		utils.LogError(i.logger, err, "failed to read the postman collection", zap.Any("path", path))
+		return err
+	}
+	if len(res.TestCases) == 0 {
+		return fmt.Errorf("no request found in the postman collection %s", path)
+	}
+	testSetID, err := i.insertTestCases(ctx, res.TestCases)
+	if err != nil {
+		return err
+	}
+
+	if len(res.Variables) > 0 {
+		conf, err := i.testSetConf.Read(ctx, testSetID)
+		if err != nil || conf == nil {
+			conf = &models.TestSet{}
+		}
+		if conf.Template == nil {
+			conf.Template = map[string]interface{}{}
+		}
+		for key, value := range res.Variables {
+			conf.Template[key] = value
+		}
+		err = i.testSetConf.Write(ctx, testSetID, conf)
+		if err != nil {
+			utils.LogError(i.logger, err, "failed to save the variables of the collection")
+			return err
+		}
+	}
+
+	if res.NoResponse > 0 {
+		i.logger.Warn("some requests have no saved response, record their responses with keploy rerecord", zap.Any("testSet", testSetID), zap.Any("requests", res.NoResponse))
+	}
+	i.logger.Info("imported the postman collection", zap.Any("testSet", testSetID), zap.Any("tests", len(res.TestCases)), zap.Any("variables", len(res.Variables)))
+	return nil
+}
+
+// insertTestCases inserts the test cases in a new test-set and returns its id.
+func (i *Importer) insertTestCases(ctx context.Context, tcs []*models.TestCase) (string, error) {
+	testSetID, err := i.nextTestSetID(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, tc := range tcs {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		err := i.testDB.InsertTestCase(ctx, tc, testSetID)
+		if err != nil {
+			utils.LogError(i.logger, err, "failed to insert the test case")
+			return "", err
+		}
+	}
+	return testSetID, nil
+}
+
+func (i *Importer) nextTestSetID(ctx context.Context) (string, error) {
+	testSetIDs, err := i.testDB.GetAllTestSetIDs(ctx)
+	if err != nil {
+		utils.LogError(i.logger, err, "failed to get the test-sets")
+		return "", err
+	}
+	return pkg.NextID(testSetIDs, models.TestSetPattern), nil
+}
@@ -0,0 +1,35 @@
+package importer
+
+import (
+	"context"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type Service interface {
+	// ImportPcap turns the calls of a packet capture into the test cases and the mocks of a new test-set
+	ImportPcap(ctx context.Context, path string) error
+	// ImportHar turns the entries of a HAR file into the test cases of a new test-set
+	ImportHar(ctx context.Context, path string) error
+	// ImportPostman turns the requests of a Postman collection into the test cases of a new test-set
+	ImportPostman(ctx context.Context, path string) error
+}
+
+type Instrumentation interface {
+	CaptureIncoming(ctx context.Context, captured *models.CapturedConn, opts models.IncomingOptions) <-chan *models.TestCase
+	CaptureOutgoing(ctx context.Context, captured *models.CapturedConn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error
+}
+
+type TestDB interface {
+	GetAllTestSetIDs(ctx context.Context) ([]string, error)
+	InsertTestCase(ctx context.Context, tc *models.TestCase, testSetID string) error
+}
+
+type MockDB interface {
+	InsertMock(ctx context.Context, mock *models.Mock, testSetID string) error
+}
+
+type TestSetConfig interface {
+	Read(ctx context.Context, testSetID string) (*models.TestSet, error)
+	Write(ctx context.Context, testSetID string, testSet *models.TestSet) error
+}
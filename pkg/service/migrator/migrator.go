@@ -0,0 +1,139 @@
+// Package migrator moves the test-sets recorded in the yaml files to the sqlite storage backend.
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+type Migrator struct {
+	logger      *zap.Logger
+	testDB      TestDB
+	mockDB      MockDB
+	testSetConf TestSetConfig
+	reportDB    ReportDB
+	dest        Destination
+	config      *config.Config
+}
+
+func New(logger *zap.Logger, testDB TestDB, mockDB MockDB, testSetConf TestSetConfig, reportDB ReportDB, dest Destination, config *config.Config) Service {
+	return &Migrator{
+		logger:      logger,
+		testDB:      testDB,
+		mockDB:      mockDB,
+		testSetConf: testSetConf,
+		reportDB:    reportDB,
+		dest:        dest,
+		config:      config,
+	}
+}
+
+// Migrate copies every test-set with its tests, mocks and config, then the reports of the test runs. The
+// names of the test cases and the mocks are kept, the yaml files are left as they are.
+func (m *Migrator) Migrate(ctx context.Context) error {
+	testSetIDs, err := m.testDB.GetAllTestSetIDs(ctx)
+	if err != nil {
+		utils.LogError(m.logger, err, "failed to get the test-sets")
+		return err
+	}
+	if len(testSetIDs) == 0 {
+		return fmt.Errorf("no test-set found in %s", m.config.Path)
+	}
+
+	for _, testSetID := range testSetIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := m.migrateTestSet(ctx, testSetID); err != nil {
+			utils.LogError(m.logger, err, "failed to migrate the test-set", zap.Any("testSet", testSetID))
+			return err
+		}
+	}
+
+	testRunIDs, err := m.reportDB.GetAllTestRunIDs(ctx)
+	if err != nil {
+		utils.LogError(m.logger, err, "failed to get the test runs")
+		return err
+	}
+	for _, testRunID := range testRunIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := m.migrateTestRun(ctx, testRunID); err != nil {
+			utils.LogError(m.logger, err, "failed to migrate the reports of the test run", zap.Any("testRun", testRunID))
+			return err
+		}
+	}
+
+	m.logger.Info("migrated the test-sets to the sqlite database, set storageBackend to sqlite in the keploy config or pass --storage-backend sqlite to use it",
+		zap.Any("path", m.dest.Path()), zap.Any("testSets", len(testSetIDs)), zap.Any("testRuns", len(testRunIDs)))
+	return nil
+}
+
+func (m *Migrator) migrateTestSet(ctx context.Context, testSetID string) error {
+	tcs, err := m.testDB.GetTestCases(ctx, testSetID)
+	if err != nil {
+		return err
+	}
+	// without a time window all the mocks of the test-set are returned
+	filtered, err := m.mockDB.GetFilteredMocks(ctx, testSetID, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	unfiltered, err := m.mockDB.GetUnFilteredMocks(ctx, testSetID, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	mocks := append(filtered, unfiltered...)
+	// keep the recorded order of the mocks, it is the order they are matched in
+	sort.SliceStable(mocks, func(i, j int) bool {
+		return mockIndex(mocks[i]) < mockIndex(mocks[j])
+	})
+
+	conf, err := m.testSetConf.Read(ctx, testSetID)
+	if err != nil {
+		// most of the test-sets have no config
+		conf = nil
+	}
+
+	err = m.dest.ImportTestSet(ctx, testSetID, tcs, mocks, conf)
+	if err != nil {
+		return err
+	}
+	m.logger.Info("migrated the test-set", zap.Any("testSet", testSetID), zap.Any("tests", len(tcs)), zap.Any("mocks", len(mocks)))
+	return nil
+}
+
+func (m *Migrator) migrateTestRun(ctx context.Context, testRunID string) error {
+	testSetIDs, err := m.reportDB.GetReportTestSetIDs(ctx, testRunID)
+	if err != nil {
+		return err
+	}
+	for _, testSetID := range testSetIDs {
+		report, err := m.reportDB.GetReport(ctx, testRunID, testSetID)
+		if err != nil {
+			return err
+		}
+		err = m.dest.ImportReport(ctx, testRunID, testSetID, report)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mockIndex returns the index of the mock in the order of the recording, from its name.
+func mockIndex(mock *models.Mock) int {
+	var index int
+	if _, err := fmt.Sscanf(mock.Name, "mock-%d", &index); err != nil {
+		return int(^uint(0) >> 1)
+	}
+	return index
+}
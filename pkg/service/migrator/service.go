@@ -0,0 +1,40 @@
+package migrator
+
+import (
+	"context"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type Service interface {
+	// Migrate copies the test-sets and the reports of the yaml files to the sqlite database
+	Migrate(ctx context.Context) error
+}
+
+type TestDB interface {
+	GetAllTestSetIDs(ctx context.Context) ([]string, error)
+	GetTestCases(ctx context.Context, testSetID string) ([]*models.TestCase, error)
+}
+
+type MockDB interface {
+	GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+	GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+}
+
+type TestSetConfig interface {
+	Read(ctx context.Context, testSetID string) (*models.TestSet, error)
+}
+
+type ReportDB interface {
+	GetAllTestRunIDs(ctx context.Context) ([]string, error)
+	GetReportTestSetIDs(ctx context.Context, testRunID string) ([]string, error)
+	GetReport(ctx context.Context, testRunID string, testSetID string) (*models.TestReport, error)
+}
+
+// Destination is the database the test-sets are migrated to.
+type Destination interface {
+	Path() string
+	ImportTestSet(ctx context.Context, testSetID string, tcs []*models.TestCase, mocks []*models.Mock, conf *models.TestSet) error
+	ImportReport(ctx context.Context, testRunID string, testSetID string, report *models.TestReport) error
+}
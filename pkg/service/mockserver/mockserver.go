@@ -0,0 +1,84 @@
+// Package mockserver serves the recorded mocks of a test-set on local ports or as a http proxy,
+// without the eBPF hooks, so that the mocks can be used where keploy can't instrument the application.
+package mockserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+type MockServer struct {
+	logger      *zap.Logger
+	mockDB      MockDB
+	testSetConf TestSetConfig
+	server      Server
+	config      *config.Config
+}
+
+func New(logger *zap.Logger, mockDB MockDB, testSetConf TestSetConfig, server Server, config *config.Config) Service {
+	return &MockServer{
+		logger:      logger,
+		mockDB:      mockDB,
+		testSetConf: testSetConf,
+		server:      server,
+		config:      config,
+	}
+}
+
+func (m *MockServer) Serve(ctx context.Context) error {
+	testSetID := m.config.MockServer.TestSet
+	if testSetID == "" {
+		return errors.New("the test-set whose mocks are to be served is not given, use --test-set")
+	}
+
+	filtered, err := m.mockDB.GetFilteredMocks(ctx, testSetID, models.BaseTime, time.Now())
+	if err != nil {
+		utils.LogError(m.logger, err, "failed to get filtered mocks", zap.Any("testSet", testSetID))
+		return err
+	}
+	unfiltered, err := m.mockDB.GetUnFilteredMocks(ctx, testSetID, models.BaseTime, time.Now())
+	if err != nil {
+		utils.LogError(m.logger, err, "failed to get unfiltered mocks", zap.Any("testSet", testSetID))
+		return err
+	}
+	if len(filtered)+len(unfiltered) == 0 {
+		return fmt.Errorf("no mocks found in the test-set %s", testSetID)
+	}
+	m.logger.Info("loaded the mocks of the test-set", zap.Any("testSet", testSetID), zap.Any("mocks", len(filtered)+len(unfiltered)))
+
+	protocols := config.GetProtocolRules(m.config)
+	conf, confErr := m.testSetConf.Read(ctx, testSetID)
+	if confErr == nil && conf != nil {
+		for port, protocol := range conf.Protocols {
+			// the protocols configured by the user take precedence over the recorded ones
+			if _, ok := protocols[port]; !ok {
+				protocols[port] = protocol
+			}
+		}
+	}
+
+	err = m.server.ServeMocks(ctx, models.MockServerOptions{
+		Listeners: m.config.MockServer.Listeners,
+		HTTPProxy: m.config.MockServer.HTTPProxy,
+		OutgoingOptions: models.OutgoingOptions{
+			Rules:          m.config.BypassRules,
+			MongoPassword:  m.config.Test.MongoPassword,
+			FallBackOnMiss: m.config.Test.FallBackOnMiss,
+			Mocking:        true,
+			RedisNoise:     m.config.Test.RedisNoise,
+			Protocols:      protocols,
+		},
+	}, filtered, unfiltered)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		utils.LogError(m.logger, err, "failed to serve the mocks")
+		return err
+	}
+	return nil
+}
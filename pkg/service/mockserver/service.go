@@ -0,0 +1,27 @@
+package mockserver
+
+import (
+	"context"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type Service interface {
+	// Serve serves the mocks of the test-set until the context is cancelled
+	Serve(ctx context.Context) error
+}
+
+type MockDB interface {
+	GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+	GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+}
+
+type TestSetConfig interface {
+	Read(ctx context.Context, testSetID string) (*models.TestSet, error)
+}
+
+// Server serves the mocks without instrumenting the application, it is implemented by the proxy.
+type Server interface {
+	ServeMocks(ctx context.Context, opts models.MockServerOptions, filtered, unFiltered []*models.Mock) error
+}
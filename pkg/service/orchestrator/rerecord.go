@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -81,7 +82,7 @@ func (o *Orchestrator) ReRecord(ctx context.Context) error {
 		default:
 			errGrp.Go(func() error {
 				defer utils.Recover(o.logger)
-				err := o.record.Start(recordCtx, true)
+				err := o.record.Start(recordCtx, models.RecordOptions{ReRecord: true})
 				errCh <- err
 				return nil
 			})
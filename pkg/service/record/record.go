@@ -23,23 +23,25 @@ type Recorder struct {
 	logger          *zap.Logger
 	testDB          TestDB
 	mockDB          MockDB
+	testSetConf     TestSetConfig
 	telemetry       Telemetry
 	instrumentation Instrumentation
 	config          *config.Config
 }
 
-func New(logger *zap.Logger, testDB TestDB, mockDB MockDB, telemetry Telemetry, instrumentation Instrumentation, config *config.Config) Service {
+func New(logger *zap.Logger, testDB TestDB, mockDB MockDB, testSetConf TestSetConfig, telemetry Telemetry, instrumentation Instrumentation, config *config.Config) Service {
 	return &Recorder{
 		logger:          logger,
 		testDB:          testDB,
 		mockDB:          mockDB,
+		testSetConf:     testSetConf,
 		telemetry:       telemetry,
 		instrumentation: instrumentation,
 		config:          config,
 	}
 }
 
-func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
+func (r *Recorder) Start(ctx context.Context, opts models.RecordOptions) error {
 
 	// creating error group to manage proper shutdown of all the go routines and to propagate the error to the caller
 	errGrp, _ := errgroup.WithContext(ctx)
@@ -53,6 +55,8 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 	hookCtx := context.WithoutCancel(ctx)
 	hookCtx, hookCtxCancel := context.WithCancel(hookCtx)
 	hookCtx = context.WithValue(hookCtx, models.ErrGroupKey, hookErrGrp)
+	// the outgoing session lasts until the protocols detected in it are saved
+	outgoingCtx, outgoingCtxCancel := context.WithCancel(context.WithoutCancel(ctx))
 	// reRecordCtx, reRecordCancel := context.WithCancel(ctx)
 	// defer reRecordCancel() // Cancel the context when the function returns
 
@@ -73,7 +77,7 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 		select {
 		case <-ctx.Done():
 		default:
-			if !reRecord {
+			if !opts.ReRecord && !opts.Instrumented {
 				err := utils.Stop(r.logger, stopReason)
 				if err != nil {
 					utils.LogError(r.logger, err, "failed to stop recording")
@@ -94,6 +98,13 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 		if err != nil {
 			utils.LogError(r.logger, err, "failed to stop recording")
 		}
+		if testCount > 0 || len(mockCountMap) > 0 {
+			err = r.saveProtocols(context.WithoutCancel(ctx), appID, newTestSetID)
+			if err != nil {
+				utils.LogError(r.logger, err, "failed to save the protocols of the dependencies")
+			}
+		}
+		outgoingCtxCancel()
 		r.telemetry.RecordedTestSuite(newTestSetID, testCount, mockCountMap)
 	}()
 
@@ -115,18 +126,22 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 	default:
 	}
 
-	// Instrument will setup the environment and start the hooks and proxy
-	appID, err = r.Instrument(hookCtx)
-	if err != nil {
-		stopReason = "failed to instrument the application"
-		utils.LogError(r.logger, err, stopReason)
-		return fmt.Errorf(stopReason)
+	if opts.Instrumented {
+		appID = opts.AppID
+	} else {
+		// Instrument will setup the environment and start the hooks and proxy
+		appID, err = r.Instrument(hookCtx)
+		if err != nil {
+			stopReason = "failed to instrument the application"
+			utils.LogError(r.logger, err, stopReason)
+			return fmt.Errorf(stopReason)
+		}
 	}
 
 	r.config.AppID = appID
 
 	// fetching test cases and mocks from the application and inserting them into the database
-	frames, err := r.GetTestAndMockChans(ctx, appID)
+	frames, err := r.GetTestAndMockChans(ctx, outgoingCtx, appID)
 	if err != nil {
 		stopReason = "failed to get data frames"
 		utils.LogError(r.logger, err, stopReason)
@@ -153,8 +168,24 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 		return nil
 	})
 
+	// the proxy closes the mock channel when it stops, the proxy of an instrumented app outlives the session
+	// so its mocks are read until the session ends.
+	var sessionDone <-chan struct{}
+	if opts.Instrumented {
+		sessionDone = ctx.Done()
+	}
 	errGrp.Go(func() error {
-		for mock := range frames.Outgoing {
+		for {
+			var mock *models.Mock
+			var ok bool
+			select {
+			case mock, ok = <-frames.Outgoing:
+			case <-sessionDone:
+				return nil
+			}
+			if !ok {
+				return nil
+			}
 			err := r.mockDB.InsertMock(ctx, mock, newTestSetID)
 			if err != nil {
 				if ctx.Err() == context.Canceled {
@@ -166,7 +197,6 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 				r.telemetry.RecordedTestCaseMock(mock.GetKind())
 			}
 		}
-		return nil
 	})
 
 	// running the user application
@@ -264,7 +294,9 @@ func (r *Recorder) Instrument(ctx context.Context) (uint64, error) {
 	return appID, nil
 }
 
-func (r *Recorder) GetTestAndMockChans(ctx context.Context, appID uint64) (FrameChan, error) {
+// GetTestAndMockChans returns the channels of the test cases and the mocks recorded for the app,
+// the outgoing session of the proxy ends with outgoingCtx.
+func (r *Recorder) GetTestAndMockChans(ctx context.Context, outgoingCtx context.Context, appID uint64) (FrameChan, error) {
 	incomingOpts := models.IncomingOptions{
 		Filters: r.config.Record.Filters,
 	}
@@ -277,8 +309,9 @@ func (r *Recorder) GetTestAndMockChans(ctx context.Context, appID uint64) (Frame
 		Rules:          r.config.BypassRules,
 		MongoPassword:  r.config.Test.MongoPassword,
 		FallBackOnMiss: r.config.Test.FallBackOnMiss,
+		Protocols:      config.GetProtocolRules(r.config),
 	}
-	outgoingChan, err := r.instrumentation.GetOutgoing(ctx, appID, outgoingOpts)
+	outgoingChan, err := r.instrumentation.GetOutgoing(outgoingCtx, appID, outgoingOpts)
 	if err != nil {
 		return FrameChan{}, fmt.Errorf("failed to get outgoing mocks: %w", err)
 	}
@@ -289,6 +322,25 @@ func (r *Recorder) GetTestAndMockChans(ctx context.Context, appID uint64) (Frame
 	}, nil
 }
 
+// saveProtocols persists the protocols used on the destination ports in the test-set config,
+// so that the test mode routes the outgoing calls to the same parsers.
+func (r *Recorder) saveProtocols(ctx context.Context, appID uint64, testSetID string) error {
+	protocols, err := r.instrumentation.GetProtocols(ctx, appID)
+	if err != nil {
+		return err
+	}
+	if len(protocols) == 0 {
+		return nil
+	}
+
+	conf, err := r.testSetConf.Read(ctx, testSetID)
+	if err != nil || conf == nil {
+		conf = &models.TestSet{}
+	}
+	conf.Protocols = protocols
+	return r.testSetConf.Write(ctx, testSetID, conf)
+}
+
 func (r *Recorder) RunApplication(ctx context.Context, appID uint64, opts models.RunOptions) models.AppError {
 	return r.instrumentation.Run(ctx, appID, opts)
 }
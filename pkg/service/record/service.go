@@ -16,10 +16,14 @@ type Instrumentation interface {
 	// Run is blocking call and will execute until error
 	Run(ctx context.Context, id uint64, opts models.RunOptions) models.AppError
 	GetContainerIP(ctx context.Context, id uint64) (string, error)
+	// GetProtocols returns the protocols of the server-first dependencies detected on the destination ports
+	GetProtocols(ctx context.Context, id uint64) (map[uint32]string, error)
 }
 
 type Service interface {
-	Start(ctx context.Context, reRecord bool) error
+	Start(ctx context.Context, opts models.RecordOptions) error
+	// Instrument sets up the app of the config and hooks it for recording, the hooks last until the context is cancelled
+	Instrument(ctx context.Context) (uint64, error)
 	GetContainerIP(ctx context.Context, id uint64) (string, error)
 }
 
@@ -33,6 +37,11 @@ type MockDB interface {
 	InsertMock(ctx context.Context, mock *models.Mock, testSetID string) error
 }
 
+type TestSetConfig interface {
+	Read(ctx context.Context, testSetID string) (*models.TestSet, error)
+	Write(ctx context.Context, testSetID string, testSet *models.TestSet) error
+}
+
 type Telemetry interface {
 	RecordedTestSuite(testSet string, testsTotal int, mockTotal map[string]int)
 	RecordedTestCaseMock(mockType string)
@@ -0,0 +1,293 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// noiseProposal is the noise found for a test case by the denoise.
+type noiseProposal struct {
+	testCase *models.TestCase
+	fields   []string
+	// reason why the responses still differ with the proposed noise, e.g. a different status code
+	unstable string
+}
+
+// denoise replays the test-sets Test.DenoiseRuns times against the same mocks and diffs the actual responses of
+// the runs. The fields which vary between the runs (timestamps, uuids, random tokens) are proposed as the noise
+// of the test cases, and written once the user confirms them.
+func (r *Replayer) denoise(ctx context.Context, testSets []string, appID uint64) error {
+	runs := r.config.Test.DenoiseRuns
+	// actual responses of the http test cases, by test-set and test case, one per run
+	responses := make(map[string]map[string][]models.HTTPResp)
+
+	for run := 1; run <= runs; run++ {
+		testRunID, err := r.GetNextTestRunID(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get next test run id: %w", err)
+		}
+		r.logger.Info("replaying the test-sets to find the noisy fields", zap.Any("run", run), zap.Any("of", runs), zap.Any("test-run", testRunID))
+
+		for _, testSet := range testSets {
+			err := HookImpl.BeforeTestSetRun(ctx, testSet)
+			if err != nil {
+				return fmt.Errorf("failed to run before test hook: %w", err)
+			}
+			testSetStatus, err := r.RunTestSet(ctx, testSet, testRunID, appID, false)
+			if err != nil {
+				return fmt.Errorf("failed to run test set: %w", err)
+			}
+			switch testSetStatus {
+			case models.TestSetStatusUserAbort:
+				return context.Canceled
+			case models.TestSetStatusAppHalted, models.TestSetStatusInternalErr, models.TestSetStatusFaultUserApp, models.TestSetStatusFaultScript:
+				return fmt.Errorf("the test-set %s could not be replayed: %s", testSet, testSetStatus)
+			}
+			err = HookImpl.AfterTestSetRun(ctx, testSet, testSetStatus == models.TestSetStatusPassed)
+			if err != nil {
+				utils.LogError(r.logger, err, "failed to execute after test set run hook", zap.Any("testSet", testSet))
+			}
+
+			results, err := r.reportDB.GetTestCaseResults(ctx, testRunID, testSet)
+			if err != nil {
+				r.logger.Debug("no test results to denoise", zap.Any("testSet", testSet), zap.Error(err))
+				continue
+			}
+			if responses[testSet] == nil {
+				responses[testSet] = make(map[string][]models.HTTPResp)
+			}
+			for _, result := range results {
+				if result.Kind != models.HTTP || result.Status == models.TestStatusIgnored {
+					continue
+				}
+				responses[testSet][result.TestCaseID] = append(responses[testSet][result.TestCaseID], result.Res)
+			}
+		}
+	}
+
+	proposals := make(map[string][]noiseProposal)
+	var total int
+	for _, testSet := range testSets {
+		testCases, err := r.testDB.GetTestCases(ctx, testSet)
+		if err != nil {
+			return fmt.Errorf("failed to get test cases: %w", err)
+		}
+		for _, tc := range testCases {
+			resps := responses[testSet][tc.Name]
+			if len(resps) < 2 {
+				continue
+			}
+			proposal := r.proposeNoise(tc, resps)
+			if len(proposal.fields) == 0 && proposal.unstable == "" {
+				continue
+			}
+			proposals[testSet] = append(proposals[testSet], proposal)
+			total += len(proposal.fields)
+		}
+	}
+
+	if len(proposals) == 0 {
+		r.logger.Info("the responses are identical across the runs, no noise to add", zap.Any("runs", runs))
+		return nil
+	}
+	r.printNoiseProposals(testSets, proposals)
+	if total == 0 {
+		return nil
+	}
+
+	if r.config.InCi {
+		r.logger.Info("the noise is not written in CI, run the denoise locally to confirm it")
+		return nil
+	}
+	r.logger.Info("Do you want to add the proposed noise to the testcases? (y/n)")
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		r.logger.Warn("failed to read input. The testcases will be kept as they are.")
+		return nil
+	}
+	if input != "y\n" && input != "Y\n" {
+		r.logger.Info("skipping the noise of the testcases")
+		return nil
+	}
+
+	for _, testSet := range testSets {
+		for _, proposal := range proposals[testSet] {
+			if len(proposal.fields) == 0 {
+				continue
+			}
+			tc := proposal.testCase
+			if tc.Noise == nil {
+				tc.Noise = map[string][]string{}
+			}
+			noise := make(map[string][]string, len(proposal.fields))
+			for _, field := range proposal.fields {
+				noise[field] = []string{}
+			}
+			tc.Noise = mergeMaps(tc.Noise, noise)
+			err := r.testDB.UpdateTestCase(ctx, tc, testSet)
+			if err != nil {
+				return fmt.Errorf("failed to update test case: %w", err)
+			}
+		}
+	}
+	r.logger.Info("Added the noise to the testcases successfully. Please run keploy tests to verify the changes.")
+	return nil
+}
+
+// proposeNoise returns the fields of the responses which vary between the runs and aren't noise already.
+func (r *Replayer) proposeNoise(tc *models.TestCase, resps []models.HTTPResp) noiseProposal {
+	proposal := noiseProposal{testCase: tc}
+	first := resps[0]
+	noisy := map[string]bool{}
+	for field := range tc.Noise {
+		noisy[strings.ToLower(field)] = true
+	}
+	add := func(field string) {
+		if noisy[strings.ToLower(field)] {
+			return
+		}
+		noisy[strings.ToLower(field)] = true
+		proposal.fields = append(proposal.fields, field)
+	}
+
+	for _, resp := range resps[1:] {
+		if resp.StatusCode != first.StatusCode {
+			proposal.unstable = fmt.Sprintf("status code %d and %d", first.StatusCode, resp.StatusCode)
+		}
+	}
+
+	// the headers missing in a run vary as well
+	headers := map[string]bool{}
+	for _, resp := range resps {
+		for key := range resp.Header {
+			headers[key] = true
+		}
+	}
+	for key := range headers {
+		for _, resp := range resps[1:] {
+			val, ok := resp.Header[key]
+			firstVal, firstOk := first.Header[key]
+			if ok != firstOk || val != firstVal {
+				add("header." + key)
+				break
+			}
+		}
+	}
+
+	if noisy["body"] {
+		return proposal
+	}
+	bodies := make([]interface{}, 0, len(resps))
+	for _, resp := range resps {
+		if !json.Valid([]byte(resp.Body)) {
+			bodies = nil
+			break
+		}
+		body, err := matcherUtils.UnmarshallJSON(resp.Body, r.logger)
+		if err != nil {
+			bodies = nil
+			break
+		}
+		bodies = append(bodies, body)
+	}
+	if bodies == nil {
+		// the bodies which aren't json can only be ignored as a whole
+		for _, resp := range resps[1:] {
+			if resp.Body != first.Body {
+				add("body")
+				break
+			}
+		}
+		return proposal
+	}
+
+	flattened := make([]map[string][]string, 0, len(bodies))
+	for _, body := range bodies {
+		flattened = append(flattened, matcherUtils.Flatten(body))
+	}
+	for key, values := range flattened[0] {
+		for _, flat := range flattened[1:] {
+			other, ok := flat[key]
+			// the values of the arrays of a different length differ anyway, noise wouldn't fix them
+			if !ok || len(values) != len(other) || reflect.DeepEqual(values, other) {
+				continue
+			}
+			if key == "" {
+				add("body")
+			} else {
+				add("body." + key)
+			}
+			break
+		}
+	}
+
+	// the fields missing in a run, or the arrays of a different length, can't be made noisy
+	if proposal.unstable == "" {
+		proposal.unstable = r.unstableBody(tc, resps, noisy)
+	}
+	return proposal
+}
+
+// unstableBody compares the json bodies of the runs with the noise and returns why they still differ.
+func (r *Replayer) unstableBody(tc *models.TestCase, resps []models.HTTPResp, noisy map[string]bool) string {
+	if noisy["body"] {
+		return ""
+	}
+	bodyNoise := map[string][]string{}
+	for field := range noisy {
+		if strings.HasPrefix(field, "body.") {
+			bodyNoise[strings.TrimPrefix(field, "body.")] = []string{}
+		}
+	}
+	for _, resp := range resps[1:] {
+		exp, act := resps[0].Body, resp.Body
+		validatedJSON, err := matcherUtils.ValidateAndMarshalJSON(r.logger, &exp, &act)
+		if err != nil {
+			return ""
+		}
+		if !validatedJSON.IsIdentical() {
+			return "the type of the body"
+		}
+		result, err := matcherUtils.JSONDiffWithNoiseControl(validatedJSON, bodyNoise, r.config.Test.IgnoreOrdering)
+		if err != nil || !result.IsExact() {
+			r.logger.Debug("the bodies of the runs differ with the proposed noise", zap.Any("testcase", tc.Name), zap.Error(err))
+			return "the fields or the array lengths of the body"
+		}
+	}
+	return ""
+}
+
+func (r *Replayer) printNoiseProposals(testSets []string, proposals map[string][]noiseProposal) {
+	for _, testSet := range testSets {
+		if len(proposals[testSet]) == 0 {
+			continue
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "\n <=========================================> \n  NOISE FOUND. For test-set: %s\n", testSet)
+		for _, proposal := range proposals[testSet] {
+			sort.Strings(proposal.fields)
+			if len(proposal.fields) > 0 {
+				fmt.Fprintf(&sb, "\t%s: %s\n", proposal.testCase.Name, strings.Join(proposal.fields, ", "))
+			}
+			if proposal.unstable != "" {
+				fmt.Fprintf(&sb, "\t%s: still varies between the runs in %s, it can't be fixed by noise\n", proposal.testCase.Name, proposal.unstable)
+			}
+		}
+		sb.WriteString(" <=========================================> \n\n")
+		if _, err := fmt.Print(sb.String()); err != nil {
+			utils.LogError(r.logger, err, "failed to print the proposed noise")
+		}
+	}
+}
@@ -36,15 +36,25 @@ func NewHooks(logger *zap.Logger, cfg *config.Config, tsConfigDB TestSetConfig,
 	}
 }
 
-func (h *Hooks) SimulateRequest(ctx context.Context, _ uint64, tc *models.TestCase, testSetID string) (*models.HTTPResp, error) {
+func (h *Hooks) SimulateRequest(ctx context.Context, _ uint64, tc *models.TestCase, testSetID string) (interface{}, error) {
 	switch tc.Kind {
 	case models.HTTP:
 		h.logger.Debug("Before simulating the request", zap.Any("Test case", tc))
 		resp, err := pkg.SimulateHTTP(ctx, tc, testSetID, h.logger, h.cfg.Test.APITimeout)
 		h.logger.Debug("After simulating the request", zap.Any("test case id", tc.Name))
 		return resp, err
+	case models.GRPC_EXPORT:
+		h.logger.Debug("Before simulating the grpc request", zap.Any("Test case", tc))
+		resp, err := pkg.SimulateGRPC(ctx, tc, testSetID, h.logger, h.cfg.Test.APITimeout)
+		h.logger.Debug("After simulating the grpc request", zap.Any("test case id", tc.Name))
+		return resp, err
+	case models.WebSocket:
+		h.logger.Debug("Before simulating the websocket request", zap.Any("Test case", tc))
+		resp, err := pkg.SimulateWebSocket(ctx, tc, testSetID, h.logger, h.cfg.Test.APITimeout)
+		h.logger.Debug("After simulating the websocket request", zap.Any("test case id", tc.Name))
+		return resp, err
 	}
-	return nil, nil
+	return nil, fmt.Errorf("simulating the request of %s testcases is not supported", tc.Kind)
 }
 
 func (h *Hooks) AfterTestSetRun(ctx context.Context, testSetID string, status bool) error {
@@ -0,0 +1,201 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// runTestSetsConcurrently runs the test-sets using the given number of workers. It returns the result of
+// the test run and whether the test run has been aborted.
+// Each worker runs its test-sets on an app of its own, whose outgoing calls are mocked by its own session,
+// unless the app is not started by keploy (--base-path).
+// The templatized values are shared by all the test-sets (utils.TemplatizedValues), hence the test-sets
+// using a template are run one after the other once the independent test-sets are done. The independent
+// test-sets only read the values, which are emptied before they start.
+func (r *Replayer) runTestSetsConcurrently(ctx context.Context, testSets []string, testRunID string, appIDs []uint64, workers int) (bool, bool, error) {
+	var independent, templatized []string
+	for _, testSetID := range testSets {
+		conf, err := r.testSetConf.Read(ctx, testSetID)
+		if err != nil && !strings.Contains(err.Error(), "no such file or directory") {
+			return false, false, fmt.Errorf("failed to read test set config: %w", err)
+		}
+		if conf != nil && len(conf.Template) > 0 {
+			templatized = append(templatized, testSetID)
+			continue
+		}
+		independent = append(independent, testSetID)
+	}
+
+	r.logger.Info("running the test-sets concurrently", zap.Int("workers", workers), zap.Int("test-sets", len(independent)))
+
+	apps := make(chan uint64, workers)
+	for i := 0; i < workers; i++ {
+		apps <- appIDs[i%len(appIDs)]
+	}
+
+	utils.TemplatizedValues = map[string]interface{}{}
+	r.concurrent = true
+	testRunResult, abortTestRun, err := r.runTestSetBatch(ctx, independent, testRunID, apps, workers)
+	r.concurrent = false
+	if err != nil || abortTestRun || len(templatized) == 0 {
+		return testRunResult, abortTestRun, err
+	}
+
+	r.logger.Info("running the test-sets having templatized values sequentially", zap.Any("test-sets", templatized))
+	result, abortTestRun, err := r.runTestSetBatch(ctx, templatized, testRunID, apps, 1)
+	return testRunResult && result, abortTestRun, err
+}
+
+// runTestSetBatch runs the test-sets using the given number of workers, each test-set taking an app from
+// the ones available for the time it runs.
+func (r *Replayer) runTestSetBatch(ctx context.Context, testSets []string, testRunID string, apps chan uint64, workers int) (bool, bool, error) {
+	var mu sync.Mutex
+	testRunResult := true
+	abortTestRun := false
+
+	// the remaining test-sets are not started once the test run is aborted
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g := errgroup.Group{}
+	g.SetLimit(workers)
+
+	for _, testSet := range testSets {
+		if batchCtx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			defer utils.Recover(r.logger)
+			if batchCtx.Err() != nil {
+				return nil
+			}
+			appID := <-apps
+			defer func() {
+				apps <- appID
+			}()
+
+			err := HookImpl.BeforeTestSetRun(batchCtx, testSet)
+			if err != nil {
+				cancel()
+				return fmt.Errorf("failed to run before test hook: %w", err)
+			}
+
+			testSetStatus, err := r.RunTestSet(batchCtx, testSet, testRunID, appID, false)
+			if err != nil {
+				cancel()
+				return err
+			}
+
+			testSetResult := false
+			switch testSetStatus {
+			case models.TestSetStatusAppHalted, models.TestSetStatusInternalErr, models.TestSetStatusFaultUserApp:
+				mu.Lock()
+				testRunResult = false
+				abortTestRun = true
+				mu.Unlock()
+				cancel()
+				return nil
+			case models.TestSetStatusUserAbort:
+				cancel()
+				return nil
+			case models.TestSetStatusPassed:
+				testSetResult = true
+			}
+
+			if testSetStatus != models.TestSetStatusIgnored {
+				mu.Lock()
+				testRunResult = testRunResult && testSetResult
+				mu.Unlock()
+			}
+
+			err = HookImpl.AfterTestSetRun(batchCtx, testSet, testSetResult)
+			if err != nil {
+				utils.LogError(r.logger, err, "failed to execute after test set run hook", zap.Any("testSet", testSet))
+			}
+			return nil
+		})
+	}
+
+	err := g.Wait()
+	return testRunResult, abortTestRun, err
+}
+
+// instrumentInstances sets up and hooks the given number of further instances of the app, for the test-sets to
+// run concurrently. Each instance runs in a container of its own, so that the eBPF programs attribute its
+// outgoing calls to it.
+func (r *Replayer) instrumentInstances(ctx context.Context, count int) ([]uint64, []context.CancelFunc, error) {
+	var appIDs []uint64
+	var cancels []context.CancelFunc
+	for n := 1; n <= count; n++ {
+		cmd, container := instanceCommand(r.config.Command, r.config.ContainerName, n)
+		appID, cancel, err := r.hookApp(ctx, cmd, container)
+		if err != nil {
+			return appIDs, cancels, fmt.Errorf("failed to instrument the instance %d of the app: %w", n, err)
+		}
+		r.logger.Debug("instrumented an instance of the app", zap.Uint64("appID", appID), zap.String("container", container))
+		appIDs = append(appIDs, appID)
+		cancels = append(cancels, cancel)
+	}
+	return appIDs, cancels, nil
+}
+
+// dockerRunBoolFlags are the flags of docker run taking no value, which are needed to tell the image apart.
+var dockerRunBoolFlags = map[string]bool{
+	"-d": true, "--detach": true, "-i": true, "--interactive": true, "-t": true, "--tty": true, "-it": true, "-ti": true,
+	"--rm": true, "--privileged": true, "--init": true, "-P": true, "--publish-all": true, "--read-only": true,
+}
+
+// instanceCommand returns the docker run command of the n-th instance of the app and the name of its container.
+// The instance gets a container named after the one of the app, and its published ports are dropped since the
+// test cases are sent to the IP of the container.
+func instanceCommand(cmd, container string, n int) (string, string) {
+	name := fmt.Sprintf("%s-%d", container, n)
+	fields := strings.Fields(cmd)
+	out := make([]string, 0, len(fields)+2)
+	named := false
+	i := 0
+	// the flags of docker run come before the image, the ones after it belong to the command of the app
+	for ; i < len(fields); i++ {
+		f := fields[i]
+		if i < 2 {
+			// docker run
+			out = append(out, f)
+			continue
+		}
+		if !strings.HasPrefix(f, "-") {
+			break
+		}
+		flag, _, hasValue := strings.Cut(f, "=")
+		switch flag {
+		case "--name":
+			named = true
+			out = append(out, "--name="+name)
+			if !hasValue {
+				i++
+			}
+			continue
+		case "-p", "--publish":
+			if !hasValue {
+				i++
+			}
+			continue
+		}
+		out = append(out, f)
+		if !hasValue && !dockerRunBoolFlags[flag] && i+1 < len(fields) {
+			i++
+			out = append(out, fields[i])
+		}
+	}
+	if !named {
+		out = append(out, "--name="+name)
+	}
+	out = append(out, fields[i:]...)
+	return strings.Join(out, " "), name
+}
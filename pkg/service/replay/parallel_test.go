@@ -0,0 +1,167 @@
+package replay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+type testStore struct {
+	mu       sync.Mutex
+	cases    map[string][]*models.TestCase
+	confs    map[string]*models.TestSet
+	results  map[string][]models.TestResult
+	requests []string
+}
+
+func (s *testStore) GetAllTestSetIDs(_ context.Context) ([]string, error) {
+	var ids []string
+	for id := range s.cases {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *testStore) GetTestCases(_ context.Context, testSetID string) ([]*models.TestCase, error) {
+	var tcs []*models.TestCase
+	for _, tc := range s.cases[testSetID] {
+		c := *tc
+		tcs = append(tcs, &c)
+	}
+	return tcs, nil
+}
+
+func (s *testStore) UpdateTestCase(context.Context, *models.TestCase, string) error { return nil }
+func (s *testStore) DeleteTests(context.Context, string, []string) error            { return nil }
+func (s *testStore) DeleteTestSet(context.Context, string) error                    { return nil }
+
+func (s *testStore) Read(_ context.Context, testSetID string) (*models.TestSet, error) {
+	return s.confs[testSetID], nil
+}
+
+func (s *testStore) Write(context.Context, string, *models.TestSet) error { return nil }
+
+func (s *testStore) GetAllTestRunIDs(context.Context) ([]string, error) { return nil, nil }
+
+func (s *testStore) GetTestCaseResults(_ context.Context, _ string, testSetID string) ([]models.TestResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.results[testSetID], nil
+}
+
+func (s *testStore) GetReport(context.Context, string, string) (*models.TestReport, error) {
+	return nil, nil
+}
+
+func (s *testStore) InsertTestCaseResult(_ context.Context, _ string, testSetID string, result *models.TestResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[testSetID] = append(s.results[testSetID], *result)
+	return nil
+}
+
+func (s *testStore) InsertReport(context.Context, string, string, *models.TestReport) error {
+	return nil
+}
+
+func (s *testStore) UpdateReport(context.Context, string, any) error { return nil }
+
+func (s *testStore) TestSetRun(int, int, string, string) {}
+func (s *testStore) TestRun(int, int, int, string)       {}
+func (s *testStore) MockTestRun(int)                     {}
+
+// testHooks sends the requests of the test cases as keploy does, rendering the templatized values.
+type testHooks struct {
+	logger *zap.Logger
+}
+
+func (h *testHooks) SimulateRequest(ctx context.Context, _ uint64, tc *models.TestCase, testSetID string) (interface{}, error) {
+	return pkg.SimulateHTTP(ctx, tc, testSetID, h.logger, 5)
+}
+
+func (h *testHooks) BeforeTestSetRun(context.Context, string) error      { return nil }
+func (h *testHooks) AfterTestSetRun(context.Context, string, bool) error { return nil }
+func (h *testHooks) AfterTestRun(context.Context, string, []string, models.TestCoverage) error {
+	return nil
+}
+
+func testCase(name, url, body string) *models.TestCase {
+	now := time.Now()
+	return &models.TestCase{
+		Name:    name,
+		Kind:    models.HTTP,
+		Version: models.GetVersion(),
+		HTTPReq: models.HTTPReq{
+			Method:     models.Method(http.MethodGet),
+			URL:        url,
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Timestamp:  now,
+		},
+		HTTPResp: models.HTTPResp{
+			StatusCode: http.StatusOK,
+			Body:       body,
+			Timestamp:  now,
+		},
+	}
+}
+
+// TestRunTestSetsConcurrently runs independent test-sets alongside each other and a templatized one after
+// them, to be run with -race.
+func TestRunTestSetsConcurrently(t *testing.T) {
+	store := &testStore{
+		cases:   map[string][]*models.TestCase{},
+		confs:   map[string]*models.TestSet{},
+		results: map[string][]models.TestResult{},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store.mu.Lock()
+		store.requests = append(store.requests, r.URL.Path)
+		store.mu.Unlock()
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	testSets := []string{"test-set-0", "test-set-1", "test-set-2", "test-set-3", "test-set-4"}
+	for _, testSetID := range testSets[:4] {
+		store.cases[testSetID] = []*models.TestCase{
+			testCase("test-1", server.URL+"/plain", "/plain"),
+			testCase("test-2", server.URL+"/plain", "/plain"),
+		}
+	}
+	store.cases["test-set-4"] = []*models.TestCase{testCase("test-1", server.URL+"/items/{{.id}}", "/items/7")}
+	store.confs["test-set-4"] = &models.TestSet{Template: map[string]interface{}{"id": "7"}}
+
+	logger := zap.NewNop()
+	prev := HookImpl
+	SetTestHooks(&testHooks{logger: logger})
+	defer SetTestHooks(prev)
+
+	cfg := &config.Config{Path: t.TempDir()}
+	cfg.Test.Parallel = 3
+	r := &Replayer{
+		logger:      logger,
+		testDB:      store,
+		reportDB:    store,
+		testSetConf: store,
+		telemetry:   store,
+		config:      cfg,
+		summary:     newTestRunSummary(),
+	}
+
+	_, aborted, err := r.runTestSetsConcurrently(context.Background(), testSets, "test-run-0", []uint64{0}, 3)
+	if err != nil || aborted {
+		t.Fatalf("failed to run the test-sets (aborted: %t, error: %v)", aborted, err)
+	}
+	if len(store.requests) != 9 || store.requests[8] != "/items/7" {
+		t.Fatalf("got the requests %v, want the templatized one to be sent last", store.requests)
+	}
+}
@@ -20,7 +20,9 @@ import (
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg"
 	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	grpcMatcher "go.keploy.io/server/v2/pkg/matcher/grpc"
 	httpMatcher "go.keploy.io/server/v2/pkg/matcher/http"
+	wsMatcher "go.keploy.io/server/v2/pkg/matcher/websocket"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/platform/coverage"
 	"go.keploy.io/server/v2/pkg/platform/coverage/golang"
@@ -33,12 +35,6 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-var completeTestReport = make(map[string]TestReportVerdict)
-var totalTests int
-var totalTestPassed int
-var totalTestFailed int
-var totalTestIgnored int
-var totalTestTimeTaken time.Duration
 var HookImpl TestHooks
 
 type Replayer struct {
@@ -46,14 +42,19 @@ type Replayer struct {
 	testDB          TestDB
 	mockDB          MockDB
 	reportDB        ReportDB
+	exporters       []ReportExporter
 	testSetConf     TestSetConfig
 	telemetry       Telemetry
 	instrumentation Instrumentation
 	config          *config.Config
 	instrument      bool
+	summary         *testRunSummary
+	// concurrent is set while the test-sets having no template run concurrently, they leave the shared
+	// templatized values alone
+	concurrent bool
 }
 
-func NewReplayer(logger *zap.Logger, testDB TestDB, mockDB MockDB, reportDB ReportDB, testSetConf TestSetConfig, telemetry Telemetry, instrumentation Instrumentation, auth service.Auth, storage Storage, config *config.Config) Service {
+func NewReplayer(logger *zap.Logger, testDB TestDB, mockDB MockDB, reportDB ReportDB, exporters []ReportExporter, testSetConf TestSetConfig, telemetry Telemetry, instrumentation Instrumentation, auth service.Auth, storage Storage, config *config.Config) Service {
 	// set the request emulator for simulating test case requests, if not set
 	if HookImpl == nil {
 		SetTestHooks(NewHooks(logger, config, testSetConf, storage, auth))
@@ -67,11 +68,13 @@ func NewReplayer(logger *zap.Logger, testDB TestDB, mockDB MockDB, reportDB Repo
 		testDB:          testDB,
 		mockDB:          mockDB,
 		reportDB:        reportDB,
+		exporters:       exporters,
 		testSetConf:     testSetConf,
 		telemetry:       telemetry,
 		instrumentation: instrumentation,
 		config:          config,
 		instrument:      instrument,
+		summary:         newTestRunSummary(),
 	}
 }
 
@@ -115,6 +118,9 @@ func (r *Replayer) Start(ctx context.Context) error {
 		return fmt.Errorf(errMsg)
 	}
 
+	// results are aggregated per test run
+	r.summary = newTestRunSummary()
+
 	testRunID, err := r.GetNextTestRunID(ctx)
 	if err != nil {
 		stopReason = fmt.Sprintf("failed to get next test run id: %v", err)
@@ -182,7 +188,14 @@ func (r *Replayer) Start(ctx context.Context) error {
 		return fmt.Errorf(stopReason)
 	}
 
-	hookCancel = inst.HookCancel
+	hookCancels := []context.CancelFunc{inst.HookCancel}
+	hookCancel = func() {
+		for _, cancel := range hookCancels {
+			if cancel != nil {
+				cancel()
+			}
+		}
+	}
 
 	var testSetResult bool
 	testRunResult := true
@@ -200,27 +213,45 @@ func (r *Replayer) Start(ctx context.Context) error {
 
 	// Sort the testsets.
 	natsort.Sort(testSets)
-	for i, testSet := range testSets {
-		testSetResult = false
-		err := HookImpl.BeforeTestSetRun(ctx, testSet)
+
+	if r.config.Test.Denoise {
+		stopReason = "denoise completed successfully"
+		err = r.denoise(ctx, testSets, inst.AppID)
 		if err != nil {
-			stopReason = fmt.Sprintf("failed to run before test hook: %v", err)
-			utils.LogError(r.logger, err, stopReason)
-			if ctx.Err() == context.Canceled {
-				return err
+			if ctx.Err() == context.Canceled || errors.Is(err, context.Canceled) {
+				return nil
 			}
+			stopReason = fmt.Sprintf("failed to denoise the test-sets: %v", err)
+			utils.LogError(r.logger, err, stopReason)
 			return fmt.Errorf(stopReason)
 		}
+		return nil
+	}
 
-		if !r.config.Test.SkipCoverage {
-			err = os.Setenv("TESTSETID", testSet) // related to java coverage calculation
+	parallel := r.config.Test.Parallel
+	appIDs := []uint64{inst.AppID}
+	if parallel > 1 && r.instrument {
+		if utils.CmdType(r.config.CommandType) != utils.DockerRun {
+			// the eBPF programs tell the outgoing calls of the apps apart by their container, the calls of
+			// several native instances of the app would be mixed up
+			r.logger.Warn("running the test-sets sequentially, --parallel needs the app to be started with docker run when keploy starts it")
+			parallel = 1
+		} else {
+			ids, cancels, err := r.instrumentInstances(ctx, parallel-1)
+			hookCancels = append(hookCancels, cancels...)
 			if err != nil {
-				r.config.Test.SkipCoverage = true
-				r.logger.Warn("failed to set TESTSETID env variable, skipping coverage caluclation", zap.Error(err))
+				stopReason = fmt.Sprintf("failed to instrument the instances of the app: %v", err)
+				utils.LogError(r.logger, err, stopReason)
+				if ctx.Err() == context.Canceled {
+					return err
+				}
+				return fmt.Errorf(stopReason)
 			}
+			appIDs = append(appIDs, ids...)
 		}
-
-		testSetStatus, err := r.RunTestSet(ctx, testSet, testRunID, inst.AppID, false)
+	}
+	if parallel > 1 {
+		testRunResult, abortTestRun, err = r.runTestSetsConcurrently(ctx, testSets, testRunID, appIDs, parallel)
 		if err != nil {
 			stopReason = fmt.Sprintf("failed to run test set: %v", err)
 			utils.LogError(r.logger, err, stopReason)
@@ -229,48 +260,82 @@ func (r *Replayer) Start(ctx context.Context) error {
 			}
 			return fmt.Errorf(stopReason)
 		}
-		switch testSetStatus {
-		case models.TestSetStatusAppHalted:
-			testSetResult = false
-			abortTestRun = true
-		case models.TestSetStatusInternalErr:
-			testSetResult = false
-			abortTestRun = true
-		case models.TestSetStatusFaultUserApp:
-			testSetResult = false
-			abortTestRun = true
-		case models.TestSetStatusUserAbort:
+		if ctx.Err() == context.Canceled {
 			return nil
-		case models.TestSetStatusFailed:
-			testSetResult = false
-		case models.TestSetStatusPassed:
-			testSetResult = true
-		case models.TestSetStatusIgnored:
-			testSetResult = false
 		}
-
-		if testSetStatus != models.TestSetStatusIgnored {
-			testRunResult = testRunResult && testSetResult
-			if abortTestRun {
-				break
+	} else {
+		for i, testSet := range testSets {
+			testSetResult = false
+			err := HookImpl.BeforeTestSetRun(ctx, testSet)
+			if err != nil {
+				stopReason = fmt.Sprintf("failed to run before test hook: %v", err)
+				utils.LogError(r.logger, err, stopReason)
+				if ctx.Err() == context.Canceled {
+					return err
+				}
+				return fmt.Errorf(stopReason)
 			}
-		}
 
-		err = HookImpl.AfterTestSetRun(ctx, testSet, testSetResult)
-		if err != nil {
-			utils.LogError(r.logger, err, "failed to execute after test set run hook", zap.Any("testSet", testSet))
-		}
+			if !r.config.Test.SkipCoverage {
+				err = os.Setenv("TESTSETID", testSet) // related to java coverage calculation
+				if err != nil {
+					r.config.Test.SkipCoverage = true
+					r.logger.Warn("failed to set TESTSETID env variable, skipping coverage caluclation", zap.Error(err))
+				}
+			}
 
-		if i == 0 && !r.config.Test.SkipCoverage {
-			err = os.Setenv("CLEAN", "false") // related to javascript coverage calculation
+			testSetStatus, err := r.RunTestSet(ctx, testSet, testRunID, inst.AppID, false)
 			if err != nil {
-				r.config.Test.SkipCoverage = true
-				r.logger.Warn("failed to set CLEAN env variable, skipping coverage caluclation.", zap.Error(err))
+				stopReason = fmt.Sprintf("failed to run test set: %v", err)
+				utils.LogError(r.logger, err, stopReason)
+				if ctx.Err() == context.Canceled {
+					return err
+				}
+				return fmt.Errorf(stopReason)
+			}
+			switch testSetStatus {
+			case models.TestSetStatusAppHalted:
+				testSetResult = false
+				abortTestRun = true
+			case models.TestSetStatusInternalErr:
+				testSetResult = false
+				abortTestRun = true
+			case models.TestSetStatusFaultUserApp:
+				testSetResult = false
+				abortTestRun = true
+			case models.TestSetStatusUserAbort:
+				return nil
+			case models.TestSetStatusFailed:
+				testSetResult = false
+			case models.TestSetStatusPassed:
+				testSetResult = true
+			case models.TestSetStatusIgnored:
+				testSetResult = false
 			}
-			err = os.Setenv("APPEND", "--append") // related to python coverage calculation
+
+			if testSetStatus != models.TestSetStatusIgnored {
+				testRunResult = testRunResult && testSetResult
+				if abortTestRun {
+					break
+				}
+			}
+
+			err = HookImpl.AfterTestSetRun(ctx, testSet, testSetResult)
 			if err != nil {
-				r.config.Test.SkipCoverage = true
-				r.logger.Warn("failed to set APPEND env variable, skipping coverage caluclation.", zap.Error(err))
+				utils.LogError(r.logger, err, "failed to execute after test set run hook", zap.Any("testSet", testSet))
+			}
+
+			if i == 0 && !r.config.Test.SkipCoverage {
+				err = os.Setenv("CLEAN", "false") // related to javascript coverage calculation
+				if err != nil {
+					r.config.Test.SkipCoverage = true
+					r.logger.Warn("failed to set CLEAN env variable, skipping coverage caluclation.", zap.Error(err))
+				}
+				err = os.Setenv("APPEND", "--append") // related to python coverage calculation
+				if err != nil {
+					r.config.Test.SkipCoverage = true
+					r.logger.Warn("failed to set APPEND env variable, skipping coverage caluclation.", zap.Error(err))
+				}
 			}
 		}
 	}
@@ -290,7 +355,7 @@ func (r *Replayer) Start(ctx context.Context) error {
 		r.logger.Warn("To enable storing mocks in cloud, please use --disableMockUpload=false flag or test:disableMockUpload:false in config file")
 	}
 
-	r.telemetry.TestRun(totalTestPassed, totalTestFailed, len(testSets), testRunStatus)
+	r.telemetry.TestRun(r.summary.passed, r.summary.failed, len(testSets), testRunStatus)
 
 	if !abortTestRun {
 		r.printSummary(ctx, testRunResult)
@@ -318,6 +383,8 @@ func (r *Replayer) Start(ctx context.Context) error {
 		}
 	}
 
+	r.exportReports(ctx, testRunID, testSets)
+
 	// return non-zero error code so that pipeline processes
 	// know that there is a failure in tests
 	if !testRunResult {
@@ -331,20 +398,29 @@ func (r *Replayer) Instrument(ctx context.Context) (*InstrumentState, error) {
 		r.logger.Info("Keploy will not mock the outgoing calls when base path is provided", zap.Any("base path", r.config.Test.BasePath))
 		return &InstrumentState{}, nil
 	}
-	appID, err := r.instrumentation.Setup(ctx, r.config.Command, models.SetupOptions{Container: r.config.ContainerName, DockerNetwork: r.config.NetworkName, DockerDelay: r.config.BuildDelay})
+	appID, cancel, err := r.hookApp(ctx, r.config.Command, r.config.ContainerName)
+	if err != nil {
+		return &InstrumentState{}, err
+	}
+	r.config.AppID = appID
+	return &InstrumentState{AppID: appID, HookCancel: cancel}, nil
+}
+
+// hookApp sets up the app run by the command and starts the hooks and the proxy for it.
+func (r *Replayer) hookApp(ctx context.Context, cmd, container string) (uint64, context.CancelFunc, error) {
+	appID, err := r.instrumentation.Setup(ctx, cmd, models.SetupOptions{Container: container, DockerNetwork: r.config.NetworkName, DockerDelay: r.config.BuildDelay})
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			return &InstrumentState{}, err
+			return 0, nil, err
 		}
-		return &InstrumentState{}, fmt.Errorf("failed to setup instrumentation: %w", err)
+		return 0, nil, fmt.Errorf("failed to setup instrumentation: %w", err)
 	}
-	r.config.AppID = appID
 
 	var cancel context.CancelFunc
 	// starting the hooks and proxy
 	select {
 	case <-ctx.Done():
-		return &InstrumentState{}, context.Canceled
+		return 0, nil, context.Canceled
 	default:
 		hookCtx := context.WithoutCancel(ctx)
 		hookCtx, cancel = context.WithCancel(hookCtx)
@@ -352,12 +428,12 @@ func (r *Replayer) Instrument(ctx context.Context) (*InstrumentState, error) {
 		if err != nil {
 			cancel()
 			if errors.Is(err, context.Canceled) {
-				return &InstrumentState{}, err
+				return 0, nil, err
 			}
-			return &InstrumentState{}, fmt.Errorf("failed to start the hooks and proxy: %w", err)
+			return 0, nil, fmt.Errorf("failed to start the hooks and proxy: %w", err)
 		}
 	}
-	return &InstrumentState{AppID: appID, HookCancel: cancel}, nil
+	return appID, cancel, nil
 }
 
 func (r *Replayer) GetNextTestRunID(ctx context.Context) (string, error) {
@@ -431,9 +507,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 			duration: time.Duration(0),
 		}
 
-		completeTestReport[testSetID] = verdict
-		totalTests += testReport.Total
-		totalTestIgnored += testReport.Ignored
+		r.summary.add(testSetID, verdict)
 
 		return models.TestSetStatusIgnored, nil
 	}
@@ -466,6 +540,10 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 	var failure int
 	var ignored int
 	var totalConsumedMocks = map[string]bool{}
+	// number of the mocks recorded on a miss, by kind
+	var autoAddedMocks = map[models.Kind]int{}
+	// the mocks recorded on a miss already appended to the test-set
+	var recordedMocks = map[string]bool{}
 
 	testSetStatus := models.TestSetStatusPassed
 	testSetStatusByErrChan := models.TestSetStatusRunning
@@ -480,6 +558,16 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		return models.TestSetStatusFailed, err
 	}
 
+	// the filtered mocks of the test-set, to find the mocks each test case left unused
+	var testSetMocks []*models.Mock
+	if r.config.Test.StrictMocks && r.instrument {
+		testSetMocks, err = r.mockDB.GetFilteredMocks(runTestSetCtx, testSetID, models.BaseTime, time.Now())
+		if err != nil {
+			utils.LogError(r.logger, err, "failed to get the mocks of the test-set")
+			return models.TestSetStatusFailed, err
+		}
+	}
+
 	if r.instrument {
 		if !serveTest {
 			runTestSetErrGrp.Go(func() error {
@@ -562,7 +650,9 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 	var exitLoop bool
 	// var to store the error in the loop
 	var loopErr error
-	utils.TemplatizedValues = conf.Template
+	if !r.concurrent {
+		utils.TemplatizedValues = conf.Template
+	}
 
 	for _, testCase := range testCases {
 
@@ -572,7 +662,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 
 		if _, ok := ignoredTests[testCase.Name]; ok {
 			testCaseResult := &models.TestResult{
-				Kind:         models.HTTP,
+				Kind:         testCase.Kind,
 				Name:         testSetID,
 				Status:       models.TestStatusIgnored,
 				TestCaseID:   testCase.Name,
@@ -589,7 +679,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		}
 
 		// replace the request URL's BasePath/origin if provided
-		if r.config.Test.BasePath != "" {
+		if r.config.Test.BasePath != "" && testCase.Kind == models.HTTP {
 			newURL, err := ReplaceBaseURL(r.config.Test.BasePath, testCase.HTTPReq.URL)
 			if err != nil {
 				r.logger.Warn("failed to replace the request basePath", zap.String("testcase", testCase.Name), zap.String("basePath", r.config.Test.BasePath), zap.Error(err))
@@ -617,14 +707,17 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		var loopErr error
 
 		//No need to handle mocking when basepath is provided
-		err := r.SetupOrUpdateMocks(runTestSetCtx, appID, testSetID, testCase.HTTPReq.Timestamp, testCase.HTTPResp.Timestamp, Update)
+		reqTime, respTime := testCaseTimestamps(testCase)
+		err := r.SetupOrUpdateMocks(runTestSetCtx, appID, testSetID, reqTime, respTime, Update)
 		if err != nil {
 			utils.LogError(r.logger, err, "failed to update mocks")
 			break
 		}
 
 		if utils.IsDockerCmd(cmdType) {
-			testCase.HTTPReq.URL, err = utils.ReplaceHost(testCase.HTTPReq.URL, userIP)
+			err = replaceTarget(testCase, func(target string) (string, error) {
+				return utils.ReplaceHost(target, userIP)
+			})
 			if err != nil {
 				utils.LogError(r.logger, err, "failed to replace host to docker container's IP")
 				break
@@ -634,7 +727,9 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 
 		// send the flag replace-host instead of sending the IP
 		if r.config.Test.Host != "" {
-			testCase.HTTPReq.URL, err = utils.ReplaceHost(testCase.HTTPReq.URL, r.config.Test.Host)
+			err = replaceTarget(testCase, func(target string) (string, error) {
+				return utils.ReplaceHost(target, r.config.Test.Host)
+			})
 			if err != nil {
 				utils.LogError(r.logger, err, "failed to replace host to provided host by the user")
 				break
@@ -642,11 +737,25 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		}
 
 		if r.config.Test.Port != 0 {
-			testCase.HTTPReq.URL, err = utils.ReplacePort(testCase.HTTPReq.URL, strconv.Itoa(int(r.config.Test.Port)))
+			err = replaceTarget(testCase, func(target string) (string, error) {
+				return utils.ReplacePort(target, strconv.Itoa(int(r.config.Test.Port)))
+			})
+		}
+
+		if r.instrument {
+			// the calls missed at the startup of the app or in between the test cases are not blamed on this test case
+			calls, err := r.instrumentation.GetUnmatchedCalls(runTestSetCtx, appID)
+			if err != nil {
+				utils.LogError(r.logger, err, "failed to get the unmatched calls")
+			}
+			if len(calls) > 0 {
+				r.logger.Debug("the app made calls that no mock matched outside of the test cases", zap.Any("testset id", testSetID), zap.Any("calls", calls))
+			}
 		}
 
-		started := time.Now().UTC()
-		resp, loopErr := HookImpl.SimulateRequest(runTestSetCtx, appID, testCase, testSetID)
+		started := time.Now()
+		trace := &latencyTrace{}
+		resp, loopErr := HookImpl.SimulateRequest(trace.withTrace(runTestSetCtx), appID, testCase, testSetID)
 		if loopErr != nil {
 			utils.LogError(r.logger, err, "failed to simulate request")
 			failure++
@@ -654,6 +763,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		}
 
 		var consumedMocks []string
+		var unmatchedCalls []models.UnmatchedCall
 		if r.instrument {
 			consumedMocks, err = r.instrumentation.GetConsumedMocks(runTestSetCtx, appID)
 			if err != nil {
@@ -664,9 +774,62 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 					totalConsumedMocks[mockName] = true
 				}
 			}
+			// the calls are fetched even out of the strict mode, so that they don't pile up for the next test case
+			unmatchedCalls, err = r.instrumentation.GetUnmatchedCalls(runTestSetCtx, appID)
+			if err != nil {
+				utils.LogError(r.logger, err, "failed to get the unmatched calls")
+			}
+			if r.config.Test.RecordOnMiss {
+				for _, mock := range r.appendRecordedMocks(runTestSetCtx, appID, testSetID, recordedMocks) {
+					// the mocks recorded in this run must not be removed as unused
+					totalConsumedMocks[mock.Name] = true
+					autoAddedMocks[mock.Kind]++
+				}
+			}
+		}
+
+		var httpResp *models.HTTPResp
+		var grpcResp *models.GrpcResp
+		var wsResp *models.WebSocketResp
+		switch testCase.Kind {
+		case models.GRPC_EXPORT:
+			grpcResp, _ = resp.(*models.GrpcResp)
+		case models.WebSocket:
+			wsResp, _ = resp.(*models.WebSocketResp)
+		default:
+			httpResp, _ = resp.(*models.HTTPResp)
+		}
+		if httpResp == nil && grpcResp == nil && wsResp == nil {
+			utils.LogError(r.logger, nil, "invalid response received from the application", zap.Any("testcase", testCase.Name))
+			failure++
+			continue
 		}
 
-		testPass, testResult = r.compareResp(testCase, resp, testSetID)
+		switch {
+		case grpcResp != nil:
+			testPass, testResult = r.compareGrpcResp(testCase, grpcResp, testSetID)
+		case wsResp != nil:
+			testPass, testResult = r.compareWebSocketResp(testCase, wsResp, testSetID)
+		default:
+			testPass, testResult = r.compareResp(testCase, httpResp, testSetID)
+		}
+		if r.config.Test.StrictMocks && r.instrument && testResult != nil {
+			unusedMocks := getUnusedMocks(testSetMocks, reqTime, respTime, consumedMocks)
+			testResult.DepResult = append(testResult.DepResult, depResults(unmatchedCalls, unusedMocks)...)
+			if len(unmatchedCalls) > 0 {
+				testPass = false
+				r.logger.Warn("the test case made calls that no mock matched", zap.Any("testcase id", testCase.Name), zap.Any("testset id", testSetID), zap.Any("calls", unmatchedCalls))
+			}
+		}
+		if threshold := r.config.Test.Latency.For(testSetID); threshold.IsSet() && testResult != nil {
+			if latency, ok := trace.latency(); ok {
+				testResult.LatencyResult = compareLatency(testCase, latency, threshold)
+			}
+			if testResult.LatencyResult != nil && !testResult.LatencyResult.Normal {
+				testPass = false
+				r.logger.Warn("latency regression, the test case responded slower than allowed", zap.Any("testcase id", testCase.Name), zap.Any("testset id", testSetID), zap.Any("recorded(ms)", testResult.LatencyResult.Expected), zap.Any("actual(ms)", testResult.LatencyResult.Actual), zap.Any("allowed(ms)", testResult.LatencyResult.Allowed))
+			}
+		}
 		if !testPass {
 			// log the consumed mocks during the test run of the test case for test set
 			r.logger.Info("result", zap.Any("testcase id", models.HighlightFailingString(testCase.Name)), zap.Any("testset id", models.HighlightFailingString(testSetID)), zap.Any("passed", models.HighlightFailingString(testPass)))
@@ -685,13 +848,26 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 
 		if testResult != nil {
 			testCaseResult := &models.TestResult{
-				Kind:       models.HTTP,
-				Name:       testSetID,
-				Status:     testStatus,
-				Started:    started.Unix(),
-				Completed:  time.Now().UTC().Unix(),
-				TestCaseID: testCase.Name,
-				Req: models.HTTPReq{
+				Kind:         testCase.Kind,
+				Name:         testSetID,
+				Status:       testStatus,
+				Started:      started.Unix(),
+				Completed:    time.Now().UTC().Unix(),
+				TestCaseID:   testCase.Name,
+				TestCasePath: filepath.Join(r.config.Path, testSetID),
+				MockPath:     filepath.Join(r.config.Path, testSetID, "mocks.yaml"),
+				Noise:        testCase.Noise,
+				Result:       *testResult,
+			}
+			if grpcResp != nil {
+				testCaseResult.GrpcReq = testCase.GrpcReq
+				testCaseResult.GrpcRes = *grpcResp
+			} else {
+				if wsResp != nil {
+					httpResp = &wsResp.Handshake
+					testCaseResult.WebSocket = wsResp.Messages
+				}
+				testCaseResult.Req = models.HTTPReq{
 					Method:     testCase.HTTPReq.Method,
 					ProtoMajor: testCase.HTTPReq.ProtoMajor,
 					ProtoMinor: testCase.HTTPReq.ProtoMinor,
@@ -702,12 +878,8 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 					Binary:     testCase.HTTPReq.Binary,
 					Form:       testCase.HTTPReq.Form,
 					Timestamp:  testCase.HTTPReq.Timestamp,
-				},
-				Res:          *resp,
-				TestCasePath: filepath.Join(r.config.Path, testSetID),
-				MockPath:     filepath.Join(r.config.Path, testSetID, "mocks.yaml"),
-				Noise:        testCase.Noise,
-				Result:       *testResult,
+				}
+				testCaseResult.Res = *httpResp
 			}
 			loopErr = r.reportDB.InsertTestCaseResult(runTestSetCtx, testRunID, testSetID, testCaseResult)
 			if loopErr != nil {
@@ -782,6 +954,14 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		utils.LogError(r.logger, err, "failed to create .gitignore file")
 	}
 
+	// the connections recorded on a miss may outlive the test case which opened them
+	if r.config.Test.RecordOnMiss && r.instrument {
+		for _, mock := range r.appendRecordedMocks(reportCtx, appID, testSetID, recordedMocks) {
+			totalConsumedMocks[mock.Name] = true
+			autoAddedMocks[mock.Kind]++
+		}
+	}
+
 	// remove the unused mocks by the test cases of a testset (if the base path is not provided )
 	if r.config.Test.RemoveUnusedMocks && testSetStatus == models.TestSetStatusPassed && r.instrument {
 		r.logger.Debug("consumed mocks from the completed testset", zap.Any("for test-set", testSetID), zap.Any("consumed mocks", totalConsumedMocks))
@@ -792,26 +972,23 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		}
 	}
 
-	// TODO Need to decide on whether to use global variable or not
 	verdict := TestReportVerdict{
-		total:    testReport.Total,
-		failed:   testReport.Failure,
-		passed:   testReport.Success,
-		ignored:  testReport.Ignored,
-		status:   testSetStatus == models.TestSetStatusPassed,
-		duration: timeTaken,
+		total:     testReport.Total,
+		failed:    testReport.Failure,
+		passed:    testReport.Success,
+		ignored:   testReport.Ignored,
+		status:    testSetStatus == models.TestSetStatusPassed,
+		duration:  timeTaken,
+		autoAdded: autoAddedMocks,
 	}
 
-	completeTestReport[testSetID] = verdict
-	totalTests += testReport.Total
-	totalTestPassed += testReport.Success
-	totalTestFailed += testReport.Failure
-	totalTestIgnored += testReport.Ignored
-	totalTestTimeTaken += timeTaken
+	r.summary.add(testSetID, verdict)
 
 	timeTakenStr := timeWithUnits(timeTaken)
 
 	if testSetStatus == models.TestSetStatusFailed || testSetStatus == models.TestSetStatusPassed {
+		// the summaries of the concurrently run test-sets should not be interleaved
+		r.summary.mu.Lock()
 		if testSetStatus == models.TestSetStatusFailed {
 			pp.SetColorScheme(models.GetFailingColorScheme())
 		} else {
@@ -826,6 +1003,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 				utils.LogError(r.logger, err, "failed to print testrun summary")
 			}
 		}
+		r.summary.mu.Unlock()
 	}
 
 	r.telemetry.TestSetRun(testReport.Success, testReport.Failure, testSetID, string(testSetStatus))
@@ -863,6 +1041,24 @@ func (r *Replayer) GetMocks(ctx context.Context, testSetID string, afterTime tim
 	return filtered, unfiltered, err
 }
 
+// getUnusedMocks returns the filtered mocks recorded during the test case which it didn't consume.
+func getUnusedMocks(mocks []*models.Mock, reqTime, respTime time.Time, consumedMocks []string) []*models.Mock {
+	consumed := make(map[string]bool, len(consumedMocks))
+	for _, name := range consumedMocks {
+		consumed[name] = true
+	}
+	var unused []*models.Mock
+	for _, mock := range mocks {
+		if !mock.Spec.ReqTimestampMock.After(reqTime) || !mock.Spec.ResTimestampMock.Before(respTime) {
+			continue
+		}
+		if !consumed[mock.Name] {
+			unused = append(unused, mock)
+		}
+	}
+	return unused
+}
+
 func (r *Replayer) SetupOrUpdateMocks(ctx context.Context, appID uint64, testSetID string, afterTime, beforeTime time.Time, action MockAction) error {
 
 	if !r.instrument {
@@ -876,12 +1072,26 @@ func (r *Replayer) SetupOrUpdateMocks(ctx context.Context, appID uint64, testSet
 	}
 
 	if action == Start {
+		protocols := config.GetProtocolRules(r.config)
+		conf, confErr := r.testSetConf.Read(ctx, testSetID)
+		if confErr == nil && conf != nil {
+			for port, protocol := range conf.Protocols {
+				// the protocols configured by the user take precedence over the recorded ones
+				if _, ok := protocols[port]; !ok {
+					protocols[port] = protocol
+				}
+			}
+		}
+
 		err = r.instrumentation.MockOutgoing(ctx, appID, models.OutgoingOptions{
 			Rules:          r.config.BypassRules,
 			MongoPassword:  r.config.Test.MongoPassword,
 			SQLDelay:       time.Duration(r.config.Test.Delay),
 			FallBackOnMiss: r.config.Test.FallBackOnMiss,
+			RecordOnMiss:   r.config.Test.RecordOnMiss,
 			Mocking:        r.config.Test.Mocking,
+			RedisNoise:     r.config.Test.RedisNoise,
+			Protocols:      protocols,
 		})
 		if err != nil {
 			utils.LogError(r.logger, err, "failed to mock outgoing")
@@ -897,6 +1107,36 @@ func (r *Replayer) SetupOrUpdateMocks(ctx context.Context, appID uint64, testSet
 	return nil
 }
 
+// appendRecordedMocks appends the mocks recorded on a miss since the last call to the mocks of the test-set
+// and returns them. The connections missing the same calls at once record the same mocks, the ones already
+// appended are dropped.
+func (r *Replayer) appendRecordedMocks(ctx context.Context, appID uint64, testSetID string, appended map[string]bool) []*models.Mock {
+	recorded, err := r.instrumentation.GetRecordedMocks(ctx, appID)
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to get the mocks recorded on a miss")
+		return nil
+	}
+	var mocks []*models.Mock
+	for _, mock := range recorded {
+		signature := mockSignature(mock)
+		if appended[signature] {
+			continue
+		}
+		appended[signature] = true
+		mocks = append(mocks, mock)
+	}
+	if len(mocks) == 0 {
+		return nil
+	}
+	err = r.mockDB.AppendMocks(ctx, testSetID, mocks)
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to append the mocks recorded on a miss", zap.String("test-set", testSetID))
+		return nil
+	}
+	r.logger.Info("appended the mocks recorded on a miss to the test-set", zap.String("test-set", testSetID), zap.Int("mocks", len(mocks)))
+	return mocks
+}
+
 func (r *Replayer) GetTestSetStatus(ctx context.Context, testRunID string, testSetID string) (models.TestSetStatus, error) {
 	testReport, err := r.reportDB.GetReport(ctx, testRunID, testSetID)
 	if err != nil {
@@ -918,10 +1158,32 @@ func (r *Replayer) compareResp(tc *models.TestCase, actualResponse *models.HTTPR
 	return httpMatcher.Match(tc, actualResponse, noiseConfig, r.config.Test.IgnoreOrdering, r.logger)
 }
 
+func (r *Replayer) compareGrpcResp(tc *models.TestCase, actualResponse *models.GrpcResp, testSetID string) (bool, *models.Result) {
+
+	noiseConfig := r.config.Test.GlobalNoise.Global
+	if tsNoise, ok := r.config.Test.GlobalNoise.Testsets[testSetID]; ok {
+		noiseConfig = LeftJoinNoise(r.config.Test.GlobalNoise.Global, tsNoise)
+	}
+	return grpcMatcher.Match(tc, actualResponse, noiseConfig, r.logger)
+}
+
+func (r *Replayer) compareWebSocketResp(tc *models.TestCase, actualResponse *models.WebSocketResp, testSetID string) (bool, *models.Result) {
+
+	noiseConfig := r.config.Test.GlobalNoise.Global
+	if tsNoise, ok := r.config.Test.GlobalNoise.Testsets[testSetID]; ok {
+		noiseConfig = LeftJoinNoise(r.config.Test.GlobalNoise.Global, tsNoise)
+	}
+	return wsMatcher.Match(tc, actualResponse, noiseConfig, r.config.Test.IgnoreOrdering, r.logger)
+}
+
 func (r *Replayer) printSummary(_ context.Context, _ bool) {
-	if totalTests > 0 {
-		testSuiteNames := make([]string, 0, len(completeTestReport))
-		for testSuiteName := range completeTestReport {
+	summary := r.summary
+	summary.mu.Lock()
+	defer summary.mu.Unlock()
+
+	if summary.total > 0 {
+		testSuiteNames := make([]string, 0, len(summary.testSets))
+		for testSuiteName := range summary.testSets {
 			testSuiteNames = append(testSuiteNames, testSuiteName)
 		}
 		sort.SliceStable(testSuiteNames, func(i, j int) bool {
@@ -938,10 +1200,10 @@ func (r *Replayer) printSummary(_ context.Context, _ bool) {
 			return testSuiteIDNumberI < testSuiteIDNumberJ
 		})
 
-		totalTestTimeTakenStr := timeWithUnits(totalTestTimeTaken)
+		totalTestTimeTakenStr := timeWithUnits(summary.timeTaken)
 
-		if totalTestIgnored > 0 {
-			if _, err := pp.Printf("\n <=========================================> \n  COMPLETE TESTRUN SUMMARY. \n\tTotal tests: %s\n"+"\tTotal test passed: %s\n"+"\tTotal test failed: %s\n"+"\tTotal test ignored: %s\n"+"\tTotal time taken: %s\n", totalTests, totalTestPassed, totalTestFailed, totalTestIgnored, totalTestTimeTakenStr); err != nil {
+		if summary.ignored > 0 {
+			if _, err := pp.Printf("\n <=========================================> \n  COMPLETE TESTRUN SUMMARY. \n\tTotal tests: %s\n"+"\tTotal test passed: %s\n"+"\tTotal test failed: %s\n"+"\tTotal test ignored: %s\n"+"\tTotal time taken: %s\n", summary.total, summary.passed, summary.failed, summary.ignored, totalTestTimeTakenStr); err != nil {
 				utils.LogError(r.logger, err, "failed to print test run summary")
 				return
 			}
@@ -950,7 +1212,7 @@ func (r *Replayer) printSummary(_ context.Context, _ bool) {
 				return
 			}
 		} else {
-			if _, err := pp.Printf("\n <=========================================> \n  COMPLETE TESTRUN SUMMARY. \n\tTotal tests: %s\n"+"\tTotal test passed: %s\n"+"\tTotal test failed: %s\n"+"\tTotal time taken: %s\n", totalTests, totalTestPassed, totalTestFailed, totalTestTimeTakenStr); err != nil {
+			if _, err := pp.Printf("\n <=========================================> \n  COMPLETE TESTRUN SUMMARY. \n\tTotal tests: %s\n"+"\tTotal test passed: %s\n"+"\tTotal test failed: %s\n"+"\tTotal time taken: %s\n", summary.total, summary.passed, summary.failed, totalTestTimeTakenStr); err != nil {
 				utils.LogError(r.logger, err, "failed to print test run summary")
 				return
 			}
@@ -960,26 +1222,38 @@ func (r *Replayer) printSummary(_ context.Context, _ bool) {
 			}
 		}
 		for _, testSuiteName := range testSuiteNames {
-			if completeTestReport[testSuiteName].status {
+			if summary.testSets[testSuiteName].status {
 				pp.SetColorScheme(models.GetPassingColorScheme())
 			} else {
 				pp.SetColorScheme(models.GetFailingColorScheme())
 			}
 
-			testSetTimeTakenStr := timeWithUnits(completeTestReport[testSuiteName].duration)
+			testSetTimeTakenStr := timeWithUnits(summary.testSets[testSuiteName].duration)
 
-			if totalTestIgnored > 0 {
-				if _, err := pp.Printf("\n\t%s\t\t%s\t\t%s\t\t%s\t\t%s\t\t%s", testSuiteName, completeTestReport[testSuiteName].total, completeTestReport[testSuiteName].passed, completeTestReport[testSuiteName].failed, completeTestReport[testSuiteName].ignored, testSetTimeTakenStr); err != nil {
+			if summary.ignored > 0 {
+				if _, err := pp.Printf("\n\t%s\t\t%s\t\t%s\t\t%s\t\t%s\t\t%s", testSuiteName, summary.testSets[testSuiteName].total, summary.testSets[testSuiteName].passed, summary.testSets[testSuiteName].failed, summary.testSets[testSuiteName].ignored, testSetTimeTakenStr); err != nil {
 					utils.LogError(r.logger, err, "failed to print test suite details")
 					return
 				}
 			} else {
-				if _, err := pp.Printf("\n\t%s\t\t%s\t\t%s\t\t%s\t\t%s", testSuiteName, completeTestReport[testSuiteName].total, completeTestReport[testSuiteName].passed, completeTestReport[testSuiteName].failed, testSetTimeTakenStr); err != nil {
+				if _, err := pp.Printf("\n\t%s\t\t%s\t\t%s\t\t%s\t\t%s", testSuiteName, summary.testSets[testSuiteName].total, summary.testSets[testSuiteName].passed, summary.testSets[testSuiteName].failed, testSetTimeTakenStr); err != nil {
 					utils.LogError(r.logger, err, "failed to print test suite details")
 					return
 				}
 			}
 		}
+		var autoAdded []string
+		for _, testSuiteName := range testSuiteNames {
+			if mocks := summary.testSets[testSuiteName].autoAdded; len(mocks) > 0 {
+				autoAdded = append(autoAdded, fmt.Sprintf("\t%s\t\t%s", testSuiteName, countByKind(mocks)))
+			}
+		}
+		if len(autoAdded) > 0 {
+			if _, err := pp.Printf("\n\n\tNew mocks recorded on miss\n%s", strings.Join(autoAdded, "\n")); err != nil {
+				utils.LogError(r.logger, err, "failed to print the new mocks")
+				return
+			}
+		}
 		if _, err := pp.Printf("\n<=========================================> \n\n"); err != nil {
 			utils.LogError(r.logger, err, "failed to print separator")
 			return
@@ -987,6 +1261,31 @@ func (r *Replayer) printSummary(_ context.Context, _ bool) {
 	}
 }
 
+// exportReports exports the reports of the test-sets run in the test run using the configured exporters
+func (r *Replayer) exportReports(ctx context.Context, testRunID string, testSets []string) {
+	if len(r.exporters) == 0 {
+		return
+	}
+	var reports []*models.TestReport
+	for _, testSetID := range testSets {
+		report, err := r.reportDB.GetReport(ctx, testRunID, testSetID)
+		if err != nil {
+			r.logger.Debug("no report found for the test-set", zap.String("test-set", testSetID), zap.Error(err))
+			continue
+		}
+		if report.TestSet == "" {
+			report.TestSet = testSetID
+		}
+		reports = append(reports, report)
+	}
+	for _, exporter := range r.exporters {
+		err := exporter.Export(ctx, testRunID, reports)
+		if err != nil {
+			utils.LogError(r.logger, err, "failed to export the test run report")
+		}
+	}
+}
+
 func (r *Replayer) RunApplication(ctx context.Context, appID uint64, opts models.RunOptions) models.AppError {
 	return r.instrumentation.Run(ctx, appID, opts)
 }
@@ -1102,7 +1401,15 @@ func (r *Replayer) NormalizeTestCases(ctx context.Context, testRun string, testS
 		if testCaseResultMap[testCase.Name].Status == models.TestStatusPassed {
 			continue
 		}
-		testCase.HTTPResp = testCaseResultMap[testCase.Name].Res
+		switch testCase.Kind {
+		case models.GRPC_EXPORT:
+			testCase.GrpcResp = testCaseResultMap[testCase.Name].GrpcRes
+		case models.WebSocket:
+			testCase.HTTPResp = testCaseResultMap[testCase.Name].Res
+			testCase.WebSocket = testCaseResultMap[testCase.Name].WebSocket
+		default:
+			testCase.HTTPResp = testCaseResultMap[testCase.Name].Res
+		}
 		err = r.testDB.UpdateTestCase(ctx, testCase, testSetID)
 		if err != nil {
 			return fmt.Errorf("failed to update test case: %w", err)
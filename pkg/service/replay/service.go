@@ -18,6 +18,10 @@ type Instrumentation interface {
 	SetMocks(ctx context.Context, id uint64, filtered []*models.Mock, unFiltered []*models.Mock) error
 	// GetConsumedMocks to log the names of the mocks that were consumed during the test run of failed test cases
 	GetConsumedMocks(ctx context.Context, id uint64) ([]string, error)
+	// GetUnmatchedCalls returns the outgoing calls that no mock matched during the test run of the last test case
+	GetUnmatchedCalls(ctx context.Context, id uint64) ([]models.UnmatchedCall, error)
+	// GetRecordedMocks returns the mocks recorded on a miss during the test run of the last test case
+	GetRecordedMocks(ctx context.Context, id uint64) ([]*models.Mock, error)
 	// Run is blocking call and will execute until error
 	Run(ctx context.Context, id uint64, opts models.RunOptions) models.AppError
 
@@ -54,6 +58,7 @@ type MockDB interface {
 	GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
 	GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
 	UpdateMocks(ctx context.Context, testSetID string, mockNames map[string]bool) error
+	AppendMocks(ctx context.Context, testSetID string, mocks []*models.Mock) error
 }
 
 type ReportDB interface {
@@ -65,6 +70,11 @@ type ReportDB interface {
 	UpdateReport(ctx context.Context, testRunID string, testCoverage any) error
 }
 
+// ReportExporter exports the reports of a test run in a format understood by other tools, e.g. JUnit XML for the CI systems
+type ReportExporter interface {
+	Export(ctx context.Context, testRunID string, reports []*models.TestReport) error
+}
+
 type TestSetConfig interface {
 	Read(ctx context.Context, testSetID string) (*models.TestSet, error)
 	Write(ctx context.Context, testSetID string, testSet *models.TestSet) error
@@ -77,7 +87,9 @@ type Telemetry interface {
 }
 
 type TestHooks interface {
-	SimulateRequest(ctx context.Context, appID uint64, tc *models.TestCase, testSetID string) (*models.HTTPResp, error)
+	// SimulateRequest sends the request of the test case to the application and returns the actual response,
+	// *models.HTTPResp for http test cases and *models.GrpcResp for grpc test cases.
+	SimulateRequest(ctx context.Context, appID uint64, tc *models.TestCase, testSetID string) (interface{}, error)
 	BeforeTestSetRun(ctx context.Context, testSetID string) error
 	AfterTestSetRun(ctx context.Context, testSetID string, status bool) error
 	AfterTestRun(ctx context.Context, testRunID string, testSetIDs []string, coverage models.TestCoverage) error // hook executed after running all the test-sets
@@ -1,12 +1,20 @@
 package replay
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http/httptrace"
 	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	// "encoding/json"
 	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
 )
 
 type TestReportVerdict struct {
@@ -16,6 +24,36 @@ type TestReportVerdict struct {
 	ignored  int
 	status   bool
 	duration time.Duration
+	// mocks recorded on a miss, by kind
+	autoAdded map[models.Kind]int
+}
+
+// testRunSummary aggregates the verdicts of the test-sets of a test run, which can be run concurrently.
+type testRunSummary struct {
+	mu        sync.Mutex
+	testSets  map[string]TestReportVerdict
+	total     int
+	passed    int
+	failed    int
+	ignored   int
+	timeTaken time.Duration
+}
+
+func newTestRunSummary() *testRunSummary {
+	return &testRunSummary{
+		testSets: make(map[string]TestReportVerdict),
+	}
+}
+
+func (s *testRunSummary) add(testSetID string, verdict TestReportVerdict) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.testSets[testSetID] = verdict
+	s.total += verdict.total
+	s.passed += verdict.passed
+	s.failed += verdict.failed
+	s.ignored += verdict.ignored
+	s.timeTaken += verdict.duration
 }
 
 func LeftJoinNoise(globalNoise config.GlobalNoise, tsNoise config.GlobalNoise) config.GlobalNoise {
@@ -102,3 +140,166 @@ func timeWithUnits(duration time.Duration) string {
 	}
 	return fmt.Sprintf("%.2f hr", duration.Hours())
 }
+
+// testCaseTimestamps returns the time window in which the test case was recorded.
+func testCaseTimestamps(tc *models.TestCase) (time.Time, time.Time) {
+	if tc.Kind == models.GRPC_EXPORT {
+		return tc.GrpcReq.Timestamp, tc.GrpcResp.Timestamp
+	}
+	return tc.HTTPReq.Timestamp, tc.HTTPResp.Timestamp
+}
+
+// replaceTarget applies the replace func on the URL of the http test case, or on the
+// :authority pseudo header of the grpc test case.
+func replaceTarget(tc *models.TestCase, replace func(target string) (string, error)) error {
+	if tc.Kind != models.GRPC_EXPORT {
+		newURL, err := replace(tc.HTTPReq.URL)
+		if err != nil {
+			return err
+		}
+		tc.HTTPReq.URL = newURL
+		return nil
+	}
+	target, err := replace("http://" + tc.GrpcReq.Headers.PseudoHeaders[pkg.GrpcAuthority])
+	if err != nil {
+		return err
+	}
+	tc.GrpcReq.Headers.PseudoHeaders[pkg.GrpcAuthority] = strings.TrimPrefix(target, "http://")
+	return nil
+}
+
+// depResults returns the results of the dependencies in the strict mock mode, the calls that no mock
+// matched and the mocks recorded for the test case that weren't consumed.
+func depResults(unmatchedCalls []models.UnmatchedCall, unusedMocks []*models.Mock) []models.DepResult {
+	var results []models.DepResult
+	for _, call := range unmatchedCalls {
+		results = append(results, models.DepResult{
+			Name: strings.TrimSpace(string(call.Kind) + " " + call.Destination),
+			Type: "unmatched",
+			Meta: []models.DepMetaResult{{
+				Key:      "request",
+				Expected: "a recorded mock",
+				Actual:   call.Summary,
+			}},
+		})
+	}
+	for _, mock := range unusedMocks {
+		results = append(results, models.DepResult{
+			Name: mock.Name,
+			Type: "unused",
+			Meta: []models.DepMetaResult{{
+				Key:      string(mock.Kind),
+				Expected: "consumed",
+				Actual:   "not consumed",
+			}},
+		})
+	}
+	return results
+}
+
+// latencyTrace measures the latency of a simulated request the same way it is recorded, from the request
+// being sent to the first byte of the response.
+type latencyTrace struct {
+	mu        sync.Mutex
+	sent      time.Time
+	firstByte time.Time
+}
+
+func (l *latencyTrace) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteHeaders: func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.sent = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.firstByte = time.Now()
+		},
+	})
+}
+
+// latency returns false if the request was not traced up to the first byte of the response.
+func (l *latencyTrace) latency() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sent.IsZero() || l.firstByte.Before(l.sent) {
+		return 0, false
+	}
+	return l.firstByte.Sub(l.sent), true
+}
+
+// compareLatency checks the response time of the test run against the latency recorded for the test case. It
+// returns nil for the test cases without a recorded latency and for the websocket test cases, whose response
+// timestamp is the time of their last message.
+func compareLatency(tc *models.TestCase, actual time.Duration, threshold config.LatencyThreshold) *models.LatencyResult {
+	if tc.Kind == models.WebSocket {
+		return nil
+	}
+	reqTime, respTime := testCaseTimestamps(tc)
+	if reqTime.IsZero() || !respTime.After(reqTime) {
+		return nil
+	}
+	recorded := respTime.Sub(reqTime)
+	absolute := recorded + time.Duration(threshold.Absolute)*time.Millisecond
+	percentage := recorded + time.Duration(float64(recorded)*threshold.Percentage/100)
+
+	var allowed time.Duration
+	switch {
+	case threshold.Absolute > 0 && threshold.Percentage > 0:
+		allowed = max(absolute, percentage)
+	case threshold.Absolute > 0:
+		allowed = absolute
+	default:
+		allowed = percentage
+	}
+	return &models.LatencyResult{
+		Normal:   actual <= allowed,
+		Expected: recorded.Milliseconds(),
+		Actual:   actual.Milliseconds(),
+		Allowed:  allowed.Milliseconds(),
+	}
+}
+
+// countByKind formats the numbers of mocks by kind, e.g. "Http: 2, Redis: 1"
+func countByKind(counts map[models.Kind]int) string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s: %d", kind, counts[models.Kind(kind)]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mockSignature identifies the calls of a mock regardless of when and on which connection they were made.
+func mockSignature(mock *models.Mock) string {
+	m := *mock
+	m.Name = ""
+	m.ConnectionID = ""
+	m.TestModeInfo = models.TestModeInfo{}
+	m.Spec.Metadata = nil
+	m.Spec.Created = 0
+	m.Spec.ReqTimestampMock = time.Time{}
+	m.Spec.ResTimestampMock = time.Time{}
+	if m.Spec.HTTPReq != nil {
+		req := *m.Spec.HTTPReq
+		req.Timestamp = time.Time{}
+		m.Spec.HTTPReq = &req
+	}
+	if m.Spec.HTTPResp != nil {
+		resp := *m.Spec.HTTPResp
+		resp.Timestamp = time.Time{}
+		m.Spec.HTTPResp = &resp
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		// the mock can't be compared, it is kept
+		return fmt.Sprintf("%p", mock)
+	}
+	return string(data)
+}
@@ -1,10 +1,13 @@
 package utgen
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/xml"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,8 +22,12 @@ type CoverageProcessor struct {
 	Format     string
 }
 
-// NewCoverageProcessor initializes a CoverageProcessor object
+// NewCoverageProcessor initializes a CoverageProcessor object. The go coverprofile names the files by their
+// import path, the source file is looked up by its path, the other reports by its name.
 func NewCoverageProcessor(reportPath, srcpath, format string) *CoverageProcessor {
+	if format != "go" {
+		srcpath = getFilename(srcpath)
+	}
 	return &CoverageProcessor{
 		ReportPath: reportPath,
 		SrcPath:    srcpath,
@@ -71,7 +78,9 @@ func (cp *CoverageProcessor) ParseCoverageReport() (*models.CoverageResult, erro
 	case "jacoco":
 		return cp.ParseCoverageReportJacoco()
 	case "lcov":
-		return nil, fmt.Errorf("parsing for %s coverage reports is not implemented yet", cp.Format)
+		return cp.ParseCoverageReportLcov()
+	case "go":
+		return cp.ParseCoverageReportGo()
 	default:
 		return nil, fmt.Errorf("unsupported coverage report type: %s", cp.Format)
 	}
@@ -247,3 +256,246 @@ func (cp *CoverageProcessor) ParseCoverageReportJacoco() (*models.CoverageResult
 
 	return coverageResult, nil
 }
+
+// ParseCoverageReportLcov parses the lcov tracefile, which is made of one record per source file:
+// SF:<path>, DA:<line>,<hits> for every instrumented line and end_of_record.
+func (cp *CoverageProcessor) ParseCoverageReportLcov() (*models.CoverageResult, error) {
+
+	filesToCover := make([]string, 0)
+	lcovFile, err := os.Open(cp.ReportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := lcovFile.Close(); err != nil {
+			return
+		}
+	}()
+
+	var linesCovered, linesMissed []int
+	var filteredRecord strings.Builder
+	var record []string
+	var found, inTarget bool
+
+	scanner := bufio.NewScanner(lcovFile)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		record = append(record, line)
+
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			fileName := strings.TrimPrefix(line, "SF:")
+			if cp.SrcPath == "." {
+				filesToCover = append(filesToCover, fileName)
+			}
+			// only the first record of the source file is considered
+			inTarget = !found && strings.HasSuffix(fileName, cp.SrcPath)
+			found = found || inTarget
+		case strings.HasPrefix(line, "DA:") && inTarget:
+			// DA:<line number>,<execution count>[,<checksum>]
+			parts := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(parts) < 2 {
+				continue
+			}
+			lineNumber, errLine := strconv.Atoi(parts[0])
+			hits, errHits := strconv.ParseFloat(parts[1], 64)
+			if errLine != nil || errHits != nil {
+				continue
+			}
+			if hits > 0 {
+				linesCovered = append(linesCovered, lineNumber)
+			} else {
+				linesMissed = append(linesMissed, lineNumber)
+			}
+		case line == "end_of_record":
+			if inTarget {
+				filteredRecord.WriteString(strings.Join(record, "\n") + "\n")
+			}
+			inTarget = false
+			record = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var coveragePercentage float64
+	if totalLines := len(linesCovered) + len(linesMissed); totalLines > 0 {
+		coveragePercentage = float64(len(linesCovered)) / float64(totalLines)
+	}
+
+	coverageResult := &models.CoverageResult{
+		LinesCovered:  linesCovered,
+		LinesMissed:   linesMissed,
+		Coverage:      coveragePercentage,
+		Files:         filesToCover,
+		ReportContent: filteredRecord.String(),
+	}
+
+	return coverageResult, nil
+}
+
+// ParseCoverageReportGo parses the coverprofile generated by `go test -coverprofile`. Every line after the
+// mode line is a block: <file>:<startLine>.<startCol>,<endLine>.<endCol> <numStatements> <count>.
+// A line is covered when any of the blocks spanning it has been executed.
+func (cp *CoverageProcessor) ParseCoverageReportGo() (*models.CoverageResult, error) {
+
+	filesToCover := make([]string, 0)
+	profile, err := os.Open(cp.ReportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := profile.Close(); err != nil {
+			return
+		}
+	}()
+
+	// the files are named by their import path, they are mapped to their path using the module of the source file
+	allFiles := cp.SrcPath == "" || cp.SrcPath == "."
+	modPath, modRoot, err := goModule(filepath.Dir(cp.SrcPath))
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	target, err := filepath.Abs(cp.SrcPath)
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(wd, target); err == nil {
+		target = rel
+	}
+
+	var targetFile string
+	var filteredBlocks strings.Builder
+	seenFiles := make(map[string]bool)
+	lineHits := make(map[int]bool)
+
+	scanner := bufio.NewScanner(profile)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "mode:") {
+			filteredBlocks.WriteString(line + "\n")
+			continue
+		}
+
+		idx := strings.LastIndex(line, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid block in the go coverage profile: %s", line)
+		}
+		fileName := line[:idx]
+		if !seenFiles[fileName] {
+			seenFiles[fileName] = true
+			srcPath, ok := goSourcePath(fileName, modPath, modRoot, wd)
+			if ok && allFiles {
+				filesToCover = append(filesToCover, srcPath)
+			} else if ok && targetFile == "" && srcPath == target {
+				targetFile = fileName
+			}
+		}
+		if fileName != targetFile {
+			continue
+		}
+
+		var startLine, startCol, endLine, endCol, numStmts, count int
+		_, err := fmt.Sscanf(line[idx+1:], "%d.%d,%d.%d %d %d", &startLine, &startCol, &endLine, &endCol, &numStmts, &count)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block in the go coverage profile: %s", line)
+		}
+		filteredBlocks.WriteString(line + "\n")
+		for l := startLine; l <= endLine; l++ {
+			lineHits[l] = lineHits[l] || count > 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var linesCovered, linesMissed []int
+	for l, hit := range lineHits {
+		if hit {
+			linesCovered = append(linesCovered, l)
+		} else {
+			linesMissed = append(linesMissed, l)
+		}
+	}
+	sort.Ints(linesCovered)
+	sort.Ints(linesMissed)
+
+	var coveragePercentage float64
+	if totalLines := len(lineHits); totalLines > 0 {
+		coveragePercentage = float64(len(linesCovered)) / float64(totalLines)
+	}
+
+	coverageResult := &models.CoverageResult{
+		LinesCovered:  linesCovered,
+		LinesMissed:   linesMissed,
+		Coverage:      coveragePercentage,
+		Files:         filesToCover,
+		ReportContent: filteredBlocks.String(),
+	}
+
+	return coverageResult, nil
+}
+
+// goModule returns the module path and the root directory of the go module containing the given directory.
+func goModule(dir string) (string, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) >= 2 && fields[0] == "module" {
+					return strings.Trim(fields[1], "\"`"), dir, nil
+				}
+			}
+			return "", "", fmt.Errorf("no module path in %s", filepath.Join(dir, "go.mod"))
+		}
+		if !os.IsNotExist(err) {
+			return "", "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("no go.mod found for the coverage profile")
+		}
+		dir = parent
+	}
+}
+
+// goSourcePath returns the path of a file of the coverprofile relative to the working directory. The files of
+// the module are named by their import path, the ones built out of a module by their absolute path, possibly
+// prefixed by "_". It returns false for the files of the other modules.
+func goSourcePath(fileName, modPath, modRoot, wd string) (string, bool) {
+	var path string
+	switch {
+	case strings.HasPrefix(fileName, modPath+"/"):
+		path = filepath.Join(modRoot, filepath.FromSlash(strings.TrimPrefix(fileName, modPath+"/")))
+	case filepath.IsAbs(fileName):
+		path = fileName
+	case strings.HasPrefix(fileName, "_/"):
+		path = filepath.FromSlash(fileName[1:])
+	default:
+		return "", false
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil {
+		return path, true
+	}
+	return rel, true
+}
@@ -352,7 +352,7 @@ func (g *UnitTestGenerator) runCoverage() error {
 	if exitCode != 0 {
 		utils.LogError(g.logger, err, "Error running test command")
 	}
-	coverageProcessor := NewCoverageProcessor(g.cov.Path, getFilename(g.srcPath), g.cov.Format)
+	coverageProcessor := NewCoverageProcessor(g.cov.Path, g.srcPath, g.cov.Format)
 	coverageResult, err := coverageProcessor.ProcessCoverageReport(lastUpdatedTime)
 	if err != nil {
 		utils.LogError(g.logger, err, "Error in coverage processing")
@@ -556,7 +556,7 @@ func (g *UnitTestGenerator) ValidateTest(generatedTest models.UT, passedTests, n
 	}
 
 	// Check for coverage increase
-	newCoverageProcessor := NewCoverageProcessor(g.cov.Path, getFilename(g.srcPath), g.cov.Format)
+	newCoverageProcessor := NewCoverageProcessor(g.cov.Path, g.srcPath, g.cov.Format)
 	covResult, err := newCoverageProcessor.ProcessCoverageReport(testCommandStartTime)
 	if err != nil {
 		return fmt.Errorf("error processing coverage report: %w", err)
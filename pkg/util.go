@@ -87,37 +87,9 @@ func IsTime(stringDate string) bool {
 func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64) (*models.HTTPResp, error) {
 	var resp *models.HTTPResp
 
-	//TODO: adjust this logic in the render function in order to remove the redundant code
-	// convert testcase to string and render the template values.
-	if len(utils.TemplatizedValues) > 0 {
-		testCaseStr, err := json.Marshal(tc)
-		if err != nil {
-			utils.LogError(logger, err, "failed to marshal the testcase")
-			return nil, err
-		}
-		funcMap := template.FuncMap{
-			"int":    utils.ToInt,
-			"string": utils.ToString,
-			"float":  utils.ToFloat,
-		}
-		tmpl, err := template.New("template").Funcs(funcMap).Parse(string(testCaseStr))
-		if err != nil || tmpl == nil {
-			utils.LogError(logger, err, "failed to parse the template", zap.Any("TestCaseString", string(testCaseStr)), zap.Any("TestCase", tc.Name), zap.Any("TestSet", testSet))
-			return nil, err
-		}
-
-		var output bytes.Buffer
-		err = tmpl.Execute(&output, utils.TemplatizedValues)
-		if err != nil {
-			utils.LogError(logger, err, "failed to execute the template")
-			return nil, err
-		}
-		testCaseStr = output.Bytes()
-		err = json.Unmarshal([]byte(testCaseStr), &tc)
-		if err != nil {
-			utils.LogError(logger, err, "failed to unmarshal the testcase")
-			return nil, err
-		}
+	err := renderTestCaseTemplate(tc, testSet, logger)
+	if err != nil {
+		return nil, err
 	}
 
 	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
@@ -206,6 +178,43 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 	return resp, errHTTPReq
 }
 
+// renderTestCaseTemplate renders the templatized values of the test set into the test case.
+func renderTestCaseTemplate(tc *models.TestCase, testSet string, logger *zap.Logger) error {
+	//TODO: adjust this logic in the render function in order to remove the redundant code
+	// convert testcase to string and render the template values.
+	if len(utils.TemplatizedValues) > 0 {
+		testCaseStr, err := json.Marshal(tc)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the testcase")
+			return err
+		}
+		funcMap := template.FuncMap{
+			"int":    utils.ToInt,
+			"string": utils.ToString,
+			"float":  utils.ToFloat,
+		}
+		tmpl, err := template.New("template").Funcs(funcMap).Parse(string(testCaseStr))
+		if err != nil || tmpl == nil {
+			utils.LogError(logger, err, "failed to parse the template", zap.Any("TestCaseString", string(testCaseStr)), zap.Any("TestCase", tc.Name), zap.Any("TestSet", testSet))
+			return err
+		}
+
+		var output bytes.Buffer
+		err = tmpl.Execute(&output, utils.TemplatizedValues)
+		if err != nil {
+			utils.LogError(logger, err, "failed to execute the template")
+			return err
+		}
+		testCaseStr = output.Bytes()
+		err = json.Unmarshal([]byte(testCaseStr), &tc)
+		if err != nil {
+			utils.LogError(logger, err, "failed to unmarshal the testcase")
+			return err
+		}
+	}
+	return nil
+}
+
 func ParseHTTPRequest(requestBytes []byte) (*http.Request, error) {
 	// Parse the request using the http.ReadRequest function
 	request, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(requestBytes)))
@@ -0,0 +1,424 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// opcodes of the websocket frames (RFC 6455)
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// the GUID the Sec-WebSocket-Accept header is derived with
+const wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketPayload bounds the size of a frame, and of a fragmented message once joined, the larger ones
+// are taken as a corrupted stream.
+const maxWebSocketPayload = 64 << 20
+
+// maxControlPayload is the maximum size of the payload of the close, ping and pong frames.
+const maxControlPayload = 125
+
+// WebSocketFrame is a frame of a websocket connection, with its payload unmasked.
+type WebSocketFrame struct {
+	Fin        bool
+	Compressed bool // RSV1, set on the first frame of the messages compressed by permessage-deflate
+	Opcode     byte
+	Payload    []byte
+}
+
+// IsWebSocketUpgrade checks whether the headers ask for, or accept, the upgrade to a websocket.
+func IsWebSocketUpgrade(header http.Header) bool {
+	return strings.EqualFold(header.Get("Upgrade"), "websocket") && headerHasToken(header, "Connection", "upgrade")
+}
+
+// WebSocketAccept returns the Sec-WebSocket-Accept the server answers the Sec-WebSocket-Key of the client with.
+func WebSocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadWebSocketFrame reads a frame, unmasking the payload of the frames sent by the clients.
+func ReadWebSocketFrame(r io.Reader) (*WebSocketFrame, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	frame := &WebSocketFrame{
+		Fin:        header[0]&0x80 != 0,
+		Compressed: header[0]&0x40 != 0,
+		Opcode:     header[0] & 0x0f,
+	}
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	if length > maxWebSocketPayload {
+		return nil, fmt.Errorf("websocket frame of %d bytes exceeds the maximum size", length)
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+	frame.Payload = make([]byte, length)
+	if _, err := io.ReadFull(r, frame.Payload); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if masked {
+		for i := range frame.Payload {
+			frame.Payload[i] ^= mask[i%4]
+		}
+	}
+	return frame, nil
+}
+
+// unexpectedEOF reports the connection closed in the middle of a frame.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// EncodeWebSocketFrame encodes the frame, masking its payload when sent by a client.
+func EncodeWebSocketFrame(frame *WebSocketFrame, mask bool) []byte {
+	b0 := frame.Opcode
+	if frame.Fin {
+		b0 |= 0x80
+	}
+	if frame.Compressed {
+		b0 |= 0x40
+	}
+	buf := []byte{b0, 0}
+	if mask {
+		buf[1] = 0x80
+	}
+	length := len(frame.Payload)
+	switch {
+	case length < 126:
+		buf[1] |= byte(length)
+	case length <= 0xffff:
+		buf[1] |= 126
+		buf = binary.BigEndian.AppendUint16(buf, uint16(length))
+	default:
+		buf[1] |= 127
+		buf = binary.BigEndian.AppendUint64(buf, uint64(length))
+	}
+	if !mask {
+		return append(buf, frame.Payload...)
+	}
+	var key [4]byte
+	_, _ = rand.Read(key[:])
+	buf = append(buf, key[:]...)
+	for i, b := range frame.Payload {
+		buf = append(buf, b^key[i%4])
+	}
+	return buf
+}
+
+// WebSocketMessages assembles the frames sent by a side of a websocket connection into the messages of its log.
+// The fragments of a message are joined, the control frames sent in between them are messages of their own.
+type WebSocketMessages struct {
+	from    string
+	opcode  byte
+	payload []byte
+}
+
+func NewWebSocketMessages(from string) *WebSocketMessages {
+	return &WebSocketMessages{from: from}
+}
+
+// Add adds the frame, and returns the message it completes, if any.
+func (m *WebSocketMessages) Add(frame *WebSocketFrame) (*models.WebSocketMessage, error) {
+	if frame.Compressed {
+		return nil, errors.New("the websocket messages compressed by permessage-deflate aren't supported")
+	}
+	switch frame.Opcode {
+	case wsText, wsBinary:
+		if m.payload != nil {
+			return nil, errors.New("websocket message started before the end of the fragmented one")
+		}
+		if !frame.Fin {
+			m.opcode = frame.Opcode
+			m.payload = append([]byte{}, frame.Payload...)
+			return nil, nil
+		}
+		return newWebSocketMessage(m.from, frame.Opcode, frame.Payload), nil
+	case wsContinuation:
+		if m.payload == nil {
+			return nil, errors.New("websocket continuation frame without a fragmented message")
+		}
+		if len(m.payload)+len(frame.Payload) > maxWebSocketPayload {
+			return nil, fmt.Errorf("fragmented websocket message exceeds the maximum size of %d bytes", maxWebSocketPayload)
+		}
+		m.payload = append(m.payload, frame.Payload...)
+		if !frame.Fin {
+			return nil, nil
+		}
+		msg := newWebSocketMessage(m.from, m.opcode, m.payload)
+		m.payload = nil
+		return msg, nil
+	case wsClose, wsPing, wsPong:
+		if !frame.Fin || len(frame.Payload) > maxControlPayload {
+			return nil, fmt.Errorf("invalid websocket control frame %#x of %d bytes", frame.Opcode, len(frame.Payload))
+		}
+		return newWebSocketMessage(m.from, frame.Opcode, frame.Payload), nil
+	}
+	return nil, fmt.Errorf("unknown websocket opcode %#x", frame.Opcode)
+}
+
+func newWebSocketMessage(from string, opcode byte, payload []byte) *models.WebSocketMessage {
+	msg := &models.WebSocketMessage{From: from}
+	switch opcode {
+	case wsText:
+		msg.Type = models.WebSocketText
+		msg.Data = string(payload)
+	case wsBinary:
+		msg.Type = models.WebSocketBinary
+		msg.Data = base64.StdEncoding.EncodeToString(payload)
+	case wsPing:
+		msg.Type = models.WebSocketPing
+		msg.Data = string(payload)
+	case wsPong:
+		msg.Type = models.WebSocketPong
+		msg.Data = string(payload)
+	case wsClose:
+		msg.Type = models.WebSocketClose
+		if len(payload) >= 2 {
+			msg.CloseCode = int(binary.BigEndian.Uint16(payload))
+			msg.Data = string(payload[2:])
+		}
+	}
+	return msg
+}
+
+// WebSocketMessageFrame returns the frame sending the message of a log.
+func WebSocketMessageFrame(msg models.WebSocketMessage) (*WebSocketFrame, error) {
+	frame := &WebSocketFrame{Fin: true, Payload: []byte(msg.Data)}
+	switch msg.Type {
+	case models.WebSocketText:
+		frame.Opcode = wsText
+	case models.WebSocketBinary:
+		payload, err := base64.StdEncoding.DecodeString(msg.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the binary websocket message: %v", err)
+		}
+		frame.Opcode = wsBinary
+		frame.Payload = payload
+	case models.WebSocketPing:
+		frame.Opcode = wsPing
+	case models.WebSocketPong:
+		frame.Opcode = wsPong
+	case models.WebSocketClose:
+		frame.Opcode = wsClose
+		frame.Payload = nil
+		if msg.CloseCode != 0 {
+			frame.Payload = binary.BigEndian.AppendUint16(nil, uint16(msg.CloseCode))
+			frame.Payload = append(frame.Payload, msg.Data...)
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q of websocket message", msg.Type)
+	}
+	return frame, nil
+}
+
+// SameWebSocketMessage compares the type and the data of the messages. The data of the pings and the pongs
+// isn't compared, it is usually random.
+func SameWebSocketMessage(a, b models.WebSocketMessage) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case models.WebSocketPing, models.WebSocketPong:
+		return true
+	case models.WebSocketClose:
+		return a.CloseCode == b.CloseCode
+	}
+	return a.Data == b.Data
+}
+
+// ReadWebSocketMessage reads the frames of a side of the connection until they complete a message.
+func ReadWebSocketMessage(r io.Reader, messages *WebSocketMessages) (*models.WebSocketMessage, error) {
+	for {
+		frame, err := ReadWebSocketFrame(r)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := messages.Add(frame)
+		if err != nil {
+			return nil, err
+		}
+		if msg != nil {
+			return msg, nil
+		}
+	}
+}
+
+// SimulateWebSocket opens the websocket of the testcase on the application and replays the log of its messages.
+// The messages of the client are sent in order, each one once the messages the server sent before it are read.
+// The log the application answers with is returned as is, up to the first message it failed to send.
+func SimulateWebSocket(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64) (*models.WebSocketResp, error) {
+	err := renderTestCaseTemplate(tc, testSet, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tc.HTTPReq.URL, nil)
+	if err != nil {
+		utils.LogError(logger, err, "failed to create a websocket request from the yaml document")
+		return nil, err
+	}
+	req.Header = ToHTTPHeader(tc.HTTPReq.Header)
+	// the messages are replayed uncompressed, as recorded
+	req.Header.Del("Sec-WebSocket-Extensions")
+	req.Header.Set("KEPLOY-TEST-ID", tc.Name)
+	req.Header.Set("KEPLOY-TEST-SET-ID", testSet)
+	if hostHeader := tc.HTTPReq.Header["Host"]; hostHeader != "" {
+		req.Host = hostHeader
+	}
+
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		addr = net.JoinHostPort(req.URL.Hostname(), "80")
+	}
+	timeout := time.Second * time.Duration(apiTimeout)
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		utils.LogError(logger, err, "failed to connect to the app for the websocket testcase")
+		return nil, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Debug("failed to close the websocket connection to the app", zap.Error(err))
+		}
+	}()
+	// the connection is closed on the cancellation of the test run
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	logger.Debug(fmt.Sprintf("Sending websocket request to user app:%v", req))
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+	err = req.Write(conn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to send the websocket handshake to the app")
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	httpResp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the websocket handshake of the app")
+		return nil, err
+	}
+	resp := &models.WebSocketResp{
+		Handshake: models.HTTPResp{
+			StatusCode: httpResp.StatusCode,
+			Header:     ToYamlHTTPHeader(httpResp.Header),
+		},
+	}
+	if httpResp.StatusCode != http.StatusSwitchingProtocols {
+		// the upgrade is refused, the response is compared as is
+		body, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			utils.LogError(logger, err, "failed reading response body")
+			return nil, err
+		}
+		resp.Handshake.Body = string(body)
+		resp.Timestamp = time.Now()
+		return resp, nil
+	}
+
+	messages := NewWebSocketMessages(models.WebSocketFromServer)
+	clientClosed := false
+	for _, expected := range tc.WebSocket {
+		err = conn.SetDeadline(time.Now().Add(timeout))
+		if err != nil {
+			break
+		}
+		if expected.From == models.WebSocketFromClient {
+			frame, err := WebSocketMessageFrame(expected)
+			if err != nil {
+				utils.LogError(logger, err, "failed to encode the recorded websocket message")
+				return nil, err
+			}
+			_, err = conn.Write(EncodeWebSocketFrame(frame, true))
+			if err != nil {
+				logger.Debug("failed to send the websocket message to the app", zap.Error(err))
+				break
+			}
+			clientClosed = clientClosed || expected.Type == models.WebSocketClose
+			resp.Messages = append(resp.Messages, expected)
+			continue
+		}
+		// the messages missing from the log are reported by the comparison
+		msg, err := ReadWebSocketMessage(reader, messages)
+		if err != nil {
+			logger.Debug("failed to read the websocket message of the app", zap.Error(err))
+			break
+		}
+		resp.Messages = append(resp.Messages, *msg)
+	}
+	resp.Timestamp = time.Now()
+
+	if !clientClosed {
+		frame, err := WebSocketMessageFrame(models.WebSocketMessage{Type: models.WebSocketClose, CloseCode: 1000})
+		if err == nil {
+			_, _ = conn.Write(EncodeWebSocketFrame(frame, true))
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,209 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func TestWebSocketFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *WebSocketFrame
+		mask  bool
+	}{
+		{name: "empty", frame: &WebSocketFrame{Fin: true, Opcode: wsText, Payload: []byte{}}},
+		{name: "short masked", frame: &WebSocketFrame{Fin: true, Opcode: wsText, Payload: []byte("hello")}, mask: true},
+		{name: "125 bytes", frame: &WebSocketFrame{Fin: true, Opcode: wsBinary, Payload: bytes.Repeat([]byte{1}, 125)}},
+		{name: "16 bit length", frame: &WebSocketFrame{Fin: true, Opcode: wsBinary, Payload: bytes.Repeat([]byte{2}, 126)}, mask: true},
+		{name: "largest 16 bit length", frame: &WebSocketFrame{Opcode: wsBinary, Payload: bytes.Repeat([]byte{3}, 0xffff)}},
+		{name: "64 bit length", frame: &WebSocketFrame{Fin: true, Opcode: wsBinary, Payload: bytes.Repeat([]byte{4}, 0x10000)}, mask: true},
+		{name: "compressed", frame: &WebSocketFrame{Fin: true, Compressed: true, Opcode: wsText, Payload: []byte{0xf2, 0x48}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := EncodeWebSocketFrame(tt.frame, tt.mask)
+			if masked := encoded[1]&0x80 != 0; masked != tt.mask {
+				t.Fatalf("got the mask bit %t, want %t", masked, tt.mask)
+			}
+			r := bytes.NewReader(encoded)
+			frame, err := ReadWebSocketFrame(r)
+			if err != nil {
+				t.Fatalf("failed to read the frame: %v", err)
+			}
+			if !reflect.DeepEqual(frame, tt.frame) {
+				t.Fatalf("got the frame %+v, want %+v", frame, tt.frame)
+			}
+			if r.Len() != 0 {
+				t.Errorf("%d bytes are left after the frame", r.Len())
+			}
+		})
+	}
+}
+
+func TestReadWebSocketFrameMalformed(t *testing.T) {
+	masked := EncodeWebSocketFrame(&WebSocketFrame{Fin: true, Opcode: wsText, Payload: []byte("hello")}, true)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr error
+	}{
+		{name: "empty", input: nil, wantErr: io.EOF},
+		{name: "truncated header", input: []byte{0x81}, wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated 16 bit length", input: []byte{0x82, 126, 1}, wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated 64 bit length", input: []byte{0x82, 127, 0, 0, 0}, wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated mask", input: masked[:4], wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated payload", input: masked[:8], wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadWebSocketFrame(bytes.NewReader(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got the error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	lengths := map[string][]byte{
+		"larger than the maximum":       {0x82, 127, 0, 0, 0, 0, 0x04, 0, 0, 1},
+		"highest bit of the length set": {0x82, 127, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	for name, input := range lengths {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadWebSocketFrame(bytes.NewReader(input))
+			if err == nil || errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("got the error %v, want the frame to be rejected by its length", err)
+			}
+		})
+	}
+}
+
+func TestWebSocketMessages(t *testing.T) {
+	frames := []*WebSocketFrame{
+		{Fin: true, Opcode: wsText, Payload: []byte("hello")},
+		{Opcode: wsBinary, Payload: []byte{0, 1}},
+		{Fin: true, Opcode: wsPing, Payload: []byte("keepalive")},
+		{Opcode: wsContinuation, Payload: []byte{2}},
+		{Fin: true, Opcode: wsContinuation, Payload: []byte{3}},
+		{Fin: true, Opcode: wsPong},
+		{Fin: true, Opcode: wsClose, Payload: append([]byte{0x03, 0xe8}, "bye"...)},
+	}
+	want := []models.WebSocketMessage{
+		{From: models.WebSocketFromClient, Type: models.WebSocketText, Data: "hello"},
+		{From: models.WebSocketFromClient, Type: models.WebSocketPing, Data: "keepalive"},
+		{From: models.WebSocketFromClient, Type: models.WebSocketBinary, Data: "AAECAw=="},
+		{From: models.WebSocketFromClient, Type: models.WebSocketPong},
+		{From: models.WebSocketFromClient, Type: models.WebSocketClose, Data: "bye", CloseCode: 1000},
+	}
+
+	var stream []byte
+	for _, frame := range frames {
+		stream = append(stream, EncodeWebSocketFrame(frame, true)...)
+	}
+	r := bytes.NewReader(stream)
+	messages := NewWebSocketMessages(models.WebSocketFromClient)
+	var got []models.WebSocketMessage
+	for {
+		msg, err := ReadWebSocketMessage(r, messages)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read the message: %v", err)
+		}
+		got = append(got, *msg)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got the messages %+v, want %+v", got, want)
+	}
+}
+
+func TestWebSocketMessagesMalformed(t *testing.T) {
+	large := make([]byte, maxWebSocketPayload/2+1)
+
+	tests := []struct {
+		name   string
+		frames []*WebSocketFrame
+	}{
+		{name: "compressed", frames: []*WebSocketFrame{{Fin: true, Compressed: true, Opcode: wsText}}},
+		{name: "unknown opcode", frames: []*WebSocketFrame{{Fin: true, Opcode: 0x3}}},
+		{name: "continuation without a message", frames: []*WebSocketFrame{{Fin: true, Opcode: wsContinuation}}},
+		{name: "message within a fragmented one", frames: []*WebSocketFrame{{Opcode: wsText}, {Fin: true, Opcode: wsText}}},
+		{name: "fragmented control frame", frames: []*WebSocketFrame{{Opcode: wsPing}}},
+		{name: "control frame too long", frames: []*WebSocketFrame{{Fin: true, Opcode: wsClose, Payload: make([]byte, 126)}}},
+		{name: "fragmented message too long", frames: []*WebSocketFrame{{Opcode: wsBinary, Payload: large}, {Fin: true, Opcode: wsContinuation, Payload: large}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := NewWebSocketMessages(models.WebSocketFromServer)
+			var err error
+			for _, frame := range tt.frames {
+				_, err = messages.Add(frame)
+				if err != nil {
+					break
+				}
+			}
+			if err == nil {
+				t.Fatalf("the malformed frames are accepted")
+			}
+		})
+	}
+}
+
+func TestWebSocketMessageFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     models.WebSocketMessage
+		wantErr bool
+	}{
+		{name: "text", msg: models.WebSocketMessage{Type: models.WebSocketText, Data: "hello"}},
+		{name: "binary", msg: models.WebSocketMessage{Type: models.WebSocketBinary, Data: "AAEC"}},
+		{name: "ping", msg: models.WebSocketMessage{Type: models.WebSocketPing, Data: "1"}},
+		{name: "pong", msg: models.WebSocketMessage{Type: models.WebSocketPong}},
+		{name: "close", msg: models.WebSocketMessage{Type: models.WebSocketClose, Data: "going away", CloseCode: 1001}},
+		{name: "close without a code", msg: models.WebSocketMessage{Type: models.WebSocketClose}},
+		{name: "invalid base64", msg: models.WebSocketMessage{Type: models.WebSocketBinary, Data: "%%"}, wantErr: true},
+		{name: "unknown type", msg: models.WebSocketMessage{Type: "stream"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := WebSocketMessageFrame(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got the error %v, want an error: %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			tt.msg.From = models.WebSocketFromServer
+			msg, err := ReadWebSocketMessage(bytes.NewReader(EncodeWebSocketFrame(frame, false)), NewWebSocketMessages(models.WebSocketFromServer))
+			if err != nil {
+				t.Fatalf("failed to read the message: %v", err)
+			}
+			if !reflect.DeepEqual(*msg, tt.msg) {
+				t.Fatalf("got the message %+v, want %+v", *msg, tt.msg)
+			}
+		})
+	}
+}
+
+// TestReadWebSocketMessageTruncated reads every prefix of a fragmented message, a connection closed in the
+// middle of a frame must be reported as such.
+func TestReadWebSocketMessageTruncated(t *testing.T) {
+	stream := EncodeWebSocketFrame(&WebSocketFrame{Opcode: wsText, Payload: bytes.Repeat([]byte("a"), 200)}, true)
+	stream = append(stream, EncodeWebSocketFrame(&WebSocketFrame{Fin: true, Opcode: wsContinuation, Payload: []byte("b")}, true)...)
+	for i := 1; i < len(stream); i++ {
+		_, err := ReadWebSocketMessage(bytes.NewReader(stream[:i]), NewWebSocketMessages(models.WebSocketFromClient))
+		if !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			t.Fatalf("got the error %v for the first %d bytes", err, i)
+		}
+	}
+}